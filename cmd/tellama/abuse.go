@@ -0,0 +1,243 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/k4yt3x/tellama/internal/database"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/telebot.v4"
+	"gorm.io/gorm"
+)
+
+// chatUser identifies a user in a chat.
+type chatUser struct {
+	ChatID int64
+	UserID int64
+}
+
+// isUserBanned reports whether the messages of the user are ignored in the chat.
+func (t *Tellama) isUserBanned(chatID int64, userID int64) bool {
+	_, err := t.dm.GetUserBan(chatID, userID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Error().Err(err).Int64("chat_id", chatID).Msg("Failed to get user ban")
+	}
+	return err == nil
+}
+
+// checkAbuse records a message to the bot and silences the user for the cooldown period
+// if the message exceeds an abuse limit. It returns whether the user was silenced.
+func (t *Tellama) checkAbuse(
+	ctx telebot.Context,
+	chat *telebot.Chat,
+	user *telebot.User,
+	text string,
+	contentType string,
+) bool {
+	settings := t.settings()
+	if t.isAdmin(user) {
+		return false
+	}
+
+	reason := ""
+	if limit := settings.abuse.MaxMessageLength; limit > 0 && contentType != database.ContentTypeDocumentExcerpt &&
+		utf8.RuneCountInString(text) > limit {
+		reason = "message too long"
+	}
+	if limit := settings.abuse.MaxMessagesPerMinute; limit > 0 && t.recordRecentMessage(chat.ID, user.ID) > limit {
+		reason = "too many messages"
+	}
+	if reason == "" {
+		return false
+	}
+
+	until := time.Now().Add(settings.abuse.Cooldown)
+	if err := t.dm.BanUser(chat.ID, user.ID, reason, until); err != nil {
+		log.Error().Err(err).Msg("Failed to silence user")
+		return false
+	}
+	log.Warn().
+		Int64("chat_id", chat.ID).
+		Int64("user_id", user.ID).
+		Str("reason", reason).
+		Time("until", until).
+		Msg("User silenced")
+
+	if notice := settings.responseMessages.UserSilenced; notice != "" {
+		if err := ctx.Reply(notice); err != nil {
+			log.Error().Err(err).Msg("Failed to send silenced notice")
+		}
+	}
+	return true
+}
+
+// recordRecentMessage records a message of the user and returns the number of messages sent in the last minute.
+func (t *Tellama) recordRecentMessage(chatID int64, userID int64) int {
+	t.recentMessagesMutex.Lock()
+	defer t.recentMessagesMutex.Unlock()
+
+	now := time.Now()
+	key := chatUser{ChatID: chatID, UserID: userID}
+	recent := t.recentMessages[key][:0]
+	for _, sent := range t.recentMessages[key] {
+		if now.Sub(sent) < time.Minute {
+			recent = append(recent, sent)
+		}
+	}
+	t.recentMessages[key] = append(recent, now)
+
+	// Forget users who have not sent a message in the last minute
+	for other, times := range t.recentMessages {
+		if now.Sub(times[len(times)-1]) >= time.Minute {
+			delete(t.recentMessages, other)
+		}
+	}
+	return len(t.recentMessages[key])
+}
+
+// parseBanTarget returns the user a /ban or /unban command refers to, either by replying to one of
+// their messages or by user ID as the first argument, and the remaining arguments.
+func parseBanTarget(msg *telebot.Message) (int64, string, []string, bool) {
+	args := strings.Fields(msg.Payload)
+	if msg.ReplyTo != nil && msg.ReplyTo.Sender != nil {
+		return msg.ReplyTo.Sender.ID, msg.ReplyTo.Sender.Username, args, true
+	}
+	if len(args) == 0 {
+		return 0, "", nil, false
+	}
+	userID, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return 0, "", nil, false
+	}
+	return userID, "", args[1:], true
+}
+
+// banTargetLabel returns the username of a user if known, or their ID otherwise.
+func banTargetLabel(userID int64, username string) string {
+	if username != "" {
+		return "@" + username
+	}
+	return strconv.FormatInt(userID, 10)
+}
+
+func (t *Tellama) banUser(ctx telebot.Context) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil {
+		return nil
+	}
+
+	if !t.authorize(chat, msg.Sender, msg, permissionChatAdmin) {
+		return ctx.Reply("You do not have permission to use this command.")
+	}
+
+	userID, username, args, ok := parseBanTarget(msg)
+	if !ok {
+		return ctx.Reply("Usage: reply to a message with /ban [duration] [reason], or /ban <user id> [duration] [reason]")
+	}
+	if userID == t.bot.Me.ID || (msg.Sender != nil && userID == msg.Sender.ID) {
+		return ctx.Reply("You cannot ban this user.")
+	}
+
+	// The duration is optional, and the ban is permanent without one
+	var until time.Time
+	if len(args) > 0 {
+		if duration, err := time.ParseDuration(args[0]); err == nil {
+			if duration <= 0 {
+				return ctx.Reply("The ban duration must be positive.")
+			}
+			until = time.Now().Add(duration)
+			args = args[1:]
+		}
+	}
+	reason := strings.Join(args, " ")
+
+	if err := t.dm.BanUser(chat.ID, userID, reason, until); err != nil {
+		log.Error().Err(err).Msg("Failed to ban user")
+		return ctx.Reply("Failed to ban the user. Please check logs for details.")
+	}
+
+	log.Info().
+		Int64("chat_id", chat.ID).
+		Int64("user_id", msg.Sender.ID).
+		Int64("banned_user_id", userID).
+		Time("until", until).
+		Msg("User banned")
+
+	label := banTargetLabel(userID, username)
+	if until.IsZero() {
+		return ctx.Reply(tr(ctx, "%s is banned from using the bot in this chat.", label))
+	}
+	return ctx.Reply(tr(ctx, "%s is banned from using the bot in this chat until %s.",
+		label, until.UTC().Format(time.DateTime+" MST")))
+}
+
+func (t *Tellama) unbanUser(ctx telebot.Context) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil {
+		return nil
+	}
+
+	if !t.authorize(chat, msg.Sender, msg, permissionChatAdmin) {
+		return ctx.Reply("You do not have permission to use this command.")
+	}
+
+	userID, username, _, ok := parseBanTarget(msg)
+	if !ok {
+		return ctx.Reply("Usage: reply to a message with /unban, or /unban <user id>")
+	}
+
+	if err := t.dm.UnbanUser(chat.ID, userID); err != nil {
+		log.Error().Err(err).Msg("Failed to unban user")
+		return ctx.Reply("Failed to unban the user. Please check logs for details.")
+	}
+
+	log.Info().
+		Int64("chat_id", chat.ID).
+		Int64("user_id", msg.Sender.ID).
+		Int64("unbanned_user_id", userID).
+		Msg("User unbanned")
+
+	return ctx.Reply(tr(ctx, "%s can use the bot in this chat again.", banTargetLabel(userID, username)))
+}
+
+func (t *Tellama) listBans(ctx telebot.Context) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil {
+		return nil
+	}
+
+	if !t.authorize(chat, msg.Sender, msg, permissionChatAdmin) {
+		return ctx.Reply("You do not have permission to use this command.")
+	}
+
+	bannedUsers, err := t.dm.ListBannedUsers(chat.ID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list banned users")
+		return ctx.Reply("Failed to list banned users. Please check logs for details.")
+	}
+
+	if len(bannedUsers) == 0 {
+		return ctx.Reply("No users are banned in this chat.")
+	}
+
+	var reply strings.Builder
+	reply.WriteString("Banned users:")
+	for _, bannedUser := range bannedUsers {
+		reply.WriteString(fmt.Sprintf("\n%d", bannedUser.UserID))
+		if !bannedUser.Until.IsZero() {
+			reply.WriteString(" until " + bannedUser.Until.UTC().Format(time.DateTime+" MST"))
+		}
+		if bannedUser.Reason != "" {
+			reply.WriteString(": " + bannedUser.Reason)
+		}
+	}
+	return ctx.Reply(reply.String())
+}
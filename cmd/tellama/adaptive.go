@@ -0,0 +1,22 @@
+package main
+
+import (
+	"github.com/k4yt3x/tellama/internal/database"
+
+	"github.com/rs/zerolog/log"
+)
+
+// limitHistory drops the oldest history messages beyond the adaptive history limit of the chat.
+func (t *Tellama) limitHistory(chatID int64, messages []database.Message) []database.Message {
+	limit := t.adaptiveHistory.Limit(chatID)
+	if limit == 0 || len(messages) <= limit {
+		return messages
+	}
+
+	log.Debug().
+		Int64("chat_id", chatID).
+		Int("messages", len(messages)).
+		Int("limit", limit).
+		Msg("Limiting history to keep responses fast")
+	return messages[len(messages)-limit:]
+}
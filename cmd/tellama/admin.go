@@ -0,0 +1,307 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/telebot.v4"
+)
+
+// permission is the level of access a command requires.
+type permission int
+
+const (
+	// permissionUntrusted allows anyone in trusted chats, and in any chat if untrusted chats are allowed.
+	permissionUntrusted permission = iota
+	// permissionTrusted allows anyone in trusted chats and trusted users in any chat.
+	permissionTrusted
+	// permissionChatAdmin allows the administrators of trusted chats to change their configuration.
+	permissionChatAdmin
+	// permissionAdmin allows only the configured bot administrators, in any chat.
+	permissionAdmin
+)
+
+// authorize reports whether the user may use a command that requires the permission in the chat.
+// It is the single permission check of all commands.
+func (t *Tellama) authorize(
+	chat *telebot.Chat,
+	user *telebot.User,
+	message *telebot.Message,
+	required permission,
+) bool {
+	switch required {
+	case permissionUntrusted:
+		return t.checkPermissions(chat, user, message) || t.allowUntrustedChats
+	case permissionTrusted:
+		return t.checkPermissions(chat, user, message)
+	case permissionChatAdmin:
+		return t.checkPermissions(chat, user, message) && t.isChatAdmin(chat, user)
+	case permissionAdmin:
+		// Bot administrators must be able to trust chats that are not trusted yet
+		return t.isAdmin(user)
+	}
+	return false
+}
+
+// isAdmin reports whether the user is one of the configured bot administrators.
+func (t *Tellama) isAdmin(user *telebot.User) bool {
+	return user != nil && slices.Contains(t.adminUserIDs, user.ID)
+}
+
+// isChatAdmin reports whether the user may change the configuration of the chat.
+// Bot administrators may configure any chat; otherwise the user must be an
+// administrator of the Telegram group or the owner of the private chat,
+// unless group administrator status is not required by the configuration.
+func (t *Tellama) isChatAdmin(chat *telebot.Chat, user *telebot.User) bool {
+	if t.isAdmin(user) {
+		return true
+	}
+	if user == nil {
+		return false
+	}
+	if chat.Type == telebot.ChatPrivate {
+		return chat.ID == user.ID
+	}
+	if !t.settings().requireChatAdmin {
+		return true
+	}
+
+	member, err := t.bot.ChatMemberOf(chat, user)
+	if err != nil {
+		log.Error().Err(err).Int64("chat_id", chat.ID).Msg("Failed to get chat member")
+		return false
+	}
+	return member.Role == telebot.Administrator || member.Role == telebot.Creator
+}
+
+// chatDisplayTitle returns a title for the chat that is suitable for storing in the database.
+// Private chats have no title, so the user's name and the chat ID are used instead.
+func chatDisplayTitle(chat *telebot.Chat) string {
+	if chat.Title != "" {
+		return chat.Title
+	}
+	name := strings.TrimSpace(chat.FirstName + " " + chat.LastName)
+	if chat.Username != "" {
+		name = "@" + chat.Username
+	}
+	return fmt.Sprintf("%s (%d)", name, chat.ID)
+}
+
+// resolveTargetChat returns the chat given as the command argument or the current chat.
+func (t *Tellama) resolveTargetChat(ctx telebot.Context) (*telebot.Chat, error) {
+	payload := strings.TrimSpace(ctx.Message().Payload)
+	if payload == "" {
+		return ctx.Chat(), nil
+	}
+
+	chatID, err := strconv.ParseInt(payload, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chat ID: %w", err)
+	}
+
+	chat, err := t.bot.ChatByID(chatID)
+	if err != nil {
+		// Fall back to the bare ID if the bot cannot access the chat yet
+		log.Warn().Err(err).Int64("chat_id", chatID).Msg("Failed to get chat information")
+		return &telebot.Chat{ID: chatID}, nil
+	}
+	return chat, nil
+}
+
+func (t *Tellama) trust(ctx telebot.Context) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil {
+		return nil
+	}
+
+	if !t.authorize(chat, msg.Sender, msg, permissionAdmin) {
+		return ctx.Reply("You do not have permission to use this command.")
+	}
+
+	targetChat, err := t.resolveTargetChat(ctx)
+	if err != nil {
+		return ctx.Reply("Please provide a valid chat ID or no argument for the current chat.")
+	}
+
+	if err = t.dm.TrustChat(targetChat.ID, chatDisplayTitle(targetChat)); err != nil {
+		log.Error().Err(err).Msg("Failed to trust chat")
+		return ctx.Reply("Failed to trust chat. Please check logs for details.")
+	}
+
+	log.Info().
+		Int64("chat_id", targetChat.ID).
+		Int64("user_id", msg.Sender.ID).
+		Msg("Chat trusted")
+
+	return ctx.Reply(tr(ctx, "Chat %d is now trusted.", targetChat.ID))
+}
+
+func (t *Tellama) untrust(ctx telebot.Context) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil {
+		return nil
+	}
+
+	if !t.authorize(chat, msg.Sender, msg, permissionAdmin) {
+		return ctx.Reply("You do not have permission to use this command.")
+	}
+
+	targetChat, err := t.resolveTargetChat(ctx)
+	if err != nil {
+		return ctx.Reply("Please provide a valid chat ID or no argument for the current chat.")
+	}
+
+	if err = t.dm.UntrustChat(targetChat.ID); err != nil {
+		log.Error().Err(err).Msg("Failed to untrust chat")
+		return ctx.Reply("Failed to untrust chat. Please check logs for details.")
+	}
+
+	log.Info().
+		Int64("chat_id", targetChat.ID).
+		Int64("user_id", msg.Sender.ID).
+		Msg("Chat untrusted")
+
+	return ctx.Reply(tr(ctx, "Chat %d is no longer trusted.", targetChat.ID))
+}
+
+func (t *Tellama) listTrusted(ctx telebot.Context) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil {
+		return nil
+	}
+
+	if !t.authorize(chat, msg.Sender, msg, permissionAdmin) {
+		return ctx.Reply("You do not have permission to use this command.")
+	}
+
+	trustedChats, err := t.dm.ListTrustedChats()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list trusted chats")
+		return ctx.Reply("Failed to list trusted chats. Please check logs for details.")
+	}
+
+	if len(trustedChats) == 0 {
+		return ctx.Reply("No chats are trusted.")
+	}
+
+	var reply strings.Builder
+	reply.WriteString("Trusted chats:\n")
+	for _, trustedChat := range trustedChats {
+		fmt.Fprintf(&reply, "\n%d: %s", trustedChat.ChatID, trustedChat.ChatTitle)
+	}
+	return ctx.Reply(reply.String())
+}
+
+// resolveTargetUser returns the user given as the command argument or the sender of the replied-to message.
+func resolveTargetUser(msg *telebot.Message) (*telebot.User, error) {
+	payload := strings.TrimSpace(msg.Payload)
+	if payload == "" {
+		if msg.ReplyTo == nil || msg.ReplyTo.Sender == nil {
+			return nil, errors.New("no user ID given and no message replied to")
+		}
+		return msg.ReplyTo.Sender, nil
+	}
+
+	userID, err := strconv.ParseInt(payload, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID: %w", err)
+	}
+	return &telebot.User{ID: userID}, nil
+}
+
+func (t *Tellama) trustUser(ctx telebot.Context) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil {
+		return nil
+	}
+
+	if !t.authorize(chat, msg.Sender, msg, permissionAdmin) {
+		return ctx.Reply("You do not have permission to use this command.")
+	}
+
+	targetUser, err := resolveTargetUser(msg)
+	if err != nil {
+		return ctx.Reply("Please provide a valid user ID or reply to a message of the user.")
+	}
+
+	if err = t.dm.TrustUser(targetUser.ID, targetUser.Username); err != nil {
+		log.Error().Err(err).Msg("Failed to trust user")
+		return ctx.Reply("Failed to trust user. Please check logs for details.")
+	}
+
+	log.Info().
+		Int64("target_user_id", targetUser.ID).
+		Int64("user_id", msg.Sender.ID).
+		Msg("User trusted")
+
+	return ctx.Reply(tr(ctx, "User %d is now trusted in every chat.", targetUser.ID))
+}
+
+func (t *Tellama) untrustUser(ctx telebot.Context) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil {
+		return nil
+	}
+
+	if !t.authorize(chat, msg.Sender, msg, permissionAdmin) {
+		return ctx.Reply("You do not have permission to use this command.")
+	}
+
+	targetUser, err := resolveTargetUser(msg)
+	if err != nil {
+		return ctx.Reply("Please provide a valid user ID or reply to a message of the user.")
+	}
+
+	if err = t.dm.UntrustUser(targetUser.ID); err != nil {
+		log.Error().Err(err).Msg("Failed to untrust user")
+		return ctx.Reply("Failed to untrust user. Please check logs for details.")
+	}
+
+	log.Info().
+		Int64("target_user_id", targetUser.ID).
+		Int64("user_id", msg.Sender.ID).
+		Msg("User untrusted")
+
+	return ctx.Reply(tr(ctx, "User %d is no longer trusted.", targetUser.ID))
+}
+
+func (t *Tellama) listTrustedUsers(ctx telebot.Context) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil {
+		return nil
+	}
+
+	if !t.authorize(chat, msg.Sender, msg, permissionAdmin) {
+		return ctx.Reply("You do not have permission to use this command.")
+	}
+
+	trustedUsers, err := t.dm.ListTrustedUsers()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list trusted users")
+		return ctx.Reply("Failed to list trusted users. Please check logs for details.")
+	}
+
+	if len(trustedUsers) == 0 {
+		return ctx.Reply("No users are trusted.")
+	}
+
+	var reply strings.Builder
+	reply.WriteString("Trusted users:\n")
+	for _, trustedUser := range trustedUsers {
+		fmt.Fprintf(&reply, "\n%d", trustedUser.UserID)
+		if trustedUser.Username != "" {
+			fmt.Fprintf(&reply, ": @%s", trustedUser.Username)
+		}
+	}
+	return ctx.Reply(reply.String())
+}
@@ -0,0 +1,119 @@
+package main //nolint:testpackage // Unit tests are in the same package
+
+import (
+	"testing"
+
+	"github.com/k4yt3x/tellama/internal/database/dbtest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/telebot.v4"
+)
+
+func TestAuthorize(t *testing.T) {
+	builder := dbtest.NewBuilder(t, dbtest.NewTestManager(t))
+	trustedChat := &telebot.Chat{ID: builder.TrustedChat("Trusted"), Title: "Trusted", Type: telebot.ChatGroup}
+	untrustedChat := &telebot.Chat{ID: builder.Chat(), Title: "Untrusted", Type: telebot.ChatGroup}
+	admin := &telebot.User{ID: builder.User().ID}
+	member := &telebot.User{ID: builder.User().ID}
+	privateChat := &telebot.Chat{ID: member.ID, Type: telebot.ChatPrivate}
+	trustedUser := &telebot.User{ID: builder.User().ID}
+	require.NoError(t, builder.Manager().TrustUser(trustedUser.ID, "trusted"))
+
+	tests := []struct {
+		name                string
+		chat                *telebot.Chat
+		user                *telebot.User
+		allowUntrustedChats bool
+		expected            map[permission]bool
+	}{
+		{
+			name: "Member of a trusted chat",
+			chat: trustedChat,
+			user: member,
+			expected: map[permission]bool{
+				permissionUntrusted: true,
+				permissionTrusted:   true,
+				permissionChatAdmin: true,
+				permissionAdmin:     false,
+			},
+		},
+		{
+			name: "Member of an untrusted chat",
+			chat: untrustedChat,
+			user: member,
+			expected: map[permission]bool{
+				permissionUntrusted: false,
+				permissionTrusted:   false,
+				permissionChatAdmin: false,
+				permissionAdmin:     false,
+			},
+		},
+		{
+			name:                "Member of an untrusted chat with untrusted chats allowed",
+			chat:                untrustedChat,
+			user:                member,
+			allowUntrustedChats: true,
+			expected: map[permission]bool{
+				permissionUntrusted: true,
+				permissionTrusted:   false,
+				permissionChatAdmin: false,
+				permissionAdmin:     false,
+			},
+		},
+		{
+			name: "Admin in an untrusted chat",
+			chat: untrustedChat,
+			user: admin,
+			expected: map[permission]bool{
+				permissionUntrusted: false,
+				permissionTrusted:   false,
+				permissionChatAdmin: false,
+				permissionAdmin:     true,
+			},
+		},
+		{
+			name: "Owner of an untrusted private chat",
+			chat: privateChat,
+			user: member,
+			expected: map[permission]bool{
+				permissionUntrusted: false,
+				permissionTrusted:   false,
+				permissionChatAdmin: false,
+				permissionAdmin:     false,
+			},
+		},
+		{
+			name: "Trusted user in their private chat",
+			chat: &telebot.Chat{ID: trustedUser.ID, Type: telebot.ChatPrivate},
+			user: trustedUser,
+			expected: map[permission]bool{
+				permissionUntrusted: true,
+				permissionTrusted:   true,
+				permissionChatAdmin: true,
+				permissionAdmin:     false,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			tellama := &Tellama{
+				dm:                  builder.Manager(),
+				adminUserIDs:        []int64{admin.ID},
+				allowUntrustedChats: tt.allowUntrustedChats,
+			}
+			tellama.currentSettings.Store(&runtimeSettings{requireChatAdmin: false})
+			msg := &telebot.Message{Chat: tt.chat, Sender: tt.user, Text: "/command"}
+
+			for required, expected := range tt.expected {
+				// Act
+				authorized := tellama.authorize(tt.chat, tt.user, msg, required)
+
+				// Assert
+				assert.Equal(t, expected, authorized, "permission %d", required)
+			}
+		})
+	}
+}
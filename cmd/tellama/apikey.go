@@ -0,0 +1,159 @@
+package main
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/k4yt3x/tellama/internal/database"
+	"github.com/k4yt3x/tellama/internal/genai"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/telebot.v4"
+)
+
+// applyUserAPIKey switches to the API key the user registered for their private chat.
+// It reports whether the user's own key is used.
+func (t *Tellama) applyUserAPIKey(
+	chat *telebot.Chat,
+	user *telebot.User,
+	genaiConfig genai.ProviderConfig,
+) bool {
+	if chat.Type != telebot.ChatPrivate || t.genaiProvider != genai.ProviderOpenAI {
+		return false
+	}
+	openaiConfig, ok := genaiConfig.(*genai.OpenAIConfig)
+	if !ok {
+		return false
+	}
+
+	apiKey, err := t.dm.GetUserAPIKey(user.ID)
+	if err != nil {
+		log.Error().Err(err).Int64("user_id", user.ID).Msg("Failed to get user API key")
+		return false
+	}
+	if apiKey == "" {
+		return false
+	}
+
+	openaiConfig.APIKey = apiKey
+	log.Debug().Int64("user_id", user.ID).Msg("Using the user's own API key")
+	return true
+}
+
+// hasUserAPIKey reports whether generations for the user in the chat use the user's own API key.
+func (t *Tellama) hasUserAPIKey(chat *telebot.Chat, user *telebot.User) bool {
+	if chat.Type != telebot.ChatPrivate || t.genaiProvider != genai.ProviderOpenAI {
+		return false
+	}
+	apiKey, err := t.dm.GetUserAPIKey(user.ID)
+	return err == nil && apiKey != ""
+}
+
+func (t *Tellama) setAPIKey(ctx telebot.Context) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil {
+		return nil
+	}
+
+	if !t.authorize(chat, msg.Sender, msg, permissionUntrusted) {
+		return ctx.Reply("You do not have permission to use this command.")
+	}
+
+	// Delete the command so the key does not remain visible in the chat
+	if err := t.bot.Delete(msg); err != nil {
+		log.Warn().Err(err).Int64("chat_id", chat.ID).Msg("Failed to delete API key message")
+	}
+
+	if chat.Type != telebot.ChatPrivate {
+		return ctx.Send("API keys can only be registered in a private chat with the bot. " +
+			"Please revoke the key you just sent, since it was visible to this chat.")
+	}
+	if t.genaiProvider != genai.ProviderOpenAI {
+		return ctx.Send("Personal API keys are only supported with the OpenAI provider.")
+	}
+
+	apiKey := strings.TrimSpace(msg.Payload)
+	if apiKey == "" {
+		return ctx.Send("Usage: /setapikey <api_key>")
+	}
+
+	log.Info().
+		Int64("chat_id", chat.ID).
+		Int64("user_id", msg.Sender.ID).
+		Msg("Setting user API key")
+
+	// Verify the key before storing it
+	if err := t.verifyAPIKey(chat.ID, apiKey); err != nil {
+		log.Warn().Err(err).Int64("user_id", msg.Sender.ID).Msg("Failed to verify user API key")
+		return ctx.Send("The API key could not be verified with the provider. Please check the key and try again.")
+	}
+
+	err := t.dm.SetUserAPIKey(msg.Sender.ID, apiKey)
+	if errors.Is(err, database.ErrEncryptionNotConfigured) {
+		return ctx.Send("Personal API keys are not enabled on this bot.")
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to set user API key")
+		return ctx.Send("Failed to set API key. Please check logs for details.")
+	}
+
+	return ctx.Send("Your API key has been saved and will be used for your messages in this chat. " +
+		"Use /delapikey to remove it.")
+}
+
+// verifyAPIKey checks that the provider accepts the API key by listing its models.
+func (t *Tellama) verifyAPIKey(chatID int64, apiKey string) error {
+	chatOverride, err := t.dm.GetChatOverride(chatID)
+	if err != nil {
+		return err
+	}
+
+	genaiConfig, err := t.applyChatOverride(chatOverride)
+	if err != nil {
+		return err
+	}
+	openaiConfig, ok := genaiConfig.(*genai.OpenAIConfig)
+	if !ok {
+		return errors.New("invalid config type for OpenAI")
+	}
+	openaiConfig.APIKey = apiKey
+
+	genaiClient, err := genai.New(t.genaiProvider, openaiConfig)
+	if err != nil {
+		return err
+	}
+	lister, ok := genaiClient.(genai.ModelLister)
+	if !ok {
+		return errors.New("provider does not support listing models")
+	}
+	_, err = lister.ListModels()
+	return err
+}
+
+func (t *Tellama) delAPIKey(ctx telebot.Context) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil {
+		return nil
+	}
+
+	if !t.authorize(chat, msg.Sender, msg, permissionUntrusted) {
+		return ctx.Reply("You do not have permission to use this command.")
+	}
+
+	log.Info().
+		Int64("chat_id", chat.ID).
+		Int64("user_id", msg.Sender.ID).
+		Msg("Deleting user API key")
+
+	deleted, err := t.dm.DeleteUserAPIKey(msg.Sender.ID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to delete user API key")
+		return ctx.Reply("Failed to delete API key. Please check logs for details.")
+	}
+	if !deleted {
+		return ctx.Reply("You have not registered an API key.")
+	}
+	return ctx.Reply("Your API key has been deleted.")
+}
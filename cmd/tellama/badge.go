@@ -0,0 +1,74 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/k4yt3x/tellama/internal/database"
+	"github.com/k4yt3x/tellama/internal/genai"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/telebot.v4"
+)
+
+// Positions of the model name badge in replies.
+const (
+	modelBadgePrefix = "prefix"
+	modelBadgeSuffix = "suffix"
+)
+
+// applyModelBadge adds the name of the model that generated the response to the reply text
+// if the chat has enabled the model badge. The stored response does not include the badge.
+// Replies in a structured response format are left unchanged so that they remain parseable.
+func applyModelBadge(chatOverride database.ChatOverride, genaiConfig genai.ProviderConfig, response string) string {
+	model := modelName(genaiConfig)
+	if model == "" || chatOverride.ResponseFormat != "" {
+		return response
+	}
+
+	badge := "[" + model + "]"
+	switch chatOverride.ModelBadge {
+	case modelBadgePrefix:
+		return badge + " " + response
+	case modelBadgeSuffix:
+		return response + "\n\n" + badge
+	default:
+		return response
+	}
+}
+
+func (t *Tellama) setModelBadge(ctx telebot.Context) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil {
+		return nil
+	}
+
+	if !t.authorize(chat, msg.Sender, msg, permissionChatAdmin) {
+		return ctx.Reply("You do not have permission to use this command.")
+	}
+
+	value := strings.ToLower(strings.TrimSpace(msg.Payload))
+	switch value {
+	case modelBadgePrefix, modelBadgeSuffix:
+	case "off":
+		value = ""
+	default:
+		return ctx.Reply("Usage: /setmodelbadge <prefix|suffix|off>")
+	}
+
+	if err := t.dm.SetChatModelBadge(chat.ID, chat.Title, value); err != nil {
+		log.Error().Err(err).Msg("Failed to set model badge")
+		return ctx.Reply("Failed to set model badge. Please check logs for details.")
+	}
+
+	log.Info().
+		Int64("chat_id", chat.ID).
+		Int64("user_id", msg.Sender.ID).
+		Str("model_badge", value).
+		Msg("Model badge set")
+
+	if value == "" {
+		return ctx.Reply("Replies will no longer show the model name.")
+	}
+	return ctx.Reply(tr(ctx, "Replies will show the model name as a %s.", value))
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/k4yt3x/tellama/internal/genai"
+	"github.com/k4yt3x/tellama/internal/webhook"
+
+	"github.com/rs/zerolog/log"
+)
+
+// errProviderDown is returned instead of calling the provider while the circuit breaker is open.
+var errProviderDown = errors.New("provider is unavailable")
+
+// allowProvider returns errProviderDown if the circuit breaker does not allow a request to the provider.
+// The outcome of allowed requests must be recorded with observeProvider.
+func (t *Tellama) allowProvider() error {
+	if !t.providerBreaker.Allow() {
+		log.Warn().Msg("Provider is unavailable, skipping generation")
+		return errProviderDown
+	}
+	return nil
+}
+
+// observeProvider records the outcome of a provider call in the circuit breaker.
+// Only outages such as timeouts, connection errors, and server errors count as failures. Errors caused by
+// the settings of a chat, such as an invalid API key or model, and rate limits are only returned to the chat,
+// so that a single chat cannot stop the requests of all others.
+func (t *Tellama) observeProvider(err error) {
+	if err == nil {
+		if t.providerBreaker.Success() {
+			log.Info().Msg("Provider recovered, resuming requests")
+			t.notifyAdmins("The provider is available again.")
+		}
+		return
+	}
+	if errors.Is(err, errProviderDown) {
+		return
+	}
+	t.emitEvent(webhook.EventProviderError, webhook.ProviderError{
+		Provider: t.genaiProvider.String(),
+		Error:    err.Error(),
+	})
+	if !genai.IsUnavailable(err) {
+		t.providerBreaker.Release()
+		return
+	}
+
+	if t.providerBreaker.Failure() {
+		log.Error().
+			Err(err).
+			Int("failures", t.circuitBreaker.FailureThreshold).
+			Dur("cooldown", t.circuitBreaker.Cooldown).
+			Msg("Provider failed repeatedly, stopping requests")
+		t.notifyAdmins(fmt.Sprintf(
+			"The provider failed %d times in a row: %v. Requests are stopped until it recovers.",
+			t.circuitBreaker.FailureThreshold, err,
+		))
+		go t.probeProvider()
+	}
+}
+
+// probeProvider warms up the provider every cooldown until it responds or a request succeeds.
+// Providers that cannot be warmed up are left to the probe request allowed after each cooldown.
+func (t *Tellama) probeProvider() {
+	ticker := time.NewTicker(t.circuitBreaker.Cooldown)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if !t.providerBreaker.IsOpen() {
+			return
+		}
+
+		err := t.warmProvider()
+		if errors.Is(err, errWarmingUnsupported) {
+			return
+		}
+		if err != nil {
+			log.Warn().Err(err).Msg("Provider is still unavailable")
+			continue
+		}
+		t.observeProvider(nil)
+		return
+	}
+}
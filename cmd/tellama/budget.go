@@ -0,0 +1,291 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/k4yt3x/tellama/internal/database"
+	"github.com/k4yt3x/tellama/internal/genai"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/telebot.v4"
+)
+
+// usageTotals holds the aggregated token counts and estimated cost of a set of usage records.
+type usageTotals struct {
+	Tokens int64
+	Cost   float64
+}
+
+// currentUsageDate returns the date of the current budget window.
+func currentUsageDate() string {
+	return time.Now().UTC().Format(time.DateOnly)
+}
+
+// sumUsage aggregates usage records and estimates their cost using the configured pricing.
+func (t *Tellama) sumUsage(usages []database.Usage) usageTotals {
+	var totals usageTotals
+	for _, usage := range usages {
+		totals.Tokens += usage.PromptTokens + usage.CompletionTokens
+		if pricing, ok := t.settings().genaiPricing[strings.ToLower(usage.Model)]; ok {
+			totals.Cost += float64(usage.PromptTokens)*pricing.Prompt/1e6 +
+				float64(usage.CompletionTokens)*pricing.Completion/1e6
+		}
+	}
+	return totals
+}
+
+// exceedsBudget reports whether the totals exceed the given token or cost threshold.
+func exceedsBudget(totals usageTotals, maxTokens int64, maxCost float64) bool {
+	return (maxTokens > 0 && totals.Tokens >= maxTokens) ||
+		(maxCost > 0 && totals.Cost >= maxCost)
+}
+
+// isChatOverBudget reports whether a chat or the bot as a whole has exceeded its daily budget.
+func (t *Tellama) isChatOverBudget(chatID int64) (bool, error) {
+	date := currentUsageDate()
+
+	chatUsages, err := t.dm.GetChatUsage(chatID, date)
+	if err != nil {
+		return false, err
+	}
+	if exceedsBudget(t.sumUsage(chatUsages), t.settings().alerts.ChatDailyTokens, t.settings().alerts.ChatDailyCost) {
+		return true, nil
+	}
+
+	globalUsages, err := t.dm.GetGlobalUsage(date)
+	if err != nil {
+		return false, err
+	}
+	return exceedsBudget(
+		t.sumUsage(globalUsages),
+		t.settings().alerts.GlobalDailyTokens,
+		t.settings().alerts.GlobalDailyCost,
+	), nil
+}
+
+// dailyTokenBudget returns the daily token budget of a chat, zero if it has none.
+func (t *Tellama) dailyTokenBudget(chatID int64) (int64, error) {
+	chatOverride, err := t.dm.GetChatOverride(chatID)
+	if err != nil {
+		return 0, err
+	}
+
+	switch {
+	case chatOverride.DailyTokenBudget < 0:
+		return 0, nil
+	case chatOverride.DailyTokenBudget > 0:
+		return chatOverride.DailyTokenBudget, nil
+	default:
+		return t.settings().dailyTokenBudget, nil
+	}
+}
+
+// isBudgetExhausted reports whether a chat has used up its daily token budget.
+// Users who generate with their own API key are not subject to the budget.
+func (t *Tellama) isBudgetExhausted(chat *telebot.Chat, user *telebot.User) bool {
+	if t.hasUserAPIKey(chat, user) {
+		return false
+	}
+
+	budget, err := t.dailyTokenBudget(chat.ID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get daily token budget")
+		return false
+	}
+	if budget == 0 {
+		return false
+	}
+
+	usages, err := t.dm.GetChatUsage(chat.ID, currentUsageDate())
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get chat usage")
+		return false
+	}
+	tokens := t.sumUsage(usages).Tokens
+	if tokens < budget {
+		return false
+	}
+
+	log.Info().
+		Int64("chat_id", chat.ID).
+		Int64("tokens", tokens).
+		Int64("budget", budget).
+		Msg("Daily token budget exhausted")
+	return true
+}
+
+// replyBudgetExhausted tells the user that the chat has used up its daily token budget, if a notice is configured.
+func (t *Tellama) replyBudgetExhausted(ctx telebot.Context) error {
+	notice := t.settings().responseMessages.BudgetExhausted
+	if notice == "" {
+		return nil
+	}
+	return ctx.Reply(notice)
+}
+
+// applyBudgetFallback switches the model to the configured fallback model
+// if the chat has exceeded its daily budget.
+func (t *Tellama) applyBudgetFallback(
+	chatID int64,
+	genaiConfig genai.ProviderConfig,
+) genai.ProviderConfig {
+	if t.settings().alerts.FallbackModel == "" {
+		return genaiConfig
+	}
+
+	overBudget, err := t.isChatOverBudget(chatID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to check generation budget")
+		return genaiConfig
+	}
+	if !overBudget {
+		return genaiConfig
+	}
+
+	setModelName(genaiConfig, t.settings().alerts.FallbackModel)
+
+	log.Info().
+		Int64("chat_id", chatID).
+		Str("model", t.settings().alerts.FallbackModel).
+		Msg("Generation budget exceeded, using fallback model")
+	return genaiConfig
+}
+
+// recordUsage stores the token usage of a generation and alerts the admins
+// if a budget threshold has been crossed.
+func (t *Tellama) recordUsage(
+	chat *telebot.Chat,
+	user *telebot.User,
+	model string,
+	genStats genai.GenerateStats,
+) {
+	t.recordLastGeneration(chat.ID, model, genStats)
+
+	date := currentUsageDate()
+	err := t.dm.RecordUsage(
+		date,
+		chat.ID,
+		user.ID,
+		model,
+		max(genStats.PromptTokens, 0),
+		max(genStats.TokenCount, 0),
+	)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to record usage")
+		return
+	}
+
+	chatUsages, err := t.dm.GetChatUsage(chat.ID, date)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get chat usage")
+		return
+	}
+	chatTotals := t.sumUsage(chatUsages)
+	if exceedsBudget(chatTotals, t.settings().alerts.ChatDailyTokens, t.settings().alerts.ChatDailyCost) {
+		t.sendBudgetAlert(
+			fmt.Sprintf("%s:%d", date, chat.ID),
+			fmt.Sprintf(
+				"Chat %q (%d) exceeded its daily generation budget: %d tokens, $%.4f.",
+				chat.Title, chat.ID, chatTotals.Tokens, chatTotals.Cost,
+			),
+		)
+	}
+
+	globalUsages, err := t.dm.GetGlobalUsage(date)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get global usage")
+		return
+	}
+	globalTotals := t.sumUsage(globalUsages)
+	if exceedsBudget(globalTotals, t.settings().alerts.GlobalDailyTokens, t.settings().alerts.GlobalDailyCost) {
+		t.sendBudgetAlert(
+			date+":global",
+			fmt.Sprintf(
+				"The global daily generation budget was exceeded: %d tokens, $%.4f.",
+				globalTotals.Tokens, globalTotals.Cost,
+			),
+		)
+	}
+}
+
+// sendBudgetAlert notifies all admins once per budget window.
+func (t *Tellama) sendBudgetAlert(key string, text string) {
+	t.budgetAlertsMutex.Lock()
+	if _, sent := t.budgetAlertsSent[key]; sent {
+		t.budgetAlertsMutex.Unlock()
+		return
+	}
+	t.budgetAlertsSent[key] = struct{}{}
+	t.budgetAlertsMutex.Unlock()
+
+	if t.settings().alerts.FallbackModel != "" {
+		text += fmt.Sprintf(
+			" Switching to the fallback model %q until the window resets.",
+			t.settings().alerts.FallbackModel,
+		)
+	}
+
+	log.Warn().Str("key", key).Msg(text)
+	t.notifyAdmins(text)
+}
+
+func (t *Tellama) setDailyBudget(ctx telebot.Context) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil {
+		return nil
+	}
+
+	if !t.authorize(chat, msg.Sender, msg, permissionAdmin) {
+		return ctx.Reply("You do not have permission to use this command.")
+	}
+
+	var budget int64
+	switch payload := strings.ToLower(strings.TrimSpace(msg.Payload)); payload {
+	case "default":
+		budget = 0
+	case "unlimited":
+		budget = -1
+	default:
+		var err error
+		budget, err = strconv.ParseInt(payload, 10, 64)
+		if err != nil || budget < 1 {
+			return ctx.Reply(
+				"Usage: /setdailybudget <tokens>, /setdailybudget unlimited, " +
+					"or /setdailybudget default to use the configured budget.",
+			)
+		}
+	}
+
+	if err := t.dm.SetChatDailyTokenBudget(chat.ID, chat.Title, budget); err != nil {
+		log.Error().Err(err).Msg("Failed to set daily budget")
+		return ctx.Reply("Failed to set daily budget. Please check logs for details.")
+	}
+
+	log.Info().
+		Int64("chat_id", chat.ID).
+		Int64("user_id", msg.Sender.ID).
+		Int64("budget", budget).
+		Msg("Daily budget set")
+
+	switch {
+	case budget == 0:
+		return ctx.Reply("The chat now uses the configured daily budget.")
+	case budget < 0:
+		return ctx.Reply("The chat no longer has a daily budget.")
+	default:
+		return ctx.Reply(tr(ctx, "The daily budget of the chat is set to %d tokens.", budget))
+	}
+}
+
+// notifyAdmins sends a message to every configured admin user.
+func (t *Tellama) notifyAdmins(text string) {
+	for _, adminID := range t.adminUserIDs {
+		if _, err := t.bot.Send(&telebot.User{ID: adminID}, text); err != nil {
+			log.Error().Err(err).Int64("user_id", adminID).Msg("Failed to notify admin")
+		}
+	}
+}
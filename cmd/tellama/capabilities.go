@@ -0,0 +1,83 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/k4yt3x/tellama/internal/genai"
+
+	"github.com/rs/zerolog/log"
+)
+
+// capabilityCache holds the detected capabilities of each model, since detecting them may query the provider.
+type capabilityCache struct {
+	mu           sync.Mutex
+	capabilities map[string]genai.Capabilities
+}
+
+func newCapabilityCache() *capabilityCache {
+	return &capabilityCache{capabilities: map[string]genai.Capabilities{}}
+}
+
+// clientModel returns the model used by a provider client.
+func clientModel(genaiClient genai.GenerativeAI) string {
+	switch client := genaiClient.(type) {
+	case *genai.Ollama:
+		return client.Model
+	case *genai.OpenAI:
+		return client.Model
+	case *genai.Mock:
+		return client.Model
+	case *genai.Bedrock:
+		return client.Model
+	default:
+		return ""
+	}
+}
+
+// capabilities returns the capabilities of the model of a provider client with the configured overrides applied.
+// Every capability is assumed if detection fails so that features are not turned off by a transient error.
+func (t *Tellama) capabilities(genaiClient genai.GenerativeAI) genai.Capabilities {
+	model := clientModel(genaiClient)
+
+	t.capabilityCache.mu.Lock()
+	detected, ok := t.capabilityCache.capabilities[model]
+	t.capabilityCache.mu.Unlock()
+
+	if !ok {
+		var err error
+		detected, err = genai.DetectCapabilities(genaiClient)
+		if err != nil {
+			log.Warn().Err(err).Str("model", model).Msg("Failed to detect model capabilities")
+			detected = genai.Capabilities{
+				Tools:            true,
+				Vision:           true,
+				Streaming:        true,
+				StructuredOutput: true,
+				Reasoning:        true,
+			}
+		} else {
+			log.Debug().Str("model", model).Strs("capabilities", detected.Names()).Msg("Detected model capabilities")
+			t.capabilityCache.mu.Lock()
+			t.capabilityCache.capabilities[model] = detected
+			t.capabilityCache.mu.Unlock()
+		}
+	}
+	return t.settings().capabilityOverrides.Apply(detected)
+}
+
+// chatCapabilities returns the capabilities of the model used in a chat.
+func (t *Tellama) chatCapabilities(chatID int64) (genai.Capabilities, error) {
+	chatOverride, err := t.dm.GetChatOverride(chatID)
+	if err != nil {
+		return genai.Capabilities{}, err
+	}
+	genaiConfig, err := t.applyChatOverride(chatOverride)
+	if err != nil {
+		return genai.Capabilities{}, err
+	}
+	genaiClient, err := genai.New(t.genaiProvider, genaiConfig)
+	if err != nil {
+		return genai.Capabilities{}, err
+	}
+	return t.capabilities(genaiClient), nil
+}
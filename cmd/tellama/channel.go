@@ -0,0 +1,52 @@
+package main
+
+import (
+	"github.com/rs/zerolog/log"
+	"gopkg.in/telebot.v4"
+)
+
+// messageAuthor returns the user a message is attributed to. Messages sent on behalf of a channel,
+// such as channel posts and their automatic forwards to the discussion group, have no real sender
+// and are attributed to the channel, whose ID and title stand in for those of a user.
+func messageAuthor(msg *telebot.Message) *telebot.User {
+	channel := msg.SenderChat
+	if channel == nil && msg.Chat != nil && msg.Chat.Type == telebot.ChatChannel {
+		channel = msg.Chat
+	}
+	if channel == nil || channel.Type != telebot.ChatChannel {
+		return msg.Sender
+	}
+	return &telebot.User{ID: channel.ID, FirstName: channel.Title, Username: channel.Username}
+}
+
+// isChannelComment reports whether a message in a discussion group is a comment on a channel post,
+// which Telegram forwards to the discussion group automatically.
+func isChannelComment(msg *telebot.Message) bool {
+	return msg.ReplyTo != nil && msg.ReplyTo.AutomaticForward
+}
+
+// handleChannelPost handles the posts of channels like the messages of other chats.
+// Channels have their own trust model: since posts have no sender, a channel must be trusted itself,
+// and neither trusted users nor untrusted chat access apply.
+func (t *Tellama) handleChannelPost(ctx telebot.Context) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil || !t.settings().channelPosts {
+		return nil
+	}
+
+	if !t.dm.IsChatTrusted(chat.ID) {
+		log.Warn().
+			Int64("chat_id", chat.ID).
+			Str("chat_title", chat.Title).
+			Int("message_id", msg.ID).
+			Msg("Untrusted channel")
+		t.emitUntrustedChat(chat, messageAuthor(msg))
+		return nil
+	}
+
+	if msg.Photo != nil {
+		return t.handlePhoto(ctx)
+	}
+	return t.handleMessage(ctx)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/telebot.v4"
+)
+
+// scheduleCommandDeletion deletes the message that invoked the command after the configured delay
+// if the command is configured to be deleted and the bot is allowed to delete the message.
+func (t *Tellama) scheduleCommandDeletion(ctx telebot.Context, command string) {
+	msg := ctx.Message()
+	chat := ctx.Chat()
+	if msg == nil || chat == nil || !strings.HasPrefix(command, "/") {
+		return
+	}
+	if !slices.Contains(t.settings().deleteCommands, strings.TrimPrefix(command, "/")) {
+		return
+	}
+	if !t.canDeleteMessages(chat) {
+		log.Debug().Int64("chat_id", chat.ID).Str("command", command).Msg("Not allowed to delete command message")
+		return
+	}
+
+	time.AfterFunc(t.settings().deleteCommandsDelay, func() {
+		if err := t.bot.Delete(msg); err != nil {
+			log.Warn().Err(err).Int64("chat_id", chat.ID).Str("command", command).Msg("Failed to delete command message")
+		}
+	})
+}
+
+// canDeleteMessages reports whether the bot may delete the messages of other users in the chat.
+// Bots may always delete incoming messages in private chats.
+func (t *Tellama) canDeleteMessages(chat *telebot.Chat) bool {
+	if chat.Type == telebot.ChatPrivate {
+		return true
+	}
+
+	member, err := t.bot.ChatMemberOf(chat, t.bot.Me)
+	if err != nil {
+		log.Error().Err(err).Int64("chat_id", chat.ID).Msg("Failed to get bot chat member")
+		return false
+	}
+	return member.Role == telebot.Creator || (member.Role == telebot.Administrator && member.CanDeleteMessages)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/k4yt3x/tellama/internal/database"
+	"github.com/k4yt3x/tellama/internal/utilities"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/telebot.v4"
+)
+
+// contextPreview replies with a listing of the messages included in the next generation.
+func (t *Tellama) contextPreview(ctx telebot.Context) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil {
+		return nil
+	}
+
+	if !t.authorize(chat, msg.Sender, msg, permissionChatAdmin) {
+		return ctx.Reply("You do not have permission to use this command.")
+	}
+
+	chatOverride, err := t.dm.GetChatOverride(chat.ID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get chat override")
+		return ctx.Reply("Failed to get context. Please check logs for details.")
+	}
+
+	// Build the history the same way as when generating a response
+	messages, err := t.dm.GetMessages(chat.ID, messageThreadID(msg), t.settings().historyFetchLimit)
+	if err == nil {
+		messages = t.limitHistory(chat.ID, messages)
+		messages, err = t.includePinnedMessages(chat.ID, messages)
+	}
+	if err == nil {
+		messages, err = t.includeToolContext(messages)
+	}
+	if err == nil {
+		messages, err = t.prependChatSummary(chat.ID, messageThreadID(msg), messages)
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to build context")
+		return ctx.Reply("Failed to get context. Please check logs for details.")
+	}
+
+	systemPrompt := t.systemPromptTemplate(chatOverride)
+	if !database.IsTrue(chatOverride.MemoryDisabled) {
+		systemPrompt, err = t.appendChatMemories(chat.ID, systemPrompt)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to get chat memories")
+			return ctx.Reply("Failed to get context. Please check logs for details.")
+		}
+	}
+
+	// Count the messages by their origin
+	counts := map[string]int{}
+	historyTokens := 0
+	var first, last time.Time
+	for _, message := range messages {
+		switch {
+		case message.ContentType == database.ContentTypeToolResult:
+			counts["tool"]++
+		case message.ID == 0:
+			counts["summary"]++
+		case message.Pinned:
+			counts["pinned"]++
+			counts[message.Role]++
+		default:
+			counts[message.Role]++
+		}
+		historyTokens += utilities.EstimateTokens(message.Content)
+
+		if message.ID == 0 || message.ContentType == database.ContentTypeToolResult {
+			continue
+		}
+		if first.IsZero() || message.Timestamp.Before(first) {
+			first = message.Timestamp
+		}
+		if message.Timestamp.After(last) {
+			last = message.Timestamp
+		}
+	}
+	systemPromptTokens := utilities.EstimateTokens(systemPrompt)
+
+	var reply strings.Builder
+	reply.WriteString("Context for the next generation:\n")
+	reply.WriteString(fmt.Sprintf("\nMessages: %d", len(messages)))
+	reply.WriteString(fmt.Sprintf("\n- User: %d", counts["user"]))
+	reply.WriteString(fmt.Sprintf("\n- Assistant: %d", counts["assistant"]))
+	reply.WriteString(fmt.Sprintf("\n- Pinned: %d", counts["pinned"]))
+	reply.WriteString(fmt.Sprintf("\n- Tool results: %d", counts["tool"]))
+	reply.WriteString(fmt.Sprintf("\n- Summary: %t", counts["summary"] > 0))
+	if !first.IsZero() {
+		reply.WriteString(fmt.Sprintf("\nFrom: %s", first.UTC().Format(time.DateTime+" MST")))
+		reply.WriteString(fmt.Sprintf("\nTo: %s", last.UTC().Format(time.DateTime+" MST")))
+	}
+	reply.WriteString(fmt.Sprintf("\nHistory fetch limit: %d", t.settings().historyFetchLimit))
+	if limit := t.adaptiveHistory.Limit(chat.ID); limit > 0 {
+		reply.WriteString(fmt.Sprintf("\nAdaptive history limit: %d", limit))
+	}
+	reply.WriteString(fmt.Sprintf(
+		"\nEstimated tokens: %d (system prompt %d, history %d)",
+		systemPromptTokens+historyTokens,
+		systemPromptTokens,
+		historyTokens,
+	))
+	if maxTokens := t.settings().maxContextTokens; maxTokens > 0 {
+		reply.WriteString(fmt.Sprintf("\nMax context tokens: %d", maxTokens))
+	}
+	reply.WriteString("\n\nDocument excerpts depend on the next message and are not included.")
+	return ctx.Reply(reply.String())
+}
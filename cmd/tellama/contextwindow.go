@@ -0,0 +1,82 @@
+package main
+
+import (
+	"github.com/k4yt3x/tellama/internal/database"
+	"github.com/k4yt3x/tellama/internal/utilities"
+
+	"github.com/rs/zerolog/log"
+)
+
+// messageTokenOverhead is the estimated number of tokens a chat message adds besides its content,
+// such as its role and delimiters.
+const messageTokenOverhead = 4
+
+// estimateChatTokens returns the estimated number of tokens of a conversation in chat mode.
+func estimateChatTokens(messages []database.Message) (int, error) {
+	tokens := 0
+	for _, message := range messages {
+		tokens += utilities.EstimateTokens(message.Content) + messageTokenOverhead
+	}
+	return tokens, nil
+}
+
+// fitContextWindow estimates the size of the conversation with the estimate function and, if it exceeds
+// the maximum context size, drops the oldest messages until it fits. System messages, such as the system
+// prompt and the summary, and the latest message are always kept. A maximum of zero disables the check.
+func (t *Tellama) fitContextWindow(
+	messages []database.Message,
+	estimate func([]database.Message) (int, error),
+) ([]database.Message, error) {
+	tokens, err := estimate(messages)
+	if err != nil {
+		return nil, err
+	}
+	log.Debug().Int("tokens", tokens).Int("messages", len(messages)).Msg("Estimated prompt size")
+
+	maxTokens := t.settings().maxContextTokens
+	if maxTokens == 0 || tokens <= maxTokens {
+		return messages, nil
+	}
+	estimated := tokens
+
+	// Drop about as many tokens as the excess, then estimate again since templates add their own text
+	dropped := 0
+	for tokens > maxTokens {
+		var removed int
+		messages, removed = dropOldestMessages(messages, tokens-maxTokens)
+		if removed == 0 {
+			break
+		}
+		dropped += removed
+		tokens, err = estimate(messages)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	log.Warn().
+		Int("estimated_tokens", estimated).
+		Int("max_tokens", maxTokens).
+		Int("dropped_messages", dropped).
+		Int("tokens", tokens).
+		Msg("Prompt exceeds the maximum context size, dropped the oldest messages")
+	return messages, nil
+}
+
+// dropOldestMessages removes the oldest messages that are neither system messages nor the latest message
+// until at least the given number of tokens are removed. It returns the remaining messages and the number
+// of messages removed.
+func dropOldestMessages(messages []database.Message, excess int) ([]database.Message, int) {
+	kept := make([]database.Message, 0, len(messages))
+	removedTokens := 0
+	removed := 0
+	for i, message := range messages {
+		if removedTokens < excess && i < len(messages)-1 && message.Role != "system" {
+			removedTokens += utilities.EstimateTokens(message.Content) + messageTokenOverhead
+			removed++
+			continue
+		}
+		kept = append(kept, message)
+	}
+	return kept, removed
+}
@@ -0,0 +1,143 @@
+package main //nolint:testpackage // Unit tests are in the same package
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/k4yt3x/tellama/internal/database"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newContextWindowTellama returns a bot whose conversations may use up to maxContextTokens tokens.
+func newContextWindowTellama(maxContextTokens int) *Tellama {
+	t := &Tellama{}
+	t.currentSettings.Store(&runtimeSettings{maxContextTokens: maxContextTokens})
+	return t
+}
+
+// testMessage returns a message whose content is estimated at 10 tokens, or 14 tokens with the overhead.
+func testMessage(role string, name string) database.Message {
+	return database.Message{Role: role, Content: name + strings.Repeat(".", 40-len(name))}
+}
+
+// messageNames returns the names that the contents of messages created by testMessage start with.
+func messageNames(messages []database.Message) []string {
+	names := make([]string, len(messages))
+	for i, message := range messages {
+		names[i] = strings.TrimRight(message.Content, ".")
+	}
+	return names
+}
+
+func TestFitContextWindow(t *testing.T) {
+	conversation := []database.Message{
+		testMessage("system", "prompt"),
+		testMessage("user", "u1"),
+		testMessage("assistant", "a1"),
+		testMessage("user", "u2"),
+		testMessage("assistant", "a2"),
+		testMessage("user", "u3"),
+	}
+
+	tests := []struct {
+		name             string
+		maxContextTokens int
+		messages         []database.Message
+		expected         []string
+	}{
+		{
+			name:             "History that fits",
+			maxContextTokens: 84,
+			messages:         conversation,
+			expected:         []string{"prompt", "u1", "a1", "u2", "a2", "u3"},
+		},
+		{
+			name:             "Check disabled",
+			maxContextTokens: 0,
+			messages:         conversation,
+			expected:         []string{"prompt", "u1", "a1", "u2", "a2", "u3"},
+		},
+		{
+			name:             "Drop the oldest messages until it fits",
+			maxContextTokens: 50,
+			messages:         conversation,
+			expected:         []string{"prompt", "a2", "u3"},
+		},
+		{
+			name:             "Drop the oldest message",
+			maxContextTokens: 70,
+			messages:         conversation,
+			expected:         []string{"prompt", "a1", "u2", "a2", "u3"},
+		},
+		{
+			name:             "Keep the system messages and the latest user message",
+			maxContextTokens: 1,
+			messages: []database.Message{
+				testMessage("system", "prompt"),
+				testMessage("system", "summary"),
+				testMessage("user", "u1"),
+				testMessage("assistant", "a1"),
+				testMessage("user", "u2"),
+			},
+			expected: []string{"prompt", "summary", "u2"},
+		},
+		{
+			name:             "Budget smaller than one message",
+			maxContextTokens: 5,
+			messages:         []database.Message{testMessage("user", "u1")},
+			expected:         []string{"u1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			tellama := newContextWindowTellama(tt.maxContextTokens)
+
+			// Act
+			messages, err := tellama.fitContextWindow(tt.messages, estimateChatTokens)
+
+			// Assert
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, messageNames(messages))
+		})
+	}
+
+	t.Run("Estimate error", func(t *testing.T) {
+		// Arrange
+		tellama := newContextWindowTellama(50)
+		estimate := func([]database.Message) (int, error) {
+			return 0, errors.New("template error")
+		}
+
+		// Act
+		_, err := tellama.fitContextWindow(conversation, estimate)
+
+		// Assert
+		assert.Error(t, err)
+	})
+}
+
+func TestDropOldestMessages(t *testing.T) {
+	// Arrange
+	messages := []database.Message{
+		testMessage("system", "prompt"),
+		testMessage("user", "u1"),
+		testMessage("assistant", "a1"),
+		testMessage("user", "u2"),
+	}
+
+	// Act
+	kept, removed := dropOldestMessages(messages, 15)
+	keptAll, removedNone := dropOldestMessages(messages[:1], 100)
+
+	// Assert
+	assert.Equal(t, 2, removed)
+	assert.Equal(t, []string{"prompt", "u2"}, messageNames(kept))
+	assert.Zero(t, removedNone)
+	assert.Equal(t, []string{"prompt"}, messageNames(keptAll))
+	assert.Len(t, messages, 4)
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/k4yt3x/tellama/internal/database"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/telebot.v4"
+)
+
+const (
+	// dbInfoMaxChats is the maximum number of chats listed by /dbinfo.
+	dbInfoMaxChats = 10
+
+	// staleMessageAge is the age after which stored messages are reported as stale.
+	staleMessageAge = 90 * 24 * time.Hour
+
+	// vacuumFreeRatio is the fraction of unused pages above which VACUUM is suggested.
+	vacuumFreeRatio = 0.2
+)
+
+// formatBytes formats a size in bytes using binary units.
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+// dbInfo replies with the size of the database, the data stored per chat, and suggested cleanups.
+func (t *Tellama) dbInfo(ctx telebot.Context) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil {
+		return nil
+	}
+
+	if !t.authorize(chat, msg.Sender, msg, permissionAdmin) {
+		return ctx.Reply("You do not have permission to use this command.")
+	}
+
+	size, err := t.dm.GetDatabaseSize()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get database size")
+		return ctx.Reply("Failed to get database information. Please check logs for details.")
+	}
+
+	counts, err := t.dm.GetChatRowCounts(time.Now().Add(-staleMessageAge))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get chat row counts")
+		return ctx.Reply("Failed to get database information. Please check logs for details.")
+	}
+
+	var reply strings.Builder
+	reply.WriteString("Database information:\n")
+	reply.WriteString(fmt.Sprintf("\nSize: %s", formatBytes(size.Size)))
+	reply.WriteString(fmt.Sprintf("\nUnused: %s", formatBytes(size.FreeSize)))
+	reply.WriteString(fmt.Sprintf("\nChats: %d", len(counts)))
+
+	// Object sizes are only available if SQLite is built with the dbstat virtual table
+	objectSizes, err := t.dm.GetObjectSizes()
+	if err != nil {
+		log.Debug().Err(err).Msg("Table and index sizes are unavailable")
+	} else {
+		reply.WriteString("\n\nTables and indexes:")
+		for _, object := range objectSizes {
+			reply.WriteString(fmt.Sprintf("\n- %s (%s): %s", object.Name, object.Type, formatBytes(object.Size)))
+		}
+	}
+
+	largestCounts := counts[:min(len(counts), dbInfoMaxChats)]
+	reply.WriteString("\n\nLargest chats:")
+	for _, count := range largestCounts {
+		reply.WriteString(fmt.Sprintf(
+			"\n- %d %s: %d messages, %d tool results, %d document chunks, %d notes, %d summaries",
+			count.ChatID,
+			count.ChatTitle,
+			count.Messages,
+			count.ToolInvocations,
+			count.DocumentChunks,
+			count.ChatMemories,
+			count.ChatSummaries,
+		))
+	}
+
+	// Suggest retention actions
+	var suggestions []string
+	if size.Size > 0 && float64(size.FreeSize)/float64(size.Size) > vacuumFreeRatio {
+		reclaim := "Run VACUUM on the database"
+		if t.dm.Driver() == database.DriverMySQL {
+			reclaim = "Run OPTIMIZE TABLE on the largest tables"
+		}
+		suggestions = append(suggestions, fmt.Sprintf(
+			"%s to reclaim %s of unused space.", reclaim, formatBytes(size.FreeSize),
+		))
+	}
+	for _, count := range largestCounts {
+		switch {
+		case !t.dm.IsChatTrusted(count.ChatID):
+			suggestions = append(suggestions, fmt.Sprintf(
+				"Chat %d is not trusted but still stores %d rows.", count.ChatID, count.Total(),
+			))
+		case count.StaleMessages > 0:
+			suggestions = append(suggestions, fmt.Sprintf(
+				"Chat %d has %d messages older than %d days. Consider clearing its history with /amnesia.",
+				count.ChatID, count.StaleMessages, int(staleMessageAge.Hours()/24),
+			))
+		case count.Messages > int64(t.settings().historyFetchLimit):
+			suggestions = append(suggestions, fmt.Sprintf(
+				"Chat %d stores %d messages but only the latest %d are used.",
+				count.ChatID, count.Messages, t.settings().historyFetchLimit,
+			))
+		}
+	}
+
+	if len(suggestions) > 0 {
+		reply.WriteString("\n\nSuggestions:")
+		for _, suggestion := range suggestions {
+			reply.WriteString("\n- " + suggestion)
+		}
+	}
+
+	return ctx.Reply(reply.String())
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/k4yt3x/tellama/internal/database"
+
+	"github.com/rs/zerolog/log"
+)
+
+// degradedRecoveryInterval is how often the database is checked while it is unavailable.
+const degradedRecoveryInterval = 30 * time.Second
+
+// degradedHistorySize is the number of recent messages of each chat thread kept in memory.
+const degradedHistorySize = 100
+
+// historyKey identifies the message history of a chat thread.
+type historyKey struct {
+	chatID   int64
+	threadID int
+}
+
+// degradedMode keeps the bot replying while the database is unavailable.
+// Recent messages are kept in memory to serve as context, and the messages that could
+// not be stored are queued and written once the database recovers.
+type degradedMode struct {
+	mu        sync.Mutex
+	active    bool
+	queueSize int
+	history   map[historyKey][]database.Message
+	queue     []database.Message
+	dropped   int
+}
+
+func newDegradedMode(queueSize int) *degradedMode {
+	return &degradedMode{
+		queueSize: queueSize,
+		history:   map[historyKey][]database.Message{},
+	}
+}
+
+// isDegraded returns whether the bot is running without the database.
+func (t *Tellama) isDegraded() bool {
+	t.degraded.mu.Lock()
+	defer t.degraded.mu.Unlock()
+	return t.degraded.active
+}
+
+// enterDegradedMode switches to replying from memory after a database error.
+// It returns false if degraded mode is disabled, in which case the error must be handled by the caller.
+func (t *Tellama) enterDegradedMode(err error) bool {
+	if t.degraded.queueSize == 0 {
+		return false
+	}
+
+	t.degraded.mu.Lock()
+	if t.degraded.active {
+		t.degraded.mu.Unlock()
+		return true
+	}
+	t.degraded.active = true
+	t.degraded.mu.Unlock()
+
+	log.Error().Err(err).Msg("Database unavailable, entering degraded mode")
+	t.notifyAdmins(fmt.Sprintf(
+		"The database is unavailable: %v. Replies use the recent messages kept in memory "+
+			"and up to %d messages are queued until the database recovers.",
+		err, t.degraded.queueSize,
+	))
+	go t.recoverDatabase()
+	return true
+}
+
+// rememberMessage keeps a message in the in-memory history of its chat thread.
+func (t *Tellama) rememberMessage(message database.Message) {
+	if t.degraded.queueSize == 0 {
+		return
+	}
+
+	t.degraded.mu.Lock()
+	defer t.degraded.mu.Unlock()
+
+	key := historyKey{chatID: message.ChatID, threadID: message.ThreadID}
+	history := append(t.degraded.history[key], message)
+	if len(history) > degradedHistorySize {
+		history = history[len(history)-degradedHistorySize:]
+	}
+	t.degraded.history[key] = history
+}
+
+// queueMessage queues a message to be stored once the database recovers.
+// The oldest messages are dropped if the queue is full.
+func (t *Tellama) queueMessage(message database.Message) {
+	t.degraded.mu.Lock()
+	defer t.degraded.mu.Unlock()
+
+	t.degraded.queue = append(t.degraded.queue, message)
+	t.degraded.trimQueue()
+}
+
+// trimQueue drops the oldest queued messages beyond the queue size. The caller must hold the lock.
+func (d *degradedMode) trimQueue() {
+	if overflow := len(d.queue) - d.queueSize; overflow > 0 {
+		d.queue = d.queue[overflow:]
+		d.dropped += overflow
+	}
+}
+
+// recentHistory returns up to limit of the latest messages of a chat thread kept in memory, oldest first.
+func (t *Tellama) recentHistory(chatID int64, threadID int, limit int) []database.Message {
+	t.degraded.mu.Lock()
+	defer t.degraded.mu.Unlock()
+
+	history := t.degraded.history[historyKey{chatID: chatID, threadID: threadID}]
+	if len(history) > limit {
+		history = history[len(history)-limit:]
+	}
+	return append([]database.Message(nil), history...)
+}
+
+// getHistory returns the message history of a chat thread from the database,
+// or from memory while the database is unavailable.
+func (t *Tellama) getHistory(chatID int64, threadID int) ([]database.Message, error) {
+	limit := t.settings().historyFetchLimit
+	t.flushMessages()
+	if t.isDegraded() {
+		return t.recentHistory(chatID, threadID, limit), nil
+	}
+
+	messages, err := t.dm.GetMessages(chatID, threadID, limit)
+	if err != nil && t.enterDegradedMode(err) {
+		return t.recentHistory(chatID, threadID, limit), nil
+	}
+	return messages, err
+}
+
+// storeMessage stores a message, or queues it while the database is unavailable.
+// The returned ID is zero if the message was queued.
+func (t *Tellama) storeMessage(message database.Message) (uint, error) {
+	t.rememberMessage(message)
+	if t.isDegraded() {
+		t.queueMessage(message)
+		return 0, nil
+	}
+
+	messageID, err := t.dm.StoreMessage(
+		message.ChatID,
+		message.ChatTitle,
+		message.Role,
+		message.ContentType,
+		message.UserID,
+		message.Username,
+		message.FirstName,
+		message.LastName,
+		message.Content,
+		message.TelegramMessageID,
+		message.ReplyToMessageID,
+		message.ThreadID,
+	)
+	if err != nil && t.enterDegradedMode(err) {
+		t.queueMessage(message)
+		return 0, nil
+	}
+	return messageID, err
+}
+
+// recoverDatabase waits for the database to become available, stores the queued messages,
+// and leaves degraded mode.
+func (t *Tellama) recoverDatabase() {
+	ticker := time.NewTicker(degradedRecoveryInterval)
+	defer ticker.Stop()
+
+	stored := 0
+	for range ticker.C {
+		if err := t.dm.Ping(); err != nil {
+			log.Warn().Err(err).Msg("Database is still unavailable")
+			continue
+		}
+
+		t.degraded.mu.Lock()
+		queue := t.degraded.queue
+		t.degraded.queue = nil
+		t.degraded.mu.Unlock()
+
+		if err := t.dm.StoreMessages(queue); err != nil {
+			log.Warn().Err(err).Msg("Failed to store queued messages")
+			t.degraded.mu.Lock()
+			t.degraded.queue = append(queue, t.degraded.queue...)
+			t.degraded.trimQueue()
+			t.degraded.mu.Unlock()
+			continue
+		}
+		stored += len(queue)
+
+		// Messages queued while storing the previous batch are stored on the next tick
+		t.degraded.mu.Lock()
+		if len(t.degraded.queue) > 0 {
+			t.degraded.mu.Unlock()
+			continue
+		}
+		t.degraded.active = false
+		dropped := t.degraded.dropped
+		t.degraded.dropped = 0
+		t.degraded.mu.Unlock()
+
+		log.Info().Int("stored", stored).Int("dropped", dropped).Msg("Database recovered, leaving degraded mode")
+		text := fmt.Sprintf("The database is available again. %d queued messages were stored.", stored)
+		if dropped > 0 {
+			text += fmt.Sprintf(" %d messages were dropped because the queue was full.", dropped)
+		}
+		t.notifyAdmins(text)
+		return
+	}
+}
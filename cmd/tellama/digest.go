@@ -0,0 +1,181 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/k4yt3x/tellama/internal/database"
+	"github.com/k4yt3x/tellama/internal/genai"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/telebot.v4"
+	"gorm.io/gorm"
+)
+
+const digestPrompt = `You are writing a digest of a Telegram chat for members who missed the conversation.
+Start with a one-sentence overview of the period. Then list every topic discussed as a bullet
+with a short bold title, followed by the key points, who raised them, and any decisions made.
+End with a list of open questions or follow-ups if there are any.
+Refer to participants by name. Respond with the digest only.`
+
+// digestChunkSize is the number of messages summarized at a time before they are combined into a digest.
+// It keeps long periods within the context window of the model.
+const digestChunkSize = 200
+
+// parseDigestPeriod parses the period argument of the /summary command.
+func parseDigestPeriod(payload string) (time.Duration, bool) {
+	switch strings.ToLower(strings.TrimSpace(payload)) {
+	case "", "24h", "1d":
+		return 24 * time.Hour, true
+	case "7d", "1w":
+		return 7 * 24 * time.Hour, true
+	default:
+		return 0, false
+	}
+}
+
+// digestMessages generates a digest of the given messages using the digest prompt.
+// Messages beyond a single chunk are summarized in chunks first and the digest is written from those summaries.
+func (t *Tellama) digestMessages(
+	messages []database.Message,
+	genaiClient genai.GenerativeAI,
+) (string, genai.GenerateStats, error) {
+	var genStats genai.GenerateStats
+	transcript := formatTranscript(messages)
+
+	if len(messages) > digestChunkSize {
+		var summaries strings.Builder
+		for start := 0; start < len(messages); start += digestChunkSize {
+			chunk := messages[start:min(start+digestChunkSize, len(messages))]
+			summary, chunkStats, err := t.summarizeMessages(chunk, genaiClient)
+			genStats.Add(chunkStats)
+			if err != nil {
+				return "", genStats, err
+			}
+			fmt.Fprintf(&summaries, "Summary of the messages from %s to %s:\n%s\n\n",
+				chunk[0].Timestamp.UTC().Format("2006-01-02 15:04"),
+				chunk[len(chunk)-1].Timestamp.UTC().Format("2006-01-02 15:04"),
+				summary,
+			)
+		}
+		transcript = summaries.String()
+	}
+
+	gen, err := t.generateResponse([]database.Message{
+		{Role: "system", Content: digestPrompt},
+		{Role: "user", Content: transcript},
+	}, genaiClient)
+	genStats.Add(gen.Stats)
+	return gen.Response, genStats, err
+}
+
+func (t *Tellama) summaryCommand(ctx telebot.Context) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil {
+		return nil
+	}
+
+	if !t.authorize(chat, msg.Sender, msg, permissionTrusted) {
+		return ctx.Reply("You do not have permission to use this command.")
+	}
+
+	period, ok := parseDigestPeriod(msg.Payload)
+	if !ok {
+		return ctx.Reply("Usage: /summary [24h|7d]")
+	}
+
+	// Fetch the whole period instead of the history window used for replies
+	messages, err := t.dm.GetMessagesSince(chat.ID, messageThreadID(msg), time.Now().Add(-period))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get messages")
+		return ctx.Reply("Failed to summarize the chat. Please check logs for details.")
+	}
+	if len(messages) == 0 {
+		return ctx.Reply("No messages were stored in this chat during this period.")
+	}
+
+	return t.postDigest(ctx, messages)
+}
+
+func (t *Tellama) whatDidIMiss(ctx telebot.Context) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil {
+		return nil
+	}
+
+	if !t.authorize(chat, msg.Sender, msg, permissionTrusted) {
+		return ctx.Reply("You do not have permission to use this command.")
+	}
+
+	// Recap the messages since the user last wrote in the chat, or the last day if they never have
+	threadID := messageThreadID(msg)
+	var messages []database.Message
+	lastSeen, err := t.dm.GetLastUserMessage(chat.ID, threadID, msg.Sender.ID)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		messages, err = t.dm.GetMessagesSince(chat.ID, threadID, time.Now().Add(-24*time.Hour))
+	case err == nil:
+		messages, err = t.dm.GetMessagesAfter(chat.ID, threadID, lastSeen.ID)
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get messages")
+		return ctx.Reply("Failed to summarize the chat. Please check logs for details.")
+	}
+	if len(messages) == 0 {
+		return ctx.Reply("Nothing new has been said since your last message.")
+	}
+
+	return t.postDigest(ctx, messages)
+}
+
+// postDigest generates a digest of the messages with the chat's model and replies with it.
+func (t *Tellama) postDigest(ctx telebot.Context, messages []database.Message) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+
+	chatOverride, err := t.dm.GetChatOverride(chat.ID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get chat override")
+		return ctx.Reply("Failed to summarize the chat. Please check logs for details.")
+	}
+	genaiConfig, err := t.applyChatOverride(chatOverride)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to apply chat override")
+		return ctx.Reply("Failed to summarize the chat. Please check logs for details.")
+	}
+	genaiConfig = t.applyBudgetFallback(chat.ID, genaiConfig)
+	genaiClient, err := genai.New(t.genaiProvider, genaiConfig)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to create generative AI client")
+		return ctx.Reply("Failed to summarize the chat. Please check logs for details.")
+	}
+
+	if !t.genaiAllowConcurrent {
+		<-t.sem
+		defer func() { t.sem <- struct{}{} }()
+	}
+
+	log.Info().
+		Int64("chat_id", chat.ID).
+		Int("messages", len(messages)).
+		Msg("Generating chat digest")
+	t.sendTyping(chat, msg)
+
+	digest, genStats, err := t.digestMessages(messages, genaiClient)
+	t.recordUsage(chat, msg.Sender, modelName(genaiConfig), genStats)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to generate chat digest")
+		return ctx.Reply(t.generationErrorMessage(err))
+	}
+	if digest == "" {
+		log.Warn().Msg("Received empty chat digest from generative AI")
+		return ctx.Reply("Failed to summarize the chat. Please check logs for details.")
+	}
+
+	_, err = t.sendReply(ctx, msg, chatOverride, digest)
+	return err
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/k4yt3x/tellama/internal/database"
+	"github.com/k4yt3x/tellama/internal/rag"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/telebot.v4"
+)
+
+// answerDocument answers the question in the caption of a document with the text of the document in the prompt.
+// Documents that do not trigger a response are stored like text messages with only their caption.
+func (t *Tellama) answerDocument(ctx telebot.Context) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	caption := msg.Caption
+	documents := t.settings().documents
+
+	// Only download the documents the bot is going to answer
+	if !documents.Enabled || msg.Sender == nil || !t.shouldProcessMessage(chat, msg, caption) ||
+		!t.authorize(chat, msg.Sender, msg, permissionUntrusted) {
+		if caption == "" {
+			return nil
+		}
+		return t.handleIncomingMessage(ctx, caption, database.ContentTypeText, nil)
+	}
+
+	if msg.Document.FileSize > documents.MaxFileSize {
+		log.Info().
+			Int64("chat_id", chat.ID).
+			Int64("size", msg.Document.FileSize).
+			Msg("Ignored document that is too large")
+		return ctx.Reply(t.settings().responseMessages.DocumentTooLarge)
+	}
+
+	data, err := t.downloadFile(&msg.Document.File)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to download document")
+		return ctx.Reply(t.settings().responseMessages.InternalError)
+	}
+
+	text, err := rag.ExtractText(msg.Document.FileName, data)
+	if err != nil {
+		localized := t.localize(ctx)
+		return localized.Reply(tr(localized, "Failed to read the document: %s", err))
+	}
+	if strings.TrimSpace(text) == "" {
+		return t.localize(ctx).Reply("The document does not contain any text.")
+	}
+
+	content := fmt.Sprintf("%s:\n%s", msg.Document.FileName, t.documentExcerpt(text, caption))
+	if caption != "" {
+		content = caption + "\n\n" + content
+	}
+	return t.handleIncomingMessage(ctx, content, database.ContentTypeDocumentExcerpt, nil)
+}
+
+// documentExcerpt returns the text of a document cut down to the configured maximum length.
+// The chunks most relevant to the question are kept if document retrieval is enabled,
+// otherwise the beginning of the document is kept.
+func (t *Tellama) documentExcerpt(text string, question string) string {
+	maxLength := t.settings().documents.MaxExcerptLength
+	if len([]rune(text)) <= maxLength {
+		return text
+	}
+
+	chunks := rag.SplitText(text, min(t.rag.ChunkSize, maxLength), 0)
+	priority := make([]int, len(chunks))
+	for i := range chunks {
+		priority[i] = i
+	}
+	if t.rag.Enabled && strings.TrimSpace(question) != "" {
+		if ranked, err := t.rankChunks(chunks, question); err != nil {
+			log.Warn().Err(err).Msg("Failed to rank document chunks, using the beginning of the document")
+		} else {
+			priority = ranked
+		}
+	}
+
+	return strings.Join(rag.SelectChunks(chunks, priority, maxLength), "\n[...]\n") + "\n[...]"
+}
+
+// rankChunks returns the indices of the chunks ordered from the most to the least similar to the question.
+func (t *Tellama) rankChunks(chunks []string, question string) ([]int, error) {
+	embedder, err := t.newEmbedder()
+	if err != nil {
+		return nil, err
+	}
+	embeddings, err := embedTexts(embedder, append([]string{question}, chunks...))
+	if err != nil {
+		return nil, err
+	}
+
+	matches := rag.TopK(embeddings[0], embeddings[1:], len(chunks), math.Inf(-1))
+	ranked := make([]int, len(matches))
+	for i, match := range matches {
+		ranked[i] = match.Index
+	}
+	return ranked, nil
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/k4yt3x/tellama/internal/database"
+	"github.com/k4yt3x/tellama/internal/markdown"
+	"github.com/k4yt3x/tellama/internal/secrets"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/telebot.v4"
+	"gorm.io/gorm"
+)
+
+// handleEdited updates the stored content of an edited message and optionally
+// regenerates the bot's reply to it.
+func (t *Tellama) handleEdited(ctx telebot.Context) error {
+	message := ctx.Message()
+	chat := ctx.Chat()
+	user := ctx.Sender()
+	if message == nil || chat == nil || user == nil {
+		return nil
+	}
+
+	text := message.Text
+	contentType := database.ContentTypeText
+	if message.Photo != nil {
+		text = message.Caption
+		contentType = database.ContentTypePhotoCaption
+	}
+	if text == "" || strings.HasPrefix(text, "/") {
+		return nil
+	}
+	text = secrets.Redact(text)
+
+	if !t.authorize(chat, user, message, permissionUntrusted) {
+		return nil
+	}
+
+	stored, err := t.dm.GetMessageByTelegramID(chat.ID, message.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && stored.UserID != user.ID) {
+		log.Debug().Int64("chat_id", chat.ID).Int("message_id", message.ID).Msg("Edited message is not stored")
+		return nil
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get edited message")
+		return err
+	}
+
+	log.Info().
+		Int64("chat_id", chat.ID).
+		Int("message_id", message.ID).
+		Msg("Updating edited message")
+
+	if err = t.dm.UpdateMessageContent(stored.ID, text); err != nil {
+		log.Error().Err(err).Msg("Failed to update edited message")
+		return err
+	}
+
+	if !t.settings().regenerateEdited {
+		return nil
+	}
+	if t.isModerated(chat, text, "message") {
+		return nil
+	}
+	reply, err := t.dm.GetReplyTo(chat.ID, message.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get reply to edited message")
+		return err
+	}
+
+	if t.genaiAllowConcurrent {
+		return t.regenerateReply(ctx, chat, user, message, stored, reply, text, contentType)
+	}
+
+	waitStart := time.Now()
+	select {
+	case <-t.sem:
+		defer func() { t.sem <- struct{}{} }()
+		t.metrics.ObserveQueueWait(time.Since(waitStart))
+		return t.regenerateReply(ctx, chat, user, message, stored, reply, text, contentType)
+	case <-time.After(t.settings().genaiTimeout):
+		log.Warn().
+			Int("message_id", message.ID).
+			Msg("Failed to acquire semaphore to regenerate reply")
+		return nil
+	}
+}
+
+// regenerateReply generates a new reply to an edited message and edits the previous reply to match.
+func (t *Tellama) regenerateReply(
+	ctx telebot.Context,
+	chat *telebot.Chat,
+	user *telebot.User,
+	message *telebot.Message,
+	stored database.Message,
+	reply database.Message,
+	text string,
+	contentType string,
+) error {
+	chatOverride, err := t.dm.GetChatOverride(chat.ID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get chat override")
+		return err
+	}
+
+	// Only the history before the edited message is relevant to the new reply
+	history, err := t.dm.GetMessagesUntil(
+		chat.ID,
+		stored.ThreadID,
+		stored.ID-1,
+		t.settings().historyFetchLimit,
+	)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get message history")
+		return err
+	}
+
+	messages, genaiConfig, genaiClient, err := t.prepareGeneration(
+		chat,
+		user,
+		message,
+		text,
+		contentType,
+		nil,
+		history,
+		chatOverride,
+	)
+	if err != nil {
+		return err
+	}
+
+	t.sendTyping(chat, message)
+	gen, err := t.generateResponse(messages, genaiClient)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to regenerate response")
+		return nil
+	}
+	t.recordUsage(chat, user, modelName(genaiConfig), gen.Stats)
+	if gen.Response == "" {
+		log.Warn().Msg("Received empty response while regenerating reply")
+		return nil
+	}
+	if t.moderateResponses && t.isModerated(chat, gen.Response, "response") {
+		return nil
+	}
+
+	// Edit the previous reply in place
+	previous := &telebot.StoredMessage{MessageID: strconv.Itoa(reply.TelegramMessageID), ChatID: chat.ID}
+	replyText := t.applyStatsFooter(chat, genaiConfig, gen.Stats, applyModelBadge(chatOverride, genaiConfig, gen.Response))
+	_, err = ctx.Bot().Edit(previous, markdown.ToMarkdownV2(replyText), telebot.ModeMarkdownV2)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to edit reply with MarkdownV2 formatting")
+
+		// Retry editing the reply without Markdown formatting
+		if _, err = ctx.Bot().Edit(previous, replyText); err != nil {
+			log.Error().Err(err).Msg("Failed to edit reply")
+			return nil
+		}
+	}
+
+	if err = t.dm.UpdateMessageContent(reply.ID, gen.Response); err != nil {
+		log.Error().Err(err).Msg("Failed to update regenerated reply")
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"math"
+
+	"github.com/k4yt3x/tellama/internal/database"
+	"github.com/k4yt3x/tellama/internal/genai"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/telebot.v4"
+)
+
+const (
+	// retryTemperatureIncrease is added to the temperature when retrying an empty response
+	retryTemperatureIncrease = 0.2
+
+	// defaultOllamaTemperature is the temperature Ollama uses when none is configured
+	defaultOllamaTemperature = 0.8
+
+	// defaultOpenAITemperature is the temperature the OpenAI API uses when none is sent
+	defaultOpenAITemperature = 1.0
+
+	// maxOpenAITemperature is the highest temperature accepted by the OpenAI API
+	maxOpenAITemperature = 2.0
+
+	// defaultBedrockTemperature is assumed for Bedrock models when none is sent, as their defaults vary
+	defaultBedrockTemperature = 0.7
+
+	// maxBedrockTemperature is the highest temperature accepted by most Bedrock models
+	maxBedrockTemperature = 1.0
+)
+
+// retryProviderConfig returns a copy of the provider configuration adjusted
+// to make another empty response less likely.
+func retryProviderConfig(genaiConfig genai.ProviderConfig) (genai.ProviderConfig, error) {
+	retryConfig, err := copyProviderConfig(genaiConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	switch cfg := retryConfig.(type) {
+	case *genai.OllamaConfig:
+		temperature := defaultOllamaTemperature
+		if value, ok := cfg.Options["temperature"].(float64); ok {
+			temperature = value
+		}
+		if cfg.Options == nil {
+			cfg.Options = map[string]any{}
+		}
+		cfg.Options["temperature"] = temperature + retryTemperatureIncrease
+	case *genai.OpenAIConfig:
+		temperature := defaultOpenAITemperature
+		if cfg.Temperature != nil {
+			temperature = *cfg.Temperature
+		}
+		temperature = math.Min(temperature+retryTemperatureIncrease, maxOpenAITemperature)
+		cfg.Temperature = &temperature
+
+		// Reasoning models may spend the whole token budget before answering
+		if cfg.MaxTokens > 0 {
+			cfg.MaxTokens *= 2
+		}
+	case *genai.BedrockConfig:
+		temperature := defaultBedrockTemperature
+		if cfg.Temperature != nil {
+			temperature = *cfg.Temperature
+		}
+		temperature = math.Min(temperature+retryTemperatureIncrease, maxBedrockTemperature)
+		cfg.Temperature = &temperature
+		if cfg.MaxTokens > 0 {
+			cfg.MaxTokens *= 2
+		}
+	}
+	return retryConfig, nil
+}
+
+// retryEmptyResponse generates the response once more with adjusted parameters.
+// An empty generation is returned if the retry fails.
+func (t *Tellama) retryEmptyResponse(
+	chat *telebot.Chat,
+	user *telebot.User,
+	messages []database.Message,
+	genaiConfig genai.ProviderConfig,
+) generation {
+	log.Warn().Int64("chat_id", chat.ID).Msg("Received empty response from generative AI, retrying")
+
+	retryConfig, err := retryProviderConfig(genaiConfig)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to adjust generative AI config for retry")
+		return generation{}
+	}
+
+	genaiClient, err := genai.New(t.genaiProvider, retryConfig)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to create generative AI client for retry")
+		return generation{}
+	}
+
+	gen, err := t.generateResponse(messages, genaiClient)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to regenerate empty response")
+		return generation{}
+	}
+
+	t.recordUsage(chat, user, modelName(retryConfig), gen.Stats)
+	return gen
+}
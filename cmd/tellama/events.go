@@ -0,0 +1,222 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/k4yt3x/tellama/internal/config"
+	"github.com/k4yt3x/tellama/internal/database"
+	"github.com/k4yt3x/tellama/internal/utilities"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/telebot.v4"
+)
+
+// chatEventsNone is the chat override value that disables recording chat events.
+const chatEventsNone = "none"
+
+// pinnedExcerptLength is the number of characters of a pinned message included in its event.
+const pinnedExcerptLength = 100
+
+// chatEvents returns the chat events recorded in a chat.
+func (t *Tellama) chatEvents(chatOverride database.ChatOverride) []string {
+	switch chatOverride.ChatEvents {
+	case "":
+		return t.settings().chatEvents
+	case chatEventsNone:
+		return nil
+	default:
+		return strings.Split(chatOverride.ChatEvents, ",")
+	}
+}
+
+// userDisplayName returns the full name and username of a user.
+func userDisplayName(user *telebot.User) string {
+	name := strings.TrimSpace(user.FirstName + " " + user.LastName)
+	if user.Username == "" {
+		return name
+	}
+	if name == "" {
+		return "@" + user.Username
+	}
+	return fmt.Sprintf("%s (@%s)", name, user.Username)
+}
+
+// recordChatEvent stores a chat event in the history without generating a response.
+// The subject is the user the event is about.
+func (t *Tellama) recordChatEvent(
+	ctx telebot.Context,
+	event string,
+	subject *telebot.User,
+	description string,
+) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil || subject == nil || subject.ID == t.bot.Me.ID {
+		return nil
+	}
+	if !t.dm.IsChatTrusted(chat.ID) && !t.allowUntrustedChats {
+		return nil
+	}
+
+	chatOverride, err := t.dm.GetChatOverride(chat.ID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get chat override")
+		return err
+	}
+	if !slices.Contains(t.chatEvents(chatOverride), event) {
+		return nil
+	}
+
+	// The same join event may be delivered more than once for a message
+	recorded, err := t.dm.HasMessage(chat.ID, msg.ID, subject.ID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to check recorded chat events")
+		return err
+	}
+	if recorded {
+		return nil
+	}
+
+	content := fmt.Sprintf(
+		"%s on %s.",
+		description,
+		msg.Time().UTC().Format("Monday, January 2, 2006 at 15:04 MST"),
+	)
+	log.Info().
+		Int64("chat_id", chat.ID).
+		Str("event", event).
+		Int64("user_id", subject.ID).
+		Msg("Recording chat event")
+
+	_, err = t.dm.StoreMessage(
+		chat.ID,
+		chat.Title,
+		"system",
+		database.ContentTypeChatEvent,
+		subject.ID,
+		subject.Username,
+		subject.FirstName,
+		subject.LastName,
+		content,
+		msg.ID,
+		0,
+		messageThreadID(msg),
+	)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to store chat event")
+	}
+	return err
+}
+
+func (t *Tellama) handleUserJoined(ctx telebot.Context) error {
+	msg := ctx.Message()
+	if msg == nil || msg.UserJoined == nil {
+		return nil
+	}
+
+	description := userDisplayName(msg.UserJoined) + " joined the chat"
+	if msg.Sender != nil && msg.Sender.ID != msg.UserJoined.ID {
+		description = fmt.Sprintf("%s added %s to the chat", userDisplayName(msg.Sender), userDisplayName(msg.UserJoined))
+	}
+	if err := t.recordChatEvent(ctx, config.ChatEventJoin, msg.UserJoined, description); err != nil {
+		return err
+	}
+	return t.welcomeMember(ctx, msg.UserJoined)
+}
+
+func (t *Tellama) handleUserLeft(ctx telebot.Context) error {
+	msg := ctx.Message()
+	if msg == nil || msg.UserLeft == nil {
+		return nil
+	}
+
+	description := userDisplayName(msg.UserLeft) + " left the chat"
+	if msg.Sender != nil && msg.Sender.ID != msg.UserLeft.ID {
+		description = fmt.Sprintf("%s removed %s from the chat", userDisplayName(msg.Sender), userDisplayName(msg.UserLeft))
+	}
+	return t.recordChatEvent(ctx, config.ChatEventLeave, msg.UserLeft, description)
+}
+
+func (t *Tellama) handleNewGroupTitle(ctx telebot.Context) error {
+	msg := ctx.Message()
+	if msg == nil || msg.Sender == nil || msg.NewGroupTitle == "" {
+		return nil
+	}
+
+	description := fmt.Sprintf("%s changed the chat title to %q", userDisplayName(msg.Sender), msg.NewGroupTitle)
+	return t.recordChatEvent(ctx, config.ChatEventTitle, msg.Sender, description)
+}
+
+func (t *Tellama) handlePinned(ctx telebot.Context) error {
+	msg := ctx.Message()
+	if msg == nil || msg.Sender == nil || msg.PinnedMessage == nil {
+		return nil
+	}
+
+	pinned := msg.PinnedMessage.Text
+	if pinned == "" {
+		pinned = msg.PinnedMessage.Caption
+	}
+	description := userDisplayName(msg.Sender) + " pinned a message"
+	if pinned != "" {
+		description += fmt.Sprintf(": %q", utilities.TruncateStrToLength(pinned, pinnedExcerptLength))
+	}
+	return t.recordChatEvent(ctx, config.ChatEventPin, msg.Sender, description)
+}
+
+func (t *Tellama) setChatEvents(ctx telebot.Context) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil {
+		return nil
+	}
+
+	if !t.authorize(chat, msg.Sender, msg, permissionChatAdmin) {
+		return ctx.Reply("You do not have permission to use this command.")
+	}
+
+	// Parse the events from the command arguments
+	payload := strings.ToLower(strings.TrimSpace(msg.Payload))
+	var value string
+	switch payload {
+	case "":
+		return ctx.Reply(
+			"Usage: /setchatevents <join,leave,title,pin>, /setchatevents none to record no events, " +
+				"or /setchatevents default to use the default.",
+		)
+	case "default":
+		value = ""
+	case chatEventsNone:
+		value = chatEventsNone
+	default:
+		events, err := config.ParseChatEvents(strings.FieldsFunc(payload, func(r rune) bool {
+			return r == ',' || r == ' '
+		}))
+		if err != nil {
+			return ctx.Reply(tr(ctx, "Invalid chat events: %v.", err))
+		}
+		value = strings.Join(events, ",")
+	}
+
+	if err := t.dm.SetChatEvents(chat.ID, chat.Title, value); err != nil {
+		log.Error().Err(err).Msg("Failed to set chat events")
+		return ctx.Reply("Failed to set chat events. Please check logs for details.")
+	}
+
+	log.Info().
+		Int64("chat_id", chat.ID).
+		Int64("user_id", msg.Sender.ID).
+		Str("events", value).
+		Msg("Chat events set")
+
+	switch value {
+	case "":
+		return ctx.Reply("Chat events reset to the default.")
+	case chatEventsNone:
+		return ctx.Reply("Chat events will no longer be recorded.")
+	default:
+		return ctx.Reply(tr(ctx, "Recording chat events: %s.", strings.ReplaceAll(value, ",", ", ")))
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"io"
+	"os"
+
+	"github.com/k4yt3x/tellama/internal/backup"
+	"github.com/k4yt3x/tellama/internal/config"
+	"github.com/k4yt3x/tellama/internal/database"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// openDatabase opens the database of the configuration given by the config flag.
+func openDatabase(cmd *cobra.Command) *database.Manager {
+	configPath, err := cmd.Flags().GetString("config")
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse the config flag")
+	}
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load configuration")
+	}
+	dm, err := openConfiguredDatabase(cfg)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to initialize database")
+	}
+	return dm
+}
+
+// runExport is the Cobra command handler that dumps chats from the database.
+func runExport(cmd *cobra.Command, _ []string) {
+	chatID, err := cmd.Flags().GetInt64("chat")
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse the chat flag")
+	}
+	format, err := cmd.Flags().GetString("format")
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse the format flag")
+	}
+	if format != backup.FormatJSONL {
+		log.Fatal().Str("format", format).Msg("Unsupported export format")
+	}
+	outputPath, err := cmd.Flags().GetString("output")
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse the output flag")
+	}
+	includePlaintextKeys, err := cmd.Flags().GetBool("include-plaintext-keys")
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse the include-plaintext-keys flag")
+	}
+
+	dm := openDatabase(cmd)
+	defer dm.Close()
+
+	export, err := dm.ExportChat(chatID, includePlaintextKeys)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to export chats")
+	}
+
+	output := os.Stdout
+	if outputPath != "" && outputPath != "-" {
+		output, err = os.Create(outputPath)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to create the output file")
+		}
+		defer output.Close()
+	}
+	if err = backup.Write(output, export); err != nil {
+		log.Fatal().Err(err).Msg("Failed to write export")
+	}
+
+	log.Info().
+		Int64("chat_id", chatID).
+		Int("trusted_chats", len(export.TrustedChats)).
+		Int("chat_overrides", len(export.ChatOverrides)).
+		Int("messages", len(export.Messages)).
+		Msg("Exported chats")
+}
+
+// runImport is the Cobra command handler that restores an export into the database.
+func runImport(cmd *cobra.Command, args []string) {
+	var input io.Reader = os.Stdin
+	if len(args) > 0 && args[0] != "-" {
+		file, err := os.Open(args[0])
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to open the export file")
+		}
+		defer file.Close()
+		input = file
+	}
+
+	export, err := backup.Read(input)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to read export")
+	}
+
+	dm := openDatabase(cmd)
+	defer dm.Close()
+
+	inserted, err := dm.ImportChat(export)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to import chats")
+	}
+
+	log.Info().
+		Int64("inserted", inserted).
+		Int("records", len(export.TrustedChats)+len(export.ChatOverrides)+len(export.Messages)).
+		Msg("Imported chats")
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/k4yt3x/tellama/internal/config"
+	"github.com/k4yt3x/tellama/internal/database"
+	"github.com/k4yt3x/tellama/internal/i18n"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/telebot.v4"
+)
+
+// Settings of the global override that /setglobal changes.
+const (
+	globalSettingModel     = "model"
+	globalSettingSysPrompt = "sysprompt"
+	globalSettingOptions   = "options"
+	globalSettingTrigger   = "trigger"
+	globalSettingLanguage  = "language"
+)
+
+// applyGlobalSetting returns the global override with the setting changed to the value, or cleared if it is empty.
+func (t *Tellama) applyGlobalSetting(
+	globalOverride database.ChatOverride,
+	setting string,
+	value string,
+) (database.ChatOverride, error) {
+	switch setting {
+	case globalSettingModel:
+		globalOverride.Model = value
+	case globalSettingSysPrompt:
+		globalOverride.SystemPrompt = value
+	case globalSettingOptions:
+		if err := validateOptions(t.genaiProvider, value); err != nil {
+			return database.ChatOverride{}, err
+		}
+		globalOverride.Options = value
+	case globalSettingTrigger:
+		if value != "" {
+			if _, err := config.ParseTriggerMode(value); err != nil {
+				return database.ChatOverride{}, err
+			}
+		}
+		globalOverride.Trigger = value
+	case globalSettingLanguage:
+		if value != "" && !i18n.Supported(value) {
+			return database.ChatOverride{}, fmt.Errorf("unsupported language %q", value)
+		}
+		globalOverride.Language = value
+	default:
+		return database.ChatOverride{}, errors.New("unknown setting")
+	}
+	return globalOverride, nil
+}
+
+func (t *Tellama) setGlobal(ctx telebot.Context) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil {
+		return nil
+	}
+
+	if !t.authorize(chat, msg.Sender, msg, permissionAdmin) {
+		return ctx.Reply("You do not have permission to use this command.")
+	}
+
+	setting, value, _ := strings.Cut(strings.TrimSpace(msg.Payload), " ")
+	setting = strings.ToLower(setting)
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return ctx.Reply(
+			"Usage: /setglobal <setting> <value>, or /setglobal <setting> default to clear it. " +
+				"Settings: model, sysprompt, options, trigger, language.",
+		)
+	}
+	if value == "default" {
+		value = ""
+	}
+
+	globalOverride, err := t.dm.GetGlobalChatOverride()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get global override")
+		return ctx.Reply("Failed to set global setting. Please check logs for details.")
+	}
+
+	globalOverride, err = t.applyGlobalSetting(globalOverride, setting, value)
+	if err != nil {
+		return ctx.Reply(tr(ctx, "Invalid global setting: %v", err))
+	}
+
+	if err = t.dm.SetGlobalChatOverride(globalOverride); err != nil {
+		log.Error().Err(err).Msg("Failed to set global override")
+		return ctx.Reply("Failed to set global setting. Please check logs for details.")
+	}
+
+	log.Info().
+		Int64("user_id", msg.Sender.ID).
+		Str("setting", setting).
+		Msg("Global setting set")
+
+	if value == "" {
+		return ctx.Reply(tr(ctx, "Global setting %s cleared.", setting))
+	}
+	return ctx.Reply(tr(ctx, "Global setting %s set.", setting))
+}
+
+func (t *Tellama) getGlobal(ctx telebot.Context) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil {
+		return nil
+	}
+
+	if !t.authorize(chat, msg.Sender, msg, permissionAdmin) {
+		return ctx.Reply("You do not have permission to use this command.")
+	}
+
+	globalOverride, err := t.dm.GetGlobalChatOverride()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get global override")
+		return ctx.Reply("Failed to get global settings. Please check logs for details.")
+	}
+
+	var reply strings.Builder
+	reply.WriteString(tr(ctx, "Global settings:"))
+	reply.WriteString("\n")
+	for _, setting := range []struct {
+		name  string
+		value string
+	}{
+		{globalSettingModel, globalOverride.Model},
+		{globalSettingSysPrompt, globalOverride.SystemPrompt},
+		{globalSettingOptions, globalOverride.Options},
+		{globalSettingTrigger, globalOverride.Trigger},
+		{globalSettingLanguage, globalOverride.Language},
+	} {
+		value := setting.value
+		if value == "" {
+			value = "default"
+		}
+		fmt.Fprintf(&reply, "\n%s: %s", setting.name, value)
+	}
+	return ctx.Reply(reply.String())
+}
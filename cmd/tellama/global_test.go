@@ -0,0 +1,84 @@
+package main //nolint:testpackage // Unit tests are in the same package
+
+import (
+	"testing"
+
+	"github.com/k4yt3x/tellama/internal/database"
+	"github.com/k4yt3x/tellama/internal/genai"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyGlobalSetting(t *testing.T) {
+	current := database.ChatOverride{Model: "llama3.3", SystemPrompt: "You are a pirate.", Language: "ja"}
+
+	tests := []struct {
+		name     string
+		setting  string
+		value    string
+		expected database.ChatOverride
+		valid    bool
+	}{
+		{
+			name:     "Model",
+			setting:  globalSettingModel,
+			value:    "qwen3",
+			expected: database.ChatOverride{Model: "qwen3", SystemPrompt: "You are a pirate.", Language: "ja"},
+			valid:    true,
+		},
+		{
+			name:     "Clear system prompt",
+			setting:  globalSettingSysPrompt,
+			value:    "",
+			expected: database.ChatOverride{Model: "llama3.3", Language: "ja"},
+			valid:    true,
+		},
+		{
+			name:    "Options",
+			setting: globalSettingOptions,
+			value:   `{"temperature":0.3}`,
+			expected: database.ChatOverride{
+				Model:        "llama3.3",
+				SystemPrompt: "You are a pirate.",
+				Language:     "ja",
+				Options:      `{"temperature":0.3}`,
+			},
+			valid: true,
+		},
+		{
+			name:    "Trigger",
+			setting: globalSettingTrigger,
+			value:   "prefix",
+			expected: database.ChatOverride{
+				Model:        "llama3.3",
+				SystemPrompt: "You are a pirate.",
+				Language:     "ja",
+				Trigger:      "prefix",
+			},
+			valid: true,
+		},
+		{name: "Invalid options", setting: globalSettingOptions, value: `{"temperature":50}`},
+		{name: "Invalid trigger", setting: globalSettingTrigger, value: "always"},
+		{name: "Unsupported language", setting: globalSettingLanguage, value: "xx"},
+		{name: "Unknown setting", setting: "budget", value: "100"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			tellama := &Tellama{genaiProvider: genai.ProviderOllama}
+
+			// Act
+			globalOverride, err := tellama.applyGlobalSetting(current, tt.setting, tt.value)
+
+			// Assert
+			if !tt.valid {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, globalOverride)
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/k4yt3x/tellama/internal/database"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/telebot.v4"
+	"gorm.io/gorm"
+)
+
+const (
+	// startPayloadHandoffPrefix marks deep-link payloads that continue a group answer in private
+	startPayloadHandoffPrefix = "handoff_"
+
+	// handoffContextLimit is the number of group messages copied into the private chat
+	handoffContextLimit = 20
+)
+
+// addHandoffButton attaches a "continue in DM" button to a long answer sent in a group.
+func (t *Tellama) addHandoffButton(chat *telebot.Chat, sent *telebot.Message, messageID uint, response string) {
+	minLength := t.settings().handoffMinLength
+	if minLength <= 0 || chat.Type == telebot.ChatPrivate || len([]rune(response)) < minLength {
+		return
+	}
+
+	markup := &telebot.ReplyMarkup{}
+	markup.Inline(markup.Row(markup.URL(
+		"Continue in DM",
+		fmt.Sprintf("https://t.me/%s?start=%s%d", t.bot.Me.Username, startPayloadHandoffPrefix, messageID),
+	)))
+
+	if _, err := t.bot.EditReplyMarkup(sent, markup); err != nil {
+		log.Error().Err(err).Msg("Failed to add handoff button")
+	}
+}
+
+// handoff seeds a private chat with the group context of an answer.
+func (t *Tellama) handoff(ctx telebot.Context, payload string) error {
+	chat := ctx.Chat()
+	user := ctx.Sender()
+
+	if !t.authorize(chat, user, ctx.Message(), permissionTrusted) {
+		return ctx.Send(t.settings().responseMessages.PrivateChatDisallowed)
+	}
+
+	messageID, err := strconv.ParseUint(payload, 10, 0)
+	if err != nil {
+		return ctx.Send("This link is invalid.")
+	}
+
+	answer, err := t.dm.GetMessage(uint(messageID))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ctx.Send("This conversation is no longer available.")
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get handoff message")
+		return ctx.Send(t.settings().responseMessages.InternalError)
+	}
+
+	// Only members of the group may read its context
+	member, err := t.bot.ChatMemberOf(&telebot.Chat{ID: answer.ChatID}, user)
+	if err != nil || member.Role == telebot.Left || member.Role == telebot.Kicked {
+		log.Warn().
+			Err(err).
+			Int64("chat_id", answer.ChatID).
+			Int64("user_id", user.ID).
+			Msg("Rejected handoff from a chat the user is not a member of")
+		return ctx.Send("You must be a member of the group to continue this conversation.")
+	}
+
+	messages, err := t.dm.GetMessagesUntil(answer.ChatID, answer.ThreadID, answer.ID, handoffContextLimit)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get handoff context")
+		return ctx.Send(t.settings().responseMessages.InternalError)
+	}
+
+	if err = t.seedMessages(chat, messages); err != nil {
+		log.Error().Err(err).Msg("Failed to seed handoff context")
+		return ctx.Send(t.settings().responseMessages.InternalError)
+	}
+
+	log.Info().
+		Int64("chat_id", chat.ID).
+		Int64("source_chat_id", answer.ChatID).
+		Int("messages", len(messages)).
+		Msg("Handed off group conversation")
+
+	return ctx.Send(tr(ctx, "Continuing the conversation from %s. What would you like to know?", answer.ChatTitle))
+}
+
+// seedMessages copies messages from another chat into the history of a chat.
+func (t *Tellama) seedMessages(chat *telebot.Chat, messages []database.Message) error {
+	for _, message := range messages {
+		_, err := t.dm.StoreMessage(
+			chat.ID,
+			chat.Title,
+			message.Role,
+			message.ContentType,
+			message.UserID,
+			message.Username,
+			message.FirstName,
+			message.LastName,
+			message.Content,
+			0,
+			0,
+			0,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/k4yt3x/tellama/internal/i18n"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/telebot.v4"
+)
+
+// languageKey is the context key holding the language of the command replies.
+const languageKey = "language"
+
+// localizedContext translates the text replies of command handlers into the language of the chat.
+type localizedContext struct {
+	telebot.Context
+	language string
+}
+
+func (c *localizedContext) Send(what any, opts ...any) error {
+	if text, ok := what.(string); ok {
+		what = i18n.Translate(c.language, text)
+	}
+	return c.Context.Send(what, opts...)
+}
+
+func (c *localizedContext) Reply(what any, opts ...any) error {
+	if text, ok := what.(string); ok {
+		what = i18n.Translate(c.language, text)
+	}
+	return c.Context.Reply(what, opts...)
+}
+
+// localize wraps the context of a command so that its replies are in the language set for the chat.
+func (t *Tellama) localize(ctx telebot.Context) telebot.Context {
+	chat := ctx.Chat()
+	if chat == nil {
+		return ctx
+	}
+
+	chatOverride, err := t.dm.GetChatOverride(chat.ID)
+	if err != nil {
+		log.Warn().Err(err).Int64("chat_id", chat.ID).Msg("Failed to get chat language")
+		return ctx
+	}
+	if chatOverride.Language == "" || chatOverride.Language == i18n.DefaultLanguage {
+		return ctx
+	}
+
+	ctx.Set(languageKey, chatOverride.Language)
+	return &localizedContext{Context: ctx, language: chatOverride.Language}
+}
+
+// tr formats a reply in the language of the command's chat.
+func tr(ctx telebot.Context, format string, args ...any) string {
+	language, _ := ctx.Get(languageKey).(string)
+	return i18n.Sprintf(language, format, args...)
+}
+
+func (t *Tellama) setLanguage(ctx telebot.Context) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil {
+		return nil
+	}
+
+	if !t.authorize(chat, msg.Sender, msg, permissionChatAdmin) {
+		return ctx.Reply("You do not have permission to use this command.")
+	}
+
+	language := strings.ToLower(strings.TrimSpace(msg.Payload))
+	switch {
+	case language == "default":
+		language = ""
+	case language == "" || !i18n.Supported(language):
+		return ctx.Reply(tr(ctx,
+			"Usage: /setlang <language>, or /setlang default to use English. Available languages: %s.",
+			strings.Join(i18n.Languages(), ", "),
+		))
+	}
+
+	if err := t.dm.SetChatLanguage(chat.ID, chat.Title, language); err != nil {
+		log.Error().Err(err).Msg("Failed to set language")
+		return ctx.Reply("Failed to set language. Please check logs for details.")
+	}
+
+	log.Info().
+		Int64("chat_id", chat.ID).
+		Int64("user_id", msg.Sender.ID).
+		Str("language", language).
+		Msg("Language set")
+
+	// Confirm in the new language rather than the previous one
+	if language == "" {
+		language = i18n.DefaultLanguage
+	}
+	return ctx.Reply(i18n.Sprintf(language, "Language set to %s.", i18n.Name(language)))
+}
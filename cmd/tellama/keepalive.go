@@ -0,0 +1,110 @@
+package main
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/k4yt3x/tellama/internal/database"
+	"github.com/k4yt3x/tellama/internal/genai"
+
+	"github.com/rs/zerolog/log"
+)
+
+// keepAliveMetrics counts the keep-alive pings sent and their latency.
+type keepAliveMetrics struct {
+	ProviderPings       atomic.Int64
+	ProviderFailures    atomic.Int64
+	ProviderLastLatency atomic.Int64
+	TelegramPings       atomic.Int64
+	TelegramFailures    atomic.Int64
+	TelegramLastLatency atomic.Int64
+}
+
+// errWarmingUnsupported is returned by warmProvider if the provider cannot be warmed up.
+var errWarmingUnsupported = errors.New("provider does not support warming up")
+
+// markActive records that the bot has just talked to the provider.
+func (t *Tellama) markActive() {
+	t.lastActivity.Store(time.Now().UnixNano())
+}
+
+// warmProvider opens a connection to the provider using the global configuration.
+func (t *Tellama) warmProvider() error {
+	genaiConfig, err := t.applyChatOverride(database.ChatOverride{})
+	if err != nil {
+		return err
+	}
+
+	genaiClient, err := genai.New(t.genaiProvider, genaiConfig)
+	if err != nil {
+		return err
+	}
+
+	warmer, ok := genaiClient.(genai.Warmer)
+	if !ok {
+		return errWarmingUnsupported
+	}
+	return warmer.Warm()
+}
+
+// pingProvider warms up the provider and records the outcome.
+func (t *Tellama) pingProvider() {
+	start := time.Now()
+	err := t.warmProvider()
+	latency := time.Since(start)
+
+	t.keepAliveMetrics.ProviderPings.Add(1)
+	t.keepAliveMetrics.ProviderLastLatency.Store(int64(latency))
+	if err != nil {
+		t.keepAliveMetrics.ProviderFailures.Add(1)
+		log.Warn().Err(err).Dur("latency", latency).Msg("Provider keep-alive ping failed")
+		return
+	}
+	log.Debug().Dur("latency", latency).Msg("Provider keep-alive ping succeeded")
+}
+
+// pingTelegram sends a lightweight request to Telegram and records the outcome.
+func (t *Tellama) pingTelegram() {
+	start := time.Now()
+	_, err := t.bot.Raw("getMe", nil)
+	latency := time.Since(start)
+
+	t.keepAliveMetrics.TelegramPings.Add(1)
+	t.keepAliveMetrics.TelegramLastLatency.Store(int64(latency))
+	if err != nil {
+		t.keepAliveMetrics.TelegramFailures.Add(1)
+		log.Warn().Err(err).Dur("latency", latency).Msg("Telegram keep-alive ping failed")
+		return
+	}
+	log.Debug().Dur("latency", latency).Msg("Telegram keep-alive ping succeeded")
+}
+
+// runKeepAlive pings the provider and Telegram whenever the bot has been idle
+// for the configured interval.
+func (t *Tellama) runKeepAlive() {
+	if t.keepAlive.WarmOnStart {
+		t.pingProvider()
+	}
+	t.markActive()
+
+	ticker := time.NewTicker(t.keepAlive.IdleInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		idle := time.Since(time.Unix(0, t.lastActivity.Load()))
+		if idle < t.keepAlive.IdleInterval {
+			continue
+		}
+
+		t.pingProvider()
+		t.pingTelegram()
+		t.markActive()
+		log.Debug().
+			Int64("provider_pings", t.keepAliveMetrics.ProviderPings.Load()).
+			Int64("provider_failures", t.keepAliveMetrics.ProviderFailures.Load()).
+			Int64("telegram_pings", t.keepAliveMetrics.TelegramPings.Load()).
+			Int64("telegram_failures", t.keepAliveMetrics.TelegramFailures.Load()).
+			Msg("Keep-alive metrics")
+	}
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/k4yt3x/tellama/internal/config"
+	"github.com/k4yt3x/tellama/internal/database"
+	"github.com/k4yt3x/tellama/internal/genai"
+	"github.com/k4yt3x/tellama/internal/metrics"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"gopkg.in/telebot.v4"
+)
+
+// loadtestChatIDBase is added to the index of each simulated chat to form its ID.
+const loadtestChatIDBase = 1_000_000
+
+// loadtestTelegram is a stand-in for the Telegram Bot API that counts the bot's replies.
+type loadtestTelegram struct {
+	bot        *telebot.User
+	serverBusy string
+	messageID  atomic.Int64
+	replies    atomic.Int64
+	busy       atomic.Int64
+}
+
+// nextMessageID returns a new message ID.
+func (l *loadtestTelegram) nextMessageID() int {
+	return int(l.messageID.Add(1))
+}
+
+// ServeHTTP answers Bot API requests with the minimal responses the bot expects.
+func (l *loadtestTelegram) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	method := path.Base(req.URL.Path)
+	payload := map[string]any{}
+	if strings.HasPrefix(req.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	var result any = true
+	switch method {
+	case "sendMessage", "editMessageText", "sendDocument":
+		text, _ := payload["text"].(string)
+		if method == "sendMessage" {
+			l.replies.Add(1)
+			if text == l.serverBusy {
+				l.busy.Add(1)
+			}
+		}
+		chatID, _ := strconv.ParseInt(fmt.Sprint(payload["chat_id"]), 10, 64)
+		result = map[string]any{
+			"message_id": l.nextMessageID(),
+			"date":       time.Now().Unix(),
+			"chat":       &telebot.Chat{ID: chatID, Type: telebot.ChatPrivate},
+			"from":       l.bot,
+			"text":       text,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"ok": true, "result": result})
+}
+
+// loadtestOptions are the parameters of a load test.
+type loadtestOptions struct {
+	chats    int
+	messages int
+	rate     float64
+	latency  time.Duration
+	database string
+}
+
+// runLoadtest is the Cobra command handler that measures the bot under simulated load.
+func runLoadtest(cmd *cobra.Command, _ []string) {
+	configPath, err := cmd.Flags().GetString("config")
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse the config flag")
+	}
+	var options loadtestOptions
+	if options.chats, err = cmd.Flags().GetInt("chats"); err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse the chats flag")
+	}
+	if options.messages, err = cmd.Flags().GetInt("messages"); err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse the messages flag")
+	}
+	if options.rate, err = cmd.Flags().GetFloat64("rate"); err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse the rate flag")
+	}
+	if options.latency, err = cmd.Flags().GetDuration("latency"); err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse the latency flag")
+	}
+	if options.database, err = cmd.Flags().GetString("database"); err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse the database flag")
+	}
+	if options.chats < 1 || options.messages < 1 || options.rate <= 0 || options.latency < 0 {
+		log.Fatal().Msg("The number of chats, messages, and the rate must be positive and the latency not negative")
+	}
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load configuration")
+	}
+
+	// Use a throwaway database unless one is given so that the simulated chats do not pollute real data
+	if options.database == "" {
+		var dir string
+		dir, err = os.MkdirTemp("", "tellama-loadtest-")
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to create a temporary directory for the database")
+		}
+		defer os.RemoveAll(dir)
+		options.database = filepath.Join(dir, "tellama.db")
+	}
+
+	if err = loadtest(cfg, options); err != nil {
+		log.Fatal().Err(err).Msg("Failed to run the load test")
+	}
+}
+
+// loadtest sends messages from the simulated chats through the full message pipeline against the
+// mock provider and prints the throughput and latencies observed.
+func loadtest(cfg *config.Config, options loadtestOptions) error {
+	// Replace the provider with the mock so that only the bot itself is measured
+	cfg.Database.Driver = database.DriverSQLite
+	cfg.Database.Path = options.database
+	cfg.GenerativeAI.Provider = genai.ProviderMock
+	cfg.GenerativeAI.Config = &genai.MockConfig{Model: "mock", Latency: options.latency}
+	cfg.GenerativeAI.Routing.Enabled = false
+	cfg.Tools.WebSearch.Enabled = false
+	cfg.RAG.Enabled = false
+
+	telegram := &loadtestTelegram{
+		bot:        &telebot.User{ID: replBotID, IsBot: true, FirstName: "Tellama", Username: replBotUsername},
+		serverBusy: cfg.ResponseMessages.ServerBusy,
+	}
+	telegram.messageID.Store(time.Now().Unix())
+	server := httptest.NewServer(telegram)
+	defer server.Close()
+
+	t, err := newTellama(cfg, &telebot.LongPoller{}, telebot.Settings{
+		URL:         server.URL,
+		Token:       cfg.Telegram.BotToken,
+		Synchronous: true,
+		Offline:     true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to initialize Tellama: %w", err)
+	}
+	defer t.dm.Close()
+	t.bot.Me = telegram.bot
+
+	// Every message is logged while it is handled, which would drown out the report
+	level := zerolog.GlobalLevel()
+	zerolog.SetGlobalLevel(zerolog.WarnLevel)
+	defer zerolog.SetGlobalLevel(level)
+
+	fmt.Printf("Simulating %d chats sending %d messages each at %g messages per second with %s model latency\n",
+		options.chats, options.messages, options.rate, options.latency)
+
+	var (
+		mu        sync.Mutex
+		latencies []time.Duration
+		updateID  atomic.Int64
+		wg        sync.WaitGroup
+	)
+	interval := time.Duration(float64(time.Second) / options.rate)
+	start := time.Now()
+	for i := range options.chats {
+		chatID := int64(loadtestChatIDBase + i)
+		chat := &telebot.Chat{ID: chatID, Type: telebot.ChatPrivate, FirstName: "Load", Username: "load" + strconv.Itoa(i)}
+		user := &telebot.User{ID: chatID, FirstName: "Load", Username: chat.Username}
+		if err = t.dm.TrustChat(chatID, chat.Username); err != nil {
+			return fmt.Errorf("failed to trust simulated chat: %w", err)
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+
+			// Send messages at a fixed rate regardless of how long replies take, so that queues can build up
+			var chatWG sync.WaitGroup
+			for j := range options.messages {
+				if j > 0 {
+					<-ticker.C
+				}
+				chatWG.Add(1)
+				go func() {
+					defer chatWG.Done()
+					sent := time.Now()
+					t.bot.ProcessUpdate(telebot.Update{
+						ID: int(updateID.Add(1)),
+						Message: &telebot.Message{
+							ID:       telegram.nextMessageID(),
+							Sender:   user,
+							Chat:     chat,
+							Text:     fmt.Sprintf("Load test message %d", j+1),
+							Unixtime: sent.Unix(),
+						},
+					})
+					mu.Lock()
+					latencies = append(latencies, time.Since(sent))
+					mu.Unlock()
+				}()
+			}
+			chatWG.Wait()
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	printLoadtestReport(elapsed, latencies, telegram, t.metrics)
+	if telegram.replies.Load() == 0 {
+		return errors.New("the bot did not reply to any message")
+	}
+	return nil
+}
+
+// printLoadtestReport prints the results of a load test.
+func printLoadtestReport(
+	elapsed time.Duration,
+	latencies []time.Duration,
+	telegram *loadtestTelegram,
+	registry *metrics.Registry,
+) {
+	slices.Sort(latencies)
+	seconds := elapsed.Seconds()
+	replies := telegram.replies.Load()
+
+	fmt.Printf("\nMessages: %d in %s\n", len(latencies), elapsed.Round(time.Millisecond))
+	fmt.Printf("Replies: %d (%d rejected as busy)\n", replies, telegram.busy.Load())
+	fmt.Printf("Throughput: %.2f messages/s, %.2f replies/s\n", float64(len(latencies))/seconds, float64(replies)/seconds)
+	fmt.Printf("End-to-end latency: p50 %s, p95 %s, p99 %s, max %s\n",
+		percentile(latencies, 0.50), percentile(latencies, 0.95), percentile(latencies, 0.99), percentile(latencies, 1))
+
+	queueWait := registry.QueueWait()
+	fmt.Printf("Queue wait: %d waits, mean %s, max %s\n",
+		queueWait.Count, queueWait.Mean().Round(time.Microsecond), queueWait.Max.Round(time.Microsecond))
+	queries := registry.DatabaseQueries()
+	fmt.Printf("Database: %d statements, mean %s, max %s\n",
+		queries.Count, queries.Mean().Round(time.Microsecond), queries.Max.Round(time.Microsecond))
+}
+
+// percentile returns the duration below which the given fraction of the sorted durations fall.
+func percentile(sorted []time.Duration, fraction float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	index := max(int(math.Ceil(fraction*float64(len(sorted))))-1, 0)
+	return sorted[index].Round(time.Millisecond)
+}
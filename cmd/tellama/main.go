@@ -4,7 +4,9 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"time"
 
+	"github.com/k4yt3x/tellama/internal/backup"
 	"github.com/k4yt3x/tellama/internal/config"
 
 	"github.com/rs/zerolog"
@@ -27,24 +29,18 @@ func runBot(cmd *cobra.Command, _ []string) {
 	}
 
 	// Initialize Tellama
-	tellama, err := NewTellama(
-		config.Telegram.BotToken,
-		config.Database.Path,
-		config.Database.HistoryFetchLimit,
-		config.Telegram.Timeout,
-		config.GenerativeAI.Timeout,
-		config.Telegram.AllowUntrustedChat,
-		config.GenerativeAI.Provider,
-		config.GenerativeAI.Mode,
-		config.GenerativeAI.Config,
-		config.GenerativeAI.Template,
-		config.GenerativeAI.AllowConcurrent,
-		config.ResponseMessages,
-	)
+	tellama, err := NewTellama(config)
 	if err != nil {
 		log.Fatal().Err(err).Msg("Failed to initialize Tellama")
 	}
 
+	// Reload the configuration at runtime
+	watch, err := cmd.Flags().GetBool("watch")
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse the watch flag")
+	}
+	tellama.watchConfig(configPath, watch)
+
 	// Run Tellama
 	tellama.Run()
 }
@@ -72,6 +68,70 @@ func main() {
 
 	// Add flags to the root command
 	cmd.PersistentFlags().StringP("config", "c", "", "Path to Tellama config file")
+	cmd.PersistentFlags().BoolP("watch", "w", false, "Reload the config file when it changes")
+
+	// Add the command that validates prompts against the configured model
+	cmd.AddCommand(&cobra.Command{
+		Use:   "test-prompts <suite.yaml>",
+		Short: "Run canary prompts against the configured model and report the checks that fail",
+		Args:  cobra.ExactArgs(1),
+		Run:   runTestPrompts,
+	})
+
+	// Add the command that simulates a chat with the bot in the terminal
+	replCmd := &cobra.Command{
+		Use:     "repl --chat-id <id>",
+		Aliases: []string{"chat"},
+		Short:   "Chat with the bot in the terminal using the configured database and provider",
+		Args:    cobra.NoArgs,
+		Run:     runREPL,
+	}
+	replCmd.Flags().Int64("chat-id", 0, "ID of the simulated chat; negative IDs simulate a group")
+	replCmd.Flags().Int64("user-id", 0, "ID of the simulated user (defaults to the chat ID in private chats)")
+	replCmd.Flags().String("first-name", "Tester", "First name of the simulated user")
+	replCmd.Flags().String("username", "tester", "Username of the simulated user")
+	_ = replCmd.MarkFlagRequired("chat-id")
+	cmd.AddCommand(replCmd)
+
+	// Add the command that measures the bot under simulated load
+	loadtestCmd := &cobra.Command{
+		Use:   "loadtest [--chats <n>] [--messages <n>] [--rate <n>]",
+		Short: "Simulate chats sending messages through the bot against a mock provider and report its performance",
+		Args:  cobra.NoArgs,
+		Run:   runLoadtest,
+	}
+	loadtestCmd.Flags().Int("chats", 10, "Number of simulated chats")
+	loadtestCmd.Flags().Int("messages", 20, "Number of messages sent by each chat")
+	loadtestCmd.Flags().Float64("rate", 1, "Messages sent per second by each chat")
+	loadtestCmd.Flags().Duration("latency", 500*time.Millisecond, "Time the mock provider takes to respond")
+	loadtestCmd.Flags().String("database", "", "Path of the database to use (defaults to a temporary database)")
+	cmd.AddCommand(loadtestCmd)
+
+	// Add the command that manages trusted users
+	cmd.AddCommand(newTrustedUsersCommand())
+
+	// Add the command that manages the database schema
+	cmd.AddCommand(newMigrateCommand())
+
+	// Add the commands that back up and restore chats
+	exportCmd := &cobra.Command{
+		Use:   "export [--chat <id>] [--format jsonl]",
+		Short: "Export the messages, chat overrides, and trust entries of one or all chats",
+		Args:  cobra.NoArgs,
+		Run:   runExport,
+	}
+	exportCmd.Flags().Int64("chat", 0, "ID of the chat to export; exports all chats if not set")
+	exportCmd.Flags().String("format", backup.FormatJSONL, "Export format; only jsonl is supported")
+	exportCmd.Flags().StringP("output", "o", "", "Path of the export file (defaults to standard output)")
+	exportCmd.Flags().Bool(
+		"include-plaintext-keys", false, "Decrypt the API keys of chat overrides instead of exporting them encrypted",
+	)
+	cmd.AddCommand(exportCmd, &cobra.Command{
+		Use:   "import [file]",
+		Short: "Import an export into the database, skipping rows that already exist",
+		Args:  cobra.MaximumNArgs(1),
+		Run:   runImport,
+	})
 
 	// Execute the root command
 	err := cmd.Execute()
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/telebot.v4"
+	"gorm.io/gorm"
+)
+
+// appendChatMemories appends the memory notes of a chat to its system prompt.
+func (t *Tellama) appendChatMemories(chatID int64, systemPrompt string) (string, error) {
+	memories, err := t.dm.GetChatMemories(chatID)
+	if err != nil {
+		return "", err
+	}
+	if len(memories) == 0 {
+		return systemPrompt, nil
+	}
+
+	var prompt strings.Builder
+	prompt.WriteString(systemPrompt)
+	prompt.WriteString("\n\n# Begin Chat Memory\n\n")
+	prompt.WriteString("The following facts about this chat must always be taken into account:\n")
+	for _, memory := range memories {
+		prompt.WriteString("\n- " + memory.Content)
+	}
+	prompt.WriteString("\n\n# End Chat Memory")
+	return prompt.String(), nil
+}
+
+func (t *Tellama) remember(ctx telebot.Context) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil {
+		return nil
+	}
+
+	if !t.authorize(chat, msg.Sender, msg, permissionChatAdmin) {
+		return ctx.Reply("You do not have permission to use this command.")
+	}
+
+	fact := strings.TrimSpace(msg.Payload)
+	if fact == "" {
+		return ctx.Reply("Please provide a fact to remember.")
+	}
+
+	memoryID, err := t.dm.AddChatMemory(chat.ID, msg.Sender.ID, fact)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to add chat memory")
+		return ctx.Reply("Failed to remember the fact. Please check logs for details.")
+	}
+
+	log.Info().
+		Int64("chat_id", chat.ID).
+		Int64("user_id", msg.Sender.ID).
+		Uint("memory_id", memoryID).
+		Msg("Chat memory added")
+
+	return ctx.Reply(tr(ctx, "Remembered as note #%d.", memoryID))
+}
+
+func (t *Tellama) forgetNote(ctx telebot.Context) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil {
+		return nil
+	}
+
+	if !t.authorize(chat, msg.Sender, msg, permissionChatAdmin) {
+		return ctx.Reply("You do not have permission to use this command.")
+	}
+
+	memoryID, err := strconv.ParseUint(strings.TrimPrefix(strings.TrimSpace(msg.Payload), "#"), 10, 0)
+	if err != nil {
+		return ctx.Reply("Please provide the ID of the note to forget. Use /notes to list all notes.")
+	}
+
+	err = t.dm.DeleteChatMemory(chat.ID, uint(memoryID))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ctx.Reply("No note with this ID exists in this chat.")
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to delete chat memory")
+		return ctx.Reply("Failed to forget the note. Please check logs for details.")
+	}
+
+	log.Info().
+		Int64("chat_id", chat.ID).
+		Int64("user_id", msg.Sender.ID).
+		Uint64("memory_id", memoryID).
+		Msg("Chat memory deleted")
+
+	return ctx.Reply("Note forgotten.")
+}
+
+func (t *Tellama) listNotes(ctx telebot.Context) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil {
+		return nil
+	}
+
+	if !t.authorize(chat, msg.Sender, msg, permissionTrusted) {
+		return ctx.Reply("You do not have permission to use this command.")
+	}
+
+	memories, err := t.dm.GetChatMemories(chat.ID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get chat memories")
+		return ctx.Reply("Failed to get notes. Please check logs for details.")
+	}
+
+	if len(memories) == 0 {
+		return ctx.Reply("No notes are stored for this chat.")
+	}
+
+	var reply strings.Builder
+	reply.WriteString("Chat notes:\n")
+	for _, memory := range memories {
+		reply.WriteString(fmt.Sprintf("\n#%d: %s", memory.ID, memory.Content))
+	}
+	return ctx.Reply(reply.String())
+}
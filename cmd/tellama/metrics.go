@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/telebot.v4"
+)
+
+// metricsReadTimeout bounds the time spent reading a request to the metrics endpoint.
+const metricsReadTimeout = 10 * time.Second
+
+// handle registers a handler for an endpoint, records the metrics of its invocations,
+// and deletes the invoking messages of the commands configured to be deleted.
+func (t *Tellama) handle(endpoint string, handler telebot.HandlerFunc) {
+	// Event endpoints such as telebot.OnText start with a bell character and callback endpoints with a form feed
+	command := strings.TrimLeft(endpoint, "\a\f")
+
+	t.bot.Handle(endpoint, func(ctx telebot.Context) error {
+		chatType := "unknown"
+		if chat := ctx.Chat(); chat != nil {
+			chatType = string(chat.Type)
+		}
+
+		// Reply to commands and button presses in the language of the chat
+		if strings.HasPrefix(endpoint, "/") || strings.HasPrefix(endpoint, "\f") {
+			ctx = t.localize(ctx)
+		}
+
+		// Store the buffered messages first so that commands, buttons, and edits see the latest history
+		if strings.HasPrefix(endpoint, "/") || strings.HasPrefix(endpoint, "\f") || endpoint == telebot.OnEdited {
+			t.flushMessages()
+		}
+
+		start := time.Now()
+		err := handler(ctx)
+		t.metrics.ObserveCommand(command, chatType, time.Since(start), err != nil)
+
+		// Remove the command message from the chat history if configured
+		t.scheduleCommandDeletion(ctx, command)
+		return err
+	})
+}
+
+// serveMetrics serves the metrics to Prometheus scrapers.
+func (t *Tellama) serveMetrics() {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", t.metrics)
+	server := &http.Server{
+		Addr:              t.metricsConfig.ListenAddress,
+		Handler:           mux,
+		ReadHeaderTimeout: metricsReadTimeout,
+	}
+
+	log.Info().Str("listen_address", server.Addr).Msg("Serving metrics")
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Error().Err(err).Msg("Failed to serve metrics")
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/k4yt3x/tellama/internal/config"
+	"github.com/k4yt3x/tellama/internal/database"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// newMigrateCommand creates the command that applies and rolls back schema migrations.
+func newMigrateCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Manage the schema migrations of the database",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "up",
+		Short: "Apply the pending migrations",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, _ []string) {
+			dm := openMigrationDatabase(cmd)
+			defer dm.Close()
+
+			applied, err := dm.MigrateUp()
+			if err != nil {
+				log.Fatal().Err(err).Msg("Failed to apply migrations")
+			}
+			log.Info().Int("applied", applied).Msg("Database is up to date")
+		},
+	}, &cobra.Command{
+		Use:   "down",
+		Short: "Roll back the latest applied migration",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, _ []string) {
+			dm := openMigrationDatabase(cmd)
+			defer dm.Close()
+
+			migration, err := dm.MigrateDown()
+			if err != nil {
+				log.Fatal().Err(err).Msg("Failed to roll back migration")
+			}
+			log.Info().
+				Int("version", migration.Version).
+				Str("description", migration.Description).
+				Msg("Rolled back migration")
+		},
+	}, &cobra.Command{
+		Use:   "status",
+		Short: "List the migrations and when they were applied",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, _ []string) {
+			dm := openMigrationDatabase(cmd)
+			defer dm.Close()
+
+			statuses, err := dm.MigrationStatus()
+			if err != nil {
+				log.Fatal().Err(err).Msg("Failed to get migration status")
+			}
+			for _, status := range statuses {
+				applied := "pending"
+				if status.Applied() {
+					applied = status.AppliedAt.Local().Format(time.DateTime)
+				}
+				fmt.Printf("%d\t%s\t%s\n", status.Version, applied, status.Description)
+			}
+		},
+	})
+	return cmd
+}
+
+// openMigrationDatabase opens the configured database without applying migrations.
+func openMigrationDatabase(cmd *cobra.Command) *database.Manager {
+	configPath, err := cmd.Flags().GetString("config")
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse the config flag")
+	}
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load configuration")
+	}
+	dm, err := openDatabaseManager(cfg, false)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to open database")
+	}
+	return dm
+}
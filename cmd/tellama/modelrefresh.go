@@ -0,0 +1,134 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/k4yt3x/tellama/internal/database"
+	"github.com/k4yt3x/tellama/internal/genai"
+
+	"github.com/rs/zerolog/log"
+)
+
+// shortDigestLength is the number of digest characters shown in notifications, matching `ollama list`.
+const shortDigestLength = 12
+
+// nextMaintenance returns the next time after now at the given offset from midnight UTC.
+func nextMaintenance(now time.Time, offset time.Duration) time.Time {
+	now = now.UTC()
+	next := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).Add(offset)
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// shortDigest shortens a digest for display.
+func shortDigest(digest string) string {
+	if digest == "" {
+		return "none"
+	}
+	return digest[:min(len(digest), shortDigestLength)]
+}
+
+// ollamaClient creates an Ollama client using the global configuration.
+func (t *Tellama) ollamaClient() (*genai.Ollama, error) {
+	genaiConfig, err := t.applyChatOverride(database.ChatOverride{})
+	if err != nil {
+		return nil, err
+	}
+
+	genaiClient, err := genai.New(t.genaiProvider, genaiConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	ollama, ok := genaiClient.(*genai.Ollama)
+	if !ok {
+		return nil, errors.New("model refresh requires the Ollama provider")
+	}
+	return ollama, nil
+}
+
+// checkModelRefresh compares the local model against the registry, pulls the update if
+// configured to, and notifies the admins of version changes. It returns the local digest
+// and the registry digest the admins were last notified about.
+func (t *Tellama) checkModelRefresh(lastDigest string, notifiedDigest string) (string, string, error) {
+	ollama, err := t.ollamaClient()
+	if err != nil {
+		return lastDigest, notifiedDigest, err
+	}
+
+	remoteDigest, err := genai.RegistryDigest(t.modelRefresh.RegistryURL, ollama.Model)
+	if err != nil {
+		return lastDigest, notifiedDigest, err
+	}
+
+	// A missing model is pulled like an outdated one
+	localDigest, err := ollama.LocalDigest()
+	if err != nil {
+		log.Warn().Err(err).Str("model", ollama.Model).Msg("Failed to get local model digest")
+		localDigest = ""
+	}
+	log.Debug().
+		Str("model", ollama.Model).
+		Str("local_digest", localDigest).
+		Str("remote_digest", remoteDigest).
+		Msg("Checked model for updates")
+
+	if localDigest != remoteDigest && t.modelRefresh.AutoPull {
+		log.Info().Str("model", ollama.Model).Msg("Pulling updated model")
+		if err = ollama.Pull(); err != nil {
+			t.notifyAdmins(fmt.Sprintf("Failed to pull the update of model %s: %v", ollama.Model, err))
+			return lastDigest, notifiedDigest, err
+		}
+		previousDigest := localDigest
+		localDigest, err = ollama.LocalDigest()
+		if err != nil {
+			return lastDigest, notifiedDigest, err
+		}
+		log.Info().
+			Str("model", ollama.Model).
+			Str("previous_digest", previousDigest).
+			Str("digest", localDigest).
+			Msg("Updated model")
+		t.notifyAdmins(fmt.Sprintf(
+			"Model %s was updated from %s to %s.",
+			ollama.Model, shortDigest(previousDigest), shortDigest(localDigest),
+		))
+		return localDigest, notifiedDigest, nil
+	}
+
+	switch {
+	case localDigest != remoteDigest && remoteDigest != notifiedDigest:
+		t.notifyAdmins(fmt.Sprintf(
+			"An update is available for model %s: %s (installed: %s).",
+			ollama.Model, shortDigest(remoteDigest), shortDigest(localDigest),
+		))
+		notifiedDigest = remoteDigest
+	case lastDigest != "" && localDigest != lastDigest:
+		// The model was changed on the server since the last check
+		t.notifyAdmins(fmt.Sprintf(
+			"Model %s changed from %s to %s.",
+			ollama.Model, shortDigest(lastDigest), shortDigest(localDigest),
+		))
+	}
+	return localDigest, notifiedDigest, nil
+}
+
+// runModelRefresh checks the configured model for updates every day at the maintenance time.
+func (t *Tellama) runModelRefresh() {
+	var lastDigest, notifiedDigest string
+	for {
+		next := nextMaintenance(time.Now(), t.modelRefresh.MaintenanceTime)
+		log.Debug().Time("next_check", next).Msg("Scheduled model refresh check")
+		time.Sleep(time.Until(next))
+
+		var err error
+		lastDigest, notifiedDigest, err = t.checkModelRefresh(lastDigest, notifiedDigest)
+		if err != nil {
+			log.Error().Err(err).Msg("Model refresh check failed")
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/k4yt3x/tellama/internal/genai"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/telebot.v4"
+)
+
+// listModels returns the models available from the provider configured for the chat.
+func (t *Tellama) listModels(chatID int64) ([]string, error) {
+	chatOverride, err := t.dm.GetChatOverride(chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	genaiConfig, err := t.applyChatOverride(chatOverride)
+	if err != nil {
+		return nil, err
+	}
+
+	genaiClient, err := genai.New(t.genaiProvider, genaiConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	lister, ok := genaiClient.(genai.ModelLister)
+	if !ok {
+		return nil, errors.New("provider does not support listing models")
+	}
+
+	models, err := lister.ListModels()
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(models)
+	return models, nil
+}
+
+func (t *Tellama) setModel(ctx telebot.Context) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil {
+		return nil
+	}
+
+	if !t.authorize(chat, msg.Sender, msg, permissionChatAdmin) {
+		return ctx.Reply("You do not have permission to use this command.")
+	}
+
+	// An empty model name resets the chat to the default model
+	model := strings.TrimSpace(msg.Payload)
+	if model != "" {
+		models, err := t.listModels(chat.ID)
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed to list models, skipping model validation")
+		} else if !slices.Contains(models, model) && !slices.Contains(models, model+":latest") {
+			return ctx.Reply(tr(ctx, "Model %q is not available. Use /listmodels to see all models.", model))
+		}
+	}
+
+	if err := t.dm.SetChatModel(chat.ID, chat.Title, model); err != nil {
+		log.Error().Err(err).Msg("Failed to set model")
+		return ctx.Reply("Failed to set model. Please check logs for details.")
+	}
+
+	log.Info().
+		Int64("chat_id", chat.ID).
+		Int64("user_id", msg.Sender.ID).
+		Str("model", model).
+		Msg("Model set")
+
+	if model == "" {
+		return ctx.Reply("Model reset to the default.")
+	}
+	return ctx.Reply("Model set successfully.")
+}
+
+func (t *Tellama) listModelsCommand(ctx telebot.Context) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil {
+		return nil
+	}
+
+	if !t.authorize(chat, msg.Sender, msg, permissionTrusted) {
+		return ctx.Reply("You do not have permission to use this command.")
+	}
+
+	models, err := t.listModels(chat.ID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list models")
+		return ctx.Reply("Failed to list models. Please check logs for details.")
+	}
+
+	if len(models) == 0 {
+		return ctx.Reply("No models are available.")
+	}
+
+	var reply strings.Builder
+	reply.WriteString("Available models:\n")
+	for _, model := range models {
+		reply.WriteString("\n- " + model)
+	}
+	return ctx.Reply(reply.String())
+}
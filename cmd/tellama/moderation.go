@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/telebot.v4"
+)
+
+// isModerated reports whether content moderation flags a message or response of the chat.
+// Texts are let through if moderation is disabled or the check fails.
+func (t *Tellama) isModerated(chat *telebot.Chat, text string, kind string) bool {
+	if t.moderator == nil {
+		return false
+	}
+
+	result, err := t.moderator.Check(context.Background(), text)
+	if err != nil {
+		log.Error().Err(err).Int64("chat_id", chat.ID).Str("kind", kind).Msg("Failed to moderate content")
+		return false
+	}
+	if !result.Flagged() {
+		return false
+	}
+
+	log.Warn().
+		Int64("chat_id", chat.ID).
+		Str("kind", kind).
+		Strs("categories", result.Categories).
+		Msg("Content flagged by moderation")
+	return true
+}
+
+// replyModerated tells the user that their message or its response was flagged, if a notice is configured.
+func (t *Tellama) replyModerated(ctx telebot.Context) error {
+	notice := t.settings().responseMessages.Moderated
+	if notice == "" {
+		return nil
+	}
+	return ctx.Reply(notice)
+}
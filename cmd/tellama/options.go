@@ -0,0 +1,298 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/k4yt3x/tellama/internal/genai"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/telebot.v4"
+)
+
+// optionSpec describes the values accepted for a provider option.
+type optionSpec struct {
+	integer bool
+	min     float64
+	max     float64
+}
+
+// providerOptions returns the options of a provider that chat admins may set, keyed by their name.
+func providerOptions(provider genai.Provider) map[string]optionSpec {
+	switch provider {
+	case genai.ProviderOllama:
+		return map[string]optionSpec{
+			"temperature":       {min: 0, max: 2},
+			"top_p":             {min: 0, max: 1},
+			"top_k":             {integer: true, min: 0, max: 1000},
+			"min_p":             {min: 0, max: 1},
+			"repeat_penalty":    {min: 0, max: 2},
+			"presence_penalty":  {min: -2, max: 2},
+			"frequency_penalty": {min: -2, max: 2},
+			"num_ctx":           {integer: true, min: 1, max: 1 << 20},
+			"num_predict":       {integer: true, min: -2, max: 1 << 20},
+			"seed":              {integer: true, min: math.MinInt32, max: math.MaxInt32},
+		}
+	case genai.ProviderOpenAI:
+		return map[string]optionSpec{
+			"temperature":       {min: 0, max: 2},
+			"top_p":             {min: 0, max: 1},
+			"presence_penalty":  {min: -2, max: 2},
+			"frequency_penalty": {min: -2, max: 2},
+			"max_tokens":        {integer: true, min: 1, max: 1 << 20},
+		}
+	case genai.ProviderBedrock:
+		return map[string]optionSpec{
+			"temperature": {min: 0, max: 1},
+			"top_p":       {min: 0, max: 1},
+			"max_tokens":  {integer: true, min: 1, max: 1 << 20},
+		}
+	default:
+		return nil
+	}
+}
+
+// parse returns the value of the option given as text, or false if it is not accepted.
+func (s optionSpec) parse(text string) (any, bool) {
+	if s.integer {
+		value, err := strconv.ParseInt(text, 10, 64)
+		if err != nil || float64(value) < s.min || float64(value) > s.max {
+			return nil, false
+		}
+		return value, true
+	}
+
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil || math.IsNaN(value) || value < s.min || value > s.max {
+		return nil, false
+	}
+	return value, true
+}
+
+// validateOptions checks that JSON options only contain options of the provider that chat admins may set,
+// with values they could set with /setoption.
+func validateOptions(provider genai.Provider, options string) error {
+	if options == "" {
+		return nil
+	}
+
+	var parsed map[string]any
+	decoder := json.NewDecoder(strings.NewReader(options))
+	decoder.UseNumber()
+	if err := decoder.Decode(&parsed); err != nil {
+		return errors.New("the options must be a JSON object of numbers")
+	}
+
+	specs := providerOptions(provider)
+	names := slices.Sorted(maps.Keys(parsed))
+	for _, name := range names {
+		spec, ok := specs[name]
+		if !ok {
+			return fmt.Errorf("the %s provider does not support option %s", provider, name)
+		}
+		number, isNumber := parsed[name].(json.Number)
+		if !isNumber {
+			return fmt.Errorf("the value of option %s must be a number", name)
+		}
+		if _, ok = spec.parse(number.String()); !ok {
+			return fmt.Errorf("the value of option %s must be between %g and %g", name, spec.min, spec.max)
+		}
+	}
+	return nil
+}
+
+// openaiOptions holds the chat override options that are applied to the OpenAI provider.
+// Options that only apply to other providers are ignored.
+type openaiOptions struct {
+	Temperature      *float64 `json:"temperature"`
+	TopP             *float64 `json:"top_p"`
+	FrequencyPenalty *float64 `json:"frequency_penalty"`
+	PresencePenalty  *float64 `json:"presence_penalty"`
+	MaxTokens        *int64   `json:"max_tokens"`
+}
+
+// applyOpenAIOptions applies the JSON options of a chat override to the OpenAI configuration.
+func applyOpenAIOptions(openaiConfig *genai.OpenAIConfig, options string) error {
+	var parsed openaiOptions
+	if err := json.Unmarshal([]byte(options), &parsed); err != nil {
+		return err
+	}
+
+	if parsed.Temperature != nil {
+		openaiConfig.Temperature = parsed.Temperature
+	}
+	if parsed.TopP != nil {
+		openaiConfig.TopP = parsed.TopP
+	}
+	if parsed.FrequencyPenalty != nil {
+		openaiConfig.FrequencyPenalty = parsed.FrequencyPenalty
+	}
+	if parsed.PresencePenalty != nil {
+		openaiConfig.PresencePenalty = parsed.PresencePenalty
+	}
+	if parsed.MaxTokens != nil {
+		openaiConfig.MaxTokens = *parsed.MaxTokens
+	}
+	return nil
+}
+
+// applyBedrockOptions applies the JSON options of a chat override to the Bedrock configuration.
+// The penalties are ignored since the Converse API does not accept them.
+func applyBedrockOptions(bedrockConfig *genai.BedrockConfig, options string) error {
+	var parsed openaiOptions
+	if err := json.Unmarshal([]byte(options), &parsed); err != nil {
+		return err
+	}
+
+	if parsed.Temperature != nil {
+		bedrockConfig.Temperature = parsed.Temperature
+	}
+	if parsed.TopP != nil {
+		bedrockConfig.TopP = parsed.TopP
+	}
+	if parsed.MaxTokens != nil {
+		bedrockConfig.MaxTokens = *parsed.MaxTokens
+	}
+	return nil
+}
+
+// withOption returns the JSON options with the option set to the value, or removed if the value is nil.
+// Empty options are returned as an empty string so that the configured options are used.
+func withOption(options string, key string, value any) (string, error) {
+	parsed := map[string]any{}
+	if options != "" {
+		if err := json.Unmarshal([]byte(options), &parsed); err != nil {
+			return "", err
+		}
+	}
+
+	if value == nil {
+		delete(parsed, key)
+	} else {
+		parsed[key] = value
+	}
+	if len(parsed) == 0 {
+		return "", nil
+	}
+
+	data, err := json.Marshal(parsed)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// optionValue returns the value of an option in the JSON options of a chat override.
+func optionValue(options string, key string) (any, bool) {
+	if options == "" {
+		return nil, false
+	}
+	var parsed map[string]any
+	if err := json.Unmarshal([]byte(options), &parsed); err != nil {
+		return nil, false
+	}
+	value, ok := parsed[key]
+	return value, ok
+}
+
+func (t *Tellama) setOption(ctx telebot.Context) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil {
+		return nil
+	}
+
+	if !t.authorize(chat, msg.Sender, msg, permissionChatAdmin) {
+		return ctx.Reply("You do not have permission to use this command.")
+	}
+
+	options := providerOptions(t.genaiProvider)
+	if len(options) == 0 {
+		return ctx.Reply(tr(ctx, "The %s provider does not support options.", t.genaiProvider))
+	}
+	names := make([]string, 0, len(options))
+	for name := range options {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	args := strings.Fields(msg.Payload)
+	if len(args) != 2 {
+		return ctx.Reply(tr(ctx,
+			"Usage: /setoption <option> <value>, or /setoption <option> default to use the default. "+
+				"Available options: %s.",
+			strings.Join(names, ", "),
+		))
+	}
+	name := strings.ToLower(args[0])
+	spec, ok := options[name]
+	if !ok {
+		return ctx.Reply(tr(ctx, "Unknown option %s. Available options: %s.", name, strings.Join(names, ", ")))
+	}
+
+	// A value of "default" removes the option so that the configured value is used
+	var value any
+	if !strings.EqualFold(args[1], "default") {
+		if value, ok = spec.parse(args[1]); !ok {
+			if spec.integer {
+				return ctx.Reply(tr(ctx, "The value of %s must be a whole number between %g and %g.", name, spec.min, spec.max))
+			}
+			return ctx.Reply(tr(ctx, "The value of %s must be a number between %g and %g.", name, spec.min, spec.max))
+		}
+	}
+
+	chatOverride, err := t.dm.GetChatOverride(chat.ID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get chat override")
+		return ctx.Reply("Failed to set option. Please check logs for details.")
+	}
+	updated, err := withOption(chatOverride.Options, name, value)
+	if err == nil {
+		err = t.dm.SetChatOptions(chat.ID, chat.Title, updated)
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to set option")
+		return ctx.Reply("Failed to set option. Please check logs for details.")
+	}
+
+	log.Info().
+		Int64("chat_id", chat.ID).
+		Int64("user_id", msg.Sender.ID).
+		Str("option", name).
+		Interface("value", value).
+		Msg("Option set")
+
+	if value == nil {
+		return ctx.Reply(tr(ctx, "Option %s reset to the default.", name))
+	}
+	return ctx.Reply(tr(ctx, "Option %s set to %v.", name, value))
+}
+
+func (t *Tellama) resetOptions(ctx telebot.Context) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil {
+		return nil
+	}
+
+	if !t.authorize(chat, msg.Sender, msg, permissionChatAdmin) {
+		return ctx.Reply("You do not have permission to use this command.")
+	}
+
+	if err := t.dm.SetChatOptions(chat.ID, chat.Title, ""); err != nil {
+		log.Error().Err(err).Msg("Failed to reset options")
+		return ctx.Reply("Failed to reset options. Please check logs for details.")
+	}
+
+	log.Info().
+		Int64("chat_id", chat.ID).
+		Int64("user_id", msg.Sender.ID).
+		Msg("Options reset")
+	return ctx.Reply("All options reset to the default.")
+}
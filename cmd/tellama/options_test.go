@@ -0,0 +1,372 @@
+package main //nolint:testpackage // Unit tests are in the same package
+
+import (
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/k4yt3x/tellama/internal/genai"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProviderOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider genai.Provider
+		expected []string
+	}{
+		{
+			name:     "Ollama",
+			provider: genai.ProviderOllama,
+			expected: []string{
+				"frequency_penalty", "min_p", "num_ctx", "num_predict", "presence_penalty",
+				"repeat_penalty", "seed", "temperature", "top_k", "top_p",
+			},
+		},
+		{
+			name:     "OpenAI",
+			provider: genai.ProviderOpenAI,
+			expected: []string{"frequency_penalty", "max_tokens", "presence_penalty", "temperature", "top_p"},
+		},
+		{
+			name:     "Bedrock",
+			provider: genai.ProviderBedrock,
+			expected: []string{"max_tokens", "temperature", "top_p"},
+		},
+		{
+			name:     "Mock",
+			provider: genai.ProviderMock,
+			expected: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			options := providerOptions(tt.provider)
+
+			// Assert
+			names := []string{}
+			for name := range options {
+				names = append(names, name)
+			}
+			slices.Sort(names)
+			assert.Equal(t, tt.expected, names)
+		})
+	}
+}
+
+func TestOptionSpecParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider genai.Provider
+		option   string
+		text     string
+		expected any
+		ok       bool
+	}{
+		{
+			name:     "Ollama temperature minimum",
+			provider: genai.ProviderOllama,
+			option:   "temperature",
+			text:     "0",
+			expected: 0.0,
+			ok:       true,
+		},
+		{
+			name:     "Ollama temperature maximum",
+			provider: genai.ProviderOllama,
+			option:   "temperature",
+			text:     "2",
+			expected: 2.0,
+			ok:       true,
+		},
+		{
+			name:     "Ollama temperature above maximum",
+			provider: genai.ProviderOllama,
+			option:   "temperature",
+			text:     "2.01",
+		},
+		{
+			name:     "Ollama top_k",
+			provider: genai.ProviderOllama,
+			option:   "top_k",
+			text:     "40",
+			expected: int64(40),
+			ok:       true,
+		},
+		{
+			name:     "Ollama top_k fraction",
+			provider: genai.ProviderOllama,
+			option:   "top_k",
+			text:     "40.5",
+		},
+		{
+			name:     "Ollama num_predict minimum",
+			provider: genai.ProviderOllama,
+			option:   "num_predict",
+			text:     "-2",
+			expected: int64(-2),
+			ok:       true,
+		},
+		{
+			name:     "Ollama num_predict below minimum",
+			provider: genai.ProviderOllama,
+			option:   "num_predict",
+			text:     "-3",
+		},
+		{
+			name:     "Ollama seed minimum",
+			provider: genai.ProviderOllama,
+			option:   "seed",
+			text:     "-2147483648",
+			expected: int64(math.MinInt32),
+			ok:       true,
+		},
+		{
+			name:     "Ollama seed above maximum",
+			provider: genai.ProviderOllama,
+			option:   "seed",
+			text:     "2147483648",
+		},
+		{
+			name:     "OpenAI presence penalty minimum",
+			provider: genai.ProviderOpenAI,
+			option:   "presence_penalty",
+			text:     "-2",
+			expected: -2.0,
+			ok:       true,
+		},
+		{
+			name:     "OpenAI presence penalty below minimum",
+			provider: genai.ProviderOpenAI,
+			option:   "presence_penalty",
+			text:     "-2.5",
+		},
+		{
+			name:     "OpenAI max tokens maximum",
+			provider: genai.ProviderOpenAI,
+			option:   "max_tokens",
+			text:     "1048576",
+			expected: int64(1 << 20),
+			ok:       true,
+		},
+		{
+			name:     "OpenAI max tokens zero",
+			provider: genai.ProviderOpenAI,
+			option:   "max_tokens",
+			text:     "0",
+		},
+		{
+			name:     "Bedrock temperature maximum",
+			provider: genai.ProviderBedrock,
+			option:   "temperature",
+			text:     "1",
+			expected: 1.0,
+			ok:       true,
+		},
+		{
+			name:     "Bedrock temperature above maximum",
+			provider: genai.ProviderBedrock,
+			option:   "temperature",
+			text:     "1.5",
+		},
+		{
+			name:     "Bedrock top_p minimum",
+			provider: genai.ProviderBedrock,
+			option:   "top_p",
+			text:     "0",
+			expected: 0.0,
+			ok:       true,
+		},
+		{
+			name:     "Not a number",
+			provider: genai.ProviderOllama,
+			option:   "temperature",
+			text:     "warm",
+		},
+		{
+			name:     "NaN",
+			provider: genai.ProviderOllama,
+			option:   "temperature",
+			text:     "NaN",
+		},
+		{
+			name:     "Empty",
+			provider: genai.ProviderOpenAI,
+			option:   "max_tokens",
+			text:     "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			spec, exists := providerOptions(tt.provider)[tt.option]
+			require.True(t, exists)
+
+			// Act
+			value, ok := spec.parse(tt.text)
+
+			// Assert
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.expected, value)
+		})
+	}
+
+	t.Run("Options that are not allowed", func(t *testing.T) {
+		assert.NotContains(t, providerOptions(genai.ProviderOpenAI), "top_k")
+		assert.NotContains(t, providerOptions(genai.ProviderOpenAI), "num_ctx")
+		assert.NotContains(t, providerOptions(genai.ProviderBedrock), "presence_penalty")
+		assert.NotContains(t, providerOptions(genai.ProviderOllama), "max_tokens")
+		assert.Empty(t, providerOptions(genai.ProviderMock))
+	})
+}
+
+func TestValidateOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider genai.Provider
+		options  string
+		valid    bool
+	}{
+		{name: "No options", provider: genai.ProviderOllama, options: "", valid: true},
+		{
+			name:     "Allowed options",
+			provider: genai.ProviderOllama,
+			options:  `{"temperature":0.3,"top_k":40}`,
+			valid:    true,
+		},
+		{name: "Edges", provider: genai.ProviderOpenAI, options: `{"temperature":2,"max_tokens":1}`, valid: true},
+		{name: "Out of range", provider: genai.ProviderOllama, options: `{"temperature":50}`},
+		{name: "Not allowed", provider: genai.ProviderBedrock, options: `{"frequency_penalty":1}`},
+		{name: "No options for provider", provider: genai.ProviderMock, options: `{"temperature":0.3}`},
+		{name: "Fractional integer", provider: genai.ProviderOllama, options: `{"num_ctx":4096.5}`},
+		{name: "Not a number", provider: genai.ProviderOllama, options: `{"temperature":"0.3"}`},
+		{name: "Not an object", provider: genai.ProviderOllama, options: `0.3`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			err := validateOptions(tt.provider, tt.options)
+
+			// Assert
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestWithOption(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  string
+		key      string
+		value    any
+		expected string
+	}{
+		{name: "Set on empty options", options: "", key: "temperature", value: 0.5, expected: `{"temperature":0.5}`},
+		{name: "Set integer", options: "", key: "top_k", value: int64(40), expected: `{"top_k":40}`},
+		{
+			name:     "Replace",
+			options:  `{"temperature":0.5,"top_p":0.9}`,
+			key:      "temperature",
+			value:    1.0,
+			expected: `{"temperature":1,"top_p":0.9}`,
+		},
+		{name: "Remove", options: `{"temperature":0.5,"top_p":0.9}`, key: "temperature", expected: `{"top_p":0.9}`},
+		{name: "Remove the last option", options: `{"temperature":0.5}`, key: "temperature", expected: ""},
+		{name: "Remove from empty options", options: "", key: "temperature", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			options, err := withOption(tt.options, tt.key, tt.value)
+
+			// Assert
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, options)
+		})
+	}
+
+	t.Run("Invalid options", func(t *testing.T) {
+		// Act
+		_, err := withOption("{", "temperature", 0.5)
+
+		// Assert
+		assert.Error(t, err)
+	})
+}
+
+func TestApplyOpenAIOptions(t *testing.T) {
+	t.Run("All options", func(t *testing.T) {
+		// Arrange
+		openaiConfig := &genai.OpenAIConfig{MaxTokens: 100}
+
+		// Act
+		err := applyOpenAIOptions(
+			openaiConfig,
+			`{"temperature":0.7,"top_p":0.9,"frequency_penalty":-1,"presence_penalty":1.5,"max_tokens":512}`,
+		)
+
+		// Assert
+		require.NoError(t, err)
+		require.NotNil(t, openaiConfig.Temperature)
+		require.NotNil(t, openaiConfig.TopP)
+		require.NotNil(t, openaiConfig.FrequencyPenalty)
+		require.NotNil(t, openaiConfig.PresencePenalty)
+		assert.InDelta(t, 0.7, *openaiConfig.Temperature, 1e-9)
+		assert.InDelta(t, 0.9, *openaiConfig.TopP, 1e-9)
+		assert.InDelta(t, -1, *openaiConfig.FrequencyPenalty, 1e-9)
+		assert.InDelta(t, 1.5, *openaiConfig.PresencePenalty, 1e-9)
+		assert.Equal(t, int64(512), openaiConfig.MaxTokens)
+	})
+
+	t.Run("Unset options are kept", func(t *testing.T) {
+		// Arrange
+		temperature := 0.2
+		openaiConfig := &genai.OpenAIConfig{Temperature: &temperature, MaxTokens: 100}
+
+		// Act
+		err := applyOpenAIOptions(openaiConfig, `{"top_p":0.5}`)
+
+		// Assert
+		require.NoError(t, err)
+		require.NotNil(t, openaiConfig.Temperature)
+		assert.InDelta(t, 0.2, *openaiConfig.Temperature, 1e-9)
+		assert.Equal(t, int64(100), openaiConfig.MaxTokens)
+		assert.Nil(t, openaiConfig.FrequencyPenalty)
+	})
+
+	t.Run("Options of other providers are ignored", func(t *testing.T) {
+		// Arrange
+		openaiConfig := &genai.OpenAIConfig{}
+
+		// Act
+		err := applyOpenAIOptions(openaiConfig, `{"top_k":40,"num_ctx":4096}`)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, genai.OpenAIConfig{}, *openaiConfig)
+	})
+
+	t.Run("Invalid values", func(t *testing.T) {
+		// Arrange
+		openaiConfig := &genai.OpenAIConfig{}
+
+		// Act
+		err := applyOpenAIOptions(openaiConfig, `{"max_tokens":1.5}`)
+		notJSONErr := applyOpenAIOptions(openaiConfig, `not json`)
+
+		// Assert
+		assert.Error(t, err)
+		assert.Error(t, notJSONErr)
+	})
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"github.com/rs/zerolog/log"
+	"gopkg.in/telebot.v4"
+)
+
+// isUserOptedOut reports whether the messages of the user must be kept out of the history.
+// Messages are not stored if the preference cannot be read.
+func (t *Tellama) isUserOptedOut(userID int64) bool {
+	optedOut, err := t.dm.IsUserOptedOut(userID)
+	if err != nil {
+		log.Error().Err(err).Int64("user_id", userID).Msg("Failed to get user preference")
+		return true
+	}
+	return optedOut
+}
+
+func (t *Tellama) setOptedOut(ctx telebot.Context, optedOut bool) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil || msg.Sender == nil {
+		return nil
+	}
+
+	if err := t.dm.SetUserOptedOut(msg.Sender.ID, optedOut); err != nil {
+		log.Error().Err(err).Msg("Failed to set user preference")
+		return ctx.Reply("Failed to update your preference. Please check logs for details.")
+	}
+
+	log.Info().
+		Int64("chat_id", chat.ID).
+		Int64("user_id", msg.Sender.ID).
+		Bool("opted_out", optedOut).
+		Msg("History storage preference updated")
+
+	if optedOut {
+		return ctx.Reply(
+			"Your messages will no longer be stored. The bot still sees a message while replying to it, " +
+				"but will not remember it afterwards. Use /optin to undo this.",
+		)
+	}
+	return ctx.Reply("Your messages will be stored in the history again.")
+}
+
+func (t *Tellama) optOut(ctx telebot.Context) error {
+	return t.setOptedOut(ctx, true)
+}
+
+func (t *Tellama) optIn(ctx telebot.Context) error {
+	return t.setOptedOut(ctx, false)
+}
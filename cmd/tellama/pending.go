@@ -0,0 +1,143 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/k4yt3x/tellama/internal/database"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/telebot.v4"
+	"gorm.io/gorm"
+)
+
+// maxPendingAge is the age after which a pending generation is dropped instead of resumed,
+// since a late reply is unlikely to still be useful.
+const maxPendingAge = time.Hour
+
+// addPendingGeneration records that a response to the message is being generated.
+func (t *Tellama) addPendingGeneration(chat *telebot.Chat, message *telebot.Message) {
+	if err := t.dm.AddPendingGeneration(chat.ID, string(chat.Type), message.ID); err != nil {
+		log.Error().Err(err).Int64("chat_id", chat.ID).Msg("Failed to store pending generation")
+	}
+}
+
+// removePendingGeneration removes the record of a handled generation.
+func (t *Tellama) removePendingGeneration(chatID int64, messageID int) {
+	if err := t.dm.DeletePendingGeneration(chatID, messageID); err != nil {
+		log.Error().Err(err).Int64("chat_id", chatID).Msg("Failed to delete pending generation")
+	}
+}
+
+// resumePendingGenerations generates the responses that were interrupted by the last shutdown.
+func (t *Tellama) resumePendingGenerations() {
+	pending, err := t.dm.GetPendingGenerations()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get pending generations")
+		return
+	}
+	if len(pending) > 0 {
+		log.Info().Int("count", len(pending)).Msg("Resuming pending generations")
+	}
+
+	for _, generation := range pending {
+		if err = t.resumePendingGeneration(generation); err != nil {
+			log.Error().
+				Err(err).
+				Int64("chat_id", generation.ChatID).
+				Int("message_id", generation.TelegramMessageID).
+				Msg("Failed to resume pending generation")
+		}
+		t.removePendingGeneration(generation.ChatID, generation.TelegramMessageID)
+	}
+}
+
+// resumePendingGeneration responds to a stored message as if it had just been received.
+// Photos are not stored, so only the text of the message is used.
+func (t *Tellama) resumePendingGeneration(generation database.PendingGeneration) error {
+	if time.Since(generation.CreatedAt) > maxPendingAge {
+		log.Info().
+			Int64("chat_id", generation.ChatID).
+			Int("message_id", generation.TelegramMessageID).
+			Msg("Dropped expired pending generation")
+		return nil
+	}
+
+	stored, err := t.dm.GetMessageByTelegramID(generation.ChatID, generation.TelegramMessageID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// The reply may have been sent just before the bot stopped
+	_, err = t.dm.GetReplyTo(generation.ChatID, generation.TelegramMessageID)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	chat := &telebot.Chat{
+		ID:    generation.ChatID,
+		Type:  telebot.ChatType(generation.ChatType),
+		Title: stored.ChatTitle,
+	}
+	user := &telebot.User{
+		ID:        stored.UserID,
+		Username:  stored.Username,
+		FirstName: stored.FirstName,
+		LastName:  stored.LastName,
+	}
+	message := &telebot.Message{
+		ID:           generation.TelegramMessageID,
+		Chat:         chat,
+		Sender:       user,
+		Text:         stored.Content,
+		ThreadID:     stored.ThreadID,
+		TopicMessage: stored.ThreadID != 0,
+	}
+
+	history, err := t.dm.GetMessagesUntil(chat.ID, stored.ThreadID, stored.ID-1, t.settings().historyFetchLimit)
+	if err != nil {
+		return err
+	}
+
+	log.Info().
+		Int64("chat_id", chat.ID).
+		Int("message_id", message.ID).
+		Msg("Resuming pending generation")
+	ctx := t.bot.NewContext(telebot.Update{Message: message})
+	if t.genaiAllowConcurrent {
+		return t.processMessage(ctx, chat, user, message, stored.Content, stored.ContentType, nil, history)
+	}
+
+	<-t.sem
+	defer func() { t.sem <- struct{}{} }()
+	return t.processMessage(ctx, chat, user, message, stored.Content, stored.ContentType, nil, history)
+}
+
+// stopOnSignal stops polling for updates when the process is asked to terminate.
+// Generations that are still in progress stay pending and are resumed on the next start.
+func (t *Tellama) stopOnSignal() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+
+		// Restore the default handling so that a second signal terminates immediately
+		signal.Stop(signals)
+
+		pending, err := t.dm.GetPendingGenerations()
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to get pending generations")
+		}
+		log.Info().Int("pending_generations", len(pending)).Msg("Shutting down")
+		t.bot.Stop()
+	}()
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/k4yt3x/tellama/internal/database"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/telebot.v4"
+)
+
+// withPersona returns the chat override with the settings of the chat's persona applied.
+// The persona's system prompt and model replace those of the chat, while its options are merged over the chat's.
+// Personas that are no longer configured are ignored.
+func (t *Tellama) withPersona(chatOverride database.ChatOverride) (database.ChatOverride, error) {
+	persona, exists := t.settings().personas[chatOverride.Persona]
+	if chatOverride.Persona == "" || !exists {
+		return chatOverride, nil
+	}
+
+	if persona.SystemPrompt != "" {
+		chatOverride.SystemPrompt = persona.SystemPrompt
+	}
+	if persona.Model != "" {
+		chatOverride.Model = persona.Model
+	}
+	if persona.Options != "" {
+		options, err := mergeOptions(chatOverride.Options, persona.Options)
+		if err != nil {
+			return chatOverride, err
+		}
+		chatOverride.Options = options
+	}
+	return chatOverride, nil
+}
+
+// systemPromptTemplate returns the system prompt template of a chat, taking its persona into account.
+func (t *Tellama) systemPromptTemplate(chatOverride database.ChatOverride) string {
+	if persona, exists := t.settings().personas[chatOverride.Persona]; exists && persona.SystemPrompt != "" {
+		return persona.SystemPrompt
+	}
+	if chatOverride.SystemPrompt != "" {
+		return chatOverride.SystemPrompt
+	}
+	return defaultSystemPrompt
+}
+
+// mergeOptions returns the JSON options with the values of overlay set over those of base.
+func mergeOptions(base string, overlay string) (string, error) {
+	merged := map[string]any{}
+	for _, options := range []string{base, overlay} {
+		if options == "" {
+			continue
+		}
+		if err := json.Unmarshal([]byte(options), &merged); err != nil {
+			return "", err
+		}
+	}
+
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func (t *Tellama) setPersona(ctx telebot.Context) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil {
+		return nil
+	}
+
+	if !t.authorize(chat, msg.Sender, msg, permissionChatAdmin) {
+		return ctx.Reply("You do not have permission to use this command.")
+	}
+
+	personas := t.settings().personas
+	if len(personas) == 0 {
+		return ctx.Reply("No personas are configured.")
+	}
+
+	name := strings.ToLower(strings.TrimSpace(msg.Payload))
+	if _, exists := personas[name]; !exists && name != "default" {
+		return ctx.Reply(tr(ctx,
+			"Usage: /setpersona <name>, or /setpersona default to stop using a persona. Available personas: %s.",
+			strings.Join(slices.Sorted(maps.Keys(personas)), ", "),
+		))
+	}
+	if name == "default" {
+		name = ""
+	}
+
+	if err := t.dm.SetChatPersona(chat.ID, chat.Title, name); err != nil {
+		log.Error().Err(err).Msg("Failed to set persona")
+		return ctx.Reply("Failed to set persona. Please check logs for details.")
+	}
+
+	log.Info().
+		Int64("chat_id", chat.ID).
+		Int64("user_id", msg.Sender.ID).
+		Str("persona", name).
+		Msg("Persona set")
+
+	if name == "" {
+		return ctx.Reply("The chat no longer uses a persona.")
+	}
+	return ctx.Reply(tr(ctx, "Persona set to %s.", name))
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/k4yt3x/tellama/internal/database"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/telebot.v4"
+	"gorm.io/gorm"
+)
+
+// findRepliedMessage looks up the stored message a command message replies to.
+func (t *Tellama) findRepliedMessage(chat *telebot.Chat, reply *telebot.Message) (database.Message, error) {
+	if reply.Sender == nil {
+		return database.Message{}, gorm.ErrRecordNotFound
+	}
+
+	content := reply.Text
+	if content == "" {
+		content = reply.Caption
+	}
+	return t.dm.FindMessage(chat.ID, reply.Sender.ID, content)
+}
+
+// includePinnedMessages prepends pinned messages that fell outside of the history window.
+func (t *Tellama) includePinnedMessages(
+	chatID int64,
+	messages []database.Message,
+) ([]database.Message, error) {
+	pinned, err := t.dm.GetPinnedMessages(chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []database.Message
+	for _, message := range pinned {
+		if !slices.ContainsFunc(messages, func(m database.Message) bool { return m.ID == message.ID }) {
+			missing = append(missing, message)
+		}
+	}
+	return append(missing, messages...), nil
+}
+
+func (t *Tellama) setContextPinned(ctx telebot.Context, pinned bool) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil {
+		return nil
+	}
+
+	if !t.authorize(chat, msg.Sender, msg, permissionChatAdmin) {
+		return ctx.Reply("You do not have permission to use this command.")
+	}
+
+	if msg.ReplyTo == nil {
+		return ctx.Reply("Please reply to the message you want to pin or unpin.")
+	}
+
+	message, err := t.findRepliedMessage(chat, msg.ReplyTo)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ctx.Reply("The message is not in the conversation history.")
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to find replied message")
+		return ctx.Reply("Failed to find the message. Please check logs for details.")
+	}
+
+	if err = t.dm.SetMessagePinned(message.ID, pinned); err != nil {
+		log.Error().Err(err).Msg("Failed to update pinned message")
+		return ctx.Reply("Failed to update the message. Please check logs for details.")
+	}
+
+	log.Info().
+		Int64("chat_id", chat.ID).
+		Int64("user_id", msg.Sender.ID).
+		Uint("message_id", message.ID).
+		Bool("pinned", pinned).
+		Msg("Context message pin updated")
+
+	if pinned {
+		return ctx.Reply("Message pinned to the context.")
+	}
+	return ctx.Reply("Message unpinned from the context.")
+}
+
+func (t *Tellama) pinContext(ctx telebot.Context) error {
+	return t.setContextPinned(ctx, true)
+}
+
+func (t *Tellama) unpinContext(ctx telebot.Context) error {
+	return t.setContextPinned(ctx, false)
+}
+
+func (t *Tellama) listPinned(ctx telebot.Context) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil {
+		return nil
+	}
+
+	if !t.authorize(chat, msg.Sender, msg, permissionTrusted) {
+		return ctx.Reply("You do not have permission to use this command.")
+	}
+
+	pinned, err := t.dm.GetPinnedMessages(chat.ID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get pinned messages")
+		return ctx.Reply("Failed to get pinned messages. Please check logs for details.")
+	}
+
+	if len(pinned) == 0 {
+		return ctx.Reply("No messages are pinned to the context.")
+	}
+
+	var reply strings.Builder
+	reply.WriteString("Pinned context messages:\n")
+	for i, message := range pinned {
+		reply.WriteString(fmt.Sprintf("\n%d. %s: %s", i+1, message.FirstName, message.Content))
+	}
+	return ctx.Reply(reply.String())
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"github.com/k4yt3x/tellama/internal/database"
+	"github.com/k4yt3x/tellama/internal/plugin"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/telebot.v4"
+)
+
+// registeredPlugins returns the plugins the bot is built with, which run in this order.
+// Forks add their own plugins here rather than changing the bot itself.
+func registeredPlugins() []plugin.Plugin {
+	return nil
+}
+
+// registerPluginCommands adds the commands of the plugins.
+// Plugin commands replace the built-in commands of the same name.
+func (t *Tellama) registerPluginCommands() {
+	for _, command := range t.plugins.Commands() {
+		t.handle(command.Endpoint, command.Handler)
+	}
+	if names := t.plugins.Names(); len(names) > 0 {
+		log.Info().Strs("plugins", names).Int("commands", len(t.plugins.Commands())).Msg("Registered plugins")
+	}
+}
+
+// filterMessage runs the message filters of the plugins. Messages are ignored if a filter fails.
+func (t *Tellama) filterMessage(ctx telebot.Context, text string) (string, bool) {
+	text, keep, err := t.plugins.FilterMessage(ctx, text)
+	if err != nil {
+		log.Error().Err(err).Int64("chat_id", ctx.Chat().ID).Msg("Failed to filter message")
+		return "", false
+	}
+	if !keep {
+		log.Info().Int64("chat_id", ctx.Chat().ID).Msg("Message ignored by a plugin")
+	}
+	return text, keep
+}
+
+// beforeGeneration runs the pre-generation hooks of the plugins on the conversation.
+func (t *Tellama) beforeGeneration(ctx telebot.Context, messages []database.Message) ([]database.Message, error) {
+	messages, err := t.plugins.BeforeGeneration(ctx, messages)
+	if err != nil {
+		log.Error().Err(err).Int64("chat_id", ctx.Chat().ID).Msg("Failed to run pre-generation hooks")
+		return nil, err
+	}
+	return messages, nil
+}
+
+// afterGeneration runs the post-generation hooks of the plugins on the response.
+func (t *Tellama) afterGeneration(ctx telebot.Context, response string) (string, error) {
+	response, err := t.plugins.AfterGeneration(ctx, response)
+	if err != nil {
+		log.Error().Err(err).Int64("chat_id", ctx.Chat().ID).Msg("Failed to run post-generation hooks")
+		return "", err
+	}
+	return response, nil
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/k4yt3x/tellama/internal/config"
+	"github.com/k4yt3x/tellama/internal/genai"
+	"github.com/k4yt3x/tellama/internal/i18n"
+	"github.com/k4yt3x/tellama/internal/preset"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/telebot.v4"
+)
+
+// maxPresetMessageLength is the length above which an exported preset is sent as a file.
+const maxPresetMessageLength = 3500
+
+// maxPresetFileSize is the largest preset file that is downloaded for import.
+const maxPresetFileSize = 64 << 10
+
+func (t *Tellama) exportPreset(ctx telebot.Context) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil {
+		return nil
+	}
+
+	if !t.authorize(chat, msg.Sender, msg, permissionChatAdmin) {
+		return ctx.Reply("You do not have permission to use this command.")
+	}
+
+	chatOverride, err := t.dm.GetChatOverride(chat.ID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get chat override")
+		return ctx.Reply("Failed to export preset. Please check logs for details.")
+	}
+
+	blob, err := preset.FromChatOverride(chatOverride).Encode()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to encode preset")
+		return ctx.Reply("Failed to export preset. Please check logs for details.")
+	}
+
+	log.Info().
+		Int64("chat_id", chat.ID).
+		Int64("user_id", msg.Sender.ID).
+		Int("length", len(blob)).
+		Msg("Preset exported")
+
+	if len(blob) > maxPresetMessageLength {
+		return ctx.Reply(&telebot.Document{
+			File:     telebot.FromReader(strings.NewReader(blob)),
+			FileName: "preset.txt",
+			MIME:     "text/plain",
+			Caption:  tr(ctx, "Reply to this file with /importpreset in another chat to import it."),
+		})
+	}
+	return ctx.Reply(
+		tr(ctx, "Send /importpreset followed by this preset in another chat to import it:")+"\n\n```\n"+blob+"\n```",
+		telebot.ModeMarkdown,
+	)
+}
+
+func (t *Tellama) importPreset(ctx telebot.Context) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil {
+		return nil
+	}
+
+	if !t.authorize(chat, msg.Sender, msg, permissionChatAdmin) {
+		return ctx.Reply("You do not have permission to use this command.")
+	}
+
+	// Read the preset from the command arguments or from the replied-to preset file
+	text := msg.Payload
+	if strings.TrimSpace(text) == "" && msg.ReplyTo != nil {
+		switch {
+		case msg.ReplyTo.Document != nil:
+			if msg.ReplyTo.Document.FileSize > maxPresetFileSize {
+				return ctx.Reply("The preset file is too large.")
+			}
+			data, err := t.downloadFile(&msg.ReplyTo.Document.File)
+			if err != nil {
+				log.Error().Err(err).Msg("Failed to download preset file")
+				return ctx.Reply("Failed to download the preset file. Please check logs for details.")
+			}
+			text = string(data)
+		default:
+			text = msg.ReplyTo.Text
+		}
+	}
+	if strings.TrimSpace(text) == "" {
+		return ctx.Reply("Usage: /importpreset <preset>, or reply to an exported preset with /importpreset")
+	}
+
+	p, err := preset.Decode(text)
+	if err != nil {
+		return ctx.Reply(tr(ctx, "Invalid preset: %v.", err))
+	}
+	if err = t.validatePreset(chat.ID, p); err != nil {
+		return ctx.Reply(tr(ctx, "Invalid preset: %v.", err))
+	}
+
+	if err = t.dm.SetChatPreset(p.ChatOverride(chat.ID, chat.Title)); err != nil {
+		log.Error().Err(err).Msg("Failed to import preset")
+		return ctx.Reply("Failed to import preset. Please check logs for details.")
+	}
+
+	log.Info().
+		Int64("chat_id", chat.ID).
+		Int64("user_id", msg.Sender.ID).
+		Str("model", p.Model).
+		Msg("Preset imported")
+	return ctx.Reply("Preset imported successfully.")
+}
+
+// validatePreset checks that the settings of a preset can be used in the chat.
+func (t *Tellama) validatePreset(chatID int64, p preset.Preset) error {
+	switch p.ModelBadge {
+	case "", modelBadgePrefix, modelBadgeSuffix:
+	default:
+		return fmt.Errorf("unknown model badge position %q", p.ModelBadge)
+	}
+
+	if p.Language != "" && !i18n.Supported(p.Language) {
+		return fmt.Errorf("unsupported language %q", p.Language)
+	}
+
+	if p.Trigger != "" {
+		if _, err := config.ParseTriggerMode(p.Trigger); err != nil {
+			return fmt.Errorf("unknown trigger mode %q", p.Trigger)
+		}
+	}
+
+	if p.ChatEvents != "" && p.ChatEvents != chatEventsNone {
+		if _, err := config.ParseChatEvents(strings.Split(p.ChatEvents, ",")); err != nil {
+			return err
+		}
+	}
+
+	// Options are checked like those set with /setoption, since the exporting chat may use another provider
+	if err := validateOptions(t.genaiProvider, p.Options); err != nil {
+		return err
+	}
+
+	// A response format that the provider cannot use would fail every generation in the chat
+	if p.ResponseFormat != "" {
+		if !supportsResponseFormat(t.genaiProvider) {
+			return fmt.Errorf("the %s provider does not support response formats", t.genaiProvider)
+		}
+		if err := genai.ValidateResponseFormat(p.ResponseFormat); err != nil {
+			return err
+		}
+	}
+
+	// The model of the exporting chat may not be served by the provider of this chat
+	if p.Model != "" {
+		models, err := t.listModels(chatID)
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed to list models, skipping model validation")
+		} else if !slices.Contains(models, p.Model) && !slices.Contains(models, p.Model+":latest") {
+			return fmt.Errorf("model %q is not available", p.Model)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package main //nolint:testpackage // Unit tests are in the same package
+
+import (
+	"testing"
+
+	"github.com/k4yt3x/tellama/internal/genai"
+	"github.com/k4yt3x/tellama/internal/preset"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidatePreset(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider genai.Provider
+		preset   string
+		valid    bool
+	}{
+		{
+			name:     "Empty preset",
+			provider: genai.ProviderOllama,
+			preset:   `{"v":1}`,
+			valid:    true,
+		},
+		{
+			name:     "JSON response format",
+			provider: genai.ProviderOllama,
+			preset:   `{"v":1,"response_format":"json"}`,
+			valid:    true,
+		},
+		{
+			name:     "JSON schema response format",
+			provider: genai.ProviderOpenAI,
+			preset:   `{"v":1,"response_format":"{\"type\":\"object\"}"}`,
+			valid:    true,
+		},
+		{
+			name:     "Invalid response format",
+			provider: genai.ProviderOllama,
+			preset:   `{"v":1,"response_format":"xml"}`,
+			valid:    false,
+		},
+		{
+			name:     "Response format without provider support",
+			provider: genai.ProviderBedrock,
+			preset:   `{"v":1,"response_format":"json"}`,
+			valid:    false,
+		},
+		{
+			name:     "Options",
+			provider: genai.ProviderOllama,
+			preset:   `{"v":1,"options":"{\"temperature\":0.3,\"top_k\":40}"}`,
+			valid:    true,
+		},
+		{
+			name:     "Option out of range",
+			provider: genai.ProviderOllama,
+			preset:   `{"v":1,"options":"{\"temperature\":50}"}`,
+			valid:    false,
+		},
+		{
+			name:     "Option of another provider",
+			provider: genai.ProviderOpenAI,
+			preset:   `{"v":1,"options":"{\"top_k\":40}"}`,
+			valid:    false,
+		},
+		{
+			name:     "Option that is not a number",
+			provider: genai.ProviderOllama,
+			preset:   `{"v":1,"options":"{\"temperature\":\"0.3\"}"}`,
+			valid:    false,
+		},
+		{
+			name:     "Options that are not an object",
+			provider: genai.ProviderOllama,
+			preset:   `{"v":1,"options":"[0.3]"}`,
+			valid:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			tellama := &Tellama{genaiProvider: tt.provider}
+			p, err := preset.Decode(tt.preset)
+			require.NoError(t, err)
+
+			// Act
+			err = tellama.validatePreset(-100, p)
+
+			// Assert
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
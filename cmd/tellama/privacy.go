@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/k4yt3x/tellama/internal/config"
+	"github.com/k4yt3x/tellama/internal/genai"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/telebot.v4"
+)
+
+// acknowledgeStoredMessage lets the sender know that a message was stored without a reply.
+func (t *Tellama) acknowledgeStoredMessage(chat *telebot.Chat, message *telebot.Message) {
+	if t.settings().acknowledgement != config.AcknowledgementReaction {
+		return
+	}
+
+	err := t.bot.React(chat, message, telebot.Reactions{
+		Reactions: []telebot.Reaction{{Type: "emoji", Emoji: t.settings().acknowledgementEmoji}},
+	})
+	if err != nil {
+		log.Warn().Err(err).Int64("chat_id", chat.ID).Msg("Failed to react to stored message")
+	}
+}
+
+// privacy explains what the bot stores and who it is shared with.
+func (t *Tellama) privacy(ctx telebot.Context) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil {
+		return nil
+	}
+
+	var reply strings.Builder
+	reply.WriteString("Privacy information:\n")
+	if t.dm.IsChatTrusted(chat.ID) || t.allowUntrustedChats {
+		reply.WriteString("\nThis bot stores the following data for this chat:")
+	} else {
+		reply.WriteString("\nThis chat is not trusted, so nothing is stored. In trusted chats, this bot stores:")
+	}
+	reply.WriteString("\n- The text and photo captions of messages, except those starting with //")
+	reply.WriteString("\n- The user ID, username, and name of each sender")
+	reply.WriteString("\n- The bot's replies and the results of tools it calls")
+	reply.WriteString("\n- Summaries of earlier conversations when the history is compacted")
+	reply.WriteString("\n- Notes saved with /remember")
+	reply.WriteString("\n- Prompts scheduled with /schedule and the user who scheduled them")
+	if t.rag.Enabled {
+		reply.WriteString("\n- Documents added with /ingest")
+	}
+	reply.WriteString("\n- The number of tokens used by each sender")
+	chatOverride, err := t.dm.GetChatOverride(chat.ID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get chat override")
+	} else if events := t.chatEvents(chatOverride); len(events) > 0 {
+		reply.WriteString("\n- Chat events such as members joining and leaving: " + strings.Join(events, ", "))
+	}
+	if t.genaiProvider == genai.ProviderOpenAI && t.dm.EncryptionEnabled() {
+		reply.WriteString("\n- Personal API keys registered with /setapikey, which are stored encrypted")
+	}
+
+	reply.WriteString(fmt.Sprintf(
+		"\n\nUp to %d of the latest messages are sent to the %s provider to generate replies. "+
+			"Photos are sent to the provider but are not stored.",
+		t.settings().historyFetchLimit,
+		t.genaiProvider,
+	))
+	if t.settings().acknowledgement == config.AcknowledgementReaction {
+		reply.WriteString(fmt.Sprintf(
+			"\n\nStored messages the bot does not reply to are marked with a %s reaction.",
+			t.settings().acknowledgementEmoji,
+		))
+	}
+	reply.WriteString("\n\nThe message history of this chat can be cleared with /amnesia.")
+	reply.WriteString("\nUse /optout to stop the bot from storing your messages in any chat.")
+	return ctx.Reply(reply.String())
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/telebot.v4"
+)
+
+// quotedMessage returns the author and full text of the message the user replied to if it was not sent
+// by the bot, so that the model knows what the user is responding to.
+func (t *Tellama) quotedMessage(msg *telebot.Message) (string, string) {
+	replyTo := msg.ReplyTo
+	if replyTo == nil || (replyTo.Sender != nil && replyTo.Sender.ID == t.bot.Me.ID) {
+		return "", ""
+	}
+
+	text := replyTo.Text
+	if text == "" {
+		text = replyTo.Caption
+	}
+	if strings.TrimSpace(text) == "" {
+		return "", ""
+	}
+
+	var author string
+	switch {
+	case replyTo.Sender != nil && replyTo.Sender.Username != "":
+		author = "@" + replyTo.Sender.Username
+	case replyTo.Sender != nil:
+		author = strings.TrimSpace(replyTo.Sender.FirstName + " " + replyTo.Sender.LastName)
+	case replyTo.SenderChat != nil:
+		author = replyTo.SenderChat.Title
+	}
+	return author, text
+}
+
+// quoteMessage prefixes the text of a message with the message it replies to as a quotation.
+func quoteMessage(author string, quoted string, text string) string {
+	lines := strings.Split(quoted, "\n")
+	for i, line := range lines {
+		lines[i] = "> " + line
+	}
+
+	header := "In reply to a message:"
+	if author != "" {
+		header = fmt.Sprintf("In reply to %s:", author)
+	}
+	return fmt.Sprintf("%s\n%s\n\n%s", header, strings.Join(lines, "\n"), text)
+}
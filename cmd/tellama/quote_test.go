@@ -0,0 +1,115 @@
+package main //nolint:testpackage // Unit tests are in the same package
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/telebot.v4"
+)
+
+func TestQuotedMessage(t *testing.T) {
+	bot := &telebot.User{ID: 900000001, Username: "tellama_bot"}
+
+	tests := []struct {
+		name           string
+		replyTo        *telebot.Message
+		expectedAuthor string
+		expectedText   string
+	}{
+		{name: "No reply"},
+		{
+			name:    "Reply to the bot",
+			replyTo: &telebot.Message{Sender: bot, Text: "Hello! How can I help?"},
+		},
+		{
+			name:           "Reply to a user with a username",
+			replyTo:        &telebot.Message{Sender: &telebot.User{ID: 1, Username: "alice"}, Text: "Any ideas?"},
+			expectedAuthor: "@alice",
+			expectedText:   "Any ideas?",
+		},
+		{
+			name: "Reply to a user without a username",
+			replyTo: &telebot.Message{
+				Sender: &telebot.User{ID: 2, FirstName: "Bob", LastName: "Smith"},
+				Text:   "Any ideas?",
+			},
+			expectedAuthor: "Bob Smith",
+			expectedText:   "Any ideas?",
+		},
+		{
+			name: "Caption-only reply",
+			replyTo: &telebot.Message{
+				Sender:  &telebot.User{ID: 1, Username: "alice"},
+				Caption: "What is this bird?",
+			},
+			expectedAuthor: "@alice",
+			expectedText:   "What is this bird?",
+		},
+		{
+			name:           "Sender chat author",
+			replyTo:        &telebot.Message{SenderChat: &telebot.Chat{ID: -1001, Title: "News"}, Text: "New release"},
+			expectedAuthor: "News",
+			expectedText:   "New release",
+		},
+		{
+			name:    "Whitespace-only quote",
+			replyTo: &telebot.Message{Sender: &telebot.User{ID: 1, Username: "alice"}, Text: " \n\t "},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			tellama := &Tellama{bot: &telebot.Bot{Me: bot}}
+			msg := &telebot.Message{Text: "What do you think?", ReplyTo: tt.replyTo}
+
+			// Act
+			author, text := tellama.quotedMessage(msg)
+
+			// Assert
+			assert.Equal(t, tt.expectedAuthor, author)
+			assert.Equal(t, tt.expectedText, text)
+		})
+	}
+}
+
+func TestQuoteMessage(t *testing.T) {
+	tests := []struct {
+		name     string
+		author   string
+		quoted   string
+		text     string
+		expected string
+	}{
+		{
+			name:     "Single line",
+			author:   "@alice",
+			quoted:   "Any ideas?",
+			text:     "Try restarting it.",
+			expected: "In reply to @alice:\n> Any ideas?\n\nTry restarting it.",
+		},
+		{
+			name:     "Multi-line quote",
+			author:   "@alice",
+			quoted:   "First line\n\nThird line",
+			text:     "Agreed.",
+			expected: "In reply to @alice:\n> First line\n> \n> Third line\n\nAgreed.",
+		},
+		{
+			name:     "Unknown author",
+			quoted:   "Any ideas?",
+			text:     "Try restarting it.",
+			expected: "In reply to a message:\n> Any ideas?\n\nTry restarting it.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			message := quoteMessage(tt.author, tt.quoted, tt.text)
+
+			// Assert
+			assert.Equal(t, tt.expected, message)
+		})
+	}
+}
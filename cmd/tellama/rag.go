@@ -0,0 +1,271 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/k4yt3x/tellama/internal/database"
+	"github.com/k4yt3x/tellama/internal/genai"
+	"github.com/k4yt3x/tellama/internal/rag"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/telebot.v4"
+	"gorm.io/gorm"
+)
+
+// embeddingBatchSize is the number of chunks embedded per request
+const embeddingBatchSize = 32
+
+// newEmbedder creates a client for the configured embedding model.
+func (t *Tellama) newEmbedder() (genai.Embedder, error) {
+	genaiConfig, err := copyProviderConfig(t.settings().genaiConfig)
+	if err != nil {
+		return nil, err
+	}
+	setModelName(genaiConfig, t.rag.EmbeddingModel)
+
+	genaiClient, err := genai.New(t.genaiProvider, genaiConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	embedder, ok := genaiClient.(genai.Embedder)
+	if !ok {
+		return nil, errors.New("provider does not support embeddings")
+	}
+	return embedder, nil
+}
+
+// embedTexts computes the embeddings of the texts in batches.
+func embedTexts(embedder genai.Embedder, texts []string) ([][]float32, error) {
+	embeddings := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += embeddingBatchSize {
+		batch := texts[start:min(start+embeddingBatchSize, len(texts))]
+		batchEmbeddings, err := embedder.Embed(batch)
+		if err != nil {
+			return nil, err
+		}
+		if len(batchEmbeddings) != len(batch) {
+			return nil, errors.New("provider returned an unexpected number of embeddings")
+		}
+		embeddings = append(embeddings, batchEmbeddings...)
+	}
+	return embeddings, nil
+}
+
+// includeDocumentContext appends the document chunks most relevant to the query as a system message.
+func (t *Tellama) includeDocumentContext(
+	chatID int64,
+	query string,
+	messages []database.Message,
+) ([]database.Message, error) {
+	if !t.rag.Enabled || strings.TrimSpace(query) == "" {
+		return messages, nil
+	}
+
+	chunks, err := t.dm.GetDocumentChunks(chatID)
+	if err != nil || len(chunks) == 0 {
+		return messages, err
+	}
+
+	embedder, err := t.newEmbedder()
+	if err != nil {
+		return messages, err
+	}
+
+	queryEmbeddings, err := embedder.Embed([]string{query})
+	if err != nil {
+		return messages, err
+	}
+	if len(queryEmbeddings) != 1 {
+		return messages, errors.New("provider returned an unexpected number of embeddings")
+	}
+
+	candidates := make([][]float32, len(chunks))
+	for i, chunk := range chunks {
+		candidates[i], err = rag.DecodeEmbedding(chunk.Embedding)
+		if err != nil {
+			return messages, err
+		}
+	}
+
+	matches := rag.TopK(queryEmbeddings[0], candidates, t.rag.TopK, t.rag.MinScore)
+	if len(matches) == 0 {
+		return messages, nil
+	}
+
+	var content strings.Builder
+	content.WriteString("Relevant excerpts from the documents of this chat:")
+	for i, match := range matches {
+		chunk := chunks[match.Index]
+		content.WriteString(fmt.Sprintf("\n\n[%d] %s:\n%s", i+1, chunk.DocumentName, chunk.Content))
+	}
+
+	log.Debug().Int64("chat_id", chatID).Int("chunks", len(matches)).Msg("Retrieved document context")
+
+	return append(messages, database.Message{
+		Timestamp:   time.Now().UTC(),
+		ChatID:      chatID,
+		Role:        "system",
+		ContentType: database.ContentTypeSystem,
+		UserID:      t.bot.Me.ID,
+		Username:    t.bot.Me.Username,
+		FirstName:   "system",
+		Content:     content.String(),
+	}), nil
+}
+
+// ingestDocument splits a document into chunks, embeds them, and stores them for the chat.
+func (t *Tellama) ingestDocument(ctx telebot.Context, document *telebot.Document) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+
+	if !t.rag.Enabled {
+		return ctx.Reply("Document retrieval is disabled.")
+	}
+
+	if !t.authorize(chat, msg.Sender, msg, permissionChatAdmin) {
+		return ctx.Reply("You do not have permission to use this command.")
+	}
+
+	if t.rag.MaxDocumentSize > 0 && document.FileSize > t.rag.MaxDocumentSize {
+		return ctx.Reply(tr(ctx, "The document is too large. The maximum size is %d bytes.", t.rag.MaxDocumentSize))
+	}
+
+	data, err := t.downloadFile(&document.File)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to download document")
+		return ctx.Reply("Failed to download the document. Please check logs for details.")
+	}
+
+	text, err := rag.ExtractText(document.FileName, data)
+	if err != nil {
+		return ctx.Reply(tr(ctx, "Failed to read the document: %s", err))
+	}
+
+	texts := rag.SplitText(text, t.rag.ChunkSize, t.rag.ChunkOverlap)
+	if len(texts) == 0 {
+		return ctx.Reply("The document does not contain any text.")
+	}
+
+	embedder, err := t.newEmbedder()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to create embedding client")
+		return ctx.Reply("Failed to ingest the document. Please check logs for details.")
+	}
+
+	embeddings, err := embedTexts(embedder, texts)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to embed document")
+		return ctx.Reply("Failed to ingest the document. Please check logs for details.")
+	}
+
+	chunks := make([]database.DocumentChunk, len(texts))
+	for i, chunkText := range texts {
+		chunks[i] = database.DocumentChunk{
+			Content:   chunkText,
+			Embedding: rag.EncodeEmbedding(embeddings[i]),
+		}
+	}
+
+	if err = t.dm.StoreDocumentChunks(chat.ID, document.FileName, chunks); err != nil {
+		log.Error().Err(err).Msg("Failed to store document chunks")
+		return ctx.Reply("Failed to ingest the document. Please check logs for details.")
+	}
+
+	log.Info().
+		Int64("chat_id", chat.ID).
+		Int64("user_id", msg.Sender.ID).
+		Str("document", document.FileName).
+		Int("chunks", len(chunks)).
+		Msg("Document ingested")
+
+	return ctx.Reply(tr(ctx, "Ingested %s (%d chunks).", document.FileName, len(chunks)))
+}
+
+func (t *Tellama) ingest(ctx telebot.Context) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil {
+		return nil
+	}
+
+	if msg.ReplyTo == nil || msg.ReplyTo.Document == nil {
+		return ctx.Reply("Please reply to a document or send a document with /ingest as its caption.")
+	}
+	return t.ingestDocument(ctx, msg.ReplyTo.Document)
+}
+
+func (t *Tellama) handleDocument(ctx telebot.Context) error {
+	msg := ctx.Message()
+	if ctx.Chat() == nil || msg == nil || msg.Document == nil {
+		return nil
+	}
+
+	// Documents are only ingested when captioned with the /ingest command, otherwise they are answered
+	command, _, _ := strings.Cut(strings.TrimSpace(msg.Caption), " ")
+	command, _, _ = strings.Cut(command, "@")
+	if command != "/ingest" {
+		return t.answerDocument(ctx)
+	}
+	return t.ingestDocument(ctx, msg.Document)
+}
+
+func (t *Tellama) listDocuments(ctx telebot.Context) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil {
+		return nil
+	}
+
+	if !t.authorize(chat, msg.Sender, msg, permissionTrusted) {
+		return ctx.Reply("You do not have permission to use this command.")
+	}
+
+	documents, err := t.dm.ListDocuments(chat.ID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list documents")
+		return ctx.Reply("Failed to list documents. Please check logs for details.")
+	}
+
+	if len(documents) == 0 {
+		return ctx.Reply("No documents have been ingested in this chat.")
+	}
+	return ctx.Reply("Ingested documents:\n\n- " + strings.Join(documents, "\n- "))
+}
+
+func (t *Tellama) deleteDocument(ctx telebot.Context) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil {
+		return nil
+	}
+
+	if !t.authorize(chat, msg.Sender, msg, permissionChatAdmin) {
+		return ctx.Reply("You do not have permission to use this command.")
+	}
+
+	documentName := strings.TrimSpace(msg.Payload)
+	if documentName == "" {
+		return ctx.Reply("Please provide the name of the document to delete.")
+	}
+
+	err := t.dm.DeleteDocument(chat.ID, documentName)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ctx.Reply("No document with this name has been ingested in this chat.")
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to delete document")
+		return ctx.Reply("Failed to delete the document. Please check logs for details.")
+	}
+
+	log.Info().
+		Int64("chat_id", chat.ID).
+		Int64("user_id", msg.Sender.ID).
+		Str("document", documentName).
+		Msg("Document deleted")
+
+	return ctx.Reply("Document deleted.")
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/k4yt3x/tellama/internal/config"
+	"github.com/k4yt3x/tellama/internal/genai"
+	"github.com/k4yt3x/tellama/internal/prompts"
+	"github.com/k4yt3x/tellama/internal/routing"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+)
+
+// runtimeSettings holds the configuration values that can be reloaded while the bot is running.
+type runtimeSettings struct {
+	historyFetchLimit    int
+	genaiTimeout         time.Duration
+	genaiConfig          genai.ProviderConfig
+	genaiTemplate        string
+	messageFormat        string
+	genaiVision          bool
+	capabilityOverrides  genai.CapabilityOverrides
+	maxTurns             int
+	maxToolRounds        int
+	maxContextTokens     int
+	emptyResponsePolicy  config.EmptyResponsePolicy
+	language             string
+	detectLanguage       bool
+	logReasoning         bool
+	genaiPricing         map[string]config.ModelPricing
+	dailyTokenBudget     int64
+	routing              routing.Config
+	contextStrategy      config.ContextStrategy
+	semanticContext      config.SemanticContext
+	alerts               config.Alerts
+	handoffMinLength     int
+	fileReplyMinLength   int
+	fileReplyCodeRatio   float64
+	acknowledgement      config.AcknowledgementPolicy
+	acknowledgementEmoji string
+	regenerateEdited     bool
+	requireChatAdmin     bool
+	statsFooterChatIDs   []int64
+	chatEvents           []string
+	trigger              config.TriggerMode
+	triggerKeywords      []string
+	maxScheduledPrompts  int
+	documents            config.Documents
+	abuse                config.Abuse
+	deleteCommands       []string
+	deleteCommandsDelay  time.Duration
+	channelPosts         bool
+	channelComments      bool
+	responseMessages     config.ResponseMessages
+	persona              config.Persona
+	personas             map[string]config.NamedPersona
+	prompts              *prompts.Library
+}
+
+func newRuntimeSettings(cfg *config.Config) (*runtimeSettings, error) {
+	library, err := prompts.Load(cfg.Prompts.Directory)
+	if err != nil {
+		return nil, err
+	}
+
+	return &runtimeSettings{
+		historyFetchLimit:    cfg.Database.HistoryFetchLimit,
+		genaiTimeout:         cfg.GenerativeAI.Timeout,
+		genaiConfig:          cfg.GenerativeAI.Config,
+		genaiTemplate:        cfg.GenerativeAI.Template,
+		messageFormat:        cfg.GenerativeAI.MessageFormat,
+		genaiVision:          cfg.GenerativeAI.Vision,
+		capabilityOverrides:  cfg.GenerativeAI.Capabilities,
+		maxTurns:             cfg.GenerativeAI.MaxTurns,
+		maxToolRounds:        cfg.GenerativeAI.MaxToolRounds,
+		maxContextTokens:     cfg.GenerativeAI.MaxContextTokens,
+		emptyResponsePolicy:  cfg.GenerativeAI.EmptyResponse,
+		language:             cfg.GenerativeAI.Language,
+		detectLanguage:       cfg.GenerativeAI.DetectLanguage,
+		logReasoning:         cfg.GenerativeAI.LogReasoning,
+		genaiPricing:         cfg.GenerativeAI.Pricing,
+		dailyTokenBudget:     cfg.GenerativeAI.DailyTokenBudget,
+		routing:              cfg.GenerativeAI.Routing,
+		contextStrategy:      cfg.GenerativeAI.ContextStrategy,
+		semanticContext:      cfg.GenerativeAI.SemanticContext,
+		alerts:               cfg.Alerts,
+		handoffMinLength:     cfg.Telegram.HandoffMinLength,
+		fileReplyMinLength:   cfg.Telegram.FileReplyMinLength,
+		fileReplyCodeRatio:   cfg.Telegram.FileReplyCodeRatio,
+		acknowledgement:      cfg.Telegram.Acknowledgement,
+		acknowledgementEmoji: cfg.Telegram.AcknowledgementEmoji,
+		regenerateEdited:     cfg.Telegram.RegenerateEditedReplies,
+		requireChatAdmin:     cfg.Telegram.RequireChatAdmin,
+		statsFooterChatIDs:   cfg.Telegram.StatsFooterChatIDs,
+		chatEvents:           cfg.Telegram.ChatEvents,
+		trigger:              cfg.Telegram.Trigger,
+		triggerKeywords:      cfg.Telegram.TriggerKeywords,
+		maxScheduledPrompts:  cfg.Scheduler.MaxPromptsPerChat,
+		documents:            cfg.Documents,
+		abuse:                cfg.Abuse,
+		deleteCommands:       cfg.Telegram.DeleteCommands,
+		deleteCommandsDelay:  cfg.Telegram.DeleteCommandsDelay,
+		channelPosts:         cfg.Telegram.ChannelPosts,
+		channelComments:      cfg.Telegram.ChannelComments,
+		responseMessages:     cfg.ResponseMessages,
+		persona:              cfg.Persona,
+		personas:             cfg.Personas,
+		prompts:              library,
+	}, nil
+}
+
+// settings returns the current runtime settings.
+func (t *Tellama) settings() *runtimeSettings {
+	return t.currentSettings.Load()
+}
+
+// reload loads the configuration file again and swaps in the settings that can change at runtime.
+// Settings that require a restart, such as the provider or the bot token, are left unchanged.
+func (t *Tellama) reload(configPath string) error {
+	cfg, err := t.loadConfig(configPath)
+	if err != nil {
+		return err
+	}
+
+	if cfg.GenerativeAI.Provider != t.genaiProvider {
+		return errors.New("changing the generative AI provider requires a restart")
+	}
+	if cfg.GenerativeAI.Mode != t.genaiMode {
+		return errors.New("changing the generative AI mode requires a restart")
+	}
+
+	settings, err := newRuntimeSettings(cfg)
+	if err != nil {
+		return err
+	}
+	t.currentSettings.Store(settings)
+	log.Info().Str("model", modelName(cfg.GenerativeAI.Config)).Msg("Configuration reloaded")
+	return nil
+}
+
+// watchConfig reloads the configuration on SIGHUP and, if enabled, whenever the config file changes.
+func (t *Tellama) watchConfig(configPath string, watchFile bool) {
+	reloadConfig := func(reason string) {
+		log.Info().Str("reason", reason).Msg("Reloading configuration")
+		if err := t.reload(configPath); err != nil {
+			log.Error().Err(err).Msg("Failed to reload configuration, keeping the current one")
+		}
+	}
+
+	if watchFile {
+		viper.OnConfigChange(func(event fsnotify.Event) {
+			if event.Has(fsnotify.Write) || event.Has(fsnotify.Create) {
+				reloadConfig("config file changed")
+			}
+		})
+		viper.WatchConfig()
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	go func() {
+		for range signals {
+			reloadConfig("SIGHUP")
+		}
+	}()
+}
@@ -0,0 +1,96 @@
+package main //nolint:testpackage // Unit tests are in the same package
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/k4yt3x/tellama/internal/config"
+	"github.com/k4yt3x/tellama/internal/genai"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newReloadTellama returns a bot running with the Ollama provider in chat mode
+// whose configuration reloads return cfg, or err if it is set.
+func newReloadTellama(t *testing.T, cfg *config.Config, err error) *Tellama {
+	t.Helper()
+
+	tellama := &Tellama{
+		genaiProvider: genai.ProviderOllama,
+		genaiMode:     genai.ModeChat,
+		loadConfig: func(configPath string) (*config.Config, error) {
+			assert.Equal(t, "tellama.yaml", configPath)
+			return cfg, err
+		},
+	}
+	settings, settingsErr := newRuntimeSettings(testReloadConfig(10))
+	require.NoError(t, settingsErr)
+	tellama.currentSettings.Store(settings)
+	return tellama
+}
+
+// testReloadConfig returns a configuration for the Ollama provider in chat mode with the history fetch limit.
+func testReloadConfig(historyFetchLimit int) *config.Config {
+	cfg := &config.Config{}
+	cfg.GenerativeAI.Provider = genai.ProviderOllama
+	cfg.GenerativeAI.Mode = genai.ModeChat
+	cfg.GenerativeAI.Config = &genai.OllamaConfig{BaseURL: "http://localhost:11434", Model: "llama3.3"}
+	cfg.Database.HistoryFetchLimit = historyFetchLimit
+	return cfg
+}
+
+func TestReload(t *testing.T) {
+	t.Run("Valid reload", func(t *testing.T) {
+		// Arrange
+		tellama := newReloadTellama(t, testReloadConfig(50), nil)
+		previous := tellama.settings()
+
+		// Act
+		err := tellama.reload("tellama.yaml")
+
+		// Assert
+		require.NoError(t, err)
+		assert.NotSame(t, previous, tellama.settings())
+		assert.Equal(t, 50, tellama.settings().historyFetchLimit)
+	})
+
+	brokenPrompts := testReloadConfig(50)
+	brokenPrompts.Prompts.Directory = t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(brokenPrompts.Prompts.Directory, "broken.tmpl"), []byte("{{"), 0644))
+
+	providerChange := testReloadConfig(50)
+	providerChange.GenerativeAI.Provider = genai.ProviderOpenAI
+
+	modeChange := testReloadConfig(50)
+	modeChange.GenerativeAI.Mode = genai.ModeCompletion
+
+	tests := []struct {
+		name string
+		cfg  *config.Config
+		err  error
+	}{
+		{name: "Provider change", cfg: providerChange},
+		{name: "Mode change", cfg: modeChange},
+		{name: "Broken prompts directory", cfg: brokenPrompts},
+		{name: "Invalid config file", err: errors.New("invalid config")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			tellama := newReloadTellama(t, tt.cfg, tt.err)
+			previous := tellama.settings()
+
+			// Act
+			err := tellama.reload("tellama.yaml")
+
+			// Assert
+			require.Error(t, err)
+			assert.Same(t, previous, tellama.settings())
+			assert.Equal(t, 10, tellama.settings().historyFetchLimit)
+		})
+	}
+}
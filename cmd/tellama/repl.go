@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/k4yt3x/tellama/internal/config"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"gopkg.in/telebot.v4"
+)
+
+// The bot account the REPL pretends to be.
+const (
+	replBotID       = 1
+	replBotUsername = "tellama_repl_bot"
+)
+
+// replTelegram is a stand-in for the Telegram Bot API that prints the bot's messages
+// to the terminal instead of sending them.
+type replTelegram struct {
+	chat      *telebot.Chat
+	bot       *telebot.User
+	messageID atomic.Int64
+}
+
+// nextMessageID returns a new message ID. IDs start at the current Unix time so that they
+// do not collide with the IDs of real messages stored for the chat or with earlier sessions.
+func (r *replTelegram) nextMessageID() int {
+	return int(r.messageID.Add(1))
+}
+
+// ServeHTTP answers Bot API requests with the minimal responses the bot expects.
+func (r *replTelegram) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	method := path.Base(req.URL.Path)
+	payload := map[string]any{}
+	if strings.HasPrefix(req.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	} else if err := req.ParseMultipartForm(1 << 20); err == nil {
+		for key, values := range req.MultipartForm.Value {
+			payload[key] = values[0]
+		}
+	}
+
+	var result any = true
+	switch method {
+	case "sendMessage", "editMessageText", "sendDocument":
+		text, _ := payload["text"].(string)
+		if method == "sendDocument" {
+			text, _ = payload["caption"].(string)
+			text = "[Document] " + text
+		}
+		messageID := r.nextMessageID()
+		if method == "editMessageText" {
+			fmt.Printf("\n[Edited]\n")
+			if id, err := strconv.Atoi(fmt.Sprint(payload["message_id"])); err == nil {
+				messageID = id
+			}
+		}
+		fmt.Printf("\n%s: %s\n\n", r.bot.FirstName, text)
+		result = map[string]any{
+			"message_id": messageID,
+			"date":       time.Now().Unix(),
+			"chat":       r.chat,
+			"from":       r.bot,
+			"text":       text,
+		}
+	case "sendChatAction", "setMessageReaction", "deleteMessage":
+	default:
+		log.Debug().Str("method", method).Msg("Ignored unsupported REPL Bot API method")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"ok": true, "result": result})
+}
+
+// runREPL is the Cobra command handler that simulates a chat with the bot in the terminal.
+func runREPL(cmd *cobra.Command, _ []string) {
+	configPath, err := cmd.Flags().GetString("config")
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse the config flag")
+	}
+	chatID, err := cmd.Flags().GetInt64("chat-id")
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse the chat-id flag")
+	}
+	userID, err := cmd.Flags().GetInt64("user-id")
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse the user-id flag")
+	}
+	firstName, err := cmd.Flags().GetString("first-name")
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse the first-name flag")
+	}
+	username, err := cmd.Flags().GetString("username")
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse the username flag")
+	}
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load configuration")
+	}
+
+	// Private chats share the ID of the user, while group chats have negative IDs
+	chat := &telebot.Chat{ID: chatID, Type: telebot.ChatPrivate, FirstName: firstName, Username: username}
+	if chatID < 0 {
+		chat = &telebot.Chat{ID: chatID, Type: telebot.ChatSuperGroup, Title: "REPL"}
+	}
+	if userID == 0 {
+		userID = max(chatID, 1)
+	}
+	user := &telebot.User{ID: userID, FirstName: firstName, Username: username}
+
+	telegram := &replTelegram{
+		chat: chat,
+		bot:  &telebot.User{ID: replBotID, IsBot: true, FirstName: "Tellama", Username: replBotUsername},
+	}
+	telegram.messageID.Store(time.Now().Unix())
+	server := httptest.NewServer(telegram)
+	defer server.Close()
+
+	// Handle updates synchronously so that each reply is printed before the next prompt
+	t, err := newTellama(cfg, &telebot.LongPoller{}, telebot.Settings{
+		URL:         server.URL,
+		Token:       cfg.Telegram.BotToken,
+		Synchronous: true,
+		Offline:     true,
+	})
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to initialize Tellama")
+	}
+	t.bot.Me = telegram.bot
+
+	fmt.Printf("Simulating %s chat %d as user %d against %s model %s\n",
+		chat.Type, chat.ID, user.ID, t.genaiProvider, modelName(t.settings().genaiConfig))
+	if chat.Type != telebot.ChatPrivate {
+		fmt.Printf("Mention @%s or use the configured trigger to get a reply\n", replBotUsername)
+	}
+	fmt.Println("Type /exit or press Ctrl+D to quit")
+
+	scanner := bufio.NewScanner(os.Stdin)
+	updateID := 0
+	for {
+		fmt.Print("> ")
+		if !scanner.Scan() {
+			break
+		}
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		if text == "/exit" {
+			break
+		}
+
+		updateID++
+		t.bot.ProcessUpdate(telebot.Update{
+			ID: updateID,
+			Message: &telebot.Message{
+				ID:       telegram.nextMessageID(),
+				Sender:   user,
+				Chat:     chat,
+				Text:     text,
+				Unixtime: time.Now().Unix(),
+			},
+		})
+	}
+	if err = scanner.Err(); err != nil {
+		log.Error().Err(err).Msg("Failed to read input")
+	}
+}
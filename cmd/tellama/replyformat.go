@@ -0,0 +1,142 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/k4yt3x/tellama/internal/database"
+	"github.com/k4yt3x/tellama/internal/markdown"
+	"github.com/k4yt3x/tellama/internal/utilities"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/telebot.v4"
+)
+
+const (
+	// telegramMessageLimit is the maximum number of characters in a Telegram message.
+	telegramMessageLimit = 4096
+
+	// fileReplyCaptionLength is the maximum length of the caption of a reply sent as a file.
+	fileReplyCaptionLength = 200
+)
+
+// shouldSendAsFile reports whether the response should be sent as a file instead of a message.
+// Responses that do not fit in a message and long code-heavy responses are sent as files.
+func (t *Tellama) shouldSendAsFile(chatOverride database.ChatOverride, response string) bool {
+	if utf8.RuneCountInString(markdown.ToMarkdownV2(response)) > telegramMessageLimit {
+		return true
+	}
+
+	minLength := t.settings().fileReplyMinLength
+	if chatOverride.FileReplyMinLength != 0 {
+		minLength = chatOverride.FileReplyMinLength
+	}
+	codeRatio := t.settings().fileReplyCodeRatio
+	if chatOverride.FileReplyCodeRatio != 0 {
+		codeRatio = chatOverride.FileReplyCodeRatio
+	}
+
+	return minLength > 0 &&
+		utf8.RuneCountInString(response) >= minLength &&
+		markdown.CodeRatio(response) >= codeRatio
+}
+
+// sendReply replies to the message with the response, choosing the format based on its content.
+func (t *Tellama) sendReply(
+	ctx telebot.Context,
+	message *telebot.Message,
+	chatOverride database.ChatOverride,
+	response string,
+) (*telebot.Message, error) {
+	if t.shouldSendAsFile(chatOverride, response) {
+		sent, err := t.sendReplyFile(ctx, message, response)
+		if err == nil {
+			return sent, nil
+		}
+		log.Error().Err(err).Msg("Failed to send reply as a file")
+	}
+
+	sent, err := ctx.Bot().Reply(message, markdown.ToMarkdownV2(response), telebot.ModeMarkdownV2)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to send reply with MarkdownV2 formatting")
+
+		// Retry sending the response without Markdown formatting
+		sent, err = ctx.Bot().Reply(message, response)
+	}
+	return sent, err
+}
+
+// sendReplyFile replies to the message with the response attached as a Markdown file.
+// The caption holds the beginning of the response if it starts with text rather than code.
+func (t *Tellama) sendReplyFile(
+	ctx telebot.Context,
+	message *telebot.Message,
+	response string,
+) (*telebot.Message, error) {
+	caption := "The response is attached as a file."
+	firstLine, _, _ := strings.Cut(strings.TrimSpace(response), "\n")
+	if firstLine != "" && !strings.HasPrefix(firstLine, "```") {
+		caption = utilities.TruncateStrToLength(firstLine, fileReplyCaptionLength)
+	}
+
+	document := &telebot.Document{
+		File:     telebot.FromReader(strings.NewReader(response)),
+		FileName: "response.md",
+		MIME:     "text/markdown",
+		Caption:  caption,
+	}
+
+	log.Info().
+		Int64("chat_id", message.Chat.ID).
+		Int("length", utf8.RuneCountInString(response)).
+		Msg("Sending reply as a file")
+	return ctx.Bot().Reply(message, document)
+}
+
+func (t *Tellama) setReplyFormat(ctx telebot.Context) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil {
+		return nil
+	}
+
+	if !t.authorize(chat, msg.Sender, msg, permissionChatAdmin) {
+		return ctx.Reply("You do not have permission to use this command.")
+	}
+
+	// Parse the minimum length and the optional code ratio from the command arguments
+	usage := "Usage: /setreplyformat <min length> [code ratio], or /setreplyformat 0 to use the default."
+	args := strings.Fields(msg.Payload)
+	if len(args) == 0 || len(args) > 2 {
+		return ctx.Reply(usage)
+	}
+	minLength, err := strconv.Atoi(args[0])
+	if err != nil || minLength < 0 {
+		return ctx.Reply(usage)
+	}
+	var codeRatio float64
+	if len(args) == 2 {
+		codeRatio, err = strconv.ParseFloat(args[1], 64)
+		if err != nil || codeRatio < 0 || codeRatio > 1 {
+			return ctx.Reply("Please provide a code ratio between 0 and 1.")
+		}
+	}
+
+	if err = t.dm.SetChatReplyFormat(chat.ID, chat.Title, minLength, codeRatio); err != nil {
+		log.Error().Err(err).Msg("Failed to set reply format")
+		return ctx.Reply("Failed to set reply format. Please check logs for details.")
+	}
+
+	log.Info().
+		Int64("chat_id", chat.ID).
+		Int64("user_id", msg.Sender.ID).
+		Int("min_length", minLength).
+		Float64("code_ratio", codeRatio).
+		Msg("Reply format set")
+
+	if minLength == 0 {
+		return ctx.Reply("Reply format reset to the default.")
+	}
+	return ctx.Reply(tr(ctx, "Code-heavy replies of at least %d characters will be sent as files.", minLength))
+}
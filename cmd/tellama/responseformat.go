@@ -0,0 +1,76 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/k4yt3x/tellama/internal/genai"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/telebot.v4"
+)
+
+// supportsResponseFormat returns whether a provider can constrain replies to a response format.
+func supportsResponseFormat(provider genai.Provider) bool {
+	return provider == genai.ProviderOllama || provider == genai.ProviderOpenAI
+}
+
+// setResponseFormat sets the format replies are constrained to in the provider configuration.
+// It is only applied to replies, since summaries and other internal generations must stay free text.
+func setResponseFormat(genaiConfig genai.ProviderConfig, responseFormat string) {
+	switch cfg := genaiConfig.(type) {
+	case *genai.OllamaConfig:
+		cfg.ResponseFormat = responseFormat
+	case *genai.OpenAIConfig:
+		cfg.ResponseFormat = responseFormat
+	}
+}
+
+func (t *Tellama) setResponseFormatCommand(ctx telebot.Context) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil {
+		return nil
+	}
+
+	if !t.authorize(chat, msg.Sender, msg, permissionChatAdmin) {
+		return ctx.Reply("You do not have permission to use this command.")
+	}
+
+	if !supportsResponseFormat(t.genaiProvider) {
+		return ctx.Reply(tr(ctx, "The %s provider does not support response formats.", t.genaiProvider))
+	}
+
+	value := strings.TrimSpace(msg.Payload)
+	switch strings.ToLower(value) {
+	case "off":
+		value = ""
+	case genai.ResponseFormatJSON:
+		value = genai.ResponseFormatJSON
+	case "":
+		return ctx.Reply("Usage: /setresponseformat <json|schema|off>, where schema is a JSON schema object.")
+	default:
+		if err := genai.ValidateResponseFormat(value); err != nil {
+			return ctx.Reply("The response format must be json, off, or a JSON schema object.")
+		}
+	}
+
+	if err := t.dm.SetChatResponseFormat(chat.ID, chat.Title, value); err != nil {
+		log.Error().Err(err).Msg("Failed to set response format")
+		return ctx.Reply("Failed to set response format. Please check logs for details.")
+	}
+
+	log.Info().
+		Int64("chat_id", chat.ID).
+		Int64("user_id", msg.Sender.ID).
+		Str("response_format", value).
+		Msg("Response format set")
+
+	switch value {
+	case "":
+		return ctx.Reply("Replies are no longer constrained to a format.")
+	case genai.ResponseFormatJSON:
+		return ctx.Reply("Replies will be JSON objects.")
+	default:
+		return ctx.Reply("Replies will conform to the JSON schema.")
+	}
+}
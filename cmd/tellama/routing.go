@@ -0,0 +1,104 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/k4yt3x/tellama/internal/database"
+	"github.com/k4yt3x/tellama/internal/genai"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/telebot.v4"
+)
+
+// applyModelRouting switches the model based on the length and complexity of the message.
+// The chat's routing overrides take precedence over the configured routing models.
+func (t *Tellama) applyModelRouting(
+	chatID int64,
+	chatOverride database.ChatOverride,
+	text string,
+	genaiConfig genai.ProviderConfig,
+) genai.ProviderConfig {
+	routingConfig := t.settings().routing
+	if !routingConfig.Enabled || database.IsTrue(chatOverride.RoutingDisabled) {
+		return genaiConfig
+	}
+
+	if chatOverride.RoutingSmallModel != "" {
+		routingConfig.SmallModel = chatOverride.RoutingSmallModel
+	}
+	if chatOverride.RoutingLargeModel != "" {
+		routingConfig.LargeModel = chatOverride.RoutingLargeModel
+	}
+
+	model := routingConfig.Model(text)
+	if model == "" {
+		return genaiConfig
+	}
+
+	setModelName(genaiConfig, model)
+
+	log.Info().
+		Int64("chat_id", chatID).
+		Str("complexity", routingConfig.Classify(text).String()).
+		Str("model", model).
+		Msg("Routing message to model")
+	return genaiConfig
+}
+
+func (t *Tellama) setRouting(ctx telebot.Context) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil {
+		return nil
+	}
+
+	if !t.authorize(chat, msg.Sender, msg, permissionChatAdmin) {
+		return ctx.Reply("You do not have permission to use this command.")
+	}
+
+	// Accept "off", no arguments to reset, or the small and large models with "-" keeping the default
+	var disabled bool
+	var smallModel, largeModel string
+	args := strings.Fields(msg.Payload)
+	switch {
+	case len(args) == 0:
+	case len(args) == 1 && strings.EqualFold(args[0], "off"):
+		disabled = true
+	case len(args) == 2:
+		smallModel = strings.TrimPrefix(args[0], "-")
+		largeModel = strings.TrimPrefix(args[1], "-")
+	default:
+		return ctx.Reply("Usage: /setrouting <small model|-> <large model|->, /setrouting off, or /setrouting to reset.")
+	}
+
+	if err := t.dm.SetChatRouting(chat.ID, chat.Title, disabled, smallModel, largeModel); err != nil {
+		log.Error().Err(err).Msg("Failed to set routing")
+		return ctx.Reply("Failed to set routing. Please check logs for details.")
+	}
+
+	log.Info().
+		Int64("chat_id", chat.ID).
+		Int64("user_id", msg.Sender.ID).
+		Bool("disabled", disabled).
+		Str("small_model", smallModel).
+		Str("large_model", largeModel).
+		Msg("Routing set")
+
+	switch {
+	case disabled:
+		return ctx.Reply("Model routing disabled for this chat.")
+	case smallModel == "" && largeModel == "":
+		return ctx.Reply("Model routing reset to the default.")
+	default:
+		return ctx.Reply(tr(ctx, "Model routing set successfully.\n\nSmall model: %s\nLarge model: %s",
+			defaultIfEmpty(smallModel), defaultIfEmpty(largeModel)))
+	}
+}
+
+// defaultIfEmpty describes an empty model override as the default.
+func defaultIfEmpty(model string) string {
+	if model == "" {
+		return "(default)"
+	}
+	return model
+}
@@ -0,0 +1,360 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/k4yt3x/tellama/internal/database"
+	"github.com/k4yt3x/tellama/internal/markdown"
+	"github.com/k4yt3x/tellama/internal/schedule"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/telebot.v4"
+	"gorm.io/gorm"
+)
+
+// cronFieldCount is the number of fields in a cron expression without a shortcut.
+const cronFieldCount = 5
+
+// scheduleUsage explains the arguments of the /schedule command.
+const scheduleUsage = "Usage: /schedule [name:] <minute> <hour> <day of month> <month> <day of week> <prompt>\n" +
+	"Example: /schedule weekend: 0 17 * * 5 Suggest a plan for the weekend.\n" +
+	"Shortcuts such as @daily can replace the five fields. Times are in UTC."
+
+// maxScheduleNameLength is the maximum length of the name of a scheduled prompt.
+const maxScheduleNameLength = 32
+
+// isScheduleName reports whether the text is a valid scheduled prompt name, which starts with a letter
+// so that it cannot be confused with a prompt ID.
+func isScheduleName(text string) bool {
+	if text == "" || len(text) > maxScheduleNameLength || text[0] < 'a' || text[0] > 'z' {
+		return false
+	}
+	for _, r := range text {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' && r != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// parseScheduleArgs splits the arguments of the /schedule command into the optional name,
+// the cron expression, and the prompt.
+func parseScheduleArgs(payload string) (string, schedule.Spec, string, error) {
+	fields := strings.Fields(payload)
+
+	// The name is given as the first argument followed by a colon
+	var name string
+	if len(fields) > 0 && strings.HasSuffix(fields[0], ":") {
+		name = strings.ToLower(strings.TrimSuffix(fields[0], ":"))
+		if !isScheduleName(name) {
+			return "", schedule.Spec{}, "", errors.New(
+				"names must start with a letter and only contain letters, digits, hyphens, and underscores",
+			)
+		}
+		fields = fields[1:]
+	}
+
+	cronFields := cronFieldCount
+	if len(fields) > 0 && strings.HasPrefix(fields[0], "@") {
+		cronFields = 1
+	}
+	if len(fields) <= cronFields {
+		return "", schedule.Spec{}, "", errors.New("the cron expression and the prompt are required")
+	}
+
+	spec, err := schedule.Parse(strings.Join(fields[:cronFields], " "))
+	if err != nil {
+		return "", schedule.Spec{}, "", err
+	}
+	return name, spec, strings.Join(fields[cronFields:], " "), nil
+}
+
+// findScheduledPrompt returns the scheduled prompt with the given ID or name.
+func findScheduledPrompt(prompts []database.ScheduledPrompt, reference string) (database.ScheduledPrompt, bool) {
+	reference = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(reference), "#"))
+	promptID, err := strconv.ParseUint(reference, 10, 0)
+	for _, prompt := range prompts {
+		if (err == nil && prompt.ID == uint(promptID)) || (prompt.Name != "" && prompt.Name == reference) {
+			return prompt, true
+		}
+	}
+	return database.ScheduledPrompt{}, false
+}
+
+// scheduledPromptLabel returns how a scheduled prompt is referred to in replies.
+func scheduledPromptLabel(prompt database.ScheduledPrompt) string {
+	if prompt.Name != "" {
+		return fmt.Sprintf("#%d (%s)", prompt.ID, prompt.Name)
+	}
+	return fmt.Sprintf("#%d", prompt.ID)
+}
+
+func (t *Tellama) schedulePrompt(ctx telebot.Context) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil {
+		return nil
+	}
+
+	if !t.authorize(chat, msg.Sender, msg, permissionChatAdmin) {
+		return ctx.Reply("You do not have permission to use this command.")
+	}
+
+	name, spec, prompt, err := parseScheduleArgs(msg.Payload)
+	if err != nil {
+		return ctx.Reply(tr(ctx, "Invalid schedule: %v.\n\n%s", err, scheduleUsage))
+	}
+	next, err := spec.Next(time.Now().UTC())
+	if err != nil {
+		return ctx.Reply(tr(ctx, "Invalid schedule: %v.", err))
+	}
+
+	prompts, err := t.dm.GetScheduledPrompts(chat.ID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get scheduled prompts")
+		return ctx.Reply("Failed to schedule the prompt. Please check logs for details.")
+	}
+	if maxPrompts := t.settings().maxScheduledPrompts; len(prompts) >= maxPrompts {
+		return ctx.Reply(tr(ctx,
+			"This chat already has the maximum number of %d scheduled prompts. Use /unschedule to remove one.",
+			maxPrompts,
+		))
+	}
+	if _, exists := findScheduledPrompt(prompts, name); name != "" && exists {
+		return ctx.Reply(tr(ctx, "A prompt named %s is already scheduled in this chat.", name))
+	}
+
+	promptID, err := t.dm.AddScheduledPrompt(database.ScheduledPrompt{
+		ChatID:    chat.ID,
+		ChatTitle: chat.Title,
+		ChatType:  string(chat.Type),
+		ThreadID:  messageThreadID(msg),
+		UserID:    msg.Sender.ID,
+		Username:  msg.Sender.Username,
+		FirstName: msg.Sender.FirstName,
+		LastName:  msg.Sender.LastName,
+		Cron:      spec.String(),
+		Prompt:    prompt,
+		Name:      name,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to add scheduled prompt")
+		return ctx.Reply("Failed to schedule the prompt. Please check logs for details.")
+	}
+
+	log.Info().
+		Int64("chat_id", chat.ID).
+		Int64("user_id", msg.Sender.ID).
+		Uint("prompt_id", promptID).
+		Str("name", name).
+		Str("cron", spec.String()).
+		Msg("Scheduled prompt added")
+
+	return ctx.Reply(tr(ctx,
+		"Scheduled as prompt %s. It will next run at %s.",
+		scheduledPromptLabel(database.ScheduledPrompt{ID: promptID, Name: name}), next.Format(time.DateTime+" MST"),
+	))
+}
+
+func (t *Tellama) unschedulePrompt(ctx telebot.Context) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil {
+		return nil
+	}
+
+	if !t.authorize(chat, msg.Sender, msg, permissionChatAdmin) {
+		return ctx.Reply("You do not have permission to use this command.")
+	}
+
+	if strings.TrimSpace(msg.Payload) == "" {
+		return ctx.Reply(
+			"Please provide the ID or name of the scheduled prompt to remove. " +
+				"Use /schedules to list all scheduled prompts.",
+		)
+	}
+
+	prompts, err := t.dm.GetScheduledPrompts(chat.ID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get scheduled prompts")
+		return ctx.Reply("Failed to remove the scheduled prompt. Please check logs for details.")
+	}
+	prompt, ok := findScheduledPrompt(prompts, msg.Payload)
+	if !ok {
+		return ctx.Reply("No scheduled prompt with this ID or name exists in this chat.")
+	}
+
+	err = t.dm.DeleteScheduledPrompt(chat.ID, prompt.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ctx.Reply("No scheduled prompt with this ID or name exists in this chat.")
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to delete scheduled prompt")
+		return ctx.Reply("Failed to remove the scheduled prompt. Please check logs for details.")
+	}
+
+	log.Info().
+		Int64("chat_id", chat.ID).
+		Int64("user_id", msg.Sender.ID).
+		Uint("prompt_id", prompt.ID).
+		Msg("Scheduled prompt deleted")
+
+	return ctx.Reply(tr(ctx, "Scheduled prompt %s removed.", scheduledPromptLabel(prompt)))
+}
+
+func (t *Tellama) listSchedules(ctx telebot.Context) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil {
+		return nil
+	}
+
+	if !t.authorize(chat, msg.Sender, msg, permissionTrusted) {
+		return ctx.Reply("You do not have permission to use this command.")
+	}
+
+	prompts, err := t.dm.GetScheduledPrompts(chat.ID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get scheduled prompts")
+		return ctx.Reply("Failed to get scheduled prompts. Please check logs for details.")
+	}
+
+	if len(prompts) == 0 {
+		return ctx.Reply("No prompts are scheduled for this chat.")
+	}
+
+	var reply strings.Builder
+	reply.WriteString("Scheduled prompts (times in UTC):\n")
+	for _, prompt := range prompts {
+		reply.WriteString(fmt.Sprintf("\n%s [%s]: %s", scheduledPromptLabel(prompt), prompt.Cron, prompt.Prompt))
+	}
+	return ctx.Reply(reply.String())
+}
+
+// runScheduler runs the scheduled prompts that are due at the start of every minute.
+// Prompts that were due while the bot was offline run once when it starts.
+func (t *Tellama) runScheduler() {
+	for {
+		now := time.Now().UTC()
+		time.Sleep(now.Truncate(time.Minute).Add(time.Minute).Sub(now))
+		t.runDuePrompts(time.Now().UTC())
+	}
+}
+
+// runDuePrompts runs the scheduled prompts whose next run after the last one has passed.
+func (t *Tellama) runDuePrompts(now time.Time) {
+	prompts, err := t.dm.GetAllScheduledPrompts()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get scheduled prompts")
+		return
+	}
+
+	for _, prompt := range prompts {
+		spec, parseErr := schedule.Parse(prompt.Cron)
+		if parseErr != nil {
+			log.Error().Err(parseErr).Uint("prompt_id", prompt.ID).Msg("Invalid scheduled prompt")
+			continue
+		}
+
+		lastRun := prompt.LastRunAt
+		if lastRun.IsZero() {
+			lastRun = prompt.CreatedAt
+		}
+		next, nextErr := spec.Next(lastRun.UTC())
+		if nextErr != nil || next.After(now) {
+			continue
+		}
+
+		// Record the run first so that a failing prompt is not retried every minute
+		if err = t.dm.SetScheduledPromptLastRun(prompt.ID, now); err != nil {
+			log.Error().Err(err).Uint("prompt_id", prompt.ID).Msg("Failed to record scheduled prompt run")
+			continue
+		}
+		if err = t.runScheduledPrompt(prompt); err != nil {
+			log.Error().Err(err).Uint("prompt_id", prompt.ID).Msg("Failed to run scheduled prompt")
+		}
+	}
+}
+
+// runScheduledPrompt generates the response to a scheduled prompt and posts it to the chat.
+func (t *Tellama) runScheduledPrompt(prompt database.ScheduledPrompt) error {
+	if !t.dm.IsChatTrusted(prompt.ChatID) && !t.dm.IsUserTrusted(prompt.UserID) && !t.allowUntrustedChats {
+		log.Warn().Int64("chat_id", prompt.ChatID).Uint("prompt_id", prompt.ID).Msg("Skipped prompt for untrusted chat")
+		return nil
+	}
+
+	chat := &telebot.Chat{ID: prompt.ChatID, Type: telebot.ChatType(prompt.ChatType), Title: prompt.ChatTitle}
+	user := &telebot.User{
+		ID:        prompt.UserID,
+		Username:  prompt.Username,
+		FirstName: prompt.FirstName,
+		LastName:  prompt.LastName,
+	}
+	message := &telebot.Message{
+		Chat:         chat,
+		Sender:       user,
+		Text:         prompt.Prompt,
+		ThreadID:     prompt.ThreadID,
+		TopicMessage: prompt.ThreadID != 0,
+	}
+
+	chatOverride, err := t.dm.GetChatOverride(chat.ID)
+	if err != nil {
+		return err
+	}
+	t.flushMessages()
+	history, err := t.dm.GetMessages(chat.ID, prompt.ThreadID, t.settings().historyFetchLimit)
+	if err != nil {
+		return err
+	}
+
+	if !t.genaiAllowConcurrent {
+		<-t.sem
+		defer func() { t.sem <- struct{}{} }()
+	}
+
+	log.Info().Int64("chat_id", chat.ID).Uint("prompt_id", prompt.ID).Msg("Running scheduled prompt")
+	messages, genaiConfig, genaiClient, err := t.prepareGeneration(
+		chat,
+		user,
+		message,
+		prompt.Prompt,
+		database.ContentTypeText,
+		nil,
+		history,
+		chatOverride,
+	)
+	if err != nil {
+		return err
+	}
+
+	gen, err := t.generateResponse(messages, genaiClient)
+	if err != nil {
+		return err
+	}
+	t.recordUsage(chat, user, modelName(genaiConfig), gen.Stats)
+	if gen.Response == "" {
+		log.Warn().Uint("prompt_id", prompt.ID).Msg("Received empty response to scheduled prompt")
+		return nil
+	}
+
+	reply := t.applyStatsFooter(chat, genaiConfig, gen.Stats, applyModelBadge(chatOverride, genaiConfig, gen.Response))
+	sendOptions := &telebot.SendOptions{ParseMode: telebot.ModeMarkdownV2, ThreadID: prompt.ThreadID}
+	sent, err := t.bot.Send(chat, markdown.ToMarkdownV2(reply), sendOptions)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to send scheduled prompt response with MarkdownV2 formatting")
+
+		// Retry sending the response without Markdown formatting
+		sendOptions.ParseMode = telebot.ModeDefault
+		if sent, err = t.bot.Send(chat, reply, sendOptions); err != nil {
+			return err
+		}
+	}
+
+	_, err = t.storeBotResponse(chat, sent, gen.Response)
+	return err
+}
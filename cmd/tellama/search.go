@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/telebot.v4"
+)
+
+// searchResultLimit is the number of messages returned by /search.
+const searchResultLimit = 10
+
+// messageLink returns the link to a message in a group, supergroup, or channel.
+// It returns an empty string for chats whose messages cannot be linked to, such as private chats.
+func messageLink(chat *telebot.Chat, messageID int) string {
+	if messageID == 0 {
+		return ""
+	}
+	if chat.Username != "" && chat.Type != telebot.ChatPrivate {
+		return fmt.Sprintf("https://t.me/%s/%d", chat.Username, messageID)
+	}
+	if chat.Type == telebot.ChatSuperGroup || chat.Type == telebot.ChatChannel {
+		// Private supergroups and channels are linked to by their ID without the -100 prefix
+		internalID, found := strings.CutPrefix(strconv.FormatInt(chat.ID, 10), "-100")
+		if found {
+			return fmt.Sprintf("https://t.me/c/%s/%d", internalID, messageID)
+		}
+	}
+	return ""
+}
+
+func (t *Tellama) searchMessages(ctx telebot.Context) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil {
+		return nil
+	}
+
+	if !t.authorize(chat, msg.Sender, msg, permissionUntrusted) {
+		return ctx.Reply("You do not have permission to use this command.")
+	}
+
+	query := strings.TrimSpace(msg.Payload)
+	if query == "" {
+		return ctx.Reply("Usage: /search <terms>")
+	}
+
+	results, err := t.dm.SearchMessages(chat.ID, query, searchResultLimit)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to search messages")
+		return ctx.Reply("Failed to search messages. Please check logs for details.")
+	}
+
+	if len(results) == 0 {
+		return ctx.Reply(tr(ctx, "No messages match \"%s\".", query))
+	}
+
+	var reply strings.Builder
+	reply.WriteString(tr(ctx, "Messages matching \"%s\":", query))
+	for i, result := range results {
+		name := result.FirstName
+		if result.Role == "assistant" {
+			name = t.bot.Me.FirstName
+		}
+		reply.WriteString(fmt.Sprintf(
+			"\n\n%d. %s, %s: %s",
+			i+1, result.Timestamp.UTC().Format("2006-01-02 15:04"), name, result.Excerpt,
+		))
+		if link := messageLink(chat, result.TelegramMessageID); link != "" {
+			reply.WriteString("\n" + link)
+		}
+	}
+	return ctx.Reply(reply.String(), telebot.NoPreview)
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/k4yt3x/tellama/internal/config"
+	"github.com/k4yt3x/tellama/internal/database"
+	"github.com/k4yt3x/tellama/internal/genai"
+	"github.com/k4yt3x/tellama/internal/rag"
+
+	"github.com/rs/zerolog/log"
+)
+
+// getContextHistory returns the past messages of a chat thread included in the context of a response to text.
+// With the semantic context strategy, these are the most recent messages and the older ones most similar to
+// the text. It falls back to the most recent messages if the similarity cannot be computed.
+func (t *Tellama) getContextHistory(chatID int64, threadID int, text string) ([]database.Message, error) {
+	if t.settings().contextStrategy != config.ContextSemantic || strings.TrimSpace(text) == "" || t.isDegraded() {
+		return t.getHistory(chatID, threadID)
+	}
+
+	messages, err := t.semanticHistory(chatID, threadID, text)
+	if err != nil {
+		log.Warn().Err(err).Int64("chat_id", chatID).Msg("Failed to select relevant history, using recent messages")
+		return t.getHistory(chatID, threadID)
+	}
+	return messages, nil
+}
+
+// semanticHistory returns the most recent messages of a chat thread and the older candidates most similar to
+// the text, oldest first. Candidates are embedded the first time they are searched and their embeddings stored.
+func (t *Tellama) semanticHistory(chatID int64, threadID int, text string) ([]database.Message, error) {
+	semantic := t.settings().semanticContext
+	t.flushMessages()
+
+	candidates, err := t.dm.GetMessages(chatID, threadID, semantic.Candidates)
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) <= semantic.RecentMessages+semantic.TopK {
+		return candidates, nil
+	}
+	older := candidates[:len(candidates)-semantic.RecentMessages]
+	recent := candidates[len(older):]
+
+	embedder, err := t.newEmbedder()
+	if err != nil {
+		return nil, err
+	}
+	embeddings, err := t.messageEmbeddings(chatID, older, embedder)
+	if err != nil {
+		return nil, err
+	}
+
+	queryEmbeddings, err := embedder.Embed([]string{text})
+	if err != nil {
+		return nil, err
+	}
+	if len(queryEmbeddings) != 1 {
+		return nil, errors.New("provider returned an unexpected number of embeddings")
+	}
+
+	matches := rag.TopK(queryEmbeddings[0], embeddings, semantic.TopK, semantic.MinScore)
+	indices := make([]int, len(matches))
+	for i, match := range matches {
+		indices[i] = match.Index
+	}
+	slices.Sort(indices)
+
+	history := make([]database.Message, 0, len(indices)+len(recent))
+	for _, index := range indices {
+		history = append(history, older[index])
+	}
+	history = append(history, recent...)
+
+	log.Debug().
+		Int64("chat_id", chatID).
+		Int("relevant", len(indices)).
+		Int("recent", len(recent)).
+		Msg("Selected semantic history")
+	return history, nil
+}
+
+// messageEmbeddings returns the embeddings of messages in their order, computing and storing the missing ones.
+// Messages without text, such as system messages, have no embedding.
+func (t *Tellama) messageEmbeddings(
+	chatID int64,
+	messages []database.Message,
+	embedder genai.Embedder,
+) ([][]float32, error) {
+	model := t.rag.EmbeddingModel
+	messageIDs := make([]uint, len(messages))
+	for i, message := range messages {
+		messageIDs[i] = message.ID
+	}
+	stored, err := t.dm.GetMessageEmbeddings(messageIDs, model)
+	if err != nil {
+		return nil, err
+	}
+
+	embeddings := make([][]float32, len(messages))
+	var missing []int
+	var texts []string
+	for i, message := range messages {
+		if message.ContentType == database.ContentTypeSystem || strings.TrimSpace(message.Content) == "" {
+			continue
+		}
+		data, exists := stored[message.ID]
+		if !exists {
+			missing = append(missing, i)
+			texts = append(texts, message.Content)
+			continue
+		}
+		embeddings[i], err = rag.DecodeEmbedding(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(missing) == 0 {
+		return embeddings, nil
+	}
+
+	computed, err := embedTexts(embedder, texts)
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]database.MessageEmbedding, len(missing))
+	for i, index := range missing {
+		embeddings[index] = computed[i]
+		rows[i] = database.MessageEmbedding{
+			MessageID: messages[index].ID,
+			ChatID:    chatID,
+			Model:     model,
+			Embedding: rag.EncodeEmbedding(computed[i]),
+		}
+	}
+	if err = t.dm.StoreMessageEmbeddings(rows); err != nil {
+		return nil, err
+	}
+
+	log.Debug().Int64("chat_id", chatID).Int("messages", len(missing)).Msg("Embedded messages")
+	return embeddings, nil
+}
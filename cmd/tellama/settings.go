@@ -0,0 +1,380 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/k4yt3x/tellama/internal/config"
+	"github.com/k4yt3x/tellama/internal/database"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/telebot.v4"
+)
+
+const (
+	// settingsUnique identifies the callbacks of the settings menu buttons.
+	settingsUnique = "settings"
+
+	// settingsModelsPerPage is the number of models listed on each page of the model menu.
+	settingsModelsPerPage = 8
+)
+
+// Actions of the settings menu buttons.
+const (
+	settingsActionMenu         = "menu"
+	settingsActionClose        = "close"
+	settingsActionModels       = "models"
+	settingsActionTemperature  = "temperature"
+	settingsActionBadge        = "badge"
+	settingsActionTrigger      = "trigger"
+	settingsActionSetModel     = "setmodel"
+	settingsActionSetTemp      = "settemp"
+	settingsActionSetBadge     = "setbadge"
+	settingsActionSetTrigger   = "settrigger"
+	settingsActionToggleMemory = "togglememory"
+)
+
+// settingsButton returns a settings menu button that performs the action when pressed.
+func settingsButton(markup *telebot.ReplyMarkup, text string, action string, value string) telebot.Btn {
+	return markup.Data(text, settingsUnique, action, value)
+}
+
+// settingsBackRow returns the row that leads back to the main settings menu.
+func settingsBackRow(ctx telebot.Context, markup *telebot.ReplyMarkup) telebot.Row {
+	return markup.Row(settingsButton(markup, tr(ctx, "Back"), settingsActionMenu, ""))
+}
+
+func (t *Tellama) settingsCommand(ctx telebot.Context) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil {
+		return nil
+	}
+
+	if !t.authorize(chat, msg.Sender, msg, permissionChatAdmin) {
+		return ctx.Reply("You do not have permission to use this command.")
+	}
+
+	chatOverride, err := t.dm.GetChatOverride(chat.ID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get chat override")
+		return ctx.Reply("Failed to get settings. Please check logs for details.")
+	}
+
+	text, markup, err := t.settingsMenu(ctx, chatOverride)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to build settings menu")
+		return ctx.Reply("Failed to get settings. Please check logs for details.")
+	}
+	return ctx.Reply(text, markup)
+}
+
+// handleSettingsCallback navigates the settings menu and applies the selected settings.
+func (t *Tellama) handleSettingsCallback(ctx telebot.Context) error {
+	chat := ctx.Chat()
+	user := ctx.Sender()
+	msg := ctx.Message()
+	if chat == nil || user == nil || msg == nil {
+		return ctx.Respond()
+	}
+
+	// Any member can press the buttons, so the permissions are checked for every press
+	if !t.authorize(chat, user, msg, permissionChatAdmin) {
+		return ctx.Respond(&telebot.CallbackResponse{
+			Text:      tr(ctx, "You do not have permission to use this command."),
+			ShowAlert: true,
+		})
+	}
+
+	args := ctx.Args()
+	action, value := args[0], ""
+	if len(args) > 1 {
+		value = args[1]
+	}
+	if action == settingsActionClose {
+		if err := t.bot.Delete(msg); err != nil {
+			log.Warn().Err(err).Int64("chat_id", chat.ID).Msg("Failed to delete settings menu")
+		}
+		return ctx.Respond()
+	}
+
+	chatOverride, err := t.dm.GetChatOverride(chat.ID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get chat override")
+		return ctx.Respond(&telebot.CallbackResponse{
+			Text:      tr(ctx, "Failed to get settings. Please check logs for details."),
+			ShowAlert: true,
+		})
+	}
+
+	var text string
+	var markup *telebot.ReplyMarkup
+	response := &telebot.CallbackResponse{}
+	switch action {
+	case settingsActionMenu:
+		text, markup, err = t.settingsMenu(ctx, chatOverride)
+	case settingsActionModels:
+		page, _ := strconv.Atoi(value)
+		text, markup, err = t.settingsModelsMenu(ctx, chat.ID, chatOverride, page)
+	case settingsActionTemperature:
+		text, markup = t.settingsTemperatureMenu(ctx)
+	case settingsActionBadge:
+		text, markup = t.settingsBadgeMenu(ctx)
+	case settingsActionTrigger:
+		text, markup = t.settingsTriggerMenu(ctx)
+	default:
+		// Every other action changes a setting and returns to the main menu
+		if err = t.applySetting(chat, chatOverride, action, value); err != nil {
+			log.Error().Err(err).Str("action", action).Msg("Failed to update settings")
+			return ctx.Respond(&telebot.CallbackResponse{
+				Text:      tr(ctx, "Failed to update settings. Please check logs for details."),
+				ShowAlert: true,
+			})
+		}
+
+		log.Info().
+			Int64("chat_id", chat.ID).
+			Int64("user_id", user.ID).
+			Str("action", action).
+			Str("value", value).
+			Msg("Settings updated")
+		response.Text = tr(ctx, "Settings updated.")
+
+		if chatOverride, err = t.dm.GetChatOverride(chat.ID); err == nil {
+			text, markup, err = t.settingsMenu(ctx, chatOverride)
+		}
+	}
+	if err != nil {
+		log.Error().Err(err).Str("action", action).Msg("Failed to build settings menu")
+		return ctx.Respond(&telebot.CallbackResponse{
+			Text:      tr(ctx, "Failed to get settings. Please check logs for details."),
+			ShowAlert: true,
+		})
+	}
+
+	err = ctx.Edit(text, markup)
+	if err != nil && !errors.Is(err, telebot.ErrSameMessageContent) && !errors.Is(err, telebot.ErrMessageNotModified) {
+		log.Error().Err(err).Msg("Failed to edit settings menu")
+	}
+	return ctx.Respond(response)
+}
+
+// settingsMenu returns the main settings menu showing the current settings of the chat.
+func (t *Tellama) settingsMenu(
+	ctx telebot.Context,
+	chatOverride database.ChatOverride,
+) (string, *telebot.ReplyMarkup, error) {
+	genaiConfig, err := t.applyChatOverride(chatOverride)
+	if err != nil {
+		return "", nil, err
+	}
+
+	temperature := tr(ctx, "Default")
+	if value, ok := optionValue(chatOverride.Options, "temperature"); ok {
+		temperature = fmt.Sprint(value)
+	}
+	memory := tr(ctx, "On")
+	if database.IsTrue(chatOverride.MemoryDisabled) {
+		memory = tr(ctx, "Off")
+	}
+
+	markup := &telebot.ReplyMarkup{}
+	markup.Inline(
+		markup.Row(settingsButton(markup, tr(ctx, "Model: %s", modelName(genaiConfig)), settingsActionModels, "0")),
+		markup.Row(settingsButton(markup, tr(ctx, "Temperature: %s", temperature), settingsActionTemperature, "")),
+		markup.Row(settingsButton(
+			markup,
+			tr(ctx, "Model badge: %s", badgeLabel(ctx, chatOverride.ModelBadge)),
+			settingsActionBadge,
+			"",
+		)),
+		markup.Row(settingsButton(
+			markup,
+			tr(ctx, "Trigger: %s", triggerLabel(ctx, chatOverride.Trigger)),
+			settingsActionTrigger,
+			"",
+		)),
+		markup.Row(settingsButton(markup, tr(ctx, "Memory: %s", memory), settingsActionToggleMemory, "")),
+		markup.Row(settingsButton(markup, tr(ctx, "Close"), settingsActionClose, "")),
+	)
+	return tr(ctx, "Settings of this chat. Select a setting to change it."), markup, nil
+}
+
+// settingsModelsMenu returns a page of the menu for selecting the model of the chat.
+func (t *Tellama) settingsModelsMenu(
+	ctx telebot.Context,
+	chatID int64,
+	chatOverride database.ChatOverride,
+	page int,
+) (string, *telebot.ReplyMarkup, error) {
+	models, err := t.listModels(chatID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	pages := max((len(models)+settingsModelsPerPage-1)/settingsModelsPerPage, 1)
+	page = min(max(page, 0), pages-1)
+
+	markup := &telebot.ReplyMarkup{}
+	var rows []telebot.Row
+	for index := page * settingsModelsPerPage; index < min((page+1)*settingsModelsPerPage, len(models)); index++ {
+		label := models[index]
+		if models[index] == chatOverride.Model {
+			label = "✓ " + label
+		}
+		rows = append(rows, markup.Row(settingsButton(markup, label, settingsActionSetModel, strconv.Itoa(index))))
+	}
+
+	var navigation []telebot.Btn
+	if page > 0 {
+		navigation = append(navigation, settingsButton(markup, "◀", settingsActionModels, strconv.Itoa(page-1)))
+	}
+	if page < pages-1 {
+		navigation = append(navigation, settingsButton(markup, "▶", settingsActionModels, strconv.Itoa(page+1)))
+	}
+	if len(navigation) > 0 {
+		rows = append(rows, markup.Row(navigation...))
+	}
+
+	rows = append(rows,
+		markup.Row(settingsButton(markup, tr(ctx, "Default model"), settingsActionSetModel, "")),
+		settingsBackRow(ctx, markup),
+	)
+	markup.Inline(rows...)
+	return tr(ctx, "Select the model of this chat (page %d of %d).", page+1, pages), markup, nil
+}
+
+// settingsTemperatureMenu returns the menu for selecting the sampling temperature of the chat.
+func (t *Tellama) settingsTemperatureMenu(ctx telebot.Context) (string, *telebot.ReplyMarkup) {
+	markup := &telebot.ReplyMarkup{}
+	var buttons []telebot.Btn
+	for _, temperature := range []string{"0", "0.3", "0.7", "1", "1.5"} {
+		buttons = append(buttons, settingsButton(markup, temperature, settingsActionSetTemp, temperature))
+	}
+
+	markup.Inline(
+		markup.Row(buttons...),
+		markup.Row(settingsButton(markup, tr(ctx, "Default"), settingsActionSetTemp, "")),
+		settingsBackRow(ctx, markup),
+	)
+	return tr(ctx, "Select the sampling temperature. Lower values give more focused replies, "+
+		"higher values more varied ones."), markup
+}
+
+// settingsBadgeMenu returns the menu for selecting the position of the model name badge.
+func (t *Tellama) settingsBadgeMenu(ctx telebot.Context) (string, *telebot.ReplyMarkup) {
+	markup := &telebot.ReplyMarkup{}
+	var rows []telebot.Row
+	for _, modelBadge := range []string{"", modelBadgePrefix, modelBadgeSuffix} {
+		rows = append(rows, markup.Row(
+			settingsButton(markup, badgeLabel(ctx, modelBadge), settingsActionSetBadge, modelBadge),
+		))
+	}
+
+	markup.Inline(append(rows, settingsBackRow(ctx, markup))...)
+	return tr(ctx, "Select where the name of the model is shown in replies."), markup
+}
+
+// settingsTriggerMenu returns the menu for selecting which group messages trigger a response.
+func (t *Tellama) settingsTriggerMenu(ctx telebot.Context) (string, *telebot.ReplyMarkup) {
+	triggers := []string{"", config.TriggerPrefix.String(), config.TriggerMentionAnywhere.String()}
+
+	// The keywords mode behaves like the mention mode unless keywords are configured
+	if len(t.settings().triggerKeywords) > 0 {
+		triggers = append(triggers, config.TriggerKeywords.String())
+	}
+
+	markup := &telebot.ReplyMarkup{}
+	var rows []telebot.Row
+	for _, trigger := range triggers {
+		rows = append(rows, markup.Row(
+			settingsButton(markup, triggerLabel(ctx, trigger), settingsActionSetTrigger, trigger),
+		))
+	}
+
+	markup.Inline(append(rows, settingsBackRow(ctx, markup))...)
+	return tr(ctx, "Select which group messages get a response. Replies to the bot always get a response."), markup
+}
+
+// applySetting changes the setting of the chat selected in the settings menu.
+func (t *Tellama) applySetting(
+	chat *telebot.Chat,
+	chatOverride database.ChatOverride,
+	action string,
+	value string,
+) error {
+	switch action {
+	case settingsActionSetModel:
+		// Models are referenced by their index because callback data is limited to 64 bytes
+		model := ""
+		if value != "" {
+			models, err := t.listModels(chat.ID)
+			if err != nil {
+				return err
+			}
+			index, err := strconv.Atoi(value)
+			if err != nil || index < 0 || index >= len(models) {
+				return errors.New("the selected model is no longer available")
+			}
+			model = models[index]
+		}
+		return t.dm.SetChatModel(chat.ID, chat.Title, model)
+	case settingsActionSetTemp:
+		var temperature any
+		if value != "" {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return err
+			}
+			temperature = parsed
+		}
+		options, err := withOption(chatOverride.Options, "temperature", temperature)
+		if err != nil {
+			return err
+		}
+		return t.dm.SetChatOptions(chat.ID, chat.Title, options)
+	case settingsActionSetBadge:
+		if value != "" && value != modelBadgePrefix && value != modelBadgeSuffix {
+			return fmt.Errorf("unknown model badge position %q", value)
+		}
+		return t.dm.SetChatModelBadge(chat.ID, chat.Title, value)
+	case settingsActionSetTrigger:
+		if value != "" {
+			if _, err := config.ParseTriggerMode(value); err != nil {
+				return err
+			}
+		}
+		return t.dm.SetChatTrigger(chat.ID, chat.Title, value)
+	case settingsActionToggleMemory:
+		return t.dm.SetChatMemoryDisabled(chat.ID, chat.Title, !database.IsTrue(chatOverride.MemoryDisabled))
+	default:
+		return fmt.Errorf("unknown settings action %q", action)
+	}
+}
+
+// badgeLabel returns the label of a model badge position.
+func badgeLabel(ctx telebot.Context, modelBadge string) string {
+	switch modelBadge {
+	case modelBadgePrefix:
+		return tr(ctx, "Before the reply")
+	case modelBadgeSuffix:
+		return tr(ctx, "After the reply")
+	default:
+		return tr(ctx, "Off")
+	}
+}
+
+// triggerLabel returns the label of a trigger mode.
+func triggerLabel(ctx telebot.Context, trigger string) string {
+	switch trigger {
+	case config.TriggerPrefix.String():
+		return tr(ctx, "Messages starting with a mention")
+	case config.TriggerMentionAnywhere.String():
+		return tr(ctx, "Messages with a mention")
+	case config.TriggerKeywords.String():
+		return tr(ctx, "Mentions and keywords")
+	default:
+		return tr(ctx, "Default")
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/telebot.v4"
+)
+
+// startPayloadChatPrefix marks deep-link payloads that refer to a chat, such as t.me/bot?start=chat_-100123.
+const startPayloadChatPrefix = "chat_"
+
+const defaultStartMessage = `Hi {{.FirstName}}! I'm {{.BotName}}.
+{{if .GroupTitle}}You came here from {{.GroupTitle}}.
+{{end}}{{if .Allowed}}Send me a message to start chatting.{{else}}This chat has not been enabled yet.{{end}}`
+
+// renderStartMessage renders the onboarding message template for a /start command.
+func (t *Tellama) renderStartMessage(user *telebot.User, payload string, allowed bool) (string, error) {
+	startTemplateString := defaultStartMessage
+	if t.settings().responseMessages.Start != "" {
+		startTemplateString = t.settings().responseMessages.Start
+	}
+
+	startTemplate, err := template.New("start").Parse(startTemplateString)
+	if err != nil {
+		return "", err
+	}
+
+	data := map[string]any{
+		"FirstName":   user.FirstName,
+		"LastName":    user.LastName,
+		"Username":    user.Username,
+		"BotName":     t.bot.Me.FirstName,
+		"BotUsername": t.bot.Me.Username,
+		"Payload":     payload,
+		"Allowed":     allowed,
+	}
+
+	// Resolve the chat referenced by a deep link, such as a group invite
+	if chatID, ok := strings.CutPrefix(payload, startPayloadChatPrefix); ok {
+		var id int64
+		id, err = strconv.ParseInt(chatID, 10, 64)
+		if err == nil {
+			var chat *telebot.Chat
+			chat, err = t.bot.ChatByID(id)
+			if err != nil {
+				log.Warn().Err(err).Int64("chat_id", id).Msg("Failed to resolve deep-link chat")
+			} else {
+				data["GroupID"] = chat.ID
+				data["GroupTitle"] = chat.Title
+			}
+		}
+	}
+
+	var message bytes.Buffer
+	if err = startTemplate.Execute(&message, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(message.String()), nil
+}
+
+func (t *Tellama) start(ctx telebot.Context) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil || msg.Sender == nil {
+		return nil
+	}
+
+	// The onboarding message is only meant for private chats
+	if chat.Type != telebot.ChatPrivate {
+		return nil
+	}
+
+	payload := strings.TrimSpace(msg.Payload)
+
+	// Continue a group conversation handed off to the private chat
+	if messageID, ok := strings.CutPrefix(payload, startPayloadHandoffPrefix); ok {
+		return t.handoff(ctx, messageID)
+	}
+
+	allowed := t.authorize(chat, msg.Sender, msg, permissionTrusted)
+
+	message, err := t.renderStartMessage(msg.Sender, payload, allowed)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to render start message")
+		return ctx.Reply(t.settings().responseMessages.InternalError)
+	}
+
+	log.Info().
+		Int64("chat_id", chat.ID).
+		Int64("user_id", msg.Sender.ID).
+		Str("payload", payload).
+		Msg("Sent start message")
+
+	return ctx.Send(message)
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/k4yt3x/tellama/internal/database"
+	"github.com/k4yt3x/tellama/internal/genai"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/telebot.v4"
+)
+
+// backfillBatchSize is the number of updates fetched per getUpdates request while backfilling
+const backfillBatchSize = 100
+
+// trackUpdate records the last message received in each chat.
+// It is used as a poller filter and never drops updates.
+func (t *Tellama) trackUpdate(update *telebot.Update) bool {
+	msg := update.Message
+	if msg == nil || msg.Chat == nil {
+		return true
+	}
+
+	if err := t.dm.UpdateChatState(msg.Chat.ID, msg.Chat.Title, msg.ID, update.ID); err != nil {
+		log.Error().Err(err).Int64("chat_id", msg.Chat.ID).Msg("Failed to update chat state")
+	}
+	return true
+}
+
+// backfillMissedUpdates stores the messages received while the bot was offline
+// in the chat history without generating responses, then advances the poller
+// past them so they are not processed again.
+func (t *Tellama) backfillMissedUpdates() error {
+	lastUpdateID, err := t.dm.GetLastUpdateID()
+	if err != nil {
+		return err
+	}
+
+	backfilled := 0
+	for {
+		var data []byte
+		data, err = t.bot.Raw("getUpdates", map[string]string{
+			"offset":  strconv.Itoa(lastUpdateID + 1),
+			"limit":   strconv.Itoa(backfillBatchSize),
+			"timeout": "0",
+		})
+		if err != nil {
+			return err
+		}
+
+		var response struct {
+			Result []telebot.Update
+		}
+		if err = json.Unmarshal(data, &response); err != nil {
+			return err
+		}
+
+		for i := range response.Result {
+			update := &response.Result[i]
+			lastUpdateID = update.ID
+			t.trackUpdate(update)
+			if t.backfillMessage(update.Message) {
+				backfilled++
+			}
+		}
+
+		if len(response.Result) < backfillBatchSize {
+			break
+		}
+	}
+
+	t.poller.LastUpdateID = lastUpdateID
+	log.Info().Int("messages", backfilled).Int("last_update_id", lastUpdateID).Msg("Backfilled missed messages")
+	return nil
+}
+
+// backfillMessage stores a missed message in the history if it would have been stored when online.
+func (t *Tellama) backfillMessage(msg *telebot.Message) bool {
+	if msg == nil || msg.Chat == nil || msg.Sender == nil || !t.dm.IsChatTrusted(msg.Chat.ID) {
+		return false
+	}
+
+	text := msg.Text
+	contentType := database.ContentTypeText
+	if msg.Photo != nil {
+		text = msg.Caption
+		contentType = database.ContentTypePhotoCaption
+	}
+
+	// Skip commands and commented messages
+	if text == "" || strings.HasPrefix(text, "/") || strings.HasPrefix(text, "//") {
+		return false
+	}
+
+	return t.storeUserMessage(msg.Chat, msg.Sender, msg, text, contentType, true) == nil
+}
+
+func (t *Tellama) status(ctx telebot.Context) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil {
+		return nil
+	}
+
+	if !t.authorize(chat, msg.Sender, msg, permissionTrusted) {
+		return ctx.Reply("You do not have permission to use this command.")
+	}
+
+	state, err := t.dm.GetChatState(chat.ID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get chat state")
+		return ctx.Reply("Failed to get status. Please check logs for details.")
+	}
+
+	chatOverride, err := t.dm.GetChatOverride(chat.ID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get chat override")
+		return ctx.Reply("Failed to get status. Please check logs for details.")
+	}
+
+	genaiConfig, err := t.applyChatOverride(chatOverride)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to apply chat override")
+		return ctx.Reply("Failed to get status. Please check logs for details.")
+	}
+
+	var reply strings.Builder
+	reply.WriteString("Status:\n")
+	reply.WriteString(fmt.Sprintf("\nProvider: %s", t.genaiProvider))
+	reply.WriteString(fmt.Sprintf("\nModel: %s", modelName(genaiConfig)))
+	var genaiClient genai.GenerativeAI
+	if genaiClient, err = genai.New(t.genaiProvider, genaiConfig); err == nil {
+		capabilities := strings.Join(t.capabilities(genaiClient).Names(), ", ")
+		if capabilities == "" {
+			capabilities = "none"
+		}
+		reply.WriteString(fmt.Sprintf("\nCapabilities: %s", capabilities))
+	}
+	reply.WriteString(fmt.Sprintf("\nLast processed message ID: %d", state.LastMessageID))
+	if !state.UpdatedAt.IsZero() {
+		reply.WriteString(fmt.Sprintf("\nLast activity: %s", state.UpdatedAt.UTC().Format(time.DateTime+" MST")))
+	}
+	return ctx.Reply(reply.String())
+}
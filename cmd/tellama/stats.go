@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/k4yt3x/tellama/internal/genai"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/telebot.v4"
+)
+
+// lastGeneration is the model and statistics of the most recent generation in a chat.
+type lastGeneration struct {
+	Model string
+	Stats genai.GenerateStats
+	Time  time.Time
+}
+
+// recordLastGeneration keeps the statistics of a generation for /laststats.
+func (t *Tellama) recordLastGeneration(chatID int64, model string, genStats genai.GenerateStats) {
+	t.lastGenerationsMutex.Lock()
+	defer t.lastGenerationsMutex.Unlock()
+	t.lastGenerations[chatID] = lastGeneration{Model: model, Stats: genStats, Time: time.Now()}
+}
+
+// applyStatsFooter adds the model, token counts, and duration of the generation to the reply text
+// in the chats listed in telegram.stats_footer_chat_ids. The stored response does not include the footer.
+func (t *Tellama) applyStatsFooter(
+	chat *telebot.Chat,
+	genaiConfig genai.ProviderConfig,
+	genStats genai.GenerateStats,
+	response string,
+) string {
+	if !slices.Contains(t.settings().statsFooterChatIDs, chat.ID) {
+		return response
+	}
+
+	fields := []string{}
+	if model := modelName(genaiConfig); model != "" {
+		fields = append(fields, model)
+	}
+	fields = append(fields,
+		fmt.Sprintf("%d → %d tokens", max(genStats.PromptTokens, 0), max(genStats.TokenCount, 0)))
+	if genStats.TotalDuration > 0 {
+		fields = append(fields, genStats.TotalDuration.Round(time.Millisecond).String())
+	}
+	return response + "\n\n" + strings.Join(fields, " · ")
+}
+
+// formatDuration formats a generation duration, which providers report as negative if it is unknown.
+func formatDuration(duration time.Duration) string {
+	if duration < 0 {
+		return "n/a"
+	}
+	return duration.Round(time.Millisecond).String()
+}
+
+// lastStats replies with the statistics of the last generation in the current chat.
+func (t *Tellama) lastStats(ctx telebot.Context) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil {
+		return nil
+	}
+
+	if !t.authorize(chat, msg.Sender, msg, permissionChatAdmin) {
+		return ctx.Reply("You do not have permission to use this command.")
+	}
+
+	t.lastGenerationsMutex.Lock()
+	last, ok := t.lastGenerations[chat.ID]
+	t.lastGenerationsMutex.Unlock()
+	if !ok {
+		return ctx.Reply("No response has been generated in this chat since the bot started.")
+	}
+
+	log.Info().
+		Int64("chat_id", chat.ID).
+		Int64("user_id", msg.Sender.ID).
+		Msg("Getting last generation stats")
+
+	stats := last.Stats
+	var reply strings.Builder
+	reply.WriteString("Last generation:\n")
+	reply.WriteString(fmt.Sprintf("\nTime: %s", last.Time.UTC().Format(time.DateTime)))
+	reply.WriteString(fmt.Sprintf("\nModel: %s", last.Model))
+	if stats.DoneReason != "" {
+		reply.WriteString(fmt.Sprintf("\nDone reason: %s", stats.DoneReason))
+	}
+	reply.WriteString(fmt.Sprintf("\nPrompt tokens: %d", max(stats.PromptTokens, 0)))
+	reply.WriteString(fmt.Sprintf("\nCompletion tokens: %d", max(stats.TokenCount, 0)))
+	reply.WriteString(fmt.Sprintf("\nTotal duration: %s", formatDuration(stats.TotalDuration)))
+	reply.WriteString(fmt.Sprintf("\nLoad duration: %s", formatDuration(stats.LoadDuration)))
+	reply.WriteString(fmt.Sprintf("\nPrompt evaluation: %s", formatDuration(stats.PromptEvalDuration)))
+	reply.WriteString(fmt.Sprintf("\nGeneration: %s", formatDuration(stats.EvalDuration)))
+	if stats.EvalDuration > 0 && stats.TokenCount > 0 {
+		reply.WriteString(fmt.Sprintf("\nSpeed: %.1f tokens/s", float64(stats.TokenCount)/stats.EvalDuration.Seconds()))
+	}
+	return ctx.Reply(reply.String())
+}
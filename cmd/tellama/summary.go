@@ -0,0 +1,141 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/k4yt3x/tellama/internal/database"
+	"github.com/k4yt3x/tellama/internal/genai"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/telebot.v4"
+)
+
+const summaryPrompt = `You are summarizing a Telegram chat transcript.
+Write a concise summary of the conversation that preserves the topics discussed,
+the facts, decisions, and preferences stated by participants, and any open questions.
+Refer to participants by name. Respond with the summary only.`
+
+// formatTranscript renders messages as a plain text transcript.
+func formatTranscript(messages []database.Message) string {
+	var transcript strings.Builder
+	for _, message := range tagMessageContents(messages) {
+		speaker := message.Role
+		if message.Role == "user" {
+			speaker = strings.TrimSpace(message.FirstName + " " + message.LastName)
+			if message.Username != "" {
+				speaker += " (@" + message.Username + ")"
+			}
+		}
+		fmt.Fprintf(&transcript, "[%s] %s: %s\n",
+			message.Timestamp.UTC().Format("2006-01-02 15:04"),
+			speaker,
+			message.Content,
+		)
+	}
+	return transcript.String()
+}
+
+// summarizeMessages generates a summary of the given messages using the summarization prompt.
+func (t *Tellama) summarizeMessages(
+	messages []database.Message,
+	genaiClient genai.GenerativeAI,
+) (string, genai.GenerateStats, error) {
+	if len(messages) == 0 {
+		return "", genai.GenerateStats{}, errors.New("no messages to summarize")
+	}
+
+	gen, err := t.generateResponse([]database.Message{
+		{Role: "system", Content: summaryPrompt},
+		{Role: "user", Content: formatTranscript(messages)},
+	}, genaiClient)
+	return gen.Response, gen.Stats, err
+}
+
+// prependChatSummary adds the latest stored conversation summary to the beginning of the history.
+func (t *Tellama) prependChatSummary(
+	chatID int64,
+	threadID int,
+	messages []database.Message,
+) ([]database.Message, error) {
+	summary, err := t.dm.GetLatestChatSummary(chatID, threadID)
+	if err != nil {
+		return nil, err
+	}
+	if summary.Content == "" {
+		return messages, nil
+	}
+
+	return append([]database.Message{{
+		Timestamp:   summary.Timestamp,
+		ChatID:      chatID,
+		Role:        "system",
+		ContentType: database.ContentTypeSystem,
+		Content:     "Summary of the earlier conversation:\n" + summary.Content,
+	}}, messages...), nil
+}
+
+// refreshContextIfNeeded summarizes and clears the history of the chat thread once the turn limit is reached.
+func (t *Tellama) refreshContextIfNeeded(
+	ctx telebot.Context,
+	chat *telebot.Chat,
+	user *telebot.User,
+	chatOverride database.ChatOverride,
+	genaiConfig genai.ProviderConfig,
+	genaiClient genai.GenerativeAI,
+) error {
+	maxTurns := t.settings().maxTurns
+	if chatOverride.MaxTurns != 0 {
+		maxTurns = chatOverride.MaxTurns
+	}
+	if maxTurns <= 0 {
+		return nil
+	}
+
+	threadID := messageThreadID(ctx.Message())
+	turns, err := t.dm.CountMessages(chat.ID, threadID, "assistant")
+	if err != nil {
+		return err
+	}
+	if turns < int64(maxTurns) {
+		return nil
+	}
+
+	log.Info().
+		Int64("chat_id", chat.ID).
+		Int64("turns", turns).
+		Msg("Turn limit reached, summarizing conversation")
+
+	messages, err := t.dm.GetMessages(chat.ID, threadID, t.settings().historyFetchLimit)
+	if err != nil {
+		return err
+	}
+
+	// Carry over the previous summary so that older context is not lost
+	messages, err = t.prependChatSummary(chat.ID, threadID, messages)
+	if err != nil {
+		return err
+	}
+
+	summary, genStats, err := t.summarizeMessages(messages, genaiClient)
+	if err != nil {
+		return err
+	}
+	t.recordUsage(chat, user, modelName(genaiConfig), genStats)
+	if summary == "" {
+		return errors.New("received empty summary from generative AI")
+	}
+
+	if err = t.dm.StoreChatSummary(chat.ID, threadID, summary); err != nil {
+		return err
+	}
+	if err = t.dm.ClearThreadMessages(chat.ID, threadID); err != nil {
+		return err
+	}
+
+	if t.settings().responseMessages.ContextRefreshed != "" {
+		return ctx.Send(t.settings().responseMessages.ContextRefreshed, telebot.ModeMarkdown)
+	}
+	return nil
+}
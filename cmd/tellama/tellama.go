@@ -5,14 +5,35 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"maps"
+	"reflect"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"text/template"
 	"time"
 
+	"github.com/k4yt3x/tellama/internal/adaptive"
+	"github.com/k4yt3x/tellama/internal/breaker"
 	"github.com/k4yt3x/tellama/internal/config"
+	"github.com/k4yt3x/tellama/internal/dashboard"
 	"github.com/k4yt3x/tellama/internal/database"
 	"github.com/k4yt3x/tellama/internal/genai"
+	"github.com/k4yt3x/tellama/internal/language"
+	"github.com/k4yt3x/tellama/internal/metrics"
+	"github.com/k4yt3x/tellama/internal/moderation"
+	"github.com/k4yt3x/tellama/internal/plugin"
+	"github.com/k4yt3x/tellama/internal/prompts"
+	"github.com/k4yt3x/tellama/internal/rag"
+	"github.com/k4yt3x/tellama/internal/redact"
+	"github.com/k4yt3x/tellama/internal/scripting"
+	"github.com/k4yt3x/tellama/internal/secrets"
+	"github.com/k4yt3x/tellama/internal/tts"
 	"github.com/k4yt3x/tellama/internal/utilities"
+	"github.com/k4yt3x/tellama/internal/webhook"
+	"github.com/k4yt3x/tellama/internal/websearch"
 
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/rs/zerolog/log"
@@ -25,9 +46,10 @@ const defaultSystemPrompt = `{{if .CurrentTime}}current_time="{{.CurrentTime}}"
 {{end}}
 # Begin System Directives
 
-Your name is Tellama.
-You are an AI chatbot built by K4YT3X for Telegram group chats.
-Your task is to help users by providing information and answering questions.
+Your name is {{.PersonaName}}.
+You are {{.PersonaDescription}}.
+{{if .PersonaStyle}}Your style is {{.PersonaStyle}}.
+{{end}}Your task is to help users by providing information and answering questions.
 You must not engage in any harmful, illegal, or unethical conversations.
 You must be polite, respectful, and helpful to all users.
 You must obey laws, morals, and ethics.
@@ -36,81 +58,358 @@ You should respond in plain text.
 # End System Directives`
 
 type Tellama struct {
-	historyFetchLimit    int
-	genaiTimeout         time.Duration
+	currentSettings      atomic.Pointer[runtimeSettings]
 	allowUntrustedChats  bool
 	genaiProvider        genai.Provider
 	genaiMode            genai.Mode
-	genaiConfig          genai.ProviderConfig
-	genaiTemplate        string
 	genaiAllowConcurrent bool
-	responseMessages     config.ResponseMessages
+	tools                []genai.Tool
+	tts                  *tts.Synthesizer
+	moderator            *moderation.Moderator
+	moderateResponses    bool
+	webhooks             *webhook.Notifier
+	dashboard            *dashboard.Server
+	plugins              *plugin.Registry
+	untrustedChats       map[int64]struct{}
+	untrustedChatsMutex  sync.Mutex
+	rag                  rag.Config
+	adaptiveHistory      *adaptive.History
+	circuitBreaker       breaker.Config
+	providerBreaker      *breaker.Breaker
+	adminUserIDs         []int64
+	budgetAlertsSent     map[string]struct{}
+	budgetAlertsMutex    sync.Mutex
+	lastGenerations      map[int64]lastGeneration
+	lastGenerationsMutex sync.Mutex
+	recentMessages       map[chatUser][]time.Time
+	recentMessagesMutex  sync.Mutex
+	keepAlive            config.KeepAlive
+	keepAliveMetrics     keepAliveMetrics
+	modelRefresh         config.ModelRefresh
+	metricsConfig        config.Metrics
+	metrics              *metrics.Registry
+	lastActivity         atomic.Int64
 	sem                  chan struct{}
+	backfill             bool
+	degraded             *degradedMode
+	writeBuffer          *writeBuffer
+	capabilityCache      *capabilityCache
 	dm                   *database.Manager
 	bot                  *telebot.Bot
+	poller               *telebot.LongPoller
+
+	// loadConfig loads the configuration file on reloads, replaced in tests
+	loadConfig func(configPath string) (*config.Config, error)
+}
+
+func NewTellama(cfg *config.Config) (*Tellama, error) {
+	poller := &telebot.LongPoller{Timeout: cfg.Telegram.Timeout}
+	return newTellama(cfg, poller, telebot.Settings{
+		Token:  cfg.Telegram.BotToken,
+		Poller: poller,
+	})
+}
+
+// openDatabaseManager opens the database of the configured driver, applying the pending migrations if migrate is set.
+func openDatabaseManager(cfg *config.Config, migrate bool) (*database.Manager, error) {
+	if cfg.Database.Driver == database.DriverMySQL {
+		if migrate {
+			return database.NewMySQLDatabaseManager(cfg.Database.DSN)
+		}
+		return database.OpenMySQLDatabaseManager(cfg.Database.DSN)
+	}
+	if migrate {
+		return database.NewDatabaseManager(cfg.Database.Path, cfg.Database.SQLite)
+	}
+	return database.OpenDatabaseManager(cfg.Database.Path, cfg.Database.SQLite)
+}
+
+// openConfiguredDatabase opens the database and sets up the encryption and redaction of stored data.
+func openConfiguredDatabase(cfg *config.Config) (*database.Manager, error) {
+	db, err := openDatabaseManager(cfg, cfg.Database.AutoMigrate)
+	if err != nil {
+		return nil, err
+	}
+	if !cfg.Database.AutoMigrate {
+		var pending int
+		pending, err = db.PendingMigrations()
+		if err != nil {
+			return nil, fmt.Errorf("failed to check database migrations: %w", err)
+		}
+		if pending > 0 {
+			return nil, fmt.Errorf("%d database migrations are pending, apply them with tellama migrate up", pending)
+		}
+	}
+	if err = db.SetChatOverrideCacheTTL(cfg.Database.OverrideCacheTTL); err != nil {
+		return nil, fmt.Errorf("failed to set up chat override cache: %w", err)
+	}
+	if cfg.Database.EncryptionKey != nil {
+		var cipher *secrets.Cipher
+		cipher, err = secrets.NewCipher(cfg.Database.EncryptionKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create cipher: %w", err)
+		}
+		db.SetCipher(cipher)
+
+		// Encrypt the API keys that were stored before the encryption key was configured
+		var encrypted int
+		encrypted, err = db.EncryptChatAPIKeys()
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt chat API keys: %w", err)
+		}
+		if encrypted > 0 {
+			log.Info().Int("count", encrypted).Msg("Encrypted chat API keys stored in plain text")
+		}
+	}
+	if cfg.Database.Redaction.Enabled {
+		var redactor *redact.Redactor
+		redactor, err = redact.New(&cfg.Database.Redaction)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create redactor: %w", err)
+		}
+		db.SetRedactor(redactor)
+	}
+	return db, nil
 }
 
-func NewTellama(
-	telegramToken string,
-	dbPath string,
-	historyFetchLimit int,
-	telegramTimeout time.Duration,
-	genaiTimeout time.Duration,
-	allowUntrustedChats bool,
-	genaiProvider genai.Provider,
-	genaiMode genai.Mode,
-	genaiConfig genai.ProviderConfig,
-	genaiTemplate string,
-	genaiAllowConcurrent bool,
-	responseMessages config.ResponseMessages,
-) (*Tellama, error) {
-	db, err := database.NewDatabaseManager(dbPath)
+// newTellama creates a Tellama instance with a bot created from the given settings.
+func newTellama(cfg *config.Config, poller *telebot.LongPoller, botSettings telebot.Settings) (*Tellama, error) {
+	db, err := openConfiguredDatabase(cfg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize database: %w", err)
 	}
 
 	// Create a new Telebot instance
-	bot, err := telebot.NewBot(telebot.Settings{
-		Token:  telegramToken,
-		Poller: &telebot.LongPoller{Timeout: telegramTimeout},
-	})
+	bot, err := telebot.NewBot(botSettings)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Telebot: %w", err)
 	}
 
 	// Create a new Tellama instance
 	t := &Tellama{
-		historyFetchLimit:    historyFetchLimit,
-		genaiTimeout:         genaiTimeout,
-		allowUntrustedChats:  allowUntrustedChats,
-		genaiProvider:        genaiProvider,
-		genaiMode:            genaiMode,
-		genaiConfig:          genaiConfig,
-		genaiTemplate:        genaiTemplate,
-		genaiAllowConcurrent: genaiAllowConcurrent,
-		responseMessages:     responseMessages,
+		allowUntrustedChats:  cfg.Telegram.AllowUntrustedChat,
+		genaiProvider:        cfg.GenerativeAI.Provider,
+		genaiMode:            cfg.GenerativeAI.Mode,
+		genaiAllowConcurrent: cfg.GenerativeAI.AllowConcurrent,
+		rag:                  cfg.RAG,
+		adaptiveHistory:      adaptive.NewHistory(cfg.GenerativeAI.AdaptiveHistory),
+		circuitBreaker:       cfg.GenerativeAI.CircuitBreaker,
+		providerBreaker:      breaker.New(cfg.GenerativeAI.CircuitBreaker),
+		adminUserIDs:         cfg.Telegram.AdminUserIDs,
+		budgetAlertsSent:     map[string]struct{}{},
+		lastGenerations:      map[int64]lastGeneration{},
+		recentMessages:       map[chatUser][]time.Time{},
+		untrustedChats:       map[int64]struct{}{},
+		keepAlive:            cfg.KeepAlive,
+		modelRefresh:         cfg.ModelRefresh,
+		metricsConfig:        cfg.Metrics,
+		metrics:              metrics.NewRegistry(),
 		sem:                  make(chan struct{}, 1),
+		backfill:             cfg.Telegram.BackfillMissedMessages,
+		degraded:             newDegradedMode(cfg.Database.DegradedQueueSize),
+		writeBuffer:          newWriteBuffer(cfg.Database.WriteBufferSize, cfg.Database.WriteBufferInterval),
+		capabilityCache:      newCapabilityCache(),
+		loadConfig:           config.Load,
 		dm:                   db,
 		bot:                  bot,
+		poller:               poller,
+	}
+	settings, err := newRuntimeSettings(cfg)
+	if err != nil {
+		return nil, err
+	}
+	t.currentSettings.Store(settings)
+	db.ObserveQueries(t.metrics.ObserveDatabaseQuery)
+
+	// Track the last message received in each chat
+	bot.Poller = telebot.NewMiddlewarePoller(poller, t.trackUpdate)
+
+	// Set up the tools available to the model
+	if cfg.Tools.WebSearch.Enabled {
+		var webSearch *websearch.WebSearch
+		webSearch, err = websearch.New(&cfg.Tools.WebSearch)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create web search tool: %w", err)
+		}
+		t.tools = append(t.tools, webSearch)
+	}
+
+	// Set up text-to-speech for voice replies
+	if cfg.TTS.Enabled {
+		var synthesizer *tts.Synthesizer
+		synthesizer, err = tts.New(&cfg.TTS)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create text-to-speech synthesizer: %w", err)
+		}
+		t.tts = synthesizer
+	}
+
+	// Set up content moderation of messages and responses
+	if cfg.Moderation.Enabled {
+		var moderator *moderation.Moderator
+		moderator, err = moderation.New(&cfg.Moderation)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create moderator: %w", err)
+		}
+		t.moderator = moderator
+		t.moderateResponses = cfg.Moderation.CheckResponses
+	}
+
+	// Set up the plugins the bot is built with, followed by the scripts of the operator
+	plugins := registeredPlugins()
+	if cfg.Scripting.Enabled {
+		var scripts *scripting.Plugin
+		scripts, err = scripting.New(&cfg.Scripting)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load scripts: %w", err)
+		}
+		plugins = append(plugins, scripts)
+	}
+	t.plugins, err = plugin.NewRegistry(plugins...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register plugins: %w", err)
+	}
+
+	// Set up the outgoing webhooks
+	if cfg.Webhooks.Enabled {
+		var notifier *webhook.Notifier
+		notifier, err = webhook.New(&cfg.Webhooks)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create webhook notifier: %w", err)
+		}
+		t.webhooks = notifier
+	}
+
+	// Set up the web dashboard
+	if cfg.Dashboard.Enabled {
+		t.dashboard, err = dashboard.New(&cfg.Dashboard, db, func(options string) error {
+			return validateOptions(t.genaiProvider, options)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create dashboard: %w", err)
+		}
 	}
 
 	// Initialize the semaphore with a token
 	t.sem <- struct{}{}
 
 	// Register handlers
-	bot.Handle("/getsysprompt", t.getSysPrompt)
-	bot.Handle("/setsysprompt", t.setSysPrompt)
-	bot.Handle("/delsysprompt", t.delSysPrompt)
-	bot.Handle("/getconfig", t.getConfig)
-	bot.Handle("/amnesia", t.amnesia)
-	bot.Handle(telebot.OnText, t.handleMessage)
+	t.handle("/start", t.start)
+	t.handle("/status", t.status)
+	t.handle("/context", t.contextPreview)
+	t.handle("/dbinfo", t.dbInfo)
+	t.handle("/usage", t.usageCommand)
+	t.handle("/laststats", t.lastStats)
+	t.handle("/privacy", t.privacy)
+	t.handle("/getsysprompt", t.getSysPrompt)
+	t.handle("/setsysprompt", t.setSysPrompt)
+	t.handle("/delsysprompt", t.delSysPrompt)
+	t.handle("/getconfig", t.getConfig)
+	t.handle("/setmaxturns", t.setMaxTurns)
+	t.handle("/setmodel", t.setModel)
+	t.handle("/listmodels", t.listModelsCommand)
+	t.handle("/setrouting", t.setRouting)
+	t.handle("/setreplyformat", t.setReplyFormat)
+	t.handle("/setchatevents", t.setChatEvents)
+	t.handle("/setmodelbadge", t.setModelBadge)
+	t.handle("/exportpreset", t.exportPreset)
+	t.handle("/importpreset", t.importPreset)
+	t.handle("/setlang", t.setLanguage)
+	t.handle("/settings", t.settingsCommand)
+	t.handle("/setoption", t.setOption)
+	t.handle("/resetoptions", t.resetOptions)
+	t.handle("\f"+settingsUnique, t.handleSettingsCallback)
+	t.handle("/pincontext", t.pinContext)
+	t.handle("/unpincontext", t.unpinContext)
+	t.handle("/listpinned", t.listPinned)
+	t.handle("/search", t.searchMessages)
+	t.handle("/ban", t.banUser)
+	t.handle("/unban", t.unbanUser)
+	t.handle("/bans", t.listBans)
+	t.handle("/optout", t.optOut)
+	t.handle("/optin", t.optIn)
+	t.handle("/remember", t.remember)
+	t.handle("/forgetnote", t.forgetNote)
+	t.handle("/notes", t.listNotes)
+	t.handle("/schedule", t.schedulePrompt)
+	t.handle("/unschedule", t.unschedulePrompt)
+	t.handle("/schedules", t.listSchedules)
+	t.handle("/summary", t.summaryCommand)
+	t.handle("/whatdidimiss", t.whatDidIMiss)
+	t.handle("/ingest", t.ingest)
+	t.handle("/documents", t.listDocuments)
+	t.handle("/deldocument", t.deleteDocument)
+	t.handle("/amnesia", t.amnesia)
+	t.handle("/setapikey", t.setAPIKey)
+	t.handle("/delapikey", t.delAPIKey)
+	t.handle("/trust", t.trust)
+	t.handle("/untrust", t.untrust)
+	t.handle("/listtrusted", t.listTrusted)
+	t.handle("/trustuser", t.trustUser)
+	t.handle("/untrustuser", t.untrustUser)
+	t.handle("/listtrustedusers", t.listTrustedUsers)
+	t.handle("/tts", t.textToSpeech)
+	t.handle("/setvoicereply", t.setVoiceReply)
+	t.handle("/setpersona", t.setPersona)
+	t.handle("/setresponseformat", t.setResponseFormatCommand)
+	t.handle("/setdailybudget", t.setDailyBudget)
+	t.handle("/setwelcome", t.setWelcome)
+	t.handle("/setglobal", t.setGlobal)
+	t.handle("/getglobal", t.getGlobal)
+	t.handle(telebot.OnText, t.handleMessage)
+	t.handle(telebot.OnPhoto, t.handlePhoto)
+	t.handle(telebot.OnEdited, t.handleEdited)
+	t.handle(telebot.OnUserJoined, t.handleUserJoined)
+	t.handle(telebot.OnUserLeft, t.handleUserLeft)
+	t.handle(telebot.OnNewGroupTitle, t.handleNewGroupTitle)
+	t.handle(telebot.OnPinned, t.handlePinned)
+	t.handle(telebot.OnDocument, t.handleDocument)
+	t.handle(telebot.OnChannelPost, t.handleChannelPost)
+	t.registerPluginCommands()
 
 	return t, nil
 }
 
 func (t *Tellama) Run() {
+	if t.backfill {
+		if err := t.backfillMissedUpdates(); err != nil {
+			log.Error().Err(err).Msg("Failed to backfill missed messages")
+		}
+	}
+
+	if t.keepAlive.Enabled {
+		go t.runKeepAlive()
+	}
+
+	if t.modelRefresh.Enabled {
+		go t.runModelRefresh()
+	}
+
+	if t.metricsConfig.Enabled {
+		go t.serveMetrics()
+	}
+
+	if t.writeBuffer.interval > 0 {
+		go t.runWriteBuffer()
+	}
+
+	if t.webhooks != nil {
+		go t.webhooks.Run()
+	}
+
+	if t.dashboard != nil {
+		go t.dashboard.Run()
+	}
+
+	go t.resumePendingGenerations()
+	go t.runScheduler()
+	t.stopOnSignal()
+
 	log.Info().Msg("Starting Telegram bot polling loop")
 	t.bot.Start()
+
+	// Store the messages still buffered after the polling loop has stopped
+	t.flushMessages()
 }
 
 func (t *Tellama) getSysPrompt(ctx telebot.Context) error {
@@ -120,7 +419,7 @@ func (t *Tellama) getSysPrompt(ctx telebot.Context) error {
 		return nil
 	}
 
-	if !t.checkPermissions(chat, msg.Sender, msg) {
+	if !t.authorize(chat, msg.Sender, msg, permissionTrusted) {
 		return ctx.Reply("You do not have permission to use this command.")
 	}
 
@@ -143,7 +442,7 @@ func (t *Tellama) setSysPrompt(ctx telebot.Context) error {
 		return nil
 	}
 
-	if !t.checkPermissions(chat, msg.Sender, msg) {
+	if !t.authorize(chat, msg.Sender, msg, permissionChatAdmin) {
 		return ctx.Reply("You do not have permission to use this command.")
 	}
 
@@ -158,6 +457,19 @@ func (t *Tellama) setSysPrompt(ctx telebot.Context) error {
 		return ctx.Reply("Please provide a non-empty prompt to set.")
 	}
 
+	// Refer to a template of the library instead of storing the prompt itself
+	if name, ok := prompts.Reference(prompt); ok {
+		library := t.settings().prompts
+		if len(library.Names()) == 0 {
+			return ctx.Reply("No prompt templates are loaded.")
+		}
+		if !library.Has(name) {
+			return ctx.Reply(tr(ctx, "Unknown prompt template. Available templates: %s.",
+				strings.Join(library.Names(), ", ")))
+		}
+		prompt = prompts.Prefix + name
+	}
+
 	if err := t.dm.SetChatOverride(chat.ID, chat.Title, "", "", "", "", prompt); err != nil {
 		log.Error().Err(err).Msg("Failed to set prompt")
 		return ctx.Reply("Failed to set prompt. Please check logs for details.")
@@ -178,7 +490,7 @@ func (t *Tellama) delSysPrompt(ctx telebot.Context) error {
 		return nil
 	}
 
-	if !t.checkPermissions(chat, msg.Sender, msg) {
+	if !t.authorize(chat, msg.Sender, msg, permissionChatAdmin) {
 		return ctx.Reply("You do not have permission to use this command.")
 	}
 
@@ -202,7 +514,7 @@ func (t *Tellama) getConfig(ctx telebot.Context) error { //nolint:funlen
 		return nil
 	}
 
-	if !t.checkPermissions(chat, msg.Sender, msg) {
+	if !t.authorize(chat, msg.Sender, msg, permissionTrusted) {
 		return ctx.Reply("You do not have permission to use this command.")
 	}
 
@@ -211,17 +523,20 @@ func (t *Tellama) getConfig(ctx telebot.Context) error { //nolint:funlen
 		Int64("user_id", msg.Sender.ID).
 		Msg("Getting configuration")
 
+	// Defer keep-alive pings while the provider is in use
+	t.markActive()
+
 	// Get override values for this chat
 	chatOverride, err := t.dm.GetChatOverride(chat.ID)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to get chat override")
-		return ctx.Reply(t.responseMessages.InternalError)
+		return ctx.Reply(t.settings().responseMessages.InternalError)
 	}
 
 	genaiConfig, err := t.applyChatOverride(chatOverride)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to apply chat override")
-		return ctx.Reply(t.responseMessages.InternalError)
+		return ctx.Reply(t.settings().responseMessages.InternalError)
 	}
 
 	// Marshal the config struct to JSON then unmarshal to map to get all fields
@@ -243,10 +558,32 @@ func (t *Tellama) getConfig(ctx telebot.Context) error { //nolint:funlen
 		maskedConfig := *openaiConfig
 		maskedConfig.APIKey = "sk-proj-************************************************"
 		configObj = &maskedConfig
+	case genai.ProviderMock:
+		providerName = "mock"
+		configObj, ok = genaiConfig.(*genai.MockConfig)
+	case genai.ProviderBedrock:
+		providerName = "bedrock"
+		var bedrockConfig *genai.BedrockConfig
+		bedrockConfig, ok = genaiConfig.(*genai.BedrockConfig)
+		if !ok || bedrockConfig == nil {
+			break
+		}
+		maskedConfig := *bedrockConfig
+		if maskedConfig.SecretAccessKey != "" {
+			maskedConfig.SecretAccessKey = "****************************************"
+		}
+		if maskedConfig.SessionToken != "" {
+			maskedConfig.SessionToken = "****************"
+		}
+		configObj = &maskedConfig
+	default:
+		// Providers added with genai.Register are shown as they are configured
+		providerName = t.genaiProvider.String()
+		configObj, ok = genaiConfig, genaiConfig != nil
 	}
 
 	if !ok || configObj == nil {
-		return ctx.Reply(fmt.Sprintf("Invalid configuration type for %s", providerName))
+		return ctx.Reply(tr(ctx, "Invalid configuration type for %s", providerName))
 	}
 
 	// Marshal the config to JSON
@@ -282,6 +619,37 @@ func (t *Tellama) getConfig(ctx telebot.Context) error { //nolint:funlen
 	return ctx.Reply(reply.String(), telebot.ModeMarkdown)
 }
 
+func (t *Tellama) setMaxTurns(ctx telebot.Context) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil {
+		return nil
+	}
+
+	if !t.authorize(chat, msg.Sender, msg, permissionChatAdmin) {
+		return ctx.Reply("You do not have permission to use this command.")
+	}
+
+	// Parse the maximum number of turns from the command arguments
+	maxTurns, err := strconv.Atoi(strings.TrimSpace(msg.Payload))
+	if err != nil || maxTurns < 0 {
+		return ctx.Reply("Please provide a non-negative number of turns (0 to use the default).")
+	}
+
+	if err = t.dm.SetChatMaxTurns(chat.ID, chat.Title, maxTurns); err != nil {
+		log.Error().Err(err).Msg("Failed to set max turns")
+		return ctx.Reply("Failed to set max turns. Please check logs for details.")
+	}
+
+	log.Info().
+		Int64("chat_id", chat.ID).
+		Int64("user_id", msg.Sender.ID).
+		Int("max_turns", maxTurns).
+		Msg("Max turns set")
+
+	return ctx.Reply("Max turns set successfully.")
+}
+
 func (t *Tellama) amnesia(ctx telebot.Context) error {
 	chat := ctx.Chat()
 	msg := ctx.Message()
@@ -289,7 +657,7 @@ func (t *Tellama) amnesia(ctx telebot.Context) error {
 		return nil
 	}
 
-	if !t.checkPermissions(chat, msg.Sender, msg) && !t.allowUntrustedChats {
+	if !t.authorize(chat, msg.Sender, msg, permissionUntrusted) {
 		return ctx.Reply("You do not have permission to use this command.")
 	}
 
@@ -298,6 +666,11 @@ func (t *Tellama) amnesia(ctx telebot.Context) error {
 		return ctx.Reply("Failed to clear messages. Please check logs for details.")
 	}
 
+	if err := t.dm.ClearChatSummaries(chat.ID); err != nil {
+		log.Error().Err(err).Msg("Failed to clear chat summaries")
+		return ctx.Reply("Failed to clear messages. Please check logs for details.")
+	}
+
 	log.Info().
 		Int64("group_id", chat.ID).
 		Int64("user_id", msg.Sender.ID).
@@ -314,60 +687,175 @@ func (t *Tellama) handleMessage(ctx telebot.Context) error {
 		return nil
 	}
 
+	return t.handleIncomingMessage(ctx, message.Text, database.ContentTypeText, nil)
+}
+
+func (t *Tellama) handlePhoto(ctx telebot.Context) error {
+	// Validate that the received message contains a photo
+	message := ctx.Message()
+	if message == nil || message.Photo == nil {
+		log.Info().Msg("Received message with invalid photo")
+		return nil
+	}
+
+	// Treat the photo as a plain text message if vision input is disabled or the model cannot read images
+	vision := t.settings().genaiVision
+	if vision {
+		capabilities, err := t.chatCapabilities(message.Chat.ID)
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed to get model capabilities")
+		} else if !capabilities.Vision {
+			vision = false
+			if message.Caption == "" && message.Sender != nil &&
+				t.authorize(message.Chat, message.Sender, message, permissionTrusted) &&
+				t.shouldProcessMessage(message.Chat, message, "") {
+				return t.localize(ctx).Reply(
+					"The model of this chat cannot read images. Please describe the image in text instead.",
+				)
+			}
+		}
+	}
+	if !vision {
+		if message.Caption == "" {
+			log.Info().Msg("Ignored photo without caption")
+			return nil
+		}
+		return t.handleIncomingMessage(ctx, message.Caption, database.ContentTypePhotoCaption, nil)
+	}
+
+	return t.handleIncomingMessage(ctx, message.Caption, database.ContentTypePhotoCaption, message.Photo)
+}
+
+func (t *Tellama) handleIncomingMessage(
+	ctx telebot.Context,
+	text string,
+	contentType string,
+	photo *telebot.Photo,
+) error {
+	message := ctx.Message()
+
+	// Never store or forward API keys pasted into the chat
+	text = secrets.Redact(text)
+
 	// Get chat and user information
 	chat := ctx.Chat()
-	user := ctx.Sender()
+	user := messageAuthor(message)
 	if user == nil {
 		log.Info().Msg("Received message without a valid sender")
 		return nil
 	}
 
 	// Verify user/group has permission to use the bot
-	if !t.checkPermissions(chat, user, message) && !t.allowUntrustedChats {
+	if !t.authorize(chat, user, message, permissionUntrusted) {
 		if chat.Type == telebot.ChatPrivate {
-			return ctx.Reply(t.responseMessages.PrivateChatDisallowed)
+			return ctx.Reply(t.settings().responseMessages.PrivateChatDisallowed)
 		}
 		return nil
 	}
 
 	// Ignore messages that start with "//"
-	if strings.HasPrefix(message.Text, "//") {
+	if strings.HasPrefix(text, "//") {
 		log.Info().Msg("Ignored commented message")
 		return nil
 	}
 
+	// Ignore users who are banned or silenced in this chat, and silence users who flood the bot
+	if t.isUserBanned(chat.ID, user.ID) {
+		log.Info().Int64("chat_id", chat.ID).Int64("user_id", user.ID).Msg("Ignored message from banned user")
+		return nil
+	}
+
+	// Let the plugins rewrite or ignore the message
+	var keep bool
+	text, keep = t.filterMessage(ctx, text)
+	if !keep {
+		return nil
+	}
+	t.emitMessageReceived(chat, user, message, text)
+
+	shouldProcess := t.shouldProcessMessage(chat, message, text)
+	if shouldProcess {
+		if t.checkAbuse(ctx, chat, user, text, contentType) {
+			return nil
+		}
+
+		// Refuse flagged messages without storing them in the history
+		if t.isModerated(chat, text, "message") {
+			return t.replyModerated(ctx)
+		}
+	}
+
+	// Messages that do not trigger a bot response are only stored, in a batch if write buffering is enabled
+	if !shouldProcess {
+		if err := t.storeUserMessage(chat, user, message, text, contentType, true); err != nil {
+			return err
+		}
+		t.acknowledgeStoredMessage(chat, message)
+		return nil
+	}
+
+	// Store the message without a response once the chat has used up its daily token budget
+	if t.isBudgetExhausted(chat, user) {
+		if err := t.storeUserMessage(chat, user, message, text, contentType, true); err != nil {
+			return err
+		}
+		return t.replyBudgetExhausted(ctx)
+	}
+
 	// Get historical messages for the chat
-	messages, err := t.dm.GetMessages(chat.ID, t.historyFetchLimit)
+	messages, err := t.getContextHistory(chat.ID, messageThreadID(message), text)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to get message history")
-		return ctx.Reply(t.responseMessages.InternalError)
+		return ctx.Reply(t.settings().responseMessages.InternalError)
 	}
 
 	// Store the user's message in the database
-	if err = t.storeUserMessage(chat, user, message.Text); err != nil {
-		log.Error().Err(err).Msg("Failed to store user message")
+	if err = t.storeUserMessage(chat, user, message, text, contentType, false); err != nil {
 		return err
 	}
 
-	// Check if this message should trigger a bot response
-	if !t.shouldProcessMessage(chat, message) {
-		return nil
+	// Keep track of the response until it is handled so that it can be resumed after a restart
+	t.addPendingGeneration(chat, message)
+	defer t.removePendingGeneration(chat.ID, message.ID)
+
+	// Download the attached photo to pass it to the generative AI
+	var images [][]byte
+	if photo != nil {
+		var image []byte
+		image, err = t.downloadFile(&photo.File)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to download photo")
+			return ctx.Reply(t.settings().responseMessages.InternalError)
+		}
+		images = append(images, image)
 	}
 
 	if t.genaiAllowConcurrent {
-		return t.processMessage(ctx, chat, user, message, messages)
+		return t.processMessage(ctx, chat, user, message, text, contentType, images, messages)
 	}
 
+	waitStart := time.Now()
 	select {
 	case <-t.sem:
 		defer func() { t.sem <- struct{}{} }()
-		return t.processMessage(ctx, chat, user, message, messages)
-	case <-time.After(t.genaiTimeout):
+		t.metrics.ObserveQueueWait(time.Since(waitStart))
+		return t.processMessage(ctx, chat, user, message, text, contentType, images, messages)
+	case <-time.After(t.settings().genaiTimeout):
 		log.Warn().
 			Int("message_id", message.ID).
 			Msg("Failed to acquire semaphore to process message")
-		return ctx.Reply(t.responseMessages.ServerBusy)
+		return ctx.Reply(t.settings().responseMessages.ServerBusy)
+	}
+}
+
+// downloadFile downloads a file from Telegram into memory.
+func (t *Tellama) downloadFile(file *telebot.File) ([]byte, error) {
+	reader, err := t.bot.File(file)
+	if err != nil {
+		return nil, err
 	}
+	defer reader.Close()
+	return io.ReadAll(reader)
 }
 
 func (t *Tellama) processMessage(
@@ -375,44 +863,47 @@ func (t *Tellama) processMessage(
 	chat *telebot.Chat,
 	user *telebot.User,
 	message *telebot.Message,
+	text string,
+	contentType string,
+	images [][]byte,
 	messages []database.Message,
 ) error {
-	// Get override values for this chat
+	// Get override values for this chat, or use the configured defaults while the database is unavailable
 	chatOverride, err := t.dm.GetChatOverride(chat.ID)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to get chat override")
-		return err
+		if !t.enterDegradedMode(err) {
+			return err
+		}
+		chatOverride = database.ChatOverride{ChatID: chat.ID}
 	}
 
-	// Add system prompt and current message to the conversation
-	messages, err = t.appendCurrentMessages(messages, chat, user, message, chatOverride)
-	if err != nil {
-		log.Error().Err(err).Msg("Failed to append current messages")
-		return ctx.Reply(t.responseMessages.InternalError)
-	}
+	// Send fewer history messages while recent responses in this chat have been slow
+	messages = t.limitHistory(chat.ID, messages)
+	historySize := len(messages)
 
-	// Generate bot's response using Ollama
-	log.Info().
-		Int64("chat_id", chat.ID).
-		Int("message_id", message.ID).
-		Msg("Generating response for message")
-
-	genaiConfig, err := t.applyChatOverride(chatOverride)
+	messages, genaiConfig, genaiClient, err := t.prepareGeneration(
+		chat,
+		user,
+		message,
+		text,
+		contentType,
+		images,
+		messages,
+		chatOverride,
+	)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to apply chat override")
-		return ctx.Reply(t.responseMessages.InternalError)
+		return ctx.Reply(t.settings().responseMessages.InternalError)
 	}
-
-	genaiClient, err := genai.New(t.genaiProvider, genaiConfig)
+	messages, err = t.beforeGeneration(ctx, messages)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to create generative AI client")
-		return ctx.Reply(t.responseMessages.InternalError)
+		return ctx.Reply(t.settings().responseMessages.InternalError)
 	}
 
 	// Send typing notification to the chat at intervals
 	stopTyping := make(chan struct{})
 	go func() {
-		_ = ctx.Bot().Notify(chat, telebot.Typing)
+		t.sendTyping(chat, message)
 
 		// Create a ticker to send typing notifications at intervals
 		ticker := time.NewTicker(1 * time.Second)
@@ -421,7 +912,7 @@ func (t *Tellama) processMessage(
 		for {
 			select {
 			case <-ticker.C:
-				_ = ctx.Bot().Notify(chat, telebot.Typing)
+				t.sendTyping(chat, message)
 			case <-stopTyping:
 				return
 			case <-time.After(60 * time.Second):
@@ -433,32 +924,195 @@ func (t *Tellama) processMessage(
 	// Ensure we stop the typing notifications when done
 	defer close(stopTyping)
 
-	response, err := t.generateResponse(messages, genaiClient)
+	start := time.Now()
+	gen, err := t.generateResponse(messages, genaiClient)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to generate response")
-		return ctx.Reply(t.responseMessages.InternalError)
+		return ctx.Reply(t.generationErrorMessage(err))
+	}
+	duration := time.Since(start)
+	t.adaptiveHistory.Observe(chat.ID, historySize, duration)
+	response := gen.Response
+
+	// Record token usage and check the generation budget
+	t.recordUsage(chat, user, modelName(genaiConfig), gen.Stats)
+
+	// Retry once with adjusted parameters if the response is empty
+	if response == "" && t.settings().emptyResponsePolicy == config.EmptyResponseRetry {
+		gen = t.retryEmptyResponse(chat, user, messages, genaiConfig)
+		response = gen.Response
 	}
 
 	if response == "" {
 		log.Warn().Msg("Received empty response from generative AI")
+		emptyResponse := t.settings().responseMessages.EmptyResponse
+		if t.settings().emptyResponsePolicy != config.EmptyResponseIgnore && emptyResponse != "" {
+			return ctx.Reply(emptyResponse)
+		}
 		return nil
 	}
 
+	// Let the plugins rewrite or withhold the response
+	response, err = t.afterGeneration(ctx, response)
+	if err != nil {
+		return ctx.Reply(t.settings().responseMessages.InternalError)
+	}
+	if response == "" {
+		return nil
+	}
+
+	if t.moderateResponses && t.isModerated(chat, response, "response") {
+		return t.replyModerated(ctx)
+	}
+
 	// Send the response back to the chat
-	_, err = ctx.Bot().Reply(message, response, telebot.ModeMarkdown)
+	replyText := t.applyStatsFooter(chat, genaiConfig, gen.Stats, applyModelBadge(chatOverride, genaiConfig, response))
+	sent, err := t.sendReply(ctx, message, chatOverride, replyText)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to send reply with Markdown formatting")
+		log.Error().Err(err).Msg("Failed to send reply")
+		return err
+	}
+	t.emitResponseGenerated(chat, user, message, sent, modelName(genaiConfig), gen.Stats, duration, response)
+
+	// Store the bot's response in the database
+	messageID, err := t.storeBotResponse(chat, sent, response)
+	if err != nil {
+		return err
+	}
+
+	// The response was queued while the database is unavailable
+	if messageID == 0 {
+		return nil
+	}
+
+	// Offer to continue long group answers in a private chat
+	t.addHandoffButton(chat, sent, messageID, response)
+
+	// Read the response out if the chat has enabled voice replies
+	if database.IsTrue(chatOverride.VoiceReply) && t.tts != nil {
+		t.sendVoiceReply(chat, sent, response)
+	}
 
-		// Retry sending the response without Markdown formatting
-		_, err = ctx.Bot().Reply(message, response)
+	// Store the tool calls made while generating the response
+	if len(gen.ToolInvocations) > 0 {
+		err = t.dm.StoreToolInvocations(chat.ID, messageID, gen.ToolInvocations)
 		if err != nil {
-			log.Error().Err(err).Msg("Failed to send reply")
-			return err
+			log.Error().Err(err).Msg("Failed to store tool invocations")
 		}
 	}
 
-	// Store the bot's response in the database
-	return t.storeBotResponse(chat, response)
+	// Summarize and reset the conversation if the turn limit has been reached
+	err = t.refreshContextIfNeeded(ctx, chat, user, chatOverride, genaiConfig, genaiClient)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to refresh conversation context")
+	}
+	return nil
+}
+
+// prepareGeneration builds the conversation for a message and the client used to respond to it.
+func (t *Tellama) prepareGeneration(
+	chat *telebot.Chat,
+	user *telebot.User,
+	message *telebot.Message,
+	text string,
+	contentType string,
+	images [][]byte,
+	messages []database.Message,
+	chatOverride database.ChatOverride,
+) ([]database.Message, genai.ProviderConfig, genai.GenerativeAI, error) {
+	messages, err := t.includeStoredContext(chat.ID, messageThreadID(message), messages)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// Include the knowledge base excerpts relevant to the message
+	messages, err = t.includeDocumentContext(chat.ID, text, messages)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to retrieve document context")
+	}
+
+	// Add system prompt and current message to the conversation
+	messages, err = t.appendCurrentMessages(
+		messages,
+		chat,
+		user,
+		message,
+		text,
+		contentType,
+		images,
+		chatOverride,
+	)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to append current messages")
+		return nil, nil, nil, err
+	}
+
+	// Generate bot's response using Ollama
+	log.Info().
+		Int64("chat_id", chat.ID).
+		Int("message_id", message.ID).
+		Msg("Generating response for message")
+
+	genaiConfig, err := t.applyChatOverride(chatOverride)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to apply chat override")
+		return nil, nil, nil, err
+	}
+
+	// Route the message to a model suited to its complexity
+	genaiConfig = t.applyModelRouting(chat.ID, chatOverride, text, genaiConfig)
+	setResponseFormat(genaiConfig, chatOverride.ResponseFormat)
+
+	// Use the user's own API key in private chats, which is not subject to the budget
+	if !t.applyUserAPIKey(chat, user, genaiConfig) {
+		// Switch to the fallback model if the chat has exceeded its daily budget
+		genaiConfig = t.applyBudgetFallback(chat.ID, genaiConfig)
+	}
+
+	genaiClient, err := genai.New(t.genaiProvider, genaiConfig)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to create generative AI client")
+		return nil, nil, nil, err
+	}
+	return messages, genaiConfig, genaiClient, nil
+}
+
+// includeStoredContext adds the pinned messages, tool calls, and summary stored for the chat to the history.
+// The history is used as is while the database is unavailable.
+func (t *Tellama) includeStoredContext(
+	chatID int64,
+	threadID int,
+	history []database.Message,
+) ([]database.Message, error) {
+	// Always include pinned messages regardless of the history window
+	messages, err := t.includePinnedMessages(chatID, history)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get pinned messages")
+		return t.degradedContext(history, err)
+	}
+
+	// Include the tool calls made in previous responses
+	messages, err = t.includeToolContext(messages)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get tool invocations")
+		return t.degradedContext(history, err)
+	}
+
+	// Include the summary of earlier conversations before the history
+	messages, err = t.prependChatSummary(chatID, threadID, messages)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get chat summary")
+		return t.degradedContext(history, err)
+	}
+	return messages, nil
+}
+
+// degradedContext returns the history without the stored context if degraded mode can be entered, or the error.
+func (t *Tellama) degradedContext(history []database.Message, err error) ([]database.Message, error) {
+	if t.enterDegradedMode(err) {
+		return history, nil
+	}
+	return nil, err
 }
 
 func (t *Tellama) checkPermissions(
@@ -478,31 +1132,54 @@ func (t *Tellama) checkPermissions(
 		Str("username", user.Username).
 		Str("full_name", fullName).
 		// Int("message_id", message.ID).
-		Str("text", message.Text).
+		Str("text", secrets.Redact(message.Text)).
 		Msg("Received message")
 
-	if !t.dm.IsChatTrusted(chat.ID) {
+	// Trusted users may use the bot in any chat
+	if !t.dm.IsChatTrusted(chat.ID) && !t.dm.IsUserTrusted(user.ID) {
 		log.Warn().
 			Int64("chat_id", chat.ID).
 			Str("chat_title", chat.Title).
 			Int("message_id", message.ID).
 			Msg("Untrusted chat")
+		t.emitUntrustedChat(chat, user)
 		return false
 	}
 	return true
 }
 
-func (t *Tellama) shouldProcessMessage(chat *telebot.Chat, msg *telebot.Message) bool {
+func (t *Tellama) shouldProcessMessage(
+	chat *telebot.Chat,
+	msg *telebot.Message,
+	text string,
+) bool {
+	// Channel posts forwarded to the discussion group are answered in the channel, if at all
+	if msg.AutomaticForward {
+		return false
+	}
+
 	isReplyToBot := false
 	if msg.ReplyTo != nil && msg.ReplyTo.Sender != nil {
 		isReplyToBot = msg.ReplyTo.Sender.ID == t.bot.Me.ID
 	}
 
-	if chat.Type != telebot.ChatPrivate && !isReplyToBot &&
-		!strings.Contains(strings.ToLower(msg.Text), "@"+strings.ToLower(t.bot.Me.Username)) {
+	settings := t.settings()
+	if chat.Type == telebot.ChatPrivate || isReplyToBot || (settings.channelComments && isChannelComment(msg)) {
+		return true
+	}
+
+	text = strings.ToLower(text)
+	mention := "@" + strings.ToLower(t.bot.Me.Username)
+	switch t.chatTrigger(chat.ID) {
+	case config.TriggerPrefix:
+		return startsWithMention(strings.TrimSpace(text), mention)
+	case config.TriggerMentionAnywhere:
+		return containsKeyword(text, []string{mention})
+	case config.TriggerKeywords:
+		return containsKeyword(text, []string{mention}) || containsKeyword(text, settings.triggerKeywords)
+	default:
 		return false
 	}
-	return true
 }
 
 func (t *Tellama) appendCurrentMessages(
@@ -510,6 +1187,9 @@ func (t *Tellama) appendCurrentMessages(
 	chat *telebot.Chat,
 	user *telebot.User,
 	msg *telebot.Message,
+	text string,
+	contentType string,
+	images [][]byte,
 	chatOverride database.ChatOverride,
 ) ([]database.Message, error) {
 	// If the message is a reply to the bot, include the original message
@@ -525,17 +1205,7 @@ func (t *Tellama) appendCurrentMessages(
 		}
 	}
 
-	systemPromptTemplateString := defaultSystemPrompt
-	if chatOverride.SystemPrompt != "" {
-		systemPromptTemplateString = chatOverride.SystemPrompt
-	}
-
-	// Add system prompt
-	systemPromptTemplate, err := template.New("sysprompt").Parse(systemPromptTemplateString)
-	if err != nil {
-		log.Error().Err(err).Msg("Failed to parse system prompt template")
-		return nil, err
-	}
+	systemPromptTemplateString := t.systemPromptTemplate(chatOverride)
 
 	// Inject context information into the system prompt template
 	contextInfo := map[string]any{
@@ -543,46 +1213,100 @@ func (t *Tellama) appendCurrentMessages(
 		"ChatTitle":   title,
 		"ChatType":    chat.Type,
 	}
+	addPersona(contextInfo, t.settings().persona)
 
 	// Include the reply message in the context if the message is a reply to the bot
 	if isReplyToBot {
 		contextInfo["ReplyMessage"] = utilities.TruncateStrToLength(msg.ReplyTo.Text, 20)
 	}
 
-	var systemPrompt bytes.Buffer
-	err = systemPromptTemplate.Execute(&systemPrompt, contextInfo)
+	// Quote the message of another user that this message replies to
+	content := text
+	if quotedAuthor, quoted := t.quotedMessage(msg); quoted != "" {
+		contextInfo["QuotedMessage"] = quoted
+		contextInfo["QuotedAuthor"] = quotedAuthor
+		content = quoteMessage(quotedAuthor, quoted, text)
+	}
+
+	// Add system prompt
+	systemPrompt, err := t.renderSystemPrompt(systemPromptTemplateString, contextInfo)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to execute system prompt template")
 		return nil, err
 	}
 
+	// Inject the durable facts remembered for this chat unless memory is turned off
+	systemPromptString := systemPrompt
+	if !database.IsTrue(chatOverride.MemoryDisabled) {
+		systemPromptString, err = t.appendChatMemories(chat.ID, systemPrompt)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to get chat memories")
+			if !t.enterDegradedMode(err) {
+				return nil, err
+			}
+			systemPromptString = systemPrompt
+		}
+	}
+
+	// Instruct the model to reply in the configured or detected language
+	if replyLanguage := t.replyLanguage(text); replyLanguage != "" {
+		systemPromptString += fmt.Sprintf("\n\nReply in %s.", replyLanguage)
+	}
+
 	return append(messages, database.Message{
-		Timestamp: time.Now().UTC(),
-		ChatID:    chat.ID,
-		ChatTitle: title,
-		Role:      "system",
-		UserID:    t.bot.Me.ID,
-		Username:  t.bot.Me.Username,
-		FirstName: "system",
-		Content:   systemPrompt.String(),
+		Timestamp:   time.Now().UTC(),
+		ChatID:      chat.ID,
+		ChatTitle:   title,
+		Role:        "system",
+		ContentType: database.ContentTypeSystem,
+		UserID:      t.bot.Me.ID,
+		Username:    t.bot.Me.Username,
+		FirstName:   "system",
+		Content:     systemPromptString,
 	}, database.Message{
-		Timestamp: time.Now().UTC(),
-		ChatID:    chat.ID,
-		ChatTitle: title,
-		Role:      "user",
-		UserID:    user.ID,
-		Username:  user.Username,
-		FirstName: user.FirstName,
-		LastName:  user.LastName,
-		Content:   msg.Text,
+		Timestamp:   time.Now().UTC(),
+		ChatID:      chat.ID,
+		ChatTitle:   title,
+		Role:        "user",
+		ContentType: contentType,
+		UserID:      user.ID,
+		Username:    user.Username,
+		FirstName:   user.FirstName,
+		LastName:    user.LastName,
+		Content:     content,
+		Images:      images,
 	}), nil
 }
 
+// addPersona adds the configured name and character of the assistant to the system prompt context.
+func addPersona(contextInfo map[string]any, persona config.Persona) {
+	contextInfo["PersonaName"] = persona.Name
+	contextInfo["PersonaDescription"] = persona.Description
+	contextInfo["PersonaStyle"] = persona.Style
+}
+
+// renderSystemPrompt executes a system prompt template, or the library template it refers to,
+// with the given context information.
+func (t *Tellama) renderSystemPrompt(systemPromptTemplateString string, contextInfo map[string]any) (string, error) {
+	systemPrompt, err := t.settings().prompts.Render(systemPromptTemplateString, contextInfo)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to render system prompt template")
+		return "", err
+	}
+	return systemPrompt, nil
+}
+
 func (t *Tellama) applyChatOverride(
 	chatOverride database.ChatOverride,
 ) (genai.ProviderConfig, error) {
 	// Make a copy of the generative AI configuration
-	genaiConfig := t.genaiConfig
+	genaiConfig, err := copyProviderConfig(t.settings().genaiConfig)
+	if err != nil {
+		return nil, err
+	}
+	chatOverride, err = t.withPersona(chatOverride)
+	if err != nil {
+		return nil, err
+	}
 
 	// Apply chat override values
 	switch t.genaiProvider {
@@ -618,34 +1342,191 @@ func (t *Tellama) applyChatOverride(
 		if chatOverride.Model != "" {
 			openaiConfig.Model = chatOverride.Model
 		}
+		if chatOverride.Options != "" {
+			if err = applyOpenAIOptions(openaiConfig, chatOverride.Options); err != nil {
+				log.Error().Err(err).Msg("Failed to unmarshal chat override options")
+				return nil, err
+			}
+		}
+	case genai.ProviderMock:
+		mockConfig, ok := genaiConfig.(*genai.MockConfig)
+		if !ok {
+			return nil, errors.New("invalid config type for mock")
+		}
+		if chatOverride.Model != "" {
+			mockConfig.Model = chatOverride.Model
+		}
+	case genai.ProviderBedrock:
+		bedrockConfig, ok := genaiConfig.(*genai.BedrockConfig)
+		if !ok {
+			return nil, errors.New("invalid config type for Bedrock")
+		}
+		if chatOverride.Model != "" {
+			bedrockConfig.Model = chatOverride.Model
+		}
+		if chatOverride.Options != "" {
+			if err = applyBedrockOptions(bedrockConfig, chatOverride.Options); err != nil {
+				log.Error().Err(err).Msg("Failed to unmarshal chat override options")
+				return nil, err
+			}
+		}
 	}
 
 	return genaiConfig, nil
 }
 
+// copyProviderConfig returns a deep copy of the provider configuration so chat
+// overrides never leak into the global configuration.
+func copyProviderConfig(genaiConfig genai.ProviderConfig) (genai.ProviderConfig, error) {
+	switch cfg := genaiConfig.(type) {
+	case *genai.OllamaConfig:
+		ollamaConfig := *cfg
+		ollamaConfig.Options = maps.Clone(cfg.Options)
+		return &ollamaConfig, nil
+	case *genai.OpenAIConfig:
+		openaiConfig := *cfg
+		return &openaiConfig, nil
+	case *genai.MockConfig:
+		mockConfig := *cfg
+		return &mockConfig, nil
+	case *genai.BedrockConfig:
+		bedrockConfig := *cfg
+		return &bedrockConfig, nil
+	default:
+		// Configurations of providers added with genai.Register are copied shallowly
+		value := reflect.ValueOf(genaiConfig)
+		if value.Kind() != reflect.Pointer || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+			return nil, errors.New("unsupported provider config type")
+		}
+		copied := reflect.New(value.Elem().Type())
+		copied.Elem().Set(value.Elem())
+		providerConfig, ok := copied.Interface().(genai.ProviderConfig)
+		if !ok {
+			return nil, errors.New("unsupported provider config type")
+		}
+		return providerConfig, nil
+	}
+}
+
+// modelName returns the name of the model used by the provider configuration.
+func modelName(genaiConfig genai.ProviderConfig) string {
+	switch cfg := genaiConfig.(type) {
+	case *genai.OllamaConfig:
+		return cfg.Model
+	case *genai.OpenAIConfig:
+		return cfg.Model
+	case *genai.MockConfig:
+		return cfg.Model
+	case *genai.BedrockConfig:
+		return cfg.Model
+	default:
+		return ""
+	}
+}
+
+// replyLanguage returns the language the bot should reply to the message in, if any.
+// A detected language takes precedence over the configured one.
+func (t *Tellama) replyLanguage(text string) string {
+	if t.settings().detectLanguage {
+		if detected := language.Detect(text); detected != "" {
+			return detected
+		}
+	}
+	return t.settings().language
+}
+
+// generationErrorMessage returns the response message for a failed generation.
+func (t *Tellama) generationErrorMessage(err error) string {
+	if errors.Is(err, genai.ErrRateLimited) {
+		return t.settings().responseMessages.ServerBusy
+	}
+	if errors.Is(err, errProviderDown) {
+		return t.settings().responseMessages.ProviderDown
+	}
+	return t.settings().responseMessages.InternalError
+}
+
+// setModelName sets the model used by the provider configuration.
+func setModelName(genaiConfig genai.ProviderConfig, model string) {
+	switch cfg := genaiConfig.(type) {
+	case *genai.OllamaConfig:
+		cfg.Model = model
+	case *genai.OpenAIConfig:
+		cfg.Model = model
+	case *genai.MockConfig:
+		cfg.Model = model
+	case *genai.BedrockConfig:
+		cfg.Model = model
+	}
+}
+
+// generation holds the outcome of generating a single response.
+type generation struct {
+	Response        string
+	Stats           genai.GenerateStats
+	ToolInvocations []database.ToolInvocation
+}
+
 func (t *Tellama) generateResponse(
 	messages []database.Message,
 	genaiClient genai.GenerativeAI,
-) (string, error) {
+) (generation, error) {
 	var response string
 	var genStats genai.GenerateStats
+	var toolInvocations []database.ToolInvocation
 	var err error
 
+	// Mark non-text content so the model knows where it came from
+	messages = tagMessageContents(messages)
+
 	switch t.genaiMode {
 	case genai.ModeChat:
+		// Include the senders of user messages if a message format is configured
+		messages, err = formatUserMessages(messages, t.settings().messageFormat)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to format user messages")
+			return generation{}, err
+		}
+
+		// Keep the conversation within the context size of the model
+		messages, err = t.fitContextWindow(messages, estimateChatTokens)
+		if err != nil {
+			return generation{}, err
+		}
+
+		// Leave out the features that the model does not support
+		capabilities := t.capabilities(genaiClient)
 		genaiMessages := make([]genai.Message, len(messages))
 		for i, message := range messages {
 			genaiMessages[i] = genai.Message{
 				Role:    message.Role,
 				Content: message.Content,
 			}
+			if capabilities.Vision {
+				genaiMessages[i].Images = message.Images
+			}
+		}
+
+		// Do not send requests while the provider is failing
+		if err = t.allowProvider(); err != nil {
+			return generation{}, err
 		}
 
-		// Use the generative AI to chat with the user
-		response, genStats, err = genaiClient.Chat(genaiMessages)
+		// Use the generative AI to chat with the user, calling tools if available
+		toolCaller, ok := genaiClient.(genai.ToolCaller)
+		if ok && len(t.tools) > 0 && capabilities.Tools {
+			response, genStats, toolInvocations, err = t.chatWithTools(
+				genaiMessages,
+				genaiClient,
+				toolCaller,
+			)
+		} else {
+			response, genStats, err = genaiClient.Chat(genaiMessages)
+		}
+		t.observeProvider(err)
 		if err != nil {
 			log.Error().Err(err).Msg("Generative AI completion error")
-			return "", err
+			return generation{}, err
 		}
 	case genai.ModeCompletion:
 		// Create a function map with utility functions
@@ -660,28 +1541,38 @@ func (t *Tellama) generateResponse(
 
 		// Load the prompt template
 		var promptTemplate *template.Template
-		promptTemplate, err = template.New("prompt").Funcs(funcMap).Parse(t.genaiTemplate)
+		promptTemplate, err = template.New("prompt").Funcs(funcMap).Parse(t.settings().genaiTemplate)
 		if err != nil {
 			log.Error().Err(err).Msg("Failed to parse prompt template")
-			return "", err
+			return generation{}, err
 		}
 
-		// Render the prompt to be sent to the generative AI
+		// Render the prompt to be sent to the generative AI, within the context size of the model
 		var prompt bytes.Buffer
-		err = promptTemplate.Execute(&prompt, messages)
+		_, err = t.fitContextWindow(messages, func(messages []database.Message) (int, error) {
+			prompt.Reset()
+			if renderErr := promptTemplate.Execute(&prompt, messages); renderErr != nil {
+				return 0, renderErr
+			}
+			return utilities.EstimateTokens(prompt.String()), nil
+		})
 		if err != nil {
 			log.Error().Err(err).Msg("Failed to execute prompt template")
-			return "", err
+			return generation{}, err
 		}
 
-		// Use the generative AI to complete the prompt
+		// Use the generative AI to complete the prompt, unless the provider is failing
+		if err = t.allowProvider(); err != nil {
+			return generation{}, err
+		}
 		response, genStats, err = genaiClient.Complete(prompt.String())
+		t.observeProvider(err)
 		if err != nil {
 			log.Error().Err(err).Msg("Generative AI completion error")
-			return "", err
+			return generation{}, err
 		}
 	default:
-		return "", fmt.Errorf("unsupported Generative AI mode: %s", t.genaiMode)
+		return generation{}, fmt.Errorf("unsupported Generative AI mode: %s", t.genaiMode)
 	}
 
 	response = strings.TrimSpace(response)
@@ -692,47 +1583,256 @@ func (t *Tellama) generateResponse(
 		Float32("tokens/s", float32(genStats.TokenCount)/float32(genStats.EvalDuration.Seconds())).
 		Msg("Generative AI response")
 
-	// Remove reasoning content
-	if idx := strings.Index(response, "</think>"); idx != -1 {
-		response = strings.TrimSpace(response[idx+len("</think>"):])
+	// The provider removes the reasoning from the response
+	if t.settings().logReasoning && genStats.Reasoning != "" {
+		log.Debug().
+			Str("reasoning", strings.ReplaceAll(genStats.Reasoning, "\n", "\\n")).
+			Msg("Generative AI reasoning")
+	}
+	return generation{
+		Response:        response,
+		Stats:           genStats,
+		ToolInvocations: toolInvocations,
+	}, nil
+}
+
+// contentTypeTags maps content types to the labels prepended to their content in prompts.
+var contentTypeTags = map[string]string{ //nolint:gochecknoglobals // Read-only lookup table
+	database.ContentTypePhotoCaption:    "[Photo]",
+	database.ContentTypeVoiceTranscript: "[Voice message transcript]",
+	database.ContentTypeDocumentExcerpt: "[Document excerpt]",
+	database.ContentTypeToolResult:      "[Tool result]",
+	database.ContentTypeChatEvent:       "[Chat event]",
+}
+
+// tagMessageContents returns a copy of the messages with their content labeled by content type.
+// Messages that carry their original attachment, such as photos, are left unchanged.
+func tagMessageContents(messages []database.Message) []database.Message {
+	tagged := make([]database.Message, len(messages))
+	for i, message := range messages {
+		tag, ok := contentTypeTags[message.ContentType]
+		if ok && len(message.Images) == 0 {
+			message.Content = strings.TrimSpace(tag + " " + message.Content)
+		}
+		tagged[i] = message
+	}
+	return tagged
+}
+
+// formatUserMessages renders the content of user messages with the message format template,
+// leaving the messages unchanged if the format is empty.
+func formatUserMessages(messages []database.Message, format string) ([]database.Message, error) {
+	if format == "" {
+		return messages, nil
+	}
+	messageTemplate, err := template.New("message").Parse(format)
+	if err != nil {
+		return nil, err
+	}
+
+	formatted := make([]database.Message, len(messages))
+	for i, message := range messages {
+		if message.Role == "user" {
+			var content bytes.Buffer
+			if err = messageTemplate.Execute(&content, message); err != nil {
+				return nil, err
+			}
+			message.Content = content.String()
+		}
+		formatted[i] = message
 	}
-	return response, nil
+	return formatted, nil
+}
+
+// chatWithTools runs the tool calling loop until the model produces a final answer
+// or the maximum number of rounds is reached.
+// The executed tool calls are returned so that they can be persisted.
+func (t *Tellama) chatWithTools(
+	messages []genai.Message,
+	genaiClient genai.GenerativeAI,
+	toolCaller genai.ToolCaller,
+) (string, genai.GenerateStats, []database.ToolInvocation, error) {
+	var totalStats genai.GenerateStats
+	var invocations []database.ToolInvocation
+	for round := range t.settings().maxToolRounds {
+		reply, genStats, err := toolCaller.ChatWithTools(messages, t.tools)
+		totalStats.Add(genStats)
+		if err != nil {
+			return "", totalStats, invocations, err
+		}
+		if len(reply.ToolCalls) == 0 {
+			return reply.Content, totalStats, invocations, nil
+		}
+
+		log.Info().
+			Int("round", round+1).
+			Int("tool_calls", len(reply.ToolCalls)).
+			Msg("Executing tool calls")
+
+		results := genai.ExecuteToolCalls(t.tools, reply.ToolCalls)
+		for i, toolCall := range reply.ToolCalls {
+			invocations = append(invocations, database.ToolInvocation{
+				Round:      round + 1,
+				ToolCallID: toolCall.ID,
+				ToolName:   toolCall.Name,
+				Arguments:  toolCall.Arguments,
+				Result:     results[i].Content,
+			})
+		}
+
+		messages = append(messages, reply)
+		messages = append(messages, results...)
+	}
+
+	// Ask for a final answer without offering tools once the round limit is reached
+	log.Warn().Int("max_rounds", t.settings().maxToolRounds).Msg("Tool calling round limit reached")
+	response, genStats, err := genaiClient.Chat(messages)
+	totalStats.Add(genStats)
+	return response, totalStats, invocations, err
+}
+
+// includeToolContext inserts the tool invocations of past generations into the history
+// right before the responses that used them, so the model keeps the tool context.
+func (t *Tellama) includeToolContext(messages []database.Message) ([]database.Message, error) {
+	messageIDs := []uint{}
+	for _, message := range messages {
+		if message.Role == "assistant" && message.ID != 0 {
+			messageIDs = append(messageIDs, message.ID)
+		}
+	}
+	if len(messageIDs) == 0 {
+		return messages, nil
+	}
+
+	invocations, err := t.dm.GetToolInvocations(messageIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(invocations) == 0 {
+		return messages, nil
+	}
+
+	invocationsByMessage := map[uint][]database.ToolInvocation{}
+	for _, invocation := range invocations {
+		invocationsByMessage[invocation.MessageID] = append(
+			invocationsByMessage[invocation.MessageID],
+			invocation,
+		)
+	}
+
+	history := make([]database.Message, 0, len(messages)+len(invocations))
+	for _, message := range messages {
+		for _, invocation := range invocationsByMessage[message.ID] {
+			history = append(history, database.Message{
+				Timestamp:   invocation.Timestamp,
+				ChatID:      message.ChatID,
+				ChatTitle:   message.ChatTitle,
+				Role:        "system",
+				ContentType: database.ContentTypeToolResult,
+				Content: fmt.Sprintf(
+					"%s(%s) returned:\n%s",
+					invocation.ToolName,
+					invocation.Arguments,
+					invocation.Result,
+				),
+			})
+		}
+		history = append(history, message)
+	}
+	return history, nil
 }
 
 func (t *Tellama) storeUserMessage(
 	chat *telebot.Chat,
 	user *telebot.User,
+	message *telebot.Message,
 	text string,
+	contentType string,
+	buffered bool,
 ) error {
-	err := t.dm.StoreMessage(
-		chat.ID,
-		chat.Title,
-		"user",
-		user.ID,
-		user.Username,
-		user.FirstName,
-		user.LastName,
-		text,
-	)
+	// The messages of users who opted out are only passed to the model while replying to them
+	if t.isUserOptedOut(user.ID) {
+		log.Debug().Int64("chat_id", chat.ID).Msg("Skipped storing message of opted-out user")
+		return nil
+	}
+
+	telegramMessageID, replyToMessageID := telegramMessageIDs(message)
+	userMessage := database.Message{
+		Timestamp:         time.Now().UTC(),
+		ChatID:            chat.ID,
+		ChatTitle:         chat.Title,
+		Role:              "user",
+		ContentType:       contentType,
+		UserID:            user.ID,
+		Username:          user.Username,
+		FirstName:         user.FirstName,
+		LastName:          user.LastName,
+		Content:           text,
+		TelegramMessageID: telegramMessageID,
+		ReplyToMessageID:  replyToMessageID,
+		ThreadID:          messageThreadID(message),
+	}
+
+	var err error
+	if buffered {
+		err = t.bufferMessage(userMessage)
+	} else {
+		_, err = t.storeMessage(userMessage)
+	}
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to store user message")
 	}
 	return err
 }
 
-func (t *Tellama) storeBotResponse(chat *telebot.Chat, answer string) error {
-	err := t.dm.StoreMessage(
-		chat.ID,
-		chat.Title,
-		"assistant",
-		t.bot.Me.ID,
-		t.bot.Me.Username,
-		t.bot.Me.FirstName,
-		t.bot.Me.LastName,
-		answer,
-	)
+func (t *Tellama) storeBotResponse(chat *telebot.Chat, sent *telebot.Message, answer string) (uint, error) {
+	telegramMessageID, replyToMessageID := telegramMessageIDs(sent)
+	messageID, err := t.storeMessage(database.Message{
+		Timestamp:         time.Now().UTC(),
+		ChatID:            chat.ID,
+		ChatTitle:         chat.Title,
+		Role:              "assistant",
+		ContentType:       database.ContentTypeText,
+		UserID:            t.bot.Me.ID,
+		Username:          t.bot.Me.Username,
+		FirstName:         t.bot.Me.FirstName,
+		LastName:          t.bot.Me.LastName,
+		Content:           answer,
+		TelegramMessageID: telegramMessageID,
+		ReplyToMessageID:  replyToMessageID,
+		ThreadID:          messageThreadID(sent),
+	})
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to store bot response")
 	}
-	return err
+	return messageID, err
+}
+
+// messageThreadID returns the forum topic of a message, or zero if it is not in a forum topic.
+// Replies in regular groups also carry a thread ID, which is ignored.
+func messageThreadID(message *telebot.Message) int {
+	if message == nil || !message.TopicMessage {
+		return 0
+	}
+	return message.ThreadID
+}
+
+// sendTyping shows the typing indicator in the chat or forum topic of the message.
+func (t *Tellama) sendTyping(chat *telebot.Chat, message *telebot.Message) {
+	if threadID := messageThreadID(message); threadID != 0 {
+		_ = t.bot.Notify(chat, telebot.Typing, threadID)
+		return
+	}
+	_ = t.bot.Notify(chat, telebot.Typing)
+}
+
+// telegramMessageIDs returns the Telegram ID of a message and of the message it replied to.
+func telegramMessageIDs(message *telebot.Message) (int, int) {
+	if message == nil {
+		return 0, 0
+	}
+	if message.ReplyTo == nil {
+		return message.ID, 0
+	}
+	return message.ID, message.ReplyTo.ID
 }
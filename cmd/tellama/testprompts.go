@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/k4yt3x/tellama/internal/breaker"
+	"github.com/k4yt3x/tellama/internal/config"
+	"github.com/k4yt3x/tellama/internal/database"
+	"github.com/k4yt3x/tellama/internal/genai"
+	"github.com/k4yt3x/tellama/internal/prompttest"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"gopkg.in/telebot.v4"
+)
+
+// runTestPrompts is the Cobra command handler that runs a prompt suite against the configured model.
+func runTestPrompts(cmd *cobra.Command, args []string) {
+	configPath, err := cmd.Flags().GetString("config")
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse the config flag")
+	}
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load configuration")
+	}
+
+	suite, err := prompttest.Load(args[0])
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load prompt suite")
+	}
+
+	// Only the generation settings are needed to run the prompts
+	t := &Tellama{
+		genaiProvider:   cfg.GenerativeAI.Provider,
+		genaiMode:       cfg.GenerativeAI.Mode,
+		providerBreaker: breaker.New(breaker.Config{}),
+	}
+	settings, err := newRuntimeSettings(cfg)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load prompt templates")
+	}
+	t.currentSettings.Store(settings)
+
+	genaiClient, err := genai.New(t.genaiProvider, cfg.GenerativeAI.Config)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to create generative AI client")
+	}
+
+	var judge genai.GenerativeAI
+	if suite.HasJudgeChecks() {
+		judge, err = t.newJudgeClient(suite.JudgeModel)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to create judge client")
+		}
+	}
+
+	fmt.Printf("Running %d prompts against %s model %s\n",
+		len(suite.Cases), t.genaiProvider, modelName(cfg.GenerativeAI.Config))
+
+	passed, total := 0, 0
+	for _, testCase := range suite.Cases {
+		reply, caseErr := t.runPromptCase(suite, testCase, genaiClient)
+		if caseErr != nil {
+			total += len(testCase.Checks)
+			fmt.Printf("\nERROR %s: %v\n", testCase.Name, caseErr)
+			continue
+		}
+
+		fmt.Printf("\n%s\n", testCase.Name)
+		for _, check := range testCase.Checks {
+			total++
+			ok, reason, checkErr := check.Evaluate(testCase.Prompt, reply, judge)
+			switch {
+			case checkErr != nil:
+				fmt.Printf("  ERROR %s: %v\n", check.String(), checkErr)
+			case ok:
+				passed++
+				fmt.Printf("  PASS  %s\n", check.String())
+			default:
+				fmt.Printf("  FAIL  %s: %s\n", check.String(), reason)
+			}
+		}
+	}
+
+	fmt.Printf("\n%d of %d checks passed\n", passed, total)
+	if passed != total {
+		log.Fatal().Int("failed", total-passed).Msg("Prompt checks failed")
+	}
+}
+
+// runPromptCase generates the reply to a prompt the same way as for a private chat message.
+func (t *Tellama) runPromptCase(
+	suite *prompttest.Suite,
+	testCase prompttest.Case,
+	genaiClient genai.GenerativeAI,
+) (string, error) {
+	systemPromptTemplateString := defaultSystemPrompt
+	if suite.SystemPrompt != "" {
+		systemPromptTemplateString = suite.SystemPrompt
+	}
+	if testCase.SystemPrompt != "" {
+		systemPromptTemplateString = testCase.SystemPrompt
+	}
+
+	contextInfo := map[string]any{
+		"ChatTitle": "Prompt Test",
+		"ChatType":  telebot.ChatPrivate,
+	}
+	addPersona(contextInfo, t.settings().persona)
+	systemPrompt, err := t.renderSystemPrompt(systemPromptTemplateString, contextInfo)
+	if err != nil {
+		return "", err
+	}
+
+	gen, err := t.generateResponse([]database.Message{
+		{Role: "system", ContentType: database.ContentTypeSystem, FirstName: "system", Content: systemPrompt},
+		{Role: "user", ContentType: database.ContentTypeText, FirstName: "Tester", Content: testCase.Prompt},
+	}, genaiClient)
+	if err != nil {
+		return "", err
+	}
+	return gen.Response, nil
+}
+
+// newJudgeClient creates a chat client for the judge model, which defaults to the configured model.
+func (t *Tellama) newJudgeClient(judgeModel string) (genai.GenerativeAI, error) {
+	genaiConfig, err := copyProviderConfig(t.settings().genaiConfig)
+	if err != nil {
+		return nil, err
+	}
+	if judgeModel != "" {
+		setModelName(genaiConfig, judgeModel)
+	}
+	return genai.New(t.genaiProvider, genaiConfig)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/k4yt3x/tellama/internal/config"
+
+	"github.com/rs/zerolog/log"
+)
+
+// chatTrigger returns the trigger mode of group messages in the chat.
+// The configured trigger mode is used unless the chat overrides it.
+func (t *Tellama) chatTrigger(chatID int64) config.TriggerMode {
+	trigger := t.settings().trigger
+	if t.isDegraded() {
+		return trigger
+	}
+
+	chatOverride, err := t.dm.GetChatOverride(chatID)
+	if err != nil {
+		log.Warn().Err(err).Int64("chat_id", chatID).Msg("Failed to get chat trigger mode")
+		return trigger
+	}
+	if chatOverride.Trigger == "" {
+		return trigger
+	}
+
+	chatTrigger, err := config.ParseTriggerMode(chatOverride.Trigger)
+	if err != nil {
+		log.Warn().Err(err).Int64("chat_id", chatID).Str("trigger", chatOverride.Trigger).Msg("Invalid chat trigger mode")
+		return trigger
+	}
+	return chatTrigger
+}
+
+// containsKeyword reports whether the lowercase text contains one of the keywords as a whole word.
+func containsKeyword(text string, keywords []string) bool {
+	for _, keyword := range keywords {
+		for offset := 0; offset < len(text); {
+			index := strings.Index(text[offset:], keyword)
+			if index < 0 {
+				break
+			}
+			start := offset + index
+			end := start + len(keyword)
+			before, _ := utf8.DecodeLastRuneInString(text[:start])
+			after, _ := utf8.DecodeRuneInString(text[end:])
+			if !isWordRune(before) && !isWordRune(after) {
+				return true
+			}
+			offset = start + 1
+		}
+	}
+	return false
+}
+
+// startsWithMention reports whether the lowercase text starts with the mention,
+// rather than with the mention of a longer username that starts with the same name.
+func startsWithMention(text string, mention string) bool {
+	if !strings.HasPrefix(text, mention) {
+		return false
+	}
+	after, _ := utf8.DecodeRuneInString(text[len(mention):])
+	return !isWordRune(after)
+}
+
+// isWordRune reports whether the rune is part of a word.
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
@@ -0,0 +1,79 @@
+package main //nolint:testpackage // Unit tests are in the same package
+
+import (
+	"testing"
+
+	"github.com/k4yt3x/tellama/internal/config"
+	"github.com/k4yt3x/tellama/internal/database"
+	"github.com/k4yt3x/tellama/internal/database/dbtest"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/telebot.v4"
+)
+
+func TestShouldProcessMessage(t *testing.T) {
+	tests := []struct {
+		name        string
+		trigger     config.TriggerMode
+		chatTrigger string
+		text        string
+		expected    bool
+	}{
+		{name: "Mention anywhere", trigger: config.TriggerMentionAnywhere, text: "hello @tellama_bot!", expected: true},
+		{name: "Mention at the end", trigger: config.TriggerMentionAnywhere, text: "hello @tellama_bot", expected: true},
+		{name: "Mention in another case", trigger: config.TriggerMentionAnywhere, text: "hi @Tellama_Bot", expected: true},
+		{name: "Longer username with a digit", trigger: config.TriggerMentionAnywhere, text: "hi @tellama_bot2"},
+		{name: "Longer username with an underscore", trigger: config.TriggerMentionAnywhere, text: "hi @tellama_bot_fan"},
+		{
+			name:     "Longer username before the mention",
+			trigger:  config.TriggerMentionAnywhere,
+			text:     "@tellama_bot_fan and @tellama_bot",
+			expected: true,
+		},
+		{name: "No mention", trigger: config.TriggerMentionAnywhere, text: "hello everyone"},
+		{name: "Prefix", trigger: config.TriggerPrefix, text: "  @TELLAMA_BOT, hello", expected: true},
+		{name: "Prefix of a longer username", trigger: config.TriggerPrefix, text: "@tellama_bot2 hello"},
+		{name: "Mention after the prefix", trigger: config.TriggerPrefix, text: "hello @tellama_bot"},
+		{name: "Keyword mode mention", trigger: config.TriggerKeywords, text: "ask @tellama_bot", expected: true},
+		{name: "Keyword mode longer username", trigger: config.TriggerKeywords, text: "ask @tellama_botname"},
+		{name: "Keyword", trigger: config.TriggerKeywords, text: "Hey Llama, hello", expected: true},
+		{
+			name:        "Chat trigger mode",
+			trigger:     config.TriggerMentionAnywhere,
+			chatTrigger: "prefix",
+			text:        "hello @tellama_bot",
+		},
+		{
+			name:        "Chat trigger mode prefix",
+			trigger:     config.TriggerMentionAnywhere,
+			chatTrigger: "prefix",
+			text:        "@tellama_bot hello",
+			expected:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			builder := dbtest.NewBuilder(t, dbtest.NewTestManager(t))
+			chatID := builder.TrustedChat("Group")
+			if tt.chatTrigger != "" {
+				builder.ChatOverride(chatID, database.ChatOverride{ChatTitle: "Group", Trigger: tt.chatTrigger})
+			}
+			tellama := &Tellama{
+				bot:      &telebot.Bot{Me: &telebot.User{ID: dbtest.BotUserID, Username: "Tellama_Bot"}},
+				dm:       builder.Manager(),
+				degraded: newDegradedMode(0),
+			}
+			tellama.currentSettings.Store(&runtimeSettings{trigger: tt.trigger, triggerKeywords: []string{"llama"}})
+			chat := &telebot.Chat{ID: chatID, Title: "Group", Type: telebot.ChatGroup}
+			msg := &telebot.Message{Chat: chat, Text: tt.text}
+
+			// Act
+			process := tellama.shouldProcessMessage(chat, msg, tt.text)
+
+			// Assert
+			assert.Equal(t, tt.expected, process)
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/k4yt3x/tellama/internal/database"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// newTrustedUsersCommand creates the command that manages trusted users without running the bot.
+func newTrustedUsersCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "trusted-users",
+		Short: "Manage the users who may use the bot in any chat",
+	}
+
+	addCmd := &cobra.Command{
+		Use:   "add <user id>",
+		Short: "Trust a user in every chat",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			username, err := cmd.Flags().GetString("username")
+			if err != nil {
+				log.Fatal().Err(err).Msg("Failed to parse the username flag")
+			}
+			dm, userID := openTrustedUsersDatabase(cmd, args)
+			defer dm.Close()
+
+			if err = dm.TrustUser(userID, username); err != nil {
+				log.Fatal().Err(err).Msg("Failed to trust user")
+			}
+			log.Info().Int64("user_id", userID).Msg("User trusted")
+		},
+	}
+	addCmd.Flags().String("username", "", "Username of the user, for reference only")
+
+	cmd.AddCommand(addCmd, &cobra.Command{
+		Use:   "remove <user id>",
+		Short: "Stop trusting a user",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			dm, userID := openTrustedUsersDatabase(cmd, args)
+			defer dm.Close()
+
+			if err := dm.UntrustUser(userID); err != nil {
+				log.Fatal().Err(err).Msg("Failed to untrust user")
+			}
+			log.Info().Int64("user_id", userID).Msg("User untrusted")
+		},
+	}, &cobra.Command{
+		Use:   "list",
+		Short: "List the trusted users",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			dm, _ := openTrustedUsersDatabase(cmd, args)
+			defer dm.Close()
+
+			trustedUsers, err := dm.ListTrustedUsers()
+			if err != nil {
+				log.Fatal().Err(err).Msg("Failed to list trusted users")
+			}
+			for _, trustedUser := range trustedUsers {
+				fmt.Printf("%d\t%s\n", trustedUser.UserID, trustedUser.Username)
+			}
+		},
+	})
+	return cmd
+}
+
+// openTrustedUsersDatabase opens the configured database and parses the user ID argument, if any.
+func openTrustedUsersDatabase(cmd *cobra.Command, args []string) (*database.Manager, int64) {
+	var userID int64
+	if len(args) > 0 {
+		var err error
+		userID, err = strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Invalid user ID")
+		}
+	}
+	return openDatabase(cmd), userID
+}
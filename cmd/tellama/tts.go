@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/telebot.v4"
+)
+
+// voiceMessage synthesizes the text as a Telegram voice message.
+func (t *Tellama) voiceMessage(text string) (*telebot.Voice, error) {
+	audio, err := t.tts.Synthesize(context.Background(), text)
+	if err != nil {
+		return nil, err
+	}
+	return &telebot.Voice{File: telebot.FromReader(bytes.NewReader(audio)), MIME: "audio/ogg"}, nil
+}
+
+// sendVoiceReply follows up a reply with the response read out as a voice message.
+// Failures are only logged since the text reply has already been sent.
+func (t *Tellama) sendVoiceReply(chat *telebot.Chat, sent *telebot.Message, response string) {
+	voice, err := t.voiceMessage(response)
+	if err != nil {
+		log.Error().Err(err).Int64("chat_id", chat.ID).Msg("Failed to synthesize voice reply")
+		return
+	}
+	if _, err = t.bot.Send(chat, voice, &telebot.SendOptions{ReplyTo: sent}); err != nil {
+		log.Error().Err(err).Int64("chat_id", chat.ID).Msg("Failed to send voice reply")
+	}
+}
+
+func (t *Tellama) textToSpeech(ctx telebot.Context) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil {
+		return nil
+	}
+
+	if !t.authorize(chat, msg.Sender, msg, permissionUntrusted) {
+		return ctx.Reply("You do not have permission to use this command.")
+	}
+
+	if t.tts == nil {
+		return ctx.Reply("Text-to-speech is disabled.")
+	}
+
+	if msg.ReplyTo == nil {
+		return ctx.Reply("Please reply to the message you want to hear.")
+	}
+	text := msg.ReplyTo.Text
+	if text == "" {
+		text = msg.ReplyTo.Caption
+	}
+	if strings.TrimSpace(text) == "" {
+		return ctx.Reply("Please reply to the message you want to hear.")
+	}
+
+	voice, err := t.voiceMessage(text)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to synthesize speech")
+		return ctx.Reply("Failed to synthesize speech. Please check logs for details.")
+	}
+
+	log.Info().
+		Int64("chat_id", chat.ID).
+		Int64("user_id", msg.Sender.ID).
+		Int("message_id", msg.ReplyTo.ID).
+		Msg("Sending message as speech")
+	_, err = t.bot.Reply(msg.ReplyTo, voice)
+	return err
+}
+
+func (t *Tellama) setVoiceReply(ctx telebot.Context) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil {
+		return nil
+	}
+
+	if !t.authorize(chat, msg.Sender, msg, permissionChatAdmin) {
+		return ctx.Reply("You do not have permission to use this command.")
+	}
+
+	if t.tts == nil {
+		return ctx.Reply("Text-to-speech is disabled.")
+	}
+
+	var enabled bool
+	switch strings.ToLower(strings.TrimSpace(msg.Payload)) {
+	case "on":
+		enabled = true
+	case "off":
+	default:
+		return ctx.Reply("Usage: /setvoicereply <on|off>")
+	}
+
+	if err := t.dm.SetChatVoiceReply(chat.ID, chat.Title, enabled); err != nil {
+		log.Error().Err(err).Msg("Failed to set voice replies")
+		return ctx.Reply("Failed to set voice replies. Please check logs for details.")
+	}
+
+	log.Info().
+		Int64("chat_id", chat.ID).
+		Int64("user_id", msg.Sender.ID).
+		Bool("voice_reply", enabled).
+		Msg("Voice replies set")
+
+	if enabled {
+		return ctx.Reply("Replies will also be sent as voice messages.")
+	}
+	return ctx.Reply("Replies will no longer be sent as voice messages.")
+}
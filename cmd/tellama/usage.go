@@ -0,0 +1,127 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/k4yt3x/tellama/internal/database"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/telebot.v4"
+)
+
+// defaultUsageDays is the number of days reported by /usage if none is given.
+const defaultUsageDays = 30
+
+// maxUsageDays is the largest number of days that can be reported by /usage.
+const maxUsageDays = 365
+
+// usageCommand replies with the tokens used and the estimated cost of the current chat.
+func (t *Tellama) usageCommand(ctx telebot.Context) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil {
+		return nil
+	}
+
+	if !t.authorize(chat, msg.Sender, msg, permissionChatAdmin) {
+		return ctx.Reply("You do not have permission to use this command.")
+	}
+
+	days := defaultUsageDays
+	if payload := strings.TrimSpace(msg.Payload); payload != "" {
+		var err error
+		days, err = strconv.Atoi(payload)
+		if err != nil || days < 1 || days > maxUsageDays {
+			return ctx.Reply(tr(ctx, "Please provide a number of days between 1 and %d.", maxUsageDays))
+		}
+	}
+
+	log.Info().
+		Int64("chat_id", chat.ID).
+		Int64("user_id", msg.Sender.ID).
+		Int("days", days).
+		Msg("Getting usage")
+
+	today := currentUsageDate()
+	sinceDate := time.Now().UTC().AddDate(0, 0, 1-days).Format(time.DateOnly)
+	usages, err := t.dm.GetChatUsageSince(chat.ID, sinceDate)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get chat usage")
+		return ctx.Reply("Failed to get usage. Please check logs for details.")
+	}
+	if len(usages) == 0 {
+		return ctx.Reply(tr(ctx, "No usage has been recorded in this chat in the last %d days.", days))
+	}
+
+	var todayUsages []database.Usage
+	userUsages := map[int64][]database.Usage{}
+	modelUsages := map[string][]database.Usage{}
+	for _, usage := range usages {
+		if usage.Date == today {
+			todayUsages = append(todayUsages, usage)
+		}
+		userUsages[usage.UserID] = append(userUsages[usage.UserID], usage)
+		modelUsages[usage.Model] = append(modelUsages[usage.Model], usage)
+	}
+
+	var reply strings.Builder
+	reply.WriteString("Usage:\n")
+	reply.WriteString(fmt.Sprintf("\nToday: %s", t.formatUsage(todayUsages)))
+	reply.WriteString(fmt.Sprintf("\nLast %d days: %s", days, t.formatUsage(usages)))
+
+	reply.WriteString("\n\nBy model:")
+	for _, model := range sortedUsageKeys(modelUsages) {
+		reply.WriteString(fmt.Sprintf("\n- %s: %s", model, t.formatUsage(modelUsages[model])))
+	}
+
+	reply.WriteString("\n\nBy user:")
+	for _, userID := range sortedUsageKeys(userUsages) {
+		reply.WriteString(fmt.Sprintf("\n- %d: %s", userID, t.formatUsage(userUsages[userID])))
+	}
+
+	if len(t.settings().genaiPricing) == 0 {
+		reply.WriteString("\n\nCosts are not estimated because no model pricing is configured.")
+	}
+	return ctx.Reply(reply.String())
+}
+
+// formatUsage formats the token counts and estimated cost of usage records.
+func (t *Tellama) formatUsage(usages []database.Usage) string {
+	var promptTokens, completionTokens int64
+	for _, usage := range usages {
+		promptTokens += usage.PromptTokens
+		completionTokens += usage.CompletionTokens
+	}
+	totals := t.sumUsage(usages)
+	return fmt.Sprintf(
+		"%d tokens (%d prompt, %d completion), $%.4f",
+		totals.Tokens,
+		promptTokens,
+		completionTokens,
+		totals.Cost,
+	)
+}
+
+// sortedUsageKeys returns the keys of grouped usage records ordered by descending token count.
+func sortedUsageKeys[K cmp.Ordered](groups map[K][]database.Usage) []K {
+	tokens := make(map[K]int64, len(groups))
+	keys := make([]K, 0, len(groups))
+	for key, usages := range groups {
+		for _, usage := range usages {
+			tokens[key] += usage.PromptTokens + usage.CompletionTokens
+		}
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b K) int {
+		if c := cmp.Compare(tokens[b], tokens[a]); c != 0 {
+			return c
+		}
+		return cmp.Compare(a, b)
+	})
+	return keys
+}
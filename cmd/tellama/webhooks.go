@@ -0,0 +1,93 @@
+package main
+
+import (
+	"time"
+
+	"github.com/k4yt3x/tellama/internal/genai"
+	"github.com/k4yt3x/tellama/internal/webhook"
+
+	"gopkg.in/telebot.v4"
+)
+
+// emitEvent posts an event to the webhooks if they are enabled.
+func (t *Tellama) emitEvent(eventType string, data any) {
+	if t.webhooks == nil {
+		return
+	}
+	t.webhooks.Emit(eventType, data)
+}
+
+// webhookText returns the text included in the events of the messages of a user and the responses to them.
+// The text is never included for users who opted out of history storage.
+func (t *Tellama) webhookText(userID int64, text string) string {
+	if t.webhooks == nil || !t.webhooks.IncludeText() || t.isUserOptedOut(userID) {
+		return ""
+	}
+	return text
+}
+
+// emitMessageReceived posts a message received from a chat the bot may respond in.
+func (t *Tellama) emitMessageReceived(chat *telebot.Chat, user *telebot.User, message *telebot.Message, text string) {
+	if t.webhooks == nil {
+		return
+	}
+	t.emitEvent(webhook.EventMessageReceived, webhook.MessageReceived{
+		ChatID:    chat.ID,
+		ChatTitle: chat.Title,
+		ThreadID:  messageThreadID(message),
+		MessageID: message.ID,
+		UserID:    user.ID,
+		Username:  user.Username,
+		Text:      t.webhookText(user.ID, text),
+	})
+}
+
+// emitResponseGenerated posts a response sent in reply to a message.
+func (t *Tellama) emitResponseGenerated(
+	chat *telebot.Chat,
+	user *telebot.User,
+	message *telebot.Message,
+	sent *telebot.Message,
+	model string,
+	stats genai.GenerateStats,
+	duration time.Duration,
+	response string,
+) {
+	if t.webhooks == nil {
+		return
+	}
+	t.emitEvent(webhook.EventResponseGenerated, webhook.ResponseGenerated{
+		ChatID:           chat.ID,
+		MessageID:        sent.ID,
+		ReplyToMessageID: message.ID,
+		UserID:           user.ID,
+		Model:            model,
+		PromptTokens:     stats.PromptTokens,
+		CompletionTokens: stats.TokenCount,
+		DurationMS:       duration.Milliseconds(),
+		Text:             t.webhookText(user.ID, response),
+	})
+}
+
+// emitUntrustedChat posts the first message received from an untrusted chat since the bot started.
+func (t *Tellama) emitUntrustedChat(chat *telebot.Chat, user *telebot.User) {
+	if t.webhooks == nil {
+		return
+	}
+
+	t.untrustedChatsMutex.Lock()
+	_, seen := t.untrustedChats[chat.ID]
+	t.untrustedChats[chat.ID] = struct{}{}
+	t.untrustedChatsMutex.Unlock()
+	if seen {
+		return
+	}
+
+	t.emitEvent(webhook.EventUntrustedChat, webhook.UntrustedChat{
+		ChatID:    chat.ID,
+		ChatTitle: chat.Title,
+		ChatType:  string(chat.Type),
+		UserID:    user.ID,
+		Username:  user.Username,
+	})
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/k4yt3x/tellama/internal/database"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/telebot.v4"
+)
+
+const welcomePrompt = `%s has just joined the chat. Write a short and friendly welcome addressed to them,
+in at most three sentences. If the recent conversation shows what the chat is currently talking about,
+mention one or two of those topics to help them join in. Respond with the welcome message only.`
+
+// welcomeHistoryLimit is the number of recent messages the topics of a welcome are taken from.
+const welcomeHistoryLimit = 30
+
+// welcomeMember posts a generated welcome for a new member of a chat that has enabled welcomes.
+// The welcome is written with the chat's system prompt and model. Failures are logged without replying.
+func (t *Tellama) welcomeMember(ctx telebot.Context, member *telebot.User) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil || member == nil || member.IsBot {
+		return nil
+	}
+	if !t.dm.IsChatTrusted(chat.ID) && !t.allowUntrustedChats {
+		return nil
+	}
+
+	chatOverride, err := t.dm.GetChatOverride(chat.ID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get chat override")
+		return err
+	}
+	if !database.IsTrue(chatOverride.WelcomeMembers) || t.isBudgetExhausted(chat, member) {
+		return nil
+	}
+
+	t.flushMessages()
+	threadID := messageThreadID(msg)
+	history, err := t.dm.GetMessages(chat.ID, threadID, min(t.settings().historyFetchLimit, welcomeHistoryLimit))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get message history")
+		return err
+	}
+
+	if !t.genaiAllowConcurrent {
+		<-t.sem
+		defer func() { t.sem <- struct{}{} }()
+	}
+
+	log.Info().Int64("chat_id", chat.ID).Int64("user_id", member.ID).Msg("Generating welcome for new member")
+	prompt := fmt.Sprintf(welcomePrompt, userDisplayName(member))
+	messages, genaiConfig, genaiClient, err := t.prepareGeneration(
+		chat,
+		member,
+		msg,
+		prompt,
+		database.ContentTypeChatEvent,
+		nil,
+		history,
+		chatOverride,
+	)
+	if err != nil {
+		return err
+	}
+
+	gen, err := t.generateResponse(messages, genaiClient)
+	if err != nil {
+		log.Error().Err(err).Int64("chat_id", chat.ID).Msg("Failed to generate welcome")
+		return err
+	}
+	t.recordUsage(chat, member, modelName(genaiConfig), gen.Stats)
+	welcome := strings.TrimSpace(gen.Response)
+	if welcome == "" {
+		log.Warn().Int64("chat_id", chat.ID).Msg("Received empty welcome from generative AI")
+		return nil
+	}
+	if t.moderateResponses && t.isModerated(chat, welcome, "response") {
+		return nil
+	}
+
+	sent, err := t.sendReply(ctx, msg, chatOverride, welcome)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to send welcome")
+		return err
+	}
+	_, err = t.storeBotResponse(chat, sent, welcome)
+	return err
+}
+
+func (t *Tellama) setWelcome(ctx telebot.Context) error {
+	chat := ctx.Chat()
+	msg := ctx.Message()
+	if chat == nil || msg == nil {
+		return nil
+	}
+
+	if !t.authorize(chat, msg.Sender, msg, permissionChatAdmin) {
+		return ctx.Reply("You do not have permission to use this command.")
+	}
+
+	if chat.Type == telebot.ChatPrivate {
+		return ctx.Reply("New members can only be welcomed in groups.")
+	}
+
+	var enabled bool
+	switch strings.ToLower(strings.TrimSpace(msg.Payload)) {
+	case "on":
+		enabled = true
+	case "off":
+	default:
+		return ctx.Reply("Usage: /setwelcome <on|off>")
+	}
+
+	if err := t.dm.SetChatWelcomeMembers(chat.ID, chat.Title, enabled); err != nil {
+		log.Error().Err(err).Msg("Failed to set new member welcomes")
+		return ctx.Reply("Failed to set new member welcomes. Please check logs for details.")
+	}
+
+	log.Info().
+		Int64("chat_id", chat.ID).
+		Int64("user_id", msg.Sender.ID).
+		Bool("welcome_members", enabled).
+		Msg("New member welcomes set")
+
+	if enabled {
+		return ctx.Reply("New members will be welcomed with a generated message.")
+	}
+	return ctx.Reply("New members will no longer be welcomed.")
+}
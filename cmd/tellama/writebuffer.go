@@ -0,0 +1,98 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/k4yt3x/tellama/internal/database"
+
+	"github.com/rs/zerolog/log"
+)
+
+// writeBuffer holds the messages that do not trigger a response until they are stored in a batch.
+// The history is only read to respond, so the buffered messages are stored before it is read.
+type writeBuffer struct {
+	mu       sync.Mutex
+	size     int
+	interval time.Duration
+	messages []database.Message
+
+	// flushMu keeps concurrent flushes from storing the batches out of order
+	flushMu sync.Mutex
+}
+
+func newWriteBuffer(size int, interval time.Duration) *writeBuffer {
+	return &writeBuffer{
+		size:     size,
+		interval: interval,
+	}
+}
+
+// bufferMessage stores a message in the next batch, or immediately if write buffering is disabled.
+func (t *Tellama) bufferMessage(message database.Message) error {
+	if t.writeBuffer.interval == 0 {
+		_, err := t.storeMessage(message)
+		return err
+	}
+
+	t.rememberMessage(message)
+	if t.isDegraded() {
+		t.queueMessage(message)
+		return nil
+	}
+
+	t.writeBuffer.mu.Lock()
+	t.writeBuffer.messages = append(t.writeBuffer.messages, message)
+	full := len(t.writeBuffer.messages) >= t.writeBuffer.size
+	t.writeBuffer.mu.Unlock()
+
+	if full {
+		t.flushMessages()
+	}
+	return nil
+}
+
+// flushMessages stores the buffered messages.
+// They are queued for degraded mode if the database is unavailable.
+func (t *Tellama) flushMessages() {
+	t.writeBuffer.flushMu.Lock()
+	defer t.writeBuffer.flushMu.Unlock()
+
+	t.writeBuffer.mu.Lock()
+	messages := t.writeBuffer.messages
+	t.writeBuffer.messages = nil
+	t.writeBuffer.mu.Unlock()
+
+	if len(messages) == 0 {
+		return
+	}
+	if t.isDegraded() {
+		for _, message := range messages {
+			t.queueMessage(message)
+		}
+		return
+	}
+
+	err := t.dm.StoreMessages(messages)
+	if err == nil {
+		log.Debug().Int("count", len(messages)).Msg("Stored buffered messages")
+		return
+	}
+	if t.enterDegradedMode(err) {
+		for _, message := range messages {
+			t.queueMessage(message)
+		}
+		return
+	}
+	log.Error().Err(err).Int("dropped", len(messages)).Msg("Failed to store buffered messages")
+}
+
+// runWriteBuffer stores the buffered messages periodically.
+func (t *Tellama) runWriteBuffer() {
+	ticker := time.NewTicker(t.writeBuffer.interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		t.flushMessages()
+	}
+}
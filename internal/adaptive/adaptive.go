@@ -0,0 +1,82 @@
+// Package adaptive adjusts the number of history messages sent to the model of each chat
+// based on the latency of recent generations.
+package adaptive
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+type Config struct {
+	Enabled       bool
+	TargetLatency time.Duration
+	MinMessages   int
+}
+
+func (c *Config) Validate() error {
+	if c.TargetLatency <= 0 {
+		return errors.New("target latency must be positive")
+	}
+	if c.MinMessages < 1 {
+		return errors.New("minimum number of messages must be at least 1")
+	}
+	return nil
+}
+
+// History tracks the history limit of each chat.
+// A generation slower than the target latency shrinks the limit of its chat by a quarter,
+// and a generation faster than half of the target grows it back by a quarter until it no longer applies.
+type History struct {
+	config Config
+	mu     sync.Mutex
+	limits map[int64]int
+}
+
+func NewHistory(config Config) *History {
+	return &History{
+		config: config,
+		limits: map[int64]int{},
+	}
+}
+
+// Limit returns the maximum number of history messages to send for the chat, or zero for no limit.
+func (h *History) Limit(chatID int64) int {
+	if !h.config.Enabled {
+		return 0
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.limits[chatID]
+}
+
+// Observe adjusts the history limit of the chat after a generation with the given number of
+// history messages took the given time.
+func (h *History) Observe(chatID int64, messages int, latency time.Duration) {
+	if !h.config.Enabled {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	limit := h.limits[chatID]
+	switch {
+	case latency > h.config.TargetLatency:
+		limit = max(h.config.MinMessages, messages*3/4)
+	case limit == 0 || latency >= h.config.TargetLatency/2:
+		return
+	case messages < limit:
+		// The history is shorter than the limit, so the limit no longer has an effect
+		limit = 0
+	default:
+		limit += max(1, limit/4)
+	}
+
+	if limit == 0 {
+		delete(h.limits, chatID)
+		return
+	}
+	h.limits[chatID] = limit
+}
@@ -0,0 +1,123 @@
+package adaptive //nolint:testpackage // Unit tests are in the same package
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistory(t *testing.T) {
+	config := Config{Enabled: true, TargetLatency: 10 * time.Second, MinMessages: 10}
+
+	t.Run("No limit by default", func(t *testing.T) {
+		// Arrange
+		history := NewHistory(config)
+
+		// Act
+		limit := history.Limit(1)
+
+		// Assert
+		assert.Zero(t, limit)
+	})
+
+	t.Run("Shrink when slow", func(t *testing.T) {
+		// Arrange
+		history := NewHistory(config)
+
+		// Act
+		history.Observe(1, 100, 20*time.Second)
+		first := history.Limit(1)
+		history.Observe(1, first, 20*time.Second)
+		second := history.Limit(1)
+
+		// Assert
+		assert.Equal(t, 75, first)
+		assert.Equal(t, 56, second)
+		assert.Zero(t, history.Limit(2))
+	})
+
+	t.Run("Never shrink below the minimum", func(t *testing.T) {
+		// Arrange
+		history := NewHistory(config)
+
+		// Act
+		history.Observe(1, 12, 20*time.Second)
+
+		// Assert
+		assert.Equal(t, 10, history.Limit(1))
+	})
+
+	t.Run("Grow when fast", func(t *testing.T) {
+		// Arrange
+		history := NewHistory(config)
+		history.Observe(1, 100, 20*time.Second)
+
+		// Act
+		history.Observe(1, 75, time.Second)
+
+		// Assert
+		assert.Equal(t, 93, history.Limit(1))
+	})
+
+	t.Run("Keep the limit near the target", func(t *testing.T) {
+		// Arrange
+		history := NewHistory(config)
+		history.Observe(1, 100, 20*time.Second)
+
+		// Act
+		history.Observe(1, 75, 8*time.Second)
+
+		// Assert
+		assert.Equal(t, 75, history.Limit(1))
+	})
+
+	t.Run("Remove the limit when the history is shorter", func(t *testing.T) {
+		// Arrange
+		history := NewHistory(config)
+		history.Observe(1, 100, 20*time.Second)
+
+		// Act
+		history.Observe(1, 40, time.Second)
+
+		// Assert
+		assert.Zero(t, history.Limit(1))
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		// Arrange
+		history := NewHistory(Config{TargetLatency: time.Second, MinMessages: 1})
+
+		// Act
+		history.Observe(1, 100, time.Minute)
+
+		// Assert
+		assert.Zero(t, history.Limit(1))
+	})
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		err    string
+	}{
+		{name: "Valid", config: Config{TargetLatency: time.Second, MinMessages: 1}},
+		{name: "Zero target latency", config: Config{MinMessages: 1}, err: "target latency must be positive"},
+		{name: "Zero minimum", config: Config{TargetLatency: time.Second}, err: "at least 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			err := tt.config.Validate()
+
+			// Assert
+			if tt.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.err)
+			}
+		})
+	}
+}
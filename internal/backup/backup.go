@@ -0,0 +1,87 @@
+// Package backup writes database exports as JSON Lines and reads them back.
+package backup
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/k4yt3x/tellama/internal/database"
+)
+
+// FormatJSONL is the JSON Lines format, with one record per line.
+const FormatJSONL = "jsonl"
+
+// Record types of the rows in an export.
+const (
+	typeTrustedChat  = "trusted_chat"
+	typeChatOverride = "chat_override"
+	typeMessage      = "message"
+)
+
+// record is a single line of an export.
+type record struct {
+	Type string `json:"type"`
+	Data any    `json:"data"`
+}
+
+// Write writes the exported rows to w, one record per line.
+func Write(w io.Writer, export database.Export) error {
+	encoder := json.NewEncoder(w)
+	for _, trustedChat := range export.TrustedChats {
+		if err := encoder.Encode(record{Type: typeTrustedChat, Data: trustedChat}); err != nil {
+			return err
+		}
+	}
+	for _, chatOverride := range export.ChatOverrides {
+		if err := encoder.Encode(record{Type: typeChatOverride, Data: chatOverride}); err != nil {
+			return err
+		}
+	}
+	for _, message := range export.Messages {
+		if err := encoder.Encode(record{Type: typeMessage, Data: message}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Read reads the records written by Write from r.
+func Read(r io.Reader) (database.Export, error) {
+	var export database.Export
+	decoder := json.NewDecoder(r)
+	for line := 1; ; line++ {
+		var raw struct {
+			Type string          `json:"type"`
+			Data json.RawMessage `json:"data"`
+		}
+		err := decoder.Decode(&raw)
+		if errors.Is(err, io.EOF) {
+			return export, nil
+		}
+		if err != nil {
+			return database.Export{}, fmt.Errorf("invalid record %d: %w", line, err)
+		}
+
+		switch raw.Type {
+		case typeTrustedChat:
+			var trustedChat database.TrustedChat
+			err = json.Unmarshal(raw.Data, &trustedChat)
+			export.TrustedChats = append(export.TrustedChats, trustedChat)
+		case typeChatOverride:
+			var chatOverride database.ChatOverride
+			err = json.Unmarshal(raw.Data, &chatOverride)
+			export.ChatOverrides = append(export.ChatOverrides, chatOverride)
+		case typeMessage:
+			var message database.Message
+			err = json.Unmarshal(raw.Data, &message)
+			export.Messages = append(export.Messages, message)
+		default:
+			err = fmt.Errorf("unknown record type %q", raw.Type)
+		}
+		if err != nil {
+			return database.Export{}, fmt.Errorf("invalid record %d: %w", line, err)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package backup //nolint:testpackage // Unit tests are in the same package
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/k4yt3x/tellama/internal/database"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteRead(t *testing.T) {
+	// Arrange
+	export := database.Export{
+		TrustedChats:  []database.TrustedChat{{ID: 1, ChatID: -100, ChatTitle: "Group"}},
+		ChatOverrides: []database.ChatOverride{{ID: 2, ChatID: -100, Model: "llama3.3", SystemPrompt: "Be brief."}},
+		Messages: []database.Message{{
+			ID:        3,
+			Timestamp: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+			ChatID:    -100,
+			Role:      "user",
+			Content:   "Hello\nworld",
+			ThreadID:  7,
+		}},
+	}
+
+	// Act
+	var buffer bytes.Buffer
+	err := Write(&buffer, export)
+	require.NoError(t, err)
+	lines := strings.Count(buffer.String(), "\n")
+	result, err := Read(&buffer)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, 3, lines)
+	assert.Equal(t, export, result)
+}
+
+func TestRead_Invalid(t *testing.T) {
+	// Act
+	_, unknownErr := Read(strings.NewReader(`{"type":"trusted_chat","data":{}}` + "\n" + `{"type":"user","data":{}}`))
+	_, syntaxErr := Read(strings.NewReader(`{"type":`))
+
+	// Assert
+	assert.ErrorContains(t, unknownErr, `invalid record 2: unknown record type "user"`)
+	assert.ErrorContains(t, syntaxErr, "invalid record 1")
+}
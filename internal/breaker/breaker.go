@@ -0,0 +1,128 @@
+// Package breaker implements a circuit breaker that stops calls to a failing service
+// for a cooldown period after several consecutive failures.
+package breaker
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+type Config struct {
+	Enabled          bool
+	FailureThreshold int
+	Cooldown         time.Duration
+}
+
+func (c *Config) Validate() error {
+	if c.FailureThreshold < 1 {
+		return errors.New("failure threshold must be at least 1")
+	}
+	if c.Cooldown <= 0 {
+		return errors.New("cooldown must be positive")
+	}
+	return nil
+}
+
+// Breaker opens after the configured number of consecutive failures and rejects calls while it is open.
+// Once the cooldown has passed, the breaker is half-open and allows a single call as a probe:
+// a success closes the breaker, while a failure keeps it open for another cooldown.
+// Other calls are rejected until the probe finishes, or until another cooldown has passed
+// in case the probe never reports its outcome.
+type Breaker struct {
+	config   Config
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+
+	// probeStartedAt is the time the probe of a half-open breaker was allowed, if it has not finished
+	probeStartedAt time.Time
+
+	// now returns the current time, replaced in tests
+	now func() time.Time
+}
+
+func New(config Config) *Breaker {
+	return &Breaker{config: config, now: time.Now}
+}
+
+// Allow reports whether a call may be made.
+// Callers allowed to make a call must report its outcome with Success, Failure, or Release.
+func (b *Breaker) Allow() bool {
+	if !b.config.Enabled {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.openedAt.IsZero() {
+		return true
+	}
+	now := b.now()
+	if now.Sub(b.openedAt) < b.config.Cooldown {
+		return false
+	}
+	if !b.probeStartedAt.IsZero() && now.Sub(b.probeStartedAt) < b.config.Cooldown {
+		return false
+	}
+	b.probeStartedAt = now
+	return true
+}
+
+// IsOpen reports whether the breaker is open, including after the cooldown until a call succeeds.
+func (b *Breaker) IsOpen() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return !b.openedAt.IsZero()
+}
+
+// Success records a successful call, closing the breaker.
+// It returns true if the breaker was open.
+func (b *Breaker) Success() bool {
+	if !b.config.Enabled {
+		return false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	wasOpen := !b.openedAt.IsZero()
+	b.failures = 0
+	b.openedAt = time.Time{}
+	b.probeStartedAt = time.Time{}
+	return wasOpen
+}
+
+// Failure records a failed call, opening the breaker once the failure threshold is reached.
+// A failure while the breaker is open, such as that of a probe, keeps it open for another cooldown.
+// It returns true if the failure opened the breaker.
+func (b *Breaker) Failure() bool {
+	if !b.config.Enabled {
+		return false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	wasOpen := !b.openedAt.IsZero()
+	if !wasOpen && b.failures < b.config.FailureThreshold {
+		return false
+	}
+	b.openedAt = b.now()
+	b.probeStartedAt = time.Time{}
+	return !wasOpen
+}
+
+// Release records a call whose outcome says nothing about the service, such as one rejected for its input.
+// A probe that is released lets the next call probe the service instead.
+func (b *Breaker) Release() {
+	if !b.config.Enabled {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probeStartedAt = time.Time{}
+}
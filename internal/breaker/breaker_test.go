@@ -0,0 +1,209 @@
+package breaker //nolint:testpackage // Unit tests are in the same package
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestBreaker returns a breaker whose clock is controlled by the returned function.
+func newTestBreaker(config Config) (*Breaker, func(time.Duration)) {
+	breaker := New(config)
+	now := time.Now()
+	breaker.now = func() time.Time { return now }
+	return breaker, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestBreaker(t *testing.T) {
+	config := Config{Enabled: true, FailureThreshold: 3, Cooldown: time.Minute}
+
+	t.Run("Open after consecutive failures", func(t *testing.T) {
+		// Arrange
+		breaker, _ := newTestBreaker(config)
+
+		// Act
+		first := breaker.Failure()
+		second := breaker.Failure()
+		third := breaker.Failure()
+
+		// Assert
+		assert.False(t, first)
+		assert.False(t, second)
+		assert.True(t, third)
+		assert.True(t, breaker.IsOpen())
+		assert.False(t, breaker.Allow())
+	})
+
+	t.Run("Success resets the failures", func(t *testing.T) {
+		// Arrange
+		breaker, _ := newTestBreaker(config)
+
+		// Act
+		breaker.Failure()
+		breaker.Failure()
+		recovered := breaker.Success()
+		opened := breaker.Failure()
+
+		// Assert
+		assert.False(t, recovered)
+		assert.False(t, opened)
+		assert.True(t, breaker.Allow())
+	})
+
+	t.Run("Trial after the cooldown", func(t *testing.T) {
+		// Arrange
+		breaker, advance := newTestBreaker(config)
+		for range 3 {
+			breaker.Failure()
+		}
+
+		// Act
+		advance(time.Minute)
+		allowed := breaker.Allow()
+		reopened := breaker.Failure()
+		rejected := !breaker.Allow()
+
+		// Assert
+		assert.True(t, allowed)
+		assert.False(t, reopened)
+		assert.True(t, rejected)
+	})
+
+	t.Run("Single probe while half-open", func(t *testing.T) {
+		// Arrange
+		breaker, advance := newTestBreaker(config)
+		for range 3 {
+			breaker.Failure()
+		}
+		advance(time.Minute)
+
+		// Act
+		probe := breaker.Allow()
+		var others []bool
+		for range 5 {
+			others = append(others, breaker.Allow())
+		}
+		reopened := breaker.Failure()
+		afterFailure := breaker.Allow()
+		advance(time.Minute)
+		secondProbe := breaker.Allow()
+		secondOthers := breaker.Allow()
+		recovered := breaker.Success()
+
+		// Assert
+		assert.True(t, probe)
+		assert.Equal(t, []bool{false, false, false, false, false}, others)
+		assert.False(t, reopened)
+		assert.False(t, afterFailure)
+		assert.True(t, secondProbe)
+		assert.False(t, secondOthers)
+		assert.True(t, recovered)
+		assert.True(t, breaker.Allow())
+		assert.True(t, breaker.Allow())
+	})
+
+	t.Run("Single probe among concurrent calls", func(t *testing.T) {
+		// Arrange
+		breaker, advance := newTestBreaker(config)
+		for range 3 {
+			breaker.Failure()
+		}
+		advance(time.Minute)
+
+		// Act
+		var allowed atomic.Int32
+		var wg sync.WaitGroup
+		for range 20 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if breaker.Allow() {
+					allowed.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+
+		// Assert
+		assert.Equal(t, int32(1), allowed.Load())
+		assert.True(t, breaker.IsOpen())
+	})
+
+	t.Run("Released probe", func(t *testing.T) {
+		// Arrange
+		breaker, advance := newTestBreaker(config)
+		for range 3 {
+			breaker.Failure()
+		}
+		advance(time.Minute)
+		breaker.Allow()
+
+		// Act
+		breaker.Release()
+		nextProbe := breaker.Allow()
+		others := breaker.Allow()
+
+		// Assert
+		assert.True(t, nextProbe)
+		assert.False(t, others)
+		assert.True(t, breaker.IsOpen())
+	})
+
+	t.Run("Unfinished probe", func(t *testing.T) {
+		// Arrange
+		breaker, advance := newTestBreaker(config)
+		for range 3 {
+			breaker.Failure()
+		}
+		advance(time.Minute)
+		breaker.Allow()
+
+		// Act
+		advance(30 * time.Second)
+		waiting := breaker.Allow()
+		advance(30 * time.Second)
+		nextProbe := breaker.Allow()
+
+		// Assert
+		assert.False(t, waiting)
+		assert.True(t, nextProbe)
+	})
+
+	t.Run("Close after a successful trial", func(t *testing.T) {
+		// Arrange
+		breaker, advance := newTestBreaker(config)
+		for range 3 {
+			breaker.Failure()
+		}
+		advance(time.Minute)
+
+		// Act
+		recovered := breaker.Success()
+
+		// Assert
+		assert.True(t, recovered)
+		assert.False(t, breaker.IsOpen())
+		assert.True(t, breaker.Allow())
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		// Arrange
+		breaker, _ := newTestBreaker(Config{FailureThreshold: 1, Cooldown: time.Minute})
+
+		// Act
+		opened := breaker.Failure()
+
+		// Assert
+		assert.False(t, opened)
+		assert.True(t, breaker.Allow())
+	})
+}
+
+func TestConfigValidate(t *testing.T) {
+	assert.NoError(t, (&Config{FailureThreshold: 1, Cooldown: time.Second}).Validate())
+	assert.Error(t, (&Config{FailureThreshold: 0, Cooldown: time.Second}).Validate())
+	assert.Error(t, (&Config{FailureThreshold: 1}).Validate())
+}
@@ -1,35 +1,343 @@
 package config
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/k4yt3x/tellama/internal/adaptive"
+	"github.com/k4yt3x/tellama/internal/breaker"
+	"github.com/k4yt3x/tellama/internal/dashboard"
+	"github.com/k4yt3x/tellama/internal/database"
 	"github.com/k4yt3x/tellama/internal/genai"
+	"github.com/k4yt3x/tellama/internal/moderation"
+	"github.com/k4yt3x/tellama/internal/rag"
+	"github.com/k4yt3x/tellama/internal/redact"
+	"github.com/k4yt3x/tellama/internal/routing"
+	"github.com/k4yt3x/tellama/internal/scripting"
+	"github.com/k4yt3x/tellama/internal/secrets"
+	"github.com/k4yt3x/tellama/internal/tts"
+	"github.com/k4yt3x/tellama/internal/webhook"
+	"github.com/k4yt3x/tellama/internal/websearch"
 
 	"github.com/rs/zerolog/log"
+	"github.com/spf13/cast"
 	"github.com/spf13/viper"
 )
 
 // Config holds all the configuration values for the application.
 type Config struct {
 	Database struct {
+		Driver            database.Driver
 		Path              string
+		DSN               string
+		AutoMigrate       bool
 		HistoryFetchLimit int
+		EncryptionKey     []byte
+		DegradedQueueSize int
+		Redaction         redact.Config
+		SQLite            database.SQLiteConfig
+
+		WriteBufferSize     int
+		WriteBufferInterval time.Duration
+		OverrideCacheTTL    time.Duration
 	}
 	Telegram struct {
-		BotToken           string
-		Timeout            time.Duration
-		AllowUntrustedChat bool
+		BotToken                string
+		Timeout                 time.Duration
+		AllowUntrustedChat      bool
+		AdminUserIDs            []int64
+		RequireChatAdmin        bool
+		StatsFooterChatIDs      []int64
+		HandoffMinLength        int
+		BackfillMissedMessages  bool
+		FileReplyMinLength      int
+		FileReplyCodeRatio      float64
+		Acknowledgement         AcknowledgementPolicy
+		AcknowledgementEmoji    string
+		RegenerateEditedReplies bool
+		ChatEvents              []string
+		Trigger                 TriggerMode
+		TriggerKeywords         []string
+		DeleteCommands          []string
+		DeleteCommandsDelay     time.Duration
+		ChannelPosts            bool
+		ChannelComments         bool
 	}
 	GenerativeAI struct {
-		Provider        genai.Provider
-		Mode            genai.Mode
-		Timeout         time.Duration
-		AllowConcurrent bool
-		Template        string
-		Config          genai.ProviderConfig
+		Provider         genai.Provider
+		Mode             genai.Mode
+		Timeout          time.Duration
+		AllowConcurrent  bool
+		Vision           bool
+		MaxTurns         int
+		MaxToolRounds    int
+		MaxContextTokens int
+		EmptyResponse    EmptyResponsePolicy
+		Language         string
+		DetectLanguage   bool
+		LogReasoning     bool
+		MessageFormat    string
+		Template         string
+		Config           genai.ProviderConfig
+		Pricing          map[string]ModelPricing
+		Routing          routing.Config
+		AdaptiveHistory  adaptive.Config
+		CircuitBreaker   breaker.Config
+		Capabilities     genai.CapabilityOverrides
+		ContextStrategy  ContextStrategy
+		SemanticContext  SemanticContext
+		DailyTokenBudget int64
+	}
+	Tools struct {
+		WebSearch websearch.Config
 	}
+	RAG              rag.Config
+	TTS              tts.Config
+	Moderation       moderation.Config
+	Webhooks         webhook.Config
+	Alerts           Alerts
+	KeepAlive        KeepAlive
+	ModelRefresh     ModelRefresh
+	Metrics          Metrics
+	Dashboard        dashboard.Config
+	Scripting        scripting.Config
+	Scheduler        Scheduler
+	Documents        Documents
+	Abuse            Abuse
 	ResponseMessages ResponseMessages
+	Persona          Persona
+	Personas         map[string]NamedPersona
+	Prompts          Prompts
+}
+
+// EmptyResponsePolicy controls how empty responses from the generative AI are handled.
+type EmptyResponsePolicy int
+
+const (
+	// EmptyResponseIgnore drops empty responses without replying.
+	EmptyResponseIgnore EmptyResponsePolicy = iota
+	// EmptyResponseRetry retries the generation once with adjusted parameters
+	// and falls back to the empty response message if it is still empty.
+	EmptyResponseRetry
+	// EmptyResponseReply replies with the empty response message.
+	EmptyResponseReply
+)
+
+func (p EmptyResponsePolicy) String() string {
+	return [...]string{"ignore", "retry", "reply"}[p]
+}
+
+func ParseEmptyResponsePolicy(s string) (EmptyResponsePolicy, error) {
+	switch s {
+	case "ignore":
+		return EmptyResponseIgnore, nil
+	case "retry":
+		return EmptyResponseRetry, nil
+	case "reply":
+		return EmptyResponseReply, nil
+	default:
+		return 0, errors.New("unknown empty response policy")
+	}
+}
+
+// AcknowledgementPolicy controls how messages that are stored without a reply are acknowledged.
+type AcknowledgementPolicy int
+
+const (
+	// AcknowledgementNone stores messages silently.
+	AcknowledgementNone AcknowledgementPolicy = iota
+	// AcknowledgementReaction reacts to stored messages with an emoji.
+	AcknowledgementReaction
+)
+
+func (p AcknowledgementPolicy) String() string {
+	return [...]string{"none", "reaction"}[p]
+}
+
+func ParseAcknowledgementPolicy(s string) (AcknowledgementPolicy, error) {
+	switch s {
+	case "none":
+		return AcknowledgementNone, nil
+	case "reaction":
+		return AcknowledgementReaction, nil
+	default:
+		return 0, errors.New("unknown acknowledgement policy")
+	}
+}
+
+// TriggerMode controls which group messages trigger a response.
+// Private messages and replies to the bot always trigger a response.
+type TriggerMode int
+
+const (
+	// TriggerPrefix responds to messages that start with a mention of the bot.
+	TriggerPrefix TriggerMode = iota
+	// TriggerMentionAnywhere responds to messages that mention the bot anywhere in the text.
+	TriggerMentionAnywhere
+	// TriggerKeywords responds to messages that mention the bot or contain one of the keywords.
+	TriggerKeywords
+)
+
+func (m TriggerMode) String() string {
+	return [...]string{"prefix", "mention-anywhere", "keywords"}[m]
+}
+
+func ParseTriggerMode(s string) (TriggerMode, error) {
+	switch s {
+	case "prefix":
+		return TriggerPrefix, nil
+	case "mention-anywhere":
+		return TriggerMentionAnywhere, nil
+	case "keywords":
+		return TriggerKeywords, nil
+	default:
+		return 0, errors.New("unknown trigger mode")
+	}
+}
+
+// ContextStrategy controls which past messages of a chat are included in the context of a response.
+type ContextStrategy int
+
+const (
+	// ContextRecent includes the most recent messages.
+	ContextRecent ContextStrategy = iota
+	// ContextSemantic includes the most recent messages and the older ones most similar to the current message.
+	ContextSemantic
+)
+
+func (s ContextStrategy) String() string {
+	return [...]string{"recent", "semantic"}[s]
+}
+
+func ParseContextStrategy(s string) (ContextStrategy, error) {
+	switch s {
+	case "recent":
+		return ContextRecent, nil
+	case "semantic":
+		return ContextSemantic, nil
+	default:
+		return 0, errors.New("unknown context strategy")
+	}
+}
+
+// Structural chat events that can be recorded in the message history.
+const (
+	ChatEventJoin  = "join"
+	ChatEventLeave = "leave"
+	ChatEventTitle = "title"
+	ChatEventPin   = "pin"
+)
+
+// ParseChatEvents validates a list of chat event names and removes duplicates.
+func ParseChatEvents(names []string) ([]string, error) {
+	var events []string
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case "":
+			continue
+		case ChatEventJoin, ChatEventLeave, ChatEventTitle, ChatEventPin:
+			if !slices.Contains(events, name) {
+				events = append(events, name)
+			}
+		default:
+			return nil, fmt.Errorf("unknown chat event %q", name)
+		}
+	}
+	return events, nil
+}
+
+// ModelPricing holds the estimated cost in USD per one million tokens for a model.
+type ModelPricing struct {
+	Prompt     float64
+	Completion float64
+}
+
+// Alerts contains the daily generation budget alert thresholds.
+// A threshold of zero disables the corresponding alert.
+type Alerts struct {
+	ChatDailyTokens   int64
+	ChatDailyCost     float64
+	GlobalDailyTokens int64
+	GlobalDailyCost   float64
+	FallbackModel     string
+}
+
+// KeepAlive contains the connection pre-warming and idle keep-alive settings.
+type KeepAlive struct {
+	Enabled      bool
+	WarmOnStart  bool
+	IdleInterval time.Duration
+}
+
+// ModelRefresh contains the settings of the nightly Ollama model update check.
+type ModelRefresh struct {
+	Enabled bool
+	// MaintenanceTime is the time of day after midnight UTC when the check runs.
+	MaintenanceTime time.Duration
+	AutoPull        bool
+	RegistryURL     string
+}
+
+// Metrics contains the settings of the Prometheus metrics endpoint.
+type Metrics struct {
+	Enabled       bool
+	ListenAddress string
+}
+
+// Scheduler contains the settings of the scheduled prompts.
+type Scheduler struct {
+	MaxPromptsPerChat int
+}
+
+// Documents contains the settings of answering questions about uploaded documents.
+type Documents struct {
+	Enabled          bool
+	MaxFileSize      int64
+	MaxExcerptLength int
+}
+
+// SemanticContext contains the settings of the semantic context strategy.
+// The embeddings of messages are computed with the document retrieval embedding model.
+type SemanticContext struct {
+	TopK           int
+	RecentMessages int
+	Candidates     int
+	MinScore       float64
+}
+
+// Abuse contains the limits above which a user is silenced for the cooldown period.
+// A limit of zero disables the corresponding check.
+type Abuse struct {
+	MaxMessagesPerMinute int
+	MaxMessageLength     int
+	Cooldown             time.Duration
+}
+
+// Persona contains the name and character of the assistant used by the default system prompt.
+type Persona struct {
+	Name        string
+	Description string
+	Style       string
+}
+
+// NamedPersona is a set of prompt and model settings that chats can switch to with /setpersona.
+// Empty fields leave the settings of the chat unchanged.
+type NamedPersona struct {
+	SystemPrompt string
+	Model        string
+	// Options are the JSON encoded provider options, merged over the options of the chat
+	Options string
+}
+
+// Prompts contains the settings of the system prompt template library.
+type Prompts struct {
+	Directory string
 }
 
 // ResponseMessages contains customizable message templates for different scenarios.
@@ -37,6 +345,14 @@ type ResponseMessages struct {
 	PrivateChatDisallowed string
 	InternalError         string
 	ServerBusy            string
+	ContextRefreshed      string
+	EmptyResponse         string
+	Start                 string
+	DocumentTooLarge      string
+	UserSilenced          string
+	Moderated             string
+	BudgetExhausted       string
+	ProviderDown          string
 }
 
 // setupConfigPaths configures viper with the paths to look for config files.
@@ -51,6 +367,15 @@ func setupConfigPaths(configPath string) {
 	}
 }
 
+// setupEnvironment configures viper to read overrides from environment variables.
+// Keys are prefixed with TELLAMA_ and use underscores in place of dots,
+// so telegram.bot_token is read from TELLAMA_TELEGRAM_BOT_TOKEN.
+func setupEnvironment() {
+	viper.SetEnvPrefix("tellama")
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	viper.AutomaticEnv()
+}
+
 // logConfigFile logs the path to the config file being used.
 func logConfigFile() {
 	configFile := viper.ConfigFileUsed()
@@ -62,31 +387,187 @@ func logConfigFile() {
 // setDefaultValues sets default values for configuration options.
 func setDefaultValues() {
 	// Database defaults
+	viper.SetDefault("database.driver", "sqlite")
 	viper.SetDefault("database.path", "tellama.db")
+	viper.SetDefault("database.dsn", "")
+	viper.SetDefault("database.auto_migrate", true)
 	viper.SetDefault("database.history_fetch_limit", 10000)
+	viper.SetDefault("database.encryption_key", "")
+	viper.SetDefault("database.degraded_queue_size", 1000)
+	viper.SetDefault("database.write_buffer_size", 100)
+	viper.SetDefault("database.write_buffer_interval", "0s")
+	viper.SetDefault("database.override_cache_ttl", "1m")
+	viper.SetDefault("database.redaction.enabled", false)
+	viper.SetDefault("database.redaction.credit_cards", true)
+	viper.SetDefault("database.redaction.emails", true)
+	viper.SetDefault("database.redaction.phone_numbers", true)
+	viper.SetDefault("database.redaction.patterns", []string{})
+	viper.SetDefault("database.sqlite.journal_mode", "wal")
+	viper.SetDefault("database.sqlite.synchronous", "normal")
+	viper.SetDefault("database.sqlite.busy_timeout", "5s")
+	viper.SetDefault("database.sqlite.max_open_conns", 0)
+	viper.SetDefault("database.sqlite.max_idle_conns", 0)
+	viper.SetDefault("database.sqlite.conn_max_lifetime", "0s")
 
 	// Telegram defaults
 	viper.SetDefault("telegram.timeout", 10*time.Second)
 	viper.SetDefault("telegram.allow_untrusted_chats", false)
+	viper.SetDefault("telegram.admin_user_ids", []int64{})
+	viper.SetDefault("telegram.require_chat_admin", true)
+	viper.SetDefault("telegram.stats_footer_chat_ids", []int64{})
+	viper.SetDefault("telegram.handoff_min_length", 0)
+	viper.SetDefault("telegram.backfill_missed_messages", false)
+	viper.SetDefault("telegram.file_reply_min_length", 0)
+	viper.SetDefault("telegram.file_reply_code_ratio", 0.5)
+	viper.SetDefault("telegram.acknowledgement", "none")
+	viper.SetDefault("telegram.acknowledgement_emoji", "👀")
+	viper.SetDefault("telegram.regenerate_edited_replies", false)
+	viper.SetDefault("telegram.chat_events", []string{})
+	viper.SetDefault("telegram.trigger", "mention-anywhere")
+	viper.SetDefault("telegram.trigger_keywords", []string{})
+	viper.SetDefault("telegram.delete_commands", []string{})
+	viper.SetDefault("telegram.delete_commands_delay", 5*time.Second)
+	viper.SetDefault("telegram.channel_posts", false)
+	viper.SetDefault("telegram.channel_comments", false)
 
 	// GenAI defaults
 	viper.SetDefault("genai.timeout", 10*time.Second)
 	viper.SetDefault("genai.allow_concurrent", false)
+	viper.SetDefault("genai.vision", true)
+	viper.SetDefault("genai.max_turns", 0)
+	viper.SetDefault("genai.max_tool_rounds", 5)
+	viper.SetDefault("genai.max_context_tokens", 0)
+	viper.SetDefault("genai.empty_response_policy", "ignore")
+	viper.SetDefault("genai.language", "")
+	viper.SetDefault("genai.detect_language", false)
+	viper.SetDefault("genai.log_reasoning", false)
+	viper.SetDefault("genai.message_format", "")
+	viper.SetDefault("genai.daily_token_budget", 0)
 	viper.SetDefault("genai.mode", "chat")
+	viper.SetDefault("genai.routing.enabled", false)
+	viper.SetDefault("genai.routing.simple_max_length", 80)
+	viper.SetDefault("genai.routing.complex_min_length", 800)
+	viper.SetDefault("genai.routing.complex_min_lines", 10)
+	viper.SetDefault("genai.adaptive_history.enabled", false)
+	viper.SetDefault("genai.adaptive_history.target_latency", 15*time.Second)
+	viper.SetDefault("genai.adaptive_history.min_messages", 10)
+	viper.SetDefault("genai.circuit_breaker.enabled", false)
+	viper.SetDefault("genai.circuit_breaker.failure_threshold", 5)
+	viper.SetDefault("genai.circuit_breaker.cooldown", time.Minute)
+	viper.SetDefault("genai.context_strategy", "recent")
+	viper.SetDefault("genai.semantic_context.top_k", 8)
+	viper.SetDefault("genai.semantic_context.recent_messages", 10)
+	viper.SetDefault("genai.semantic_context.candidates", 1000)
+	viper.SetDefault("genai.semantic_context.min_score", 0.3)
+	viper.SetDefault("genai.retry.max_attempts", 3)
+	viper.SetDefault("genai.retry.base_delay", time.Second)
+	viper.SetDefault("genai.retry.max_delay", 30*time.Second)
+	viper.SetDefault("genai.retry.jitter", 0.2)
+
+	// Tool defaults
+	viper.SetDefault("tools.websearch.enabled", false)
+	viper.SetDefault("tools.websearch.backend", "searxng")
+	viper.SetDefault("tools.websearch.max_results", 5)
+	viper.SetDefault("tools.websearch.timeout", 10*time.Second)
+
+	// Retrieval defaults
+	viper.SetDefault("rag.enabled", false)
+	viper.SetDefault("rag.chunk_size", 1000)
+	viper.SetDefault("rag.chunk_overlap", 200)
+	viper.SetDefault("rag.top_k", 4)
+	viper.SetDefault("rag.min_score", 0.3)
+	viper.SetDefault("rag.max_document_size", 10*1024*1024)
+
+	// Text-to-speech defaults
+	viper.SetDefault("tts.enabled", false)
+	viper.SetDefault("tts.backend", "openai")
+	viper.SetDefault("tts.base_url", "")
+	viper.SetDefault("tts.model", "tts-1")
+	viper.SetDefault("tts.voice", "alloy")
+	viper.SetDefault("tts.ffmpeg_path", "ffmpeg")
+	viper.SetDefault("tts.max_length", 4096)
+	viper.SetDefault("tts.timeout", 60*time.Second)
+
+	// Moderation defaults
+	viper.SetDefault("moderation.enabled", false)
+	viper.SetDefault("moderation.backend", "openai")
+	viper.SetDefault("moderation.base_url", "")
+	viper.SetDefault("moderation.model", "omni-moderation-latest")
+	viper.SetDefault("moderation.threshold", 0.5)
+	viper.SetDefault("moderation.thresholds", map[string]float64{})
+	viper.SetDefault("moderation.check_responses", false)
+	viper.SetDefault("moderation.timeout", 10*time.Second)
+	viper.SetDefault("webhooks.enabled", false)
+	viper.SetDefault("webhooks.urls", []string{})
+	viper.SetDefault("webhooks.secret", "")
+	viper.SetDefault("webhooks.events", []string{})
+	viper.SetDefault("webhooks.include_text", false)
+	viper.SetDefault("webhooks.timeout", 10*time.Second)
+	viper.SetDefault("messages.moderated", "Sorry, I can't help with that.")
+
+	// Alert defaults
+	viper.SetDefault("alerts.chat_daily_tokens", 0)
+	viper.SetDefault("alerts.chat_daily_cost", 0.0)
+	viper.SetDefault("alerts.global_daily_tokens", 0)
+	viper.SetDefault("alerts.global_daily_cost", 0.0)
+
+	// Keep-alive defaults
+	viper.SetDefault("keepalive.enabled", false)
+	viper.SetDefault("keepalive.warm_on_start", true)
+	viper.SetDefault("keepalive.idle_interval", 5*time.Minute)
+	viper.SetDefault("model_refresh.enabled", false)
+	viper.SetDefault("model_refresh.maintenance_time", "03:00")
+	viper.SetDefault("model_refresh.auto_pull", false)
+	viper.SetDefault("model_refresh.registry_url", genai.DefaultOllamaRegistry)
+	viper.SetDefault("scheduler.max_prompts_per_chat", 10)
+	viper.SetDefault("documents.enabled", true)
+	viper.SetDefault("documents.max_file_size", 1024*1024)
+	viper.SetDefault("documents.max_excerpt_length", 20000)
+	viper.SetDefault("messages.document_too_large", "The document is too large. Please send a smaller file.")
+	viper.SetDefault("abuse.max_messages_per_minute", 0)
+	viper.SetDefault("abuse.max_message_length", 0)
+	viper.SetDefault("abuse.cooldown", 10*time.Minute)
+	viper.SetDefault("messages.user_silenced", "You are sending messages too quickly. Please try again later.")
+	viper.SetDefault("messages.provider_down", "The AI service is unavailable at the moment. Please try again later.")
+	viper.SetDefault("messages.budget_exhausted", "This chat has used up its daily budget. Please try again tomorrow.")
+	viper.SetDefault("metrics.enabled", false)
+	viper.SetDefault("persona.name", "Tellama")
+	viper.SetDefault("persona.description", "an AI chatbot built by K4YT3X for Telegram group chats")
+	viper.SetDefault("persona.style", "")
+	viper.SetDefault("prompts.directory", "prompts")
+	viper.SetDefault("metrics.listen_address", "127.0.0.1:9464")
+	viper.SetDefault("dashboard.enabled", false)
+	viper.SetDefault("dashboard.listen_address", "127.0.0.1:9465")
+	viper.SetDefault("dashboard.token", "")
+	viper.SetDefault("scripting.enabled", false)
+	viper.SetDefault("scripting.directory", "scripts")
+	viper.SetDefault("scripting.timeout", time.Second)
 
 	// Ollama defaults
 	viper.SetDefault("ollama.base_url", "http://localhost:11434")
 	viper.SetDefault("ollama.model", "llama3.3:70b")
+	viper.SetDefault("ollama.reasoning_pattern", genai.DefaultReasoningPattern)
+	viper.SetDefault("ollama.keep_alive", "")
 
 	// OpenAI defaults
 	viper.SetDefault("openai.base_url", "https://api.openai.com/v1/")
 	viper.SetDefault("openai.model", "gpt-4o")
-	viper.SetDefault("openai.frequency_penalty", 0.0)
+	viper.SetDefault("openai.compatibility", "strict")
 	viper.SetDefault("openai.max_tokens", -1)
-	viper.SetDefault("openai.presence_penalty", 0.0)
-	viper.SetDefault("openai.reasoning_effort", "medium")
-	viper.SetDefault("openai.temperature", 1.0)
-	viper.SetDefault("openai.top_p", 1.0)
+	viper.SetDefault("openai.reasoning_pattern", genai.DefaultReasoningPattern)
+	viper.SetDefault("openai.reasoning_field", "reasoning_content")
+
+	// Bedrock defaults
+	viper.SetDefault("bedrock.region", "")
+	viper.SetDefault("bedrock.model", "anthropic.claude-3-5-sonnet-20240620-v1:0")
+	viper.SetDefault("bedrock.endpoint", "")
+	viper.SetDefault("bedrock.profile", "")
+	viper.SetDefault("bedrock.max_tokens", -1)
+
+	// Mock defaults
+	viper.SetDefault("mock.model", "mock")
+	viper.SetDefault("mock.response", "")
+	viper.SetDefault("mock.latency", "0s")
 }
 
 // createOllamaConfig creates Ollama provider configuration.
@@ -104,12 +585,17 @@ func createOllamaConfig() *genai.OllamaConfig {
 	log.Debug().Str("model", ollamaModel).Msg("Using Ollama model")
 
 	return &genai.OllamaConfig{
-		BaseURL: ollamaBaseURL,
-		Model:   ollamaModel,
-		Options: ollamaOptions,
+		BaseURL:          ollamaBaseURL,
+		Model:            ollamaModel,
+		Options:          ollamaOptions,
+		ReasoningPattern: viper.GetString("ollama.reasoning_pattern"),
+		KeepAlive:        viper.GetString("ollama.keep_alive"),
 	}
 }
 
+// unsetValue marks an optional provider option as unset so that it is not sent.
+const unsetValue = "unset"
+
 // createOpenAIConfig creates OpenAI provider configuration.
 func createOpenAIConfig() (*genai.OpenAIConfig, error) {
 	openaiBaseURL := viper.GetString("openai.base_url")
@@ -120,42 +606,564 @@ func createOpenAIConfig() (*genai.OpenAIConfig, error) {
 		return nil, errors.New("OpenAI API key is required")
 	}
 
+	compatibility, err := genai.ParseCompatibility(viper.GetString("openai.compatibility"))
+	if err != nil {
+		return nil, err
+	}
+
 	log.Debug().Str("base_url", openaiBaseURL).Msg("Using OpenAI base URL")
 	log.Debug().Str("model", openaiModel).Msg("Using OpenAI model")
+	log.Debug().Str("compatibility", compatibility.String()).Msg("Using OpenAI compatibility profile")
 
-	return &genai.OpenAIConfig{
+	config := &genai.OpenAIConfig{
 		BaseURL:          openaiBaseURL,
 		APIKey:           openaiAPIKey,
 		Model:            openaiModel,
-		FrequencyPenalty: viper.GetFloat64("openai.frequency_penalty"),
+		Compatibility:    compatibility,
 		MaxTokens:        viper.GetInt64("openai.max_tokens"),
-		PresencePenalty:  viper.GetFloat64("openai.presence_penalty"),
-		ReasoningEffort:  viper.GetString("openai.reasoning_effort"),
-		Stop:             viper.GetString("openai.stop"),
-		Temperature:      viper.GetFloat64("openai.temperature"),
-		TopP:             viper.GetFloat64("openai.top_p"),
-	}, nil
+		ReasoningEffort:  optionalString("openai.reasoning_effort"),
+		Stop:             optionalString("openai.stop"),
+		ReasoningPattern: viper.GetString("openai.reasoning_pattern"),
+		ReasoningField:   viper.GetString("openai.reasoning_field"),
+	}
+	for key, field := range map[string]**float64{
+		"openai.frequency_penalty": &config.FrequencyPenalty,
+		"openai.presence_penalty":  &config.PresencePenalty,
+		"openai.temperature":       &config.Temperature,
+		"openai.top_p":             &config.TopP,
+	} {
+		if *field, err = optionalFloat(key); err != nil {
+			return nil, err
+		}
+	}
+	return config, nil
+}
+
+// optionalString returns the string value of a key, or an empty string if it is set to "unset".
+func optionalString(key string) string {
+	value := viper.GetString(key)
+	if value == unsetValue {
+		return ""
+	}
+	return value
+}
+
+// optionalFloat returns the float value of a key, or nil if it is not set or set to "unset"
+// so that the provider's default is used instead of a zero value.
+func optionalFloat(key string) (*float64, error) {
+	if !viper.IsSet(key) || viper.GetString(key) == unsetValue {
+		return nil, nil //nolint:nilnil // An unset option is not an error
+	}
+	value, err := cast.ToFloat64E(viper.Get(key))
+	if err != nil {
+		return nil, fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+	return &value, nil
+}
+
+// optionalBool returns the boolean value of a key, or nil if it is not set or set to "unset".
+func optionalBool(key string) (*bool, error) {
+	if !viper.IsSet(key) || viper.GetString(key) == unsetValue {
+		return nil, nil //nolint:nilnil // An unset option is not an error
+	}
+	value, err := cast.ToBoolE(viper.Get(key))
+	if err != nil {
+		return nil, fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+	return &value, nil
+}
+
+// createCapabilityOverrides creates the capabilities that override the detected model capabilities.
+func createCapabilityOverrides() (genai.CapabilityOverrides, error) {
+	var overrides genai.CapabilityOverrides
+	for key, field := range map[string]**bool{
+		"genai.capabilities.tools":             &overrides.Tools,
+		"genai.capabilities.vision":            &overrides.Vision,
+		"genai.capabilities.streaming":         &overrides.Streaming,
+		"genai.capabilities.structured_output": &overrides.StructuredOutput,
+		"genai.capabilities.reasoning":         &overrides.Reasoning,
+	} {
+		var err error
+		if *field, err = optionalBool(key); err != nil {
+			return genai.CapabilityOverrides{}, err
+		}
+		if *field != nil {
+			log.Debug().Str("key", key).Bool("value", **field).Msg("Override model capability")
+		}
+	}
+	return overrides, nil
+}
+
+// createWebSearchConfig creates the web search tool configuration.
+func createWebSearchConfig() (websearch.Config, error) {
+	backend, err := websearch.ParseBackend(viper.GetString("tools.websearch.backend"))
+	if err != nil {
+		return websearch.Config{}, err
+	}
+
+	config := websearch.Config{
+		Enabled:    viper.GetBool("tools.websearch.enabled"),
+		Backend:    backend,
+		BaseURL:    viper.GetString("tools.websearch.base_url"),
+		APIKey:     viper.GetString("tools.websearch.api_key"),
+		MaxResults: viper.GetInt("tools.websearch.max_results"),
+		Timeout:    viper.GetDuration("tools.websearch.timeout"),
+	}
+	if config.Enabled {
+		if err = config.Validate(); err != nil {
+			return websearch.Config{}, err
+		}
+	}
+
+	log.Debug().Bool("enabled", config.Enabled).
+		Str("backend", config.Backend.String()).
+		Msg("Using web search tool")
+	return config, nil
+}
+
+// createTTSConfig creates the text-to-speech configuration.
+func createTTSConfig() (tts.Config, error) {
+	backend, err := tts.ParseBackend(viper.GetString("tts.backend"))
+	if err != nil {
+		return tts.Config{}, err
+	}
+
+	config := tts.Config{
+		Enabled:    viper.GetBool("tts.enabled"),
+		Backend:    backend,
+		BaseURL:    viper.GetString("tts.base_url"),
+		APIKey:     viper.GetString("tts.api_key"),
+		Model:      viper.GetString("tts.model"),
+		Voice:      viper.GetString("tts.voice"),
+		FFmpegPath: viper.GetString("tts.ffmpeg_path"),
+		MaxLength:  viper.GetInt("tts.max_length"),
+		Timeout:    viper.GetDuration("tts.timeout"),
+	}
+	if config.Enabled {
+		if err = config.Validate(); err != nil {
+			return tts.Config{}, err
+		}
+	}
+
+	log.Debug().Bool("enabled", config.Enabled).
+		Str("backend", config.Backend.String()).
+		Str("voice", config.Voice).
+		Msg("Using text-to-speech")
+	return config, nil
+}
+
+// createModerationConfig creates the content moderation configuration.
+func createModerationConfig() (moderation.Config, error) {
+	backend, err := moderation.ParseBackend(viper.GetString("moderation.backend"))
+	if err != nil {
+		return moderation.Config{}, err
+	}
+
+	thresholds := map[string]float64{}
+	for category, value := range viper.GetStringMap("moderation.thresholds") {
+		thresholds[category], err = cast.ToFloat64E(value)
+		if err != nil {
+			return moderation.Config{}, fmt.Errorf("invalid threshold of category %s: %w", category, err)
+		}
+	}
+
+	config := moderation.Config{
+		Enabled:        viper.GetBool("moderation.enabled"),
+		Backend:        backend,
+		BaseURL:        viper.GetString("moderation.base_url"),
+		APIKey:         viper.GetString("moderation.api_key"),
+		Model:          viper.GetString("moderation.model"),
+		Threshold:      viper.GetFloat64("moderation.threshold"),
+		Thresholds:     thresholds,
+		CheckResponses: viper.GetBool("moderation.check_responses"),
+		Timeout:        viper.GetDuration("moderation.timeout"),
+	}
+	if config.Enabled {
+		if err = config.Validate(); err != nil {
+			return moderation.Config{}, err
+		}
+	}
+
+	log.Debug().Bool("enabled", config.Enabled).
+		Str("backend", config.Backend.String()).
+		Bool("check_responses", config.CheckResponses).
+		Msg("Using content moderation")
+	return config, nil
+}
+
+// createRoutingConfig creates the model routing configuration.
+// createWebhookConfig creates the configuration of the outgoing webhooks.
+func createWebhookConfig() (webhook.Config, error) {
+	config := webhook.Config{
+		Enabled:     viper.GetBool("webhooks.enabled"),
+		URLs:        viper.GetStringSlice("webhooks.urls"),
+		Secret:      viper.GetString("webhooks.secret"),
+		Events:      viper.GetStringSlice("webhooks.events"),
+		IncludeText: viper.GetBool("webhooks.include_text"),
+		Timeout:     viper.GetDuration("webhooks.timeout"),
+	}
+	if config.Enabled {
+		if err := config.Validate(); err != nil {
+			return webhook.Config{}, err
+		}
+	}
+
+	log.Debug().Bool("enabled", config.Enabled).
+		Int("urls", len(config.URLs)).
+		Strs("events", config.Events).
+		Bool("signed", config.Secret != "").
+		Msg("Using webhooks")
+	return config, nil
+}
+
+// createDashboardConfig creates the configuration of the web dashboard.
+func createDashboardConfig() (dashboard.Config, error) {
+	config := dashboard.Config{
+		Enabled:       viper.GetBool("dashboard.enabled"),
+		ListenAddress: viper.GetString("dashboard.listen_address"),
+		Token:         viper.GetString("dashboard.token"),
+	}
+	if config.Enabled {
+		if err := config.Validate(); err != nil {
+			return dashboard.Config{}, err
+		}
+	}
+
+	log.Debug().Bool("enabled", config.Enabled).
+		Str("listen_address", config.ListenAddress).
+		Msg("Using dashboard")
+	return config, nil
+}
+
+// createScriptingConfig creates the configuration of the Starlark scripts.
+func createScriptingConfig() (scripting.Config, error) {
+	config := scripting.Config{
+		Enabled:   viper.GetBool("scripting.enabled"),
+		Directory: viper.GetString("scripting.directory"),
+		Timeout:   viper.GetDuration("scripting.timeout"),
+	}
+	if config.Enabled {
+		if err := config.Validate(); err != nil {
+			return scripting.Config{}, err
+		}
+	}
+
+	log.Debug().Bool("enabled", config.Enabled).
+		Str("directory", config.Directory).
+		Dur("timeout", config.Timeout).
+		Msg("Using scripting")
+	return config, nil
+}
+
+func createRoutingConfig() (routing.Config, error) {
+	config := routing.Config{
+		Enabled:          viper.GetBool("genai.routing.enabled"),
+		SmallModel:       viper.GetString("genai.routing.small_model"),
+		LargeModel:       viper.GetString("genai.routing.large_model"),
+		SimpleMaxLength:  viper.GetInt("genai.routing.simple_max_length"),
+		ComplexMinLength: viper.GetInt("genai.routing.complex_min_length"),
+		ComplexMinLines:  viper.GetInt("genai.routing.complex_min_lines"),
+	}
+	if config.Enabled {
+		if err := config.Validate(); err != nil {
+			return routing.Config{}, err
+		}
+	}
+
+	log.Debug().Bool("enabled", config.Enabled).
+		Str("small_model", config.SmallModel).
+		Str("large_model", config.LargeModel).
+		Msg("Using model routing")
+	return config, nil
+}
+
+// createAdaptiveHistoryConfig creates the latency-based history sizing configuration.
+func createAdaptiveHistoryConfig() (adaptive.Config, error) {
+	config := adaptive.Config{
+		Enabled:       viper.GetBool("genai.adaptive_history.enabled"),
+		TargetLatency: viper.GetDuration("genai.adaptive_history.target_latency"),
+		MinMessages:   viper.GetInt("genai.adaptive_history.min_messages"),
+	}
+	if config.Enabled {
+		if err := config.Validate(); err != nil {
+			return adaptive.Config{}, err
+		}
+	}
+
+	log.Debug().Bool("enabled", config.Enabled).
+		Dur("target_latency", config.TargetLatency).
+		Int("min_messages", config.MinMessages).
+		Msg("Using adaptive history sizing")
+	return config, nil
+}
+
+// createSemanticContext creates the semantic context strategy configuration.
+func createSemanticContext(strategy ContextStrategy, embeddingModel string) (SemanticContext, error) {
+	config := SemanticContext{
+		TopK:           viper.GetInt("genai.semantic_context.top_k"),
+		RecentMessages: viper.GetInt("genai.semantic_context.recent_messages"),
+		Candidates:     viper.GetInt("genai.semantic_context.candidates"),
+		MinScore:       viper.GetFloat64("genai.semantic_context.min_score"),
+	}
+	if strategy == ContextSemantic {
+		switch {
+		case embeddingModel == "":
+			return SemanticContext{}, errors.New("the semantic context strategy requires rag.embedding_model")
+		case config.TopK < 1:
+			return SemanticContext{}, errors.New("top k must be at least 1")
+		case config.RecentMessages < 0:
+			return SemanticContext{}, errors.New("recent messages cannot be negative")
+		case config.Candidates < 1:
+			return SemanticContext{}, errors.New("candidates must be at least 1")
+		}
+	}
+
+	log.Debug().Str("strategy", strategy.String()).
+		Int("top_k", config.TopK).
+		Int("recent_messages", config.RecentMessages).
+		Int("candidates", config.Candidates).
+		Msg("Using context strategy")
+	return config, nil
+}
+
+// createCircuitBreakerConfig creates the provider circuit breaker configuration.
+func createCircuitBreakerConfig() (breaker.Config, error) {
+	config := breaker.Config{
+		Enabled:          viper.GetBool("genai.circuit_breaker.enabled"),
+		FailureThreshold: viper.GetInt("genai.circuit_breaker.failure_threshold"),
+		Cooldown:         viper.GetDuration("genai.circuit_breaker.cooldown"),
+	}
+	if config.Enabled {
+		if err := config.Validate(); err != nil {
+			return breaker.Config{}, err
+		}
+	}
+
+	log.Debug().Bool("enabled", config.Enabled).
+		Int("failure_threshold", config.FailureThreshold).
+		Dur("cooldown", config.Cooldown).
+		Msg("Using provider circuit breaker")
+	return config, nil
+}
+
+// createRAGConfig creates the document retrieval configuration.
+func createRAGConfig() (rag.Config, error) {
+	config := rag.Config{
+		Enabled:         viper.GetBool("rag.enabled"),
+		EmbeddingModel:  viper.GetString("rag.embedding_model"),
+		ChunkSize:       viper.GetInt("rag.chunk_size"),
+		ChunkOverlap:    viper.GetInt("rag.chunk_overlap"),
+		TopK:            viper.GetInt("rag.top_k"),
+		MinScore:        viper.GetFloat64("rag.min_score"),
+		MaxDocumentSize: viper.GetInt64("rag.max_document_size"),
+	}
+	if config.Enabled {
+		if err := config.Validate(); err != nil {
+			return rag.Config{}, err
+		}
+	}
+
+	log.Debug().Bool("enabled", config.Enabled).
+		Str("embedding_model", config.EmbeddingModel).
+		Msg("Using document retrieval")
+	return config, nil
+}
+
+// createPricing creates the per-model pricing table.
+func createPricing() map[string]ModelPricing {
+	pricing := map[string]ModelPricing{}
+
+	// Model names may contain dots, so the nested keys cannot be addressed directly
+	for model, entry := range viper.GetStringMap("genai.pricing") {
+		values, ok := entry.(map[string]any)
+		if !ok {
+			continue
+		}
+		pricing[model] = ModelPricing{
+			Prompt:     cast.ToFloat64(values["prompt"]),
+			Completion: cast.ToFloat64(values["completion"]),
+		}
+		log.Debug().Str("model", model).Msg("Loaded model pricing")
+	}
+	return pricing
+}
+
+// createPersonas creates the named personas that chats can switch to.
+func createPersonas() (map[string]NamedPersona, error) {
+	personas := map[string]NamedPersona{}
+	for name, entry := range viper.GetStringMap("personas") {
+		values, ok := entry.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("persona %q must be a map", name)
+		}
+		if name == "default" || strings.ContainsAny(name, " \t") {
+			return nil, fmt.Errorf("invalid persona name %q", name)
+		}
+
+		persona := NamedPersona{
+			SystemPrompt: cast.ToString(values["system_prompt"]),
+			Model:        cast.ToString(values["model"]),
+		}
+		if options, exists := values["options"]; exists && options != nil {
+			optionsMap, err := cast.ToStringMapE(options)
+			if err != nil {
+				return nil, fmt.Errorf("options of persona %q must be a map: %w", name, err)
+			}
+			data, err := json.Marshal(optionsMap)
+			if err != nil {
+				return nil, fmt.Errorf("invalid options of persona %q: %w", name, err)
+			}
+			persona.Options = string(data)
+		}
+		personas[name] = persona
+		log.Debug().Str("name", name).Str("model", persona.Model).Msg("Loaded persona")
+	}
+	return personas, nil
+}
+
+// parseUserIDs converts a list of Telegram user IDs from the config into int64 values.
+func parseUserIDs(value any) ([]int64, error) {
+	items, err := cast.ToSliceE(value)
+	if err != nil {
+		// Fall back to a comma or space separated string
+		items = []any{}
+		for _, field := range strings.FieldsFunc(cast.ToString(value), func(r rune) bool {
+			return r == ',' || r == ' '
+		}) {
+			items = append(items, field)
+		}
+	}
+
+	ids := make([]int64, 0, len(items))
+	for _, item := range items {
+		id, castErr := cast.ToInt64E(item)
+		if castErr != nil {
+			return nil, castErr
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// createBedrockConfig creates AWS Bedrock provider configuration.
+func createBedrockConfig() (*genai.BedrockConfig, error) {
+	// Fall back to the region of the AWS CLI environment
+	region := viper.GetString("bedrock.region")
+	if region == "" {
+		region = os.Getenv("AWS_REGION")
+	}
+	if region == "" {
+		return nil, errors.New("bedrock region is required")
+	}
+
+	config := &genai.BedrockConfig{
+		Region:          region,
+		Model:           viper.GetString("bedrock.model"),
+		Endpoint:        viper.GetString("bedrock.endpoint"),
+		AccessKeyID:     viper.GetString("bedrock.access_key_id"),
+		SecretAccessKey: viper.GetString("bedrock.secret_access_key"),
+		SessionToken:    viper.GetString("bedrock.session_token"),
+		Profile:         viper.GetString("bedrock.profile"),
+		MaxTokens:       viper.GetInt64("bedrock.max_tokens"),
+		Stop:            optionalString("bedrock.stop"),
+	}
+	for key, field := range map[string]**float64{
+		"bedrock.temperature": &config.Temperature,
+		"bedrock.top_p":       &config.TopP,
+	} {
+		var err error
+		if *field, err = optionalFloat(key); err != nil {
+			return nil, err
+		}
+	}
+
+	log.Debug().Str("region", config.Region).Msg("Using Bedrock region")
+	log.Debug().Str("model", config.Model).Msg("Using Bedrock model")
+	return config, nil
+}
+
+// createMockConfig creates mock provider configuration.
+func createMockConfig() *genai.MockConfig {
+	mockConfig := &genai.MockConfig{
+		Model:    viper.GetString("mock.model"),
+		Response: viper.GetString("mock.response"),
+		Latency:  viper.GetDuration("mock.latency"),
+	}
+
+	log.Debug().Str("model", mockConfig.Model).Msg("Using mock model")
+	log.Debug().Dur("latency", mockConfig.Latency).Msg("Using mock latency")
+	return mockConfig
+}
+
+// createRetryPolicy creates the retry policy for transient provider errors.
+func createRetryPolicy() (genai.RetryPolicy, error) {
+	policy := genai.RetryPolicy{
+		MaxAttempts: viper.GetInt("genai.retry.max_attempts"),
+		BaseDelay:   viper.GetDuration("genai.retry.base_delay"),
+		MaxDelay:    viper.GetDuration("genai.retry.max_delay"),
+		Jitter:      viper.GetFloat64("genai.retry.jitter"),
+	}
+	if err := policy.Validate(); err != nil {
+		return genai.RetryPolicy{}, err
+	}
+
+	log.Debug().Int("max_attempts", policy.MaxAttempts).
+		Dur("base_delay", policy.BaseDelay).
+		Dur("max_delay", policy.MaxDelay).
+		Float64("jitter", policy.Jitter).
+		Msg("Using provider retry policy")
+	return policy, nil
 }
 
 // createProviderConfig creates the provider-specific configuration.
 func createProviderConfig(provider genai.Provider) (genai.ProviderConfig, error) {
+	retry, err := createRetryPolicy()
+	if err != nil {
+		return nil, fmt.Errorf("invalid retry config: %w", err)
+	}
+
 	switch provider {
 	case genai.ProviderOllama:
-		return createOllamaConfig(), nil
+		config := createOllamaConfig()
+		config.Retry = retry
+		return config, nil
 	case genai.ProviderOpenAI:
-		config, err := createOpenAIConfig()
+		var config *genai.OpenAIConfig
+		config, err = createOpenAIConfig()
+		if err != nil {
+			return nil, err
+		}
+		config.Retry = retry
+		return config, nil
+	case genai.ProviderBedrock:
+		var config *genai.BedrockConfig
+		config, err = createBedrockConfig()
 		if err != nil {
 			return nil, err
 		}
+		config.Retry = retry
 		return config, nil
+	case genai.ProviderMock:
+		return createMockConfig(), nil
 	default:
-		return nil, errors.New("unsupported generative AI provider")
+		// Providers added with genai.Register are configured from the section named after them
+		var config genai.ProviderConfig
+		config, err = genai.NewProviderConfig(provider)
+		if err != nil {
+			return nil, err
+		}
+		if err = viper.UnmarshalKey(provider.String(), config); err != nil {
+			return nil, fmt.Errorf("invalid %s config: %w", provider, err)
+		}
+		log.Debug().Str("provider", provider.String()).Msg("Using registered provider config")
+		return config, nil
 	}
 }
 
 // Load loads the configuration file and returns a Config struct.
 func Load(configPath string) (*Config, error) {
 	setupConfigPaths(configPath)
+	setupEnvironment()
 
 	if err := viper.ReadInConfig(); err != nil {
 		var cfErr viper.ConfigFileNotFoundError
@@ -167,11 +1175,84 @@ func Load(configPath string) (*Config, error) {
 	logConfigFile()
 	setDefaultValues()
 
+	var err error
 	config := &Config{}
+	config.Database.Driver, err = database.ParseDriver(viper.GetString("database.driver"))
+	if err != nil {
+		return nil, err
+	}
 	config.Database.Path = viper.GetString("database.path")
+	config.Database.DSN = viper.GetString("database.dsn")
+	if config.Database.Driver == database.DriverMySQL && config.Database.DSN == "" {
+		return nil, errors.New("database DSN is required for the mysql driver")
+	}
+	log.Debug().Str("driver", config.Database.Driver.String()).Msg("Using database driver")
+	config.Database.AutoMigrate = viper.GetBool("database.auto_migrate")
 	config.Database.HistoryFetchLimit = viper.GetInt("database.history_fetch_limit")
 	log.Debug().Str("path", config.Database.Path).Msg("Using database path")
 	log.Debug().Int("limit", config.Database.HistoryFetchLimit).Msg("Using history fetch limit")
+	if encryptionKey := viper.GetString("database.encryption_key"); encryptionKey != "" {
+		config.Database.EncryptionKey, err = secrets.ParseKey(encryptionKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid database encryption key: %w", err)
+		}
+		log.Debug().Msg("Database encryption key configured")
+	}
+	config.Database.DegradedQueueSize = viper.GetInt("database.degraded_queue_size")
+	if config.Database.DegradedQueueSize < 0 {
+		return nil, errors.New("database degraded queue size cannot be negative")
+	}
+	log.Debug().Int("size", config.Database.DegradedQueueSize).Msg("Using degraded mode queue size")
+	config.Database.WriteBufferSize = viper.GetInt("database.write_buffer_size")
+	config.Database.WriteBufferInterval = viper.GetDuration("database.write_buffer_interval")
+	if config.Database.WriteBufferInterval < 0 {
+		return nil, errors.New("database write buffer interval cannot be negative")
+	}
+	if config.Database.WriteBufferInterval > 0 && config.Database.WriteBufferSize < 1 {
+		return nil, errors.New("database write buffer size must be at least 1")
+	}
+	log.Debug().
+		Int("size", config.Database.WriteBufferSize).
+		Dur("interval", config.Database.WriteBufferInterval).
+		Msg("Using message write buffer settings")
+	config.Database.OverrideCacheTTL = viper.GetDuration("database.override_cache_ttl")
+	if config.Database.OverrideCacheTTL < 0 {
+		return nil, errors.New("database override cache TTL cannot be negative")
+	}
+	log.Debug().Dur("ttl", config.Database.OverrideCacheTTL).Msg("Using chat override cache TTL")
+	config.Database.Redaction = redact.Config{
+		Enabled:      viper.GetBool("database.redaction.enabled"),
+		CreditCards:  viper.GetBool("database.redaction.credit_cards"),
+		Emails:       viper.GetBool("database.redaction.emails"),
+		PhoneNumbers: viper.GetBool("database.redaction.phone_numbers"),
+		Patterns:     viper.GetStringSlice("database.redaction.patterns"),
+	}
+	if config.Database.Redaction.Enabled {
+		if err = config.Database.Redaction.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid redaction config: %w", err)
+		}
+	}
+	log.Debug().
+		Bool("enabled", config.Database.Redaction.Enabled).
+		Int("patterns", len(config.Database.Redaction.Patterns)).
+		Msg("Using message redaction settings")
+	config.Database.SQLite = database.SQLiteConfig{
+		JournalMode:     viper.GetString("database.sqlite.journal_mode"),
+		Synchronous:     viper.GetString("database.sqlite.synchronous"),
+		BusyTimeout:     viper.GetDuration("database.sqlite.busy_timeout"),
+		MaxOpenConns:    viper.GetInt("database.sqlite.max_open_conns"),
+		MaxIdleConns:    viper.GetInt("database.sqlite.max_idle_conns"),
+		ConnMaxLifetime: viper.GetDuration("database.sqlite.conn_max_lifetime"),
+	}
+	if err = config.Database.SQLite.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid SQLite config: %w", err)
+	}
+	log.Debug().
+		Str("journal_mode", config.Database.SQLite.JournalMode).
+		Str("synchronous", config.Database.SQLite.Synchronous).
+		Dur("busy_timeout", config.Database.SQLite.BusyTimeout).
+		Int("max_open_conns", config.Database.SQLite.MaxOpenConns).
+		Msg("Using SQLite settings")
 
 	// Telegram settings
 	config.Telegram.BotToken = viper.GetString("telegram.bot_token")
@@ -181,7 +1262,81 @@ func Load(configPath string) (*Config, error) {
 	config.Telegram.Timeout = viper.GetDuration("telegram.timeout")
 	config.Telegram.AllowUntrustedChat = viper.GetBool("telegram.allow_untrusted_chats")
 	log.Debug().Dur("timeout", config.Telegram.Timeout).Msg("Using Telegram timeout")
+	config.Telegram.AdminUserIDs, err = parseUserIDs(viper.Get("telegram.admin_user_ids"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid admin user IDs: %w", err)
+	}
 	log.Debug().Bool("value", config.Telegram.AllowUntrustedChat).Msg("Allow untrusted chats")
+	log.Debug().Ints64("ids", config.Telegram.AdminUserIDs).Msg("Using admin user IDs")
+	config.Telegram.RequireChatAdmin = viper.GetBool("telegram.require_chat_admin")
+	log.Debug().Bool("value", config.Telegram.RequireChatAdmin).Msg("Require chat admin for configuration commands")
+	config.Telegram.StatsFooterChatIDs, err = parseUserIDs(viper.Get("telegram.stats_footer_chat_ids"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid stats footer chat IDs: %w", err)
+	}
+	log.Debug().Ints64("ids", config.Telegram.StatsFooterChatIDs).Msg("Using stats footer chat IDs")
+	config.Telegram.HandoffMinLength = viper.GetInt("telegram.handoff_min_length")
+	log.Debug().Int("min_length", config.Telegram.HandoffMinLength).Msg("Using DM handoff threshold")
+	config.Telegram.BackfillMissedMessages = viper.GetBool("telegram.backfill_missed_messages")
+	log.Debug().Bool("value", config.Telegram.BackfillMissedMessages).Msg("Backfill missed messages")
+	config.Telegram.FileReplyMinLength = viper.GetInt("telegram.file_reply_min_length")
+	config.Telegram.FileReplyCodeRatio = viper.GetFloat64("telegram.file_reply_code_ratio")
+	log.Debug().
+		Int("min_length", config.Telegram.FileReplyMinLength).
+		Float64("code_ratio", config.Telegram.FileReplyCodeRatio).
+		Msg("Using file reply thresholds")
+	config.Telegram.Acknowledgement, err = ParseAcknowledgementPolicy(
+		viper.GetString("telegram.acknowledgement"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	config.Telegram.AcknowledgementEmoji = viper.GetString("telegram.acknowledgement_emoji")
+	log.Debug().
+		Str("policy", config.Telegram.Acknowledgement.String()).
+		Str("emoji", config.Telegram.AcknowledgementEmoji).
+		Msg("Using stored message acknowledgement")
+	config.Telegram.RegenerateEditedReplies = viper.GetBool("telegram.regenerate_edited_replies")
+	log.Debug().
+		Bool("regenerate", config.Telegram.RegenerateEditedReplies).
+		Msg("Using edited message handling")
+	config.Telegram.ChatEvents, err = ParseChatEvents(viper.GetStringSlice("telegram.chat_events"))
+	if err != nil {
+		return nil, err
+	}
+	log.Debug().Strs("events", config.Telegram.ChatEvents).Msg("Using recorded chat events")
+	config.Telegram.Trigger, err = ParseTriggerMode(viper.GetString("telegram.trigger"))
+	if err != nil {
+		return nil, err
+	}
+	for _, keyword := range viper.GetStringSlice("telegram.trigger_keywords") {
+		if keyword = strings.ToLower(strings.TrimSpace(keyword)); keyword != "" {
+			config.Telegram.TriggerKeywords = append(config.Telegram.TriggerKeywords, keyword)
+		}
+	}
+	if config.Telegram.Trigger == TriggerKeywords && len(config.Telegram.TriggerKeywords) == 0 {
+		return nil, errors.New("the keywords trigger mode requires at least one trigger keyword")
+	}
+	log.Debug().
+		Str("mode", config.Telegram.Trigger.String()).
+		Strs("keywords", config.Telegram.TriggerKeywords).
+		Msg("Using message trigger")
+	for _, command := range viper.GetStringSlice("telegram.delete_commands") {
+		if command = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(command), "/")); command != "" {
+			config.Telegram.DeleteCommands = append(config.Telegram.DeleteCommands, command)
+		}
+	}
+	config.Telegram.DeleteCommandsDelay = viper.GetDuration("telegram.delete_commands_delay")
+	log.Debug().
+		Strs("commands", config.Telegram.DeleteCommands).
+		Dur("delay", config.Telegram.DeleteCommandsDelay).
+		Msg("Using command message deletion")
+	config.Telegram.ChannelPosts = viper.GetBool("telegram.channel_posts")
+	config.Telegram.ChannelComments = viper.GetBool("telegram.channel_comments")
+	log.Debug().
+		Bool("posts", config.Telegram.ChannelPosts).
+		Bool("comments", config.Telegram.ChannelComments).
+		Msg("Using channel handling")
 
 	// GenAI settings
 	provider, err := genai.ParseProvider(viper.GetString("genai.provider"))
@@ -196,7 +1351,29 @@ func Load(configPath string) (*Config, error) {
 	config.GenerativeAI.Mode = mode
 	config.GenerativeAI.Timeout = viper.GetDuration("genai.timeout")
 	config.GenerativeAI.AllowConcurrent = viper.GetBool("genai.allow_concurrent")
+	config.GenerativeAI.Vision = viper.GetBool("genai.vision")
+	config.GenerativeAI.MaxTurns = viper.GetInt("genai.max_turns")
+	config.GenerativeAI.MaxToolRounds = viper.GetInt("genai.max_tool_rounds")
+	config.GenerativeAI.MaxContextTokens = viper.GetInt("genai.max_context_tokens")
+	if config.GenerativeAI.MaxContextTokens < 0 {
+		return nil, errors.New("max context tokens cannot be negative")
+	}
+	config.GenerativeAI.EmptyResponse, err = ParseEmptyResponsePolicy(
+		viper.GetString("genai.empty_response_policy"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	config.GenerativeAI.Language = viper.GetString("genai.language")
+	config.GenerativeAI.DetectLanguage = viper.GetBool("genai.detect_language")
+	config.GenerativeAI.LogReasoning = viper.GetBool("genai.log_reasoning")
+	config.GenerativeAI.MessageFormat = viper.GetString("genai.message_format")
 	config.GenerativeAI.Template = viper.GetString("genai.template")
+	config.GenerativeAI.Pricing = createPricing()
+	config.GenerativeAI.DailyTokenBudget = viper.GetInt64("genai.daily_token_budget")
+	if config.GenerativeAI.DailyTokenBudget < 0 {
+		return nil, errors.New("daily token budget cannot be negative")
+	}
 	log.Debug().
 		Str("provider", config.GenerativeAI.Provider.String()).
 		Msg("Using generative AI provider")
@@ -205,6 +1382,24 @@ func Load(configPath string) (*Config, error) {
 	log.Debug().
 		Bool("value", config.GenerativeAI.AllowConcurrent).
 		Msg("Allow concurrent generative AI requests")
+	log.Debug().Bool("value", config.GenerativeAI.Vision).Msg("Enable vision input")
+	log.Debug().Int("max_turns", config.GenerativeAI.MaxTurns).Msg("Using conversation turn limit")
+	log.Debug().
+		Int("max_tool_rounds", config.GenerativeAI.MaxToolRounds).
+		Msg("Using tool calling round limit")
+	log.Debug().
+		Int("max_context_tokens", config.GenerativeAI.MaxContextTokens).
+		Msg("Using context size limit")
+	log.Debug().
+		Str("policy", config.GenerativeAI.EmptyResponse.String()).
+		Msg("Using empty response policy")
+	log.Debug().
+		Str("language", config.GenerativeAI.Language).
+		Bool("detect", config.GenerativeAI.DetectLanguage).
+		Msg("Using response language")
+	log.Debug().
+		Int64("daily_token_budget", config.GenerativeAI.DailyTokenBudget).
+		Msg("Using daily token budget")
 
 	// Set provider-specific config
 	config.GenerativeAI.Config, err = createProviderConfig(provider)
@@ -212,16 +1407,224 @@ func Load(configPath string) (*Config, error) {
 		return nil, err
 	}
 
+	config.GenerativeAI.Routing, err = createRoutingConfig()
+	if err != nil {
+		return nil, fmt.Errorf("invalid routing config: %w", err)
+	}
+
+	config.GenerativeAI.AdaptiveHistory, err = createAdaptiveHistoryConfig()
+	if err != nil {
+		return nil, fmt.Errorf("invalid adaptive history config: %w", err)
+	}
+
+	config.GenerativeAI.CircuitBreaker, err = createCircuitBreakerConfig()
+	if err != nil {
+		return nil, fmt.Errorf("invalid circuit breaker config: %w", err)
+	}
+
+	config.GenerativeAI.Capabilities, err = createCapabilityOverrides()
+	if err != nil {
+		return nil, fmt.Errorf("invalid capabilities config: %w", err)
+	}
+
 	// Validation
 	if config.GenerativeAI.Template == "" && config.GenerativeAI.Mode == genai.ModeCompletion {
 		return nil, errors.New("template is required for completion mode")
 	}
 
+	// Tools
+	config.Tools.WebSearch, err = createWebSearchConfig()
+	if err != nil {
+		return nil, fmt.Errorf("invalid web search config: %w", err)
+	}
+
+	// Document retrieval
+	config.RAG, err = createRAGConfig()
+	if err != nil {
+		return nil, fmt.Errorf("invalid RAG config: %w", err)
+	}
+
+	// Context strategy, which embeds messages with the document retrieval embedding model
+	config.GenerativeAI.ContextStrategy, err = ParseContextStrategy(viper.GetString("genai.context_strategy"))
+	if err != nil {
+		return nil, err
+	}
+	config.GenerativeAI.SemanticContext, err = createSemanticContext(
+		config.GenerativeAI.ContextStrategy,
+		config.RAG.EmbeddingModel,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid semantic context config: %w", err)
+	}
+
+	// Text-to-speech
+	config.TTS, err = createTTSConfig()
+	if err != nil {
+		return nil, fmt.Errorf("invalid text-to-speech config: %w", err)
+	}
+
+	// Content moderation
+	config.Moderation, err = createModerationConfig()
+	if err != nil {
+		return nil, fmt.Errorf("invalid moderation config: %w", err)
+	}
+
+	// Outgoing webhooks
+	config.Webhooks, err = createWebhookConfig()
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook config: %w", err)
+	}
+
+	// Budget alerts
+	config.Alerts = Alerts{
+		ChatDailyTokens:   viper.GetInt64("alerts.chat_daily_tokens"),
+		ChatDailyCost:     viper.GetFloat64("alerts.chat_daily_cost"),
+		GlobalDailyTokens: viper.GetInt64("alerts.global_daily_tokens"),
+		GlobalDailyCost:   viper.GetFloat64("alerts.global_daily_cost"),
+		FallbackModel:     viper.GetString("alerts.fallback_model"),
+	}
+
+	// Keep-alive
+	config.KeepAlive = KeepAlive{
+		Enabled:      viper.GetBool("keepalive.enabled"),
+		WarmOnStart:  viper.GetBool("keepalive.warm_on_start"),
+		IdleInterval: viper.GetDuration("keepalive.idle_interval"),
+	}
+	if config.KeepAlive.Enabled && config.KeepAlive.IdleInterval <= 0 {
+		return nil, errors.New("keep-alive idle interval must be positive")
+	}
+	log.Debug().
+		Bool("enabled", config.KeepAlive.Enabled).
+		Dur("idle_interval", config.KeepAlive.IdleInterval).
+		Msg("Using keep-alive settings")
+
+	// Model refresh
+	config.ModelRefresh = ModelRefresh{
+		Enabled:     viper.GetBool("model_refresh.enabled"),
+		AutoPull:    viper.GetBool("model_refresh.auto_pull"),
+		RegistryURL: viper.GetString("model_refresh.registry_url"),
+	}
+	if config.ModelRefresh.Enabled {
+		if config.GenerativeAI.Provider != genai.ProviderOllama {
+			return nil, errors.New("model refresh requires the Ollama provider")
+		}
+		var maintenanceTime time.Time
+		maintenanceTime, err = time.Parse("15:04", viper.GetString("model_refresh.maintenance_time"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid model refresh maintenance time: %w", err)
+		}
+		config.ModelRefresh.MaintenanceTime = time.Duration(maintenanceTime.Hour())*time.Hour +
+			time.Duration(maintenanceTime.Minute())*time.Minute
+	}
+	log.Debug().
+		Bool("enabled", config.ModelRefresh.Enabled).
+		Dur("maintenance_time", config.ModelRefresh.MaintenanceTime).
+		Bool("auto_pull", config.ModelRefresh.AutoPull).
+		Msg("Using model refresh settings")
+
+	// Metrics
+	config.Metrics = Metrics{
+		Enabled:       viper.GetBool("metrics.enabled"),
+		ListenAddress: viper.GetString("metrics.listen_address"),
+	}
+	if config.Metrics.Enabled && config.Metrics.ListenAddress == "" {
+		return nil, errors.New("metrics listen address cannot be empty")
+	}
+	log.Debug().
+		Bool("enabled", config.Metrics.Enabled).
+		Str("listen_address", config.Metrics.ListenAddress).
+		Msg("Using metrics settings")
+
+	// Dashboard
+	config.Dashboard, err = createDashboardConfig()
+	if err != nil {
+		return nil, fmt.Errorf("invalid dashboard config: %w", err)
+	}
+
+	// Scripting
+	config.Scripting, err = createScriptingConfig()
+	if err != nil {
+		return nil, fmt.Errorf("invalid scripting config: %w", err)
+	}
+
+	// Scheduler
+	config.Scheduler = Scheduler{
+		MaxPromptsPerChat: viper.GetInt("scheduler.max_prompts_per_chat"),
+	}
+	log.Debug().
+		Int("max_prompts_per_chat", config.Scheduler.MaxPromptsPerChat).
+		Msg("Using scheduler settings")
+
+	// Documents
+	config.Documents = Documents{
+		Enabled:          viper.GetBool("documents.enabled"),
+		MaxFileSize:      viper.GetInt64("documents.max_file_size"),
+		MaxExcerptLength: viper.GetInt("documents.max_excerpt_length"),
+	}
+	if config.Documents.MaxFileSize < 1 || config.Documents.MaxExcerptLength < 1 {
+		return nil, errors.New("the maximum document file size and excerpt length must be positive")
+	}
+	log.Debug().
+		Bool("enabled", config.Documents.Enabled).
+		Int64("max_file_size", config.Documents.MaxFileSize).
+		Int("max_excerpt_length", config.Documents.MaxExcerptLength).
+		Msg("Using document settings")
+
+	// Abuse protection
+	config.Abuse = Abuse{
+		MaxMessagesPerMinute: viper.GetInt("abuse.max_messages_per_minute"),
+		MaxMessageLength:     viper.GetInt("abuse.max_message_length"),
+		Cooldown:             viper.GetDuration("abuse.cooldown"),
+	}
+	if config.Abuse.MaxMessagesPerMinute < 0 || config.Abuse.MaxMessageLength < 0 {
+		return nil, errors.New("the abuse limits cannot be negative")
+	}
+	if config.Abuse.Cooldown <= 0 {
+		return nil, errors.New("the abuse cooldown must be positive")
+	}
+	log.Debug().
+		Int("max_messages_per_minute", config.Abuse.MaxMessagesPerMinute).
+		Int("max_message_length", config.Abuse.MaxMessageLength).
+		Dur("cooldown", config.Abuse.Cooldown).
+		Msg("Using abuse protection settings")
+
+	// Persona
+	config.Persona = Persona{
+		Name:        strings.TrimSpace(viper.GetString("persona.name")),
+		Description: strings.TrimSpace(viper.GetString("persona.description")),
+		Style:       strings.TrimSpace(viper.GetString("persona.style")),
+	}
+	if config.Persona.Name == "" || config.Persona.Description == "" {
+		return nil, errors.New("the persona name and description cannot be empty")
+	}
+	log.Debug().
+		Str("name", config.Persona.Name).
+		Str("style", config.Persona.Style).
+		Msg("Using persona settings")
+	config.Personas, err = createPersonas()
+	if err != nil {
+		return nil, err
+	}
+
+	// Prompt template library
+	config.Prompts = Prompts{
+		Directory: viper.GetString("prompts.directory"),
+	}
+	log.Debug().Str("directory", config.Prompts.Directory).Msg("Using prompt template library")
+
 	// Response messages
 	config.ResponseMessages = ResponseMessages{
 		PrivateChatDisallowed: viper.GetString("messages.private_chat_disallowed"),
 		InternalError:         viper.GetString("messages.internal_error"),
 		ServerBusy:            viper.GetString("messages.server_busy"),
+		ContextRefreshed:      viper.GetString("messages.context_refreshed"),
+		EmptyResponse:         viper.GetString("messages.empty_response"),
+		Start:                 viper.GetString("messages.start"),
+		DocumentTooLarge:      viper.GetString("messages.document_too_large"),
+		UserSilenced:          viper.GetString("messages.user_silenced"),
+		Moderated:             viper.GetString("messages.moderated"),
+		BudgetExhausted:       viper.GetString("messages.budget_exhausted"),
+		ProviderDown:          viper.GetString("messages.provider_down"),
 	}
 
 	return config, nil
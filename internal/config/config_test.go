@@ -3,10 +3,16 @@ package config //nolint:testpackage // Unit tests are in the same package
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/k4yt3x/tellama/internal/database"
 	"github.com/k4yt3x/tellama/internal/genai"
+	"github.com/k4yt3x/tellama/internal/moderation"
+	"github.com/k4yt3x/tellama/internal/tts"
+	"github.com/k4yt3x/tellama/internal/webhook"
+	"github.com/k4yt3x/tellama/internal/websearch"
 
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
@@ -30,11 +36,18 @@ telegram:
   bot_token: test_token
   timeout: 5s
   allow_untrusted_chats: true
+  require_chat_admin: false
+  stats_footer_chat_ids: [-1001234567890]
 genai:
   provider: openai
   mode: chat
   timeout: 15s
   allow_concurrent: true
+  empty_response_policy: retry
+  language: English
+  detect_language: true
+  message_format: "{{.FirstName}}: {{.Content}}"
+  max_context_tokens: 8192
 openai:
   api_key: test_api_key
   model: gpt-4
@@ -42,6 +55,9 @@ messages:
   private_chat_disallowed: "Private chats not allowed"
   internal_error: "Error occurred"
   server_busy: "Server is busy"
+  context_refreshed: "Context refreshed"
+  empty_response: "No response"
+  start: "Hello {{.FirstName}}"
 `
 	tempDir := t.TempDir()
 	configPath := filepath.Join(tempDir, "config.yaml")
@@ -59,19 +75,251 @@ messages:
 	assert.Equal(t, "test_token", cfg.Telegram.BotToken)
 	assert.Equal(t, 5*time.Second, cfg.Telegram.Timeout)
 	assert.True(t, cfg.Telegram.AllowUntrustedChat)
+	assert.False(t, cfg.Telegram.RequireChatAdmin)
+	assert.Equal(t, []int64{-1001234567890}, cfg.Telegram.StatsFooterChatIDs)
 	assert.Equal(t, genai.ProviderOpenAI, cfg.GenerativeAI.Provider)
 	assert.Equal(t, genai.ModeChat, cfg.GenerativeAI.Mode)
 	assert.Equal(t, 15*time.Second, cfg.GenerativeAI.Timeout)
 	assert.True(t, cfg.GenerativeAI.AllowConcurrent)
+	assert.Equal(t, EmptyResponseRetry, cfg.GenerativeAI.EmptyResponse)
+	assert.Equal(t, "English", cfg.GenerativeAI.Language)
+	assert.True(t, cfg.GenerativeAI.DetectLanguage)
+	assert.Equal(t, "{{.FirstName}}: {{.Content}}", cfg.GenerativeAI.MessageFormat)
+	assert.Equal(t, 8192, cfg.GenerativeAI.MaxContextTokens)
 	assert.Equal(t, "Private chats not allowed", cfg.ResponseMessages.PrivateChatDisallowed)
 	assert.Equal(t, "Error occurred", cfg.ResponseMessages.InternalError)
 	assert.Equal(t, "Server is busy", cfg.ResponseMessages.ServerBusy)
+	assert.Equal(t, "Context refreshed", cfg.ResponseMessages.ContextRefreshed)
+	assert.Equal(t, "No response", cfg.ResponseMessages.EmptyResponse)
+	assert.Equal(t, "Hello {{.FirstName}}", cfg.ResponseMessages.Start)
 
 	// Check OpenAI config
 	openaiCfg, ok := cfg.GenerativeAI.Config.(*genai.OpenAIConfig)
 	require.True(t, ok)
 	assert.Equal(t, "test_api_key", openaiCfg.APIKey)
 	assert.Equal(t, "gpt-4", openaiCfg.Model)
+	assert.Equal(t, "reasoning_content", openaiCfg.ReasoningField)
+	assert.Equal(t, genai.CompatibilityStrict, openaiCfg.Compatibility)
+	assert.Nil(t, openaiCfg.Temperature)
+	assert.Empty(t, openaiCfg.ReasoningEffort)
+}
+
+func TestLoad_OpenAICompatibility(t *testing.T) {
+	// Arrange
+	resetViper()
+	configContent := `
+telegram:
+  bot_token: test_token
+genai:
+  provider: openai
+  mode: chat
+openai:
+  api_key: test_api_key
+  compatibility: lenient
+  temperature: 0
+  top_p: unset
+  reasoning_effort: unset
+  stop: "<|stop|>"
+`
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	// Act
+	cfg, err := Load(configPath)
+
+	// Assert
+	require.NoError(t, err)
+	openaiCfg, ok := cfg.GenerativeAI.Config.(*genai.OpenAIConfig)
+	require.True(t, ok)
+	assert.Equal(t, genai.CompatibilityLenient, openaiCfg.Compatibility)
+	require.NotNil(t, openaiCfg.Temperature)
+	assert.Zero(t, *openaiCfg.Temperature)
+	assert.Nil(t, openaiCfg.TopP)
+	assert.Nil(t, openaiCfg.FrequencyPenalty)
+	assert.Empty(t, openaiCfg.ReasoningEffort)
+	assert.Equal(t, "<|stop|>", openaiCfg.Stop)
+
+	// Unknown profiles and invalid values are rejected
+	resetViper()
+	err = os.WriteFile(configPath, []byte(strings.Replace(configContent, "lenient", "loose", 1)), 0644)
+	require.NoError(t, err)
+	_, err = Load(configPath)
+	assert.ErrorContains(t, err, "unknown compatibility profile")
+
+	resetViper()
+	err = os.WriteFile(configPath, []byte(strings.Replace(configContent, "top_p: unset", "top_p: high", 1)), 0644)
+	require.NoError(t, err)
+	_, err = Load(configPath)
+	assert.ErrorContains(t, err, "invalid value for openai.top_p")
+}
+
+func TestLoad_Bedrock(t *testing.T) {
+	// Arrange
+	resetViper()
+	t.Setenv("AWS_REGION", "")
+	configContent := `
+telegram:
+  bot_token: test_token
+genai:
+  provider: bedrock
+  mode: chat
+bedrock:
+  region: eu-central-1
+  model: meta.llama3-70b-instruct-v1:0
+  access_key_id: AKIDEXAMPLE
+  secret_access_key: secret
+  max_tokens: 1024
+  temperature: 0.5
+  top_p: unset
+`
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	// Act
+	cfg, err := Load(configPath)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, genai.ProviderBedrock, cfg.GenerativeAI.Provider)
+	bedrockCfg, ok := cfg.GenerativeAI.Config.(*genai.BedrockConfig)
+	require.True(t, ok)
+	assert.Equal(t, "eu-central-1", bedrockCfg.Region)
+	assert.Equal(t, "meta.llama3-70b-instruct-v1:0", bedrockCfg.Model)
+	assert.Empty(t, bedrockCfg.Endpoint)
+	assert.Equal(t, "AKIDEXAMPLE", bedrockCfg.AccessKeyID)
+	assert.Equal(t, "secret", bedrockCfg.SecretAccessKey)
+	assert.Equal(t, int64(1024), bedrockCfg.MaxTokens)
+	require.NotNil(t, bedrockCfg.Temperature)
+	assert.InDelta(t, 0.5, *bedrockCfg.Temperature, 1e-9)
+	assert.Nil(t, bedrockCfg.TopP)
+	assert.Equal(t, 3, bedrockCfg.Retry.MaxAttempts)
+
+	// The region falls back to the environment and is required
+	resetViper()
+	err = os.WriteFile(configPath, []byte(strings.Replace(configContent, "region: eu-central-1", "", 1)), 0644)
+	require.NoError(t, err)
+	t.Setenv("AWS_REGION", "us-west-2")
+	cfg, err = Load(configPath)
+	require.NoError(t, err)
+	bedrockCfg, ok = cfg.GenerativeAI.Config.(*genai.BedrockConfig)
+	require.True(t, ok)
+	assert.Equal(t, "us-west-2", bedrockCfg.Region)
+
+	resetViper()
+	t.Setenv("AWS_REGION", "")
+	_, err = Load(configPath)
+	assert.ErrorContains(t, err, "bedrock region is required")
+}
+
+// registeredConfig is the configuration of a provider added with genai.Register.
+type registeredConfig struct {
+	Model   string
+	BaseURL string `mapstructure:"base_url"`
+}
+
+func (c *registeredConfig) Validate() error {
+	return nil
+}
+
+func TestLoad_RegisteredProvider(t *testing.T) {
+	// Arrange
+	resetViper()
+	_, err := genai.Register("test-config-registered", func(genai.ProviderConfig) (genai.GenerativeAI, error) {
+		return &genai.Mock{}, nil
+	}, func() genai.ProviderConfig { return &registeredConfig{Model: "default"} })
+	require.NoError(t, err)
+	configContent := `
+telegram:
+  bot_token: test_token
+genai:
+  provider: test-config-registered
+  mode: chat
+test-config-registered:
+  base_url: http://localhost:9000
+`
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	err = os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	// Act
+	cfg, err := Load(configPath)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "test-config-registered", cfg.GenerativeAI.Provider.String())
+	assert.Equal(t, &registeredConfig{Model: "default", BaseURL: "http://localhost:9000"}, cfg.GenerativeAI.Config)
+}
+
+func TestLoad_EnvironmentOverrides(t *testing.T) {
+	// Arrange
+	resetViper()
+	configContent := `
+telegram:
+  bot_token: file_token
+genai:
+  provider: openai
+  mode: chat
+openai:
+  model: gpt-4
+`
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+	t.Setenv("TELLAMA_TELEGRAM_BOT_TOKEN", "env_token")
+	t.Setenv("TELLAMA_TELEGRAM_ADMIN_USER_IDS", "123,456")
+	t.Setenv("TELLAMA_OPENAI_API_KEY", "env_api_key")
+	t.Setenv("TELLAMA_GENAI_TIMEOUT", "30s")
+
+	// Act
+	cfg, err := Load(configPath)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "env_token", cfg.Telegram.BotToken)
+	assert.Equal(t, []int64{123, 456}, cfg.Telegram.AdminUserIDs)
+	assert.Equal(t, 30*time.Second, cfg.GenerativeAI.Timeout)
+
+	openaiConfig, ok := cfg.GenerativeAI.Config.(*genai.OpenAIConfig)
+	require.True(t, ok)
+	assert.Equal(t, "env_api_key", openaiConfig.APIKey)
+	assert.Equal(t, "gpt-4", openaiConfig.Model)
+}
+
+func TestLoad_EncryptionKey(t *testing.T) {
+	// Arrange
+	resetViper()
+	configContent := `
+telegram:
+  bot_token: test_token
+genai:
+  provider: openai
+  mode: chat
+openai:
+  api_key: test_api_key
+  model: gpt-4
+`
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+	t.Setenv("TELLAMA_DATABASE_ENCRYPTION_KEY", "AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8=")
+
+	// Act
+	cfg, err := Load(configPath)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Len(t, cfg.Database.EncryptionKey, 32)
+
+	t.Setenv("TELLAMA_DATABASE_ENCRYPTION_KEY", "c2hvcnQ=")
+	_, err = Load(configPath)
+	assert.ErrorContains(t, err, "invalid database encryption key")
 }
 
 func TestLoad_OllamaConfig(t *testing.T) {
@@ -91,6 +339,7 @@ ollama:
   options:
     temperature: 0.8
     top_k: 50.0
+  keep_alive: -1
 `
 	tempDir := t.TempDir()
 	configPath := filepath.Join(tempDir, "config.yaml")
@@ -109,6 +358,8 @@ ollama:
 	assert.Equal(t, "llama3:latest", ollamaCfg.Model)
 	assert.InEpsilon(t, 0.8, ollamaCfg.Options["temperature"], 0.0001)
 	assert.InEpsilon(t, 50, ollamaCfg.Options["top_k"], 0.0001)
+	assert.Equal(t, genai.DefaultReasoningPattern, ollamaCfg.ReasoningPattern)
+	assert.Equal(t, "-1", ollamaCfg.KeepAlive)
 }
 
 func TestLoad_CompletionMode(t *testing.T) {
@@ -249,17 +500,17 @@ genai:
 	assert.Nil(t, cfg)
 }
 
-func TestLoad_DefaultValues(t *testing.T) {
+func TestLoad_Acknowledgement(t *testing.T) {
 	// Arrange
 	resetViper()
 	configContent := `
 telegram:
   bot_token: test_token
+  acknowledgement: reaction
+  acknowledgement_emoji: "👍"
 genai:
   provider: ollama
   mode: chat
-ollama:
-  model: llama3:test
 `
 	tempDir := t.TempDir()
 	configPath := filepath.Join(tempDir, "config.yaml")
@@ -271,15 +522,1676 @@ ollama:
 
 	// Assert
 	require.NoError(t, err)
-	assert.Equal(t, "tellama.db", cfg.Database.Path)
-	assert.Equal(t, 10000, cfg.Database.HistoryFetchLimit)
-	assert.Equal(t, 10*time.Second, cfg.Telegram.Timeout)
-	assert.False(t, cfg.Telegram.AllowUntrustedChat)
-	assert.Equal(t, 10*time.Second, cfg.GenerativeAI.Timeout)
-	assert.False(t, cfg.GenerativeAI.AllowConcurrent)
+	assert.Equal(t, AcknowledgementReaction, cfg.Telegram.Acknowledgement)
+	assert.Equal(t, "👍", cfg.Telegram.AcknowledgementEmoji)
 
-	ollamaCfg, ok := cfg.GenerativeAI.Config.(*genai.OllamaConfig)
+	// Unknown policies are rejected
+	resetViper()
+	err = os.WriteFile(configPath, []byte(strings.Replace(configContent, "reaction", "wave", 1)), 0644)
+	require.NoError(t, err)
+	_, err = Load(configPath)
+	assert.ErrorContains(t, err, "unknown acknowledgement policy")
+}
+
+func TestLoad_ChatEvents(t *testing.T) {
+	// Arrange
+	resetViper()
+	configContent := `
+telegram:
+  bot_token: test_token
+  chat_events: [join, Leave, join]
+genai:
+  provider: ollama
+  mode: chat
+`
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	// Act
+	cfg, err := Load(configPath)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, []string{ChatEventJoin, ChatEventLeave}, cfg.Telegram.ChatEvents)
+
+	// Unknown events are rejected
+	resetViper()
+	err = os.WriteFile(configPath, []byte(strings.Replace(configContent, "Leave", "wave", 1)), 0644)
+	require.NoError(t, err)
+	_, err = Load(configPath)
+	assert.ErrorContains(t, err, "unknown chat event")
+}
+
+func TestLoad_ModelRefresh(t *testing.T) {
+	// Arrange
+	resetViper()
+	configContent := `
+telegram:
+  bot_token: test_token
+genai:
+  provider: ollama
+  mode: chat
+model_refresh:
+  enabled: true
+  maintenance_time: "04:30"
+  auto_pull: true
+`
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	// Act
+	cfg, err := Load(configPath)
+
+	// Assert
+	require.NoError(t, err)
+	assert.True(t, cfg.ModelRefresh.Enabled)
+	assert.True(t, cfg.ModelRefresh.AutoPull)
+	assert.Equal(t, 4*time.Hour+30*time.Minute, cfg.ModelRefresh.MaintenanceTime)
+	assert.Equal(t, "https://registry.ollama.ai", cfg.ModelRefresh.RegistryURL)
+
+	// Invalid maintenance times are rejected
+	resetViper()
+	err = os.WriteFile(configPath, []byte(strings.Replace(configContent, "04:30", "25:00", 1)), 0644)
+	require.NoError(t, err)
+	_, err = Load(configPath)
+	assert.ErrorContains(t, err, "invalid model refresh maintenance time")
+}
+
+func TestLoad_MockProvider(t *testing.T) {
+	// Arrange
+	resetViper()
+	configContent := `
+telegram:
+  bot_token: test_token
+genai:
+  provider: mock
+mock:
+  response: Canned reply
+  latency: 250ms
+`
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	// Act
+	cfg, err := Load(configPath)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, genai.ProviderMock, cfg.GenerativeAI.Provider)
+	mockCfg, ok := cfg.GenerativeAI.Config.(*genai.MockConfig)
 	require.True(t, ok)
-	assert.Equal(t, "http://localhost:11434", ollamaCfg.BaseURL)
-	assert.Equal(t, "llama3:test", ollamaCfg.Model)
+	assert.Equal(t, "mock", mockCfg.Model)
+	assert.Equal(t, "Canned reply", mockCfg.Response)
+	assert.Equal(t, 250*time.Millisecond, mockCfg.Latency)
+}
+
+func TestLoad_Trigger(t *testing.T) {
+	// Arrange
+	resetViper()
+	configContent := `
+telegram:
+  bot_token: test_token
+  trigger: keywords
+  trigger_keywords: [" Llama ", "hey bot", ""]
+genai:
+  provider: ollama
+  mode: chat
+`
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	// Act
+	cfg, err := Load(configPath)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, TriggerKeywords, cfg.Telegram.Trigger)
+	assert.Equal(t, []string{"llama", "hey bot"}, cfg.Telegram.TriggerKeywords)
+
+	// The keywords mode requires keywords
+	resetViper()
+	err = os.WriteFile(configPath, []byte(strings.Replace(configContent, `" Llama ", "hey bot", ""`, "", 1)), 0644)
+	require.NoError(t, err)
+	_, err = Load(configPath)
+	assert.ErrorContains(t, err, "requires at least one trigger keyword")
+
+	// Unknown modes are rejected
+	resetViper()
+	err = os.WriteFile(configPath, []byte(strings.Replace(configContent, "trigger: keywords", "trigger: always", 1)), 0644)
+	require.NoError(t, err)
+	_, err = Load(configPath)
+	assert.ErrorContains(t, err, "unknown trigger mode")
+}
+
+func TestLoad_DeleteCommands(t *testing.T) {
+	// Arrange
+	resetViper()
+	configContent := `
+telegram:
+  bot_token: test_token
+  delete_commands: ["/GetConfig", " setsysprompt ", ""]
+  delete_commands_delay: 1m
+genai:
+  provider: ollama
+  mode: chat
+`
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	// Act
+	cfg, err := Load(configPath)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, []string{"getconfig", "setsysprompt"}, cfg.Telegram.DeleteCommands)
+	assert.Equal(t, time.Minute, cfg.Telegram.DeleteCommandsDelay)
+}
+
+func TestLoad_Channels(t *testing.T) {
+	// Arrange
+	resetViper()
+	configContent := `
+telegram:
+  bot_token: test_token
+  channel_posts: true
+  channel_comments: true
+genai:
+  provider: ollama
+  mode: chat
+`
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	// Act
+	cfg, err := Load(configPath)
+
+	// Assert
+	require.NoError(t, err)
+	assert.True(t, cfg.Telegram.ChannelPosts)
+	assert.True(t, cfg.Telegram.ChannelComments)
+}
+
+func TestLoad_UnknownEmptyResponsePolicy(t *testing.T) {
+	// Arrange
+	resetViper()
+	configContent := `
+telegram:
+  bot_token: test_token
+genai:
+  provider: ollama
+  mode: chat
+  empty_response_policy: shrug
+`
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	// Act
+	cfg, err := Load(configPath)
+
+	// Assert
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown empty response policy")
+	assert.Nil(t, cfg)
+}
+
+func TestLoad_DefaultValues(t *testing.T) {
+	// Arrange
+	resetViper()
+	configContent := `
+telegram:
+  bot_token: test_token
+genai:
+  provider: ollama
+  mode: chat
+ollama:
+  model: llama3:test
+`
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	// Act
+	cfg, err := Load(configPath)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "tellama.db", cfg.Database.Path)
+	assert.Equal(t, 10000, cfg.Database.HistoryFetchLimit)
+	assert.Equal(t, 1000, cfg.Database.DegradedQueueSize)
+	assert.False(t, cfg.Database.Redaction.Enabled)
+	assert.True(t, cfg.Database.Redaction.CreditCards)
+	assert.True(t, cfg.Database.Redaction.Emails)
+	assert.True(t, cfg.Database.Redaction.PhoneNumbers)
+	assert.Empty(t, cfg.Database.Redaction.Patterns)
+	assert.Equal(t, database.DriverSQLite, cfg.Database.Driver)
+	assert.True(t, cfg.Database.AutoMigrate)
+	assert.Equal(t, 100, cfg.Database.WriteBufferSize)
+	assert.Zero(t, cfg.Database.WriteBufferInterval)
+	assert.Equal(t, time.Minute, cfg.Database.OverrideCacheTTL)
+	assert.Equal(t, "wal", cfg.Database.SQLite.JournalMode)
+	assert.Equal(t, "normal", cfg.Database.SQLite.Synchronous)
+	assert.Equal(t, 5*time.Second, cfg.Database.SQLite.BusyTimeout)
+	assert.Zero(t, cfg.Database.SQLite.MaxOpenConns)
+	assert.Equal(t, 10*time.Second, cfg.Telegram.Timeout)
+	assert.False(t, cfg.Telegram.AllowUntrustedChat)
+	assert.False(t, cfg.Telegram.BackfillMissedMessages)
+	assert.True(t, cfg.Telegram.RequireChatAdmin)
+	assert.Empty(t, cfg.Telegram.StatsFooterChatIDs)
+	assert.Zero(t, cfg.Telegram.FileReplyMinLength)
+	assert.InDelta(t, 0.5, cfg.Telegram.FileReplyCodeRatio, 1e-9)
+	assert.Equal(t, AcknowledgementNone, cfg.Telegram.Acknowledgement)
+	assert.Equal(t, "👀", cfg.Telegram.AcknowledgementEmoji)
+	assert.Equal(t, 10*time.Second, cfg.GenerativeAI.Timeout)
+	assert.False(t, cfg.GenerativeAI.AllowConcurrent)
+	assert.True(t, cfg.GenerativeAI.Vision)
+	assert.Zero(t, cfg.GenerativeAI.MaxTurns)
+	assert.Equal(t, 5, cfg.GenerativeAI.MaxToolRounds)
+	assert.Zero(t, cfg.GenerativeAI.MaxContextTokens)
+	assert.Equal(t, EmptyResponseIgnore, cfg.GenerativeAI.EmptyResponse)
+	assert.Empty(t, cfg.GenerativeAI.Language)
+	assert.False(t, cfg.GenerativeAI.DetectLanguage)
+	assert.Empty(t, cfg.GenerativeAI.MessageFormat)
+	assert.Equal(t, ContextRecent, cfg.GenerativeAI.ContextStrategy)
+	assert.Equal(t, 8, cfg.GenerativeAI.SemanticContext.TopK)
+	assert.Equal(t, 10, cfg.GenerativeAI.SemanticContext.RecentMessages)
+	assert.Equal(t, 1000, cfg.GenerativeAI.SemanticContext.Candidates)
+	assert.InDelta(t, 0.3, cfg.GenerativeAI.SemanticContext.MinScore, 0.001)
+	assert.False(t, cfg.Telegram.RegenerateEditedReplies)
+	assert.False(t, cfg.KeepAlive.Enabled)
+	assert.True(t, cfg.KeepAlive.WarmOnStart)
+	assert.Equal(t, 5*time.Minute, cfg.KeepAlive.IdleInterval)
+	assert.False(t, cfg.ModelRefresh.Enabled)
+	assert.False(t, cfg.Metrics.Enabled)
+	assert.Equal(t, 10, cfg.Scheduler.MaxPromptsPerChat)
+	assert.True(t, cfg.Documents.Enabled)
+	assert.False(t, cfg.TTS.Enabled)
+	assert.Equal(t, tts.BackendOpenAI, cfg.TTS.Backend)
+	assert.Equal(t, "alloy", cfg.TTS.Voice)
+	assert.False(t, cfg.Moderation.Enabled)
+	assert.Equal(t, moderation.BackendOpenAI, cfg.Moderation.Backend)
+	assert.Equal(t, "omni-moderation-latest", cfg.Moderation.Model)
+	assert.InDelta(t, 0.5, cfg.Moderation.Threshold, 1e-9)
+	assert.False(t, cfg.Moderation.CheckResponses)
+	assert.False(t, cfg.Webhooks.Enabled)
+	assert.Empty(t, cfg.Webhooks.URLs)
+	assert.False(t, cfg.Webhooks.IncludeText)
+	assert.Equal(t, 10*time.Second, cfg.Webhooks.Timeout)
+	assert.False(t, cfg.Dashboard.Enabled)
+	assert.Equal(t, "127.0.0.1:9465", cfg.Dashboard.ListenAddress)
+	assert.Empty(t, cfg.Dashboard.Token)
+	assert.False(t, cfg.Scripting.Enabled)
+	assert.Equal(t, "scripts", cfg.Scripting.Directory)
+	assert.Equal(t, time.Second, cfg.Scripting.Timeout)
+	assert.Equal(t, int64(1024*1024), cfg.Documents.MaxFileSize)
+	assert.Equal(t, 20000, cfg.Documents.MaxExcerptLength)
+	assert.Equal(t, 0, cfg.Abuse.MaxMessagesPerMinute)
+	assert.Equal(t, 0, cfg.Abuse.MaxMessageLength)
+	assert.Equal(t, 10*time.Minute, cfg.Abuse.Cooldown)
+	assert.Equal(t, "Tellama", cfg.Persona.Name)
+	assert.NotEmpty(t, cfg.Persona.Description)
+	assert.Empty(t, cfg.Persona.Style)
+	assert.Empty(t, cfg.Personas)
+	assert.Equal(t, "prompts", cfg.Prompts.Directory)
+	assert.NotEmpty(t, cfg.ResponseMessages.DocumentTooLarge)
+	assert.NotEmpty(t, cfg.ResponseMessages.UserSilenced)
+	assert.NotEmpty(t, cfg.ResponseMessages.BudgetExhausted)
+	assert.NotEmpty(t, cfg.ResponseMessages.ProviderDown)
+	assert.False(t, cfg.GenerativeAI.CircuitBreaker.Enabled)
+	assert.Equal(t, 5, cfg.GenerativeAI.CircuitBreaker.FailureThreshold)
+	assert.Equal(t, time.Minute, cfg.GenerativeAI.CircuitBreaker.Cooldown)
+	assert.Zero(t, cfg.GenerativeAI.DailyTokenBudget)
+	assert.Equal(t, "127.0.0.1:9464", cfg.Metrics.ListenAddress)
+	assert.Equal(t, TriggerMentionAnywhere, cfg.Telegram.Trigger)
+	assert.Empty(t, cfg.Telegram.TriggerKeywords)
+	assert.Empty(t, cfg.Telegram.DeleteCommands)
+	assert.Equal(t, 5*time.Second, cfg.Telegram.DeleteCommandsDelay)
+	assert.False(t, cfg.Telegram.ChannelPosts)
+	assert.False(t, cfg.Telegram.ChannelComments)
+
+	ollamaCfg, ok := cfg.GenerativeAI.Config.(*genai.OllamaConfig)
+	require.True(t, ok)
+	assert.Equal(t, "http://localhost:11434", ollamaCfg.BaseURL)
+	assert.Equal(t, "llama3:test", ollamaCfg.Model)
+	assert.Empty(t, ollamaCfg.KeepAlive)
+	assert.Equal(t, genai.CapabilityOverrides{}, cfg.GenerativeAI.Capabilities)
+	assert.Equal(t, genai.RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Second,
+		MaxDelay:    30 * time.Second,
+		Jitter:      0.2,
+	}, ollamaCfg.Retry)
+}
+
+func TestLoad_AlertsAndPricing(t *testing.T) {
+	// Arrange
+	resetViper()
+	configContent := `
+telegram:
+  bot_token: test_token
+  admin_user_ids: [123456789, 987654321]
+genai:
+  provider: ollama
+  mode: chat
+  pricing:
+    gpt-4o:
+      prompt: 2.5
+      completion: 10
+alerts:
+  chat_daily_tokens: 100000
+  global_daily_cost: 5.0
+  fallback_model: llama3.2:3b
+`
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	// Act
+	cfg, err := Load(configPath)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, []int64{123456789, 987654321}, cfg.Telegram.AdminUserIDs)
+	assert.Equal(t, int64(100000), cfg.Alerts.ChatDailyTokens)
+	assert.Zero(t, cfg.Alerts.ChatDailyCost)
+	assert.Zero(t, cfg.Alerts.GlobalDailyTokens)
+	assert.InEpsilon(t, 5.0, cfg.Alerts.GlobalDailyCost, 0.0001)
+	assert.Equal(t, "llama3.2:3b", cfg.Alerts.FallbackModel)
+
+	require.Contains(t, cfg.GenerativeAI.Pricing, "gpt-4o")
+	assert.InEpsilon(t, 2.5, cfg.GenerativeAI.Pricing["gpt-4o"].Prompt, 0.0001)
+	assert.InEpsilon(t, 10.0, cfg.GenerativeAI.Pricing["gpt-4o"].Completion, 0.0001)
+}
+
+func TestLoad_WebSearch(t *testing.T) {
+	// Arrange
+	resetViper()
+	configContent := `
+telegram:
+  bot_token: test_token
+genai:
+  provider: ollama
+  mode: chat
+tools:
+  websearch:
+    enabled: true
+    backend: brave
+    api_key: test_brave_key
+    max_results: 3
+`
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	// Act
+	cfg, err := Load(configPath)
+
+	// Assert
+	require.NoError(t, err)
+	assert.True(t, cfg.Tools.WebSearch.Enabled)
+	assert.Equal(t, websearch.BackendBrave, cfg.Tools.WebSearch.Backend)
+	assert.Equal(t, "test_brave_key", cfg.Tools.WebSearch.APIKey)
+	assert.Equal(t, 3, cfg.Tools.WebSearch.MaxResults)
+	assert.Equal(t, 10*time.Second, cfg.Tools.WebSearch.Timeout)
+}
+
+func TestLoad_TTS(t *testing.T) {
+	// Arrange
+	resetViper()
+	configContent := `
+telegram:
+  bot_token: test_token
+genai:
+  provider: ollama
+  mode: chat
+tts:
+  enabled: true
+  backend: piper
+  base_url: http://localhost:5000
+  voice: en_US-lessac-medium
+  max_length: 1000
+`
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	// Act
+	cfg, err := Load(configPath)
+
+	// Assert
+	require.NoError(t, err)
+	assert.True(t, cfg.TTS.Enabled)
+	assert.Equal(t, tts.BackendPiper, cfg.TTS.Backend)
+	assert.Equal(t, "http://localhost:5000", cfg.TTS.BaseURL)
+	assert.Equal(t, "en_US-lessac-medium", cfg.TTS.Voice)
+	assert.Equal(t, "ffmpeg", cfg.TTS.FFmpegPath)
+	assert.Equal(t, 1000, cfg.TTS.MaxLength)
+	assert.Equal(t, 60*time.Second, cfg.TTS.Timeout)
+}
+
+func TestLoad_Moderation(t *testing.T) {
+	// Arrange
+	resetViper()
+	configContent := `
+telegram:
+  bot_token: test_token
+genai:
+  provider: ollama
+  mode: chat
+moderation:
+  enabled: true
+  backend: ollama
+  base_url: http://localhost:11434
+  model: llama-guard3
+  thresholds:
+    S6: 2
+  check_responses: true
+messages:
+  moderated: "Flagged"
+`
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	// Act
+	cfg, err := Load(configPath)
+
+	// Assert
+	require.NoError(t, err)
+	assert.True(t, cfg.Moderation.Enabled)
+	assert.Equal(t, moderation.BackendOllama, cfg.Moderation.Backend)
+	assert.Equal(t, "http://localhost:11434", cfg.Moderation.BaseURL)
+	assert.Equal(t, "llama-guard3", cfg.Moderation.Model)
+	assert.Equal(t, map[string]float64{"s6": 2}, cfg.Moderation.Thresholds)
+	assert.True(t, cfg.Moderation.CheckResponses)
+	assert.Equal(t, 10*time.Second, cfg.Moderation.Timeout)
+	assert.Equal(t, "Flagged", cfg.ResponseMessages.Moderated)
+}
+
+func TestLoad_ModerationInvalidThreshold(t *testing.T) {
+	// Arrange
+	resetViper()
+	configContent := `
+telegram:
+  bot_token: test_token
+genai:
+  provider: ollama
+  mode: chat
+moderation:
+  enabled: true
+  threshold: 1.5
+`
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	// Act
+	cfg, err := Load(configPath)
+
+	// Assert
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid moderation config")
+	assert.Nil(t, cfg)
+}
+
+func TestLoad_Webhooks(t *testing.T) {
+	// Arrange
+	resetViper()
+	configContent := `
+telegram:
+  bot_token: test_token
+genai:
+  provider: ollama
+  mode: chat
+webhooks:
+  enabled: true
+  urls:
+    - https://example.com/hook
+  secret: secret
+  events: [message_received, provider_error]
+  include_text: true
+  timeout: 5s
+`
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	// Act
+	cfg, err := Load(configPath)
+
+	// Assert
+	require.NoError(t, err)
+	assert.True(t, cfg.Webhooks.Enabled)
+	assert.Equal(t, []string{"https://example.com/hook"}, cfg.Webhooks.URLs)
+	assert.Equal(t, "secret", cfg.Webhooks.Secret)
+	assert.Equal(t, []string{webhook.EventMessageReceived, webhook.EventProviderError}, cfg.Webhooks.Events)
+	assert.True(t, cfg.Webhooks.IncludeText)
+	assert.Equal(t, 5*time.Second, cfg.Webhooks.Timeout)
+}
+
+func TestLoad_WebhooksUnknownEvent(t *testing.T) {
+	// Arrange
+	resetViper()
+	configContent := `
+telegram:
+  bot_token: test_token
+genai:
+  provider: ollama
+  mode: chat
+webhooks:
+  enabled: true
+  urls:
+    - https://example.com/hook
+  events: [message_deleted]
+`
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	// Act
+	cfg, err := Load(configPath)
+
+	// Assert
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid webhook config")
+	assert.Nil(t, cfg)
+}
+
+func TestLoad_Dashboard(t *testing.T) {
+	tests := []struct {
+		name      string
+		dashboard string
+		valid     bool
+	}{
+		{
+			name:      "Enabled",
+			dashboard: "enabled: true\n  listen_address: 0.0.0.0:8080\n  token: 0123456789abcdef",
+			valid:     true,
+		},
+		{
+			name:      "Short token",
+			dashboard: "enabled: true\n  token: secret",
+		},
+		{
+			name:      "Empty listen address",
+			dashboard: "enabled: true\n  listen_address: \"\"\n  token: 0123456789abcdef",
+		},
+		{
+			name:      "Disabled without token",
+			dashboard: "enabled: false",
+			valid:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			resetViper()
+			configContent := `
+telegram:
+  bot_token: test_token
+genai:
+  provider: ollama
+  mode: chat
+dashboard:
+  ` + tt.dashboard + "\n"
+			tempDir := t.TempDir()
+			configPath := filepath.Join(tempDir, "config.yaml")
+			err := os.WriteFile(configPath, []byte(configContent), 0644)
+			require.NoError(t, err)
+
+			// Act
+			cfg, err := Load(configPath)
+
+			// Assert
+			if !tt.valid {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "invalid dashboard config")
+				assert.Nil(t, cfg)
+				return
+			}
+			require.NoError(t, err)
+			if cfg.Dashboard.Enabled {
+				assert.Equal(t, "0.0.0.0:8080", cfg.Dashboard.ListenAddress)
+				assert.Equal(t, "0123456789abcdef", cfg.Dashboard.Token)
+			}
+		})
+	}
+}
+
+func TestLoad_Scripting(t *testing.T) {
+	tests := []struct {
+		name      string
+		scripting string
+		valid     bool
+	}{
+		{name: "Enabled", scripting: "enabled: true\n  directory: hooks\n  timeout: 200ms", valid: true},
+		{name: "Empty directory", scripting: "enabled: true\n  directory: \"\""},
+		{name: "Zero timeout", scripting: "enabled: true\n  timeout: 0s"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			resetViper()
+			configContent := `
+telegram:
+  bot_token: test_token
+genai:
+  provider: ollama
+  mode: chat
+scripting:
+  ` + tt.scripting + "\n"
+			tempDir := t.TempDir()
+			configPath := filepath.Join(tempDir, "config.yaml")
+			err := os.WriteFile(configPath, []byte(configContent), 0644)
+			require.NoError(t, err)
+
+			// Act
+			cfg, err := Load(configPath)
+
+			// Assert
+			if !tt.valid {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "invalid scripting config")
+				assert.Nil(t, cfg)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, cfg.Scripting.Enabled)
+			assert.Equal(t, "hooks", cfg.Scripting.Directory)
+			assert.Equal(t, 200*time.Millisecond, cfg.Scripting.Timeout)
+		})
+	}
+}
+
+func TestLoad_TTSMissingBaseURL(t *testing.T) {
+	// Arrange
+	resetViper()
+	configContent := `
+telegram:
+  bot_token: test_token
+genai:
+  provider: ollama
+  mode: chat
+tts:
+  enabled: true
+  backend: piper
+`
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	// Act
+	cfg, err := Load(configPath)
+
+	// Assert
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid text-to-speech config")
+	assert.Nil(t, cfg)
+}
+
+func TestLoad_RAG(t *testing.T) {
+	// Arrange
+	resetViper()
+	configContent := `
+telegram:
+  bot_token: test_token
+genai:
+  provider: ollama
+  mode: chat
+rag:
+  enabled: true
+  embedding_model: nomic-embed-text
+  top_k: 2
+`
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	// Act
+	cfg, err := Load(configPath)
+
+	// Assert
+	require.NoError(t, err)
+	assert.True(t, cfg.RAG.Enabled)
+	assert.Equal(t, "nomic-embed-text", cfg.RAG.EmbeddingModel)
+	assert.Equal(t, 2, cfg.RAG.TopK)
+	assert.Equal(t, 1000, cfg.RAG.ChunkSize)
+	assert.Equal(t, 200, cfg.RAG.ChunkOverlap)
+	assert.Equal(t, int64(10*1024*1024), cfg.RAG.MaxDocumentSize)
+}
+
+func TestLoad_Routing(t *testing.T) {
+	// Arrange
+	resetViper()
+	configContent := `
+telegram:
+  bot_token: test_token
+genai:
+  provider: ollama
+  mode: chat
+  routing:
+    enabled: true
+    small_model: llama3.2:3b
+    large_model: llama3.3:70b
+    complex_min_lines: 0
+`
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	// Act
+	cfg, err := Load(configPath)
+
+	// Assert
+	require.NoError(t, err)
+	assert.True(t, cfg.GenerativeAI.Routing.Enabled)
+	assert.Equal(t, "llama3.2:3b", cfg.GenerativeAI.Routing.SmallModel)
+	assert.Equal(t, "llama3.3:70b", cfg.GenerativeAI.Routing.LargeModel)
+	assert.Equal(t, 80, cfg.GenerativeAI.Routing.SimpleMaxLength)
+	assert.Equal(t, 800, cfg.GenerativeAI.Routing.ComplexMinLength)
+	assert.Zero(t, cfg.GenerativeAI.Routing.ComplexMinLines)
+}
+
+func TestLoad_WriteBuffer(t *testing.T) {
+	// Arrange
+	resetViper()
+	configContent := `
+database:
+  write_buffer_interval: 2s
+  write_buffer_size: 50
+telegram:
+  bot_token: test_token
+genai:
+  provider: ollama
+  mode: chat
+`
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	// Act
+	cfg, err := Load(configPath)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Second, cfg.Database.WriteBufferInterval)
+	assert.Equal(t, 50, cfg.Database.WriteBufferSize)
+}
+
+func TestLoad_MySQL(t *testing.T) {
+	// Arrange
+	resetViper()
+	configContent := `
+database:
+  driver: mysql
+  dsn: tellama:secret@tcp(localhost:3306)/tellama
+telegram:
+  bot_token: test_token
+genai:
+  provider: ollama
+  mode: chat
+`
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	// Act
+	cfg, err := Load(configPath)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, database.DriverMySQL, cfg.Database.Driver)
+	assert.Equal(t, "tellama:secret@tcp(localhost:3306)/tellama", cfg.Database.DSN)
+}
+
+func TestLoad_MySQLMissingDSN(t *testing.T) {
+	// Arrange
+	resetViper()
+	configContent := `
+database:
+  driver: mysql
+telegram:
+  bot_token: test_token
+genai:
+  provider: ollama
+  mode: chat
+`
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	// Act
+	cfg, err := Load(configPath)
+
+	// Assert
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "database DSN is required")
+	assert.Nil(t, cfg)
+}
+
+func TestLoad_SQLite(t *testing.T) {
+	// Arrange
+	resetViper()
+	configContent := `
+database:
+  sqlite:
+    synchronous: full
+    busy_timeout: 10s
+    max_open_conns: 8
+    conn_max_lifetime: 1h
+telegram:
+  bot_token: test_token
+genai:
+  provider: ollama
+  mode: chat
+`
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	// Act
+	cfg, err := Load(configPath)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "wal", cfg.Database.SQLite.JournalMode)
+	assert.Equal(t, "full", cfg.Database.SQLite.Synchronous)
+	assert.Equal(t, 10*time.Second, cfg.Database.SQLite.BusyTimeout)
+	assert.Equal(t, 8, cfg.Database.SQLite.MaxOpenConns)
+	assert.Equal(t, time.Hour, cfg.Database.SQLite.ConnMaxLifetime)
+}
+
+func TestLoad_SQLiteInvalidJournalMode(t *testing.T) {
+	// Arrange
+	resetViper()
+	configContent := `
+database:
+  sqlite:
+    journal_mode: fast
+telegram:
+  bot_token: test_token
+genai:
+  provider: ollama
+  mode: chat
+`
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	// Act
+	cfg, err := Load(configPath)
+
+	// Assert
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid SQLite config")
+	assert.Nil(t, cfg)
+}
+
+func TestLoad_AdaptiveHistory(t *testing.T) {
+	// Arrange
+	resetViper()
+	configContent := `
+telegram:
+  bot_token: test_token
+genai:
+  provider: ollama
+  mode: chat
+  adaptive_history:
+    enabled: true
+    target_latency: 30s
+`
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	// Act
+	cfg, err := Load(configPath)
+
+	// Assert
+	require.NoError(t, err)
+	assert.True(t, cfg.GenerativeAI.AdaptiveHistory.Enabled)
+	assert.Equal(t, 30*time.Second, cfg.GenerativeAI.AdaptiveHistory.TargetLatency)
+	assert.Equal(t, 10, cfg.GenerativeAI.AdaptiveHistory.MinMessages)
+}
+
+func TestLoad_AdaptiveHistoryInvalidMinimum(t *testing.T) {
+	// Arrange
+	resetViper()
+	configContent := `
+telegram:
+  bot_token: test_token
+genai:
+  provider: ollama
+  mode: chat
+  adaptive_history:
+    enabled: true
+    min_messages: 0
+`
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	// Act
+	cfg, err := Load(configPath)
+
+	// Assert
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid adaptive history config")
+	assert.Nil(t, cfg)
+}
+
+func TestLoad_SemanticContext(t *testing.T) {
+	// Arrange
+	resetViper()
+	configContent := `
+telegram:
+  bot_token: test_token
+genai:
+  provider: ollama
+  mode: chat
+  context_strategy: semantic
+  semantic_context:
+    top_k: 5
+    recent_messages: 4
+rag:
+  embedding_model: nomic-embed-text
+`
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	// Act
+	cfg, err := Load(configPath)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, ContextSemantic, cfg.GenerativeAI.ContextStrategy)
+	assert.Equal(t, 5, cfg.GenerativeAI.SemanticContext.TopK)
+	assert.Equal(t, 4, cfg.GenerativeAI.SemanticContext.RecentMessages)
+	assert.Equal(t, 1000, cfg.GenerativeAI.SemanticContext.Candidates)
+}
+
+func TestLoad_SemanticContextWithoutEmbeddingModel(t *testing.T) {
+	// Arrange
+	resetViper()
+	configContent := `
+telegram:
+  bot_token: test_token
+genai:
+  provider: ollama
+  mode: chat
+  context_strategy: semantic
+`
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	// Act
+	cfg, err := Load(configPath)
+
+	// Assert
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid semantic context config")
+	assert.Nil(t, cfg)
+}
+
+func TestLoad_DailyTokenBudget(t *testing.T) {
+	// Arrange
+	resetViper()
+	configContent := `
+telegram:
+  bot_token: test_token
+genai:
+  provider: ollama
+  mode: chat
+  daily_token_budget: 200000
+messages:
+  budget_exhausted: Out of tokens
+`
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	// Act
+	cfg, err := Load(configPath)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, int64(200000), cfg.GenerativeAI.DailyTokenBudget)
+	assert.Equal(t, "Out of tokens", cfg.ResponseMessages.BudgetExhausted)
+}
+
+func TestLoad_DailyTokenBudgetNegative(t *testing.T) {
+	// Arrange
+	resetViper()
+	configContent := `
+telegram:
+  bot_token: test_token
+genai:
+  provider: ollama
+  mode: chat
+  daily_token_budget: -1
+`
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	// Act
+	cfg, err := Load(configPath)
+
+	// Assert
+	require.Error(t, err)
+	assert.Nil(t, cfg)
+}
+
+func TestLoad_CircuitBreaker(t *testing.T) {
+	// Arrange
+	resetViper()
+	configContent := `
+telegram:
+  bot_token: test_token
+genai:
+  provider: ollama
+  mode: chat
+  circuit_breaker:
+    enabled: true
+    failure_threshold: 3
+messages:
+  provider_down: Ollama is down
+`
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	// Act
+	cfg, err := Load(configPath)
+
+	// Assert
+	require.NoError(t, err)
+	assert.True(t, cfg.GenerativeAI.CircuitBreaker.Enabled)
+	assert.Equal(t, 3, cfg.GenerativeAI.CircuitBreaker.FailureThreshold)
+	assert.Equal(t, time.Minute, cfg.GenerativeAI.CircuitBreaker.Cooldown)
+	assert.Equal(t, "Ollama is down", cfg.ResponseMessages.ProviderDown)
+}
+
+func TestLoad_CircuitBreakerInvalidThreshold(t *testing.T) {
+	// Arrange
+	resetViper()
+	configContent := `
+telegram:
+  bot_token: test_token
+genai:
+  provider: ollama
+  mode: chat
+  circuit_breaker:
+    enabled: true
+    failure_threshold: 0
+`
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	// Act
+	cfg, err := Load(configPath)
+
+	// Assert
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid circuit breaker config")
+	assert.Nil(t, cfg)
+}
+
+func TestLoad_Retry(t *testing.T) {
+	// Arrange
+	resetViper()
+	configContent := `
+telegram:
+  bot_token: test_token
+genai:
+  provider: openai
+  mode: chat
+  retry:
+    max_attempts: 5
+    base_delay: 500ms
+    max_delay: 1m
+    jitter: 0
+openai:
+  api_key: test_key
+`
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	// Act
+	cfg, err := Load(configPath)
+
+	// Assert
+	require.NoError(t, err)
+	openaiCfg, ok := cfg.GenerativeAI.Config.(*genai.OpenAIConfig)
+	require.True(t, ok)
+	assert.Equal(t, 5, openaiCfg.Retry.MaxAttempts)
+	assert.Equal(t, 500*time.Millisecond, openaiCfg.Retry.BaseDelay)
+	assert.Equal(t, time.Minute, openaiCfg.Retry.MaxDelay)
+	assert.Zero(t, openaiCfg.Retry.Jitter)
+}
+
+func TestLoad_RetryInvalidJitter(t *testing.T) {
+	// Arrange
+	resetViper()
+	configContent := `
+telegram:
+  bot_token: test_token
+genai:
+  provider: ollama
+  mode: chat
+  retry:
+    jitter: 1.5
+`
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	// Act
+	cfg, err := Load(configPath)
+
+	// Assert
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid retry config")
+	assert.Nil(t, cfg)
+}
+
+func TestLoad_Capabilities(t *testing.T) {
+	// Arrange
+	resetViper()
+	configContent := `
+telegram:
+  bot_token: test_token
+genai:
+  provider: ollama
+  mode: chat
+  capabilities:
+    vision: false
+    tools: unset
+    reasoning: true
+`
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	// Act
+	cfg, err := Load(configPath)
+
+	// Assert
+	require.NoError(t, err)
+	overrides := cfg.GenerativeAI.Capabilities
+	require.NotNil(t, overrides.Vision)
+	assert.False(t, *overrides.Vision)
+	require.NotNil(t, overrides.Reasoning)
+	assert.True(t, *overrides.Reasoning)
+	assert.Nil(t, overrides.Tools)
+	assert.Nil(t, overrides.Streaming)
+}
+
+func TestLoad_CapabilitiesInvalidValue(t *testing.T) {
+	// Arrange
+	resetViper()
+	configContent := `
+telegram:
+  bot_token: test_token
+genai:
+  provider: ollama
+  mode: chat
+  capabilities:
+    vision: sometimes
+`
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	// Act
+	cfg, err := Load(configPath)
+
+	// Assert
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid capabilities config")
+	assert.Nil(t, cfg)
+}
+
+func TestLoad_Documents(t *testing.T) {
+	// Arrange
+	resetViper()
+	configContent := `
+telegram:
+  bot_token: test_token
+genai:
+  provider: ollama
+  mode: chat
+documents:
+  enabled: false
+  max_file_size: 2048
+  max_excerpt_length: 500
+messages:
+  document_too_large: "Too large"
+`
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	// Act
+	cfg, err := Load(configPath)
+
+	// Assert
+	require.NoError(t, err)
+	assert.False(t, cfg.Documents.Enabled)
+	assert.Equal(t, int64(2048), cfg.Documents.MaxFileSize)
+	assert.Equal(t, 500, cfg.Documents.MaxExcerptLength)
+	assert.Equal(t, "Too large", cfg.ResponseMessages.DocumentTooLarge)
+}
+
+func TestLoad_Abuse(t *testing.T) {
+	// Arrange
+	resetViper()
+	configContent := `
+telegram:
+  bot_token: test_token
+genai:
+  provider: ollama
+  mode: chat
+abuse:
+  max_messages_per_minute: 5
+  max_message_length: 4000
+  cooldown: 30m
+messages:
+  user_silenced: ""
+`
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	// Act
+	cfg, err := Load(configPath)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, 5, cfg.Abuse.MaxMessagesPerMinute)
+	assert.Equal(t, 4000, cfg.Abuse.MaxMessageLength)
+	assert.Equal(t, 30*time.Minute, cfg.Abuse.Cooldown)
+	assert.Empty(t, cfg.ResponseMessages.UserSilenced)
+}
+
+func TestLoad_Persona(t *testing.T) {
+	// Arrange
+	resetViper()
+	configContent := `
+telegram:
+  bot_token: test_token
+genai:
+  provider: ollama
+  mode: chat
+persona:
+  name: Ada
+  description: a helpful assistant for the Example community
+  style: friendly and concise
+`
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	// Act
+	cfg, err := Load(configPath)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "Ada", cfg.Persona.Name)
+	assert.Equal(t, "a helpful assistant for the Example community", cfg.Persona.Description)
+	assert.Equal(t, "friendly and concise", cfg.Persona.Style)
+}
+
+func TestLoad_Personas(t *testing.T) {
+	// Arrange
+	resetViper()
+	configContent := `
+telegram:
+  bot_token: test_token
+genai:
+  provider: ollama
+  mode: chat
+personas:
+  helpdesk:
+    system_prompt: You are a helpdesk assistant.
+    model: llama3.3:70b
+    options:
+      temperature: 0.2
+  casual:
+    system_prompt: You are a friendly assistant.
+`
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	// Act
+	cfg, err := Load(configPath)
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, cfg.Personas, 2)
+	assert.Equal(t, NamedPersona{
+		SystemPrompt: "You are a helpdesk assistant.",
+		Model:        "llama3.3:70b",
+		Options:      `{"temperature":0.2}`,
+	}, cfg.Personas["helpdesk"])
+	assert.Equal(t, NamedPersona{SystemPrompt: "You are a friendly assistant."}, cfg.Personas["casual"])
+}
+
+func TestLoad_PersonasReservedName(t *testing.T) {
+	// Arrange
+	resetViper()
+	configContent := `
+telegram:
+  bot_token: test_token
+genai:
+  provider: ollama
+  mode: chat
+personas:
+  default:
+    model: llama3.3:70b
+`
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	// Act
+	_, err = Load(configPath)
+
+	// Assert
+	require.Error(t, err)
+}
+
+func TestLoad_PersonaEmptyName(t *testing.T) {
+	// Arrange
+	resetViper()
+	configContent := `
+telegram:
+  bot_token: test_token
+genai:
+  provider: ollama
+  mode: chat
+persona:
+  name: ""
+`
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	// Act
+	_, err = Load(configPath)
+
+	// Assert
+	require.Error(t, err)
+}
+
+func TestLoad_AbuseInvalidCooldown(t *testing.T) {
+	// Arrange
+	resetViper()
+	configContent := `
+telegram:
+  bot_token: test_token
+genai:
+  provider: ollama
+  mode: chat
+abuse:
+  cooldown: 0s
+`
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	// Act
+	cfg, err := Load(configPath)
+
+	// Assert
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "abuse cooldown")
+	assert.Nil(t, cfg)
+}
+
+func TestLoad_DocumentsInvalidFileSize(t *testing.T) {
+	// Arrange
+	resetViper()
+	configContent := `
+telegram:
+  bot_token: test_token
+genai:
+  provider: ollama
+  mode: chat
+documents:
+  max_file_size: 0
+`
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	// Act
+	cfg, err := Load(configPath)
+
+	// Assert
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "must be positive")
+	assert.Nil(t, cfg)
+}
+
+func TestLoad_RoutingMissingModels(t *testing.T) {
+	// Arrange
+	resetViper()
+	configContent := `
+telegram:
+  bot_token: test_token
+genai:
+  provider: ollama
+  mode: chat
+  routing:
+    enabled: true
+`
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	// Act
+	cfg, err := Load(configPath)
+
+	// Assert
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid routing config")
+	assert.Nil(t, cfg)
+}
+
+func TestLoad_RAGMissingEmbeddingModel(t *testing.T) {
+	// Arrange
+	resetViper()
+	configContent := `
+telegram:
+  bot_token: test_token
+genai:
+  provider: ollama
+  mode: chat
+rag:
+  enabled: true
+`
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	// Act
+	cfg, err := Load(configPath)
+
+	// Assert
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "embedding model cannot be empty")
+	assert.Nil(t, cfg)
+}
+
+func TestLoad_WebSearchMissingBaseURL(t *testing.T) {
+	// Arrange
+	resetViper()
+	configContent := `
+telegram:
+  bot_token: test_token
+genai:
+  provider: ollama
+  mode: chat
+tools:
+  websearch:
+    enabled: true
+    backend: searxng
+`
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	// Act
+	cfg, err := Load(configPath)
+
+	// Assert
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "base URL is required")
+	assert.Nil(t, cfg)
+}
+
+func TestLoad_Redaction(t *testing.T) {
+	// Arrange
+	resetViper()
+	configContent := `
+database:
+  redaction:
+    enabled: true
+    phone_numbers: false
+    patterns:
+      - '\bEMP-\d{6}\b'
+telegram:
+  bot_token: test_token
+genai:
+  provider: ollama
+  mode: chat
+`
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	// Act
+	cfg, err := Load(configPath)
+
+	// Assert
+	require.NoError(t, err)
+	assert.True(t, cfg.Database.Redaction.Enabled)
+	assert.True(t, cfg.Database.Redaction.Emails)
+	assert.False(t, cfg.Database.Redaction.PhoneNumbers)
+	assert.Equal(t, []string{`\bEMP-\d{6}\b`}, cfg.Database.Redaction.Patterns)
+}
+
+func TestLoad_RedactionInvalidPattern(t *testing.T) {
+	// Arrange
+	resetViper()
+	configContent := `
+database:
+  redaction:
+    enabled: true
+    patterns: ["("]
+telegram:
+  bot_token: test_token
+genai:
+  provider: ollama
+  mode: chat
+`
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	// Act
+	cfg, err := Load(configPath)
+
+	// Assert
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid redaction config")
+	assert.Nil(t, cfg)
+}
+
+func TestLoad_NegativeDegradedQueueSize(t *testing.T) {
+	// Arrange
+	resetViper()
+	configContent := `
+database:
+  degraded_queue_size: -1
+telegram:
+  bot_token: test_token
+genai:
+  provider: ollama
+  mode: chat
+`
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "config.yaml")
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	// Act
+	cfg, err := Load(configPath)
+
+	// Assert
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "degraded queue size cannot be negative")
+	assert.Nil(t, cfg)
 }
@@ -0,0 +1,371 @@
+// Package dashboard serves a web dashboard that shows the history and token usage of chats
+// and edits their chat overrides.
+package dashboard
+
+import (
+	"crypto/subtle"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/k4yt3x/tellama/internal/database"
+
+	"github.com/rs/zerolog/log"
+)
+
+// static holds the single-page application served at the root of the dashboard.
+//
+//go:embed static
+var static embed.FS //nolint:gochecknoglobals // Embedded files must be package variables
+
+const (
+	// minTokenLength is the minimum length of the authentication token.
+	minTokenLength = 16
+
+	// defaultHistoryLimit and maxHistoryLimit bound the number of messages returned per request.
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 500
+
+	// defaultUsageDays and maxUsageDays bound the number of days of token usage returned per request.
+	defaultUsageDays = 14
+	maxUsageDays     = 90
+
+	// maxRequestBodySize limits the size of override updates.
+	maxRequestBodySize = 1 << 20
+
+	// readHeaderTimeout bounds the time spent reading the headers of a request.
+	readHeaderTimeout = 10 * time.Second
+)
+
+type Config struct {
+	Enabled       bool
+	ListenAddress string
+
+	// Token must be sent as a bearer token with every API request
+	Token string
+}
+
+func (c *Config) Validate() error {
+	if c.ListenAddress == "" {
+		return errors.New("listen address cannot be empty")
+	}
+	if len(c.Token) < minTokenLength {
+		return fmt.Errorf("token must be at least %d characters long", minTokenLength)
+	}
+	return nil
+}
+
+// Server serves the dashboard and the API it reads the database with.
+type Server struct {
+	listenAddress   string
+	token           string
+	dm              *database.Manager
+	validateOptions func(options string) error
+	files           fs.FS
+}
+
+// New returns a dashboard of the database. Options set through the dashboard are checked with validateOptions.
+func New(config *Config, dm *database.Manager, validateOptions func(options string) error) (*Server, error) {
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid dashboard config: %w", err)
+	}
+
+	files, err := fs.Sub(static, "static")
+	if err != nil {
+		return nil, err
+	}
+	return &Server{
+		listenAddress:   config.ListenAddress,
+		token:           config.Token,
+		dm:              dm,
+		validateOptions: validateOptions,
+		files:           files,
+	}, nil
+}
+
+// Handler returns the handler of the dashboard and its API.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("GET /", http.FileServerFS(s.files))
+	mux.HandleFunc("GET /api/chats", s.authorize(s.listChats))
+	mux.HandleFunc("GET /api/chats/{chatID}/messages", s.authorize(s.listMessages))
+	mux.HandleFunc("GET /api/chats/{chatID}/usage", s.authorize(s.chatUsage))
+	mux.HandleFunc("GET /api/chats/{chatID}/override", s.authorize(s.getOverride))
+	mux.HandleFunc("PUT /api/chats/{chatID}/override", s.authorize(s.updateOverride))
+	mux.HandleFunc("GET /api/usage", s.authorize(s.globalUsage))
+	return mux
+}
+
+// Run serves the dashboard. It only returns if the server fails, so it is run in its own goroutine.
+func (s *Server) Run() {
+	server := &http.Server{
+		Addr:              s.listenAddress,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+
+	log.Info().Str("listen_address", server.Addr).Msg("Serving dashboard")
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Error().Err(err).Msg("Failed to serve dashboard")
+	}
+}
+
+// authorize rejects the requests that do not carry the token of the dashboard.
+func (s *Server) authorize(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="tellama"`)
+			writeError(w, http.StatusUnauthorized, "invalid token")
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// Chat is a trusted chat listed by the dashboard.
+type Chat struct {
+	ChatID    int64  `json:"chat_id"`
+	ChatTitle string `json:"chat_title"`
+}
+
+func (s *Server) listChats(w http.ResponseWriter, _ *http.Request) {
+	trustedChats, err := s.dm.ListTrustedChats()
+	if err != nil {
+		writeInternalError(w, err, "Failed to list trusted chats")
+		return
+	}
+
+	chats := make([]Chat, len(trustedChats))
+	for i, trustedChat := range trustedChats {
+		chats[i] = Chat{ChatID: trustedChat.ChatID, ChatTitle: trustedChat.ChatTitle}
+	}
+	writeJSON(w, http.StatusOK, chats)
+}
+
+// Message is a stored message of a chat, oldest first.
+type Message struct {
+	ID          uint      `json:"id"`
+	Timestamp   time.Time `json:"timestamp"`
+	Role        string    `json:"role"`
+	ContentType string    `json:"content_type"`
+	UserID      int64     `json:"user_id"`
+	Username    string    `json:"username,omitempty"`
+	FirstName   string    `json:"first_name,omitempty"`
+	LastName    string    `json:"last_name,omitempty"`
+	Content     string    `json:"content"`
+}
+
+func (s *Server) listMessages(w http.ResponseWriter, r *http.Request) {
+	chatID, ok := chatIDParam(w, r)
+	if !ok {
+		return
+	}
+	limit, ok := intParam(w, r, "limit", defaultHistoryLimit, maxHistoryLimit)
+	if !ok {
+		return
+	}
+	threadID, ok := intParam(w, r, "thread", 0, 0)
+	if !ok {
+		return
+	}
+
+	history, err := s.dm.GetMessages(chatID, threadID, limit)
+	if err != nil {
+		writeInternalError(w, err, "Failed to get messages")
+		return
+	}
+
+	messages := make([]Message, len(history))
+	for i, message := range history {
+		messages[i] = Message{
+			ID:          message.ID,
+			Timestamp:   message.Timestamp,
+			Role:        message.Role,
+			ContentType: message.ContentType,
+			UserID:      message.UserID,
+			Username:    message.Username,
+			FirstName:   message.FirstName,
+			LastName:    message.LastName,
+			Content:     message.Content,
+		}
+	}
+	writeJSON(w, http.StatusOK, messages)
+}
+
+// DailyUsage is the number of tokens used on a UTC day.
+type DailyUsage struct {
+	Date             string `json:"date"`
+	PromptTokens     int64  `json:"prompt_tokens"`
+	CompletionTokens int64  `json:"completion_tokens"`
+}
+
+func (s *Server) chatUsage(w http.ResponseWriter, r *http.Request) {
+	chatID, ok := chatIDParam(w, r)
+	if !ok {
+		return
+	}
+	s.writeUsage(w, r, func(sinceDate string) ([]database.Usage, error) {
+		return s.dm.GetChatUsageSince(chatID, sinceDate)
+	})
+}
+
+func (s *Server) globalUsage(w http.ResponseWriter, r *http.Request) {
+	s.writeUsage(w, r, s.dm.GetGlobalUsageSince)
+}
+
+// writeUsage writes the daily token usage of the days requested, including the days without usage.
+func (s *Server) writeUsage(
+	w http.ResponseWriter,
+	r *http.Request,
+	usageSince func(sinceDate string) ([]database.Usage, error),
+) {
+	days, ok := intParam(w, r, "days", defaultUsageDays, maxUsageDays)
+	if !ok {
+		return
+	}
+
+	today := time.Now().UTC()
+	usages, err := usageSince(today.AddDate(0, 0, 1-days).Format(time.DateOnly))
+	if err != nil {
+		writeInternalError(w, err, "Failed to get usage")
+		return
+	}
+
+	dailyUsages := make([]DailyUsage, days)
+	index := make(map[string]int, days)
+	for i := range dailyUsages {
+		date := today.AddDate(0, 0, i+1-days).Format(time.DateOnly)
+		dailyUsages[i].Date = date
+		index[date] = i
+	}
+	for _, usage := range usages {
+		if i, exists := index[usage.Date]; exists {
+			dailyUsages[i].PromptTokens += usage.PromptTokens
+			dailyUsages[i].CompletionTokens += usage.CompletionTokens
+		}
+	}
+	writeJSON(w, http.StatusOK, dailyUsages)
+}
+
+// Override is the editable part of the chat override of a chat merged into the global override.
+// API keys are never returned.
+type Override struct {
+	Model        string `json:"model"`
+	SystemPrompt string `json:"system_prompt"`
+	Options      string `json:"options"`
+	Language     string `json:"language,omitempty"`
+	Trigger      string `json:"trigger,omitempty"`
+	Persona      string `json:"persona,omitempty"`
+}
+
+func (s *Server) getOverride(w http.ResponseWriter, r *http.Request) {
+	chatID, ok := chatIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	chatOverride, err := s.dm.GetChatOverride(chatID)
+	if err != nil {
+		writeInternalError(w, err, "Failed to get chat override")
+		return
+	}
+	writeJSON(w, http.StatusOK, Override{
+		Model:        chatOverride.Model,
+		SystemPrompt: chatOverride.SystemPrompt,
+		Options:      chatOverride.Options,
+		Language:     chatOverride.Language,
+		Trigger:      chatOverride.Trigger,
+		Persona:      chatOverride.Persona,
+	})
+}
+
+// updateOverride sets the model, system prompt, and options of a chat. Empty fields are left unchanged.
+func (s *Server) updateOverride(w http.ResponseWriter, r *http.Request) {
+	chatID, ok := chatIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var update Override
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodySize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&update); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid override")
+		return
+	}
+	if update.Language != "" || update.Trigger != "" || update.Persona != "" {
+		writeError(w, http.StatusBadRequest, "only the model, system prompt, and options can be changed")
+		return
+	}
+	if update.Model == "" && update.SystemPrompt == "" && update.Options == "" {
+		writeError(w, http.StatusBadRequest, "nothing to change")
+		return
+	}
+	if update.Options != "" {
+		if err := s.validateOptions(update.Options); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid options: %v", err))
+			return
+		}
+	}
+
+	err := s.dm.SetChatOverride(chatID, "", "", "", update.Model, update.Options, update.SystemPrompt)
+	if err != nil {
+		writeInternalError(w, err, "Failed to set chat override")
+		return
+	}
+
+	log.Info().Int64("chat_id", chatID).Msg("Chat override set from the dashboard")
+	s.getOverride(w, r)
+}
+
+// chatIDParam returns the chat ID in the path of a request, or writes an error if it is invalid.
+func chatIDParam(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	chatID, err := strconv.ParseInt(r.PathValue("chatID"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid chat ID")
+		return 0, false
+	}
+	return chatID, true
+}
+
+// intParam returns the non-negative integer query parameter of a request, or fallback if it is not set.
+// Values above maxValue are rejected unless maxValue is zero.
+func intParam(w http.ResponseWriter, r *http.Request, name string, fallback int, maxValue int) (int, bool) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return fallback, true
+	}
+
+	value, err := strconv.Atoi(raw)
+	if err != nil || value < 0 || (maxValue > 0 && (value == 0 || value > maxValue)) {
+		writeError(w, http.StatusBadRequest, "invalid "+name)
+		return 0, false
+	}
+	return value, true
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		log.Error().Err(err).Msg("Failed to write dashboard response")
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+// writeInternalError logs an error and hides its details from the client.
+func writeInternalError(w http.ResponseWriter, err error, message string) {
+	log.Error().Err(err).Msg(message)
+	writeError(w, http.StatusInternalServerError, "internal error")
+}
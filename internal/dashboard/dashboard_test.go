@@ -0,0 +1,306 @@
+package dashboard //nolint:testpackage // Unit tests are in the same package
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/k4yt3x/tellama/internal/database"
+	"github.com/k4yt3x/tellama/internal/database/dbtest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testToken = "0123456789abcdef"
+
+// newTestServer returns a dashboard of a test database that rejects options containing "invalid".
+func newTestServer(t *testing.T, dm *database.Manager) http.Handler {
+	t.Helper()
+
+	server, err := New(&Config{Enabled: true, ListenAddress: "127.0.0.1:0", Token: testToken}, dm,
+		func(options string) error {
+			if strings.Contains(options, "invalid") {
+				return errors.New("invalid option")
+			}
+			return nil
+		})
+	require.NoError(t, err)
+	return server.Handler()
+}
+
+// request sends a request with the test token to the handler and decodes the JSON response into response.
+func request(t *testing.T, handler http.Handler, method string, path string, body string, response any) int {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	if response != nil {
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), response))
+	}
+	return recorder.Code
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		valid  bool
+	}{
+		{name: "Valid", config: Config{ListenAddress: "127.0.0.1:9465", Token: testToken}, valid: true},
+		{name: "Empty listen address", config: Config{Token: testToken}},
+		{name: "Short token", config: Config{ListenAddress: "127.0.0.1:9465", Token: "secret"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			err := tt.config.Validate()
+
+			// Assert
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	handler := newTestServer(t, dbtest.NewTestManager(t))
+
+	tests := []struct {
+		name          string
+		path          string
+		authorization string
+		expected      int
+	}{
+		{name: "Static files", path: "/", expected: http.StatusOK},
+		{name: "Valid token", path: "/api/chats", authorization: "Bearer " + testToken, expected: http.StatusOK},
+		{name: "Missing token", path: "/api/chats", expected: http.StatusUnauthorized},
+		{
+			name:          "Wrong token",
+			path:          "/api/chats",
+			authorization: "Bearer fedcba9876543210",
+			expected:      http.StatusUnauthorized,
+		},
+		{name: "Token without scheme", path: "/api/usage", authorization: testToken, expected: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			recorder := httptest.NewRecorder()
+
+			// Act
+			handler.ServeHTTP(recorder, req)
+
+			// Assert
+			assert.Equal(t, tt.expected, recorder.Code)
+			if tt.expected == http.StatusUnauthorized {
+				assert.NotEmpty(t, recorder.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestListChats(t *testing.T) {
+	// Arrange
+	b := dbtest.NewBuilder(t, dbtest.NewTestManager(t))
+	chatID := b.TrustedChat("Test Chat")
+	b.Chat()
+	handler := newTestServer(t, b.Manager())
+
+	// Act
+	var chats []Chat
+	status := request(t, handler, http.MethodGet, "/api/chats", "", &chats)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, []Chat{{ChatID: chatID, ChatTitle: "Test Chat"}}, chats)
+}
+
+func TestListMessages(t *testing.T) {
+	b := dbtest.NewBuilder(t, dbtest.NewTestManager(t))
+	chatID := b.TrustedChat("Test Chat")
+	user := b.User()
+	b.Conversation(chatID, user, 4)
+	b.ThreadMessage(chatID, 7, "user", user, "topic message")
+	handler := newTestServer(t, b.Manager())
+
+	t.Run("Latest messages", func(t *testing.T) {
+		// Act
+		var messages []Message
+		status := request(t, handler, http.MethodGet, chatPath(chatID, "messages?limit=3"), "", &messages)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, status)
+		require.Len(t, messages, 3)
+		assert.Equal(t, "message 2", messages[0].Content)
+		assert.Equal(t, "message 4", messages[2].Content)
+		assert.Equal(t, "assistant", messages[2].Role)
+	})
+
+	t.Run("Thread messages", func(t *testing.T) {
+		// Act
+		var messages []Message
+		status := request(t, handler, http.MethodGet, chatPath(chatID, "messages?thread=7"), "", &messages)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, status)
+		require.Len(t, messages, 1)
+		assert.Equal(t, "topic message", messages[0].Content)
+		assert.Equal(t, user.Username, messages[0].Username)
+	})
+
+	t.Run("Invalid parameters", func(t *testing.T) {
+		for _, path := range []string{
+			"/api/chats/abc/messages",
+			chatPath(chatID, "messages?limit=0"),
+			chatPath(chatID, "messages?limit=501"),
+			chatPath(chatID, "messages?thread=-1"),
+		} {
+			// Act
+			var response map[string]string
+			status := request(t, handler, http.MethodGet, path, "", &response)
+
+			// Assert
+			assert.Equal(t, http.StatusBadRequest, status, path)
+			assert.NotEmpty(t, response["error"], path)
+		}
+	})
+}
+
+func TestUsage(t *testing.T) {
+	dm := dbtest.NewTestManager(t)
+	today := time.Now().UTC()
+	date := func(daysAgo int) string {
+		return today.AddDate(0, 0, -daysAgo).Format(time.DateOnly)
+	}
+	require.NoError(t, dm.RecordUsage(date(0), 1, 10, "llama3.3", 100, 50))
+	require.NoError(t, dm.RecordUsage(date(0), 1, 11, "qwen3", 20, 10))
+	require.NoError(t, dm.RecordUsage(date(2), 1, 10, "llama3.3", 5, 5))
+	require.NoError(t, dm.RecordUsage(date(2), 2, 12, "llama3.3", 7, 3))
+	require.NoError(t, dm.RecordUsage(date(30), 1, 10, "llama3.3", 1000, 1000))
+	handler := newTestServer(t, dm)
+
+	t.Run("Chat usage", func(t *testing.T) {
+		// Act
+		var usages []DailyUsage
+		status := request(t, handler, http.MethodGet, "/api/chats/1/usage?days=3", "", &usages)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, []DailyUsage{
+			{Date: date(2), PromptTokens: 5, CompletionTokens: 5},
+			{Date: date(1)},
+			{Date: date(0), PromptTokens: 120, CompletionTokens: 60},
+		}, usages)
+	})
+
+	t.Run("Global usage", func(t *testing.T) {
+		// Act
+		var usages []DailyUsage
+		status := request(t, handler, http.MethodGet, "/api/usage", "", &usages)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, status)
+		require.Len(t, usages, defaultUsageDays)
+		assert.Equal(t, DailyUsage{Date: date(2), PromptTokens: 12, CompletionTokens: 8}, usages[defaultUsageDays-3])
+		assert.Equal(t, DailyUsage{Date: date(0), PromptTokens: 120, CompletionTokens: 60}, usages[defaultUsageDays-1])
+	})
+
+	t.Run("Too many days", func(t *testing.T) {
+		// Act
+		status := request(t, handler, http.MethodGet, "/api/usage?days=91", "", nil)
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, status)
+	})
+}
+
+func TestOverride(t *testing.T) {
+	b := dbtest.NewBuilder(t, dbtest.NewTestManager(t))
+	chatID := b.TrustedChat("Test Chat")
+	b.ChatOverride(chatID, database.ChatOverride{
+		APIKey:       "sk-secret",
+		Model:        "llama3.3",
+		SystemPrompt: "You are a pirate.",
+	})
+	handler := newTestServer(t, b.Manager())
+	path := chatPath(chatID, "override")
+
+	t.Run("Get override", func(t *testing.T) {
+		// Act
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		handler.ServeHTTP(recorder, req)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.NotContains(t, recorder.Body.String(), "sk-secret")
+		var override Override
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &override))
+		assert.Equal(t, Override{Model: "llama3.3", SystemPrompt: "You are a pirate."}, override)
+	})
+
+	t.Run("Update override", func(t *testing.T) {
+		// Act
+		var override Override
+		status := request(t, handler, http.MethodPut, path, `{"options":"{\"temperature\":0.3}"}`, &override)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, Override{
+			Model:        "llama3.3",
+			SystemPrompt: "You are a pirate.",
+			Options:      `{"temperature":0.3}`,
+		}, override)
+		chatOverride, err := b.Manager().GetChatOverride(chatID)
+		require.NoError(t, err)
+		assert.Equal(t, `{"temperature":0.3}`, chatOverride.Options)
+		assert.Equal(t, "sk-secret", chatOverride.APIKey)
+	})
+
+	t.Run("Invalid updates", func(t *testing.T) {
+		for _, body := range []string{
+			`{"options":"invalid"}`,
+			`{"api_key":"sk-other"}`,
+			`{"trigger":"prefix"}`,
+			`{}`,
+			`not json`,
+		} {
+			// Act
+			var response map[string]string
+			status := request(t, handler, http.MethodPut, path, body, &response)
+
+			// Assert
+			assert.Equal(t, http.StatusBadRequest, status, body)
+			assert.NotEmpty(t, response["error"], body)
+		}
+
+		chatOverride, err := b.Manager().GetChatOverride(chatID)
+		require.NoError(t, err)
+		assert.Equal(t, "sk-secret", chatOverride.APIKey)
+		assert.Empty(t, chatOverride.Trigger)
+	})
+}
+
+// chatPath returns the path of an API endpoint of a chat.
+func chatPath(chatID int64, endpoint string) string {
+	return "/api/chats/" + strconv.FormatInt(chatID, 10) + "/" + endpoint
+}
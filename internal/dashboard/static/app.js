@@ -0,0 +1,169 @@
+"use strict";
+
+// The token is kept for the session only so it is not left behind in the browser
+const tokenKey = "tellama-dashboard-token";
+let selectedChat = null;
+
+function setStatus(text, isError) {
+  const status = document.getElementById("status");
+  status.textContent = text;
+  status.className = isError ? "error" : "";
+}
+
+async function api(path, options = {}) {
+  const response = await fetch(path, {
+    ...options,
+    headers: {
+      "Authorization": "Bearer " + sessionStorage.getItem(tokenKey),
+      "Content-Type": "application/json",
+    },
+  });
+  const body = await response.json();
+  if (!response.ok) {
+    throw new Error(body.error || response.statusText);
+  }
+  return body;
+}
+
+// renderUsage draws the daily prompt and completion tokens as stacked bars
+function renderUsage(container, usages) {
+  const width = 480;
+  const height = 120;
+  const barWidth = width / usages.length;
+  const max = Math.max(1, ...usages.map((u) => u.prompt_tokens + u.completion_tokens));
+  const ns = "http://www.w3.org/2000/svg";
+
+  const svg = document.createElementNS(ns, "svg");
+  svg.setAttribute("viewBox", `0 0 ${width} ${height}`);
+  svg.setAttribute("width", "100%");
+  let total = 0;
+  usages.forEach((usage, i) => {
+    total += usage.prompt_tokens + usage.completion_tokens;
+    const promptHeight = (usage.prompt_tokens / max) * height;
+    const completionHeight = (usage.completion_tokens / max) * height;
+    const bars = [
+      ["prompt", height - promptHeight, promptHeight],
+      ["completion", height - promptHeight - completionHeight, completionHeight],
+    ];
+    for (const [kind, y, barHeight] of bars) {
+      const rect = document.createElementNS(ns, "rect");
+      rect.setAttribute("class", kind);
+      rect.setAttribute("x", i * barWidth + 1);
+      rect.setAttribute("y", y);
+      rect.setAttribute("width", Math.max(1, barWidth - 2));
+      rect.setAttribute("height", barHeight);
+      const title = document.createElementNS(ns, "title");
+      title.textContent = `${usage.date}: ${usage.prompt_tokens} prompt, ${usage.completion_tokens} completion`;
+      rect.appendChild(title);
+      svg.appendChild(rect);
+    }
+  });
+
+  const summary = document.createElement("p");
+  summary.textContent = `${total} tokens in the last ${usages.length} days`;
+  container.replaceChildren(svg, summary);
+}
+
+function renderMessages(messages) {
+  const container = document.getElementById("messages");
+  if (messages.length === 0) {
+    container.textContent = "No messages.";
+    return;
+  }
+  container.replaceChildren(...messages.map((message) => {
+    const item = document.createElement("div");
+    item.className = "message " + message.role;
+    const meta = document.createElement("div");
+    meta.className = "meta";
+    const author = message.username ? "@" + message.username :
+      [message.first_name, message.last_name].filter(Boolean).join(" ") || message.role;
+    meta.textContent = `${new Date(message.timestamp).toLocaleString()} · ${author}`;
+    const content = document.createElement("div");
+    content.textContent = message.content;
+    item.append(meta, content);
+    return item;
+  }));
+}
+
+function renderOverride(override) {
+  const form = document.getElementById("override");
+  form.model.value = override.model;
+  form.system_prompt.value = override.system_prompt;
+  form.options.value = override.options;
+  const info = [
+    override.language && "language " + override.language,
+    override.trigger && "trigger " + override.trigger,
+    override.persona && "persona " + override.persona,
+  ].filter(Boolean);
+  document.getElementById("override-info").textContent = info.join(", ");
+}
+
+async function selectChat(chat, item) {
+  selectedChat = chat;
+  for (const other of document.querySelectorAll("#chats li")) {
+    other.classList.toggle("selected", other === item);
+  }
+  document.getElementById("chat-title").textContent = `${chat.chat_title} (${chat.chat_id})`;
+  document.getElementById("chat").hidden = false;
+
+  try {
+    const [usages, override, messages] = await Promise.all([
+      api(`/api/chats/${chat.chat_id}/usage`),
+      api(`/api/chats/${chat.chat_id}/override`),
+      api(`/api/chats/${chat.chat_id}/messages`),
+    ]);
+    renderUsage(document.getElementById("chat-usage"), usages);
+    renderOverride(override);
+    renderMessages(messages);
+    setStatus("", false);
+  } catch (err) {
+    setStatus(err.message, true);
+  }
+}
+
+async function connect() {
+  try {
+    const [chats, usages] = await Promise.all([api("/api/chats"), api("/api/usage")]);
+    const list = document.getElementById("chats");
+    list.replaceChildren(...chats.map((chat) => {
+      const item = document.createElement("li");
+      item.textContent = chat.chat_title || String(chat.chat_id);
+      item.addEventListener("click", () => selectChat(chat, item));
+      return item;
+    }));
+    renderUsage(document.getElementById("global-usage"), usages);
+    document.getElementById("content").hidden = false;
+    setStatus("", false);
+  } catch (err) {
+    document.getElementById("content").hidden = true;
+    setStatus(err.message, true);
+  }
+}
+
+document.getElementById("connect").addEventListener("click", () => {
+  sessionStorage.setItem(tokenKey, document.getElementById("token").value);
+  connect();
+});
+
+document.getElementById("override").addEventListener("submit", async (event) => {
+  event.preventDefault();
+  const form = event.target;
+  try {
+    const override = await api(`/api/chats/${selectedChat.chat_id}/override`, {
+      method: "PUT",
+      body: JSON.stringify({
+        model: form.model.value.trim(),
+        system_prompt: form.system_prompt.value.trim(),
+        options: form.options.value.trim(),
+      }),
+    });
+    renderOverride(override);
+    setStatus("Override saved.", false);
+  } catch (err) {
+    setStatus(err.message, true);
+  }
+});
+
+if (sessionStorage.getItem(tokenKey)) {
+  connect();
+}
@@ -1,11 +1,17 @@
 package database
 
 import (
+	"cmp"
+	"context"
 	"errors"
 	"fmt"
 	"slices"
+	"sync"
 	"time"
 
+	"github.com/k4yt3x/tellama/internal/redact"
+	"github.com/k4yt3x/tellama/internal/secrets"
+
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -13,13 +19,56 @@ import (
 )
 
 type Manager struct {
-	db *gorm.DB
+	db     *gorm.DB
+	driver Driver
+
+	// writeMu serializes writes since SQLite allows only one writer at a time,
+	// while reads may still run concurrently
+	writeMu sync.Mutex
+
+	// cipher encrypts secrets before they are stored, nil if no encryption key is configured
+	cipher *secrets.Cipher
+
+	// redactor removes personal information from message contents before they are stored, nil if disabled
+	redactor *redact.Redactor
+
+	// overrideCache holds recently read chat overrides, nil if disabled
+	overrideCache *chatOverrideCache
 }
 
+// ErrEncryptionNotConfigured is returned when storing a secret without an encryption key.
+var ErrEncryptionNotConfigured = errors.New("database encryption key is not configured")
+
 type TrustedChat struct {
 	ID        uint   `gorm:"primaryKey;autoIncrement"`
 	ChatID    int64  `gorm:"unique"`
-	ChatTitle string `gorm:"unique"`
+	ChatTitle string `gorm:"unique;size:255"`
+}
+
+// TrustedUser is a user who may use the bot in any chat, including chats that are not trusted.
+type TrustedUser struct {
+	ID       uint  `gorm:"primaryKey;autoIncrement"`
+	UserID   int64 `gorm:"unique"`
+	Username string
+}
+
+// UserPreference holds the choices a user made about how the bot treats their data.
+type UserPreference struct {
+	ID     uint  `gorm:"primaryKey;autoIncrement"`
+	UserID int64 `gorm:"unique"`
+	// OptedOut prevents the messages of the user from being stored.
+	OptedOut bool
+}
+
+// BannedUser is a user whose messages are ignored in a chat until the ban expires.
+type BannedUser struct {
+	ID     uint  `gorm:"primaryKey;autoIncrement"`
+	ChatID int64 `gorm:"uniqueIndex:idx_banned_user"`
+	UserID int64 `gorm:"uniqueIndex:idx_banned_user"`
+	Reason string
+	// Until is when the ban expires, or the zero time if the ban is permanent.
+	Until     time.Time
+	CreatedAt time.Time
 }
 
 type ChatOverride struct {
@@ -31,35 +80,316 @@ type ChatOverride struct {
 	Model        string
 	Options      string
 	SystemPrompt string
+	MaxTurns     int
+
+	// Model routing overrides, empty models use the configured routing models
+	RoutingDisabled   *bool
+	RoutingSmallModel string
+	RoutingLargeModel string
+
+	// Reply format overrides, zero values use the configured thresholds
+	FileReplyMinLength int
+	FileReplyCodeRatio float64
+
+	// Comma-separated chat events to record, "none" to record none, empty to use the configured events
+	ChatEvents string
+
+	// Position of the model name badge in replies, "prefix", "suffix", or empty for no badge
+	ModelBadge string
+
+	// Language code of the command replies, empty for English
+	Language string
+
+	// Trigger mode of group messages, empty to use the configured trigger mode
+	Trigger string
+
+	// Whether the memory notes of the chat are left out of the system prompt
+	MemoryDisabled *bool
+
+	// Whether replies are also sent as voice messages
+	VoiceReply *bool
+
+	// Name of the configured persona used by the chat, empty for none
+	Persona string
+
+	// Format replies are constrained to, "json" or a JSON schema, empty for free text
+	ResponseFormat string
+
+	// Daily token budget, zero to use the configured budget and negative for no budget
+	DailyTokenBudget int64
+
+	// Whether new members are welcomed with a generated message
+	WelcomeMembers *bool
 }
 
+// IsTrue reports whether an optional boolean setting of a chat override is set and true.
+// Boolean settings are nil when the chat inherits the setting of the global override.
+func IsTrue(value *bool) bool {
+	return value != nil && *value
+}
+
+// Content types of stored messages.
+const (
+	ContentTypeText            = "text"
+	ContentTypePhotoCaption    = "photo_caption"
+	ContentTypeVoiceTranscript = "voice_transcript"
+	ContentTypeDocumentExcerpt = "document_excerpt"
+	ContentTypeSystem          = "system"
+	ContentTypeToolResult      = "tool_result"
+	ContentTypeChatEvent       = "chat_event"
+)
+
 type Message struct {
+	ID          uint      `gorm:"primaryKey;autoIncrement"`
+	Timestamp   time.Time `gorm:"autoCreateTime"`
+	ChatID      int64     `gorm:"index"`
+	ChatTitle   string
+	Role        string
+	ContentType string `gorm:"default:text"`
+	UserID      int64
+	Username    string
+	FirstName   string
+	LastName    string
+	Content     string
+	Pinned      bool     `gorm:"index"`
+	Images      [][]byte `gorm:"-" json:"-"`
+
+	// Telegram IDs of the message and the message it replied to, zero if unknown
+	TelegramMessageID int `gorm:"index"`
+	ReplyToMessageID  int
+
+	// ThreadID is the forum topic of the message, zero outside of forum topics
+	ThreadID int `gorm:"index"`
+}
+
+type ToolInvocation struct {
+	ID         uint      `gorm:"primaryKey;autoIncrement"`
+	Timestamp  time.Time `gorm:"autoCreateTime"`
+	ChatID     int64     `gorm:"index"`
+	MessageID  uint      `gorm:"index"`
+	Round      int
+	ToolCallID string
+	ToolName   string
+	Arguments  string
+	Result     string
+}
+
+type ChatSummary struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement"`
+	Timestamp time.Time `gorm:"autoCreateTime"`
+	ChatID    int64     `gorm:"index"`
+	ThreadID  int
+	Content   string
+}
+
+type ChatMemory struct {
 	ID        uint      `gorm:"primaryKey;autoIncrement"`
 	Timestamp time.Time `gorm:"autoCreateTime"`
 	ChatID    int64     `gorm:"index"`
+	UserID    int64
+	Content   string
+}
+
+// ScheduledPrompt is a prompt that is run on a cron schedule with the output posted to the chat.
+// The user who scheduled the prompt is recorded as its sender.
+type ScheduledPrompt struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	ChatID    int64     `gorm:"index"`
 	ChatTitle string
-	Role      string
+	ChatType  string
+	ThreadID  int
 	UserID    int64
 	Username  string
 	FirstName string
 	LastName  string
-	Content   string
+	Cron      string
+	Prompt    string
+	LastRunAt time.Time
+
+	// Name identifies the prompt in commands, empty if the prompt is only identified by its ID
+	Name string
+}
+
+type DocumentChunk struct {
+	ID           uint      `gorm:"primaryKey;autoIncrement"`
+	Timestamp    time.Time `gorm:"autoCreateTime"`
+	ChatID       int64     `gorm:"index"`
+	DocumentName string    `gorm:"index;size:255"`
+	ChunkIndex   int
+	Content      string
+	Embedding    []byte
+}
+
+// MessageEmbedding is the embedding of a stored message, computed when it is first searched for
+// relevance to a question. The model records which embedding model computed it.
+type MessageEmbedding struct {
+	MessageID uint   `gorm:"primaryKey;autoIncrement:false"`
+	ChatID    int64  `gorm:"index"`
+	Model     string `gorm:"size:255"`
+	Embedding []byte
+}
+
+type ChatState struct {
+	ID            uint      `gorm:"primaryKey;autoIncrement"`
+	UpdatedAt     time.Time `gorm:"autoUpdateTime"`
+	ChatID        int64     `gorm:"unique"`
+	ChatTitle     string
+	LastMessageID int
+	LastUpdateID  int
+}
+
+// PendingGeneration is a message that triggered a response that has not been sent yet,
+// so that the response can be generated again if the bot restarts in the meantime.
+type PendingGeneration struct {
+	ID                uint      `gorm:"primaryKey;autoIncrement"`
+	CreatedAt         time.Time `gorm:"autoCreateTime"`
+	ChatID            int64     `gorm:"uniqueIndex:idx_pending_generation"`
+	ChatType          string
+	TelegramMessageID int `gorm:"uniqueIndex:idx_pending_generation"`
+}
+
+type UserAPIKey struct {
+	ID           uint      `gorm:"primaryKey;autoIncrement"`
+	UpdatedAt    time.Time `gorm:"autoUpdateTime"`
+	UserID       int64     `gorm:"unique"`
+	EncryptedKey []byte
+}
+
+type Usage struct {
+	ID               uint   `gorm:"primaryKey;autoIncrement"`
+	Date             string `gorm:"uniqueIndex:idx_usage_key;size:32"`
+	ChatID           int64  `gorm:"uniqueIndex:idx_usage_key"`
+	UserID           int64  `gorm:"uniqueIndex:idx_usage_key"`
+	Model            string `gorm:"uniqueIndex:idx_usage_key;size:255"`
+	PromptTokens     int64
+	CompletionTokens int64
+}
+
+// NewDatabaseManager opens the database and applies the pending migrations.
+func NewDatabaseManager(dbPath string, sqliteConfig SQLiteConfig) (*Manager, error) {
+	dm, err := OpenDatabaseManager(dbPath, sqliteConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = dm.MigrateUp()
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	}
+	return dm, nil
 }
 
-func NewDatabaseManager(dbPath string) (*Manager, error) {
-	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{
+// OpenDatabaseManager opens the database without applying migrations.
+func OpenDatabaseManager(dbPath string, sqliteConfig SQLiteConfig) (*Manager, error) {
+	err := sqliteConfig.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid SQLite config: %w", err)
+	}
+
+	db, err := gorm.Open(sqlite.Open(sqliteConfig.dsn(dbPath)), &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Silent),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
+	err = sqliteConfig.applyPool(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure connection pool: %w", err)
+	}
+
+	return &Manager{db: db, driver: DriverSQLite}, nil
+}
+
+// Driver returns the database engine of the manager.
+func (dm *Manager) Driver() Driver {
+	return dm.driver
+}
+
+// queryLogger reports the duration of every statement and delegates logging to the wrapped logger.
+type queryLogger struct {
+	logger.Interface
+	observe func(time.Duration)
+}
+
+func (l queryLogger) LogMode(level logger.LogLevel) logger.Interface {
+	return queryLogger{Interface: l.Interface.LogMode(level), observe: l.observe}
+}
+
+func (l queryLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	l.observe(time.Since(begin))
+	l.Interface.Trace(ctx, begin, fc, err)
+}
+
+// ObserveQueries calls observe with the duration of every statement run against the database.
+// It must be called before the manager is used.
+func (dm *Manager) ObserveQueries(observe func(time.Duration)) {
+	dm.db.Logger = queryLogger{Interface: dm.db.Logger, observe: observe}
+}
+
+// SetCipher sets the cipher used to encrypt stored secrets.
+func (dm *Manager) SetCipher(cipher *secrets.Cipher) {
+	dm.cipher = cipher
+}
+
+// SetRedactor sets the redactor applied to message contents before they are stored.
+func (dm *Manager) SetRedactor(redactor *redact.Redactor) {
+	dm.redactor = redactor
+}
+
+// redact returns the content with personal information removed if redaction is enabled.
+func (dm *Manager) redact(content string) string {
+	if dm.redactor == nil {
+		return content
+	}
+	return dm.redactor.Redact(content)
+}
+
+// EncryptionEnabled reports whether secrets can be stored.
+func (dm *Manager) EncryptionEnabled() bool {
+	return dm.cipher != nil
+}
 
-	err = db.AutoMigrate(&TrustedChat{}, &ChatOverride{}, &Message{})
+func (dm *Manager) Close() error {
+	sqlDB, err := dm.db.DB()
 	if err != nil {
-		return nil, fmt.Errorf("failed to migrate tables: %w", err)
+		return err
 	}
+	return sqlDB.Close()
+}
+
+// Ping checks that the database can be queried.
+func (dm *Manager) Ping() error {
+	var count int64
+	return dm.db.Model(&TrustedChat{}).Count(&count).Error
+}
 
-	return &Manager{db: db}, nil
+func (dm *Manager) TrustChat(chatID int64, chatTitle string) error {
+	dm.writeMu.Lock()
+	defer dm.writeMu.Unlock()
+
+	return dm.db.Clauses(
+		clause.OnConflict{
+			Columns:   []clause.Column{{Name: "chat_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"chat_title"}),
+		},
+	).Create(&TrustedChat{ChatID: chatID, ChatTitle: chatTitle}).Error
+}
+
+func (dm *Manager) UntrustChat(chatID int64) error {
+	dm.writeMu.Lock()
+	defer dm.writeMu.Unlock()
+
+	return dm.db.Where("chat_id = ?", chatID).Delete(&TrustedChat{}).Error
+}
+
+func (dm *Manager) ListTrustedChats() ([]TrustedChat, error) {
+	var trustedChats []TrustedChat
+	result := dm.db.Order("id").Find(&trustedChats)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return trustedChats, nil
 }
 
 func (dm *Manager) IsChatTrusted(chatID int64) bool {
@@ -68,18 +398,146 @@ func (dm *Manager) IsChatTrusted(chatID int64) bool {
 	return !errors.Is(result.Error, gorm.ErrRecordNotFound)
 }
 
+// TrustUser allows the user to use the bot in any chat.
+func (dm *Manager) TrustUser(userID int64, username string) error {
+	dm.writeMu.Lock()
+	defer dm.writeMu.Unlock()
+
+	return dm.db.Clauses(
+		clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"username"}),
+		},
+	).Create(&TrustedUser{UserID: userID, Username: username}).Error
+}
+
+func (dm *Manager) UntrustUser(userID int64) error {
+	dm.writeMu.Lock()
+	defer dm.writeMu.Unlock()
+
+	return dm.db.Where("user_id = ?", userID).Delete(&TrustedUser{}).Error
+}
+
+func (dm *Manager) ListTrustedUsers() ([]TrustedUser, error) {
+	var trustedUsers []TrustedUser
+	result := dm.db.Order("id").Find(&trustedUsers)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return trustedUsers, nil
+}
+
+func (dm *Manager) IsUserTrusted(userID int64) bool {
+	var trustedUser TrustedUser
+	result := dm.db.Where("user_id = ?", userID).First(&trustedUser)
+	return !errors.Is(result.Error, gorm.ErrRecordNotFound)
+}
+
+// SetUserOptedOut sets whether the messages of the user are kept out of the history of every chat.
+func (dm *Manager) SetUserOptedOut(userID int64, optedOut bool) error {
+	dm.writeMu.Lock()
+	defer dm.writeMu.Unlock()
+
+	return dm.db.Clauses(
+		clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"opted_out"}),
+		},
+	).Create(&UserPreference{UserID: userID, OptedOut: optedOut}).Error
+}
+
+// IsUserOptedOut reports whether the messages of the user must not be stored.
+func (dm *Manager) IsUserOptedOut(userID int64) (bool, error) {
+	var userPreference UserPreference
+	result := dm.db.Where("user_id = ?", userID).First(&userPreference)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return userPreference.OptedOut, nil
+}
+
+// BanUser ignores the messages of the user in the chat until the given time, or permanently if until is zero.
+// Banning a user who is already banned replaces the previous ban.
+func (dm *Manager) BanUser(chatID int64, userID int64, reason string, until time.Time) error {
+	dm.writeMu.Lock()
+	defer dm.writeMu.Unlock()
+
+	return dm.db.Clauses(
+		clause.OnConflict{
+			Columns:   []clause.Column{{Name: "chat_id"}, {Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"reason", "until", "created_at"}),
+		},
+	).Create(&BannedUser{ChatID: chatID, UserID: userID, Reason: reason, Until: until.UTC()}).Error
+}
+
+func (dm *Manager) UnbanUser(chatID int64, userID int64) error {
+	dm.writeMu.Lock()
+	defer dm.writeMu.Unlock()
+
+	return dm.db.Where("chat_id = ? AND user_id = ?", chatID, userID).Delete(&BannedUser{}).Error
+}
+
+// GetUserBan returns the ban of the user in the chat, or gorm.ErrRecordNotFound if the user is not banned.
+// Expired bans are ignored.
+func (dm *Manager) GetUserBan(chatID int64, userID int64) (BannedUser, error) {
+	var bannedUser BannedUser
+	result := dm.db.
+		Where("chat_id = ? AND user_id = ?", chatID, userID).
+		Where("until = ? OR until > ?", time.Time{}, time.Now().UTC()).
+		First(&bannedUser)
+	return bannedUser, result.Error
+}
+
+// ListBannedUsers returns the users banned in the chat, excluding expired bans.
+func (dm *Manager) ListBannedUsers(chatID int64) ([]BannedUser, error) {
+	var bannedUsers []BannedUser
+	result := dm.db.
+		Where("chat_id = ?", chatID).
+		Where("until = ? OR until > ?", time.Time{}, time.Now().UTC()).
+		Order("id").
+		Find(&bannedUsers)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return bannedUsers, nil
+}
+
 func (dm *Manager) GetGlobalChatOverride() (ChatOverride, error) {
 	var chatOverride ChatOverride
-	result := dm.db.Where("chat_id IS NULL").First(&chatOverride)
+	result := dm.db.Where(clause.Eq{Column: clause.Column{Name: "chat_id"}, Value: nil}).First(&chatOverride)
 	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 		return ChatOverride{}, nil
 	} else if result.Error != nil {
 		return ChatOverride{}, result.Error
 	}
+
+	var err error
+	chatOverride.APIKey, err = dm.decryptValue(chatOverride.APIKey)
+	if err != nil {
+		return ChatOverride{}, err
+	}
 	return chatOverride, nil
 }
 
+// GetChatOverride returns the override of a chat merged into the global override.
 func (dm *Manager) GetChatOverride(chatID int64) (ChatOverride, error) {
+	cached, generation, ok := dm.overrideCache.get(chatID)
+	if ok {
+		return cached, nil
+	}
+
+	chatOverride, err := dm.getChatOverride(chatID)
+	if err != nil {
+		return ChatOverride{}, err
+	}
+	dm.overrideCache.put(chatID, chatOverride, generation)
+	return chatOverride, nil
+}
+
+func (dm *Manager) getChatOverride(chatID int64) (ChatOverride, error) {
 	// Get the default chat override
 	globalChatOverride, err := dm.GetGlobalChatOverride()
 	if err != nil {
@@ -94,8 +552,12 @@ func (dm *Manager) GetChatOverride(chatID int64) (ChatOverride, error) {
 	} else if result.Error != nil {
 		return ChatOverride{}, result.Error
 	}
+	chatOverride.APIKey, err = dm.decryptValue(chatOverride.APIKey)
+	if err != nil {
+		return ChatOverride{}, err
+	}
 
-	// Merge non-empty fields from chatOverride into globalChatOverride
+	// Merge non-empty fields and set boolean fields from chatOverride into globalChatOverride
 	globalChatOverride.ChatID = chatOverride.ChatID
 	if chatOverride.ChatTitle != "" {
 		globalChatOverride.ChatTitle = chatOverride.ChatTitle
@@ -115,6 +577,54 @@ func (dm *Manager) GetChatOverride(chatID int64) (ChatOverride, error) {
 	if chatOverride.SystemPrompt != "" {
 		globalChatOverride.SystemPrompt = chatOverride.SystemPrompt
 	}
+	if chatOverride.MaxTurns != 0 {
+		globalChatOverride.MaxTurns = chatOverride.MaxTurns
+	}
+	if chatOverride.RoutingDisabled != nil {
+		globalChatOverride.RoutingDisabled = chatOverride.RoutingDisabled
+	}
+	if chatOverride.RoutingSmallModel != "" {
+		globalChatOverride.RoutingSmallModel = chatOverride.RoutingSmallModel
+	}
+	if chatOverride.RoutingLargeModel != "" {
+		globalChatOverride.RoutingLargeModel = chatOverride.RoutingLargeModel
+	}
+	if chatOverride.FileReplyMinLength != 0 {
+		globalChatOverride.FileReplyMinLength = chatOverride.FileReplyMinLength
+	}
+	if chatOverride.FileReplyCodeRatio != 0 {
+		globalChatOverride.FileReplyCodeRatio = chatOverride.FileReplyCodeRatio
+	}
+	if chatOverride.ChatEvents != "" {
+		globalChatOverride.ChatEvents = chatOverride.ChatEvents
+	}
+	if chatOverride.ModelBadge != "" {
+		globalChatOverride.ModelBadge = chatOverride.ModelBadge
+	}
+	if chatOverride.Language != "" {
+		globalChatOverride.Language = chatOverride.Language
+	}
+	if chatOverride.Trigger != "" {
+		globalChatOverride.Trigger = chatOverride.Trigger
+	}
+	if chatOverride.MemoryDisabled != nil {
+		globalChatOverride.MemoryDisabled = chatOverride.MemoryDisabled
+	}
+	if chatOverride.VoiceReply != nil {
+		globalChatOverride.VoiceReply = chatOverride.VoiceReply
+	}
+	if chatOverride.Persona != "" {
+		globalChatOverride.Persona = chatOverride.Persona
+	}
+	if chatOverride.ResponseFormat != "" {
+		globalChatOverride.ResponseFormat = chatOverride.ResponseFormat
+	}
+	if chatOverride.DailyTokenBudget != 0 {
+		globalChatOverride.DailyTokenBudget = chatOverride.DailyTokenBudget
+	}
+	if chatOverride.WelcomeMembers != nil {
+		globalChatOverride.WelcomeMembers = chatOverride.WelcomeMembers
+	}
 
 	return globalChatOverride, nil
 }
@@ -128,6 +638,9 @@ func (dm *Manager) SetChatOverride(
 	options string,
 	systemPrompt string,
 ) error {
+	dm.writeMu.Lock()
+	defer dm.writeMu.Unlock()
+
 	chatOverride := ChatOverride{
 		ChatID: chatID,
 	}
@@ -144,8 +657,12 @@ func (dm *Manager) SetChatOverride(
 		updates["base_url"] = baseURL
 	}
 	if apiKey != "" {
-		chatOverride.APIKey = apiKey
-		updates["api_key"] = apiKey
+		encryptedAPIKey, err := dm.encryptValue(apiKey)
+		if err != nil {
+			return err
+		}
+		chatOverride.APIKey = encryptedAPIKey
+		updates["api_key"] = encryptedAPIKey
 	}
 	if model != "" {
 		chatOverride.Model = model
@@ -169,61 +686,1238 @@ func (dm *Manager) SetChatOverride(
 	).Create(&chatOverride).Error
 }
 
-func (dm *Manager) DeleteChatOverride(chatID int64) error {
-	return dm.db.Where("chat_id = ?", chatID).Delete(&ChatOverride{}).Error
+func (dm *Manager) SetChatModel(chatID int64, chatTitle string, model string) error {
+	dm.writeMu.Lock()
+	defer dm.writeMu.Unlock()
+
+	return dm.db.Clauses(
+		clause.OnConflict{
+			Columns: []clause.Column{{Name: "chat_id"}},
+			DoUpdates: clause.Assignments(map[string]any{
+				"chat_title": chatTitle,
+				"model":      model,
+			}),
+		},
+	).Create(&ChatOverride{
+		ChatID:    chatID,
+		ChatTitle: chatTitle,
+		Model:     model,
+	}).Error
 }
 
-func (dm *Manager) StoreMessage(
+func (dm *Manager) SetChatMaxTurns(chatID int64, chatTitle string, maxTurns int) error {
+	dm.writeMu.Lock()
+	defer dm.writeMu.Unlock()
+
+	return dm.db.Clauses(
+		clause.OnConflict{
+			Columns: []clause.Column{{Name: "chat_id"}},
+			DoUpdates: clause.Assignments(map[string]any{
+				"chat_title": chatTitle,
+				"max_turns":  maxTurns,
+			}),
+		},
+	).Create(&ChatOverride{
+		ChatID:    chatID,
+		ChatTitle: chatTitle,
+		MaxTurns:  maxTurns,
+	}).Error
+}
+
+// SetChatRouting sets the model routing overrides of a chat.
+// Empty model names use the configured routing models.
+func (dm *Manager) SetChatRouting(
 	chatID int64,
 	chatTitle string,
-	role string,
-	userID int64,
-	username string,
-	firstName string,
-	lastName string,
-	messageText string,
+	disabled bool,
+	smallModel string,
+	largeModel string,
 ) error {
-	return dm.db.Create(&Message{
-		ChatID:    chatID,
-		ChatTitle: chatTitle,
-		Role:      role,
-		UserID:    userID,
-		Username:  username,
-		FirstName: firstName,
-		LastName:  lastName,
-		Content:   messageText,
+	dm.writeMu.Lock()
+	defer dm.writeMu.Unlock()
+
+	return dm.db.Clauses(
+		clause.OnConflict{
+			Columns: []clause.Column{{Name: "chat_id"}},
+			DoUpdates: clause.Assignments(map[string]any{
+				"chat_title":          chatTitle,
+				"routing_disabled":    disabled,
+				"routing_small_model": smallModel,
+				"routing_large_model": largeModel,
+			}),
+		},
+	).Create(&ChatOverride{
+		ChatID:            chatID,
+		ChatTitle:         chatTitle,
+		RoutingDisabled:   &disabled,
+		RoutingSmallModel: smallModel,
+		RoutingLargeModel: largeModel,
 	}).Error
 }
 
-func (dm *Manager) GetMessages(chatID int64, limit int) ([]Message, error) {
-	var messages []Message
-	result := dm.db.Where("chat_id = ?", chatID).
-		Order("id DESC").
-		Limit(limit).
-		Find(&messages)
-	if result.Error != nil {
-		return nil, result.Error
-	}
+// SetChatReplyFormat sets the thresholds for sending the replies of a chat as files.
+// Zero values use the configured thresholds.
+func (dm *Manager) SetChatReplyFormat(chatID int64, chatTitle string, minLength int, codeRatio float64) error {
+	dm.writeMu.Lock()
+	defer dm.writeMu.Unlock()
 
-	history := make([]Message, len(messages))
-	for i, m := range messages {
-		history[i] = Message{
-			Timestamp: m.Timestamp,
-			ChatID:    m.ChatID,
-			ChatTitle: m.ChatTitle,
-			Role:      m.Role,
-			UserID:    m.UserID,
-			Username:  m.Username,
-			FirstName: m.FirstName,
-			LastName:  m.LastName,
-			Content:   m.Content,
-		}
-	}
+	return dm.db.Clauses(
+		clause.OnConflict{
+			Columns: []clause.Column{{Name: "chat_id"}},
+			DoUpdates: clause.Assignments(map[string]any{
+				"chat_title":            chatTitle,
+				"file_reply_min_length": minLength,
+				"file_reply_code_ratio": codeRatio,
+			}),
+		},
+	).Create(&ChatOverride{
+		ChatID:             chatID,
+		ChatTitle:          chatTitle,
+		FileReplyMinLength: minLength,
+		FileReplyCodeRatio: codeRatio,
+	}).Error
+}
 
-	slices.Reverse(history)
-	return history, nil
+// SetChatEvents sets the chat events recorded in a chat.
+func (dm *Manager) SetChatEvents(chatID int64, chatTitle string, events string) error {
+	dm.writeMu.Lock()
+	defer dm.writeMu.Unlock()
+
+	return dm.db.Clauses(
+		clause.OnConflict{
+			Columns: []clause.Column{{Name: "chat_id"}},
+			DoUpdates: clause.Assignments(map[string]any{
+				"chat_title":  chatTitle,
+				"chat_events": events,
+			}),
+		},
+	).Create(&ChatOverride{
+		ChatID:     chatID,
+		ChatTitle:  chatTitle,
+		ChatEvents: events,
+	}).Error
 }
 
-func (dm *Manager) ClearMessages(chatID int64) error {
-	return dm.db.Where("chat_id = ?", chatID).Delete(&Message{}).Error
+// SetChatModelBadge sets the position of the model name badge in the replies of a chat.
+func (dm *Manager) SetChatModelBadge(chatID int64, chatTitle string, modelBadge string) error {
+	dm.writeMu.Lock()
+	defer dm.writeMu.Unlock()
+
+	return dm.db.Clauses(
+		clause.OnConflict{
+			Columns: []clause.Column{{Name: "chat_id"}},
+			DoUpdates: clause.Assignments(map[string]any{
+				"chat_title":  chatTitle,
+				"model_badge": modelBadge,
+			}),
+		},
+	).Create(&ChatOverride{
+		ChatID:     chatID,
+		ChatTitle:  chatTitle,
+		ModelBadge: modelBadge,
+	}).Error
+}
+
+// SetChatLanguage sets the language of the command replies in a chat.
+func (dm *Manager) SetChatLanguage(chatID int64, chatTitle string, language string) error {
+	dm.writeMu.Lock()
+	defer dm.writeMu.Unlock()
+
+	return dm.db.Clauses(
+		clause.OnConflict{
+			Columns: []clause.Column{{Name: "chat_id"}},
+			DoUpdates: clause.Assignments(map[string]any{
+				"chat_title": chatTitle,
+				"language":   language,
+			}),
+		},
+	).Create(&ChatOverride{
+		ChatID:    chatID,
+		ChatTitle: chatTitle,
+		Language:  language,
+	}).Error
+}
+
+// SetChatOptions sets the options passed to the provider for a chat.
+// Unlike SetChatOverride, empty options clear the existing options.
+func (dm *Manager) SetChatOptions(chatID int64, chatTitle string, options string) error {
+	dm.writeMu.Lock()
+	defer dm.writeMu.Unlock()
+
+	return dm.db.Clauses(
+		clause.OnConflict{
+			Columns: []clause.Column{{Name: "chat_id"}},
+			DoUpdates: clause.Assignments(map[string]any{
+				"chat_title": chatTitle,
+				"options":    options,
+			}),
+		},
+	).Create(&ChatOverride{
+		ChatID:    chatID,
+		ChatTitle: chatTitle,
+		Options:   options,
+	}).Error
+}
+
+// SetChatTrigger sets the trigger mode of group messages in a chat.
+// An empty trigger mode uses the configured trigger mode.
+func (dm *Manager) SetChatTrigger(chatID int64, chatTitle string, trigger string) error {
+	dm.writeMu.Lock()
+	defer dm.writeMu.Unlock()
+
+	return dm.db.Clauses(
+		clause.OnConflict{
+			Columns: []clause.Column{{Name: "chat_id"}},
+			DoUpdates: clause.Assignments(map[string]any{
+				"chat_title": chatTitle,
+				"trigger":    trigger,
+			}),
+		},
+	).Create(&ChatOverride{
+		ChatID:    chatID,
+		ChatTitle: chatTitle,
+		Trigger:   trigger,
+	}).Error
+}
+
+// SetChatMemoryDisabled sets whether the memory notes of a chat are left out of the system prompt.
+func (dm *Manager) SetChatMemoryDisabled(chatID int64, chatTitle string, disabled bool) error {
+	dm.writeMu.Lock()
+	defer dm.writeMu.Unlock()
+
+	return dm.db.Clauses(
+		clause.OnConflict{
+			Columns: []clause.Column{{Name: "chat_id"}},
+			DoUpdates: clause.Assignments(map[string]any{
+				"chat_title":      chatTitle,
+				"memory_disabled": disabled,
+			}),
+		},
+	).Create(&ChatOverride{
+		ChatID:         chatID,
+		ChatTitle:      chatTitle,
+		MemoryDisabled: &disabled,
+	}).Error
+}
+
+// SetChatVoiceReply sets whether the replies in a chat are also sent as voice messages.
+func (dm *Manager) SetChatVoiceReply(chatID int64, chatTitle string, enabled bool) error {
+	dm.writeMu.Lock()
+	defer dm.writeMu.Unlock()
+
+	return dm.db.Clauses(
+		clause.OnConflict{
+			Columns: []clause.Column{{Name: "chat_id"}},
+			DoUpdates: clause.Assignments(map[string]any{
+				"chat_title":  chatTitle,
+				"voice_reply": enabled,
+			}),
+		},
+	).Create(&ChatOverride{
+		ChatID:     chatID,
+		ChatTitle:  chatTitle,
+		VoiceReply: &enabled,
+	}).Error
+}
+
+// SetChatPersona sets the configured persona used by a chat, or clears it if empty.
+func (dm *Manager) SetChatPersona(chatID int64, chatTitle string, persona string) error {
+	dm.writeMu.Lock()
+	defer dm.writeMu.Unlock()
+
+	return dm.db.Clauses(
+		clause.OnConflict{
+			Columns: []clause.Column{{Name: "chat_id"}},
+			DoUpdates: clause.Assignments(map[string]any{
+				"chat_title": chatTitle,
+				"persona":    persona,
+			}),
+		},
+	).Create(&ChatOverride{
+		ChatID:    chatID,
+		ChatTitle: chatTitle,
+		Persona:   persona,
+	}).Error
+}
+
+// SetChatResponseFormat sets the format replies in a chat are constrained to, or clears it if empty.
+func (dm *Manager) SetChatResponseFormat(chatID int64, chatTitle string, responseFormat string) error {
+	dm.writeMu.Lock()
+	defer dm.writeMu.Unlock()
+
+	return dm.db.Clauses(
+		clause.OnConflict{
+			Columns: []clause.Column{{Name: "chat_id"}},
+			DoUpdates: clause.Assignments(map[string]any{
+				"chat_title":      chatTitle,
+				"response_format": responseFormat,
+			}),
+		},
+	).Create(&ChatOverride{
+		ChatID:         chatID,
+		ChatTitle:      chatTitle,
+		ResponseFormat: responseFormat,
+	}).Error
+}
+
+func (dm *Manager) SetChatDailyTokenBudget(chatID int64, chatTitle string, budget int64) error {
+	dm.writeMu.Lock()
+	defer dm.writeMu.Unlock()
+
+	return dm.db.Clauses(
+		clause.OnConflict{
+			Columns: []clause.Column{{Name: "chat_id"}},
+			DoUpdates: clause.Assignments(map[string]any{
+				"chat_title":         chatTitle,
+				"daily_token_budget": budget,
+			}),
+		},
+	).Create(&ChatOverride{
+		ChatID:           chatID,
+		ChatTitle:        chatTitle,
+		DailyTokenBudget: budget,
+	}).Error
+}
+
+// SetChatWelcomeMembers sets whether new members of a chat are welcomed with a generated message.
+func (dm *Manager) SetChatWelcomeMembers(chatID int64, chatTitle string, enabled bool) error {
+	dm.writeMu.Lock()
+	defer dm.writeMu.Unlock()
+
+	return dm.db.Clauses(
+		clause.OnConflict{
+			Columns: []clause.Column{{Name: "chat_id"}},
+			DoUpdates: clause.Assignments(map[string]any{
+				"chat_title":      chatTitle,
+				"welcome_members": enabled,
+			}),
+		},
+	).Create(&ChatOverride{
+		ChatID:         chatID,
+		ChatTitle:      chatTitle,
+		WelcomeMembers: &enabled,
+	}).Error
+}
+
+// SetChatPreset replaces the prompt, model, and behavior settings of a chat with those of a preset.
+// Unlike the other setters, empty fields clear the existing value.
+// The base URL, API key, and daily token budget are kept.
+func (dm *Manager) SetChatPreset(chatOverride ChatOverride) error {
+	dm.writeMu.Lock()
+	defer dm.writeMu.Unlock()
+
+	return dm.db.Clauses(
+		clause.OnConflict{
+			Columns: []clause.Column{{Name: "chat_id"}},
+			DoUpdates: clause.Assignments(map[string]any{
+				"chat_title":            chatOverride.ChatTitle,
+				"system_prompt":         chatOverride.SystemPrompt,
+				"model":                 chatOverride.Model,
+				"options":               chatOverride.Options,
+				"max_turns":             chatOverride.MaxTurns,
+				"routing_disabled":      chatOverride.RoutingDisabled,
+				"routing_small_model":   chatOverride.RoutingSmallModel,
+				"routing_large_model":   chatOverride.RoutingLargeModel,
+				"file_reply_min_length": chatOverride.FileReplyMinLength,
+				"file_reply_code_ratio": chatOverride.FileReplyCodeRatio,
+				"chat_events":           chatOverride.ChatEvents,
+				"model_badge":           chatOverride.ModelBadge,
+				"language":              chatOverride.Language,
+				"trigger":               chatOverride.Trigger,
+				"memory_disabled":       chatOverride.MemoryDisabled,
+				"voice_reply":           chatOverride.VoiceReply,
+				"persona":               chatOverride.Persona,
+				"response_format":       chatOverride.ResponseFormat,
+				"welcome_members":       chatOverride.WelcomeMembers,
+			}),
+		},
+	).Create(&ChatOverride{
+		ChatID:             chatOverride.ChatID,
+		ChatTitle:          chatOverride.ChatTitle,
+		SystemPrompt:       chatOverride.SystemPrompt,
+		Model:              chatOverride.Model,
+		Options:            chatOverride.Options,
+		MaxTurns:           chatOverride.MaxTurns,
+		RoutingDisabled:    chatOverride.RoutingDisabled,
+		RoutingSmallModel:  chatOverride.RoutingSmallModel,
+		RoutingLargeModel:  chatOverride.RoutingLargeModel,
+		FileReplyMinLength: chatOverride.FileReplyMinLength,
+		FileReplyCodeRatio: chatOverride.FileReplyCodeRatio,
+		ChatEvents:         chatOverride.ChatEvents,
+		ModelBadge:         chatOverride.ModelBadge,
+		Language:           chatOverride.Language,
+		Trigger:            chatOverride.Trigger,
+		MemoryDisabled:     chatOverride.MemoryDisabled,
+		VoiceReply:         chatOverride.VoiceReply,
+		Persona:            chatOverride.Persona,
+		ResponseFormat:     chatOverride.ResponseFormat,
+		WelcomeMembers:     chatOverride.WelcomeMembers,
+	}).Error
+}
+
+// SetGlobalChatOverride replaces the global override that the overrides of all chats are merged into.
+// Like SetChatPreset, empty fields clear the existing value. The chat ID and title are ignored.
+func (dm *Manager) SetGlobalChatOverride(chatOverride ChatOverride) error {
+	dm.writeMu.Lock()
+	defer dm.writeMu.Unlock()
+
+	apiKey := chatOverride.APIKey
+	if apiKey != "" {
+		var err error
+		apiKey, err = dm.encryptValue(apiKey)
+		if err != nil {
+			return err
+		}
+	}
+
+	values := map[string]any{
+		"base_url":              chatOverride.BaseURL,
+		"api_key":               apiKey,
+		"model":                 chatOverride.Model,
+		"options":               chatOverride.Options,
+		"system_prompt":         chatOverride.SystemPrompt,
+		"max_turns":             chatOverride.MaxTurns,
+		"routing_disabled":      chatOverride.RoutingDisabled,
+		"routing_small_model":   chatOverride.RoutingSmallModel,
+		"routing_large_model":   chatOverride.RoutingLargeModel,
+		"file_reply_min_length": chatOverride.FileReplyMinLength,
+		"file_reply_code_ratio": chatOverride.FileReplyCodeRatio,
+		"chat_events":           chatOverride.ChatEvents,
+		"model_badge":           chatOverride.ModelBadge,
+		"language":              chatOverride.Language,
+		"trigger":               chatOverride.Trigger,
+		"memory_disabled":       chatOverride.MemoryDisabled,
+		"voice_reply":           chatOverride.VoiceReply,
+		"persona":               chatOverride.Persona,
+		"response_format":       chatOverride.ResponseFormat,
+		"daily_token_budget":    chatOverride.DailyTokenBudget,
+		"welcome_members":       chatOverride.WelcomeMembers,
+	}
+
+	// The global override has no chat ID, so it cannot be upserted on the unique chat ID column
+	globalOverride := clause.Eq{Column: clause.Column{Name: "chat_id"}, Value: nil}
+	var count int64
+	if err := dm.db.Model(&ChatOverride{}).Where(globalOverride).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return dm.db.Model(&ChatOverride{}).Where(globalOverride).Updates(values).Error
+	}
+
+	values["chat_id"] = nil
+	return dm.db.Model(&ChatOverride{}).Create(values).Error
+}
+
+func (dm *Manager) DeleteChatOverride(chatID int64) error {
+	dm.writeMu.Lock()
+	defer dm.writeMu.Unlock()
+
+	return dm.db.Where("chat_id = ?", chatID).Delete(&ChatOverride{}).Error
+}
+
+func (dm *Manager) StoreMessage(
+	chatID int64,
+	chatTitle string,
+	role string,
+	contentType string,
+	userID int64,
+	username string,
+	firstName string,
+	lastName string,
+	messageText string,
+	telegramMessageID int,
+	replyToMessageID int,
+	threadID int,
+) (uint, error) {
+	dm.writeMu.Lock()
+	defer dm.writeMu.Unlock()
+
+	message := Message{
+		ChatID:      chatID,
+		ChatTitle:   chatTitle,
+		Role:        role,
+		ContentType: contentType,
+		UserID:      userID,
+		Username:    username,
+		FirstName:   firstName,
+		LastName:    lastName,
+		Content:     dm.redact(messageText),
+
+		TelegramMessageID: telegramMessageID,
+		ReplyToMessageID:  replyToMessageID,
+		ThreadID:          threadID,
+	}
+	if err := dm.db.Create(&message).Error; err != nil {
+		return 0, err
+	}
+	return message.ID, nil
+}
+
+// StoreMessages stores messages in order, keeping their timestamps if set.
+func (dm *Manager) StoreMessages(messages []Message) error {
+	dm.writeMu.Lock()
+	defer dm.writeMu.Unlock()
+
+	if len(messages) == 0 {
+		return nil
+	}
+	for i := range messages {
+		messages[i].Content = dm.redact(messages[i].Content)
+	}
+	return dm.db.CreateInBatches(messages, importBatchSize).Error
+}
+
+// GetMessages returns up to limit of the latest messages of a chat thread, oldest first.
+func (dm *Manager) GetMessages(chatID int64, threadID int, limit int) ([]Message, error) {
+	var messages []Message
+	result := dm.db.Where("chat_id = ? AND thread_id = ?", chatID, threadID).
+		Order("id DESC").
+		Limit(limit).
+		Find(&messages)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	history := make([]Message, len(messages))
+	for i, m := range messages {
+		history[i] = Message{
+			ID:          m.ID,
+			Timestamp:   m.Timestamp,
+			ChatID:      m.ChatID,
+			ChatTitle:   m.ChatTitle,
+			Role:        m.Role,
+			ContentType: m.ContentType,
+			UserID:      m.UserID,
+			Username:    m.Username,
+			FirstName:   m.FirstName,
+			LastName:    m.LastName,
+			Content:     m.Content,
+			Pinned:      m.Pinned,
+
+			TelegramMessageID: m.TelegramMessageID,
+			ReplyToMessageID:  m.ReplyToMessageID,
+			ThreadID:          m.ThreadID,
+		}
+	}
+
+	slices.Reverse(history)
+	return history, nil
+}
+
+func (dm *Manager) GetMessage(messageID uint) (Message, error) {
+	var message Message
+	result := dm.db.First(&message, messageID)
+	return message, result.Error
+}
+
+// GetMessagesUntil returns up to limit messages of a chat thread ending with the given message, oldest first.
+func (dm *Manager) GetMessagesUntil(chatID int64, threadID int, messageID uint, limit int) ([]Message, error) {
+	var messages []Message
+	result := dm.db.Where("chat_id = ? AND thread_id = ? AND id <= ?", chatID, threadID, messageID).
+		Order("id DESC").
+		Limit(limit).
+		Find(&messages)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	slices.Reverse(messages)
+	return messages, nil
+}
+
+// GetLastUserMessage returns the latest message a user sent in a chat thread.
+func (dm *Manager) GetLastUserMessage(chatID int64, threadID int, userID int64) (Message, error) {
+	var message Message
+	result := dm.db.Where("chat_id = ? AND thread_id = ? AND user_id = ? AND role = ?", chatID, threadID, userID, "user").
+		Order("id DESC").
+		First(&message)
+	return message, result.Error
+}
+
+// GetMessagesAfter returns all messages of a chat thread stored after the given message, oldest first.
+func (dm *Manager) GetMessagesAfter(chatID int64, threadID int, messageID uint) ([]Message, error) {
+	var messages []Message
+	result := dm.db.Where("chat_id = ? AND thread_id = ? AND id > ?", chatID, threadID, messageID).
+		Order("id ASC").
+		Find(&messages)
+	return messages, result.Error
+}
+
+// GetMessagesSince returns all messages of a chat thread stored at or after the given time, oldest first.
+func (dm *Manager) GetMessagesSince(chatID int64, threadID int, since time.Time) ([]Message, error) {
+	var messages []Message
+	result := dm.db.Where("chat_id = ? AND thread_id = ? AND timestamp >= ?", chatID, threadID, since).
+		Order("id ASC").
+		Find(&messages)
+	return messages, result.Error
+}
+
+// FindMessage returns the latest message in the chat sent by the user with the given content.
+func (dm *Manager) FindMessage(chatID int64, userID int64, content string) (Message, error) {
+	var message Message
+	result := dm.db.Where("chat_id = ? AND user_id = ? AND content = ?", chatID, userID, dm.redact(content)).
+		Order("id DESC").
+		First(&message)
+	return message, result.Error
+}
+
+// GetMessageByTelegramID returns the latest stored message with the given Telegram message ID in a chat.
+func (dm *Manager) GetMessageByTelegramID(chatID int64, telegramMessageID int) (Message, error) {
+	var message Message
+	result := dm.db.Where("chat_id = ? AND telegram_message_id = ?", chatID, telegramMessageID).
+		Order("id DESC").
+		First(&message)
+	return message, result.Error
+}
+
+// HasMessage reports whether a message with the given Telegram message ID and sender is stored in a chat.
+func (dm *Manager) HasMessage(chatID int64, telegramMessageID int, userID int64) (bool, error) {
+	var count int64
+	result := dm.db.Model(&Message{}).
+		Where("chat_id = ? AND telegram_message_id = ? AND user_id = ?", chatID, telegramMessageID, userID).
+		Count(&count)
+	return count > 0, result.Error
+}
+
+// GetReplyTo returns the latest stored assistant message that replied to the given Telegram message in a chat.
+func (dm *Manager) GetReplyTo(chatID int64, telegramMessageID int) (Message, error) {
+	var message Message
+	result := dm.db.Where(
+		"chat_id = ? AND role = ? AND reply_to_message_id = ?",
+		chatID,
+		"assistant",
+		telegramMessageID,
+	).
+		Order("id DESC").
+		First(&message)
+	return message, result.Error
+}
+
+// UpdateMessageContent replaces the content of a stored message.
+func (dm *Manager) UpdateMessageContent(messageID uint, content string) error {
+	dm.writeMu.Lock()
+	defer dm.writeMu.Unlock()
+
+	if err := dm.db.Where("message_id = ?", messageID).Delete(&MessageEmbedding{}).Error; err != nil {
+		return err
+	}
+	return dm.db.Model(&Message{}).Where("id = ?", messageID).Update("content", dm.redact(content)).Error
+}
+
+func (dm *Manager) SetMessagePinned(messageID uint, pinned bool) error {
+	dm.writeMu.Lock()
+	defer dm.writeMu.Unlock()
+
+	return dm.db.Model(&Message{}).Where("id = ?", messageID).Update("pinned", pinned).Error
+}
+
+func (dm *Manager) GetPinnedMessages(chatID int64) ([]Message, error) {
+	var messages []Message
+	result := dm.db.Where("chat_id = ? AND pinned = ?", chatID, true).Order("id").Find(&messages)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return messages, nil
+}
+
+func (dm *Manager) CountMessages(chatID int64, threadID int, role string) (int64, error) {
+	var count int64
+	result := dm.db.Model(&Message{}).
+		Where("chat_id = ? AND thread_id = ? AND role = ?", chatID, threadID, role).
+		Count(&count)
+	return count, result.Error
+}
+
+// ClearMessages deletes the messages of a chat except for pinned ones.
+func (dm *Manager) ClearMessages(chatID int64) error {
+	dm.writeMu.Lock()
+	defer dm.writeMu.Unlock()
+
+	pinned := dm.db.Model(&Message{}).Select("id").Where("chat_id = ? AND pinned = ?", chatID, true)
+	err := dm.db.Where("chat_id = ? AND message_id NOT IN (?)", chatID, pinned).
+		Delete(&ToolInvocation{}).Error
+	if err != nil {
+		return err
+	}
+	err = dm.db.Where("chat_id = ? AND message_id NOT IN (?)", chatID, pinned).
+		Delete(&MessageEmbedding{}).Error
+	if err != nil {
+		return err
+	}
+	return dm.db.Where("chat_id = ? AND pinned = ?", chatID, false).Delete(&Message{}).Error
+}
+
+// ClearThreadMessages deletes the messages of a chat thread except for pinned ones.
+func (dm *Manager) ClearThreadMessages(chatID int64, threadID int) error {
+	dm.writeMu.Lock()
+	defer dm.writeMu.Unlock()
+
+	cleared := dm.db.Model(&Message{}).
+		Select("id").
+		Where("chat_id = ? AND thread_id = ? AND pinned = ?", chatID, threadID, false)
+	err := dm.db.Where("chat_id = ? AND message_id IN (?)", chatID, cleared).
+		Delete(&ToolInvocation{}).Error
+	if err != nil {
+		return err
+	}
+	err = dm.db.Where("chat_id = ? AND message_id IN (?)", chatID, cleared).
+		Delete(&MessageEmbedding{}).Error
+	if err != nil {
+		return err
+	}
+	return dm.db.Where("chat_id = ? AND thread_id = ? AND pinned = ?", chatID, threadID, false).
+		Delete(&Message{}).Error
+}
+
+func (dm *Manager) StoreToolInvocations(
+	chatID int64,
+	messageID uint,
+	invocations []ToolInvocation,
+) error {
+	dm.writeMu.Lock()
+	defer dm.writeMu.Unlock()
+
+	for i := range invocations {
+		invocations[i].ID = 0
+		invocations[i].ChatID = chatID
+		invocations[i].MessageID = messageID
+	}
+	return dm.db.Create(&invocations).Error
+}
+
+func (dm *Manager) GetToolInvocations(messageIDs []uint) ([]ToolInvocation, error) {
+	var invocations []ToolInvocation
+	result := dm.db.Where("message_id IN ?", messageIDs).Order("id").Find(&invocations)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return invocations, nil
+}
+
+func (dm *Manager) StoreChatSummary(chatID int64, threadID int, content string) error {
+	dm.writeMu.Lock()
+	defer dm.writeMu.Unlock()
+
+	return dm.db.Create(&ChatSummary{
+		ChatID:   chatID,
+		ThreadID: threadID,
+		Content:  content,
+	}).Error
+}
+
+func (dm *Manager) GetLatestChatSummary(chatID int64, threadID int) (ChatSummary, error) {
+	var summary ChatSummary
+	result := dm.db.Where("chat_id = ? AND thread_id = ?", chatID, threadID).Order("id DESC").First(&summary)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return ChatSummary{}, nil
+	}
+	return summary, result.Error
+}
+
+func (dm *Manager) ClearChatSummaries(chatID int64) error {
+	dm.writeMu.Lock()
+	defer dm.writeMu.Unlock()
+
+	return dm.db.Where("chat_id = ?", chatID).Delete(&ChatSummary{}).Error
+}
+
+func (dm *Manager) AddChatMemory(chatID int64, userID int64, content string) (uint, error) {
+	dm.writeMu.Lock()
+	defer dm.writeMu.Unlock()
+
+	memory := ChatMemory{
+		ChatID:  chatID,
+		UserID:  userID,
+		Content: content,
+	}
+	if err := dm.db.Create(&memory).Error; err != nil {
+		return 0, err
+	}
+	return memory.ID, nil
+}
+
+func (dm *Manager) GetChatMemories(chatID int64) ([]ChatMemory, error) {
+	var memories []ChatMemory
+	result := dm.db.Where("chat_id = ?", chatID).Order("id").Find(&memories)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return memories, nil
+}
+
+// DeleteChatMemory deletes a memory note of a chat.
+// It returns gorm.ErrRecordNotFound if the chat has no note with the given ID.
+func (dm *Manager) DeleteChatMemory(chatID int64, memoryID uint) error {
+	dm.writeMu.Lock()
+	defer dm.writeMu.Unlock()
+
+	result := dm.db.Where("chat_id = ? AND id = ?", chatID, memoryID).Delete(&ChatMemory{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// AddScheduledPrompt stores a scheduled prompt and returns its ID.
+func (dm *Manager) AddScheduledPrompt(prompt ScheduledPrompt) (uint, error) {
+	dm.writeMu.Lock()
+	defer dm.writeMu.Unlock()
+
+	if err := dm.db.Create(&prompt).Error; err != nil {
+		return 0, err
+	}
+	return prompt.ID, nil
+}
+
+// GetScheduledPrompts returns the scheduled prompts of a chat.
+func (dm *Manager) GetScheduledPrompts(chatID int64) ([]ScheduledPrompt, error) {
+	var prompts []ScheduledPrompt
+	result := dm.db.Where("chat_id = ?", chatID).Order("id").Find(&prompts)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return prompts, nil
+}
+
+// GetAllScheduledPrompts returns the scheduled prompts of every chat.
+func (dm *Manager) GetAllScheduledPrompts() ([]ScheduledPrompt, error) {
+	var prompts []ScheduledPrompt
+	result := dm.db.Order("id").Find(&prompts)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return prompts, nil
+}
+
+// SetScheduledPromptLastRun records when a scheduled prompt last ran.
+func (dm *Manager) SetScheduledPromptLastRun(promptID uint, lastRunAt time.Time) error {
+	dm.writeMu.Lock()
+	defer dm.writeMu.Unlock()
+
+	return dm.db.Model(&ScheduledPrompt{}).Where("id = ?", promptID).Update("last_run_at", lastRunAt).Error
+}
+
+// DeleteScheduledPrompt deletes a scheduled prompt of a chat.
+// It returns gorm.ErrRecordNotFound if the chat has no scheduled prompt with the given ID.
+func (dm *Manager) DeleteScheduledPrompt(chatID int64, promptID uint) error {
+	dm.writeMu.Lock()
+	defer dm.writeMu.Unlock()
+
+	result := dm.db.Where("chat_id = ? AND id = ?", chatID, promptID).Delete(&ScheduledPrompt{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// StoreDocumentChunks replaces the chunks of a document in a chat.
+func (dm *Manager) StoreDocumentChunks(chatID int64, documentName string, chunks []DocumentChunk) error {
+	dm.writeMu.Lock()
+	defer dm.writeMu.Unlock()
+
+	return dm.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("chat_id = ? AND document_name = ?", chatID, documentName).
+			Delete(&DocumentChunk{}).Error
+		if err != nil {
+			return err
+		}
+
+		for i := range chunks {
+			chunks[i].ID = 0
+			chunks[i].ChatID = chatID
+			chunks[i].DocumentName = documentName
+			chunks[i].ChunkIndex = i
+		}
+		return tx.CreateInBatches(&chunks, 100).Error
+	})
+}
+
+func (dm *Manager) GetDocumentChunks(chatID int64) ([]DocumentChunk, error) {
+	var chunks []DocumentChunk
+	result := dm.db.Where("chat_id = ?", chatID).Order("id").Find(&chunks)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return chunks, nil
+}
+
+func (dm *Manager) ListDocuments(chatID int64) ([]string, error) {
+	var documents []string
+	result := dm.db.Model(&DocumentChunk{}).
+		Where("chat_id = ?", chatID).
+		Distinct("document_name").
+		Order("document_name").
+		Pluck("document_name", &documents)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return documents, nil
+}
+
+// GetMessageEmbeddings returns the embeddings computed by a model of the given messages, indexed by message ID.
+// Messages without an embedding by the model are missing from the result.
+func (dm *Manager) GetMessageEmbeddings(messageIDs []uint, model string) (map[uint][]byte, error) {
+	embeddings := make(map[uint][]byte, len(messageIDs))
+	if len(messageIDs) == 0 {
+		return embeddings, nil
+	}
+
+	var rows []MessageEmbedding
+	result := dm.db.Where("message_id IN ? AND model = ?", messageIDs, model).Find(&rows)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	for _, row := range rows {
+		embeddings[row.MessageID] = row.Embedding
+	}
+	return embeddings, nil
+}
+
+// StoreMessageEmbeddings stores the embeddings of messages, replacing the ones they already have.
+func (dm *Manager) StoreMessageEmbeddings(embeddings []MessageEmbedding) error {
+	if len(embeddings) == 0 {
+		return nil
+	}
+
+	dm.writeMu.Lock()
+	defer dm.writeMu.Unlock()
+
+	return dm.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "message_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"chat_id", "model", "embedding"}),
+	}).CreateInBatches(&embeddings, 100).Error
+}
+
+// DeleteDocument deletes all chunks of a document in a chat.
+// It returns gorm.ErrRecordNotFound if the chat has no such document.
+func (dm *Manager) DeleteDocument(chatID int64, documentName string) error {
+	dm.writeMu.Lock()
+	defer dm.writeMu.Unlock()
+
+	result := dm.db.Where("chat_id = ? AND document_name = ?", chatID, documentName).
+		Delete(&DocumentChunk{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// UpdateChatState records the last Telegram message and update received in a chat.
+func (dm *Manager) UpdateChatState(chatID int64, chatTitle string, messageID int, updateID int) error {
+	dm.writeMu.Lock()
+	defer dm.writeMu.Unlock()
+
+	return dm.db.Clauses(
+		clause.OnConflict{
+			Columns: []clause.Column{{Name: "chat_id"}},
+			DoUpdates: clause.AssignmentColumns(
+				[]string{"chat_title", "last_message_id", "last_update_id", "updated_at"},
+			),
+		},
+	).Create(&ChatState{
+		ChatID:        chatID,
+		ChatTitle:     chatTitle,
+		LastMessageID: messageID,
+		LastUpdateID:  updateID,
+	}).Error
+}
+
+// GetChatState returns the state of a chat, or an empty state if none has been recorded.
+func (dm *Manager) GetChatState(chatID int64) (ChatState, error) {
+	var state ChatState
+	result := dm.db.Where("chat_id = ?", chatID).First(&state)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return ChatState{}, nil
+	}
+	return state, result.Error
+}
+
+// GetLastUpdateID returns the ID of the latest Telegram update recorded in any chat.
+func (dm *Manager) GetLastUpdateID() (int, error) {
+	var lastUpdateID int
+	result := dm.db.Model(&ChatState{}).Select("COALESCE(MAX(last_update_id), 0)").Scan(&lastUpdateID)
+	return lastUpdateID, result.Error
+}
+
+// AddPendingGeneration records that a response to a message is being generated.
+func (dm *Manager) AddPendingGeneration(chatID int64, chatType string, telegramMessageID int) error {
+	dm.writeMu.Lock()
+	defer dm.writeMu.Unlock()
+
+	return dm.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&PendingGeneration{
+		ChatID:            chatID,
+		ChatType:          chatType,
+		TelegramMessageID: telegramMessageID,
+	}).Error
+}
+
+// DeletePendingGeneration removes a pending generation once the response has been handled.
+func (dm *Manager) DeletePendingGeneration(chatID int64, telegramMessageID int) error {
+	dm.writeMu.Lock()
+	defer dm.writeMu.Unlock()
+
+	return dm.db.Where("chat_id = ? AND telegram_message_id = ?", chatID, telegramMessageID).
+		Delete(&PendingGeneration{}).Error
+}
+
+// GetPendingGenerations returns the pending generations in the order they were added.
+func (dm *Manager) GetPendingGenerations() ([]PendingGeneration, error) {
+	var pending []PendingGeneration
+	result := dm.db.Order("id ASC").Find(&pending)
+	return pending, result.Error
+}
+
+func (dm *Manager) RecordUsage(
+	date string,
+	chatID int64,
+	userID int64,
+	model string,
+	promptTokens int64,
+	completionTokens int64,
+) error {
+	dm.writeMu.Lock()
+	defer dm.writeMu.Unlock()
+
+	return dm.db.Clauses(
+		clause.OnConflict{
+			Columns: []clause.Column{
+				{Name: "date"},
+				{Name: "chat_id"},
+				{Name: "user_id"},
+				{Name: "model"},
+			},
+			DoUpdates: clause.Assignments(map[string]any{
+				"prompt_tokens":     gorm.Expr("prompt_tokens + ?", promptTokens),
+				"completion_tokens": gorm.Expr("completion_tokens + ?", completionTokens),
+			}),
+		},
+	).Create(&Usage{
+		Date:             date,
+		ChatID:           chatID,
+		UserID:           userID,
+		Model:            model,
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+	}).Error
+}
+
+func (dm *Manager) GetChatUsage(chatID int64, date string) ([]Usage, error) {
+	var usages []Usage
+	result := dm.db.Where("chat_id = ? AND date = ?", chatID, date).Find(&usages)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return usages, nil
+}
+
+// GetChatUsageSince returns the usage records of a chat from the given date onwards.
+func (dm *Manager) GetChatUsageSince(chatID int64, sinceDate string) ([]Usage, error) {
+	var usages []Usage
+	result := dm.db.Where("chat_id = ? AND date >= ?", chatID, sinceDate).Order("date").Find(&usages)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return usages, nil
+}
+
+func (dm *Manager) GetGlobalUsage(date string) ([]Usage, error) {
+	var usages []Usage
+	result := dm.db.Where("date = ?", date).Find(&usages)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return usages, nil
+}
+
+// GetGlobalUsageSince returns the usage records of all chats from the given date onwards.
+func (dm *Manager) GetGlobalUsageSince(sinceDate string) ([]Usage, error) {
+	var usages []Usage
+	result := dm.db.Where("date >= ?", sinceDate).Order("date").Find(&usages)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return usages, nil
+}
+
+// SetUserAPIKey encrypts and stores the API key a user registered for their private chat.
+func (dm *Manager) SetUserAPIKey(userID int64, apiKey string) error {
+	if dm.cipher == nil {
+		return ErrEncryptionNotConfigured
+	}
+	encryptedKey, err := dm.cipher.Encrypt(apiKey)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt API key: %w", err)
+	}
+
+	dm.writeMu.Lock()
+	defer dm.writeMu.Unlock()
+
+	return dm.db.Clauses(
+		clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"encrypted_key", "updated_at"}),
+		},
+	).Create(&UserAPIKey{UserID: userID, EncryptedKey: encryptedKey}).Error
+}
+
+// GetUserAPIKey returns the decrypted API key of a user, or an empty string if none is registered.
+func (dm *Manager) GetUserAPIKey(userID int64) (string, error) {
+	var userAPIKey UserAPIKey
+	result := dm.db.Where("user_id = ?", userID).First(&userAPIKey)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
+	if result.Error != nil {
+		return "", result.Error
+	}
+	if dm.cipher == nil {
+		return "", ErrEncryptionNotConfigured
+	}
+	return dm.cipher.Decrypt(userAPIKey.EncryptedKey)
+}
+
+// DeleteUserAPIKey deletes the API key of a user and reports whether one was registered.
+func (dm *Manager) DeleteUserAPIKey(userID int64) (bool, error) {
+	dm.writeMu.Lock()
+	defer dm.writeMu.Unlock()
+
+	result := dm.db.Where("user_id = ?", userID).Delete(&UserAPIKey{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// ChatRowCounts holds the number of rows stored for a chat in each table.
+type ChatRowCounts struct {
+	ChatID          int64
+	ChatTitle       string
+	Messages        int64
+	StaleMessages   int64
+	ToolInvocations int64
+	DocumentChunks  int64
+	ChatMemories    int64
+	ChatSummaries   int64
+}
+
+// Total returns the total number of rows stored for the chat.
+func (c *ChatRowCounts) Total() int64 {
+	return c.Messages + c.ToolInvocations + c.DocumentChunks + c.ChatMemories + c.ChatSummaries
+}
+
+// DatabaseSize holds the size of the database file in bytes.
+type DatabaseSize struct {
+	Size     int64
+	FreeSize int64
+}
+
+// ObjectSize holds the size in bytes of a table or index.
+type ObjectSize struct {
+	Name string
+	Type string
+	Size int64
+}
+
+// GetChatRowCounts returns the row counts of every chat with stored data, ordered by total rows.
+// Messages stored before staleBefore are counted as stale.
+func (dm *Manager) GetChatRowCounts(staleBefore time.Time) ([]ChatRowCounts, error) {
+	var messageCounts []ChatRowCounts
+	result := dm.db.Model(&Message{}).
+		Select(
+			"chat_id, MAX(chat_title) AS chat_title, COUNT(*) AS messages, "+
+				"SUM(CASE WHEN timestamp < ? THEN 1 ELSE 0 END) AS stale_messages",
+			staleBefore,
+		).
+		Group("chat_id").
+		Scan(&messageCounts)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	countsByChat := map[int64]*ChatRowCounts{}
+	for i := range messageCounts {
+		countsByChat[messageCounts[i].ChatID] = &messageCounts[i]
+	}
+
+	// Count the rows of the other tables that are stored per chat
+	tables := []struct {
+		model any
+		count func(*ChatRowCounts) *int64
+	}{
+		{&ToolInvocation{}, func(c *ChatRowCounts) *int64 { return &c.ToolInvocations }},
+		{&DocumentChunk{}, func(c *ChatRowCounts) *int64 { return &c.DocumentChunks }},
+		{&ChatMemory{}, func(c *ChatRowCounts) *int64 { return &c.ChatMemories }},
+		{&ChatSummary{}, func(c *ChatRowCounts) *int64 { return &c.ChatSummaries }},
+	}
+	for _, table := range tables {
+		var rows []struct {
+			ChatID int64
+			Count  int64
+		}
+		result = dm.db.Model(table.model).Select("chat_id, COUNT(*) AS count").Group("chat_id").Scan(&rows)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		for _, row := range rows {
+			counts, ok := countsByChat[row.ChatID]
+			if !ok {
+				counts = &ChatRowCounts{ChatID: row.ChatID}
+				countsByChat[row.ChatID] = counts
+			}
+			*table.count(counts) = row.Count
+		}
+	}
+
+	chatRowCounts := make([]ChatRowCounts, 0, len(countsByChat))
+	for _, counts := range countsByChat {
+		chatRowCounts = append(chatRowCounts, *counts)
+	}
+	slices.SortFunc(chatRowCounts, func(a, b ChatRowCounts) int {
+		return cmp.Or(cmp.Compare(b.Total(), a.Total()), cmp.Compare(a.ChatID, b.ChatID))
+	})
+	return chatRowCounts, nil
+}
+
+// GetDatabaseSize returns the size of the database and the size of its unused pages.
+func (dm *Manager) GetDatabaseSize() (DatabaseSize, error) {
+	if dm.driver == DriverMySQL {
+		return dm.getMySQLDatabaseSize()
+	}
+
+	var pageSize, pageCount, freelistCount int64
+	if err := dm.db.Raw("PRAGMA page_size").Scan(&pageSize).Error; err != nil {
+		return DatabaseSize{}, err
+	}
+	if err := dm.db.Raw("PRAGMA page_count").Scan(&pageCount).Error; err != nil {
+		return DatabaseSize{}, err
+	}
+	if err := dm.db.Raw("PRAGMA freelist_count").Scan(&freelistCount).Error; err != nil {
+		return DatabaseSize{}, err
+	}
+	return DatabaseSize{Size: pageSize * pageCount, FreeSize: pageSize * freelistCount}, nil
+}
+
+// GetObjectSizes returns the sizes of all tables and indexes, ordered from the largest.
+// SQLite must be built with the dbstat virtual table, while MySQL reports the tables only.
+func (dm *Manager) GetObjectSizes() ([]ObjectSize, error) {
+	if dm.driver == DriverMySQL {
+		return dm.getMySQLObjectSizes()
+	}
+
+	var sizes []ObjectSize
+	result := dm.db.Raw(
+		"SELECT dbstat.name AS name, sqlite_master.type AS type, SUM(dbstat.pgsize) AS size " +
+			"FROM dbstat JOIN sqlite_master ON sqlite_master.name = dbstat.name " +
+			"GROUP BY dbstat.name ORDER BY size DESC",
+	).Scan(&sizes)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return sizes, nil
 }
@@ -1,12 +1,20 @@
 package database //nolint:testpackage // Unit tests are in the same package
 
 import (
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/k4yt3x/tellama/internal/redact"
+	"github.com/k4yt3x/tellama/internal/secrets"
+
 	"github.com/go-faker/faker/v4"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
 )
 
 type FakerModels struct {
@@ -16,7 +24,7 @@ type FakerModels struct {
 }
 
 func setupTestDB(t *testing.T) *Manager {
-	dbManager, err := NewDatabaseManager("file::memory:?cache=shared")
+	dbManager, err := NewDatabaseManager("file::memory:?cache=shared", SQLiteConfig{})
 	require.NoError(t, err)
 	return dbManager
 }
@@ -26,7 +34,7 @@ func TestNewDatabaseManager(t *testing.T) {
 	dbPath := "file::memory:?cache=shared"
 
 	// Act
-	dbManager, err := NewDatabaseManager(dbPath)
+	dbManager, err := NewDatabaseManager(dbPath, SQLiteConfig{})
 
 	// Assert
 	require.NoError(t, err)
@@ -37,6 +45,9 @@ func TestNewDatabaseManager(t *testing.T) {
 		&TrustedChat{},
 		&ChatOverride{},
 		&Message{},
+		&ToolInvocation{},
+		&ChatSummary{},
+		&Usage{},
 	)
 	assert.NoError(t, err)
 }
@@ -57,6 +68,43 @@ func TestIsChatAllowed(t *testing.T) {
 		assert.True(t, allowed)
 	})
 
+	t.Run("Trusted chat", func(t *testing.T) {
+		// Arrange
+		chatIDs, err := faker.RandomInt(-1000000, 1000000, 1)
+		require.NoError(t, err)
+		chatID := int64(chatIDs[0])
+
+		// Act
+		err = dbManager.TrustChat(chatID, faker.Sentence())
+		require.NoError(t, err)
+
+		// Assert
+		assert.True(t, dbManager.IsChatTrusted(chatID))
+	})
+
+	t.Run("List and untrust chat", func(t *testing.T) {
+		// Arrange
+		chatIDs, err := faker.RandomInt(-1000000, 1000000, 1)
+		require.NoError(t, err)
+		chatID := int64(chatIDs[0])
+		chatTitle := faker.Sentence()
+		require.NoError(t, dbManager.TrustChat(chatID, chatTitle))
+
+		// Act
+		trustedChats, err := dbManager.ListTrustedChats()
+		require.NoError(t, err)
+		err = dbManager.UntrustChat(chatID)
+		require.NoError(t, err)
+
+		// Assert
+		assert.Contains(t, trustedChats, TrustedChat{
+			ID:        trustedChats[len(trustedChats)-1].ID,
+			ChatID:    chatID,
+			ChatTitle: chatTitle,
+		})
+		assert.False(t, dbManager.IsChatTrusted(chatID))
+	})
+
 	t.Run("Not allowed chat", func(t *testing.T) {
 		// Act
 		allowed := dbManager.IsChatTrusted(-1) // Non-existent ID
@@ -66,6 +114,130 @@ func TestIsChatAllowed(t *testing.T) {
 	})
 }
 
+func TestTrustedUsers(t *testing.T) {
+	dbManager := setupTestDB(t)
+	userIDs, err := faker.RandomInt(1, 1000000, 1)
+	require.NoError(t, err)
+	userID := int64(userIDs[0])
+
+	t.Run("Trust user", func(t *testing.T) {
+		// Act
+		err = dbManager.TrustUser(userID, "owner")
+		require.NoError(t, err)
+		err = dbManager.TrustUser(userID, "renamed_owner")
+		require.NoError(t, err)
+		var trustedUsers []TrustedUser
+		trustedUsers, err = dbManager.ListTrustedUsers()
+
+		// Assert
+		require.NoError(t, err)
+		assert.True(t, dbManager.IsUserTrusted(userID))
+		assert.Contains(t, trustedUsers, TrustedUser{
+			ID:       trustedUsers[len(trustedUsers)-1].ID,
+			UserID:   userID,
+			Username: "renamed_owner",
+		})
+	})
+
+	t.Run("Untrust user", func(t *testing.T) {
+		// Act
+		err = dbManager.UntrustUser(userID)
+
+		// Assert
+		require.NoError(t, err)
+		assert.False(t, dbManager.IsUserTrusted(userID))
+	})
+}
+
+func TestUserOptOut(t *testing.T) {
+	dbManager := setupTestDB(t)
+	userIDs, err := faker.RandomInt(1, 1000000, 1)
+	require.NoError(t, err)
+	userID := int64(userIDs[0])
+
+	t.Run("Not opted out by default", func(t *testing.T) {
+		// Act
+		var optedOut bool
+		optedOut, err = dbManager.IsUserOptedOut(userID)
+
+		// Assert
+		require.NoError(t, err)
+		assert.False(t, optedOut)
+	})
+
+	t.Run("Opt out", func(t *testing.T) {
+		// Act
+		err = dbManager.SetUserOptedOut(userID, true)
+		require.NoError(t, err)
+		var optedOut bool
+		optedOut, err = dbManager.IsUserOptedOut(userID)
+
+		// Assert
+		require.NoError(t, err)
+		assert.True(t, optedOut)
+	})
+
+	t.Run("Opt in", func(t *testing.T) {
+		// Act
+		err = dbManager.SetUserOptedOut(userID, false)
+		require.NoError(t, err)
+		var optedOut bool
+		optedOut, err = dbManager.IsUserOptedOut(userID)
+
+		// Assert
+		require.NoError(t, err)
+		assert.False(t, optedOut)
+	})
+}
+
+func TestBannedUsers(t *testing.T) {
+	dbManager := setupTestDB(t)
+	ids, err := faker.RandomInt(1, 1000000, 3)
+	require.NoError(t, err)
+	chatID, userID, otherUserID := -int64(ids[0]), int64(ids[1]), int64(ids[2])
+
+	t.Run("Ban user", func(t *testing.T) {
+		// Act
+		err = dbManager.BanUser(chatID, userID, "flooding", time.Now().Add(time.Hour))
+		require.NoError(t, err)
+		err = dbManager.BanUser(chatID, userID, "spam", time.Time{})
+		require.NoError(t, err)
+		var ban BannedUser
+		ban, err = dbManager.GetUserBan(chatID, userID)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, "spam", ban.Reason)
+		assert.True(t, ban.Until.IsZero())
+		_, err = dbManager.GetUserBan(-chatID, userID)
+		require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+
+	t.Run("Expired ban", func(t *testing.T) {
+		// Act
+		err = dbManager.BanUser(chatID, otherUserID, "", time.Now().Add(-time.Minute))
+		require.NoError(t, err)
+		_, err = dbManager.GetUserBan(chatID, otherUserID)
+		bannedUsers, listErr := dbManager.ListBannedUsers(chatID)
+
+		// Assert
+		require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+		require.NoError(t, listErr)
+		require.Len(t, bannedUsers, 1)
+		assert.Equal(t, userID, bannedUsers[0].UserID)
+	})
+
+	t.Run("Unban user", func(t *testing.T) {
+		// Act
+		err = dbManager.UnbanUser(chatID, userID)
+
+		// Assert
+		require.NoError(t, err)
+		_, err = dbManager.GetUserBan(chatID, userID)
+		require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+}
+
 func TestSystemPrompts(t *testing.T) {
 	dbManager := setupTestDB(t)
 	chatIDs, err := faker.RandomInt(-1000000, 1000000, 1)
@@ -117,6 +289,212 @@ func TestSystemPrompts(t *testing.T) {
 		assert.Equal(t, systemPrompt, chatOverride.SystemPrompt)
 	})
 
+	t.Run("Set and reset model", func(t *testing.T) {
+		// Act
+		err = dbManager.SetChatModel(chatID, faker.Sentence(), "test-model")
+		require.NoError(t, err)
+
+		var chatOverride ChatOverride
+		chatOverride, err = dbManager.GetChatOverride(chatID)
+		require.NoError(t, err)
+		assert.Equal(t, "test-model", chatOverride.Model)
+
+		err = dbManager.SetChatModel(chatID, faker.Sentence(), "")
+		require.NoError(t, err)
+		chatOverride, err = dbManager.GetChatOverride(chatID)
+		require.NoError(t, err)
+
+		// Assert
+		assert.Empty(t, chatOverride.Model)
+		assert.NotEmpty(t, chatOverride.SystemPrompt)
+	})
+
+	t.Run("Set max turns", func(t *testing.T) {
+		// Act
+		err = dbManager.SetChatMaxTurns(chatID, faker.Sentence(), 42)
+		require.NoError(t, err)
+
+		var chatOverride ChatOverride
+		chatOverride, err = dbManager.GetChatOverride(chatID)
+		require.NoError(t, err)
+
+		// Assert
+		assert.Equal(t, 42, chatOverride.MaxTurns)
+		assert.NotEmpty(t, chatOverride.SystemPrompt)
+	})
+
+	t.Run("Set routing", func(t *testing.T) {
+		// Act
+		err = dbManager.SetChatRouting(chatID, faker.Sentence(), false, "small-model", "")
+		require.NoError(t, err)
+
+		var chatOverride ChatOverride
+		chatOverride, err = dbManager.GetChatOverride(chatID)
+		require.NoError(t, err)
+
+		// Assert
+		assert.False(t, IsTrue(chatOverride.RoutingDisabled))
+		assert.Equal(t, "small-model", chatOverride.RoutingSmallModel)
+		assert.Empty(t, chatOverride.RoutingLargeModel)
+		assert.Equal(t, 42, chatOverride.MaxTurns)
+
+		err = dbManager.SetChatRouting(chatID, faker.Sentence(), true, "", "")
+		require.NoError(t, err)
+		chatOverride, err = dbManager.GetChatOverride(chatID)
+		require.NoError(t, err)
+		assert.True(t, IsTrue(chatOverride.RoutingDisabled))
+		assert.Empty(t, chatOverride.RoutingSmallModel)
+	})
+
+	t.Run("Set reply format", func(t *testing.T) {
+		// Act
+		err = dbManager.SetChatReplyFormat(chatID, faker.Sentence(), 2000, 0.75)
+		require.NoError(t, err)
+
+		var chatOverride ChatOverride
+		chatOverride, err = dbManager.GetChatOverride(chatID)
+		require.NoError(t, err)
+
+		// Assert
+		assert.Equal(t, 2000, chatOverride.FileReplyMinLength)
+		assert.InDelta(t, 0.75, chatOverride.FileReplyCodeRatio, 1e-9)
+		assert.Equal(t, 42, chatOverride.MaxTurns)
+	})
+
+	t.Run("Set chat events", func(t *testing.T) {
+		// Act
+		err = dbManager.SetChatEvents(chatID, faker.Sentence(), "join,pin")
+		require.NoError(t, err)
+
+		var chatOverride ChatOverride
+		chatOverride, err = dbManager.GetChatOverride(chatID)
+		require.NoError(t, err)
+
+		// Assert
+		assert.Equal(t, "join,pin", chatOverride.ChatEvents)
+		assert.Equal(t, 2000, chatOverride.FileReplyMinLength)
+	})
+
+	t.Run("Set model badge", func(t *testing.T) {
+		// Act
+		err = dbManager.SetChatModelBadge(chatID, faker.Sentence(), "suffix")
+		require.NoError(t, err)
+
+		var chatOverride ChatOverride
+		chatOverride, err = dbManager.GetChatOverride(chatID)
+		require.NoError(t, err)
+
+		// Assert
+		assert.Equal(t, "suffix", chatOverride.ModelBadge)
+		assert.Equal(t, "join,pin", chatOverride.ChatEvents)
+	})
+
+	t.Run("Set language", func(t *testing.T) {
+		// Act
+		err = dbManager.SetChatLanguage(chatID, faker.Sentence(), "ja")
+		require.NoError(t, err)
+
+		var chatOverride ChatOverride
+		chatOverride, err = dbManager.GetChatOverride(chatID)
+		require.NoError(t, err)
+
+		// Assert
+		assert.Equal(t, "ja", chatOverride.Language)
+		assert.Equal(t, "suffix", chatOverride.ModelBadge)
+	})
+
+	t.Run("Set and clear options", func(t *testing.T) {
+		// Act
+		err = dbManager.SetChatOptions(chatID, faker.Sentence(), `{"temperature":0.3}`)
+		require.NoError(t, err)
+
+		var chatOverride ChatOverride
+		chatOverride, err = dbManager.GetChatOverride(chatID)
+		require.NoError(t, err)
+
+		// Assert
+		assert.JSONEq(t, `{"temperature":0.3}`, chatOverride.Options)
+
+		// Act
+		err = dbManager.SetChatOptions(chatID, faker.Sentence(), "")
+		require.NoError(t, err)
+		chatOverride, err = dbManager.GetChatOverride(chatID)
+		require.NoError(t, err)
+
+		// Assert
+		assert.Empty(t, chatOverride.Options)
+	})
+
+	t.Run("Set trigger, memory, voice replies, persona, response format, and welcome", func(t *testing.T) {
+		// Act
+		err = dbManager.SetChatTrigger(chatID, faker.Sentence(), "prefix")
+		require.NoError(t, err)
+		err = dbManager.SetChatMemoryDisabled(chatID, faker.Sentence(), true)
+		require.NoError(t, err)
+		err = dbManager.SetChatVoiceReply(chatID, faker.Sentence(), true)
+		require.NoError(t, err)
+		err = dbManager.SetChatPersona(chatID, faker.Sentence(), "helpdesk")
+		require.NoError(t, err)
+		err = dbManager.SetChatResponseFormat(chatID, faker.Sentence(), "json")
+		require.NoError(t, err)
+		err = dbManager.SetChatDailyTokenBudget(chatID, faker.Sentence(), 50000)
+		require.NoError(t, err)
+		err = dbManager.SetChatWelcomeMembers(chatID, faker.Sentence(), true)
+		require.NoError(t, err)
+
+		var chatOverride ChatOverride
+		chatOverride, err = dbManager.GetChatOverride(chatID)
+		require.NoError(t, err)
+
+		// Assert
+		assert.Equal(t, "prefix", chatOverride.Trigger)
+		assert.True(t, IsTrue(chatOverride.MemoryDisabled))
+		assert.True(t, IsTrue(chatOverride.VoiceReply))
+		assert.Equal(t, "helpdesk", chatOverride.Persona)
+		assert.Equal(t, "json", chatOverride.ResponseFormat)
+		assert.Equal(t, int64(50000), chatOverride.DailyTokenBudget)
+		assert.True(t, IsTrue(chatOverride.WelcomeMembers))
+		assert.Equal(t, "ja", chatOverride.Language)
+	})
+
+	t.Run("Set chat preset", func(t *testing.T) {
+		// Arrange
+		baseURL := faker.URL()
+		apiKey := faker.Password()
+		err = dbManager.SetChatOverride(chatID, faker.Sentence(), baseURL, apiKey, "", faker.Word(), "")
+		require.NoError(t, err)
+
+		// Act
+		err = dbManager.SetChatPreset(ChatOverride{
+			ChatID:       chatID,
+			ChatTitle:    faker.Sentence(),
+			SystemPrompt: "You are a helpful pirate.",
+			Model:        "llama3.3",
+		})
+		require.NoError(t, err)
+
+		var chatOverride ChatOverride
+		chatOverride, err = dbManager.GetChatOverride(chatID)
+		require.NoError(t, err)
+
+		// Assert
+		assert.Equal(t, "You are a helpful pirate.", chatOverride.SystemPrompt)
+		assert.Equal(t, "llama3.3", chatOverride.Model)
+		assert.Empty(t, chatOverride.Options)
+		assert.Empty(t, chatOverride.ModelBadge)
+		assert.Empty(t, chatOverride.ChatEvents)
+		assert.Empty(t, chatOverride.Language)
+		assert.Empty(t, chatOverride.Trigger)
+		assert.Nil(t, chatOverride.MemoryDisabled)
+		assert.Nil(t, chatOverride.VoiceReply)
+		assert.Empty(t, chatOverride.Persona)
+		assert.Empty(t, chatOverride.ResponseFormat)
+		assert.Nil(t, chatOverride.WelcomeMembers)
+		assert.Equal(t, int64(50000), chatOverride.DailyTokenBudget)
+		assert.Equal(t, apiKey, chatOverride.APIKey)
+		assert.Equal(t, baseURL, chatOverride.BaseURL)
+	})
+
 	t.Run("Delete chat override", func(t *testing.T) {
 		// Act
 		err = dbManager.DeleteChatOverride(chatID)
@@ -136,6 +514,107 @@ func TestSystemPrompts(t *testing.T) {
 	})
 }
 
+func TestGlobalChatOverride(t *testing.T) {
+	dbManager := setupTestDB(t)
+
+	t.Run("Set global override", func(t *testing.T) {
+		// Act
+		err := dbManager.SetGlobalChatOverride(ChatOverride{
+			APIKey:       "sk-global",
+			Model:        "llama3.3",
+			SystemPrompt: "You are a helpful assistant.",
+			Language:     "ja",
+		})
+		require.NoError(t, err)
+
+		globalOverride, err := dbManager.GetGlobalChatOverride()
+		require.NoError(t, err)
+
+		// Assert
+		assert.Zero(t, globalOverride.ChatID)
+		assert.Equal(t, "sk-global", globalOverride.APIKey)
+		assert.Equal(t, "llama3.3", globalOverride.Model)
+		assert.Equal(t, "You are a helpful assistant.", globalOverride.SystemPrompt)
+		assert.Equal(t, "ja", globalOverride.Language)
+	})
+
+	t.Run("Chats inherit the global override", func(t *testing.T) {
+		// Arrange
+		require.NoError(t, dbManager.SetChatModel(1001, "Group", "qwen3"))
+
+		// Act
+		chatOverride, err := dbManager.GetChatOverride(1001)
+		require.NoError(t, err)
+		defaultOverride, err := dbManager.GetChatOverride(1002)
+		require.NoError(t, err)
+
+		// Assert
+		assert.Equal(t, "qwen3", chatOverride.Model)
+		assert.Equal(t, "You are a helpful assistant.", chatOverride.SystemPrompt)
+		assert.Equal(t, "llama3.3", defaultOverride.Model)
+		assert.Equal(t, "sk-global", defaultOverride.APIKey)
+	})
+
+	t.Run("Chats turn off boolean settings of the global override", func(t *testing.T) {
+		// Arrange
+		enabled := true
+		require.NoError(t, dbManager.SetGlobalChatOverride(ChatOverride{
+			Model:           "llama3.3",
+			RoutingDisabled: &enabled,
+			MemoryDisabled:  &enabled,
+			VoiceReply:      &enabled,
+			WelcomeMembers:  &enabled,
+		}))
+		require.NoError(t, dbManager.SetChatRouting(1003, "Group", false, "", ""))
+		require.NoError(t, dbManager.SetChatMemoryDisabled(1003, "Group", false))
+		require.NoError(t, dbManager.SetChatVoiceReply(1003, "Group", false))
+		require.NoError(t, dbManager.SetChatWelcomeMembers(1003, "Group", false))
+
+		// Act
+		chatOverride, err := dbManager.GetChatOverride(1003)
+		require.NoError(t, err)
+		inherited, err := dbManager.GetChatOverride(1002)
+		require.NoError(t, err)
+
+		// Assert
+		for _, value := range []*bool{
+			chatOverride.RoutingDisabled,
+			chatOverride.MemoryDisabled,
+			chatOverride.VoiceReply,
+			chatOverride.WelcomeMembers,
+		} {
+			require.NotNil(t, value)
+			assert.False(t, *value)
+		}
+		assert.True(t, IsTrue(inherited.RoutingDisabled))
+		assert.True(t, IsTrue(inherited.MemoryDisabled))
+		assert.True(t, IsTrue(inherited.VoiceReply))
+		assert.True(t, IsTrue(inherited.WelcomeMembers))
+	})
+
+	t.Run("Replace global override", func(t *testing.T) {
+		// Act
+		err := dbManager.SetGlobalChatOverride(ChatOverride{ChatID: 1001, Model: "mistral"})
+		require.NoError(t, err)
+
+		globalOverride, err := dbManager.GetGlobalChatOverride()
+		require.NoError(t, err)
+		chatOverride, err := dbManager.GetChatOverride(1002)
+		require.NoError(t, err)
+		var count int64
+		require.NoError(t, dbManager.db.Model(&ChatOverride{}).Count(&count).Error)
+
+		// Assert
+		assert.Zero(t, globalOverride.ChatID)
+		assert.Equal(t, "mistral", globalOverride.Model)
+		assert.Empty(t, globalOverride.APIKey)
+		assert.Empty(t, globalOverride.SystemPrompt)
+		assert.Equal(t, "mistral", chatOverride.Model)
+		assert.Nil(t, chatOverride.WelcomeMembers)
+		assert.Equal(t, int64(3), count)
+	})
+}
+
 func TestMessageStorage(t *testing.T) {
 	dbManager := setupTestDB(t)
 	chatIDs, err := faker.RandomInt(-1000000, 1000000, 1)
@@ -144,28 +623,33 @@ func TestMessageStorage(t *testing.T) {
 
 	// Generate test data
 	testMessage := Message{
-		Timestamp: time.Now().UTC(),
-		ChatID:    chatID,
-		ChatTitle: faker.Word(),
-		Role:      "user",
-		UserID:    faker.RandomUnixTime(),
-		Username:  faker.Username(),
-		FirstName: faker.FirstName(),
-		LastName:  faker.LastName(),
-		Content:   faker.Paragraph(),
+		Timestamp:   time.Now().UTC(),
+		ChatID:      chatID,
+		ChatTitle:   faker.Word(),
+		Role:        "user",
+		ContentType: ContentTypePhotoCaption,
+		UserID:      faker.RandomUnixTime(),
+		Username:    faker.Username(),
+		FirstName:   faker.FirstName(),
+		LastName:    faker.LastName(),
+		Content:     faker.Paragraph(),
 	}
 
 	t.Run("Store message", func(t *testing.T) {
 		// Act
-		err = dbManager.StoreMessage(
+		_, err = dbManager.StoreMessage(
 			testMessage.ChatID,
 			testMessage.ChatTitle,
 			testMessage.Role,
+			testMessage.ContentType,
 			testMessage.UserID,
 			testMessage.Username,
 			testMessage.FirstName,
 			testMessage.LastName,
 			testMessage.Content,
+			0,
+			0,
+			0,
 		)
 
 		// Assert
@@ -175,7 +659,7 @@ func TestMessageStorage(t *testing.T) {
 	t.Run("Retrieve messages", func(t *testing.T) {
 		// Act
 		var messages []Message
-		messages, err = dbManager.GetMessages(chatID, 10)
+		messages, err = dbManager.GetMessages(chatID, 0, 10)
 
 		// Assert
 		require.NoError(t, err)
@@ -184,19 +668,1109 @@ func TestMessageStorage(t *testing.T) {
 		msg := messages[0]
 		assert.Equal(t, testMessage.ChatID, msg.ChatID)
 		assert.Equal(t, testMessage.Content, msg.Content)
+		assert.Equal(t, testMessage.ContentType, msg.ContentType)
 		assert.WithinDuration(t, testMessage.Timestamp, msg.Timestamp, time.Second)
 	})
 
+	t.Run("Count messages", func(t *testing.T) {
+		// Act
+		var count int64
+		count, err = dbManager.CountMessages(chatID, 0, testMessage.Role)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), count)
+	})
+
+	t.Run("Get message and messages until", func(t *testing.T) {
+		// Arrange
+		var message Message
+		message, err = dbManager.FindMessage(chatID, testMessage.UserID, testMessage.Content)
+		require.NoError(t, err)
+
+		// Act
+		var found Message
+		found, err = dbManager.GetMessage(message.ID)
+		require.NoError(t, err)
+
+		var messages []Message
+		messages, err = dbManager.GetMessagesUntil(chatID, 0, message.ID, 10)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, testMessage.Content, found.Content)
+		require.Len(t, messages, 1)
+		assert.Equal(t, message.ID, messages[0].ID)
+	})
+
+	t.Run("Pin message", func(t *testing.T) {
+		// Arrange
+		var message Message
+		message, err = dbManager.FindMessage(chatID, testMessage.UserID, testMessage.Content)
+		require.NoError(t, err)
+
+		// Act
+		err = dbManager.SetMessagePinned(message.ID, true)
+		require.NoError(t, err)
+
+		var pinned []Message
+		pinned, err = dbManager.GetPinnedMessages(chatID)
+
+		// Assert
+		require.NoError(t, err)
+		require.Len(t, pinned, 1)
+		assert.Equal(t, message.ID, pinned[0].ID)
+		assert.True(t, pinned[0].Pinned)
+	})
+
+	t.Run("Clear messages keeps pinned messages", func(t *testing.T) {
+		// Act
+		err = dbManager.ClearMessages(chatID)
+		require.NoError(t, err)
+
+		var messages []Message
+		messages, err = dbManager.GetMessages(chatID, 0, 10)
+		require.NoError(t, err)
+
+		// Assert
+		require.Len(t, messages, 1)
+		assert.True(t, messages[0].Pinned)
+	})
+
+	t.Run("Unpin message", func(t *testing.T) {
+		// Arrange
+		var message Message
+		message, err = dbManager.FindMessage(chatID, testMessage.UserID, testMessage.Content)
+		require.NoError(t, err)
+
+		// Act
+		err = dbManager.SetMessagePinned(message.ID, false)
+		require.NoError(t, err)
+
+		var pinned []Message
+		pinned, err = dbManager.GetPinnedMessages(chatID)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Empty(t, pinned)
+	})
+
 	t.Run("Clear messages", func(t *testing.T) {
 		// Act
 		err = dbManager.ClearMessages(chatID)
 		require.NoError(t, err)
 
 		var messages []Message
-		messages, err = dbManager.GetMessages(chatID, 10)
+		messages, err = dbManager.GetMessages(chatID, 0, 10)
 		require.NoError(t, err)
 
 		// Assert
 		assert.Empty(t, messages)
 	})
 }
+
+func TestEditedMessages(t *testing.T) {
+	dbManager := setupTestDB(t)
+	chatIDs, err := faker.RandomInt(-1000000, 1000000, 1)
+	require.NoError(t, err)
+	chatID := int64(chatIDs[0])
+	userID := faker.RandomUnixTime()
+
+	var questionID, answerID uint
+	questionID, err = dbManager.StoreMessage(
+		chatID, "", "user", ContentTypeText, userID, "user", "First", "Last", "What is 2+3?", 100, 0, 0,
+	)
+	require.NoError(t, err)
+	answerID, err = dbManager.StoreMessage(
+		chatID, "", "assistant", ContentTypeText, 1, "bot", "Bot", "", "5", 101, 100, 0,
+	)
+	require.NoError(t, err)
+
+	t.Run("Get message by Telegram ID", func(t *testing.T) {
+		// Act
+		var message Message
+		message, err = dbManager.GetMessageByTelegramID(chatID, 100)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, questionID, message.ID)
+
+		_, err = dbManager.GetMessageByTelegramID(chatID, 999)
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+
+	t.Run("Has message", func(t *testing.T) {
+		// Act
+		var stored, other bool
+		stored, err = dbManager.HasMessage(chatID, 100, userID)
+		require.NoError(t, err)
+		other, err = dbManager.HasMessage(chatID, 100, userID+1)
+
+		// Assert
+		require.NoError(t, err)
+		assert.True(t, stored)
+		assert.False(t, other)
+	})
+
+	t.Run("Get reply to message", func(t *testing.T) {
+		// Act
+		var reply Message
+		reply, err = dbManager.GetReplyTo(chatID, 100)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, answerID, reply.ID)
+		assert.Equal(t, 101, reply.TelegramMessageID)
+	})
+
+	t.Run("Update message content", func(t *testing.T) {
+		// Act
+		err = dbManager.UpdateMessageContent(questionID, "What is 2+2?")
+		require.NoError(t, err)
+		var message Message
+		message, err = dbManager.GetMessage(questionID)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, "What is 2+2?", message.Content)
+	})
+}
+
+func TestThreadMessages(t *testing.T) {
+	dbManager := setupTestDB(t)
+	chatIDs, err := faker.RandomInt(-1000000, 1000000, 1)
+	require.NoError(t, err)
+	chatID := int64(chatIDs[0])
+
+	for _, threadID := range []int{0, 7, 7} {
+		_, err = dbManager.StoreMessage(
+			chatID, "", "user", ContentTypeText, 1, "user", "First", "Last", faker.Sentence(), 0, 0, threadID,
+		)
+		require.NoError(t, err)
+	}
+
+	t.Run("Messages are scoped by thread", func(t *testing.T) {
+		// Act
+		var general, topic []Message
+		general, err = dbManager.GetMessages(chatID, 0, 10)
+		require.NoError(t, err)
+		topic, err = dbManager.GetMessages(chatID, 7, 10)
+		require.NoError(t, err)
+		var count int64
+		count, err = dbManager.CountMessages(chatID, 7, "user")
+
+		// Assert
+		require.NoError(t, err)
+		assert.Len(t, general, 1)
+		require.Len(t, topic, 2)
+		assert.Equal(t, 7, topic[0].ThreadID)
+		assert.Equal(t, int64(2), count)
+	})
+
+	t.Run("Messages since a time are scoped by thread", func(t *testing.T) {
+		// Act
+		var recent, future []Message
+		recent, err = dbManager.GetMessagesSince(chatID, 7, time.Now().Add(-time.Hour))
+		require.NoError(t, err)
+		future, err = dbManager.GetMessagesSince(chatID, 7, time.Now().Add(time.Hour))
+
+		// Assert
+		require.NoError(t, err)
+		require.Len(t, recent, 2)
+		assert.Less(t, recent[0].ID, recent[1].ID)
+		assert.Empty(t, future)
+	})
+
+	t.Run("Messages after the last message of a user", func(t *testing.T) {
+		// Arrange
+		_, err = dbManager.StoreMessage(
+			chatID, "", "user", ContentTypeText, 2, "other", "Other", "User", "Did I miss anything?", 0, 0, 7,
+		)
+		require.NoError(t, err)
+
+		// Act
+		var last Message
+		last, err = dbManager.GetLastUserMessage(chatID, 7, 1)
+		require.NoError(t, err)
+		var missed []Message
+		missed, err = dbManager.GetMessagesAfter(chatID, 7, last.ID)
+		require.NoError(t, err)
+		_, err = dbManager.GetLastUserMessage(chatID, 7, 3)
+
+		// Assert
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+		require.Len(t, missed, 1)
+		assert.Equal(t, int64(2), missed[0].UserID)
+	})
+
+	t.Run("Summaries are scoped by thread", func(t *testing.T) {
+		// Act
+		err = dbManager.StoreChatSummary(chatID, 7, "topic summary")
+		require.NoError(t, err)
+		var general, topic ChatSummary
+		general, err = dbManager.GetLatestChatSummary(chatID, 0)
+		require.NoError(t, err)
+		topic, err = dbManager.GetLatestChatSummary(chatID, 7)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Empty(t, general.Content)
+		assert.Equal(t, "topic summary", topic.Content)
+	})
+
+	t.Run("Clear thread messages", func(t *testing.T) {
+		// Act
+		err = dbManager.ClearThreadMessages(chatID, 7)
+		require.NoError(t, err)
+		var general, topic []Message
+		general, err = dbManager.GetMessages(chatID, 0, 10)
+		require.NoError(t, err)
+		topic, err = dbManager.GetMessages(chatID, 7, 10)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Len(t, general, 1)
+		assert.Empty(t, topic)
+	})
+}
+
+func TestToolInvocations(t *testing.T) {
+	dbManager := setupTestDB(t)
+	chatIDs, err := faker.RandomInt(-1000000, 1000000, 1)
+	require.NoError(t, err)
+	chatID := int64(chatIDs[0])
+
+	var messageID uint
+	messageID, err = dbManager.StoreMessage(
+		chatID,
+		faker.Word(),
+		"assistant",
+		ContentTypeText,
+		faker.RandomUnixTime(),
+		faker.Username(),
+		faker.FirstName(),
+		faker.LastName(),
+		faker.Paragraph(),
+		0,
+		0,
+		0,
+	)
+	require.NoError(t, err)
+	require.NotZero(t, messageID)
+
+	t.Run("Store tool invocations", func(t *testing.T) {
+		// Act
+		err = dbManager.StoreToolInvocations(chatID, messageID, []ToolInvocation{
+			{Round: 1, ToolCallID: "call_0", ToolName: "web_search", Arguments: "{}", Result: "a"},
+			{Round: 2, ToolCallID: "call_1", ToolName: "web_search", Arguments: "{}", Result: "b"},
+		})
+
+		// Assert
+		assert.NoError(t, err)
+	})
+
+	t.Run("Get tool invocations", func(t *testing.T) {
+		// Act
+		var invocations []ToolInvocation
+		invocations, err = dbManager.GetToolInvocations([]uint{messageID})
+
+		// Assert
+		require.NoError(t, err)
+		require.Len(t, invocations, 2)
+		assert.Equal(t, chatID, invocations[0].ChatID)
+		assert.Equal(t, messageID, invocations[0].MessageID)
+		assert.Equal(t, "a", invocations[0].Result)
+		assert.Equal(t, 2, invocations[1].Round)
+	})
+
+	t.Run("Clear messages removes tool invocations", func(t *testing.T) {
+		// Act
+		err = dbManager.ClearMessages(chatID)
+		require.NoError(t, err)
+
+		var invocations []ToolInvocation
+		invocations, err = dbManager.GetToolInvocations([]uint{messageID})
+
+		// Assert
+		require.NoError(t, err)
+		assert.Empty(t, invocations)
+	})
+}
+
+func TestChatSummaries(t *testing.T) {
+	dbManager := setupTestDB(t)
+	chatIDs, err := faker.RandomInt(-1000000, 1000000, 1)
+	require.NoError(t, err)
+	chatID := int64(chatIDs[0])
+
+	t.Run("Get missing summary", func(t *testing.T) {
+		// Act
+		var summary ChatSummary
+		summary, err = dbManager.GetLatestChatSummary(chatID, 0)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Empty(t, summary.Content)
+	})
+
+	t.Run("Store and get latest summary", func(t *testing.T) {
+		// Arrange
+		latest := faker.Paragraph()
+
+		// Act
+		err = dbManager.StoreChatSummary(chatID, 0, faker.Paragraph())
+		require.NoError(t, err)
+		err = dbManager.StoreChatSummary(chatID, 0, latest)
+		require.NoError(t, err)
+
+		var summary ChatSummary
+		summary, err = dbManager.GetLatestChatSummary(chatID, 0)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, latest, summary.Content)
+	})
+
+	t.Run("Clear summaries", func(t *testing.T) {
+		// Act
+		err = dbManager.ClearChatSummaries(chatID)
+		require.NoError(t, err)
+
+		var summary ChatSummary
+		summary, err = dbManager.GetLatestChatSummary(chatID, 0)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Empty(t, summary.Content)
+	})
+}
+
+func TestChatMemories(t *testing.T) {
+	dbManager := setupTestDB(t)
+	chatIDs, err := faker.RandomInt(-1000000, 1000000, 2)
+	require.NoError(t, err)
+	chatID := int64(chatIDs[0])
+	otherChatID := int64(chatIDs[1])
+	content := faker.Sentence()
+
+	var memoryID uint
+	t.Run("Add memory", func(t *testing.T) {
+		// Act
+		memoryID, err = dbManager.AddChatMemory(chatID, faker.RandomUnixTime(), content)
+
+		// Assert
+		require.NoError(t, err)
+		assert.NotZero(t, memoryID)
+	})
+
+	t.Run("Get memories", func(t *testing.T) {
+		// Act
+		var memories []ChatMemory
+		memories, err = dbManager.GetChatMemories(chatID)
+
+		// Assert
+		require.NoError(t, err)
+		require.Len(t, memories, 1)
+		assert.Equal(t, content, memories[0].Content)
+	})
+
+	t.Run("Delete memory of another chat", func(t *testing.T) {
+		// Act
+		err = dbManager.DeleteChatMemory(otherChatID, memoryID)
+
+		// Assert
+		require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+
+	t.Run("Delete memory", func(t *testing.T) {
+		// Act
+		err = dbManager.DeleteChatMemory(chatID, memoryID)
+		require.NoError(t, err)
+
+		var memories []ChatMemory
+		memories, err = dbManager.GetChatMemories(chatID)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Empty(t, memories)
+	})
+}
+
+func TestScheduledPrompts(t *testing.T) {
+	dbManager := setupTestDB(t)
+	chatIDs, err := faker.RandomInt(-1000000, 1000000, 2)
+	require.NoError(t, err)
+	chatID := int64(chatIDs[0])
+	otherChatID := int64(chatIDs[1])
+	prompt := faker.Sentence()
+
+	var promptID uint
+	t.Run("Add scheduled prompt", func(t *testing.T) {
+		// Act
+		promptID, err = dbManager.AddScheduledPrompt(ScheduledPrompt{
+			ChatID: chatID,
+			UserID: faker.RandomUnixTime(),
+			Cron:   "0 8 * * *",
+			Prompt: prompt,
+			Name:   "morning",
+		})
+
+		// Assert
+		require.NoError(t, err)
+		assert.NotZero(t, promptID)
+	})
+
+	t.Run("Get scheduled prompts", func(t *testing.T) {
+		// Act
+		err = dbManager.SetScheduledPromptLastRun(promptID, time.Now())
+		require.NoError(t, err)
+
+		var prompts, allPrompts []ScheduledPrompt
+		prompts, err = dbManager.GetScheduledPrompts(chatID)
+		require.NoError(t, err)
+		allPrompts, err = dbManager.GetAllScheduledPrompts()
+
+		// Assert
+		require.NoError(t, err)
+		require.Len(t, prompts, 1)
+		assert.Equal(t, prompt, prompts[0].Prompt)
+		assert.Equal(t, "0 8 * * *", prompts[0].Cron)
+		assert.Equal(t, "morning", prompts[0].Name)
+		assert.WithinDuration(t, time.Now(), prompts[0].LastRunAt, time.Minute)
+		assert.Contains(t, allPrompts, prompts[0])
+	})
+
+	t.Run("Delete scheduled prompt of another chat", func(t *testing.T) {
+		// Act
+		err = dbManager.DeleteScheduledPrompt(otherChatID, promptID)
+
+		// Assert
+		require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+
+	t.Run("Delete scheduled prompt", func(t *testing.T) {
+		// Act
+		err = dbManager.DeleteScheduledPrompt(chatID, promptID)
+		require.NoError(t, err)
+
+		var prompts []ScheduledPrompt
+		prompts, err = dbManager.GetScheduledPrompts(chatID)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Empty(t, prompts)
+	})
+}
+
+func TestDocumentChunks(t *testing.T) {
+	dbManager := setupTestDB(t)
+	chatIDs, err := faker.RandomInt(-1000000, 1000000, 1)
+	require.NoError(t, err)
+	chatID := int64(chatIDs[0])
+	documentName := faker.Word() + ".txt"
+
+	t.Run("Store document chunks", func(t *testing.T) {
+		// Arrange
+		chunks := []DocumentChunk{
+			{Content: faker.Sentence(), Embedding: []byte{1, 2, 3, 4}},
+			{Content: faker.Sentence(), Embedding: []byte{5, 6, 7, 8}},
+		}
+
+		// Act
+		err = dbManager.StoreDocumentChunks(chatID, documentName, chunks)
+		require.NoError(t, err)
+
+		var stored []DocumentChunk
+		stored, err = dbManager.GetDocumentChunks(chatID)
+
+		// Assert
+		require.NoError(t, err)
+		require.Len(t, stored, 2)
+		assert.Equal(t, documentName, stored[0].DocumentName)
+		assert.Equal(t, 1, stored[1].ChunkIndex)
+		assert.Equal(t, []byte{5, 6, 7, 8}, stored[1].Embedding)
+	})
+
+	t.Run("Re-ingest replaces chunks", func(t *testing.T) {
+		// Act
+		err = dbManager.StoreDocumentChunks(chatID, documentName, []DocumentChunk{{Content: faker.Sentence()}})
+		require.NoError(t, err)
+
+		var stored []DocumentChunk
+		stored, err = dbManager.GetDocumentChunks(chatID)
+		require.NoError(t, err)
+
+		var documents []string
+		documents, err = dbManager.ListDocuments(chatID)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Len(t, stored, 1)
+		assert.Equal(t, []string{documentName}, documents)
+	})
+
+	t.Run("Delete document", func(t *testing.T) {
+		// Act
+		err = dbManager.DeleteDocument(chatID, documentName)
+		require.NoError(t, err)
+
+		// Assert
+		err = dbManager.DeleteDocument(chatID, documentName)
+		require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+}
+
+func TestMessageEmbeddings(t *testing.T) {
+	dbManager := setupTestDB(t)
+	chatIDs, err := faker.RandomInt(-1000000, 1000000, 1)
+	require.NoError(t, err)
+	chatID := int64(chatIDs[0])
+	model := faker.Word()
+
+	var messageID uint
+	messageID, err = dbManager.StoreMessage(
+		chatID, "", "user", ContentTypeText, 1, "user", "First", "", faker.Sentence(), 100, 0, 0,
+	)
+	require.NoError(t, err)
+
+	t.Run("Store message embeddings", func(t *testing.T) {
+		// Act
+		err = dbManager.StoreMessageEmbeddings([]MessageEmbedding{
+			{MessageID: messageID, ChatID: chatID, Model: model, Embedding: []byte{1, 2, 3, 4}},
+		})
+		require.NoError(t, err)
+		err = dbManager.StoreMessageEmbeddings([]MessageEmbedding{
+			{MessageID: messageID, ChatID: chatID, Model: model, Embedding: []byte{5, 6, 7, 8}},
+		})
+		require.NoError(t, err)
+
+		var embeddings, otherModel map[uint][]byte
+		embeddings, err = dbManager.GetMessageEmbeddings([]uint{messageID, messageID + 1}, model)
+		require.NoError(t, err)
+		otherModel, err = dbManager.GetMessageEmbeddings([]uint{messageID}, model+"-other")
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, map[uint][]byte{messageID: {5, 6, 7, 8}}, embeddings)
+		assert.Empty(t, otherModel)
+	})
+
+	t.Run("Edit deletes the embedding", func(t *testing.T) {
+		// Act
+		err = dbManager.UpdateMessageContent(messageID, faker.Sentence())
+		require.NoError(t, err)
+
+		var embeddings map[uint][]byte
+		embeddings, err = dbManager.GetMessageEmbeddings([]uint{messageID}, model)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Empty(t, embeddings)
+	})
+
+	t.Run("Clear deletes the embeddings", func(t *testing.T) {
+		// Arrange
+		err = dbManager.StoreMessageEmbeddings([]MessageEmbedding{
+			{MessageID: messageID, ChatID: chatID, Model: model, Embedding: []byte{1, 2, 3, 4}},
+		})
+		require.NoError(t, err)
+
+		// Act
+		err = dbManager.ClearMessages(chatID)
+		require.NoError(t, err)
+
+		var embeddings map[uint][]byte
+		embeddings, err = dbManager.GetMessageEmbeddings([]uint{messageID}, model)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Empty(t, embeddings)
+	})
+}
+
+func TestChatState(t *testing.T) {
+	dbManager := setupTestDB(t)
+	chatIDs, err := faker.RandomInt(-1000000, 1000000, 1)
+	require.NoError(t, err)
+	chatID := int64(chatIDs[0])
+
+	t.Run("Get missing state", func(t *testing.T) {
+		// Act
+		var state ChatState
+		state, err = dbManager.GetChatState(chatID)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Zero(t, state.LastMessageID)
+	})
+
+	t.Run("Update state", func(t *testing.T) {
+		// Act
+		err = dbManager.UpdateChatState(chatID, faker.Sentence(), 10, 1000)
+		require.NoError(t, err)
+		err = dbManager.UpdateChatState(chatID, faker.Sentence(), 11, 1001)
+		require.NoError(t, err)
+
+		var state ChatState
+		state, err = dbManager.GetChatState(chatID)
+		require.NoError(t, err)
+
+		var lastUpdateID int
+		lastUpdateID, err = dbManager.GetLastUpdateID()
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, 11, state.LastMessageID)
+		assert.Equal(t, 1001, state.LastUpdateID)
+		assert.GreaterOrEqual(t, lastUpdateID, 1001)
+		assert.WithinDuration(t, time.Now(), state.UpdatedAt, time.Minute)
+	})
+}
+
+func TestPendingGenerations(t *testing.T) {
+	dbManager := setupTestDB(t)
+	chatIDs, err := faker.RandomInt(-1000000, 1000000, 1)
+	require.NoError(t, err)
+	chatID := int64(chatIDs[0])
+
+	// pendingIDs returns the pending Telegram message IDs of the test chat
+	pendingIDs := func() []int {
+		pending, getErr := dbManager.GetPendingGenerations()
+		require.NoError(t, getErr)
+		var ids []int
+		for _, generation := range pending {
+			if generation.ChatID == chatID {
+				ids = append(ids, generation.TelegramMessageID)
+			}
+		}
+		return ids
+	}
+
+	t.Run("Add pending generations", func(t *testing.T) {
+		// Act
+		err = dbManager.AddPendingGeneration(chatID, "group", 10)
+		require.NoError(t, err)
+		err = dbManager.AddPendingGeneration(chatID, "group", 11)
+		require.NoError(t, err)
+		err = dbManager.AddPendingGeneration(chatID, "group", 10)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, []int{10, 11}, pendingIDs())
+	})
+
+	t.Run("Delete pending generation", func(t *testing.T) {
+		// Act
+		err = dbManager.DeletePendingGeneration(chatID, 10)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, []int{11}, pendingIDs())
+	})
+}
+
+func TestUsage(t *testing.T) {
+	dbManager := setupTestDB(t)
+	chatIDs, err := faker.RandomInt(-1000000, 1000000, 2)
+	require.NoError(t, err)
+	chatID := int64(chatIDs[0])
+	otherChatID := int64(chatIDs[1])
+	date := time.Now().UTC().Format(time.DateOnly)
+	userID := faker.RandomUnixTime()
+	model := faker.Word()
+
+	t.Run("Record usage", func(t *testing.T) {
+		// Act
+		err = dbManager.RecordUsage(date, chatID, userID, model, 100, 20)
+		require.NoError(t, err)
+		err = dbManager.RecordUsage(date, chatID, userID, model, 50, 10)
+		require.NoError(t, err)
+		err = dbManager.RecordUsage(date, otherChatID, userID, model, 7, 3)
+
+		// Assert
+		assert.NoError(t, err)
+	})
+
+	t.Run("Get chat usage", func(t *testing.T) {
+		// Act
+		var usages []Usage
+		usages, err = dbManager.GetChatUsage(chatID, date)
+
+		// Assert
+		require.NoError(t, err)
+		require.Len(t, usages, 1)
+		assert.Equal(t, int64(150), usages[0].PromptTokens)
+		assert.Equal(t, int64(30), usages[0].CompletionTokens)
+	})
+
+	t.Run("Get chat usage since date", func(t *testing.T) {
+		// Arrange
+		earlier := time.Now().UTC().AddDate(0, 0, -10).Format(time.DateOnly)
+		older := time.Now().UTC().AddDate(0, 0, -40).Format(time.DateOnly)
+		err = dbManager.RecordUsage(earlier, chatID, userID, model, 5, 5)
+		require.NoError(t, err)
+		err = dbManager.RecordUsage(older, chatID, userID, model, 1, 1)
+		require.NoError(t, err)
+
+		// Act
+		var usages []Usage
+		usages, err = dbManager.GetChatUsageSince(chatID, time.Now().UTC().AddDate(0, 0, -30).Format(time.DateOnly))
+
+		// Assert
+		require.NoError(t, err)
+		require.Len(t, usages, 2)
+		assert.Equal(t, earlier, usages[0].Date)
+		assert.Equal(t, date, usages[1].Date)
+	})
+
+	t.Run("Get global usage", func(t *testing.T) {
+		// Act
+		var usages []Usage
+		usages, err = dbManager.GetGlobalUsage(date)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Len(t, usages, 2)
+	})
+
+	t.Run("Get global usage since date", func(t *testing.T) {
+		// Act
+		var usages []Usage
+		usages, err = dbManager.GetGlobalUsageSince(time.Now().UTC().AddDate(0, 0, -30).Format(time.DateOnly))
+
+		// Assert
+		require.NoError(t, err)
+		require.Len(t, usages, 3)
+		assert.Equal(t, time.Now().UTC().AddDate(0, 0, -10).Format(time.DateOnly), usages[0].Date)
+	})
+}
+
+func TestUserAPIKey(t *testing.T) {
+	dbManager := setupTestDB(t)
+	userID := faker.RandomUnixTime()
+	apiKey := "sk-" + faker.Password()
+
+	t.Run("Encryption not configured", func(t *testing.T) {
+		// Act
+		err := dbManager.SetUserAPIKey(userID, apiKey)
+
+		// Assert
+		assert.ErrorIs(t, err, ErrEncryptionNotConfigured)
+	})
+
+	cipher, err := secrets.NewCipher(make([]byte, secrets.KeySize))
+	require.NoError(t, err)
+	dbManager.SetCipher(cipher)
+
+	t.Run("Set and get API key", func(t *testing.T) {
+		// Act
+		err = dbManager.SetUserAPIKey(userID, "sk-old")
+		require.NoError(t, err)
+		err = dbManager.SetUserAPIKey(userID, apiKey)
+		require.NoError(t, err)
+		var storedKey string
+		storedKey, err = dbManager.GetUserAPIKey(userID)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, apiKey, storedKey)
+
+		var row UserAPIKey
+		require.NoError(t, dbManager.db.Where("user_id = ?", userID).First(&row).Error)
+		assert.NotContains(t, string(row.EncryptedKey), apiKey)
+	})
+
+	t.Run("Delete API key", func(t *testing.T) {
+		// Act
+		var deleted bool
+		deleted, err = dbManager.DeleteUserAPIKey(userID)
+		require.NoError(t, err)
+		var storedKey string
+		storedKey, err = dbManager.GetUserAPIKey(userID)
+
+		// Assert
+		require.NoError(t, err)
+		assert.True(t, deleted)
+		assert.Empty(t, storedKey)
+
+		deleted, err = dbManager.DeleteUserAPIKey(userID)
+		require.NoError(t, err)
+		assert.False(t, deleted)
+	})
+}
+
+func TestDatabaseInfo(t *testing.T) {
+	dbManager := setupTestDB(t)
+	chatIDs, err := faker.RandomInt(-1000000, 1000000, 1)
+	require.NoError(t, err)
+	chatID := int64(chatIDs[0])
+	chatTitle := faker.Sentence()
+
+	t.Run("Get chat row counts", func(t *testing.T) {
+		// Arrange
+		for range 3 {
+			_, err = dbManager.StoreMessage(
+				chatID, chatTitle, "user", ContentTypeText, 1, "user", "First", "Last", faker.Sentence(), 0, 0, 0,
+			)
+			require.NoError(t, err)
+		}
+		_, err = dbManager.AddChatMemory(chatID, 1, faker.Sentence())
+		require.NoError(t, err)
+
+		// Act
+		var counts []ChatRowCounts
+		counts, err = dbManager.GetChatRowCounts(time.Now().Add(time.Hour))
+		require.NoError(t, err)
+
+		// Assert
+		index := slices.IndexFunc(counts, func(c ChatRowCounts) bool { return c.ChatID == chatID })
+		require.GreaterOrEqual(t, index, 0)
+		assert.Equal(t, chatTitle, counts[index].ChatTitle)
+		assert.Equal(t, int64(3), counts[index].Messages)
+		assert.Equal(t, int64(3), counts[index].StaleMessages)
+		assert.Equal(t, int64(1), counts[index].ChatMemories)
+		assert.Equal(t, int64(4), counts[index].Total())
+
+		counts, err = dbManager.GetChatRowCounts(time.Now().Add(-time.Hour))
+		require.NoError(t, err)
+		index = slices.IndexFunc(counts, func(c ChatRowCounts) bool { return c.ChatID == chatID })
+		require.GreaterOrEqual(t, index, 0)
+		assert.Zero(t, counts[index].StaleMessages)
+	})
+
+	t.Run("Get database size", func(t *testing.T) {
+		// Act
+		var size DatabaseSize
+		size, err = dbManager.GetDatabaseSize()
+
+		// Assert
+		require.NoError(t, err)
+		assert.Positive(t, size.Size)
+		assert.GreaterOrEqual(t, size.Size, size.FreeSize)
+	})
+}
+
+func TestConcurrentWrites(t *testing.T) {
+	// Arrange
+	dbManager, err := NewDatabaseManager(filepath.Join(t.TempDir(), "tellama.db"), SQLiteConfig{})
+	require.NoError(t, err)
+	defer dbManager.Close()
+	chatID := faker.RandomUnixTime()
+
+	// Act
+	var wg sync.WaitGroup
+	errs := make(chan error, 50)
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, storeErr := dbManager.StoreMessage(
+				chatID, "", "user", ContentTypeText, 1, "user", "First", "Last", faker.Sentence(), 0, 0, 0,
+			)
+			errs <- storeErr
+			_, _ = dbManager.GetMessages(chatID, 0, 10)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	// Assert
+	for storeErr := range errs {
+		require.NoError(t, storeErr)
+	}
+	var count int64
+	count, err = dbManager.CountMessages(chatID, 0, "user")
+	require.NoError(t, err)
+	assert.Equal(t, int64(50), count)
+}
+
+func TestObserveQueries(t *testing.T) {
+	// Arrange
+	dbManager := setupTestDB(t)
+	var queries atomic.Int64
+	dbManager.ObserveQueries(func(time.Duration) { queries.Add(1) })
+	chatID := faker.RandomUnixTime()
+
+	// Act
+	_, err := dbManager.StoreMessage(
+		chatID, "", "user", ContentTypeText, 1, "user", "First", "Last", faker.Sentence(), 0, 0, 0,
+	)
+	require.NoError(t, err)
+	_, err = dbManager.GetMessages(chatID, 0, 10)
+	require.NoError(t, err)
+
+	// Assert
+	assert.GreaterOrEqual(t, queries.Load(), int64(2))
+}
+
+func TestSQLiteDSN(t *testing.T) {
+	// Arrange
+	defaults := SQLiteConfig{}
+	configured := SQLiteConfig{JournalMode: "delete", Synchronous: "full", BusyTimeout: time.Second}
+
+	// Act & Assert
+	assert.Equal(t, "tellama.db?_busy_timeout=5000&_journal_mode=WAL&_synchronous=NORMAL", defaults.dsn("tellama.db"))
+	assert.Equal(t,
+		"file::memory:?cache=shared&_busy_timeout=1000&_journal_mode=DELETE&_synchronous=FULL",
+		configured.dsn("file::memory:?cache=shared"),
+	)
+}
+
+func TestSQLiteConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  SQLiteConfig
+		wantErr bool
+	}{
+		{name: "Defaults", config: SQLiteConfig{}},
+		{name: "Configured", config: SQLiteConfig{JournalMode: "WAL", Synchronous: "Normal", MaxOpenConns: 4}},
+		{name: "Invalid journal mode", config: SQLiteConfig{JournalMode: "fast"}, wantErr: true},
+		{name: "Invalid synchronous", config: SQLiteConfig{Synchronous: "sometimes"}, wantErr: true},
+		{name: "Negative busy timeout", config: SQLiteConfig{BusyTimeout: -time.Second}, wantErr: true},
+		{name: "Negative connection limit", config: SQLiteConfig{MaxIdleConns: -1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			err := tt.config.Validate()
+
+			// Assert
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestNewDatabaseManager_SQLiteSettings(t *testing.T) {
+	// Arrange
+	dbPath := filepath.Join(t.TempDir(), "tellama.db")
+
+	// Act
+	dbManager, err := NewDatabaseManager(dbPath, SQLiteConfig{MaxOpenConns: 3})
+	require.NoError(t, err)
+	defer dbManager.Close()
+
+	// Assert
+	var journalMode string
+	require.NoError(t, dbManager.db.Raw("PRAGMA journal_mode").Scan(&journalMode).Error)
+	assert.Equal(t, "wal", journalMode)
+	var synchronous int
+	require.NoError(t, dbManager.db.Raw("PRAGMA synchronous").Scan(&synchronous).Error)
+	assert.Equal(t, 1, synchronous)
+	sqlDB, err := dbManager.db.DB()
+	require.NoError(t, err)
+	assert.Equal(t, 3, sqlDB.Stats().MaxOpenConnections)
+
+	_, err = NewDatabaseManager(dbPath, SQLiteConfig{JournalMode: "fast"})
+	assert.Error(t, err)
+}
+
+func TestStoreMessages(t *testing.T) {
+	dbManager := setupTestDB(t)
+	chatIDs, err := faker.RandomInt(-1000000, 1000000, 1)
+	require.NoError(t, err)
+	chatID := int64(chatIDs[0])
+
+	t.Run("Ping", func(t *testing.T) {
+		// Act
+		err = dbManager.Ping()
+
+		// Assert
+		assert.NoError(t, err)
+	})
+
+	t.Run("Store queued messages", func(t *testing.T) {
+		// Arrange
+		queuedAt := time.Now().Add(-time.Hour).UTC()
+		queued := []Message{
+			{Timestamp: queuedAt, ChatID: chatID, Role: "user", Content: "first", ThreadID: 3},
+			{Timestamp: queuedAt.Add(time.Second), ChatID: chatID, Role: "assistant", Content: "second", ThreadID: 3},
+		}
+
+		// Act
+		err = dbManager.StoreMessages(queued)
+		require.NoError(t, err)
+
+		var messages []Message
+		messages, err = dbManager.GetMessages(chatID, 3, 10)
+
+		// Assert
+		require.NoError(t, err)
+		require.Len(t, messages, 2)
+		assert.Equal(t, "first", messages[0].Content)
+		assert.Equal(t, "second", messages[1].Content)
+		assert.WithinDuration(t, queuedAt, messages[0].Timestamp, time.Second)
+	})
+
+	t.Run("Store no messages", func(t *testing.T) {
+		// Act
+		err = dbManager.StoreMessages(nil)
+
+		// Assert
+		assert.NoError(t, err)
+	})
+}
+
+func TestMessageRedaction(t *testing.T) {
+	dbManager := setupTestDB(t)
+	redactor, err := redact.New(&redact.Config{Enabled: true, Emails: true})
+	require.NoError(t, err)
+	dbManager.SetRedactor(redactor)
+	chatIDs, err := faker.RandomInt(-1000000, 1000000, 1)
+	require.NoError(t, err)
+	chatID := int64(chatIDs[0])
+	var messageID uint
+
+	t.Run("Store message", func(t *testing.T) {
+		// Act
+		messageID, err = dbManager.StoreMessage(
+			chatID, "", "user", ContentTypeText, 1, "", "", "", "Write to jane@example.com", 1, 0, 0,
+		)
+		require.NoError(t, err)
+		var message Message
+		message, err = dbManager.GetMessage(messageID)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, "Write to [EMAIL]", message.Content)
+	})
+
+	t.Run("Find message by its original content", func(t *testing.T) {
+		// Act
+		var message Message
+		message, err = dbManager.FindMessage(chatID, 1, "Write to jane@example.com")
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, messageID, message.ID)
+	})
+
+	t.Run("Update message content", func(t *testing.T) {
+		// Act
+		err = dbManager.UpdateMessageContent(messageID, "Write to john@example.com instead")
+		require.NoError(t, err)
+		var message Message
+		message, err = dbManager.GetMessage(messageID)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, "Write to [EMAIL] instead", message.Content)
+	})
+
+	t.Run("Store queued messages", func(t *testing.T) {
+		// Act
+		err = dbManager.StoreMessages([]Message{{ChatID: chatID, Role: "user", Content: "jane@example.com"}})
+		require.NoError(t, err)
+		var messages []Message
+		messages, err = dbManager.GetMessages(chatID, 0, 1)
+
+		// Assert
+		require.NoError(t, err)
+		require.Len(t, messages, 1)
+		assert.Equal(t, "[EMAIL]", messages[0].Content)
+	})
+}
@@ -0,0 +1,171 @@
+// Package dbtest provides deterministic fixtures for tests that need a database.Manager.
+package dbtest
+
+import (
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/k4yt3x/tellama/internal/database"
+)
+
+const (
+	// FirstChatID is the ID assigned to the first chat created by a Builder.
+	FirstChatID int64 = -1000000000001
+
+	// FirstUserID is the ID assigned to the first user created by a Builder.
+	FirstUserID int64 = 100000001
+
+	// BotUserID is the user ID used for assistant messages.
+	BotUserID int64 = 900000001
+)
+
+// NewTestManager creates a database manager backed by an in-memory SQLite database
+// that is private to the test and closed when the test finishes.
+func NewTestManager(t testing.TB) *database.Manager {
+	t.Helper()
+
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", url.PathEscape(t.Name()))
+	dm, err := database.NewDatabaseManager(dsn, database.SQLiteConfig{})
+	if err != nil {
+		t.Fatalf("failed to create test database manager: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if err := dm.Close(); err != nil {
+			t.Errorf("failed to close test database manager: %v", err)
+		}
+	})
+	return dm
+}
+
+// User describes a seeded Telegram user.
+type User struct {
+	ID        int64
+	Username  string
+	FirstName string
+	LastName  string
+}
+
+// Builder seeds a database with deterministic chats, users, messages, and overrides.
+// IDs are assigned sequentially so that repeated runs produce identical data.
+type Builder struct {
+	t          testing.TB
+	dm         *database.Manager
+	nextChatID int64
+	nextUserID int64
+}
+
+// NewBuilder creates a fixture builder for the given database manager.
+func NewBuilder(t testing.TB, dm *database.Manager) *Builder {
+	t.Helper()
+	return &Builder{
+		t:          t,
+		dm:         dm,
+		nextChatID: FirstChatID,
+		nextUserID: FirstUserID,
+	}
+}
+
+// Manager returns the database manager the builder seeds.
+func (b *Builder) Manager() *database.Manager {
+	return b.dm
+}
+
+// Chat allocates a new chat ID without trusting the chat.
+func (b *Builder) Chat() int64 {
+	chatID := b.nextChatID
+	b.nextChatID--
+	return chatID
+}
+
+// TrustedChat allocates a new chat ID and inserts it into the trusted chats table.
+func (b *Builder) TrustedChat(title string) int64 {
+	b.t.Helper()
+
+	chatID := b.Chat()
+	if err := b.dm.TrustChat(chatID, title); err != nil {
+		b.t.Fatalf("failed to seed trusted chat: %v", err)
+	}
+	return chatID
+}
+
+// User allocates a new user with deterministic names.
+func (b *Builder) User() User {
+	n := b.nextUserID - FirstUserID + 1
+	user := User{
+		ID:        b.nextUserID,
+		Username:  fmt.Sprintf("user%d", n),
+		FirstName: fmt.Sprintf("First%d", n),
+		LastName:  fmt.Sprintf("Last%d", n),
+	}
+	b.nextUserID++
+	return user
+}
+
+// Message stores a single message in a chat.
+func (b *Builder) Message(chatID int64, role string, user User, content string) {
+	b.t.Helper()
+	b.ThreadMessage(chatID, 0, role, user, content)
+}
+
+// ThreadMessage stores a single message in a forum topic of a chat.
+func (b *Builder) ThreadMessage(chatID int64, threadID int, role string, user User, content string) {
+	b.t.Helper()
+
+	_, err := b.dm.StoreMessage(
+		chatID,
+		fmt.Sprintf("Chat %d", chatID),
+		role,
+		database.ContentTypeText,
+		user.ID,
+		user.Username,
+		user.FirstName,
+		user.LastName,
+		content,
+		0,
+		0,
+		threadID,
+	)
+	if err != nil {
+		b.t.Fatalf("failed to seed message: %v", err)
+	}
+}
+
+// Conversation stores count messages alternating between the user and the assistant,
+// starting with the user. The contents are "message 1", "message 2", and so on.
+func (b *Builder) Conversation(chatID int64, user User, count int) {
+	b.t.Helper()
+
+	bot := User{ID: BotUserID, Username: "tellama_bot", FirstName: "Tellama"}
+	for i := range count {
+		content := fmt.Sprintf("message %d", i+1)
+		if i%2 == 0 {
+			b.Message(chatID, "user", user, content)
+		} else {
+			b.Message(chatID, "assistant", bot, content)
+		}
+	}
+}
+
+// ChatOverride stores the connection settings, model, options, system prompt, and trigger mode
+// of the override for the chat.
+func (b *Builder) ChatOverride(chatID int64, override database.ChatOverride) {
+	b.t.Helper()
+
+	err := b.dm.SetChatOverride(
+		chatID,
+		override.ChatTitle,
+		override.BaseURL,
+		override.APIKey,
+		override.Model,
+		override.Options,
+		override.SystemPrompt,
+	)
+	if err == nil && override.Trigger != "" {
+		err = b.dm.SetChatTrigger(chatID, override.ChatTitle, override.Trigger)
+	}
+	if err != nil {
+		b.t.Fatalf("failed to seed chat override: %v", err)
+	}
+}
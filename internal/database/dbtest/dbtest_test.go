@@ -0,0 +1,72 @@
+package dbtest_test
+
+import (
+	"testing"
+
+	"github.com/k4yt3x/tellama/internal/database"
+	"github.com/k4yt3x/tellama/internal/database/dbtest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTestManager_Isolated(t *testing.T) {
+	// Arrange
+	var chatID int64
+
+	t.Run("Seed", func(t *testing.T) {
+		builder := dbtest.NewBuilder(t, dbtest.NewTestManager(t))
+		chatID = builder.TrustedChat("Seeded Chat")
+		require.True(t, builder.Manager().IsChatTrusted(chatID))
+	})
+
+	t.Run("Fresh", func(t *testing.T) {
+		// Act
+		dm := dbtest.NewTestManager(t)
+
+		// Assert
+		assert.False(t, dm.IsChatTrusted(chatID))
+	})
+}
+
+func TestBuilder_Deterministic(t *testing.T) {
+	// Arrange
+	builder := dbtest.NewBuilder(t, dbtest.NewTestManager(t))
+
+	// Act
+	chatID := builder.TrustedChat("Test Chat")
+	otherChatID := builder.Chat()
+	user := builder.User()
+	builder.Conversation(chatID, user, 3)
+	builder.ChatOverride(chatID, database.ChatOverride{Model: "test-model", Trigger: "prefix"})
+	builder.ThreadMessage(chatID, 7, "user", user, "topic message")
+
+	// Assert
+	assert.Equal(t, dbtest.FirstChatID, chatID)
+	assert.Equal(t, dbtest.FirstChatID-1, otherChatID)
+	assert.Equal(t, dbtest.User{
+		ID:        dbtest.FirstUserID,
+		Username:  "user1",
+		FirstName: "First1",
+		LastName:  "Last1",
+	}, user)
+
+	messages, err := builder.Manager().GetMessages(chatID, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, messages, 3)
+	assert.Equal(t, "user", messages[0].Role)
+	assert.Equal(t, "message 1", messages[0].Content)
+	assert.Equal(t, "assistant", messages[1].Role)
+	assert.Equal(t, dbtest.BotUserID, messages[1].UserID)
+	assert.Equal(t, "message 3", messages[2].Content)
+
+	chatOverride, err := builder.Manager().GetChatOverride(chatID)
+	require.NoError(t, err)
+	assert.Equal(t, "test-model", chatOverride.Model)
+	assert.Equal(t, "prefix", chatOverride.Trigger)
+
+	topic, err := builder.Manager().GetMessages(chatID, 7, 10)
+	require.NoError(t, err)
+	require.Len(t, topic, 1)
+	assert.Equal(t, "topic message", topic[0].Content)
+}
@@ -0,0 +1,84 @@
+package database
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// encryptedValuePrefix marks column values encrypted by the cipher of the manager.
+// Values without it are stored in plain text, as they were before encryption was configured.
+const encryptedValuePrefix = "enc:"
+
+// ErrEncryptedValue is returned when reading an encrypted value without an encryption key.
+var ErrEncryptedValue = errors.New("value is encrypted but no database encryption key is configured")
+
+// encryptValue encrypts a value for storage in a text column if an encryption key is configured.
+func (dm *Manager) encryptValue(value string) (string, error) {
+	if dm.cipher == nil || value == "" {
+		return value, nil
+	}
+	encrypted, err := dm.cipher.Encrypt(value)
+	if err != nil {
+		return "", err
+	}
+	return encryptedValuePrefix + base64.StdEncoding.EncodeToString(encrypted), nil
+}
+
+// decryptValue returns the plain text of a value stored by encryptValue.
+func (dm *Manager) decryptValue(value string) (string, error) {
+	encoded, encrypted := strings.CutPrefix(value, encryptedValuePrefix)
+	if !encrypted {
+		return value, nil
+	}
+	if dm.cipher == nil {
+		return "", ErrEncryptedValue
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", err
+	}
+	return dm.cipher.Decrypt(ciphertext)
+}
+
+// EncryptChatAPIKeys encrypts the API keys of chat overrides stored in plain text
+// and returns the number of keys encrypted. It does nothing without an encryption key.
+func (dm *Manager) EncryptChatAPIKeys() (int, error) {
+	if dm.cipher == nil {
+		return 0, nil
+	}
+
+	dm.writeMu.Lock()
+	defer dm.writeMu.Unlock()
+
+	var chatOverrides []ChatOverride
+	err := dm.db.
+		Where("api_key != '' AND api_key NOT LIKE ?", encryptedValuePrefix+"%").
+		Find(&chatOverrides).Error
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range chatOverrides {
+		chatOverrides[i].APIKey, err = dm.encryptValue(chatOverrides[i].APIKey)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	err = dm.db.Transaction(func(tx *gorm.DB) error {
+		for _, chatOverride := range chatOverrides {
+			result := tx.Model(&ChatOverride{}).Where("id = ?", chatOverride.ID).Update("api_key", chatOverride.APIKey)
+			if result.Error != nil {
+				return result.Error
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(chatOverrides), nil
+}
@@ -0,0 +1,109 @@
+package database //nolint:testpackage // Unit tests are in the same package
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/k4yt3x/tellama/internal/secrets"
+
+	"github.com/go-faker/faker/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatAPIKeyEncryption(t *testing.T) {
+	// Arrange
+	dbManager, err := NewDatabaseManager(filepath.Join(t.TempDir(), "tellama.db"), SQLiteConfig{})
+	require.NoError(t, err)
+	defer dbManager.Close()
+	chatIDs, err := faker.RandomInt(-1000000, 1000000, 2)
+	require.NoError(t, err)
+	chatID, plainChatID := int64(chatIDs[0]), int64(chatIDs[1])
+
+	// Store a key before encryption is configured
+	require.NoError(t, dbManager.SetChatOverride(plainChatID, "", "", "sk-plain", "", "", ""))
+
+	cipher, err := secrets.NewCipher(make([]byte, secrets.KeySize))
+	require.NoError(t, err)
+	dbManager.SetCipher(cipher)
+
+	storedAPIKey := func(chatID int64) string {
+		var chatOverride ChatOverride
+		require.NoError(t, dbManager.db.Where("chat_id = ?", chatID).First(&chatOverride).Error)
+		return chatOverride.APIKey
+	}
+
+	t.Run("Set and get API key", func(t *testing.T) {
+		// Act
+		err = dbManager.SetChatOverride(chatID, "", "", "sk-secret", "", "", "")
+		require.NoError(t, err)
+		var chatOverride ChatOverride
+		chatOverride, err = dbManager.GetChatOverride(chatID)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, "sk-secret", chatOverride.APIKey)
+		assert.True(t, strings.HasPrefix(storedAPIKey(chatID), encryptedValuePrefix))
+		assert.NotContains(t, storedAPIKey(chatID), "sk-secret")
+	})
+
+	t.Run("Read plain text API key", func(t *testing.T) {
+		// Act
+		var chatOverride ChatOverride
+		chatOverride, err = dbManager.GetChatOverride(plainChatID)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, "sk-plain", chatOverride.APIKey)
+	})
+
+	t.Run("Encrypt plain text API keys", func(t *testing.T) {
+		// Act
+		var encrypted int
+		encrypted, err = dbManager.EncryptChatAPIKeys()
+		require.NoError(t, err)
+		var chatOverride ChatOverride
+		chatOverride, err = dbManager.GetChatOverride(plainChatID)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, 1, encrypted)
+		assert.True(t, strings.HasPrefix(storedAPIKey(plainChatID), encryptedValuePrefix))
+		assert.Equal(t, "sk-plain", chatOverride.APIKey)
+	})
+
+	t.Run("Export encrypted API keys", func(t *testing.T) {
+		// Act
+		var export Export
+		export, err = dbManager.ExportChat(chatID, false)
+
+		// Assert
+		require.NoError(t, err)
+		require.Len(t, export.ChatOverrides, 1)
+		assert.Equal(t, storedAPIKey(chatID), export.ChatOverrides[0].APIKey)
+		assert.NotContains(t, export.ChatOverrides[0].APIKey, "sk-secret")
+	})
+
+	t.Run("Export decrypted API keys", func(t *testing.T) {
+		// Act
+		var export Export
+		export, err = dbManager.ExportChat(chatID, true)
+
+		// Assert
+		require.NoError(t, err)
+		require.Len(t, export.ChatOverrides, 1)
+		assert.Equal(t, "sk-secret", export.ChatOverrides[0].APIKey)
+	})
+
+	t.Run("Read encrypted API key without encryption key", func(t *testing.T) {
+		// Arrange
+		dbManager.SetCipher(nil)
+
+		// Act
+		_, err = dbManager.GetChatOverride(chatID)
+
+		// Assert
+		assert.ErrorIs(t, err, ErrEncryptedValue)
+	})
+}
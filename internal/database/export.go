@@ -0,0 +1,83 @@
+package database
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// importBatchSize is the number of rows inserted per statement when importing.
+const importBatchSize = 500
+
+// Export holds the rows of one or more chats that can be restored into another database.
+type Export struct {
+	TrustedChats  []TrustedChat
+	ChatOverrides []ChatOverride
+	Messages      []Message
+}
+
+// ExportChat returns the trust entry, chat override, and messages of a chat, or of all chats if chatID is zero.
+// API keys are exported as they are stored, encrypted if encryption is enabled, unless includePlaintextKeys is set.
+func (dm *Manager) ExportChat(chatID int64, includePlaintextKeys bool) (Export, error) {
+	scope := func(db *gorm.DB) *gorm.DB {
+		if chatID == 0 {
+			return db
+		}
+		return db.Where("chat_id = ?", chatID)
+	}
+
+	var export Export
+	if err := dm.db.Scopes(scope).Order("id").Find(&export.TrustedChats).Error; err != nil {
+		return Export{}, err
+	}
+	if err := dm.db.Scopes(scope).Order("id").Find(&export.ChatOverrides).Error; err != nil {
+		return Export{}, err
+	}
+	// Plain text API keys can be imported into a database with another encryption key
+	if includePlaintextKeys {
+		for i := range export.ChatOverrides {
+			apiKey, err := dm.decryptValue(export.ChatOverrides[i].APIKey)
+			if err != nil {
+				return Export{}, err
+			}
+			export.ChatOverrides[i].APIKey = apiKey
+		}
+	}
+	if err := dm.db.Scopes(scope).Order("id").Find(&export.Messages).Error; err != nil {
+		return Export{}, err
+	}
+	return export, nil
+}
+
+// ImportChat restores exported rows and returns the number of rows inserted.
+// Rows whose ID or unique keys already exist are skipped, so an export can be imported more than once.
+func (dm *Manager) ImportChat(export Export) (int64, error) {
+	dm.writeMu.Lock()
+	defer dm.writeMu.Unlock()
+
+	for i := range export.ChatOverrides {
+		if strings.HasPrefix(export.ChatOverrides[i].APIKey, encryptedValuePrefix) {
+			continue
+		}
+		apiKey, err := dm.encryptValue(export.ChatOverrides[i].APIKey)
+		if err != nil {
+			return 0, err
+		}
+		export.ChatOverrides[i].APIKey = apiKey
+	}
+
+	var inserted int64
+	err := dm.db.Transaction(func(tx *gorm.DB) error {
+		tx = tx.Clauses(clause.OnConflict{DoNothing: true})
+		for _, rows := range []any{&export.TrustedChats, &export.ChatOverrides, &export.Messages} {
+			result := tx.CreateInBatches(rows, importBatchSize)
+			if result.Error != nil {
+				return result.Error
+			}
+			inserted += result.RowsAffected
+		}
+		return nil
+	})
+	return inserted, err
+}
@@ -0,0 +1,75 @@
+package database //nolint:testpackage // Unit tests are in the same package
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/go-faker/faker/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportImportChat(t *testing.T) {
+	// Arrange
+	source := setupTestDB(t)
+	chatIDs, err := faker.RandomInt(-1000000, 1000000, 2)
+	require.NoError(t, err)
+	chatID, otherChatID := int64(chatIDs[0]), int64(chatIDs[1])
+
+	require.NoError(t, source.TrustChat(chatID, faker.Sentence()))
+	require.NoError(t, source.SetChatModel(chatID, "", "llama3.3"))
+	for _, id := range []int64{chatID, chatID, otherChatID} {
+		_, err = source.StoreMessage(id, "", "user", ContentTypeText, 1, "user", "First", "Last", faker.Sentence(), 0, 0, 0)
+		require.NoError(t, err)
+	}
+
+	target, err := NewDatabaseManager(filepath.Join(t.TempDir(), "target.db"), SQLiteConfig{})
+	require.NoError(t, err)
+	defer target.Close()
+
+	t.Run("Export a single chat", func(t *testing.T) {
+		// Act
+		var export Export
+		export, err = source.ExportChat(chatID, false)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Len(t, export.TrustedChats, 1)
+		require.Len(t, export.ChatOverrides, 1)
+		assert.Equal(t, "llama3.3", export.ChatOverrides[0].Model)
+		assert.Len(t, export.Messages, 2)
+	})
+
+	t.Run("Import is idempotent", func(t *testing.T) {
+		// Arrange
+		var export Export
+		export, err = source.ExportChat(chatID, false)
+		require.NoError(t, err)
+
+		// Act
+		var inserted, reinserted int64
+		inserted, err = target.ImportChat(export)
+		require.NoError(t, err)
+		reinserted, err = target.ImportChat(export)
+		require.NoError(t, err)
+
+		// Assert
+		assert.Equal(t, int64(4), inserted)
+		assert.Zero(t, reinserted)
+		assert.True(t, target.IsChatTrusted(chatID))
+		var messages []Message
+		messages, err = target.GetMessages(chatID, 0, 10)
+		require.NoError(t, err)
+		assert.Len(t, messages, 2)
+	})
+
+	t.Run("Import an empty export", func(t *testing.T) {
+		// Act
+		var inserted int64
+		inserted, err = target.ImportChat(Export{})
+
+		// Assert
+		require.NoError(t, err)
+		assert.Zero(t, inserted)
+	})
+}
@@ -0,0 +1,154 @@
+package database_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/k4yt3x/tellama/internal/database"
+	"github.com/k4yt3x/tellama/internal/database/dbtest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestBuilderTrustedChats(t *testing.T) {
+	builder := dbtest.NewBuilder(t, dbtest.NewTestManager(t))
+	dbManager := builder.Manager()
+
+	t.Run("Allowed chat", func(t *testing.T) {
+		// Arrange
+		chatID := builder.TrustedChat("Allowed Chat")
+
+		// Act
+		allowed := dbManager.IsChatTrusted(chatID)
+
+		// Assert
+		assert.True(t, allowed)
+	})
+
+	t.Run("Trusted chat", func(t *testing.T) {
+		// Arrange
+		chatID := builder.Chat()
+
+		// Act
+		err := dbManager.TrustChat(chatID, "Trusted Chat")
+		require.NoError(t, err)
+
+		// Assert
+		assert.True(t, dbManager.IsChatTrusted(chatID))
+	})
+
+	t.Run("List and untrust chat", func(t *testing.T) {
+		// Arrange
+		chatID := builder.TrustedChat("Listed Chat")
+
+		// Act
+		trustedChats, err := dbManager.ListTrustedChats()
+		require.NoError(t, err)
+		err = dbManager.UntrustChat(chatID)
+		require.NoError(t, err)
+
+		// Assert
+		assert.Contains(t, trustedChats, database.TrustedChat{
+			ID:        trustedChats[len(trustedChats)-1].ID,
+			ChatID:    chatID,
+			ChatTitle: "Listed Chat",
+		})
+		assert.False(t, dbManager.IsChatTrusted(chatID))
+	})
+
+	t.Run("Not allowed chat", func(t *testing.T) {
+		// Act
+		allowed := dbManager.IsChatTrusted(builder.Chat())
+
+		// Assert
+		assert.False(t, allowed)
+	})
+}
+
+func TestBuilderThreadMessages(t *testing.T) {
+	builder := dbtest.NewBuilder(t, dbtest.NewTestManager(t))
+	dbManager := builder.Manager()
+	chatID := builder.TrustedChat("Forum")
+	user := builder.User()
+	other := builder.User()
+
+	builder.ThreadMessage(chatID, 0, "user", user, "general message")
+	builder.ThreadMessage(chatID, 7, "user", user, "first topic message")
+	builder.ThreadMessage(chatID, 7, "user", user, "second topic message")
+
+	t.Run("Messages are scoped by thread", func(t *testing.T) {
+		// Act
+		general, err := dbManager.GetMessages(chatID, 0, 10)
+		require.NoError(t, err)
+		topic, err := dbManager.GetMessages(chatID, 7, 10)
+		require.NoError(t, err)
+		count, err := dbManager.CountMessages(chatID, 7, "user")
+
+		// Assert
+		require.NoError(t, err)
+		assert.Len(t, general, 1)
+		require.Len(t, topic, 2)
+		assert.Equal(t, 7, topic[0].ThreadID)
+		assert.Equal(t, int64(2), count)
+	})
+
+	t.Run("Messages since a time are scoped by thread", func(t *testing.T) {
+		// Act
+		recent, err := dbManager.GetMessagesSince(chatID, 7, time.Now().Add(-time.Hour))
+		require.NoError(t, err)
+		future, err := dbManager.GetMessagesSince(chatID, 7, time.Now().Add(time.Hour))
+
+		// Assert
+		require.NoError(t, err)
+		require.Len(t, recent, 2)
+		assert.Less(t, recent[0].ID, recent[1].ID)
+		assert.Empty(t, future)
+	})
+
+	t.Run("Messages after the last message of a user", func(t *testing.T) {
+		// Arrange
+		builder.ThreadMessage(chatID, 7, "user", other, "Did I miss anything?")
+
+		// Act
+		last, err := dbManager.GetLastUserMessage(chatID, 7, user.ID)
+		require.NoError(t, err)
+		missed, err := dbManager.GetMessagesAfter(chatID, 7, last.ID)
+		require.NoError(t, err)
+		_, err = dbManager.GetLastUserMessage(chatID, 7, builder.User().ID)
+
+		// Assert
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+		require.Len(t, missed, 1)
+		assert.Equal(t, other.ID, missed[0].UserID)
+	})
+
+	t.Run("Summaries are scoped by thread", func(t *testing.T) {
+		// Act
+		err := dbManager.StoreChatSummary(chatID, 7, "topic summary")
+		require.NoError(t, err)
+		general, err := dbManager.GetLatestChatSummary(chatID, 0)
+		require.NoError(t, err)
+		topic, err := dbManager.GetLatestChatSummary(chatID, 7)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Empty(t, general.Content)
+		assert.Equal(t, "topic summary", topic.Content)
+	})
+
+	t.Run("Clear thread messages", func(t *testing.T) {
+		// Act
+		err := dbManager.ClearThreadMessages(chatID, 7)
+		require.NoError(t, err)
+		general, err := dbManager.GetMessages(chatID, 0, 10)
+		require.NoError(t, err)
+		topic, err := dbManager.GetMessages(chatID, 7, 10)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Len(t, general, 1)
+		assert.Empty(t, topic)
+	})
+}
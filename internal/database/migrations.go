@@ -0,0 +1,345 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrIrreversibleMigration is returned when rolling back a migration that cannot be reverted.
+var ErrIrreversibleMigration = errors.New("migration cannot be rolled back")
+
+// ErrNoMigrationApplied is returned when rolling back a database that has no migrations applied.
+var ErrNoMigrationApplied = errors.New("no migrations have been applied")
+
+// Migration is a versioned change of the database schema.
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(tx *gorm.DB) error
+	// Down reverts Up, nil if the migration cannot be reverted.
+	Down func(tx *gorm.DB) error
+}
+
+// SchemaMigration records a migration applied to the database.
+type SchemaMigration struct {
+	Version     int `gorm:"primaryKey;autoIncrement:false"`
+	Description string
+	AppliedAt   time.Time
+}
+
+// MigrationStatus is a migration and when it was applied.
+type MigrationStatus struct {
+	Version     int
+	Description string
+	// AppliedAt is zero if the migration is pending.
+	AppliedAt time.Time
+}
+
+// Applied returns whether the migration has been applied.
+func (s MigrationStatus) Applied() bool {
+	return !s.AppliedAt.IsZero()
+}
+
+// schemaModels are the models whose tables make up the current schema.
+func schemaModels() []any {
+	return []any{
+		&TrustedChat{},
+		&TrustedUser{},
+		&BannedUser{},
+		&UserPreference{},
+		&ChatOverride{},
+		&Message{},
+		&ToolInvocation{},
+		&ChatSummary{},
+		&ChatMemory{},
+		&DocumentChunk{},
+		&MessageEmbedding{},
+		&ChatState{},
+		&Usage{},
+		&UserAPIKey{},
+		&PendingGeneration{},
+		&ScheduledPrompt{},
+	}
+}
+
+// migrations are the schema changes in the order they are applied.
+//
+// The first migration creates the current schema from the models. It is run on databases without
+// applied migrations, including the ones created before migrations were introduced, after which every
+// migration is recorded as applied since the models already include their changes. Schema changes must
+// therefore update the models and add a migration that makes the same change to existing databases.
+//
+//nolint:gochecknoglobals // Constant list of migrations
+var migrations = []Migration{
+	{
+		Version:     1,
+		Description: "Create the initial schema",
+		Up: func(tx *gorm.DB) error {
+			if err := withTableOptions(tx).AutoMigrate(schemaModels()...); err != nil {
+				return fmt.Errorf("failed to migrate tables: %w", err)
+			}
+
+			// MySQL searches messages without a full-text index
+			if tx.Dialector.Name() != "sqlite" {
+				return nil
+			}
+			if err := migrateMessageSearch(tx); err != nil {
+				return fmt.Errorf("failed to create message search index: %w", err)
+			}
+			return nil
+		},
+	},
+	{
+		Version:     2,
+		Description: "Add the persona of chat overrides",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().AddColumn(&ChatOverride{}, "Persona")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&ChatOverride{}, "Persona")
+		},
+	},
+	{
+		Version:     3,
+		Description: "Add the response format of chat overrides",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().AddColumn(&ChatOverride{}, "ResponseFormat")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&ChatOverride{}, "ResponseFormat")
+		},
+	},
+	{
+		Version:     4,
+		Description: "Create the table of message embeddings",
+		Up: func(tx *gorm.DB) error {
+			return withTableOptions(tx).Migrator().CreateTable(&MessageEmbedding{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&MessageEmbedding{})
+		},
+	},
+	{
+		Version:     5,
+		Description: "Add the daily token budget of chat overrides",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().AddColumn(&ChatOverride{}, "DailyTokenBudget")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&ChatOverride{}, "DailyTokenBudget")
+		},
+	},
+	{
+		Version:     6,
+		Description: "Add the new member welcome of chat overrides",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().AddColumn(&ChatOverride{}, "WelcomeMembers")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&ChatOverride{}, "WelcomeMembers")
+		},
+	},
+	{
+		Version:     7,
+		Description: "Inherit the unset boolean settings of chat overrides from the global override",
+		// False used to mean unset, so it becomes NULL to let chats turn off what the global override turns on
+		Up: func(tx *gorm.DB) error {
+			for _, column := range chatOverrideBoolColumns {
+				err := tx.Model(&ChatOverride{}).Where(column+" = ?", false).Update(column, nil).Error
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			for _, column := range chatOverrideBoolColumns {
+				err := tx.Model(&ChatOverride{}).Where(column+" IS NULL").Update(column, false).Error
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+}
+
+// chatOverrideBoolColumns are the columns of the optional boolean settings of chat overrides.
+//
+//nolint:gochecknoglobals // Constant list of columns
+var chatOverrideBoolColumns = []string{
+	"routing_disabled",
+	"memory_disabled",
+	"voice_reply",
+	"welcome_members",
+}
+
+// MigrateUp applies the pending migrations and returns the number of migrations recorded as applied.
+func (dm *Manager) MigrateUp() (int, error) {
+	dm.writeMu.Lock()
+	defer dm.writeMu.Unlock()
+	return applyMigrations(dm.db, migrations)
+}
+
+// MigrateDown rolls back the latest applied migration and returns it.
+func (dm *Manager) MigrateDown() (Migration, error) {
+	dm.writeMu.Lock()
+	defer dm.writeMu.Unlock()
+	return revertMigration(dm.db, migrations)
+}
+
+// MigrationStatus returns every migration and when it was applied, oldest first.
+func (dm *Manager) MigrationStatus() ([]MigrationStatus, error) {
+	return migrationStatus(dm.db, migrations)
+}
+
+// PendingMigrations returns the number of migrations that have not been applied.
+func (dm *Manager) PendingMigrations() (int, error) {
+	statuses, err := dm.MigrationStatus()
+	if err != nil {
+		return 0, err
+	}
+	pending := 0
+	for _, status := range statuses {
+		if !status.Applied() {
+			pending++
+		}
+	}
+	return pending, nil
+}
+
+// withTableOptions sets the options of the tables created in MySQL.
+func withTableOptions(db *gorm.DB) *gorm.DB {
+	if db.Dialector.Name() == "mysql" {
+		return db.Set("gorm:table_options", mysqlTableOptions)
+	}
+	return db
+}
+
+// appliedMigrations returns the applied migrations indexed by version.
+func appliedMigrations(db *gorm.DB) (map[int]SchemaMigration, error) {
+	if err := withTableOptions(db).AutoMigrate(&SchemaMigration{}); err != nil {
+		return nil, fmt.Errorf("failed to create migration history: %w", err)
+	}
+
+	var records []SchemaMigration
+	if err := db.Find(&records).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[int]SchemaMigration, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
+
+func applyMigrations(db *gorm.DB, list []Migration) (int, error) {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return 0, err
+	}
+
+	// Create the current schema and skip the migrations it already includes
+	if len(applied) == 0 && len(list) > 0 {
+		if err = initSchema(db, list); err != nil {
+			return 0, fmt.Errorf("migration %d failed: %w", list[0].Version, err)
+		}
+		return len(list), nil
+	}
+
+	count := 0
+	for _, migration := range list {
+		if _, exists := applied[migration.Version]; exists {
+			continue
+		}
+		if err = applyMigration(db, migration); err != nil {
+			return count, fmt.Errorf("migration %d failed: %w", migration.Version, err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// initSchema runs the first migration and records every migration as applied.
+func initSchema(db *gorm.DB, list []Migration) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := list[0].Up(tx); err != nil {
+			return err
+		}
+		now := time.Now().UTC()
+		for _, migration := range list {
+			if err := recordMigration(tx, migration, now); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func applyMigration(db *gorm.DB, migration Migration) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := migration.Up(tx); err != nil {
+			return err
+		}
+		return recordMigration(tx, migration, time.Now().UTC())
+	})
+}
+
+func recordMigration(tx *gorm.DB, migration Migration, appliedAt time.Time) error {
+	return tx.Create(&SchemaMigration{
+		Version:     migration.Version,
+		Description: migration.Description,
+		AppliedAt:   appliedAt,
+	}).Error
+}
+
+func revertMigration(db *gorm.DB, list []Migration) (Migration, error) {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return Migration{}, err
+	}
+
+	for i := len(list) - 1; i >= 0; i-- {
+		migration := list[i]
+		if _, exists := applied[migration.Version]; !exists {
+			continue
+		}
+		if migration.Down == nil {
+			return migration, fmt.Errorf("migration %d: %w", migration.Version, ErrIrreversibleMigration)
+		}
+		if err = rollbackMigration(db, migration); err != nil {
+			return migration, fmt.Errorf("rollback of migration %d failed: %w", migration.Version, err)
+		}
+		return migration, nil
+	}
+	return Migration{}, ErrNoMigrationApplied
+}
+
+func rollbackMigration(db *gorm.DB, migration Migration) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := migration.Down(tx); err != nil {
+			return err
+		}
+		return tx.Delete(&SchemaMigration{}, migration.Version).Error
+	})
+}
+
+func migrationStatus(db *gorm.DB, list []Migration) ([]MigrationStatus, error) {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, len(list))
+	for i, migration := range list {
+		statuses[i] = MigrationStatus{
+			Version:     migration.Version,
+			Description: migration.Description,
+			AppliedAt:   applied[migration.Version].AppliedAt,
+		}
+	}
+	return statuses, nil
+}
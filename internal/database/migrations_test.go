@@ -0,0 +1,207 @@
+package database //nolint:testpackage // Unit tests are in the same package
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// testMigrations returns migrations that create a table and then add a column to it.
+func testMigrations() []Migration {
+	return []Migration{
+		{
+			Version:     1,
+			Description: "Create notes",
+			Up: func(tx *gorm.DB) error {
+				return tx.Exec("CREATE TABLE notes (id INTEGER PRIMARY KEY)").Error
+			},
+		},
+		{
+			Version:     2,
+			Description: "Add note text",
+			Up: func(tx *gorm.DB) error {
+				return tx.Exec("ALTER TABLE notes ADD COLUMN text TEXT").Error
+			},
+			Down: func(tx *gorm.DB) error {
+				return tx.Exec("ALTER TABLE notes DROP COLUMN text").Error
+			},
+		},
+	}
+}
+
+func TestMigrations(t *testing.T) {
+	// Arrange
+	dbManager, err := OpenDatabaseManager(filepath.Join(t.TempDir(), "tellama.db"), SQLiteConfig{})
+	require.NoError(t, err)
+	defer dbManager.Close()
+	db := dbManager.db
+	list := testMigrations()
+
+	// Act
+	applied, err := applyMigrations(db, list[:1])
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, 1, applied)
+	assert.True(t, db.Migrator().HasTable("notes"))
+
+	t.Run("Pending migration", func(t *testing.T) {
+		// Act
+		var statuses []MigrationStatus
+		statuses, err = migrationStatus(db, list)
+		require.NoError(t, err)
+		applied, err = applyMigrations(db, list)
+
+		// Assert
+		require.Len(t, statuses, 2)
+		assert.True(t, statuses[0].Applied())
+		assert.False(t, statuses[1].Applied())
+		require.NoError(t, err)
+		assert.Equal(t, 1, applied)
+		assert.True(t, db.Migrator().HasColumn("notes", "text"))
+	})
+
+	t.Run("Up to date", func(t *testing.T) {
+		// Act
+		applied, err = applyMigrations(db, list)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Zero(t, applied)
+	})
+
+	t.Run("Rollback", func(t *testing.T) {
+		// Act
+		var migration Migration
+		migration, err = revertMigration(db, list)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, 2, migration.Version)
+		assert.False(t, db.Migrator().HasColumn("notes", "text"))
+		var statuses []MigrationStatus
+		statuses, err = migrationStatus(db, list)
+		require.NoError(t, err)
+		assert.False(t, statuses[1].Applied())
+	})
+
+	t.Run("Irreversible migration", func(t *testing.T) {
+		// Act
+		_, err = revertMigration(db, list)
+
+		// Assert
+		require.ErrorIs(t, err, ErrIrreversibleMigration)
+		assert.True(t, db.Migrator().HasTable("notes"))
+	})
+}
+
+func TestMigrations_InitSchema(t *testing.T) {
+	// Arrange
+	dbManager, err := OpenDatabaseManager(filepath.Join(t.TempDir(), "tellama.db"), SQLiteConfig{})
+	require.NoError(t, err)
+	defer dbManager.Close()
+	list := testMigrations()
+	list[0].Up = func(tx *gorm.DB) error {
+		return tx.Exec("CREATE TABLE notes (id INTEGER PRIMARY KEY, text TEXT)").Error
+	}
+
+	// Act
+	applied, err := applyMigrations(dbManager.db, list)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, 2, applied)
+	statuses, err := migrationStatus(dbManager.db, list)
+	require.NoError(t, err)
+	for _, status := range statuses {
+		assert.True(t, status.Applied())
+	}
+}
+
+func TestNewDatabaseManager_Migrations(t *testing.T) {
+	// Arrange
+	dbPath := filepath.Join(t.TempDir(), "tellama.db")
+
+	// Act
+	dbManager, err := NewDatabaseManager(dbPath, SQLiteConfig{})
+	require.NoError(t, err)
+	defer dbManager.Close()
+	pending, err := dbManager.PendingMigrations()
+
+	// Assert
+	require.NoError(t, err)
+	assert.Zero(t, pending)
+	latest := migrations[len(migrations)-1]
+
+	t.Run("Roll back and reapply", func(t *testing.T) {
+		// Act
+		var migration Migration
+		migration, err = dbManager.MigrateDown()
+		require.NoError(t, err)
+		pending, err = dbManager.PendingMigrations()
+		require.NoError(t, err)
+		var applied int
+		applied, err = dbManager.MigrateUp()
+
+		// Assert
+		assert.Equal(t, latest.Version, migration.Version)
+		assert.Equal(t, 1, pending)
+		require.NoError(t, err)
+		assert.Equal(t, 1, applied)
+	})
+
+	t.Run("Persona column", func(t *testing.T) {
+		// Act
+		_, err = revertMigration(dbManager.db, migrations[:2])
+		require.NoError(t, err)
+		hasColumn := dbManager.db.Migrator().HasColumn(&ChatOverride{}, "Persona")
+		_, err = applyMigrations(dbManager.db, migrations)
+
+		// Assert
+		assert.False(t, hasColumn)
+		require.NoError(t, err)
+		assert.True(t, dbManager.db.Migrator().HasColumn(&ChatOverride{}, "Persona"))
+	})
+
+	t.Run("Unset boolean settings", func(t *testing.T) {
+		// Arrange
+		require.NoError(t, dbManager.SetChatMemoryDisabled(1001, "Group", true))
+		storedOverride := func() ChatOverride {
+			var chatOverride ChatOverride
+			require.NoError(t, dbManager.db.Where("chat_id = ?", 1001).First(&chatOverride).Error)
+			return chatOverride
+		}
+
+		// Act
+		_, err = dbManager.MigrateDown()
+		require.NoError(t, err)
+		reverted := storedOverride()
+		_, err = dbManager.MigrateUp()
+		require.NoError(t, err)
+		migrated := storedOverride()
+
+		// Assert
+		require.NotNil(t, reverted.VoiceReply)
+		assert.False(t, *reverted.VoiceReply)
+		assert.True(t, IsTrue(reverted.MemoryDisabled))
+		assert.Nil(t, migrated.RoutingDisabled)
+		assert.Nil(t, migrated.VoiceReply)
+		assert.Nil(t, migrated.WelcomeMembers)
+		assert.True(t, IsTrue(migrated.MemoryDisabled))
+	})
+
+	t.Run("Irreversible migration", func(t *testing.T) {
+		// Act
+		for range len(migrations) - 1 {
+			_, err = dbManager.MigrateDown()
+			require.NoError(t, err)
+		}
+		_, err = dbManager.MigrateDown()
+
+		// Assert
+		require.ErrorIs(t, err, ErrIrreversibleMigration)
+	})
+}
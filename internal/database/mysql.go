@@ -0,0 +1,108 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Driver is the database engine that stores the data.
+type Driver int
+
+const (
+	DriverSQLite Driver = iota
+	DriverMySQL
+)
+
+func (d Driver) String() string {
+	return [...]string{"sqlite", "mysql"}[d]
+}
+
+func ParseDriver(s string) (Driver, error) {
+	switch s {
+	case "sqlite":
+		return DriverSQLite, nil
+	case "mysql":
+		return DriverMySQL, nil
+	default:
+		return 0, errors.New("unknown database driver")
+	}
+}
+
+// mysqlTableOptions stores text as utf8mb4 so that messages may contain any character, including emoji.
+const mysqlTableOptions = "ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci"
+
+// NewMySQLDatabaseManager opens a MySQL or MariaDB database and applies the pending migrations.
+func NewMySQLDatabaseManager(dsn string) (*Manager, error) {
+	dm, err := OpenMySQLDatabaseManager(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = dm.MigrateUp()
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	}
+	return dm, nil
+}
+
+// OpenMySQLDatabaseManager opens a MySQL or MariaDB database without applying migrations.
+func OpenMySQLDatabaseManager(dsn string) (*Manager, error) {
+	dsn, err := mysqlDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	return &Manager{db: db, driver: DriverMySQL}, nil
+}
+
+// mysqlDSN adds the connection parameters required to store the models to a MySQL data source name.
+// Times are parsed into time.Time in UTC, and the connection uses utf8mb4.
+func mysqlDSN(dsn string) (string, error) {
+	cfg, err := mysqldriver.ParseDSN(dsn)
+	if err != nil {
+		return "", fmt.Errorf("invalid MySQL DSN: %w", err)
+	}
+	cfg.ParseTime = true
+	if cfg.Params == nil {
+		cfg.Params = map[string]string{}
+	}
+	if _, exists := cfg.Params["charset"]; !exists {
+		cfg.Params["charset"] = "utf8mb4"
+	}
+	return cfg.FormatDSN(), nil
+}
+
+// getMySQLDatabaseSize returns the size of the tables of the current schema and their allocated free space.
+func (dm *Manager) getMySQLDatabaseSize() (DatabaseSize, error) {
+	var size DatabaseSize
+	result := dm.db.Raw(
+		"SELECT COALESCE(SUM(data_length + index_length), 0) AS size, COALESCE(SUM(data_free), 0) AS free_size " +
+			"FROM information_schema.tables WHERE table_schema = DATABASE()",
+	).Scan(&size)
+	return size, result.Error
+}
+
+// getMySQLObjectSizes returns the sizes of the tables of the current schema, including their indexes.
+func (dm *Manager) getMySQLObjectSizes() ([]ObjectSize, error) {
+	var sizes []ObjectSize
+	result := dm.db.Raw(
+		"SELECT table_name AS name, 'table' AS type, data_length + index_length AS size " +
+			"FROM information_schema.tables WHERE table_schema = DATABASE() ORDER BY size DESC",
+	).Scan(&sizes)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return sizes, nil
+}
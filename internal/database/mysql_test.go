@@ -0,0 +1,51 @@
+package database //nolint:testpackage // Unit tests are in the same package
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDriver(t *testing.T) {
+	// Act
+	sqliteDriver, sqliteErr := ParseDriver("sqlite")
+	mysqlDriver, mysqlErr := ParseDriver("mysql")
+	_, unknownErr := ParseDriver("postgres")
+
+	// Assert
+	require.NoError(t, sqliteErr)
+	assert.Equal(t, DriverSQLite, sqliteDriver)
+	require.NoError(t, mysqlErr)
+	assert.Equal(t, DriverMySQL, mysqlDriver)
+	assert.Equal(t, "mysql", mysqlDriver.String())
+	assert.Error(t, unknownErr)
+}
+
+func TestMySQLDSN(t *testing.T) {
+	t.Run("Required parameters", func(t *testing.T) {
+		// Act
+		dsn, err := mysqlDSN("tellama:secret@tcp(localhost:3306)/tellama")
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, "tellama:secret@tcp(localhost:3306)/tellama?parseTime=true&charset=utf8mb4", dsn)
+	})
+
+	t.Run("Configured charset", func(t *testing.T) {
+		// Act
+		dsn, err := mysqlDSN("tellama@unix(/run/mysqld/mysqld.sock)/tellama?charset=utf8mb4,utf8")
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, "tellama@unix(/run/mysqld/mysqld.sock)/tellama?parseTime=true&charset=utf8mb4%2Cutf8", dsn)
+	})
+
+	t.Run("Invalid DSN", func(t *testing.T) {
+		// Act
+		_, err := mysqlDSN("localhost:3306")
+
+		// Assert
+		assert.Error(t, err)
+	})
+}
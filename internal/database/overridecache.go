@@ -0,0 +1,94 @@
+package database
+
+import (
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// chatOverridesTable is the table whose writes invalidate the chat override cache.
+const chatOverridesTable = "chat_overrides"
+
+// chatOverrideCache holds the merged chat overrides returned by GetChatOverride for a limited time,
+// since they are read several times for every message.
+// Every write to the chat overrides clears the cache, while the time limit bounds how long
+// changes made outside of the Manager, such as with the sqlite3 shell, take to apply.
+type chatOverrideCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[int64]cachedChatOverride
+
+	// generation is incremented by every invalidation so that overrides read before it are not cached
+	generation uint64
+}
+
+type cachedChatOverride struct {
+	chatOverride ChatOverride
+	expiresAt    time.Time
+}
+
+// SetChatOverrideCacheTTL sets how long chat overrides are cached, disabling the cache if zero.
+// It registers the callbacks that clear the cache and must be called before the manager is used.
+func (dm *Manager) SetChatOverrideCacheTTL(ttl time.Duration) error {
+	if ttl == 0 {
+		dm.overrideCache = nil
+		return nil
+	}
+	cache := &chatOverrideCache{ttl: ttl, entries: map[int64]cachedChatOverride{}}
+	dm.overrideCache = cache
+
+	invalidate := func(tx *gorm.DB) {
+		if tx.Statement.Table == chatOverridesTable {
+			cache.invalidate()
+		}
+	}
+	callbacks := dm.db.Callback()
+	if err := callbacks.Create().After("gorm:create").Register("tellama:invalidate_overrides", invalidate); err != nil {
+		return err
+	}
+	if err := callbacks.Update().After("gorm:update").Register("tellama:invalidate_overrides", invalidate); err != nil {
+		return err
+	}
+	return callbacks.Delete().After("gorm:delete").Register("tellama:invalidate_overrides", invalidate)
+}
+
+// get returns the cached override of a chat and the generation to pass to put if it is not cached.
+func (c *chatOverrideCache) get(chatID int64) (ChatOverride, uint64, bool) {
+	if c == nil {
+		return ChatOverride{}, 0, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[chatID]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return ChatOverride{}, c.generation, false
+	}
+	return entry.chatOverride, c.generation, true
+}
+
+// put caches the override of a chat unless the cache was invalidated since the generation was returned by get.
+func (c *chatOverrideCache) put(chatID int64, chatOverride ChatOverride, generation uint64) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+	c.entries[chatID] = cachedChatOverride{chatOverride: chatOverride, expiresAt: time.Now().Add(c.ttl)}
+}
+
+// invalidate clears the cache, since a change of the global override applies to every chat.
+func (c *chatOverrideCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	clear(c.entries)
+}
@@ -0,0 +1,57 @@
+package database //nolint:testpackage // Unit tests are in the same package
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatOverrideCache(t *testing.T) {
+	// Arrange
+	dbManager, err := NewDatabaseManager(filepath.Join(t.TempDir(), "tellama.db"), SQLiteConfig{})
+	require.NoError(t, err)
+	defer dbManager.Close()
+	require.NoError(t, dbManager.SetChatOverrideCacheTTL(time.Minute))
+	chatID := int64(1001)
+	require.NoError(t, dbManager.SetChatModel(chatID, "", "llama3"))
+
+	// Act
+	first, err := dbManager.GetChatOverride(chatID)
+	require.NoError(t, err)
+	require.NoError(t, dbManager.db.Exec("UPDATE chat_overrides SET model = 'mistral'").Error)
+	cached, err := dbManager.GetChatOverride(chatID)
+	require.NoError(t, err)
+	require.NoError(t, dbManager.SetChatLanguage(chatID, "", "ja"))
+	invalidated, err := dbManager.GetChatOverride(chatID)
+	require.NoError(t, err)
+
+	// Assert
+	assert.Equal(t, "llama3", first.Model)
+	assert.Equal(t, "llama3", cached.Model, "changes made outside of the manager apply after the TTL")
+	assert.Equal(t, "mistral", invalidated.Model, "writes through the manager clear the cache")
+	assert.Equal(t, "ja", invalidated.Language)
+}
+
+func TestChatOverrideCache_Expiry(t *testing.T) {
+	// Arrange
+	dbManager, err := NewDatabaseManager(filepath.Join(t.TempDir(), "tellama.db"), SQLiteConfig{})
+	require.NoError(t, err)
+	defer dbManager.Close()
+	require.NoError(t, dbManager.SetChatOverrideCacheTTL(time.Millisecond))
+	chatID := int64(1001)
+	require.NoError(t, dbManager.SetChatModel(chatID, "", "llama3"))
+	_, err = dbManager.GetChatOverride(chatID)
+	require.NoError(t, err)
+	require.NoError(t, dbManager.db.Exec("UPDATE chat_overrides SET model = 'mistral'").Error)
+
+	// Act
+	time.Sleep(5 * time.Millisecond)
+	chatOverride, err := dbManager.GetChatOverride(chatID)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "mistral", chatOverride.Model)
+}
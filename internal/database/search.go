@@ -0,0 +1,163 @@
+package database
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// messageSearchStatements create the full-text index of message contents and the triggers that keep it in sync.
+// The index uses FTS4, since FTS5 is only available when SQLite is built with the sqlite_fts5 tag.
+//
+//nolint:gochecknoglobals // Constant list of statements
+var messageSearchStatements = []string{
+	`CREATE VIRTUAL TABLE IF NOT EXISTS message_search ` +
+		`USING fts4(content, content="messages", tokenize=unicode61)`,
+	`CREATE TRIGGER IF NOT EXISTS message_search_insert AFTER INSERT ON messages BEGIN ` +
+		`INSERT INTO message_search(docid, content) VALUES (new.id, new.content); END`,
+	`CREATE TRIGGER IF NOT EXISTS message_search_before_update BEFORE UPDATE OF content ON messages BEGIN ` +
+		`DELETE FROM message_search WHERE docid = old.id; END`,
+	`CREATE TRIGGER IF NOT EXISTS message_search_after_update AFTER UPDATE OF content ON messages BEGIN ` +
+		`INSERT INTO message_search(docid, content) VALUES (new.id, new.content); END`,
+	`CREATE TRIGGER IF NOT EXISTS message_search_delete BEFORE DELETE ON messages BEGIN ` +
+		`DELETE FROM message_search WHERE docid = old.id; END`,
+}
+
+// SearchResult is a message matching a search query.
+type SearchResult struct {
+	Message
+	// Excerpt is the part of the content around the matches, with the matched terms enclosed in brackets.
+	Excerpt string
+}
+
+// migrateMessageSearch creates the full-text index of message contents,
+// indexing the existing messages if the index did not exist.
+func migrateMessageSearch(tx *gorm.DB) error {
+	var exists int64
+	err := tx.Raw("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'message_search'").
+		Scan(&exists).Error
+	if err != nil {
+		return err
+	}
+
+	for _, statement := range messageSearchStatements {
+		if err = tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	if exists == 0 {
+		return tx.Exec("INSERT INTO message_search(message_search) VALUES ('rebuild')").Error
+	}
+	return nil
+}
+
+// SearchMessages returns up to limit messages of a chat that contain every term of the query, newest first.
+func (dm *Manager) SearchMessages(chatID int64, query string, limit int) ([]SearchResult, error) {
+	if dm.driver == DriverMySQL {
+		return dm.searchMessagesLike(chatID, strings.Fields(query), limit)
+	}
+
+	matchQuery := searchMatchQuery(query)
+	if matchQuery == "" {
+		return nil, nil
+	}
+
+	var results []SearchResult
+	err := dm.db.Raw(
+		"SELECT messages.*, snippet(message_search, '[', ']', '…', -1, 12) AS excerpt "+
+			"FROM message_search JOIN messages ON messages.id = message_search.docid "+
+			"WHERE message_search MATCH ? AND messages.chat_id = ? "+
+			"ORDER BY messages.timestamp DESC, messages.id DESC LIMIT ?",
+		matchQuery, chatID, limit,
+	).Scan(&results).Error
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// searchMatchQuery quotes every term of a query so that the full-text query syntax is matched literally.
+func searchMatchQuery(query string) string {
+	terms := strings.Fields(query)
+	for i, term := range terms {
+		terms[i] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+	}
+	return strings.Join(terms, " ")
+}
+
+// searchMessagesLike returns up to limit messages of a chat that contain every term, newest first,
+// for MySQL, which has no full-text index of messages. Matching is case-insensitive under the default
+// collation, and the terms are escaped with the default escape character of MySQL.
+func (dm *Manager) searchMessagesLike(chatID int64, terms []string, limit int) ([]SearchResult, error) {
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	query := dm.db.Where("chat_id = ?", chatID)
+	escaper := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+	for _, term := range terms {
+		query = query.Where("content LIKE ?", "%"+escaper.Replace(term)+"%")
+	}
+
+	var messages []Message
+	result := query.Order("timestamp DESC, id DESC").Limit(limit).Find(&messages)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	results := make([]SearchResult, len(messages))
+	for i, message := range messages {
+		results[i] = SearchResult{Message: message, Excerpt: searchExcerpt(message.Content, terms)}
+	}
+	return results, nil
+}
+
+// searchExcerpt returns up to searchExcerptWords words of the content starting shortly before
+// the first match, with the matched terms enclosed in brackets like the excerpts of the full-text index.
+func searchExcerpt(content string, terms []string) string {
+	const searchExcerptWords = 12
+
+	lowerTerms := make([]string, len(terms))
+	for i, term := range terms {
+		lowerTerms[i] = strings.ToLower(term)
+	}
+
+	words := strings.Fields(content)
+	first := -1
+	for i, word := range words {
+		if marked, ok := markTerms(word, lowerTerms); ok {
+			words[i] = marked
+			if first < 0 {
+				first = i
+			}
+		}
+	}
+
+	start := max(0, min(first-searchExcerptWords/3, len(words)-searchExcerptWords))
+	end := min(len(words), start+searchExcerptWords)
+	excerpt := strings.Join(words[start:end], " ")
+	if start > 0 {
+		excerpt = "…" + excerpt
+	}
+	if end < len(words) {
+		excerpt += "…"
+	}
+	return excerpt
+}
+
+// markTerms encloses the first term found in the word in brackets.
+func markTerms(word string, lowerTerms []string) (string, bool) {
+	lowerWord := strings.ToLower(word)
+	for _, term := range lowerTerms {
+		i := strings.Index(lowerWord, term)
+		if i < 0 {
+			continue
+		}
+		// Lowercasing may change the length of some characters, in which case the whole word is marked
+		if len(lowerWord) != len(word) {
+			return "[" + word + "]", true
+		}
+		return word[:i] + "[" + word[i:i+len(term)] + "]" + word[i+len(term):], true
+	}
+	return word, false
+}
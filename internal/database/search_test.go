@@ -0,0 +1,162 @@
+package database //nolint:testpackage // Unit tests are in the same package
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/go-faker/faker/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchMessages(t *testing.T) {
+	// Arrange
+	dbManager := setupTestDB(t)
+	chatIDs, err := faker.RandomInt(-1000000, 1000000, 2)
+	require.NoError(t, err)
+	chatID, otherChatID := int64(chatIDs[0]), int64(chatIDs[1])
+
+	older, err := dbManager.StoreMessage(
+		chatID, "", "user", ContentTypeText, 1, "alice", "Alice", "", "The llama grazed on the hill", 10, 0, 0,
+	)
+	require.NoError(t, err)
+	newer, err := dbManager.StoreMessage(
+		chatID, "", "assistant", ContentTypeText, 0, "", "", "", "A llama is a domesticated camelid", 11, 0, 0,
+	)
+	require.NoError(t, err)
+	_, err = dbManager.StoreMessage(
+		otherChatID, "", "user", ContentTypeText, 1, "alice", "Alice", "", "Another llama", 12, 0, 0,
+	)
+	require.NoError(t, err)
+
+	t.Run("Match a term in the chat", func(t *testing.T) {
+		// Act
+		var results []SearchResult
+		results, err = dbManager.SearchMessages(chatID, "LLAMA", 10)
+
+		// Assert
+		require.NoError(t, err)
+		require.Len(t, results, 2)
+		assert.Equal(t, newer, results[0].ID)
+		assert.Equal(t, 11, results[0].TelegramMessageID)
+		assert.Equal(t, "A [llama] is a domesticated camelid", results[0].Excerpt)
+		assert.Equal(t, older, results[1].ID)
+	})
+
+	t.Run("Match every term", func(t *testing.T) {
+		// Act
+		var results []SearchResult
+		results, err = dbManager.SearchMessages(chatID, "llama hill", 10)
+
+		// Assert
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.Equal(t, older, results[0].ID)
+	})
+
+	t.Run("Limit the results", func(t *testing.T) {
+		// Act
+		var results []SearchResult
+		results, err = dbManager.SearchMessages(chatID, "llama", 1)
+
+		// Assert
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.Equal(t, newer, results[0].ID)
+	})
+
+	t.Run("Query syntax is matched literally", func(t *testing.T) {
+		// Act
+		var results []SearchResult
+		results, err = dbManager.SearchMessages(chatID, `llama OR "hill* -NEAR`, 10)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Empty(t, results)
+	})
+
+	t.Run("Edited messages are reindexed", func(t *testing.T) {
+		// Arrange
+		require.NoError(t, dbManager.UpdateMessageContent(older, "The alpaca grazed on the hill"))
+
+		// Act
+		var llamaResults, alpacaResults []SearchResult
+		llamaResults, err = dbManager.SearchMessages(chatID, "llama", 10)
+		require.NoError(t, err)
+		alpacaResults, err = dbManager.SearchMessages(chatID, "alpaca", 10)
+
+		// Assert
+		require.NoError(t, err)
+		require.Len(t, llamaResults, 1)
+		assert.Equal(t, newer, llamaResults[0].ID)
+		require.Len(t, alpacaResults, 1)
+		assert.Equal(t, older, alpacaResults[0].ID)
+	})
+
+	t.Run("Cleared messages are removed", func(t *testing.T) {
+		// Arrange
+		require.NoError(t, dbManager.ClearMessages(chatID))
+
+		// Act
+		var results []SearchResult
+		results, err = dbManager.SearchMessages(chatID, "alpaca", 10)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Empty(t, results)
+	})
+}
+
+func TestMigrateMessageSearch_ExistingMessages(t *testing.T) {
+	// Arrange
+	dbPath := filepath.Join(t.TempDir(), "tellama.db")
+	dbManager, err := NewDatabaseManager(dbPath, SQLiteConfig{})
+	require.NoError(t, err)
+	_, err = dbManager.StoreMessage(1, "", "user", ContentTypeText, 1, "", "", "", "Existing message", 1, 0, 0)
+	require.NoError(t, err)
+	// Simulate a database created before the index and migrations were introduced
+	require.NoError(t, dbManager.db.Exec("DROP TABLE message_search").Error)
+	require.NoError(t, dbManager.db.Exec("DROP TABLE schema_migrations").Error)
+	require.NoError(t, dbManager.Close())
+
+	// Act
+	dbManager, err = NewDatabaseManager(dbPath, SQLiteConfig{})
+	require.NoError(t, err)
+	defer dbManager.Close()
+	var results []SearchResult
+	results, err = dbManager.SearchMessages(1, "existing", 10)
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "[Existing] message", results[0].Excerpt)
+}
+
+func TestSearchExcerpt(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		terms    []string
+		expected string
+	}{
+		{name: "Short content", content: "Existing message", terms: []string{"existing"}, expected: "[Existing] message"},
+		{name: "Part of a word", content: "Llamas, alpacas", terms: []string{"llama"}, expected: "[Llama]s, alpacas"},
+		{
+			name: "Long content",
+			content: "one two three four five six seven eight nine ten llama twelve thirteen fourteen fifteen " +
+				"sixteen seventeen eighteen nineteen twenty",
+			terms:    []string{"LLAMA"},
+			expected: "…seven eight nine ten [llama] twelve thirteen fourteen fifteen sixteen seventeen eighteen…",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			excerpt := searchExcerpt(tt.content, tt.terms)
+
+			// Assert
+			assert.Equal(t, tt.expected, excerpt)
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package database
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultJournalMode lets readers run while another connection writes.
+	defaultJournalMode = "wal"
+
+	// defaultSynchronous is safe with the WAL journal and avoids syncing on every commit.
+	defaultSynchronous = "normal"
+
+	// defaultBusyTimeout is how long SQLite waits for a lock held by another connection before failing.
+	defaultBusyTimeout = 5 * time.Second
+)
+
+// SQLiteConfig holds the pragmas and connection pool settings applied to the database.
+// Zero values select the defaults.
+type SQLiteConfig struct {
+	// JournalMode is the journal_mode pragma, WAL by default.
+	JournalMode string
+	// Synchronous is the synchronous pragma, NORMAL by default.
+	Synchronous string
+	// BusyTimeout is how long a connection waits for a lock before failing, 5 seconds by default.
+	BusyTimeout time.Duration
+	// MaxOpenConns limits the open connections, unlimited if zero.
+	MaxOpenConns int
+	// MaxIdleConns limits the idle connections kept in the pool, the database/sql default if zero.
+	MaxIdleConns int
+	// ConnMaxLifetime is how long a connection may be reused, forever if zero.
+	ConnMaxLifetime time.Duration
+}
+
+// Validate checks the pragma values and pool limits.
+func (c *SQLiteConfig) Validate() error {
+	journalModes := []string{"delete", "truncate", "persist", "memory", "wal", "off"}
+	if c.JournalMode != "" && !slices.Contains(journalModes, strings.ToLower(c.JournalMode)) {
+		return fmt.Errorf("journal mode must be one of %s", strings.Join(journalModes, ", "))
+	}
+	synchronousModes := []string{"off", "normal", "full", "extra"}
+	if c.Synchronous != "" && !slices.Contains(synchronousModes, strings.ToLower(c.Synchronous)) {
+		return fmt.Errorf("synchronous must be one of %s", strings.Join(synchronousModes, ", "))
+	}
+	if c.BusyTimeout < 0 {
+		return errors.New("busy timeout cannot be negative")
+	}
+	if c.MaxOpenConns < 0 || c.MaxIdleConns < 0 {
+		return errors.New("connection limits cannot be negative")
+	}
+	if c.ConnMaxLifetime < 0 {
+		return errors.New("connection lifetime cannot be negative")
+	}
+	return nil
+}
+
+// dsn adds the pragmas to the database path so that every connection of the pool is opened with them.
+func (c *SQLiteConfig) dsn(dbPath string) string {
+	journalMode := cmp.Or(c.JournalMode, defaultJournalMode)
+	synchronous := cmp.Or(c.Synchronous, defaultSynchronous)
+	busyTimeout := cmp.Or(c.BusyTimeout, defaultBusyTimeout)
+
+	separator := "?"
+	if strings.Contains(dbPath, "?") {
+		separator = "&"
+	}
+	return fmt.Sprintf(
+		"%s%s_busy_timeout=%d&_journal_mode=%s&_synchronous=%s",
+		dbPath, separator, busyTimeout.Milliseconds(), strings.ToUpper(journalMode), strings.ToUpper(synchronous),
+	)
+}
+
+// applyPool sets the connection pool limits of the database.
+func (c *SQLiteConfig) applyPool(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	if c.MaxOpenConns > 0 {
+		sqlDB.SetMaxOpenConns(c.MaxOpenConns)
+	}
+	if c.MaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(c.MaxIdleConns)
+	}
+	if c.ConnMaxLifetime > 0 {
+		sqlDB.SetConnMaxLifetime(c.ConnMaxLifetime)
+	}
+	return nil
+}
@@ -0,0 +1,528 @@
+package genai
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Bedrock generates responses with models hosted on AWS Bedrock through the Converse API.
+// The sampling options are unset when nil and are then left to the model's defaults.
+// MaxTokens is unset when not positive, and Stop when empty.
+type Bedrock struct {
+	Client      *http.Client
+	Endpoint    string
+	Region      string
+	Model       string
+	MaxTokens   int64
+	Stop        string
+	Temperature *float64
+	TopP        *float64
+	Retry       RetryPolicy
+	credentials awsCredentials
+}
+
+type BedrockConfig struct {
+	Region string
+
+	// Model is the model ID or inference profile ID, such as anthropic.claude-3-5-sonnet-20240620-v1:0
+	Model string
+
+	// Endpoint overrides the Bedrock Runtime endpoint of the region, such as for VPC endpoints
+	Endpoint string
+
+	// The credentials are looked up in the environment and the shared credentials file if not set
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+
+	// Profile is the shared credentials file profile, AWS_PROFILE or "default" if empty
+	Profile string
+
+	MaxTokens   int64
+	Stop        string
+	Temperature *float64
+	TopP        *float64
+	Retry       RetryPolicy
+}
+
+func (c *BedrockConfig) Validate() error {
+	if c.Region == "" {
+		return errors.New("region cannot be empty")
+	}
+	if c.Model == "" {
+		return errors.New("model cannot be empty")
+	}
+	if (c.AccessKeyID == "") != (c.SecretAccessKey == "") {
+		return errors.New("access key ID and secret access key must be set together")
+	}
+	return c.Retry.Validate()
+}
+
+func newBedrockClient(config ProviderConfig) (GenerativeAI, error) {
+	cfg, ok := config.(*BedrockConfig)
+	if !ok {
+		return nil, errors.New("invalid config type for Bedrock")
+	}
+
+	credentials, err := resolveAWSCredentials(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://bedrock-runtime.%s.amazonaws.com", cfg.Region)
+	}
+
+	return &Bedrock{
+		Client:      &http.Client{},
+		Endpoint:    strings.TrimSuffix(endpoint, "/"),
+		Region:      cfg.Region,
+		Model:       cfg.Model,
+		MaxTokens:   cfg.MaxTokens,
+		Stop:        cfg.Stop,
+		Temperature: cfg.Temperature,
+		TopP:        cfg.TopP,
+		Retry:       cfg.Retry,
+		credentials: credentials,
+	}, nil
+}
+
+// resolveAWSCredentials returns the configured credentials, or looks them up in the environment
+// and then in the shared credentials file in the same order as the AWS CLI.
+// Instance metadata, container, and SSO credentials are not supported.
+func resolveAWSCredentials(cfg *BedrockConfig) (awsCredentials, error) {
+	if cfg.AccessKeyID != "" {
+		return awsCredentials{
+			AccessKeyID:     cfg.AccessKeyID,
+			SecretAccessKey: cfg.SecretAccessKey,
+			SessionToken:    cfg.SessionToken,
+		}, nil
+	}
+
+	if accessKeyID := os.Getenv("AWS_ACCESS_KEY_ID"); accessKeyID != "" {
+		return awsCredentials{
+			AccessKeyID:     accessKeyID,
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		}, nil
+	}
+
+	path := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return awsCredentials{}, fmt.Errorf("failed to locate the AWS credentials file: %w", err)
+		}
+		path = filepath.Join(home, ".aws", "credentials")
+	}
+	profile := cfg.Profile
+	if profile == "" {
+		profile = os.Getenv("AWS_PROFILE")
+	}
+	if profile == "" {
+		profile = "default"
+	}
+
+	credentials, err := readSharedCredentials(path, profile)
+	if err != nil {
+		return awsCredentials{}, fmt.Errorf("no AWS credentials found: %w", err)
+	}
+	return credentials, nil
+}
+
+// readSharedCredentials reads the credentials of a profile from an AWS shared credentials file.
+func readSharedCredentials(path string, profile string) (awsCredentials, error) {
+	file, err := os.Open(path) //nolint:gosec // The path comes from the environment of the operator
+	if err != nil {
+		return awsCredentials{}, err
+	}
+	defer file.Close()
+
+	var credentials awsCredentials
+	var section string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		if section != profile {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "aws_access_key_id":
+			credentials.AccessKeyID = strings.TrimSpace(value)
+		case "aws_secret_access_key":
+			credentials.SecretAccessKey = strings.TrimSpace(value)
+		case "aws_session_token":
+			credentials.SessionToken = strings.TrimSpace(value)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return awsCredentials{}, err
+	}
+
+	if credentials.AccessKeyID == "" || credentials.SecretAccessKey == "" {
+		return awsCredentials{}, fmt.Errorf("profile %q has no access key in %s", profile, path)
+	}
+	return credentials, nil
+}
+
+// BedrockError is an error response of the Bedrock API.
+type BedrockError struct {
+	StatusCode int
+
+	// Type is the exception name, such as ThrottlingException or ValidationException
+	Type    string
+	Message string
+}
+
+func (e *BedrockError) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.StatusCode, e.Type, e.Message)
+}
+
+type bedrockMessage struct {
+	Role    string                `json:"role"`
+	Content []bedrockContentBlock `json:"content"`
+}
+
+type bedrockContentBlock struct {
+	Text             string                   `json:"text,omitempty"`
+	Image            *bedrockImage            `json:"image,omitempty"`
+	ToolUse          *bedrockToolUse          `json:"toolUse,omitempty"`
+	ToolResult       *bedrockToolResult       `json:"toolResult,omitempty"`
+	ReasoningContent *bedrockReasoningContent `json:"reasoningContent,omitempty"`
+}
+
+type bedrockReasoningContent struct {
+	ReasoningText struct {
+		Text string `json:"text"`
+	} `json:"reasoningText"`
+}
+
+type bedrockImage struct {
+	Format string `json:"format"`
+	Source struct {
+		Bytes []byte `json:"bytes"`
+	} `json:"source"`
+}
+
+type bedrockToolUse struct {
+	ToolUseID string          `json:"toolUseId"`
+	Name      string          `json:"name"`
+	Input     json.RawMessage `json:"input"`
+}
+
+type bedrockToolResult struct {
+	ToolUseID string                `json:"toolUseId"`
+	Content   []bedrockContentBlock `json:"content"`
+}
+
+type bedrockInferenceConfig struct {
+	MaxTokens     int64    `json:"maxTokens,omitempty"`
+	StopSequences []string `json:"stopSequences,omitempty"`
+	Temperature   *float64 `json:"temperature,omitempty"`
+	TopP          *float64 `json:"topP,omitempty"`
+}
+
+type bedrockTool struct {
+	ToolSpec struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		InputSchema struct {
+			JSON map[string]any `json:"json"`
+		} `json:"inputSchema"`
+	} `json:"toolSpec"`
+}
+
+type bedrockToolConfig struct {
+	Tools []bedrockTool `json:"tools"`
+}
+
+type bedrockConverseRequest struct {
+	Messages        []bedrockMessage        `json:"messages"`
+	System          []bedrockContentBlock   `json:"system,omitempty"`
+	InferenceConfig *bedrockInferenceConfig `json:"inferenceConfig,omitempty"`
+	ToolConfig      *bedrockToolConfig      `json:"toolConfig,omitempty"`
+}
+
+type bedrockConverseResponse struct {
+	Output struct {
+		Message bedrockMessage `json:"message"`
+	} `json:"output"`
+	StopReason string `json:"stopReason"`
+	Usage      struct {
+		InputTokens  int64 `json:"inputTokens"`
+		OutputTokens int64 `json:"outputTokens"`
+	} `json:"usage"`
+}
+
+// Chat generates a response from Bedrock using a conversation history.
+func (b *Bedrock) Chat(messages []Message) (string, GenerateStats, error) {
+	reply, genStats, err := b.chat(messages, nil)
+	if err != nil {
+		return "", GenerateStats{}, err
+	}
+	return reply.Content, genStats, nil
+}
+
+// ChatWithTools generates the next assistant message, allowing the model to call tools.
+func (b *Bedrock) ChatWithTools(messages []Message, tools []Tool) (Message, GenerateStats, error) {
+	apiTools := make([]bedrockTool, len(tools))
+	for i, tool := range tools {
+		apiTools[i].ToolSpec.Name = tool.Name()
+		apiTools[i].ToolSpec.Description = tool.Description()
+		apiTools[i].ToolSpec.InputSchema.JSON = tool.Parameters()
+	}
+	return b.chat(messages, apiTools)
+}
+
+// Complete generates a completion of the prompt, which Bedrock only supports as a single user message.
+func (b *Bedrock) Complete(prompt string) (string, GenerateStats, error) {
+	return b.Chat([]Message{{Role: "user", Content: prompt}})
+}
+
+func (b *Bedrock) chat(messages []Message, tools []bedrockTool) (Message, GenerateStats, error) {
+	request := bedrockConverseRequest{InferenceConfig: b.inferenceConfig()}
+	request.System, request.Messages = bedrockMessages(messages)
+	if len(request.Messages) == 0 {
+		return Message{}, GenerateStats{}, errors.New("Bedrock requires at least one user message")
+	}
+	if len(tools) > 0 {
+		request.ToolConfig = &bedrockToolConfig{Tools: tools}
+	}
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return Message{}, GenerateStats{}, err
+	}
+
+	startTime := time.Now()
+	var response bedrockConverseResponse
+	err = b.Retry.do(func() error {
+		return b.converse(body, &response)
+	})
+	if err != nil {
+		return Message{}, GenerateStats{}, fmt.Errorf(
+			"Bedrock failed to generate chat completion: %w", wrapBedrockError(err),
+		)
+	}
+	duration := time.Since(startTime)
+
+	reply := Message{Role: "assistant"}
+	var text, reasoning []string
+	for _, block := range response.Output.Message.Content {
+		switch {
+		case block.ToolUse != nil:
+			reply.ToolCalls = append(reply.ToolCalls, ToolCall{
+				ID:        block.ToolUse.ToolUseID,
+				Name:      block.ToolUse.Name,
+				Arguments: string(block.ToolUse.Input),
+			})
+		case block.ReasoningContent != nil:
+			reasoning = append(reasoning, block.ReasoningContent.ReasoningText.Text)
+		case block.Text != "":
+			text = append(text, block.Text)
+		}
+	}
+	reply.Content = strings.Join(text, "\n")
+
+	genStats := GenerateStats{
+		DoneReason:         response.StopReason,
+		TotalDuration:      duration,
+		LoadDuration:       -1,
+		PromptTokens:       response.Usage.InputTokens,
+		PromptEvalDuration: -1,
+		TokenCount:         response.Usage.OutputTokens,
+		EvalDuration:       duration,
+		Reasoning:          strings.Join(reasoning, "\n"),
+	}
+	return reply, genStats, nil
+}
+
+// inferenceConfig returns the inference parameters with only the options that are set,
+// or nil if none are set.
+func (b *Bedrock) inferenceConfig() *bedrockInferenceConfig {
+	if b.MaxTokens <= 0 && b.Stop == "" && b.Temperature == nil && b.TopP == nil {
+		return nil
+	}
+
+	config := &bedrockInferenceConfig{
+		Temperature: b.Temperature,
+		TopP:        b.TopP,
+	}
+	if b.MaxTokens > 0 {
+		config.MaxTokens = b.MaxTokens
+	}
+	if b.Stop != "" {
+		config.StopSequences = []string{b.Stop}
+	}
+	return config
+}
+
+// converse sends a signed Converse request and decodes the response.
+func (b *Bedrock) converse(body []byte, response *bedrockConverseResponse) error {
+	endpoint, err := url.Parse(b.Endpoint)
+	if err != nil {
+		return err
+	}
+
+	// Model IDs contain colons, which must be escaped in the path
+	endpoint.RawPath = endpoint.EscapedPath() + "/model/" + awsURIEncode(b.Model) + "/converse"
+	endpoint.Path += "/model/" + b.Model + "/converse"
+
+	req, err := http.NewRequest(http.MethodPost, endpoint.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	signAWSRequest(req, body, b.credentials, b.Region, "bedrock", time.Now())
+
+	resp, err := b.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return newBedrockError(resp, data)
+	}
+	return json.Unmarshal(data, response)
+}
+
+// newBedrockError creates an error from a failed Bedrock response.
+func newBedrockError(resp *http.Response, data []byte) *BedrockError {
+	var body struct {
+		Message      string `json:"message"`
+		MessageUpper string `json:"Message"`
+	}
+	_ = json.Unmarshal(data, &body)
+
+	message := body.Message
+	if message == "" {
+		message = body.MessageUpper
+	}
+	if message == "" {
+		message = strings.TrimSpace(string(data))
+	}
+
+	// The header holds the exception name, optionally followed by a colon and a namespace
+	errorType, _, _ := strings.Cut(resp.Header.Get("X-Amzn-Errortype"), ":")
+	if errorType == "" {
+		errorType = strconv.Itoa(resp.StatusCode)
+	}
+	return &BedrockError{StatusCode: resp.StatusCode, Type: errorType, Message: message}
+}
+
+// bedrockMessages converts the conversation into the system prompt and messages of a Converse request.
+// Bedrock requires the conversation to start with a user message and to alternate between the user
+// and the assistant, so consecutive messages of the same role, such as those of several group members,
+// are merged and leading assistant messages are dropped.
+func bedrockMessages(messages []Message) ([]bedrockContentBlock, []bedrockMessage) {
+	var system []bedrockContentBlock
+	var converted []bedrockMessage
+	for _, message := range messages {
+		role := "user"
+		var content []bedrockContentBlock
+		switch message.Role {
+		case "system":
+			if message.Content != "" {
+				system = append(system, bedrockContentBlock{Text: message.Content})
+			}
+			continue
+		case "assistant":
+			role = "assistant"
+			if message.Content != "" {
+				content = append(content, bedrockContentBlock{Text: message.Content})
+			}
+			for _, toolCall := range message.ToolCalls {
+				input := json.RawMessage(toolCall.Arguments)
+				if !json.Valid(input) {
+					input = json.RawMessage("{}")
+				}
+				content = append(content, bedrockContentBlock{ToolUse: &bedrockToolUse{
+					ToolUseID: toolCall.ID,
+					Name:      toolCall.Name,
+					Input:     input,
+				}})
+			}
+		case "tool":
+			content = append(content, bedrockContentBlock{ToolResult: &bedrockToolResult{
+				ToolUseID: message.ToolCallID,
+				Content:   []bedrockContentBlock{{Text: message.Content}},
+			}})
+		default:
+			if message.Content != "" {
+				content = append(content, bedrockContentBlock{Text: message.Content})
+			}
+			for _, image := range message.Images {
+				if block, ok := bedrockImageBlock(image); ok {
+					content = append(content, block)
+				}
+			}
+		}
+
+		switch {
+		case len(content) == 0, len(converted) == 0 && role == "assistant":
+			continue
+		case len(converted) > 0 && converted[len(converted)-1].Role == role:
+			last := &converted[len(converted)-1]
+			last.Content = append(last.Content, content...)
+		default:
+			converted = append(converted, bedrockMessage{Role: role, Content: content})
+		}
+	}
+	return system, converted
+}
+
+// bedrockImageBlock returns the image as a content block, or false if its format is not supported.
+func bedrockImageBlock(image []byte) (bedrockContentBlock, bool) {
+	format, ok := map[string]string{
+		"image/png":  "png",
+		"image/jpeg": "jpeg",
+		"image/gif":  "gif",
+		"image/webp": "webp",
+	}[http.DetectContentType(image)]
+	if !ok {
+		return bedrockContentBlock{}, false
+	}
+
+	block := bedrockContentBlock{Image: &bedrockImage{Format: format}}
+	block.Image.Source.Bytes = image
+	return block, true
+}
+
+// DetectCapabilities returns the capabilities of the Converse API.
+// Bedrock models cannot be inspected, so tools and images are assumed to be supported.
+func (b *Bedrock) DetectCapabilities() (Capabilities, error) {
+	return Capabilities{
+		Tools:  true,
+		Vision: true,
+	}, nil
+}
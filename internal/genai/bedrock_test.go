@@ -0,0 +1,222 @@
+package genai //nolint:testpackage // Unit tests are in the same package
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBedrockChatWithTools(t *testing.T) {
+	// Arrange
+	var request map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/model/anthropic.claude-v2%3A1/converse", r.URL.EscapedPath())
+		assert.True(t, strings.HasPrefix(
+			r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/",
+		))
+		assert.Contains(t, r.Header.Get("Authorization"), "/us-east-1/bedrock/aws4_request")
+		request = map[string]any{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{
+			"output": {"message": {"role": "assistant", "content": [
+				{"reasoningContent": {"reasoningText": {"text": "The user wants the weather."}}},
+				{"text": "Let me check."},
+				{"toolUse": {"toolUseId": "call_1", "name": "echo", "input": {"city": "Tokyo"}}}
+			]}},
+			"stopReason": "tool_use",
+			"usage": {"inputTokens": 12, "outputTokens": 5, "totalTokens": 17}
+		}`))
+	}))
+	defer server.Close()
+
+	temperature := 0.5
+	client, err := New(ProviderBedrock, &BedrockConfig{
+		Region:          "us-east-1",
+		Model:           "anthropic.claude-v2:1",
+		Endpoint:        server.URL,
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "secret",
+		MaxTokens:       256,
+		Temperature:     &temperature,
+	})
+	require.NoError(t, err)
+	toolCaller, ok := client.(ToolCaller)
+	require.True(t, ok)
+
+	// Act
+	reply, genStats, err := toolCaller.ChatWithTools([]Message{
+		{Role: "system", Content: "Be brief."},
+		{Role: "user", Content: "Hi"},
+		{Role: "user", Content: "What is the weather in Tokyo?"},
+	}, []Tool{&echoTool{}})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "Let me check.", reply.Content)
+	assert.Equal(t, []ToolCall{{ID: "call_1", Name: "echo", Arguments: `{"city": "Tokyo"}`}}, reply.ToolCalls)
+	assert.Equal(t, "tool_use", genStats.DoneReason)
+	assert.Equal(t, int64(12), genStats.PromptTokens)
+	assert.Equal(t, int64(5), genStats.TokenCount)
+	assert.Equal(t, "The user wants the weather.", genStats.Reasoning)
+
+	assert.Equal(t, []any{map[string]any{"text": "Be brief."}}, request["system"])
+	assert.Equal(t, []any{map[string]any{
+		"role": "user",
+		"content": []any{
+			map[string]any{"text": "Hi"},
+			map[string]any{"text": "What is the weather in Tokyo?"},
+		},
+	}}, request["messages"])
+	assert.Equal(t, map[string]any{"maxTokens": 256.0, "temperature": 0.5}, request["inferenceConfig"])
+	tools := request["toolConfig"].(map[string]any)["tools"].([]any)
+	require.Len(t, tools, 1)
+	assert.Equal(t, "echo", tools[0].(map[string]any)["toolSpec"].(map[string]any)["name"])
+}
+
+func TestBedrockChat_Error(t *testing.T) {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("X-Amzn-Errortype", "ThrottlingException:http://internal.amazon.com/coral/com.amazon.bedrock/")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"message": "Too many requests, please wait before trying again."}`))
+	}))
+	defer server.Close()
+
+	client, err := New(ProviderBedrock, &BedrockConfig{
+		Region:          "us-east-1",
+		Model:           "meta.llama3-70b-instruct-v1:0",
+		Endpoint:        server.URL,
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "secret",
+	})
+	require.NoError(t, err)
+
+	// Act
+	_, _, err = client.Chat([]Message{{Role: "user", Content: "Hi"}})
+
+	// Assert
+	require.ErrorIs(t, err, ErrRateLimited)
+	var apiError *BedrockError
+	require.ErrorAs(t, err, &apiError)
+	assert.Equal(t, "ThrottlingException", apiError.Type)
+	assert.Equal(t, "Too many requests, please wait before trying again.", apiError.Message)
+}
+
+func TestBedrockMessages(t *testing.T) {
+	// Arrange
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR")
+	messages := []Message{
+		{Role: "assistant", Content: "Hello, I am Tellama."},
+		{Role: "system", Content: "Be brief."},
+		{Role: "user", Content: "Look at this", Images: [][]byte{png, []byte("not an image")}},
+		{Role: "assistant", ToolCalls: []ToolCall{{ID: "call_1", Name: "search", Arguments: "invalid"}}},
+		{Role: "tool", ToolCallID: "call_1", Content: "result"},
+		{Role: "user", Content: ""},
+		{Role: "assistant", Content: "Done."},
+	}
+
+	// Act
+	system, converted := bedrockMessages(messages)
+
+	// Assert
+	assert.Equal(t, []bedrockContentBlock{{Text: "Be brief."}}, system)
+	require.Len(t, converted, 4)
+
+	assert.Equal(t, "user", converted[0].Role)
+	require.Len(t, converted[0].Content, 2)
+	assert.Equal(t, "Look at this", converted[0].Content[0].Text)
+	require.NotNil(t, converted[0].Content[1].Image)
+	assert.Equal(t, "png", converted[0].Content[1].Image.Format)
+
+	assert.Equal(t, "assistant", converted[1].Role)
+	require.Len(t, converted[1].Content, 1)
+	assert.JSONEq(t, "{}", string(converted[1].Content[0].ToolUse.Input))
+
+	assert.Equal(t, "user", converted[2].Role)
+	require.Len(t, converted[2].Content, 1)
+	assert.Equal(t, "call_1", converted[2].Content[0].ToolResult.ToolUseID)
+
+	assert.Equal(t, bedrockMessage{Role: "assistant", Content: []bedrockContentBlock{{Text: "Done."}}}, converted[3])
+}
+
+func TestReadSharedCredentials(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "credentials")
+	content := `# Shared credentials
+[default]
+aws_access_key_id = AKIDDEFAULT
+aws_secret_access_key = default_secret
+
+[work]
+aws_access_key_id=AKIDWORK
+aws_secret_access_key=work_secret
+aws_session_token=work_token
+`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	t.Run("Default profile", func(t *testing.T) {
+		// Act
+		credentials, err := readSharedCredentials(path, "default")
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, awsCredentials{AccessKeyID: "AKIDDEFAULT", SecretAccessKey: "default_secret"}, credentials)
+	})
+
+	t.Run("Named profile", func(t *testing.T) {
+		// Act
+		credentials, err := readSharedCredentials(path, "work")
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, awsCredentials{
+			AccessKeyID:     "AKIDWORK",
+			SecretAccessKey: "work_secret",
+			SessionToken:    "work_token",
+		}, credentials)
+	})
+
+	t.Run("Missing profile", func(t *testing.T) {
+		// Act
+		_, err := readSharedCredentials(path, "personal")
+
+		// Assert
+		assert.Error(t, err)
+	})
+}
+
+func TestResolveAWSCredentials(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "credentials")
+	content := "[default]\naws_access_key_id = AKIDFILE\naws_secret_access_key = file\n"
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", path)
+	t.Setenv("AWS_PROFILE", "")
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDENV")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "env")
+	t.Setenv("AWS_SESSION_TOKEN", "")
+
+	// Act
+	configured, configuredErr := resolveAWSCredentials(
+		&BedrockConfig{AccessKeyID: "AKIDCONFIG", SecretAccessKey: "config"},
+	)
+	environment, environmentErr := resolveAWSCredentials(&BedrockConfig{})
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	file, fileErr := resolveAWSCredentials(&BedrockConfig{})
+
+	// Assert
+	require.NoError(t, configuredErr)
+	assert.Equal(t, "AKIDCONFIG", configured.AccessKeyID)
+	require.NoError(t, environmentErr)
+	assert.Equal(t, "AKIDENV", environment.AccessKeyID)
+	require.NoError(t, fileErr)
+	assert.Equal(t, "AKIDFILE", file.AccessKeyID)
+}
@@ -0,0 +1,116 @@
+package genai
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+)
+
+// Capabilities describes the features supported by a provider and model.
+type Capabilities struct {
+	Tools            bool
+	Vision           bool
+	Streaming        bool
+	StructuredOutput bool
+	Reasoning        bool
+}
+
+// Names returns the names of the supported capabilities.
+func (c Capabilities) Names() []string {
+	var names []string
+	for _, capability := range []struct {
+		name      string
+		supported bool
+	}{
+		{"tools", c.Tools},
+		{"vision", c.Vision},
+		{"streaming", c.Streaming},
+		{"structured_output", c.StructuredOutput},
+		{"reasoning", c.Reasoning},
+	} {
+		if capability.supported {
+			names = append(names, capability.name)
+		}
+	}
+	return names
+}
+
+// CapabilityDetector is implemented by providers that can detect the capabilities of their model.
+type CapabilityDetector interface {
+	DetectCapabilities() (Capabilities, error)
+}
+
+// CapabilityOverrides forces capabilities on or off regardless of what is detected.
+// Nil fields keep the detected value.
+type CapabilityOverrides struct {
+	Tools            *bool
+	Vision           *bool
+	Streaming        *bool
+	StructuredOutput *bool
+	Reasoning        *bool
+}
+
+// Apply returns the capabilities with the overrides applied.
+func (o CapabilityOverrides) Apply(capabilities Capabilities) Capabilities {
+	for _, field := range []struct {
+		override   *bool
+		capability *bool
+	}{
+		{o.Tools, &capabilities.Tools},
+		{o.Vision, &capabilities.Vision},
+		{o.Streaming, &capabilities.Streaming},
+		{o.StructuredOutput, &capabilities.StructuredOutput},
+		{o.Reasoning, &capabilities.Reasoning},
+	} {
+		if field.override != nil {
+			*field.capability = *field.override
+		}
+	}
+	return capabilities
+}
+
+// DetectCapabilities returns the capabilities of the client, or the capabilities assumed for
+// its provider if it cannot detect them.
+func DetectCapabilities(client GenerativeAI) (Capabilities, error) {
+	if detector, ok := client.(CapabilityDetector); ok {
+		return detector.DetectCapabilities()
+	}
+	_, tools := client.(ToolCaller)
+	return Capabilities{Tools: tools}, nil
+}
+
+// DetectCapabilities inspects the template and projector of the model.
+// Models whose template handles tools support tool calling, and models with a vision projector accept images.
+func (o *Ollama) DetectCapabilities() (Capabilities, error) {
+	resp, err := o.Client.Show(context.Background(), &api.ShowRequest{Model: o.Model})
+	if err != nil {
+		return Capabilities{}, wrapOllamaError(err)
+	}
+
+	vision := len(resp.ProjectorInfo) > 0 ||
+		slices.ContainsFunc(resp.Details.Families, func(family string) bool {
+			return family == "clip" || family == "mllama"
+		})
+	return Capabilities{
+		Tools:            strings.Contains(resp.Template, ".Tools"),
+		Vision:           vision,
+		Streaming:        true,
+		StructuredOutput: true,
+		Reasoning:        strings.Contains(resp.Template, "<think>"),
+	}, nil
+}
+
+// DetectCapabilities returns the capabilities of the OpenAI API.
+// The models of OpenAI-compatible servers cannot be inspected, so all features of the API are assumed
+// except for structured output on lenient servers, and reasoning unless a reasoning effort is configured.
+func (o *OpenAI) DetectCapabilities() (Capabilities, error) {
+	return Capabilities{
+		Tools:            true,
+		Vision:           true,
+		Streaming:        true,
+		StructuredOutput: o.Compatibility == CompatibilityStrict,
+		Reasoning:        o.ReasoningEffort != "",
+	}, nil
+}
@@ -0,0 +1,98 @@
+package genai //nolint:testpackage // Unit tests are in the same package
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/ollama/ollama/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOllamaDetectCapabilities(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		expected Capabilities
+	}{
+		{
+			name:     "Text model",
+			response: `{"template":"{{ .Prompt }}","details":{"families":["llama"]}}`,
+			expected: Capabilities{Streaming: true, StructuredOutput: true},
+		},
+		{
+			name:     "Tool calling model",
+			response: `{"template":"{{ if .Tools }}{{ .Tools }}{{ end }}{{ .Prompt }}"}`,
+			expected: Capabilities{Tools: true, Streaming: true, StructuredOutput: true},
+		},
+		{
+			name:     "Vision model",
+			response: `{"template":"{{ .Prompt }}","details":{"families":["llama","clip"]}}`,
+			expected: Capabilities{Vision: true, Streaming: true, StructuredOutput: true},
+		},
+		{
+			name:     "Reasoning model",
+			response: `{"template":"{{ .Prompt }}<think>"}`,
+			expected: Capabilities{Streaming: true, StructuredOutput: true, Reasoning: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/api/show", r.URL.Path)
+				_, _ = w.Write([]byte(tt.response))
+			}))
+			defer server.Close()
+			baseURL, err := url.Parse(server.URL)
+			require.NoError(t, err)
+			client := &Ollama{Client: api.NewClient(baseURL, http.DefaultClient), Model: "llama3"}
+
+			// Act
+			capabilities, err := client.DetectCapabilities()
+
+			// Assert
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, capabilities)
+		})
+	}
+}
+
+func TestDetectCapabilities(t *testing.T) {
+	t.Run("OpenAI", func(t *testing.T) {
+		// Act
+		capabilities, err := DetectCapabilities(&OpenAI{Compatibility: CompatibilityLenient})
+
+		// Assert
+		require.NoError(t, err)
+		assert.True(t, capabilities.Tools)
+		assert.True(t, capabilities.Vision)
+		assert.False(t, capabilities.StructuredOutput)
+		assert.False(t, capabilities.Reasoning)
+	})
+
+	t.Run("Provider without detection", func(t *testing.T) {
+		// Act
+		capabilities, err := DetectCapabilities(&Mock{})
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, Capabilities{}, capabilities)
+	})
+}
+
+func TestCapabilityOverrides(t *testing.T) {
+	// Arrange
+	enabled, disabled := true, false
+	overrides := CapabilityOverrides{Vision: &disabled, Reasoning: &enabled}
+
+	// Act
+	capabilities := overrides.Apply(Capabilities{Tools: true, Vision: true})
+
+	// Assert
+	assert.Equal(t, Capabilities{Tools: true, Reasoning: true}, capabilities)
+	assert.Equal(t, []string{"tools", "reasoning"}, capabilities.Names())
+}
@@ -0,0 +1,152 @@
+package genai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+	"github.com/openai/openai-go"
+)
+
+var (
+	// ErrContextLengthExceeded is returned when the prompt does not fit into the model's context window.
+	ErrContextLengthExceeded = errors.New("context length exceeded")
+
+	// ErrRateLimited is returned when the provider rejects a request due to rate limits or quotas.
+	ErrRateLimited = errors.New("rate limited")
+
+	// ErrAuth is returned when the provider rejects the configured credentials.
+	ErrAuth = errors.New("authentication failed")
+
+	// ErrModelNotFound is returned when the configured model does not exist on the provider.
+	ErrModelNotFound = errors.New("model not found")
+)
+
+// classifyError returns the error kind matching a provider response, or nil if it is unknown.
+func classifyError(statusCode int, code string, message string) error {
+	message = strings.ToLower(message)
+	switch {
+	case code == "context_length_exceeded",
+		strings.Contains(message, "context length"),
+		strings.Contains(message, "context window"),
+		strings.Contains(message, "maximum context"),
+		strings.Contains(message, "input is too long"):
+		return ErrContextLengthExceeded
+	case code == "model_not_found",
+		statusCode == http.StatusNotFound && strings.Contains(message, "model"),
+		strings.Contains(message, "model") && strings.Contains(message, "not found"):
+		return ErrModelNotFound
+	case statusCode == http.StatusTooManyRequests, code == "rate_limit_exceeded", code == "insufficient_quota":
+		return ErrRateLimited
+	case statusCode == http.StatusUnauthorized, statusCode == http.StatusForbidden, code == "invalid_api_key":
+		return ErrAuth
+	default:
+		return nil
+	}
+}
+
+// IsUnavailable reports whether a request failed because the provider is unavailable, such as after timeouts,
+// connection errors, and server errors. Other errors, such as invalid credentials or models, concern the request.
+func IsUnavailable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var apiError *openai.Error
+	var statusError api.StatusError
+	var bedrockError *BedrockError
+	var netError net.Error
+	switch {
+	case errors.As(err, &apiError):
+		return apiError.StatusCode >= http.StatusInternalServerError
+	case errors.As(err, &statusError):
+		return statusError.StatusCode >= http.StatusInternalServerError
+	case errors.As(err, &bedrockError):
+		return bedrockError.StatusCode >= http.StatusInternalServerError
+	case errors.As(err, &netError):
+		return true
+	default:
+		return errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.ErrUnexpectedEOF)
+	}
+}
+
+// wrapOllamaError annotates an Ollama API error with its error kind.
+func wrapOllamaError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var kind error
+	var statusError api.StatusError
+	if errors.As(err, &statusError) {
+		kind = classifyError(statusError.StatusCode, "", statusError.ErrorMessage)
+	} else {
+		kind = classifyError(0, "", err.Error())
+	}
+
+	if kind == nil {
+		return err
+	}
+	return fmt.Errorf("%w: %w", kind, err)
+}
+
+// wrapOpenAIError annotates an OpenAI API error with its error kind.
+func wrapOpenAIError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var kind error
+	var apiError *openai.Error
+	if errors.As(err, &apiError) {
+		kind = classifyError(apiError.StatusCode, apiError.Code, apiError.Message)
+	} else {
+		kind = classifyError(0, "", err.Error())
+	}
+
+	if kind == nil {
+		return err
+	}
+	return fmt.Errorf("%w: %w", kind, err)
+}
+
+// wrapBedrockError annotates a Bedrock API error with its error kind.
+func wrapBedrockError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var kind error
+	var apiError *BedrockError
+	if errors.As(err, &apiError) {
+		kind = classifyError(apiError.StatusCode, bedrockErrorCode(apiError.Type), apiError.Message)
+	} else {
+		kind = classifyError(0, "", err.Error())
+	}
+
+	if kind == nil {
+		return err
+	}
+	return fmt.Errorf("%w: %w", kind, err)
+}
+
+// bedrockErrorCode maps a Bedrock exception to the OpenAI error code that classifyError understands.
+func bedrockErrorCode(errorType string) string {
+	switch errorType {
+	case "ThrottlingException":
+		return "rate_limit_exceeded"
+	case "ServiceQuotaExceededException":
+		return "insufficient_quota"
+	case "ResourceNotFoundException":
+		return "model_not_found"
+	case "UnrecognizedClientException":
+		return "invalid_api_key"
+	default:
+		return ""
+	}
+}
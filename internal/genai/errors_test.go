@@ -0,0 +1,193 @@
+package genai //nolint:testpackage // Unit tests are in the same package
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ollama/ollama/api"
+	"github.com/openai/openai-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrapOllamaError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected error
+	}{
+		{
+			name:     "Model not found",
+			err:      api.StatusError{StatusCode: http.StatusNotFound, ErrorMessage: `model "llama" not found, try pulling it first`},
+			expected: ErrModelNotFound,
+		},
+		{
+			name:     "Rate limited",
+			err:      api.StatusError{StatusCode: http.StatusTooManyRequests, ErrorMessage: "too many requests"},
+			expected: ErrRateLimited,
+		},
+		{
+			name:     "Unauthorized",
+			err:      api.StatusError{StatusCode: http.StatusUnauthorized, ErrorMessage: "unauthorized"},
+			expected: ErrAuth,
+		},
+		{
+			name:     "Context length exceeded",
+			err:      errors.New("input length exceeds maximum context length"),
+			expected: ErrContextLengthExceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			err := wrapOllamaError(tt.err)
+
+			// Assert
+			assert.ErrorIs(t, err, tt.expected)
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+
+	t.Run("Unknown error", func(t *testing.T) {
+		// Arrange
+		original := api.StatusError{StatusCode: http.StatusInternalServerError, ErrorMessage: "boom"}
+
+		// Act
+		err := wrapOllamaError(original)
+
+		// Assert
+		assert.Equal(t, original, err)
+		assert.NoError(t, wrapOllamaError(nil))
+	})
+}
+
+func TestWrapOpenAIError(t *testing.T) {
+	newError := func(statusCode int, code string, message string) *openai.Error {
+		return &openai.Error{
+			Code:       code,
+			Message:    message,
+			StatusCode: statusCode,
+			Request:    httptest.NewRequest(http.MethodPost, "https://api.openai.com/v1/chat/completions", nil),
+			Response:   &http.Response{StatusCode: statusCode},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		err      error
+		expected error
+	}{
+		{
+			name:     "Context length exceeded",
+			err:      newError(http.StatusBadRequest, "context_length_exceeded", "too long"),
+			expected: ErrContextLengthExceeded,
+		},
+		{
+			name:     "Rate limited",
+			err:      newError(http.StatusTooManyRequests, "rate_limit_exceeded", "slow down"),
+			expected: ErrRateLimited,
+		},
+		{
+			name:     "Invalid API key",
+			err:      newError(http.StatusUnauthorized, "invalid_api_key", "incorrect API key"),
+			expected: ErrAuth,
+		},
+		{
+			name:     "Model not found",
+			err:      newError(http.StatusNotFound, "model_not_found", "the model does not exist"),
+			expected: ErrModelNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			err := wrapOpenAIError(tt.err)
+
+			// Assert
+			assert.ErrorIs(t, err, tt.expected)
+
+			var apiError *openai.Error
+			assert.ErrorAs(t, err, &apiError)
+		})
+	}
+}
+
+func TestIsUnavailable(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		unavailable bool
+	}{
+		{
+			name:        "Ollama server error",
+			err:         api.StatusError{StatusCode: http.StatusServiceUnavailable},
+			unavailable: true,
+		},
+		{
+			name:        "OpenAI server error",
+			err:         fmt.Errorf("chat: %w", &openai.Error{StatusCode: http.StatusBadGateway}),
+			unavailable: true,
+		},
+		{
+			name:        "Bedrock server error",
+			err:         &BedrockError{StatusCode: http.StatusInternalServerError, Type: "InternalServerException"},
+			unavailable: true,
+		},
+		{
+			name:        "Connection refused",
+			err:         &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")},
+			unavailable: true,
+		},
+		{
+			name:        "Timeout",
+			err:         fmt.Errorf("request: %w", context.DeadlineExceeded),
+			unavailable: true,
+		},
+		{
+			name:        "Invalid API key",
+			err:         fmt.Errorf("%w: %w", ErrAuth, &openai.Error{StatusCode: http.StatusUnauthorized}),
+			unavailable: false,
+		},
+		{
+			name:        "Model not found",
+			err:         api.StatusError{StatusCode: http.StatusNotFound, ErrorMessage: "model not found"},
+			unavailable: false,
+		},
+		{
+			name:        "Bad request",
+			err:         &BedrockError{StatusCode: http.StatusBadRequest, Type: "ValidationException"},
+			unavailable: false,
+		},
+		{
+			name:        "Rate limited",
+			err:         api.StatusError{StatusCode: http.StatusTooManyRequests},
+			unavailable: false,
+		},
+		{
+			name:        "Other error",
+			err:         errors.New("OpenAI chat completion returned no choices"),
+			unavailable: false,
+		},
+		{
+			name:        "No error",
+			err:         nil,
+			unavailable: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			unavailable := IsUnavailable(tt.err)
+
+			// Assert
+			assert.Equal(t, tt.unavailable, unavailable)
+		})
+	}
+}
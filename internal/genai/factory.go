@@ -1,7 +1,9 @@
 package genai
 
 import (
+	"errors"
 	"fmt"
+	"sync"
 )
 
 type ProviderConfig interface {
@@ -10,13 +12,74 @@ type ProviderConfig interface {
 
 type ProviderFactory func(ProviderConfig) (GenerativeAI, error)
 
-func New(p Provider, config ProviderConfig) (GenerativeAI, error) {
-	providerRegistry := map[Provider]ProviderFactory{
-		ProviderOllama: newOllamaClient,
-		ProviderOpenAI: newOpenAIClient,
+// registeredProvider is a provider that can be created by New.
+type registeredProvider struct {
+	name          string
+	factory       ProviderFactory
+	configFactory func() ProviderConfig
+}
+
+var (
+	// providerRegistry holds the providers indexed by their Provider value, starting with the built-in ones.
+	providerRegistry = []registeredProvider{ //nolint:gochecknoglobals // Extended by Register
+		{name: "ollama", factory: newOllamaClient, configFactory: func() ProviderConfig { return &OllamaConfig{} }},
+		{name: "openai", factory: newOpenAIClient, configFactory: func() ProviderConfig { return &OpenAIConfig{} }},
+		{name: "mock", factory: newMockClient, configFactory: func() ProviderConfig { return &MockConfig{} }},
+		{name: "bedrock", factory: newBedrockClient, configFactory: func() ProviderConfig { return &BedrockConfig{} }},
+	}
+	providerRegistryMutex sync.RWMutex //nolint:gochecknoglobals // Guards providerRegistry
+)
+
+// Register adds a provider so that it can be selected by name with genai.provider and created by New.
+// The configuration returned by configFactory is filled from the configuration section named after
+// the provider, matching keys to field names or mapstructure tags.
+// It returns the Provider value assigned to the provider.
+func Register(name string, factory ProviderFactory, configFactory func() ProviderConfig) (Provider, error) {
+	if name == "" {
+		return 0, errors.New("provider name cannot be empty")
 	}
+	if factory == nil || configFactory == nil {
+		return 0, errors.New("provider factories cannot be nil")
+	}
+
+	providerRegistryMutex.Lock()
+	defer providerRegistryMutex.Unlock()
+
+	for _, registered := range providerRegistry {
+		if registered.name == name {
+			return 0, fmt.Errorf("provider %s is already registered", name)
+		}
+	}
+	providerRegistry = append(providerRegistry, registeredProvider{
+		name:          name,
+		factory:       factory,
+		configFactory: configFactory,
+	})
+	return Provider(len(providerRegistry) - 1), nil
+}
 
-	factory, exists := providerRegistry[p]
+// lookupProvider returns the registration of a provider.
+func lookupProvider(p Provider) (registeredProvider, bool) {
+	providerRegistryMutex.RLock()
+	defer providerRegistryMutex.RUnlock()
+
+	if p < 0 || int(p) >= len(providerRegistry) {
+		return registeredProvider{}, false
+	}
+	return providerRegistry[p], true
+}
+
+// NewProviderConfig returns an empty configuration of the provider.
+func NewProviderConfig(p Provider) (ProviderConfig, error) {
+	registered, exists := lookupProvider(p)
+	if !exists {
+		return nil, fmt.Errorf("provider %s not supported", p)
+	}
+	return registered.configFactory(), nil
+}
+
+func New(p Provider, config ProviderConfig) (GenerativeAI, error) {
+	registered, exists := lookupProvider(p)
 	if !exists {
 		return nil, fmt.Errorf("provider %s not supported", p)
 	}
@@ -26,5 +89,5 @@ func New(p Provider, config ProviderConfig) (GenerativeAI, error) {
 		return nil, fmt.Errorf("invalid config: %w", err)
 	}
 
-	return factory(config)
+	return registered.factory(config)
 }
@@ -0,0 +1,56 @@
+package genai //nolint:testpackage // Unit tests are in the same package
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegister(t *testing.T) {
+	// Arrange
+	factory := func(config ProviderConfig) (GenerativeAI, error) {
+		cfg, ok := config.(*MockConfig)
+		require.True(t, ok)
+		return &Mock{Model: cfg.Model, Response: "registered"}, nil
+	}
+
+	// Act
+	provider, err := Register("test-registered", factory, func() ProviderConfig { return &MockConfig{} })
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "test-registered", provider.String())
+
+	parsed, err := ParseProvider("test-registered")
+	require.NoError(t, err)
+	assert.Equal(t, provider, parsed)
+
+	config, err := NewProviderConfig(provider)
+	require.NoError(t, err)
+	assert.IsType(t, &MockConfig{}, config)
+
+	client, err := New(provider, &MockConfig{Model: "plugin"})
+	require.NoError(t, err)
+	response, _, err := client.Chat([]Message{{Role: "user", Content: "Hi"}})
+	require.NoError(t, err)
+	assert.Equal(t, "registered", response)
+
+	// Names cannot be registered twice, including those of the built-in providers
+	_, err = Register("test-registered", factory, func() ProviderConfig { return &MockConfig{} })
+	require.Error(t, err)
+	_, err = Register("openai", factory, func() ProviderConfig { return &MockConfig{} })
+	require.Error(t, err)
+	_, err = Register("", factory, func() ProviderConfig { return &MockConfig{} })
+	require.Error(t, err)
+}
+
+func TestProviderString(t *testing.T) {
+	// Assert
+	assert.Equal(t, "ollama", ProviderOllama.String())
+	assert.Equal(t, "bedrock", ProviderBedrock.String())
+	assert.Equal(t, "Provider(1000)", Provider(1000).String())
+
+	_, err := New(Provider(1000), &MockConfig{Model: "mock"})
+	assert.ErrorContains(t, err, "provider Provider(1000) not supported")
+}
@@ -0,0 +1,45 @@
+package genai
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// ResponseFormatJSON constrains replies to a JSON object of any shape.
+// Any other non-empty response format is a JSON schema that replies must conform to.
+const ResponseFormatJSON = "json"
+
+// responseSchemaName is the name given to JSON schemas in OpenAI requests, which require one.
+const responseSchemaName = "response"
+
+// ValidateResponseFormat checks that a response format is empty, "json", or a JSON schema object.
+func ValidateResponseFormat(format string) error {
+	if format == "" || format == ResponseFormatJSON {
+		return nil
+	}
+	if _, err := responseSchema(format); err != nil {
+		return err
+	}
+	return nil
+}
+
+// responseSchema parses a JSON schema response format.
+func responseSchema(format string) (map[string]any, error) {
+	var schema map[string]any
+	if err := json.Unmarshal([]byte(format), &schema); err != nil || schema == nil {
+		return nil, errors.New(`response format must be "json" or a JSON schema object`)
+	}
+	return schema, nil
+}
+
+// ollamaFormat returns the format parameter of Ollama requests for a response format.
+func ollamaFormat(format string) json.RawMessage {
+	switch format {
+	case "":
+		return nil
+	case ResponseFormatJSON:
+		return json.RawMessage(`"json"`)
+	default:
+		return json.RawMessage(format)
+	}
+}
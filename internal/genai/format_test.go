@@ -0,0 +1,23 @@
+package genai //nolint:testpackage // Unit tests are in the same package
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateResponseFormat(t *testing.T) {
+	for _, format := range []string{"", "json", `{"type":"object","properties":{"answer":{"type":"string"}}}`} {
+		assert.NoError(t, ValidateResponseFormat(format), format)
+	}
+	for _, format := range []string{"xml", `["object"]`, `{"type":`, "null"} {
+		assert.Error(t, ValidateResponseFormat(format), format)
+	}
+}
+
+func TestOllamaFormat(t *testing.T) {
+	assert.Nil(t, ollamaFormat(""))
+	assert.Equal(t, json.RawMessage(`"json"`), ollamaFormat(ResponseFormatJSON))
+	assert.Equal(t, json.RawMessage(`{"type":"object"}`), ollamaFormat(`{"type":"object"}`))
+}
@@ -2,6 +2,7 @@ package genai
 
 import (
 	"errors"
+	"fmt"
 	"time"
 )
 
@@ -10,21 +11,29 @@ type Provider int
 const (
 	ProviderOllama Provider = iota
 	ProviderOpenAI
+	ProviderMock
+	ProviderBedrock
 )
 
 func (p Provider) String() string {
-	return [...]string{"ollama", "openai"}[p]
+	registered, exists := lookupProvider(p)
+	if !exists {
+		return fmt.Sprintf("Provider(%d)", int(p))
+	}
+	return registered.name
 }
 
+// ParseProvider returns the built-in or registered provider with the given name.
 func ParseProvider(s string) (Provider, error) {
-	switch s {
-	case "ollama":
-		return ProviderOllama, nil
-	case "openai":
-		return ProviderOpenAI, nil
-	default:
-		return 0, errors.New("unknown provider")
+	providerRegistryMutex.RLock()
+	defer providerRegistryMutex.RUnlock()
+
+	for i, registered := range providerRegistry {
+		if registered.name == s {
+			return Provider(i), nil
+		}
 	}
+	return 0, errors.New("unknown provider")
 }
 
 type Mode int
@@ -50,8 +59,12 @@ func ParseMode(s string) (Mode, error) {
 }
 
 type Message struct {
-	Role    string
-	Content string
+	Role       string
+	Content    string
+	Images     [][]byte
+	ToolCalls  []ToolCall
+	ToolCallID string
+	ToolName   string
 }
 
 type GenerateStats struct {
@@ -62,9 +75,40 @@ type GenerateStats struct {
 	PromptEvalDuration time.Duration
 	TokenCount         int64
 	EvalDuration       time.Duration
+
+	// Reasoning is the reasoning removed from the response, if any
+	Reasoning string
+}
+
+// Add accumulates the statistics of another generation, such as one round of a tool-calling loop.
+func (s *GenerateStats) Add(other GenerateStats) {
+	s.DoneReason = other.DoneReason
+	s.TotalDuration += other.TotalDuration
+	s.LoadDuration += other.LoadDuration
+	s.PromptTokens += other.PromptTokens
+	s.PromptEvalDuration += other.PromptEvalDuration
+	s.TokenCount += other.TokenCount
+	s.EvalDuration += other.EvalDuration
+	s.Reasoning = joinReasoning(s.Reasoning, other.Reasoning)
 }
 
 type GenerativeAI interface {
 	Chat(messages []Message) (string, GenerateStats, error)
 	Complete(prompt string) (string, GenerateStats, error)
 }
+
+// ModelLister is implemented by providers that can list the models they serve.
+type ModelLister interface {
+	ListModels() ([]string, error)
+}
+
+// Warmer is implemented by providers that can be warmed up ahead of requests,
+// such as by opening a connection or loading the model into memory.
+type Warmer interface {
+	Warm() error
+}
+
+// Embedder is implemented by providers that can compute text embeddings.
+type Embedder interface {
+	Embed(inputs []string) ([][]float32, error)
+}
@@ -0,0 +1,92 @@
+package genai
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// Mock is a provider that returns canned responses without calling a model,
+// for running the bot during development without a generative AI backend.
+type Mock struct {
+	Model    string
+	Response string
+	Latency  time.Duration
+}
+
+type MockConfig struct {
+	Model string
+
+	// Response is returned for every request; if empty, the last user message is echoed
+	Response string
+
+	// Latency is the artificial delay before each response
+	Latency time.Duration
+}
+
+func (c *MockConfig) Validate() error {
+	if c.Model == "" {
+		return errors.New("model cannot be empty")
+	}
+	if c.Latency < 0 {
+		return errors.New("latency cannot be negative")
+	}
+	return nil
+}
+
+func newMockClient(config ProviderConfig) (GenerativeAI, error) {
+	cfg, ok := config.(*MockConfig)
+	if !ok {
+		return nil, errors.New("invalid config type for mock")
+	}
+
+	return &Mock{
+		Model:    cfg.Model,
+		Response: cfg.Response,
+		Latency:  cfg.Latency,
+	}, nil
+}
+
+// Chat returns the canned response or echoes the last user message of the conversation.
+func (m *Mock) Chat(messages []Message) (string, GenerateStats, error) {
+	var prompt string
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "user" {
+			prompt = messages[i].Content
+			break
+		}
+	}
+	return m.respond(prompt)
+}
+
+// Complete returns the canned response or echoes the prompt.
+func (m *Mock) Complete(prompt string) (string, GenerateStats, error) {
+	return m.respond(prompt)
+}
+
+// ListModels returns the configured model as the only available model.
+func (m *Mock) ListModels() ([]string, error) {
+	return []string{m.Model}, nil
+}
+
+// Warm does nothing since there is no backend to connect to.
+func (m *Mock) Warm() error {
+	return nil
+}
+
+// respond waits for the configured latency and returns the response to the prompt.
+func (m *Mock) respond(prompt string) (string, GenerateStats, error) {
+	time.Sleep(m.Latency)
+
+	response := m.Response
+	if response == "" {
+		response = "Echo: " + strings.TrimSpace(prompt)
+	}
+	return response, GenerateStats{
+		DoneReason:    "stop",
+		TotalDuration: m.Latency,
+		EvalDuration:  m.Latency,
+		PromptTokens:  int64(len(strings.Fields(prompt))),
+		TokenCount:    int64(len(strings.Fields(response))),
+	}, nil
+}
@@ -0,0 +1,52 @@
+package genai //nolint:testpackage // Unit tests are in the same package
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMock(t *testing.T) {
+	t.Run("Echo the last user message", func(t *testing.T) {
+		// Arrange
+		client, err := New(ProviderMock, &MockConfig{Model: "mock", Latency: 10 * time.Millisecond})
+		require.NoError(t, err)
+
+		// Act
+		start := time.Now()
+		response, stats, err := client.Chat([]Message{
+			{Role: "system", Content: "You are a bot."},
+			{Role: "user", Content: "Hello there"},
+			{Role: "assistant", Content: "Hi"},
+		})
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, "Echo: Hello there", response)
+		assert.Equal(t, int64(2), stats.PromptTokens)
+		assert.GreaterOrEqual(t, time.Since(start), 10*time.Millisecond)
+	})
+
+	t.Run("Canned response", func(t *testing.T) {
+		// Arrange
+		client, err := New(ProviderMock, &MockConfig{Model: "mock", Response: "Canned reply"})
+		require.NoError(t, err)
+
+		// Act
+		response, _, err := client.Complete("Anything")
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, "Canned reply", response)
+	})
+
+	t.Run("Invalid config", func(t *testing.T) {
+		// Act
+		_, err := New(ProviderMock, &MockConfig{Model: "mock", Latency: -time.Second})
+
+		// Assert
+		assert.ErrorContains(t, err, "latency cannot be negative")
+	})
+}
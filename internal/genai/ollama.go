@@ -2,25 +2,40 @@ package genai
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/ollama/ollama/api"
 )
 
 type Ollama struct {
-	Client  *api.Client
-	Model   string
-	Options map[string]any
+	Client           *api.Client
+	Model            string
+	Options          map[string]any
+	Format           json.RawMessage
+	KeepAlive        *api.Duration
+	ReasoningPattern *regexp.Regexp
+	Retry            RetryPolicy
 }
 
 type OllamaConfig struct {
-	BaseURL string
-	Model   string
-	Options map[string]any
+	BaseURL          string
+	Model            string
+	Options          map[string]any
+	ResponseFormat   string
+	ReasoningPattern string
+	Retry            RetryPolicy
+
+	// KeepAlive is how long the model stays loaded after a request, such as "30m", or a number of seconds.
+	// Negative values keep the model loaded indefinitely, and an empty value uses the server default.
+	KeepAlive string
 }
 
 func (c *OllamaConfig) Validate() error {
@@ -30,7 +45,32 @@ func (c *OllamaConfig) Validate() error {
 	if c.Model == "" {
 		return errors.New("model cannot be empty")
 	}
-	return nil
+	if _, err := compileReasoningPattern(c.ReasoningPattern); err != nil {
+		return err
+	}
+	if err := ValidateResponseFormat(c.ResponseFormat); err != nil {
+		return err
+	}
+	if _, err := parseKeepAlive(c.KeepAlive); err != nil {
+		return err
+	}
+	return c.Retry.Validate()
+}
+
+// parseKeepAlive parses the keep-alive duration of Ollama models, returning nil if it is empty.
+// Like the Ollama API, it accepts durations and numbers of seconds.
+func parseKeepAlive(keepAlive string) (*api.Duration, error) {
+	if keepAlive == "" {
+		return nil, nil //nolint:nilnil // An empty keep-alive is not sent
+	}
+	if seconds, err := strconv.Atoi(keepAlive); err == nil {
+		return &api.Duration{Duration: time.Duration(seconds) * time.Second}, nil
+	}
+	duration, err := time.ParseDuration(keepAlive)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keep-alive duration: %w", err)
+	}
+	return &api.Duration{Duration: duration}, nil
 }
 
 func newOllamaClient(config ProviderConfig) (GenerativeAI, error) {
@@ -44,43 +84,126 @@ func newOllamaClient(config ProviderConfig) (GenerativeAI, error) {
 		return nil, fmt.Errorf("invalid host URL: %w", err)
 	}
 
+	reasoningPattern, err := compileReasoningPattern(cfg.ReasoningPattern)
+	if err != nil {
+		return nil, err
+	}
+
+	keepAlive, err := parseKeepAlive(cfg.KeepAlive)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Ollama{
-		Client:  api.NewClient(baseURL, http.DefaultClient),
-		Model:   cfg.Model,
-		Options: cfg.Options,
+		Client:           api.NewClient(baseURL, http.DefaultClient),
+		Model:            cfg.Model,
+		Options:          cfg.Options,
+		Format:           ollamaFormat(cfg.ResponseFormat),
+		KeepAlive:        keepAlive,
+		ReasoningPattern: reasoningPattern,
+		Retry:            cfg.Retry,
 	}, nil
 }
 
 // Chat generates a response from Ollama using a conversation history.
 func (o *Ollama) Chat(messages []Message) (string, GenerateStats, error) {
+	reply, genStats, err := o.chat(messages, nil)
+	if err != nil {
+		return "", GenerateStats{}, err
+	}
+	return reply.Content, genStats, nil
+}
+
+// ChatWithTools generates the next assistant message, allowing the model to call tools.
+func (o *Ollama) ChatWithTools(messages []Message, tools []Tool) (Message, GenerateStats, error) {
+	apiTools := make(api.Tools, len(tools))
+	for i, tool := range tools {
+		apiTools[i] = api.Tool{
+			Type: "function",
+			Function: api.ToolFunction{
+				Name:        tool.Name(),
+				Description: tool.Description(),
+			},
+		}
+
+		// Convert the JSON schema into the structure expected by the Ollama API
+		schema, err := json.Marshal(tool.Parameters())
+		if err != nil {
+			return Message{}, GenerateStats{}, fmt.Errorf("invalid schema for tool %s: %w", tool.Name(), err)
+		}
+		err = json.Unmarshal(schema, &apiTools[i].Function.Parameters)
+		if err != nil {
+			return Message{}, GenerateStats{}, fmt.Errorf("invalid schema for tool %s: %w", tool.Name(), err)
+		}
+	}
+
+	return o.chat(messages, apiTools)
+}
+
+func (o *Ollama) chat(messages []Message, tools api.Tools) (Message, GenerateStats, error) {
 	apiMessages := make([]api.Message, len(messages))
 	for i, message := range messages {
 		apiMessages[i] = api.Message{
 			Role:    message.Role,
 			Content: message.Content,
 		}
+		for _, image := range message.Images {
+			apiMessages[i].Images = append(apiMessages[i].Images, api.ImageData(image))
+		}
+		for _, toolCall := range message.ToolCalls {
+			var arguments api.ToolCallFunctionArguments
+			if err := json.Unmarshal([]byte(toolCall.Arguments), &arguments); err != nil {
+				return Message{}, GenerateStats{}, fmt.Errorf("invalid tool call arguments: %w", err)
+			}
+			apiMessages[i].ToolCalls = append(apiMessages[i].ToolCalls, api.ToolCall{
+				Function: api.ToolCallFunction{
+					Name:      toolCall.Name,
+					Arguments: arguments,
+				},
+			})
+		}
 	}
 
 	var responseBuilder strings.Builder
 	var chatResp api.ChatResponse
+	var toolCalls []ToolCall
 
-	err := o.Client.Chat(
-		context.Background(),
-		&api.ChatRequest{
-			Model:    o.Model,
-			Messages: apiMessages,
-			Options:  o.Options,
-		},
-		func(resp api.ChatResponse) error {
-			chatResp = resp
-			responseBuilder.WriteString(resp.Message.Content)
-			return nil
-		},
-	)
+	err := o.Retry.do(func() error {
+		// Discard the output streamed by a failed attempt
+		responseBuilder.Reset()
+		toolCalls = nil
+
+		return o.Client.Chat(
+			context.Background(),
+			&api.ChatRequest{
+				Model:     o.Model,
+				Messages:  apiMessages,
+				Tools:     tools,
+				Format:    o.Format,
+				Options:   o.Options,
+				KeepAlive: o.KeepAlive,
+			},
+			func(resp api.ChatResponse) error {
+				chatResp = resp
+				responseBuilder.WriteString(resp.Message.Content)
+				for _, toolCall := range resp.Message.ToolCalls {
+					// Ollama does not assign IDs to tool calls, so generate them locally
+					toolCalls = append(toolCalls, ToolCall{
+						ID:        fmt.Sprintf("call_%d", len(toolCalls)),
+						Name:      toolCall.Function.Name,
+						Arguments: toolCall.Function.Arguments.String(),
+					})
+				}
+				return nil
+			},
+		)
+	})
 	if err != nil {
-		return "", GenerateStats{}, err
+		return Message{}, GenerateStats{}, wrapOllamaError(err)
 	}
 
+	content, reasoning := extractReasoning(o.ReasoningPattern, responseBuilder.String())
+
 	genStats := GenerateStats{
 		DoneReason:         chatResp.DoneReason,
 		TotalDuration:      chatResp.TotalDuration,
@@ -89,34 +212,45 @@ func (o *Ollama) Chat(messages []Message) (string, GenerateStats, error) {
 		PromptEvalDuration: chatResp.PromptEvalDuration,
 		TokenCount:         int64(chatResp.EvalCount),
 		EvalDuration:       chatResp.EvalDuration,
+		Reasoning:          reasoning,
 	}
 
-	return responseBuilder.String(), genStats, nil
+	return Message{
+		Role:      "assistant",
+		Content:   content,
+		ToolCalls: toolCalls,
+	}, genStats, nil
 }
 
 func (o *Ollama) Complete(prompt string) (string, GenerateStats, error) {
 	var responseBuilder strings.Builder
 	var generateResp api.GenerateResponse
 
-	err := o.Client.Generate(
-		context.Background(),
-		&api.GenerateRequest{
-			Model:   o.Model,
-			Prompt:  prompt,
-			Raw:     true,
-			Options: o.Options,
-		},
-		func(resp api.GenerateResponse) error {
-			generateResp = resp
-			responseBuilder.WriteString(resp.Response)
-			return nil
-		},
-	)
+	err := o.Retry.do(func() error {
+		// Discard the output streamed by a failed attempt
+		responseBuilder.Reset()
+
+		return o.Client.Generate(
+			context.Background(),
+			&api.GenerateRequest{
+				Model:     o.Model,
+				Prompt:    prompt,
+				Raw:       true,
+				Options:   o.Options,
+				KeepAlive: o.KeepAlive,
+			},
+			func(resp api.GenerateResponse) error {
+				generateResp = resp
+				responseBuilder.WriteString(resp.Response)
+				return nil
+			},
+		)
+	})
 	if err != nil {
-		return "", GenerateStats{}, err
+		return "", GenerateStats{}, wrapOllamaError(err)
 	}
 
-	response := strings.TrimSpace(responseBuilder.String())
+	response, reasoning := extractReasoning(o.ReasoningPattern, strings.TrimSpace(responseBuilder.String()))
 
 	genStats := GenerateStats{
 		DoneReason:         generateResp.DoneReason,
@@ -126,7 +260,47 @@ func (o *Ollama) Complete(prompt string) (string, GenerateStats, error) {
 		PromptEvalDuration: generateResp.PromptEvalDuration,
 		TokenCount:         int64(generateResp.EvalCount),
 		EvalDuration:       generateResp.EvalDuration,
+		Reasoning:          reasoning,
 	}
 
 	return response, genStats, nil
 }
+
+// ListModels returns the names of the models available on the Ollama server.
+func (o *Ollama) ListModels() ([]string, error) {
+	response, err := o.Client.List(context.Background())
+	if err != nil {
+		return nil, wrapOllamaError(err)
+	}
+
+	models := make([]string, len(response.Models))
+	for i, model := range response.Models {
+		models[i] = model.Name
+	}
+	return models, nil
+}
+
+// Warm loads the model into memory by sending a generate request without a prompt.
+func (o *Ollama) Warm() error {
+	err := o.Client.Generate(
+		context.Background(),
+		&api.GenerateRequest{Model: o.Model, KeepAlive: o.KeepAlive},
+		func(api.GenerateResponse) error { return nil },
+	)
+	if err != nil {
+		return fmt.Errorf("failed to load model: %w", wrapOllamaError(err))
+	}
+	return nil
+}
+
+// Embed computes the embeddings of the inputs using the configured model.
+func (o *Ollama) Embed(inputs []string) ([][]float32, error) {
+	response, err := o.Client.Embed(context.Background(), &api.EmbedRequest{
+		Model: o.Model,
+		Input: inputs,
+	})
+	if err != nil {
+		return nil, wrapOllamaError(err)
+	}
+	return response.Embeddings, nil
+}
@@ -0,0 +1,46 @@
+package genai //nolint:testpackage // Unit tests are in the same package
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseKeepAlive(t *testing.T) {
+	t.Run("Empty", func(t *testing.T) {
+		// Act
+		keepAlive, err := parseKeepAlive("")
+
+		// Assert
+		require.NoError(t, err)
+		assert.Nil(t, keepAlive)
+	})
+
+	t.Run("Duration", func(t *testing.T) {
+		// Act
+		keepAlive, err := parseKeepAlive("30m")
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, 30*time.Minute, keepAlive.Duration)
+	})
+
+	t.Run("Seconds", func(t *testing.T) {
+		// Act
+		keepAlive, err := parseKeepAlive("-1")
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, -time.Second, keepAlive.Duration)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		// Act
+		_, err := parseKeepAlive("forever")
+
+		// Assert
+		assert.Error(t, err)
+	})
+}
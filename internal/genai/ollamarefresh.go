@@ -0,0 +1,147 @@
+package genai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ollama/ollama/api"
+)
+
+// DefaultOllamaRegistry is the registry Ollama pulls models from when the model name has no host.
+const DefaultOllamaRegistry = "https://registry.ollama.ai"
+
+// ollamaManifestMediaType is the media type of the model manifests served by the registry.
+const ollamaManifestMediaType = "application/vnd.docker.distribution.manifest.v2+json"
+
+// registryTimeout bounds the time spent fetching a manifest from the registry.
+const registryTimeout = 30 * time.Second
+
+// ModelReference is a model name split into the parts used to address it in a registry.
+type ModelReference struct {
+	Host      string
+	Namespace string
+	Name      string
+	Tag       string
+}
+
+// ParseModelReference splits a model name such as "llama3", "library/llama3:8b", or
+// "registry.example.com/team/model:tag" into its parts, filling in Ollama's defaults.
+// An empty host means the default registry.
+func ParseModelReference(model string) (ModelReference, error) {
+	ref := ModelReference{Namespace: "library", Tag: "latest"}
+
+	name := model
+	if index := strings.LastIndex(name, ":"); index > strings.LastIndex(name, "/") {
+		ref.Tag = name[index+1:]
+		name = name[:index]
+	}
+
+	parts := strings.Split(name, "/")
+	if len(parts) > 1 && strings.ContainsAny(parts[0], ".:") {
+		ref.Host = parts[0]
+		parts = parts[1:]
+	}
+	switch len(parts) {
+	case 1:
+		ref.Name = parts[0]
+	case 2:
+		ref.Namespace, ref.Name = parts[0], parts[1]
+	default:
+		return ModelReference{}, fmt.Errorf("invalid model name %q", model)
+	}
+	if ref.Name == "" || ref.Tag == "" {
+		return ModelReference{}, fmt.Errorf("invalid model name %q", model)
+	}
+	return ref, nil
+}
+
+// String returns the name Ollama uses for the model in its local model list.
+func (r ModelReference) String() string {
+	name := r.Name + ":" + r.Tag
+	if r.Host != "" {
+		return r.Host + "/" + r.Namespace + "/" + name
+	}
+	if r.Namespace != "library" {
+		return r.Namespace + "/" + name
+	}
+	return name
+}
+
+// RegistryDigest returns the digest of the latest manifest of a model in the registry.
+// The registry URL is used for models that do not name their own registry host.
+func RegistryDigest(registryURL string, model string) (string, error) {
+	ref, err := ParseModelReference(model)
+	if err != nil {
+		return "", err
+	}
+
+	baseURL := strings.TrimSuffix(registryURL, "/")
+	if ref.Host != "" {
+		baseURL = "https://" + ref.Host
+	}
+	manifestURL := fmt.Sprintf("%s/v2/%s/%s/manifests/%s", baseURL, ref.Namespace, ref.Name, ref.Tag)
+
+	ctx, cancel := context.WithTimeout(context.Background(), registryTimeout)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, manifestURL, nil)
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Accept", ollamaManifestMediaType)
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch model manifest: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to fetch model manifest: registry returned %s", response.Status)
+	}
+
+	// Ollama identifies local models by the digest of their manifest
+	manifest, err := io.ReadAll(response.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read model manifest: %w", err)
+	}
+	digest := sha256.Sum256(manifest)
+	return hex.EncodeToString(digest[:]), nil
+}
+
+// LocalDigest returns the digest of the configured model on the Ollama server.
+func (o *Ollama) LocalDigest() (string, error) {
+	ref, err := ParseModelReference(o.Model)
+	if err != nil {
+		return "", err
+	}
+
+	response, err := o.Client.List(context.Background())
+	if err != nil {
+		return "", wrapOllamaError(err)
+	}
+	for _, model := range response.Models {
+		if model.Name == ref.String() || model.Model == ref.String() {
+			return model.Digest, nil
+		}
+	}
+	return "", errors.New("model is not available on the Ollama server")
+}
+
+// Pull downloads the latest version of the configured model.
+func (o *Ollama) Pull() error {
+	err := o.Client.Pull(
+		context.Background(),
+		&api.PullRequest{Model: o.Model},
+		func(api.ProgressResponse) error { return nil },
+	)
+	if err != nil {
+		return fmt.Errorf("failed to pull model: %w", wrapOllamaError(err))
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package genai //nolint:testpackage // Unit tests are in the same package
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/ollama/ollama/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseModelReference(t *testing.T) {
+	tests := []struct {
+		model    string
+		expected ModelReference
+		name     string
+	}{
+		{"llama3", ModelReference{"", "library", "llama3", "latest"}, "llama3:latest"},
+		{"llama3:8b", ModelReference{"", "library", "llama3", "8b"}, "llama3:8b"},
+		{"team/model:v2", ModelReference{"", "team", "model", "v2"}, "team/model:v2"},
+		{
+			"registry.example.com:5000/team/model",
+			ModelReference{"registry.example.com:5000", "team", "model", "latest"},
+			"registry.example.com:5000/team/model:latest",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.model, func(t *testing.T) {
+			// Act
+			ref, err := ParseModelReference(test.model)
+
+			// Assert
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, ref)
+			assert.Equal(t, test.name, ref.String())
+		})
+	}
+
+	_, err := ParseModelReference("a/b/c/d")
+	assert.Error(t, err)
+}
+
+func TestRegistryDigest(t *testing.T) {
+	// Arrange
+	manifest := []byte(`{"schemaVersion":2}`)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/library/llama3/manifests/8b" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		assert.Equal(t, ollamaManifestMediaType, r.Header.Get("Accept"))
+		_, _ = w.Write(manifest)
+	}))
+	defer server.Close()
+	sum := sha256.Sum256(manifest)
+
+	// Act
+	digest, err := RegistryDigest(server.URL, "llama3:8b")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, hex.EncodeToString(sum[:]), digest)
+
+	_, err = RegistryDigest(server.URL, "missing")
+	assert.ErrorContains(t, err, "404")
+}
+
+func TestOllamaLocalDigest(t *testing.T) {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"models":[{"name":"llama3:latest","model":"llama3:latest","digest":"abc123"}]}`))
+	}))
+	defer server.Close()
+	baseURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	client := &Ollama{Client: api.NewClient(baseURL, http.DefaultClient), Model: "llama3"}
+
+	// Act
+	digest, err := client.LocalDigest()
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "abc123", digest)
+
+	client.Model = "mistral"
+	_, err = client.LocalDigest()
+	assert.Error(t, err)
+}
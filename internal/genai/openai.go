@@ -2,8 +2,11 @@ package genai
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"net/http"
+	"regexp"
 	"time"
 
 	"github.com/openai/openai-go"
@@ -11,29 +14,67 @@ import (
 	"github.com/openai/openai-go/shared"
 )
 
+// Compatibility selects which request fields are sent to an OpenAI-compatible server.
+type Compatibility int
+
+const (
+	// CompatibilityStrict sends requests as specified by the OpenAI API.
+	CompatibilityStrict Compatibility = iota
+	// CompatibilityLenient omits fields and formats that third-party gateways
+	// such as Groq, OpenRouter, and Together commonly reject.
+	CompatibilityLenient
+)
+
+func (c Compatibility) String() string {
+	return [...]string{"strict", "lenient"}[c]
+}
+
+func ParseCompatibility(s string) (Compatibility, error) {
+	switch s {
+	case "strict":
+		return CompatibilityStrict, nil
+	case "lenient":
+		return CompatibilityLenient, nil
+	default:
+		return 0, errors.New("unknown compatibility profile")
+	}
+}
+
+// The sampling options are unset when nil and are then left to the server's defaults.
+// MaxTokens is unset when not positive, and ReasoningEffort and Stop when empty.
 type OpenAI struct {
 	Client           *openai.Client
 	Model            string
-	FrequencyPenalty float64
+	Compatibility    Compatibility
+	FrequencyPenalty *float64
 	MaxTokens        int64
-	PresencePenalty  float64
+	PresencePenalty  *float64
 	ReasoningEffort  string
 	Stop             string
-	Temperature      float64
-	TopP             float64
+	Temperature      *float64
+	TopP             *float64
+	ResponseFormat   string
+	ReasoningPattern *regexp.Regexp
+	ReasoningField   string
+	Retry            RetryPolicy
 }
 
 type OpenAIConfig struct {
 	BaseURL          string
 	APIKey           string
 	Model            string
-	FrequencyPenalty float64
+	Compatibility    Compatibility
+	FrequencyPenalty *float64
 	MaxTokens        int64
-	PresencePenalty  float64
+	PresencePenalty  *float64
 	ReasoningEffort  string
 	Stop             string
-	Temperature      float64
-	TopP             float64
+	Temperature      *float64
+	TopP             *float64
+	ResponseFormat   string
+	ReasoningPattern string
+	ReasoningField   string
+	Retry            RetryPolicy
 }
 
 func (c *OpenAIConfig) Validate() error {
@@ -46,7 +87,13 @@ func (c *OpenAIConfig) Validate() error {
 	if c.Model == "" {
 		return errors.New("model cannot be empty")
 	}
-	return nil
+	if _, err := compileReasoningPattern(c.ReasoningPattern); err != nil {
+		return err
+	}
+	if err := ValidateResponseFormat(c.ResponseFormat); err != nil {
+		return err
+	}
+	return c.Retry.Validate()
 }
 
 func newOpenAIClient(config ProviderConfig) (GenerativeAI, error) {
@@ -55,12 +102,21 @@ func newOpenAIClient(config ProviderConfig) (GenerativeAI, error) {
 		return nil, errors.New("invalid config type for OpenAI")
 	}
 
+	reasoningPattern, err := compileReasoningPattern(cfg.ReasoningPattern)
+	if err != nil {
+		return nil, err
+	}
+
 	return &OpenAI{
 		Client: openai.NewClient(
 			option.WithBaseURL(cfg.BaseURL),
 			option.WithAPIKey(cfg.APIKey),
+
+			// Retries are handled by the configured retry policy instead of the client
+			option.WithMaxRetries(0),
 		),
 		Model:            cfg.Model,
+		Compatibility:    cfg.Compatibility,
 		FrequencyPenalty: cfg.FrequencyPenalty,
 		MaxTokens:        cfg.MaxTokens,
 		PresencePenalty:  cfg.PresencePenalty,
@@ -68,23 +124,45 @@ func newOpenAIClient(config ProviderConfig) (GenerativeAI, error) {
 		Stop:             cfg.Stop,
 		Temperature:      cfg.Temperature,
 		TopP:             cfg.TopP,
+		ResponseFormat:   cfg.ResponseFormat,
+		ReasoningPattern: reasoningPattern,
+		ReasoningField:   cfg.ReasoningField,
+		Retry:            cfg.Retry,
 	}, nil
 }
 
-// Chat generates a response from Ollama using a conversation history.
+// Chat generates a response from OpenAI using a conversation history.
 func (o *OpenAI) Chat(messages []Message) (string, GenerateStats, error) {
-	params := openai.ChatCompletionNewParams{
-		Messages:            openai.F([]openai.ChatCompletionMessageParamUnion{}),
-		Model:               openai.F(o.Model),
-		FrequencyPenalty:    openai.F(o.FrequencyPenalty),
-		MaxCompletionTokens: openai.F(o.MaxTokens),
-		PresencePenalty:     openai.F(o.PresencePenalty),
-		ReasoningEffort:     openai.F(openai.ChatCompletionReasoningEffort(o.ReasoningEffort)),
-		Stop: openai.F[openai.ChatCompletionNewParamsStopUnion](
-			shared.UnionString(o.Stop),
-		),
-		Temperature: openai.F(o.Temperature),
-		TopP:        openai.F(o.TopP),
+	reply, genStats, err := o.chat(messages, nil)
+	if err != nil {
+		return "", GenerateStats{}, err
+	}
+	return reply.Content, genStats, nil
+}
+
+// ChatWithTools generates the next assistant message, allowing the model to call tools.
+func (o *OpenAI) ChatWithTools(messages []Message, tools []Tool) (Message, GenerateStats, error) {
+	apiTools := make([]openai.ChatCompletionToolParam, len(tools))
+	for i, tool := range tools {
+		apiTools[i] = openai.ChatCompletionToolParam{
+			Type: openai.F(openai.ChatCompletionToolTypeFunction),
+			Function: openai.F(openai.FunctionDefinitionParam{
+				Name:        openai.F(tool.Name()),
+				Description: openai.F(tool.Description()),
+				Parameters:  openai.F(openai.FunctionParameters(tool.Parameters())),
+			}),
+		}
+	}
+	return o.chat(messages, apiTools)
+}
+
+func (o *OpenAI) chat(
+	messages []Message,
+	tools []openai.ChatCompletionToolParam,
+) (Message, GenerateStats, error) {
+	params := o.chatParams()
+	if len(tools) > 0 {
+		params.Tools = openai.F(tools)
 	}
 
 	for _, message := range messages {
@@ -92,18 +170,23 @@ func (o *OpenAI) Chat(messages []Message) (string, GenerateStats, error) {
 		case "user":
 			params.Messages.Value = append(
 				params.Messages.Value,
-				openai.UserMessage(message.Content),
+				userMessage(message),
 			)
 		case "assistant":
 			params.Messages.Value = append(
 				params.Messages.Value,
-				openai.AssistantMessage(message.Content),
+				assistantMessage(message),
 			)
 		case "system":
 			params.Messages.Value = append(
 				params.Messages.Value,
 				openai.SystemMessage(message.Content),
 			)
+		case "tool":
+			params.Messages.Value = append(
+				params.Messages.Value,
+				openai.ToolMessage(message.ToolCallID, message.Content),
+			)
 		default:
 			params.Messages.Value = append(
 				params.Messages.Value,
@@ -113,20 +196,30 @@ func (o *OpenAI) Chat(messages []Message) (string, GenerateStats, error) {
 	}
 
 	startTime := time.Now()
-	chatCompletion, err := o.Client.Chat.Completions.New(
-		context.Background(),
-		params,
-	)
+	var chatCompletion *openai.ChatCompletion
+	err := o.Retry.do(func() error {
+		var err error
+		chatCompletion, err = o.Client.Chat.Completions.New(context.Background(), params)
+		return err
+	})
 	if err != nil {
-		return "", GenerateStats{}, fmt.Errorf("OpenAI failed to generate chat completion: %w", err)
+		return Message{}, GenerateStats{}, fmt.Errorf(
+			"OpenAI failed to generate chat completion: %w", wrapOpenAIError(err),
+		)
 	}
 	duration := time.Since(startTime)
 
 	if len(chatCompletion.Choices) == 0 {
-		return "", GenerateStats{}, errors.New("OpenAI chat completion returned no choices")
+		return Message{}, GenerateStats{}, errors.New("OpenAI chat completion returned no choices")
 	}
 	choice := chatCompletion.Choices[0]
 
+	// Some servers return the reasoning in a separate field instead of the content
+	content, reasoning := extractReasoning(o.ReasoningPattern, choice.Message.Content)
+	if field, ok := choice.Message.JSON.ExtraFields[o.ReasoningField]; ok && o.ReasoningField != "" {
+		reasoning = joinReasoning(reasoningField(field.Raw()), reasoning)
+	}
+
 	genStats := GenerateStats{
 		DoneReason:         string(choice.FinishReason),
 		TotalDuration:      duration,
@@ -135,32 +228,155 @@ func (o *OpenAI) Chat(messages []Message) (string, GenerateStats, error) {
 		PromptEvalDuration: -1,
 		TokenCount:         chatCompletion.Usage.CompletionTokens,
 		EvalDuration:       duration,
+		Reasoning:          reasoning,
+	}
+
+	reply := Message{
+		Role:    "assistant",
+		Content: content,
+	}
+	for _, toolCall := range choice.Message.ToolCalls {
+		reply.ToolCalls = append(reply.ToolCalls, ToolCall{
+			ID:        toolCall.ID,
+			Name:      toolCall.Function.Name,
+			Arguments: toolCall.Function.Arguments,
+		})
 	}
 
-	return choice.Message.Content, genStats, nil
+	return reply, genStats, nil
 }
 
-func (o *OpenAI) Complete(prompt string) (string, GenerateStats, error) {
-	params := openai.CompletionNewParams{
-		Model: openai.F(openai.CompletionNewParamsModel(o.Model)),
-		Prompt: openai.F[openai.CompletionNewParamsPromptUnion](
-			shared.UnionString(prompt),
-		),
-		FrequencyPenalty: openai.F(o.FrequencyPenalty),
-		MaxTokens:        openai.F(o.MaxTokens),
-		PresencePenalty:  openai.F(o.PresencePenalty),
-		Stop:             openai.F[openai.CompletionNewParamsStopUnion](shared.UnionString(o.Stop)),
-		Temperature:      openai.F(o.Temperature),
-		TopP:             openai.F(o.TopP),
+// chatParams returns the chat completion parameters with only the options that are set.
+func (o *OpenAI) chatParams() openai.ChatCompletionNewParams {
+	params := openai.ChatCompletionNewParams{
+		Messages: openai.F([]openai.ChatCompletionMessageParamUnion{}),
+		Model:    openai.F(o.Model),
+	}
+	if o.FrequencyPenalty != nil {
+		params.FrequencyPenalty = openai.F(*o.FrequencyPenalty)
+	}
+	if o.PresencePenalty != nil {
+		params.PresencePenalty = openai.F(*o.PresencePenalty)
+	}
+	if o.Temperature != nil {
+		params.Temperature = openai.F(*o.Temperature)
+	}
+	if o.TopP != nil {
+		params.TopP = openai.F(*o.TopP)
+	}
+	if responseFormat := o.responseFormat(); responseFormat != nil {
+		params.ResponseFormat = openai.F(responseFormat)
+	}
+
+	// Gateways commonly only accept the older max_tokens field and a list of stop sequences
+	// and reject reasoning_effort for the models they serve
+	switch o.Compatibility {
+	case CompatibilityStrict:
+		if o.MaxTokens > 0 {
+			params.MaxCompletionTokens = openai.F(o.MaxTokens)
+		}
+		if o.ReasoningEffort != "" {
+			params.ReasoningEffort = openai.F(openai.ChatCompletionReasoningEffort(o.ReasoningEffort))
+		}
+		if o.Stop != "" {
+			params.Stop = openai.F[openai.ChatCompletionNewParamsStopUnion](shared.UnionString(o.Stop))
+		}
+	case CompatibilityLenient:
+		if o.MaxTokens > 0 {
+			params.MaxTokens = openai.F(o.MaxTokens)
+		}
+		if o.Stop != "" {
+			params.Stop = openai.F[openai.ChatCompletionNewParamsStopUnion](
+				openai.ChatCompletionNewParamsStopArray{o.Stop},
+			)
+		}
+	}
+	return params
+}
+
+// responseFormat returns the response_format parameter of the configured response format, nil if none.
+func (o *OpenAI) responseFormat() openai.ChatCompletionNewParamsResponseFormatUnion {
+	switch o.ResponseFormat {
+	case "":
+		return nil
+	case ResponseFormatJSON:
+		return shared.ResponseFormatJSONObjectParam{
+			Type: openai.F(shared.ResponseFormatJSONObjectTypeJSONObject),
+		}
+	default:
+		// The format is validated when the client is created
+		schema, _ := responseSchema(o.ResponseFormat)
+		return shared.ResponseFormatJSONSchemaParam{
+			Type: openai.F(shared.ResponseFormatJSONSchemaTypeJSONSchema),
+			JSONSchema: openai.F(shared.ResponseFormatJSONSchemaJSONSchemaParam{
+				Name:   openai.F(responseSchemaName),
+				Schema: openai.F[any](schema),
+			}),
+		}
+	}
+}
+
+// assistantMessage converts an assistant message into an OpenAI message,
+// including any tool calls it requested.
+func assistantMessage(message Message) openai.ChatCompletionMessageParamUnion {
+	if len(message.ToolCalls) == 0 {
+		return openai.AssistantMessage(message.Content)
+	}
+
+	param := openai.ChatCompletionAssistantMessageParam{
+		Role: openai.F(openai.ChatCompletionAssistantMessageParamRoleAssistant),
+	}
+	if message.Content != "" {
+		param.Content = openai.F([]openai.ChatCompletionAssistantMessageParamContentUnion{
+			openai.TextPart(message.Content),
+		})
 	}
 
+	toolCalls := make([]openai.ChatCompletionMessageToolCallParam, len(message.ToolCalls))
+	for i, toolCall := range message.ToolCalls {
+		toolCalls[i] = openai.ChatCompletionMessageToolCallParam{
+			ID:   openai.F(toolCall.ID),
+			Type: openai.F(openai.ChatCompletionMessageToolCallTypeFunction),
+			Function: openai.F(openai.ChatCompletionMessageToolCallFunctionParam{
+				Name:      openai.F(toolCall.Name),
+				Arguments: openai.F(toolCall.Arguments),
+			}),
+		}
+	}
+	param.ToolCalls = openai.F(toolCalls)
+	return param
+}
+
+// userMessage converts a user message into an OpenAI message,
+// attaching images as base64-encoded data URLs.
+func userMessage(message Message) openai.ChatCompletionMessageParamUnion {
+	if len(message.Images) == 0 {
+		return openai.UserMessage(message.Content)
+	}
+
+	parts := []openai.ChatCompletionContentPartUnionParam{}
+	if message.Content != "" {
+		parts = append(parts, openai.TextPart(message.Content))
+	}
+	for _, image := range message.Images {
+		parts = append(parts, openai.ImagePart(
+			"data:"+http.DetectContentType(image)+";base64,"+
+				base64.StdEncoding.EncodeToString(image),
+		))
+	}
+	return openai.UserMessageParts(parts...)
+}
+
+func (o *OpenAI) Complete(prompt string) (string, GenerateStats, error) {
 	startTime := time.Now()
-	chatCompletion, err := o.Client.Completions.New(
-		context.Background(),
-		params,
-	)
+	var chatCompletion *openai.Completion
+	err := o.Retry.do(func() error {
+		var err error
+		chatCompletion, err = o.Client.Completions.New(context.Background(), o.completionParams(prompt))
+		return err
+	})
 	if err != nil {
-		return "", GenerateStats{}, fmt.Errorf("OpenAI failed to generate completion: %w", err)
+		return "", GenerateStats{}, fmt.Errorf("OpenAI failed to generate completion: %w", wrapOpenAIError(err))
 	}
 	duration := time.Since(startTime)
 
@@ -168,6 +384,7 @@ func (o *OpenAI) Complete(prompt string) (string, GenerateStats, error) {
 		return "", GenerateStats{}, errors.New("OpenAI completion returned no choices")
 	}
 	choice := chatCompletion.Choices[0]
+	text, reasoning := extractReasoning(o.ReasoningPattern, choice.Text)
 
 	genStats := GenerateStats{
 		DoneReason:         string(choice.FinishReason),
@@ -177,7 +394,91 @@ func (o *OpenAI) Complete(prompt string) (string, GenerateStats, error) {
 		PromptEvalDuration: -1,
 		TokenCount:         chatCompletion.Usage.CompletionTokens,
 		EvalDuration:       duration,
+		Reasoning:          reasoning,
+	}
+
+	return text, genStats, nil
+}
+
+// completionParams returns the completion parameters with only the options that are set.
+func (o *OpenAI) completionParams(prompt string) openai.CompletionNewParams {
+	params := openai.CompletionNewParams{
+		Model: openai.F(openai.CompletionNewParamsModel(o.Model)),
+		Prompt: openai.F[openai.CompletionNewParamsPromptUnion](
+			shared.UnionString(prompt),
+		),
+	}
+	if o.FrequencyPenalty != nil {
+		params.FrequencyPenalty = openai.F(*o.FrequencyPenalty)
+	}
+	if o.MaxTokens > 0 {
+		params.MaxTokens = openai.F(o.MaxTokens)
+	}
+	if o.PresencePenalty != nil {
+		params.PresencePenalty = openai.F(*o.PresencePenalty)
 	}
+	if o.Temperature != nil {
+		params.Temperature = openai.F(*o.Temperature)
+	}
+	if o.TopP != nil {
+		params.TopP = openai.F(*o.TopP)
+	}
+	if o.Stop != "" {
+		switch o.Compatibility {
+		case CompatibilityStrict:
+			params.Stop = openai.F[openai.CompletionNewParamsStopUnion](shared.UnionString(o.Stop))
+		case CompatibilityLenient:
+			params.Stop = openai.F[openai.CompletionNewParamsStopUnion](openai.CompletionNewParamsStopArray{o.Stop})
+		}
+	}
+	return params
+}
 
-	return choice.Text, genStats, nil
+// ListModels returns the IDs of the models available through the OpenAI API.
+func (o *OpenAI) ListModels() ([]string, error) {
+	models := []string{}
+	pager := o.Client.Models.ListAutoPaging(context.Background())
+	for pager.Next() {
+		models = append(models, pager.Current().ID)
+	}
+	if err := pager.Err(); err != nil {
+		return nil, fmt.Errorf("OpenAI failed to list models: %w", wrapOpenAIError(err))
+	}
+	return models, nil
+}
+
+// Warm opens a connection to the OpenAI API by retrieving the configured model.
+func (o *OpenAI) Warm() error {
+	if _, err := o.Client.Models.Get(context.Background(), o.Model); err != nil {
+		return fmt.Errorf("OpenAI failed to retrieve model: %w", wrapOpenAIError(err))
+	}
+	return nil
+}
+
+// Embed computes the embeddings of the inputs using the configured model.
+func (o *OpenAI) Embed(inputs []string) ([][]float32, error) {
+	response, err := o.Client.Embeddings.New(context.Background(), openai.EmbeddingNewParams{
+		Input: openai.F[openai.EmbeddingNewParamsInputUnion](
+			openai.EmbeddingNewParamsInputArrayOfStrings(inputs),
+		),
+		Model:          openai.F(o.Model),
+		EncodingFormat: openai.F(openai.EmbeddingNewParamsEncodingFormatFloat),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("OpenAI embedding error: %w", wrapOpenAIError(err))
+	}
+
+	// The API returns the embeddings in double precision
+	embeddings := make([][]float32, len(response.Data))
+	for _, data := range response.Data {
+		if data.Index < 0 || int(data.Index) >= len(embeddings) {
+			return nil, errors.New("OpenAI returned an embedding with an invalid index")
+		}
+		embedding := make([]float32, len(data.Embedding))
+		for i, value := range data.Embedding {
+			embedding[i] = float32(value)
+		}
+		embeddings[data.Index] = embedding
+	}
+	return embeddings, nil
 }
@@ -0,0 +1,114 @@
+package genai //nolint:testpackage // Unit tests are in the same package
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAIRequestFields(t *testing.T) {
+	// Arrange
+	var request map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request = map[string]any{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{
+			"id": "chatcmpl-1",
+			"object": "chat.completion",
+			"created": 0,
+			"model": "test-model",
+			"choices": [{"index": 0, "finish_reason": "stop", "message": {"role": "assistant", "content": "Hi"}}],
+			"usage": {"prompt_tokens": 1, "completion_tokens": 1, "total_tokens": 2}
+		}`))
+	}))
+	defer server.Close()
+
+	temperature := 0.0
+	newConfig := func(compatibility Compatibility) *OpenAIConfig {
+		return &OpenAIConfig{
+			BaseURL:         server.URL,
+			APIKey:          "test",
+			Model:           "test-model",
+			Compatibility:   compatibility,
+			MaxTokens:       256,
+			ReasoningEffort: "low",
+			Stop:            "<|stop|>",
+			Temperature:     &temperature,
+		}
+	}
+
+	t.Run("Strict", func(t *testing.T) {
+		// Arrange
+		client, err := New(ProviderOpenAI, newConfig(CompatibilityStrict))
+		require.NoError(t, err)
+
+		// Act
+		_, _, err = client.Chat([]Message{{Role: "user", Content: "Hi"}})
+
+		// Assert
+		require.NoError(t, err)
+		assert.InDelta(t, 256, request["max_completion_tokens"], 0)
+		assert.Equal(t, "low", request["reasoning_effort"])
+		assert.Equal(t, "<|stop|>", request["stop"])
+		assert.Contains(t, request, "temperature")
+		assert.NotContains(t, request, "max_tokens")
+		assert.NotContains(t, request, "top_p")
+		assert.NotContains(t, request, "frequency_penalty")
+		assert.NotContains(t, request, "presence_penalty")
+	})
+
+	t.Run("Lenient", func(t *testing.T) {
+		// Arrange
+		client, err := New(ProviderOpenAI, newConfig(CompatibilityLenient))
+		require.NoError(t, err)
+
+		// Act
+		_, _, err = client.Chat([]Message{{Role: "user", Content: "Hi"}})
+
+		// Assert
+		require.NoError(t, err)
+		assert.InDelta(t, 256, request["max_tokens"], 0)
+		assert.Equal(t, []any{"<|stop|>"}, request["stop"])
+		assert.NotContains(t, request, "max_completion_tokens")
+		assert.NotContains(t, request, "reasoning_effort")
+		assert.NotContains(t, request, "response_format")
+	})
+
+	t.Run("Response format", func(t *testing.T) {
+		for format, expected := range map[string]map[string]any{
+			ResponseFormatJSON: {"type": "json_object"},
+			`{"type":"object"}`: {
+				"type":        "json_schema",
+				"json_schema": map[string]any{"name": "response", "schema": map[string]any{"type": "object"}},
+			},
+		} {
+			// Arrange
+			cfg := newConfig(CompatibilityStrict)
+			cfg.ResponseFormat = format
+			client, err := New(ProviderOpenAI, cfg)
+			require.NoError(t, err)
+
+			// Act
+			_, _, err = client.Chat([]Message{{Role: "user", Content: "Hi"}})
+
+			// Assert
+			require.NoError(t, err)
+			assert.Equal(t, expected, request["response_format"], format)
+		}
+	})
+}
+
+func TestParseCompatibility(t *testing.T) {
+	compatibility, err := ParseCompatibility("lenient")
+	require.NoError(t, err)
+	assert.Equal(t, CompatibilityLenient, compatibility)
+	assert.Equal(t, "lenient", compatibility.String())
+
+	_, err = ParseCompatibility("loose")
+	assert.Error(t, err)
+}
@@ -0,0 +1,60 @@
+package genai
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultReasoningPattern matches the reasoning that models such as DeepSeek R1 emit before their answer.
+// The opening tag is optional because some chat templates add it to the prompt instead of the response.
+const DefaultReasoningPattern = `(?s)^\s*(?:<think>)?(.*?)</think>`
+
+// compileReasoningPattern compiles a reasoning pattern. An empty pattern disables extraction.
+func compileReasoningPattern(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil //nolint:nilnil // A nil pattern disables extraction
+	}
+	compiled, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid reasoning pattern: %w", err)
+	}
+	return compiled, nil
+}
+
+// extractReasoning removes the reasoning matched by the pattern from the content.
+// The first capture group of the pattern, or the whole match if it has none, is returned as the reasoning.
+func extractReasoning(pattern *regexp.Regexp, content string) (string, string) {
+	if pattern == nil {
+		return content, ""
+	}
+
+	match := pattern.FindStringSubmatchIndex(content)
+	if match == nil {
+		return content, ""
+	}
+
+	reasoning := content[match[0]:match[1]]
+	if len(match) >= 4 && match[2] >= 0 {
+		reasoning = content[match[2]:match[3]]
+	}
+	return strings.TrimSpace(content[:match[0]] + content[match[1]:]), strings.TrimSpace(reasoning)
+}
+
+// joinReasoning appends the reasoning of another generation round.
+func joinReasoning(reasoning string, other string) string {
+	if reasoning == "" || other == "" {
+		return reasoning + other
+	}
+	return reasoning + "\n\n" + other
+}
+
+// reasoningField decodes a string field returned outside of the standard response fields.
+func reasoningField(raw string) string {
+	var reasoning string
+	if err := json.Unmarshal([]byte(raw), &reasoning); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(reasoning)
+}
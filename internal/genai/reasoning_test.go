@@ -0,0 +1,116 @@
+package genai //nolint:testpackage // Unit tests are in the same package
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractReasoning(t *testing.T) {
+	tests := []struct {
+		name      string
+		pattern   string
+		content   string
+		response  string
+		reasoning string
+	}{
+		{
+			name:      "Think tags",
+			pattern:   DefaultReasoningPattern,
+			content:   "<think>\nLet me think.\n</think>\n\nHello!",
+			response:  "Hello!",
+			reasoning: "Let me think.",
+		},
+		{
+			name:      "Missing opening tag",
+			pattern:   DefaultReasoningPattern,
+			content:   "Let me think.</think>Hello!",
+			response:  "Hello!",
+			reasoning: "Let me think.",
+		},
+		{
+			name:     "No reasoning",
+			pattern:  DefaultReasoningPattern,
+			content:  "Hello!",
+			response: "Hello!",
+		},
+		{
+			name:      "Custom pattern without groups",
+			pattern:   `(?s)\[reasoning\].*?\[/reasoning\]`,
+			content:   "[reasoning]Hmm[/reasoning] Hello!",
+			response:  "Hello!",
+			reasoning: "[reasoning]Hmm[/reasoning]",
+		},
+		{
+			name:     "Disabled",
+			content:  "<think>Hmm</think>Hello!",
+			response: "<think>Hmm</think>Hello!",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			pattern, err := compileReasoningPattern(tt.pattern)
+			require.NoError(t, err)
+
+			// Act
+			response, reasoning := extractReasoning(pattern, tt.content)
+
+			// Assert
+			assert.Equal(t, tt.response, response)
+			assert.Equal(t, tt.reasoning, reasoning)
+		})
+	}
+}
+
+func TestCompileReasoningPattern(t *testing.T) {
+	_, err := compileReasoningPattern("(")
+	assert.ErrorContains(t, err, "invalid reasoning pattern")
+}
+
+func TestReasoningField(t *testing.T) {
+	assert.Equal(t, "Hmm", reasoningField(`" Hmm "`))
+	assert.Empty(t, reasoningField("null"))
+	assert.Empty(t, reasoningField(""))
+}
+
+func TestOpenAIReasoningField(t *testing.T) {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{
+			"id": "chatcmpl-1",
+			"object": "chat.completion",
+			"created": 0,
+			"model": "test-model",
+			"choices": [{
+				"index": 0,
+				"finish_reason": "stop",
+				"message": {"role": "assistant", "content": "Hello!", "reasoning_content": "The user said hi."}
+			}],
+			"usage": {"prompt_tokens": 1, "completion_tokens": 2, "total_tokens": 3}
+		}`))
+	}))
+	defer server.Close()
+
+	client, err := New(ProviderOpenAI, &OpenAIConfig{
+		BaseURL:          server.URL,
+		APIKey:           "test",
+		Model:            "test-model",
+		ReasoningPattern: DefaultReasoningPattern,
+		ReasoningField:   "reasoning_content",
+	})
+	require.NoError(t, err)
+
+	// Act
+	response, genStats, err := client.Chat([]Message{{Role: "user", Content: "Hi"}})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "Hello!", response)
+	assert.Equal(t, "The user said hi.", genStats.Reasoning)
+}
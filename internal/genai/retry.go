@@ -0,0 +1,133 @@
+package genai
+
+import (
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ollama/ollama/api"
+	"github.com/openai/openai-go"
+	"github.com/rs/zerolog/log"
+)
+
+// RetryPolicy controls how requests that failed with a transient provider error are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts per request, values below 2 disable retries
+	MaxAttempts int
+
+	// BaseDelay is the delay before the first retry, which doubles with every further retry
+	BaseDelay time.Duration
+
+	// MaxDelay caps the delay between attempts
+	// Requests the provider asks to retry after a longer delay are not retried
+	MaxDelay time.Duration
+
+	// Jitter is the fraction of the delay that is randomly added or subtracted
+	Jitter float64
+}
+
+func (p *RetryPolicy) Validate() error {
+	if p.MaxAttempts < 0 {
+		return errors.New("max attempts cannot be negative")
+	}
+	if p.BaseDelay < 0 || p.MaxDelay < 0 {
+		return errors.New("delays cannot be negative")
+	}
+	if p.Jitter < 0 || p.Jitter > 1 {
+		return errors.New("jitter must be between 0 and 1")
+	}
+	return nil
+}
+
+// do calls fn until it succeeds, fails with an error that is not transient, or the attempts are exhausted.
+func (p RetryPolicy) do(fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= p.MaxAttempts {
+			return err
+		}
+
+		transient, retryAfter := isTransientError(err)
+		if !transient {
+			return err
+		}
+		delay, ok := p.delay(attempt, retryAfter)
+		if !ok {
+			return err
+		}
+
+		log.Warn().Err(err).
+			Int("attempt", attempt).
+			Dur("delay", delay).
+			Msg("Retrying request after transient provider error")
+		time.Sleep(delay)
+	}
+}
+
+// delay returns the delay before the retry following the given attempt.
+// It returns false if the provider asked to wait longer than the maximum delay.
+func (p RetryPolicy) delay(attempt int, retryAfter time.Duration) (time.Duration, bool) {
+	if retryAfter > 0 {
+		if p.MaxDelay > 0 && retryAfter > p.MaxDelay {
+			return 0, false
+		}
+		return retryAfter, true
+	}
+
+	delay := p.BaseDelay << min(attempt-1, 30)
+	if p.MaxDelay > 0 && (delay > p.MaxDelay || delay < 0) {
+		delay = p.MaxDelay
+	}
+	if p.Jitter > 0 {
+		delay += time.Duration((rand.Float64()*2 - 1) * p.Jitter * float64(delay)) //nolint:gosec // Not security sensitive
+	}
+	return delay, true
+}
+
+// isTransientError returns whether the request failed with a rate limit or server error that may succeed
+// when retried, and how long the provider asked to wait before retrying, if it did.
+func isTransientError(err error) (bool, time.Duration) {
+	var statusCode int
+	var retryAfter time.Duration
+
+	var apiError *openai.Error
+	var statusError api.StatusError
+	var bedrockError *BedrockError
+	switch {
+	case errors.As(err, &apiError):
+		// Exhausted quotas are reported as rate limits but do not recover by retrying
+		if apiError.Code == "insufficient_quota" {
+			return false, 0
+		}
+		statusCode = apiError.StatusCode
+		if apiError.Response != nil {
+			retryAfter = parseRetryAfter(apiError.Response.Header.Get("Retry-After"), time.Now())
+		}
+	case errors.As(err, &statusError):
+		statusCode = statusError.StatusCode
+	case errors.As(err, &bedrockError):
+		statusCode = bedrockError.StatusCode
+	default:
+		return false, 0
+	}
+
+	return statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError, retryAfter
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(max(seconds, 0)) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0)
+	}
+	return 0
+}
@@ -0,0 +1,230 @@
+package genai //nolint:testpackage // Unit tests are in the same package
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ollama/ollama/api"
+	"github.com/openai/openai-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 5, BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+
+	tests := []struct {
+		name       string
+		attempt    int
+		retryAfter time.Duration
+		expected   time.Duration
+		ok         bool
+	}{
+		{name: "First retry", attempt: 1, expected: time.Second, ok: true},
+		{name: "Exponential backoff", attempt: 3, expected: 4 * time.Second, ok: true},
+		{name: "Capped at maximum delay", attempt: 4, expected: 5 * time.Second, ok: true},
+		{name: "Large attempt does not overflow", attempt: 100, expected: 5 * time.Second, ok: true},
+		{name: "Retry-After", attempt: 1, retryAfter: 3 * time.Second, expected: 3 * time.Second, ok: true},
+		{name: "Retry-After beyond maximum delay", attempt: 1, retryAfter: time.Minute, ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			delay, ok := policy.delay(tt.attempt, tt.retryAfter)
+
+			// Assert
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.expected, delay)
+		})
+	}
+
+	t.Run("Jitter", func(t *testing.T) {
+		// Arrange
+		jittered := RetryPolicy{BaseDelay: time.Second, Jitter: 0.5}
+
+		for range 100 {
+			// Act
+			delay, ok := jittered.delay(1, 0)
+
+			// Assert
+			assert.True(t, ok)
+			assert.GreaterOrEqual(t, delay, 500*time.Millisecond)
+			assert.LessOrEqual(t, delay, 1500*time.Millisecond)
+		}
+	})
+}
+
+func TestRetryPolicyDo(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}
+	transientError := api.StatusError{StatusCode: http.StatusServiceUnavailable, ErrorMessage: "overloaded"}
+
+	t.Run("Succeeds after transient errors", func(t *testing.T) {
+		// Arrange
+		attempts := 0
+
+		// Act
+		err := policy.do(func() error {
+			attempts++
+			if attempts < 3 {
+				return transientError
+			}
+			return nil
+		})
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, 3, attempts)
+	})
+
+	t.Run("Gives up after maximum attempts", func(t *testing.T) {
+		// Arrange
+		attempts := 0
+
+		// Act
+		err := policy.do(func() error {
+			attempts++
+			return transientError
+		})
+
+		// Assert
+		assert.Equal(t, transientError, err)
+		assert.Equal(t, 3, attempts)
+	})
+
+	t.Run("Does not retry permanent errors", func(t *testing.T) {
+		// Arrange
+		attempts := 0
+		permanentError := api.StatusError{StatusCode: http.StatusUnauthorized, ErrorMessage: "unauthorized"}
+
+		// Act
+		err := policy.do(func() error {
+			attempts++
+			return permanentError
+		})
+
+		// Assert
+		assert.Equal(t, permanentError, err)
+		assert.Equal(t, 1, attempts)
+	})
+
+	t.Run("Retries disabled", func(t *testing.T) {
+		// Arrange
+		attempts := 0
+
+		// Act
+		err := RetryPolicy{}.do(func() error {
+			attempts++
+			return transientError
+		})
+
+		// Assert
+		assert.Equal(t, transientError, err)
+		assert.Equal(t, 1, attempts)
+	})
+}
+
+func TestIsTransientError(t *testing.T) {
+	newError := func(statusCode int, code string, header http.Header) *openai.Error {
+		return &openai.Error{
+			Code:       code,
+			StatusCode: statusCode,
+			Request:    httptest.NewRequest(http.MethodPost, "https://api.openai.com/v1/chat/completions", nil),
+			Response:   &http.Response{StatusCode: statusCode, Header: header},
+		}
+	}
+
+	tests := []struct {
+		name       string
+		err        error
+		transient  bool
+		retryAfter time.Duration
+	}{
+		{
+			name:      "Ollama rate limited",
+			err:       api.StatusError{StatusCode: http.StatusTooManyRequests},
+			transient: true,
+		},
+		{
+			name:      "Ollama server error",
+			err:       api.StatusError{StatusCode: http.StatusBadGateway},
+			transient: true,
+		},
+		{
+			name:      "Ollama model not found",
+			err:       api.StatusError{StatusCode: http.StatusNotFound},
+			transient: false,
+		},
+		{
+			name:       "OpenAI rate limited with Retry-After",
+			err:        newError(http.StatusTooManyRequests, "rate_limit_exceeded", http.Header{"Retry-After": {"2"}}),
+			transient:  true,
+			retryAfter: 2 * time.Second,
+		},
+		{
+			name:      "OpenAI insufficient quota",
+			err:       newError(http.StatusTooManyRequests, "insufficient_quota", nil),
+			transient: false,
+		},
+		{
+			name:      "OpenAI bad request",
+			err:       newError(http.StatusBadRequest, "invalid_request_error", nil),
+			transient: false,
+		},
+		{
+			name:      "Bedrock throttled",
+			err:       &BedrockError{StatusCode: http.StatusTooManyRequests, Type: "ThrottlingException"},
+			transient: true,
+		},
+		{
+			name:      "Bedrock validation error",
+			err:       &BedrockError{StatusCode: http.StatusBadRequest, Type: "ValidationException"},
+			transient: false,
+		},
+		{
+			name:      "Other error",
+			err:       errors.New("connection refused"),
+			transient: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			transient, retryAfter := isTransientError(tt.err)
+
+			// Assert
+			assert.Equal(t, tt.transient, transient)
+			assert.Equal(t, tt.retryAfter, retryAfter)
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		value    string
+		expected time.Duration
+	}{
+		{name: "Seconds", value: "30", expected: 30 * time.Second},
+		{name: "HTTP date", value: "Wed, 01 Jan 2025 12:00:10 GMT", expected: 10 * time.Second},
+		{name: "Date in the past", value: "Wed, 01 Jan 2025 11:00:00 GMT", expected: 0},
+		{name: "Empty", value: "", expected: 0},
+		{name: "Invalid", value: "soon", expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			retryAfter := parseRetryAfter(tt.value, now)
+
+			// Assert
+			assert.Equal(t, tt.expected, retryAfter)
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package genai //nolint:testpackage // Unit tests are in the same package
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignAWSRequest(t *testing.T) {
+	// Arrange
+	// The example request from the AWS Signature Version 4 documentation
+	req, err := http.NewRequest(http.MethodGet, "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08", nil)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	credentials := awsCredentials{
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	}
+
+	// Act
+	signAWSRequest(req, nil, credentials, "us-east-1", "iam", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	// Assert
+	assert.Equal(t, "20150830T123600Z", req.Header.Get("X-Amz-Date"))
+	assert.Equal(t,
+		"AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, "+
+			"SignedHeaders=content-type;host;x-amz-date, "+
+			"Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7",
+		req.Header.Get("Authorization"),
+	)
+}
+
+func TestSignAWSRequest_SessionToken(t *testing.T) {
+	// Arrange
+	req, err := http.NewRequest(http.MethodPost, "https://bedrock-runtime.us-east-1.amazonaws.com/converse", nil)
+	require.NoError(t, err)
+	credentials := awsCredentials{AccessKeyID: "AKID", SecretAccessKey: "secret", SessionToken: "token"}
+
+	// Act
+	signAWSRequest(req, []byte("{}"), credentials, "us-east-1", "bedrock", time.Now())
+
+	// Assert
+	assert.Equal(t, "token", req.Header.Get("X-Amz-Security-Token"))
+	assert.Contains(t, req.Header.Get("Authorization"), "SignedHeaders=host;x-amz-date;x-amz-security-token,")
+}
+
+func TestAWSURIEncode(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "Unreserved", input: "anthropic.claude-v2_1~", expected: "anthropic.claude-v2_1~"},
+		{name: "Colon", input: "claude-v2:1", expected: "claude-v2%3A1"},
+		{name: "Percent", input: "v1%3A0", expected: "v1%253A0"},
+		{name: "Space", input: "a b", expected: "a%20b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			encoded := awsURIEncode(tt.input)
+
+			// Assert
+			assert.Equal(t, tt.expected, encoded)
+		})
+	}
+}
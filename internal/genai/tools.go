@@ -0,0 +1,80 @@
+package genai
+
+import (
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Tool is a function that the model can call while generating a chat response.
+type Tool interface {
+	// Name returns the unique name of the tool.
+	Name() string
+
+	// Description explains to the model what the tool does and when to use it.
+	Description() string
+
+	// Parameters returns the JSON schema of the tool arguments.
+	Parameters() map[string]any
+
+	// Execute runs the tool with the JSON-encoded arguments and returns the result.
+	Execute(arguments string) (string, error)
+}
+
+// ToolCall is a request from the model to execute a tool.
+type ToolCall struct {
+	ID        string
+	Name      string
+	Arguments string
+}
+
+// ToolCaller is implemented by providers that support tool calling in chat mode.
+type ToolCaller interface {
+	// ChatWithTools generates the next assistant message, which either contains
+	// the final response or a list of tool calls to execute.
+	ChatWithTools(messages []Message, tools []Tool) (Message, GenerateStats, error)
+}
+
+// ExecuteToolCalls runs the requested tools and returns their results as tool messages.
+// Errors are reported back to the model so that it can recover.
+func ExecuteToolCalls(tools []Tool, calls []ToolCall) []Message {
+	results := make([]Message, 0, len(calls))
+	for _, call := range calls {
+		var content string
+		tool := findTool(tools, call.Name)
+		if tool == nil {
+			content = fmt.Sprintf("error: unknown tool %q", call.Name)
+		} else {
+			result, err := tool.Execute(call.Arguments)
+			if err != nil {
+				log.Warn().Err(err).Str("tool", call.Name).Msg("Tool execution failed")
+				content = "error: " + err.Error()
+			} else {
+				content = result
+			}
+		}
+
+		log.Debug().
+			Str("tool", call.Name).
+			Str("arguments", call.Arguments).
+			Msg("Executed tool call")
+
+		results = append(results, Message{
+			Role:       "tool",
+			Content:    content,
+			ToolCallID: call.ID,
+			ToolName:   call.Name,
+		})
+	}
+	return results
+}
+
+// findTool returns the tool with the given name or nil if it does not exist.
+func findTool(tools []Tool, name string) Tool {
+	for _, tool := range tools {
+		if tool.Name() == name {
+			return tool
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package genai //nolint:testpackage // Unit tests are in the same package
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type echoTool struct {
+	err error
+}
+
+func (e *echoTool) Name() string        { return "echo" }
+func (e *echoTool) Description() string { return "Echoes the arguments back" }
+func (e *echoTool) Parameters() map[string]any {
+	return map[string]any{"type": "object", "properties": map[string]any{}}
+}
+
+func (e *echoTool) Execute(arguments string) (string, error) {
+	if e.err != nil {
+		return "", e.err
+	}
+	return arguments, nil
+}
+
+func TestExecuteToolCalls(t *testing.T) {
+	t.Run("Known tool", func(t *testing.T) {
+		// Act
+		results := ExecuteToolCalls([]Tool{&echoTool{}}, []ToolCall{
+			{ID: "call_0", Name: "echo", Arguments: `{"text":"hello"}`},
+		})
+
+		// Assert
+		require.Len(t, results, 1)
+		assert.Equal(t, "tool", results[0].Role)
+		assert.Equal(t, "call_0", results[0].ToolCallID)
+		assert.Equal(t, "echo", results[0].ToolName)
+		assert.JSONEq(t, `{"text":"hello"}`, results[0].Content)
+	})
+
+	t.Run("Unknown tool", func(t *testing.T) {
+		// Act
+		results := ExecuteToolCalls([]Tool{&echoTool{}}, []ToolCall{
+			{ID: "call_0", Name: "missing", Arguments: "{}"},
+		})
+
+		// Assert
+		require.Len(t, results, 1)
+		assert.Contains(t, results[0].Content, "unknown tool")
+	})
+
+	t.Run("Failing tool", func(t *testing.T) {
+		// Act
+		results := ExecuteToolCalls([]Tool{&echoTool{err: errors.New("boom")}}, []ToolCall{
+			{ID: "call_0", Name: "echo", Arguments: "{}"},
+		})
+
+		// Assert
+		require.Len(t, results, 1)
+		assert.Equal(t, "error: boom", results[0].Content)
+	})
+}
+
+func TestGenerateStats_Add(t *testing.T) {
+	// Arrange
+	stats := GenerateStats{PromptTokens: 10, TokenCount: 5, DoneReason: "tool_calls"}
+
+	// Act
+	stats.Add(GenerateStats{PromptTokens: 20, TokenCount: 7, DoneReason: "stop"})
+
+	// Assert
+	assert.Equal(t, int64(30), stats.PromptTokens)
+	assert.Equal(t, int64(12), stats.TokenCount)
+	assert.Equal(t, "stop", stats.DoneReason)
+}
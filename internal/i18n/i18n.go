@@ -0,0 +1,91 @@
+// Package i18n translates the replies of the bot using message catalogs keyed by the English message.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// DefaultLanguage is the language the messages are written in.
+const DefaultLanguage = "en"
+
+//go:embed locales/*.json
+var locales embed.FS //nolint:gochecknoglobals // Embedded message catalogs
+
+// catalogs maps language codes to their catalogs.
+var catalogs = loadCatalogs() //nolint:gochecknoglobals // Read-only after initialization
+
+// catalog holds the translations of the messages into one language.
+type catalog struct {
+	// Name is the name of the language in the language itself
+	Name string `json:"name"`
+
+	// Messages maps English messages or format strings to their translations
+	Messages map[string]string `json:"messages"`
+}
+
+// loadCatalogs parses the embedded message catalogs.
+// The catalogs are part of the binary, so an invalid catalog is a programming error.
+func loadCatalogs() map[string]catalog {
+	files, err := locales.ReadDir("locales")
+	if err != nil {
+		panic(err)
+	}
+
+	result := map[string]catalog{DefaultLanguage: {Name: "English"}}
+	for _, file := range files {
+		var data []byte
+		data, err = locales.ReadFile(path.Join("locales", file.Name()))
+		if err != nil {
+			panic(err)
+		}
+		var c catalog
+		if err = json.Unmarshal(data, &c); err != nil {
+			panic(fmt.Sprintf("invalid message catalog %s: %v", file.Name(), err))
+		}
+		result[strings.TrimSuffix(file.Name(), ".json")] = c
+	}
+	return result
+}
+
+// Languages returns the codes of the supported languages in alphabetical order.
+func Languages() []string {
+	languages := make([]string, 0, len(catalogs))
+	for language := range catalogs {
+		languages = append(languages, language)
+	}
+	slices.Sort(languages)
+	return languages
+}
+
+// Supported returns whether messages can be translated into the language.
+func Supported(language string) bool {
+	_, ok := catalogs[language]
+	return ok
+}
+
+// Name returns the name of the language in the language itself, or the code if it is not supported.
+func Name(language string) string {
+	if c, ok := catalogs[language]; ok {
+		return c.Name
+	}
+	return language
+}
+
+// Translate returns the translation of the message into the language.
+// The message is returned unchanged if the language or the message is not in the catalogs.
+func Translate(language string, message string) string {
+	if translation, ok := catalogs[language].Messages[message]; ok {
+		return translation
+	}
+	return message
+}
+
+// Sprintf translates the format string into the language and formats it with the arguments.
+func Sprintf(language string, format string, args ...any) string {
+	return fmt.Sprintf(Translate(language, format), args...)
+}
@@ -0,0 +1,100 @@
+package i18n //nolint:testpackage // Unit tests are in the same package
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTranslate(t *testing.T) {
+	tests := []struct {
+		name     string
+		language string
+		message  string
+		expected string
+	}{
+		{
+			name:     "Translated",
+			language: "zh",
+			message:  "Prompt set successfully.",
+			expected: "提示词设置成功。",
+		},
+		{
+			name:     "Default language",
+			language: DefaultLanguage,
+			message:  "Prompt set successfully.",
+			expected: "Prompt set successfully.",
+		},
+		{
+			name:     "Unsupported language",
+			language: "xx",
+			message:  "Prompt set successfully.",
+			expected: "Prompt set successfully.",
+		},
+		{
+			name:     "Missing message",
+			language: "ja",
+			message:  "This message is not in any catalog.",
+			expected: "This message is not in any catalog.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			translation := Translate(tt.language, tt.message)
+
+			// Assert
+			assert.Equal(t, tt.expected, translation)
+		})
+	}
+}
+
+func TestSprintf(t *testing.T) {
+	// Act
+	message := Sprintf("ja", "Remembered as note #%d.", 7)
+
+	// Assert
+	assert.Equal(t, "メモ #7 として記憶しました。", message)
+}
+
+func TestLanguages(t *testing.T) {
+	// Act
+	languages := Languages()
+
+	// Assert
+	assert.Equal(t, []string{"en", "ja", "zh"}, languages)
+	assert.True(t, Supported("en"))
+	assert.False(t, Supported("xx"))
+	assert.Equal(t, "日本語", Name("ja"))
+	assert.Equal(t, "xx", Name("xx"))
+}
+
+func TestCatalogs(t *testing.T) {
+	verb := regexp.MustCompile(`%[-+# 0-9.]*[a-zA-Z%]`)
+
+	for language, c := range catalogs {
+		if language == DefaultLanguage {
+			continue
+		}
+		t.Run(language, func(t *testing.T) {
+			// Assert
+			assert.NotEmpty(t, c.Name)
+			for message, translation := range c.Messages {
+				assert.NotEmpty(t, translation, message)
+				assert.Equal(t, verb.FindAllString(message, -1), verb.FindAllString(translation, -1), message)
+			}
+
+			// Every catalog translates the same messages
+			for other, otherCatalog := range catalogs {
+				if other == DefaultLanguage {
+					continue
+				}
+				for message := range otherCatalog.Messages {
+					assert.Contains(t, c.Messages, message, "missing translation of a message from %s", other)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+// Package language implements a lightweight detector for the language of short chat messages.
+package language
+
+import (
+	"strings"
+	"unicode"
+)
+
+// scriptLanguages maps Unicode scripts used by a single major language to that language.
+var scriptLanguages = []struct { //nolint:gochecknoglobals // Read-only lookup table
+	script   *unicode.RangeTable
+	language string
+}{
+	{unicode.Hangul, "Korean"},
+	{unicode.Hiragana, "Japanese"},
+	{unicode.Katakana, "Japanese"},
+	{unicode.Han, "Chinese"},
+	{unicode.Cyrillic, "Russian"},
+	{unicode.Arabic, "Arabic"},
+	{unicode.Hebrew, "Hebrew"},
+	{unicode.Greek, "Greek"},
+	{unicode.Thai, "Thai"},
+	{unicode.Devanagari, "Hindi"},
+}
+
+// stopwords are frequent words that identify languages written in the Latin script.
+var stopwords = map[string][]string{ //nolint:gochecknoglobals // Read-only lookup table
+	"English":    {"the", "and", "is", "are", "you", "what", "how", "this", "that", "with", "for", "can", "it"},
+	"Spanish":    {"el", "la", "los", "las", "que", "es", "por", "para", "con", "una", "cómo", "qué", "está"},
+	"French":     {"le", "la", "les", "est", "et", "que", "une", "pour", "avec", "vous", "pas", "je", "c'est"},
+	"German":     {"der", "die", "das", "und", "ist", "nicht", "ich", "du", "mit", "wie", "was", "ein", "eine"},
+	"Portuguese": {"o", "os", "que", "é", "não", "uma", "para", "com", "você", "como", "está", "do", "da"},
+	"Italian":    {"il", "che", "è", "non", "per", "una", "sono", "con", "come", "cosa", "della", "gli"},
+	"Dutch":      {"de", "het", "een", "en", "is", "niet", "ik", "je", "wat", "hoe", "van", "met", "dat"},
+}
+
+// Detect returns the English name of the language the text is most likely written in.
+// An empty string is returned if the language cannot be determined with confidence.
+func Detect(text string) string {
+	if language := detectScript(text); language != "" {
+		return language
+	}
+	return detectLatin(text)
+}
+
+// detectScript detects languages identified by their script.
+func detectScript(text string) string {
+	counts := map[string]int{}
+	letters := 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		for _, entry := range scriptLanguages {
+			if unicode.Is(entry.script, r) {
+				counts[entry.language]++
+				break
+			}
+		}
+	}
+
+	// Japanese text mixes kana with Han characters
+	if counts["Japanese"] > 0 {
+		counts["Japanese"] += counts["Chinese"]
+		delete(counts, "Chinese")
+	}
+
+	best, bestCount := "", 0
+	for language, count := range counts {
+		if count > bestCount || (count == bestCount && language < best) {
+			best, bestCount = language, count
+		}
+	}
+
+	// Require the script to make up a meaningful part of the message
+	if letters == 0 || bestCount*3 < letters {
+		return ""
+	}
+	return best
+}
+
+// detectLatin detects languages written in the Latin script by counting stopwords.
+func detectLatin(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+
+	scores := map[string]int{}
+	for _, word := range words {
+		for language, languageStopwords := range stopwords {
+			for _, stopword := range languageStopwords {
+				if word == stopword {
+					scores[language]++
+					break
+				}
+			}
+		}
+	}
+
+	best, bestScore, tied := "", 0, false
+	for language, score := range scores {
+		switch {
+		case score > bestScore:
+			best, bestScore, tied = language, score, false
+		case score == bestScore:
+			tied = true
+		}
+	}
+
+	if bestScore == 0 || tied {
+		return ""
+	}
+	return best
+}
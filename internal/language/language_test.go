@@ -0,0 +1,37 @@
+package language //nolint:testpackage // Unit tests are in the same package
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected string
+	}{
+		{name: "English", text: "What is the weather like today and how are you?", expected: "English"},
+		{name: "Spanish", text: "¿Qué es esto y para qué sirve la aplicación?", expected: "Spanish"},
+		{name: "French", text: "Je ne sais pas ce que c'est, pouvez-vous m'aider avec le code?", expected: "French"},
+		{name: "German", text: "Ich weiß nicht, was das ist und wie es funktioniert.", expected: "German"},
+		{name: "Chinese", text: "今天天气怎么样？", expected: "Chinese"},
+		{name: "Japanese", text: "今日はいい天気ですね。", expected: "Japanese"},
+		{name: "Korean", text: "오늘 날씨 어때요?", expected: "Korean"},
+		{name: "Russian", text: "Привет, как дела?", expected: "Russian"},
+		{name: "Mixed script", text: "@tellama 这个 bug 怎么修?", expected: "Chinese"},
+		{name: "Undetermined", text: "@tellama 42", expected: ""},
+		{name: "Empty", text: "", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			language := Detect(tt.text)
+
+			// Assert
+			assert.Equal(t, tt.expected, language)
+		})
+	}
+}
@@ -0,0 +1,289 @@
+// Package markdown converts the Markdown produced by language models into
+// Telegram's MarkdownV2 format.
+package markdown
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// specialChars are the characters that must be escaped outside of entities in MarkdownV2.
+const specialChars = "_*[]()~`>#+-=|{}.!\\"
+
+// horizontalRule replaces thematic breaks, which Telegram cannot render.
+const horizontalRule = "──────────"
+
+// ToMarkdownV2 converts common Markdown into Telegram MarkdownV2.
+// Unsupported or unbalanced syntax is escaped so that it renders literally.
+func ToMarkdownV2(text string) string {
+	var output strings.Builder
+	lines := strings.Split(text, "\n")
+
+	for i := 0; i < len(lines); i++ {
+		if i > 0 {
+			output.WriteByte('\n')
+		}
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		// Fenced code blocks are copied verbatim until the closing fence
+		if strings.HasPrefix(trimmed, "```") {
+			end := i + 1
+			for end < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[end]), "```") {
+				end++
+			}
+			if end == len(lines) {
+				output.WriteString(escapeText(line))
+				continue
+			}
+			output.WriteString("```" + strings.TrimPrefix(trimmed, "```"))
+			for _, codeLine := range lines[i+1 : end] {
+				output.WriteString("\n" + escapeCode(codeLine))
+			}
+			output.WriteString("\n```")
+			i = end
+			continue
+		}
+
+		// Tables are not supported by Telegram and are rendered as monospace blocks or lists
+		if rows, end := parseTable(lines, i); rows != nil {
+			output.WriteString(renderTable(rows))
+			i = end
+			continue
+		}
+
+		output.WriteString(convertLine(line))
+	}
+
+	return output.String()
+}
+
+// convertLine converts block-level syntax at the start of a line.
+func convertLine(line string) string {
+	indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+	content := line[len(indent):]
+
+	switch {
+	case isHeading(content):
+		heading := strings.TrimSpace(strings.TrimLeft(content, "#"))
+		return indent + "*" + convertInline(stripEmphasis(heading)) + "*"
+	case isHorizontalRule(content):
+		return horizontalRule
+	case strings.HasPrefix(content, "- "), strings.HasPrefix(content, "* "), strings.HasPrefix(content, "+ "):
+		item := content[2:]
+		switch {
+		case strings.HasPrefix(item, "[ ] "):
+			return indent + "☐ " + convertInline(item[4:])
+		case strings.HasPrefix(item, "[x] "), strings.HasPrefix(item, "[X] "):
+			return indent + "☑ " + convertInline(item[4:])
+		}
+		return indent + "• " + convertInline(item)
+	case strings.HasPrefix(content, ">"):
+		return ">" + convertInline(strings.TrimPrefix(content[1:], " "))
+	default:
+		return indent + convertInline(content)
+	}
+}
+
+// isHorizontalRule reports whether the line is a thematic break such as "---" or "* * *".
+func isHorizontalRule(line string) bool {
+	compact := strings.ReplaceAll(strings.TrimSpace(line), " ", "")
+	if len(compact) < 3 {
+		return false
+	}
+	return strings.Trim(compact, "-") == "" || strings.Trim(compact, "*") == "" || strings.Trim(compact, "_") == ""
+}
+
+// isHeading reports whether the line is an ATX heading such as "## Title".
+func isHeading(line string) bool {
+	level := len(line) - len(strings.TrimLeft(line, "#"))
+	return level > 0 && level <= 6 && len(line) > level && line[level] == ' '
+}
+
+// stripEmphasis removes bold markers around a heading that is rendered bold anyway.
+func stripEmphasis(text string) string {
+	if len(text) > 4 && strings.HasPrefix(text, "**") && strings.HasSuffix(text, "**") {
+		return text[2 : len(text)-2]
+	}
+	return text
+}
+
+// convertInline converts inline entities and escapes everything else.
+func convertInline(text string) string {
+	var output strings.Builder
+	runes := []rune(text)
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\\' && i+1 < len(runes) && strings.ContainsRune(specialChars, runes[i+1]):
+			output.WriteString("\\" + string(runes[i+1]))
+			i++
+		case r == '`':
+			if end := indexFrom(runes, i+1, "`"); end > i+1 {
+				output.WriteString("`" + escapeCode(string(runes[i+1:end])) + "`")
+				i = end
+				continue
+			}
+			output.WriteString("\\`")
+		case hasPrefixAt(runes, i, "**"), hasPrefixAt(runes, i, "__") && isBoundary(runes, i-1):
+			marker := string(runes[i : i+2])
+			if end := indexFrom(runes, i+2, marker); end > i+2 {
+				output.WriteString("*" + convertInline(string(runes[i+2:end])) + "*")
+				i = end + 1
+				continue
+			}
+			output.WriteString(escapeText(marker))
+			i++
+		case hasPrefixAt(runes, i, "~~"):
+			if end := indexFrom(runes, i+2, "~~"); end > i+2 {
+				output.WriteString("~" + convertInline(string(runes[i+2:end])) + "~")
+				i = end + 1
+				continue
+			}
+			output.WriteString("\\~\\~")
+			i++
+		case r == '*' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]):
+			if end := closingItalic(runes, i+1, '*'); end > 0 {
+				output.WriteString("_" + convertInline(string(runes[i+1:end])) + "_")
+				i = end
+				continue
+			}
+			output.WriteString("\\*")
+		case r == '_' && isBoundary(runes, i-1) && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]):
+			if end := closingItalic(runes, i+1, '_'); end > 0 && isBoundary(runes, end+1) {
+				output.WriteString("_" + convertInline(string(runes[i+1:end])) + "_")
+				i = end
+				continue
+			}
+			output.WriteString("\\_")
+		case r == '[':
+			if label, url, end, ok := parseLink(runes, i); ok {
+				output.WriteString("[" + convertInline(label) + "](" + escapeURL(url) + ")")
+				i = end
+				continue
+			}
+			output.WriteString("\\[")
+		default:
+			output.WriteString(escapeText(string(r)))
+		}
+	}
+
+	return output.String()
+}
+
+// parseLink parses a link of the form [label](url) starting at the given index.
+// It returns the index of the closing parenthesis.
+func parseLink(runes []rune, start int) (string, string, int, bool) {
+	labelEnd := indexFrom(runes, start+1, "](")
+	if labelEnd < 0 {
+		return "", "", 0, false
+	}
+	urlEnd := closingParenthesis(runes, labelEnd+2)
+	if urlEnd < 0 {
+		return "", "", 0, false
+	}
+	url := string(runes[labelEnd+2 : urlEnd])
+	if url == "" || strings.ContainsAny(url, " \n") {
+		return "", "", 0, false
+	}
+	return string(runes[start+1 : labelEnd]), url, urlEnd, true
+}
+
+// closingParenthesis returns the index of the parenthesis closing a link URL, or -1.
+// Balanced parentheses inside the URL are kept as part of it.
+func closingParenthesis(runes []rune, start int) int {
+	depth := 0
+	for i := start; i < len(runes); i++ {
+		switch runes[i] {
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+// closingItalic returns the index of the marker closing an italic span, or -1.
+func closingItalic(runes []rune, start int, marker rune) int {
+	for i := start; i < len(runes); i++ {
+		if runes[i] == marker && !unicode.IsSpace(runes[i-1]) {
+			return i
+		}
+	}
+	return -1
+}
+
+// isBoundary reports whether the rune at the index is not part of a word.
+func isBoundary(runes []rune, index int) bool {
+	if index < 0 || index >= len(runes) {
+		return true
+	}
+	r := runes[index]
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// hasPrefixAt reports whether the runes contain the prefix at the index.
+func hasPrefixAt(runes []rune, index int, prefix string) bool {
+	return strings.HasPrefix(string(runes[index:]), prefix)
+}
+
+// indexFrom returns the rune index of the first occurrence of substr at or after start, or -1.
+func indexFrom(runes []rune, start int, substr string) int {
+	if start > len(runes) {
+		return -1
+	}
+	index := strings.Index(string(runes[start:]), substr)
+	if index < 0 {
+		return -1
+	}
+	return start + len([]rune(string(runes[start:])[:index]))
+}
+
+// escapeText escapes all MarkdownV2 special characters.
+func escapeText(text string) string {
+	var output strings.Builder
+	for _, r := range text {
+		if strings.ContainsRune(specialChars, r) {
+			output.WriteByte('\\')
+		}
+		output.WriteRune(r)
+	}
+	return output.String()
+}
+
+// escapeCode escapes the characters that are special inside code entities.
+func escapeCode(text string) string {
+	return strings.NewReplacer("\\", "\\\\", "`", "\\`").Replace(text)
+}
+
+// escapeURL escapes the characters that are special inside link URLs.
+func escapeURL(url string) string {
+	return strings.NewReplacer("\\", "\\\\", ")", "\\)").Replace(url)
+}
+
+// CodeRatio returns the fraction of the characters in the text that are inside fenced code blocks.
+func CodeRatio(text string) float64 {
+	total := utf8.RuneCountInString(text)
+	if total == 0 {
+		return 0
+	}
+
+	code := 0
+	inCode := false
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			code += utf8.RuneCountInString(line) + 1
+		}
+	}
+	return min(float64(code)/float64(total), 1)
+}
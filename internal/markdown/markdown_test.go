@@ -0,0 +1,122 @@
+package markdown //nolint:testpackage // Unit tests are in the same package
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToMarkdownV2(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Plain text with punctuation",
+			input:    "Hello, world! It costs 1.5 (approx.)",
+			expected: "Hello, world\\! It costs 1\\.5 \\(approx\\.\\)",
+		},
+		{
+			name:     "Bold",
+			input:    "This is **important** and __also bold__",
+			expected: "This is *important* and *also bold*",
+		},
+		{
+			name:     "Italic",
+			input:    "This is *emphasized* and _slanted_",
+			expected: "This is _emphasized_ and _slanted_",
+		},
+		{
+			name:     "Strikethrough",
+			input:    "~~old~~ new",
+			expected: "~old~ new",
+		},
+		{
+			name:     "Underscores in usernames",
+			input:    "Ask @some_user_name about snake_case",
+			expected: "Ask @some\\_user\\_name about snake\\_case",
+		},
+		{
+			name:     "Inline code",
+			input:    "Run `go test ./...` now.",
+			expected: "Run `go test ./...` now\\.",
+		},
+		{
+			name:     "Link",
+			input:    "See [the docs](https://example.com/a_(b)) for more.",
+			expected: "See [the docs](https://example.com/a_(b\\)) for more\\.",
+		},
+		{
+			name:     "Code fence",
+			input:    "Example:\n```go\nfmt.Println(\"a_b*c\")\n```\nDone.",
+			expected: "Example:\n```go\nfmt.Println(\"a_b*c\")\n```\nDone\\.",
+		},
+		{
+			name:     "Unclosed code fence",
+			input:    "```\nunterminated",
+			expected: "\\`\\`\\`\nunterminated",
+		},
+		{
+			name:     "Heading and list",
+			input:    "## Steps\n- First step.\n* Second step",
+			expected: "*Steps*\n• First step\\.\n• Second step",
+		},
+		{
+			name:     "Blockquote",
+			input:    "> quoted text",
+			expected: ">quoted text",
+		},
+		{
+			name:     "Unbalanced markers",
+			input:    "2 * 3 = 6 and a ** b",
+			expected: "2 \\* 3 \\= 6 and a \\*\\* b",
+		},
+		{
+			name:     "Narrow table",
+			input:    "| Name | **Age** |\n|:-----|----:|\n| Alice | 30 |\n| Bob |\n\nDone.",
+			expected: "```\nName  | Age\n------+----\nAlice | 30\nBob   |\n```\n\nDone\\.",
+		},
+		{
+			name: "Wide table",
+			input: "| Model | Strength | Weakness |\n|---|---|---|\n" +
+				"| llama3.3 | Reasoning over long documents | Slow on small GPUs |",
+			expected: "• *llama3\\.3* — Strength: Reasoning over long documents, Weakness: Slow on small GPUs",
+		},
+		{
+			name:     "Pipe without a table",
+			input:    "a | b",
+			expected: "a \\| b",
+		},
+		{
+			name:     "Horizontal rule and task list",
+			input:    "Intro\n---\n- [ ] Todo\n- [x] Done",
+			expected: "Intro\n──────────\n☐ Todo\n☑ Done",
+		},
+		{
+			name:     "Escaped characters",
+			input:    "Not \\*bold\\*",
+			expected: "Not \\*bold\\*",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			result := ToMarkdownV2(tt.input)
+
+			// Assert
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestCodeRatio(t *testing.T) {
+	assert.Zero(t, CodeRatio(""))
+	assert.Zero(t, CodeRatio("No code here."))
+	ratio := CodeRatio("```go\nfmt.Println()\n```\nThis prints a newline.")
+	assert.Greater(t, ratio, 0.2)
+	assert.Less(t, ratio, 0.5)
+	assert.Greater(t, CodeRatio("```\n"+strings.Repeat("x := 1\n", 20)+"```"), 0.9)
+}
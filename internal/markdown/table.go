@@ -0,0 +1,154 @@
+package markdown
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// maxTableWidth is the widest table in characters that is rendered as a monospace block.
+// Wider tables wrap on phone screens and are rendered as a bullet list instead.
+const maxTableWidth = 48
+
+// parseTable parses a GitHub-style table starting at the given line.
+// It returns the header and body rows and the index of the last line of the table,
+// or nil if the lines do not start a table.
+func parseTable(lines []string, start int) ([][]string, int) {
+	if start+1 >= len(lines) || !strings.Contains(lines[start], "|") || !isTableDelimiter(lines[start+1]) {
+		return nil, 0
+	}
+
+	header := splitTableRow(lines[start])
+	if len(header) != len(splitTableRow(lines[start+1])) {
+		return nil, 0
+	}
+
+	rows := [][]string{header}
+	end := start + 1
+	for end+1 < len(lines) && strings.Contains(lines[end+1], "|") {
+		end++
+		row := splitTableRow(lines[end])
+
+		// Pad or cut the row to the number of header columns
+		for len(row) < len(header) {
+			row = append(row, "")
+		}
+		rows = append(rows, row[:len(header)])
+	}
+	return rows, end
+}
+
+// isTableDelimiter reports whether the line separates the header of a table from its body, like "|---|:-:|".
+func isTableDelimiter(line string) bool {
+	cells := splitTableRow(line)
+	if len(cells) == 0 {
+		return false
+	}
+	for _, cell := range cells {
+		cell = strings.TrimSuffix(strings.TrimPrefix(cell, ":"), ":")
+		if cell == "" || strings.Trim(cell, "-") != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// splitTableRow splits a table row into its trimmed cells. Escaped pipes are kept as part of a cell.
+func splitTableRow(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, "\\|") {
+		line = line[:len(line)-1]
+	}
+
+	var cells []string
+	var cell strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line) && line[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case line[i] == '|':
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+		default:
+			cell.WriteByte(line[i])
+		}
+	}
+	return append(cells, strings.TrimSpace(cell.String()))
+}
+
+// renderTable renders table rows as a monospace block if it is narrow enough, or as a bullet list otherwise.
+func renderTable(rows [][]string) string {
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for column, cell := range row {
+			widths[column] = max(widths[column], utf8.RuneCountInString(plainCell(cell)))
+		}
+	}
+
+	// Columns are separated by " | "
+	total := 3 * (len(widths) - 1)
+	for _, width := range widths {
+		total += width
+	}
+	if total > maxTableWidth {
+		return renderTableList(rows)
+	}
+
+	var output strings.Builder
+	output.WriteString("```")
+	for i, row := range rows {
+		cells := make([]string, len(row))
+		for column, cell := range row {
+			cells[column] = padRight(plainCell(cell), widths[column])
+		}
+		output.WriteString("\n" + escapeCode(strings.TrimRight(strings.Join(cells, " | "), " ")))
+
+		// Underline the header
+		if i == 0 {
+			separators := make([]string, len(widths))
+			for column, width := range widths {
+				separators[column] = strings.Repeat("-", width)
+			}
+			output.WriteString("\n" + strings.Join(separators, "-+-"))
+		}
+	}
+	output.WriteString("\n```")
+	return output.String()
+}
+
+// renderTableList renders each body row of a table as a bullet that names the columns of its values.
+func renderTableList(rows [][]string) string {
+	header := rows[0]
+	lines := make([]string, 0, len(rows)-1)
+	for _, row := range rows[1:] {
+		fields := make([]string, 0, len(row)-1)
+		for column, cell := range row[1:] {
+			if cell == "" {
+				continue
+			}
+			fields = append(fields, convertInline(header[column+1]+": ")+convertInline(cell))
+		}
+
+		line := "•"
+		if row[0] != "" {
+			line += " *" + convertInline(stripEmphasis(row[0])) + "*"
+		}
+		if len(fields) > 0 {
+			line += " — " + strings.Join(fields, ", ")
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// plainCell removes the inline Markdown markers that cannot be rendered inside a monospace block.
+func plainCell(cell string) string {
+	cell = strings.NewReplacer("**", "", "__", "", "~~", "", "`", "", "<br>", " ", "<br/>", " ").Replace(cell)
+	return strings.TrimSpace(cell)
+}
+
+// padRight pads the text with spaces to the given width in characters.
+func padRight(text string, width int) string {
+	return text + strings.Repeat(" ", max(width-utf8.RuneCountInString(text), 0))
+}
@@ -0,0 +1,248 @@
+// Package metrics collects bot usage metrics and exposes them in the Prometheus text format.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// durationBuckets are the upper bounds in seconds of the command duration histogram buckets.
+var durationBuckets = []float64{0.1, 0.5, 1, 2.5, 5, 10, 30, 60} //nolint:gochecknoglobals // Read-only bucket bounds
+
+// queryBuckets are the upper bounds in seconds of the database query duration histogram buckets.
+var queryBuckets = []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1} //nolint:gochecknoglobals // Read-only bucket bounds
+
+// CommandKey identifies the commands counted together.
+type CommandKey struct {
+	Command  string
+	ChatType string
+}
+
+// CommandStats contains the invocation counts and latencies of a command.
+type CommandStats struct {
+	CommandKey
+	Invocations int64
+	Failures    int64
+	Duration    time.Duration
+	// Buckets counts the invocations that took at most the upper bound of each duration bucket.
+	Buckets []int64
+}
+
+// Histogram contains the number, total, and maximum of a series of observed durations.
+type Histogram struct {
+	Count int64
+	Sum   time.Duration
+	Max   time.Duration
+	// Buckets counts the observations that took at most the upper bound of each bucket.
+	Buckets []int64
+}
+
+func newHistogram(bounds []float64) Histogram {
+	return Histogram{Buckets: make([]int64, len(bounds))}
+}
+
+// observe adds a duration to the histogram.
+func (h *Histogram) observe(duration time.Duration, bounds []float64) {
+	h.Count++
+	h.Sum += duration
+	h.Max = max(h.Max, duration)
+	for i, bound := range bounds {
+		if duration.Seconds() <= bound {
+			h.Buckets[i]++
+		}
+	}
+}
+
+// Mean returns the average observed duration.
+func (h Histogram) Mean() time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	return h.Sum / time.Duration(h.Count)
+}
+
+// snapshot returns a copy of the histogram that is not modified by later observations.
+func (h Histogram) snapshot() Histogram {
+	h.Buckets = slices.Clone(h.Buckets)
+	return h
+}
+
+// Registry records the metrics of the bot. It is safe for concurrent use.
+type Registry struct {
+	mu        sync.Mutex
+	commands  map[CommandKey]*CommandStats
+	queueWait Histogram
+	queries   Histogram
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		commands:  map[CommandKey]*CommandStats{},
+		queueWait: newHistogram(durationBuckets),
+		queries:   newHistogram(queryBuckets),
+	}
+}
+
+// ObserveCommand records an invocation of a command in a chat of the given type.
+func (r *Registry) ObserveCommand(command string, chatType string, duration time.Duration, failed bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := CommandKey{Command: command, ChatType: chatType}
+	stats, ok := r.commands[key]
+	if !ok {
+		stats = &CommandStats{CommandKey: key, Buckets: make([]int64, len(durationBuckets))}
+		r.commands[key] = stats
+	}
+
+	stats.Invocations++
+	if failed {
+		stats.Failures++
+	}
+	stats.Duration += duration
+	for i, bound := range durationBuckets {
+		if duration.Seconds() <= bound {
+			stats.Buckets[i]++
+		}
+	}
+}
+
+// ObserveQueueWait records the time a message waited for the generation semaphore.
+func (r *Registry) ObserveQueueWait(duration time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.queueWait.observe(duration, durationBuckets)
+}
+
+// ObserveDatabaseQuery records the duration of a database statement.
+func (r *Registry) ObserveDatabaseQuery(duration time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.queries.observe(duration, queryBuckets)
+}
+
+// QueueWait returns a snapshot of the time messages waited for the generation semaphore.
+func (r *Registry) QueueWait() Histogram {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.queueWait.snapshot()
+}
+
+// DatabaseQueries returns a snapshot of the durations of database statements.
+func (r *Registry) DatabaseQueries() Histogram {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.queries.snapshot()
+}
+
+// Commands returns a snapshot of the command metrics sorted by command and chat type.
+func (r *Registry) Commands() []CommandStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	commands := make([]CommandStats, 0, len(r.commands))
+	for _, stats := range r.commands {
+		snapshot := *stats
+		snapshot.Buckets = slices.Clone(stats.Buckets)
+		commands = append(commands, snapshot)
+	}
+	slices.SortFunc(commands, func(a, b CommandStats) int {
+		if c := strings.Compare(a.Command, b.Command); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ChatType, b.ChatType)
+	})
+	return commands
+}
+
+// WriteTo writes the metrics in the Prometheus text exposition format.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	commands := r.Commands()
+	output := &countingWriter{writer: bufio.NewWriter(w)}
+
+	fmt.Fprintln(output, "# HELP tellama_command_invocations_total Number of times each command or handler was invoked.")
+	fmt.Fprintln(output, "# TYPE tellama_command_invocations_total counter")
+	for _, stats := range commands {
+		fmt.Fprintf(output, "tellama_command_invocations_total{%s} %d\n", labels(stats.CommandKey), stats.Invocations)
+	}
+
+	fmt.Fprintln(output, "# HELP tellama_command_failures_total Number of times each command or handler failed.")
+	fmt.Fprintln(output, "# TYPE tellama_command_failures_total counter")
+	for _, stats := range commands {
+		fmt.Fprintf(output, "tellama_command_failures_total{%s} %d\n", labels(stats.CommandKey), stats.Failures)
+	}
+
+	fmt.Fprintln(output, "# HELP tellama_command_duration_seconds Time taken to handle each command or handler.")
+	fmt.Fprintln(output, "# TYPE tellama_command_duration_seconds histogram")
+	for _, stats := range commands {
+		for i, bound := range durationBuckets {
+			fmt.Fprintf(output, "tellama_command_duration_seconds_bucket{%s,le=%q} %d\n",
+				labels(stats.CommandKey), strconv.FormatFloat(bound, 'f', -1, 64), stats.Buckets[i])
+		}
+		fmt.Fprintf(output, "tellama_command_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n",
+			labels(stats.CommandKey), stats.Invocations)
+		fmt.Fprintf(output, "tellama_command_duration_seconds_sum{%s} %g\n",
+			labels(stats.CommandKey), stats.Duration.Seconds())
+		fmt.Fprintf(output, "tellama_command_duration_seconds_count{%s} %d\n",
+			labels(stats.CommandKey), stats.Invocations)
+	}
+
+	writeHistogram(output, "tellama_queue_wait_seconds",
+		"Time messages waited for the generation semaphore.", r.QueueWait(), durationBuckets)
+	writeHistogram(output, "tellama_database_query_duration_seconds",
+		"Time taken to run each database statement.", r.DatabaseQueries(), queryBuckets)
+
+	if output.err != nil {
+		return output.count, output.err
+	}
+	return output.count, output.writer.Flush()
+}
+
+// writeHistogram writes a histogram without labels in the Prometheus text exposition format.
+func writeHistogram(output io.Writer, name string, help string, histogram Histogram, bounds []float64) {
+	fmt.Fprintf(output, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(output, "# TYPE %s histogram\n", name)
+	for i, bound := range bounds {
+		fmt.Fprintf(output, "%s_bucket{le=%q} %d\n",
+			name, strconv.FormatFloat(bound, 'f', -1, 64), histogram.Buckets[i])
+	}
+	fmt.Fprintf(output, "%s_bucket{le=\"+Inf\"} %d\n", name, histogram.Count)
+	fmt.Fprintf(output, "%s_sum %g\n", name, histogram.Sum.Seconds())
+	fmt.Fprintf(output, "%s_count %d\n", name, histogram.Count)
+}
+
+// ServeHTTP serves the metrics to Prometheus scrapers.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = r.WriteTo(w)
+}
+
+// labels formats the labels of a command.
+func labels(key CommandKey) string {
+	return fmt.Sprintf("command=%q,chat_type=%q", key.Command, key.ChatType)
+}
+
+// countingWriter counts the bytes written and keeps the first error.
+type countingWriter struct {
+	writer *bufio.Writer
+	count  int64
+	err    error
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.writer.Write(p)
+	c.count += int64(n)
+	c.err = err
+	return n, err
+}
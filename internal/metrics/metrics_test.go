@@ -0,0 +1,81 @@
+package metrics //nolint:testpackage // Unit tests are in the same package
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObserveCommand(t *testing.T) {
+	// Arrange
+	registry := NewRegistry()
+
+	// Act
+	registry.ObserveCommand("/start", "private", 200*time.Millisecond, false)
+	registry.ObserveCommand("/start", "private", 3*time.Second, true)
+	registry.ObserveCommand("/start", "group", time.Second, false)
+	registry.ObserveCommand("text", "group", 90*time.Second, false)
+
+	// Assert
+	commands := registry.Commands()
+	require.Len(t, commands, 3)
+	assert.Equal(t, CommandKey{Command: "/start", ChatType: "group"}, commands[0].CommandKey)
+	assert.Equal(t, CommandKey{Command: "/start", ChatType: "private"}, commands[1].CommandKey)
+	assert.Equal(t, int64(2), commands[1].Invocations)
+	assert.Equal(t, int64(1), commands[1].Failures)
+	assert.Equal(t, 3200*time.Millisecond, commands[1].Duration)
+	assert.Equal(t, []int64{0, 1, 1, 1, 2, 2, 2, 2}, commands[1].Buckets)
+	assert.Equal(t, []int64{0, 0, 0, 0, 0, 0, 0, 0}, commands[2].Buckets)
+}
+
+func TestObserveDurations(t *testing.T) {
+	// Arrange
+	registry := NewRegistry()
+
+	// Act
+	registry.ObserveQueueWait(0)
+	registry.ObserveQueueWait(2 * time.Second)
+	registry.ObserveDatabaseQuery(3 * time.Millisecond)
+	registry.ObserveDatabaseQuery(time.Millisecond)
+
+	// Assert
+	queueWait := registry.QueueWait()
+	assert.Equal(t, int64(2), queueWait.Count)
+	assert.Equal(t, time.Second, queueWait.Mean())
+	assert.Equal(t, 2*time.Second, queueWait.Max)
+	assert.Equal(t, []int64{1, 1, 1, 2, 2, 2, 2, 2}, queueWait.Buckets)
+	queries := registry.DatabaseQueries()
+	assert.Equal(t, int64(2), queries.Count)
+	assert.Equal(t, 2*time.Millisecond, queries.Mean())
+	assert.Equal(t, []int64{1, 2, 2, 2, 2, 2, 2}, queries.Buckets)
+	assert.Equal(t, time.Duration(0), Histogram{}.Mean())
+}
+
+func TestServeHTTP(t *testing.T) {
+	// Arrange
+	registry := NewRegistry()
+	registry.ObserveCommand("/usage", "group", 1500*time.Millisecond, true)
+	registry.ObserveDatabaseQuery(20 * time.Millisecond)
+	recorder := httptest.NewRecorder()
+
+	// Act
+	registry.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+	// Assert
+	body := recorder.Body.String()
+	assert.True(t, strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/plain"))
+	assert.Contains(t, body, `tellama_command_invocations_total{command="/usage",chat_type="group"} 1`)
+	assert.Contains(t, body, `tellama_command_failures_total{command="/usage",chat_type="group"} 1`)
+	assert.Contains(t, body, `tellama_command_duration_seconds_bucket{command="/usage",chat_type="group",le="1"} 0`)
+	assert.Contains(t, body, `tellama_command_duration_seconds_bucket{command="/usage",chat_type="group",le="2.5"} 1`)
+	assert.Contains(t, body, `tellama_command_duration_seconds_bucket{command="/usage",chat_type="group",le="+Inf"} 1`)
+	assert.Contains(t, body, `tellama_command_duration_seconds_sum{command="/usage",chat_type="group"} 1.5`)
+	assert.Contains(t, body, `tellama_queue_wait_seconds_count 0`)
+	assert.Contains(t, body, `tellama_database_query_duration_seconds_bucket{le="0.01"} 0`)
+	assert.Contains(t, body, `tellama_database_query_duration_seconds_bucket{le="0.05"} 1`)
+	assert.Contains(t, body, `tellama_database_query_duration_seconds_count 1`)
+}
@@ -0,0 +1,242 @@
+// Package moderation classifies messages and responses as harmful before they reach the model or the chat.
+package moderation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ollama/ollama/api"
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+	"github.com/openai/openai-go/shared"
+)
+
+type Backend int
+
+const (
+	BackendOpenAI Backend = iota
+	BackendOllama
+)
+
+func (b Backend) String() string {
+	return [...]string{"openai", "ollama"}[b]
+}
+
+func ParseBackend(s string) (Backend, error) {
+	switch s {
+	case "openai":
+		return BackendOpenAI, nil
+	case "ollama":
+		return BackendOllama, nil
+	default:
+		return 0, errors.New("unknown moderation backend")
+	}
+}
+
+const openAIBaseURL = "https://api.openai.com/v1/"
+
+type Config struct {
+	Enabled bool
+	Backend Backend
+	BaseURL string
+	APIKey  string
+	Model   string
+
+	// Threshold is the score from 0 to 1 at which a category is flagged
+	Threshold float64
+
+	// Thresholds overrides the threshold of individual categories
+	Thresholds map[string]float64
+
+	// CheckResponses also checks the responses of the model before they are sent
+	CheckResponses bool
+	Timeout        time.Duration
+}
+
+func (c *Config) Validate() error {
+	if c.Model == "" {
+		return errors.New("model is required")
+	}
+	if c.Backend == BackendOllama && c.BaseURL == "" {
+		return errors.New("base URL is required for Ollama")
+	}
+	if c.Threshold <= 0 || c.Threshold > 1 {
+		return errors.New("threshold must be greater than 0 and at most 1")
+	}
+	for category, threshold := range c.Thresholds {
+		if threshold <= 0 {
+			return fmt.Errorf("threshold of category %s must be positive", category)
+		}
+	}
+	return nil
+}
+
+// Result is the outcome of checking a text.
+type Result struct {
+	// Categories are the categories whose scores reached their thresholds, sorted by name
+	Categories []string
+
+	// Scores are the scores of all categories returned by the backend
+	Scores map[string]float64
+}
+
+// Flagged reports whether any category reached its threshold.
+func (r Result) Flagged() bool {
+	return len(r.Categories) > 0
+}
+
+// Moderator checks texts with the OpenAI moderation endpoint or a Llama Guard model served by Ollama.
+type Moderator struct {
+	openai     *openai.Client
+	ollama     *api.Client
+	backend    Backend
+	model      string
+	threshold  float64
+	thresholds map[string]float64
+}
+
+func New(config *Config) (*Moderator, error) {
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid moderation config: %w", err)
+	}
+
+	// Categories are matched case-insensitively since configuration keys are lowercased
+	thresholds := make(map[string]float64, len(config.Thresholds))
+	for category, threshold := range config.Thresholds {
+		thresholds[strings.ToLower(category)] = threshold
+	}
+
+	httpClient := &http.Client{Timeout: config.Timeout}
+	moderator := &Moderator{
+		backend:    config.Backend,
+		model:      config.Model,
+		threshold:  config.Threshold,
+		thresholds: thresholds,
+	}
+	switch config.Backend {
+	case BackendOpenAI:
+		baseURL := config.BaseURL
+		if baseURL == "" {
+			baseURL = openAIBaseURL
+		}
+		moderator.openai = openai.NewClient(
+			option.WithBaseURL(baseURL),
+			option.WithAPIKey(config.APIKey),
+			option.WithHTTPClient(httpClient),
+		)
+	case BackendOllama:
+		baseURL, err := url.Parse(config.BaseURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid base URL: %w", err)
+		}
+		moderator.ollama = api.NewClient(baseURL, httpClient)
+	}
+	return moderator, nil
+}
+
+// Check returns the categories of the text whose scores reach their thresholds.
+func (m *Moderator) Check(ctx context.Context, text string) (Result, error) {
+	if strings.TrimSpace(text) == "" {
+		return Result{}, nil
+	}
+
+	var scores map[string]float64
+	var err error
+	switch m.backend {
+	case BackendOpenAI:
+		scores, err = m.checkOpenAI(ctx, text)
+	case BackendOllama:
+		scores, err = m.checkOllama(ctx, text)
+	default:
+		return Result{}, fmt.Errorf("unsupported moderation backend: %s", m.backend)
+	}
+	if err != nil {
+		return Result{}, fmt.Errorf("%s moderation failed: %w", m.backend, err)
+	}
+
+	result := Result{Scores: scores}
+	for category, score := range scores {
+		threshold, ok := m.thresholds[strings.ToLower(category)]
+		if !ok {
+			threshold = m.threshold
+		}
+		if score >= threshold {
+			result.Categories = append(result.Categories, category)
+		}
+	}
+	slices.Sort(result.Categories)
+	return result, nil
+}
+
+func (m *Moderator) checkOpenAI(ctx context.Context, text string) (map[string]float64, error) {
+	response, err := m.openai.Moderations.New(ctx, openai.ModerationNewParams{
+		Input: openai.F[openai.ModerationNewParamsInputUnion](shared.UnionString(text)),
+		Model: openai.F(openai.ModerationModel(m.model)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(response.Results) == 0 {
+		return nil, errors.New("no moderation results returned")
+	}
+
+	// Decode the raw scores so that categories added to the endpoint are included
+	var scores map[string]float64
+	if err = json.Unmarshal([]byte(response.Results[0].CategoryScores.JSON.RawJSON()), &scores); err != nil {
+		return nil, fmt.Errorf("failed to decode category scores: %w", err)
+	}
+	return scores, nil
+}
+
+// checkOllama classifies the text with a Llama Guard model, which answers "safe", or "unsafe"
+// followed by a line with the comma-separated codes of the violated categories.
+// Every violated category is given a score of 1.
+func (m *Moderator) checkOllama(ctx context.Context, text string) (map[string]float64, error) {
+	stream := false
+	var content string
+	err := m.ollama.Chat(ctx, &api.ChatRequest{
+		Model:    m.model,
+		Messages: []api.Message{{Role: "user", Content: text}},
+		Stream:   &stream,
+	}, func(resp api.ChatResponse) error {
+		content += resp.Message.Content
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return parseLlamaGuard(content)
+}
+
+// parseLlamaGuard returns the scores of the categories in the answer of a Llama Guard model.
+func parseLlamaGuard(content string) (map[string]float64, error) {
+	lines := strings.Split(strings.TrimSpace(content), "\n")
+	switch strings.ToLower(strings.TrimSpace(lines[0])) {
+	case "safe":
+		return map[string]float64{}, nil
+	case "unsafe":
+	default:
+		return nil, fmt.Errorf("unexpected classifier answer: %q", content)
+	}
+
+	scores := map[string]float64{}
+	if len(lines) > 1 {
+		for _, category := range strings.Split(lines[1], ",") {
+			if category = strings.TrimSpace(category); category != "" {
+				scores[category] = 1
+			}
+		}
+	}
+	// Flag the text even if the model did not name a category
+	if len(scores) == 0 {
+		scores["unsafe"] = 1
+	}
+	return scores, nil
+}
@@ -0,0 +1,147 @@
+package moderation //nolint:testpackage // Unit tests are in the same package
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheck_OpenAI(t *testing.T) {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/moderations", r.URL.Path)
+		assert.Equal(t, "Bearer test_key", r.Header.Get("Authorization"))
+		var body map[string]any
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "I will hurt you", body["input"])
+		assert.Equal(t, "omni-moderation-latest", body["model"])
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{
+			"id": "modr-1",
+			"model": "omni-moderation-latest",
+			"results": [{
+				"flagged": true,
+				"categories": {"harassment": true, "violence": true},
+				"category_applied_input_types": {},
+				"category_scores": {"harassment": 0.6, "harassment/threatening": 0.3, "violence": 0.9}
+			}]
+		}`))
+	}))
+	defer server.Close()
+	moderator, err := New(&Config{
+		Backend:    BackendOpenAI,
+		BaseURL:    server.URL,
+		APIKey:     "test_key",
+		Model:      "omni-moderation-latest",
+		Threshold:  0.5,
+		Thresholds: map[string]float64{"harassment": 0.8, "harassment/threatening": 0.2},
+		Timeout:    5 * time.Second,
+	})
+	require.NoError(t, err)
+
+	// Act
+	result, err := moderator.Check(context.Background(), "I will hurt you")
+
+	// Assert
+	require.NoError(t, err)
+	assert.True(t, result.Flagged())
+	assert.Equal(t, []string{"harassment/threatening", "violence"}, result.Categories)
+	assert.InDelta(t, 0.6, result.Scores["harassment"], 1e-9)
+}
+
+func TestCheck_Ollama(t *testing.T) {
+	tests := []struct {
+		name       string
+		answer     string
+		categories []string
+	}{
+		{name: "Safe", answer: "safe", categories: nil},
+		// S10 is exempt because its threshold is above 1
+		{name: "Unsafe", answer: "\n\nunsafe\nS1,S10", categories: []string{"S1"}},
+		{name: "Unsafe without categories", answer: "unsafe", categories: []string{"unsafe"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/api/chat", r.URL.Path)
+				var body map[string]any
+				assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+				assert.Equal(t, "llama-guard3", body["model"])
+				_ = json.NewEncoder(w).Encode(map[string]any{
+					"model":   "llama-guard3",
+					"message": map[string]string{"role": "assistant", "content": tt.answer},
+					"done":    true,
+				})
+			}))
+			defer server.Close()
+			moderator, err := New(&Config{
+				Backend:    BackendOllama,
+				BaseURL:    server.URL,
+				Model:      "llama-guard3",
+				Threshold:  0.5,
+				Thresholds: map[string]float64{"S10": 2},
+				Timeout:    5 * time.Second,
+			})
+			require.NoError(t, err)
+
+			// Act
+			result, err := moderator.Check(context.Background(), "Hello")
+
+			// Assert
+			require.NoError(t, err)
+			assert.Equal(t, tt.categories, result.Categories)
+		})
+	}
+}
+
+func TestParseLlamaGuard_Invalid(t *testing.T) {
+	// Act
+	_, err := parseLlamaGuard("I cannot classify this")
+
+	// Assert
+	assert.Error(t, err)
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr bool
+	}{
+		{name: "Valid", config: Config{Model: "omni-moderation-latest", Threshold: 0.5}},
+		{name: "Missing model", config: Config{Threshold: 0.5}, wantErr: true},
+		{name: "Threshold above 1", config: Config{Model: "m", Threshold: 1.5}, wantErr: true},
+		{
+			name:    "Ollama without base URL",
+			config:  Config{Backend: BackendOllama, Model: "llama-guard3", Threshold: 0.5},
+			wantErr: true,
+		},
+		{
+			name:    "Negative category threshold",
+			config:  Config{Model: "m", Threshold: 0.5, Thresholds: map[string]float64{"hate": -1}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			err := tt.config.Validate()
+
+			// Assert
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
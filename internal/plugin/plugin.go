@@ -0,0 +1,172 @@
+// Package plugin defines the extension points through which plugins add behavior to the bot,
+// such as filtering messages, changing prompts and responses, and adding commands.
+package plugin
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/k4yt3x/tellama/internal/database"
+
+	"gopkg.in/telebot.v4"
+)
+
+// Plugin is an extension of the bot. Plugins implement any of the extension point interfaces
+// MessageFilter, PreGenerationHook, PostGenerationHook, and CommandProvider.
+type Plugin interface {
+	// Name identifies the plugin in logs and errors
+	Name() string
+}
+
+// MessageFilter inspects incoming messages from permitted chats before they are stored or answered.
+type MessageFilter interface {
+	// FilterMessage returns the text to handle instead of the text of the message,
+	// or false to ignore the message entirely.
+	FilterMessage(ctx telebot.Context, text string) (string, bool, error)
+}
+
+// PreGenerationHook runs before a response is generated.
+type PreGenerationHook interface {
+	// BeforeGeneration returns the conversation sent to the model instead of messages,
+	// which include the system prompt and the current message.
+	BeforeGeneration(ctx telebot.Context, messages []database.Message) ([]database.Message, error)
+}
+
+// PostGenerationHook runs after a response is generated and before it is sent.
+type PostGenerationHook interface {
+	// AfterGeneration returns the response sent instead of response.
+	// An empty response is not sent.
+	AfterGeneration(ctx telebot.Context, response string) (string, error)
+}
+
+// CommandProvider adds bot commands.
+type CommandProvider interface {
+	Commands() []Command
+}
+
+// Command is a bot command added by a plugin.
+type Command struct {
+	// Endpoint is the command including its slash, such as "/weather"
+	Endpoint string
+	Handler  telebot.HandlerFunc
+}
+
+// Registry holds the extension points of the registered plugins, which run in the order of registration.
+type Registry struct {
+	names     []string
+	filters   []MessageFilter
+	preHooks  []PreGenerationHook
+	postHooks []PostGenerationHook
+	commands  []Command
+
+	// commandOwners maps the endpoints of the commands to the names of the plugins adding them
+	commandOwners map[string]string
+}
+
+// NewRegistry registers plugins, checking that their names and commands are unique.
+func NewRegistry(plugins ...Plugin) (*Registry, error) {
+	registry := &Registry{commandOwners: map[string]string{}}
+	for _, plugin := range plugins {
+		if err := registry.register(plugin); err != nil {
+			return nil, err
+		}
+	}
+	return registry, nil
+}
+
+// register adds the extension points implemented by a plugin.
+func (r *Registry) register(plugin Plugin) error {
+	name := plugin.Name()
+	if slices.Contains(r.names, name) {
+		return fmt.Errorf("plugin %s is registered twice", name)
+	}
+
+	// Check the commands first so that an invalid plugin is not partially registered
+	var commands []Command
+	if provider, ok := plugin.(CommandProvider); ok {
+		commands = provider.Commands()
+	}
+	endpoints := map[string]struct{}{}
+	for _, command := range commands {
+		if !strings.HasPrefix(command.Endpoint, "/") || command.Handler == nil {
+			return fmt.Errorf("plugin %s has an invalid command %q", name, command.Endpoint)
+		}
+		if owner, exists := r.commandOwners[command.Endpoint]; exists {
+			return fmt.Errorf("command %s of plugin %s is already registered by %s", command.Endpoint, name, owner)
+		}
+		if _, exists := endpoints[command.Endpoint]; exists {
+			return fmt.Errorf("plugin %s adds the command %s twice", name, command.Endpoint)
+		}
+		endpoints[command.Endpoint] = struct{}{}
+	}
+
+	r.names = append(r.names, name)
+	for _, command := range commands {
+		r.commandOwners[command.Endpoint] = name
+	}
+	r.commands = append(r.commands, commands...)
+	if filter, ok := plugin.(MessageFilter); ok {
+		r.filters = append(r.filters, filter)
+	}
+	if hook, ok := plugin.(PreGenerationHook); ok {
+		r.preHooks = append(r.preHooks, hook)
+	}
+	if hook, ok := plugin.(PostGenerationHook); ok {
+		r.postHooks = append(r.postHooks, hook)
+	}
+	return nil
+}
+
+// Names returns the names of the registered plugins.
+func (r *Registry) Names() []string {
+	return r.names
+}
+
+// Commands returns the commands added by the plugins.
+func (r *Registry) Commands() []Command {
+	return r.commands
+}
+
+// FilterMessage runs the message filters in turn. It returns false as soon as a filter ignores the message.
+func (r *Registry) FilterMessage(ctx telebot.Context, text string) (string, bool, error) {
+	for _, filter := range r.filters {
+		var keep bool
+		var err error
+		text, keep, err = filter.FilterMessage(ctx, text)
+		if err != nil {
+			return "", false, err
+		}
+		if !keep {
+			return "", false, nil
+		}
+	}
+	return text, true, nil
+}
+
+// BeforeGeneration runs the pre-generation hooks in turn, each receiving the conversation returned by the last.
+func (r *Registry) BeforeGeneration(
+	ctx telebot.Context,
+	messages []database.Message,
+) ([]database.Message, error) {
+	for _, hook := range r.preHooks {
+		var err error
+		messages, err = hook.BeforeGeneration(ctx, messages)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return messages, nil
+}
+
+// AfterGeneration runs the post-generation hooks in turn, each receiving the response returned by the last.
+func (r *Registry) AfterGeneration(ctx telebot.Context, response string) (string, error) {
+	for _, hook := range r.postHooks {
+		var err error
+		response, err = hook.AfterGeneration(ctx, response)
+		if err != nil {
+			return "", err
+		}
+	}
+	return response, nil
+}
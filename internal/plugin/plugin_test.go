@@ -0,0 +1,168 @@
+package plugin //nolint:testpackage // Unit tests are in the same package
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/k4yt3x/tellama/internal/database"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/telebot.v4"
+)
+
+// testPlugin implements every extension point.
+type testPlugin struct {
+	name     string
+	commands []Command
+	err      error
+}
+
+func (p *testPlugin) Name() string {
+	return p.name
+}
+
+func (p *testPlugin) FilterMessage(_ telebot.Context, text string) (string, bool, error) {
+	if p.err != nil {
+		return "", false, p.err
+	}
+	if strings.Contains(text, "blocked") {
+		return "", false, nil
+	}
+	return text + " [" + p.name + "]", true, nil
+}
+
+func (p *testPlugin) BeforeGeneration(_ telebot.Context, messages []database.Message) ([]database.Message, error) {
+	return append(messages, database.Message{Role: "system", Content: p.name}), p.err
+}
+
+func (p *testPlugin) AfterGeneration(_ telebot.Context, response string) (string, error) {
+	return strings.ToUpper(response), p.err
+}
+
+func (p *testPlugin) Commands() []Command {
+	return p.commands
+}
+
+// namedPlugin implements no extension point.
+type namedPlugin string
+
+func (p namedPlugin) Name() string {
+	return string(p)
+}
+
+func noopHandler(telebot.Context) error {
+	return nil
+}
+
+func TestRegistry(t *testing.T) {
+	t.Run("Hooks run in order", func(t *testing.T) {
+		// Arrange
+		registry, err := NewRegistry(&testPlugin{name: "first"}, namedPlugin("empty"), &testPlugin{name: "second"})
+		require.NoError(t, err)
+
+		// Act
+		text, keep, filterErr := registry.FilterMessage(nil, "hello")
+		messages, beforeErr := registry.BeforeGeneration(nil, nil)
+		response, afterErr := registry.AfterGeneration(nil, "reply")
+
+		// Assert
+		require.NoError(t, filterErr)
+		require.NoError(t, beforeErr)
+		require.NoError(t, afterErr)
+		assert.True(t, keep)
+		assert.Equal(t, "hello [first] [second]", text)
+		require.Len(t, messages, 2)
+		assert.Equal(t, "first", messages[0].Content)
+		assert.Equal(t, "second", messages[1].Content)
+		assert.Equal(t, "REPLY", response)
+		assert.Equal(t, []string{"first", "empty", "second"}, registry.Names())
+	})
+
+	t.Run("Filtered message", func(t *testing.T) {
+		// Arrange
+		registry, err := NewRegistry(&testPlugin{name: "filter"})
+		require.NoError(t, err)
+
+		// Act
+		text, keep, err := registry.FilterMessage(nil, "blocked topic")
+
+		// Assert
+		require.NoError(t, err)
+		assert.False(t, keep)
+		assert.Empty(t, text)
+	})
+
+	t.Run("Hook error", func(t *testing.T) {
+		// Arrange
+		registry, err := NewRegistry(&testPlugin{name: "failing", err: errors.New("failed")})
+		require.NoError(t, err)
+
+		// Act
+		_, keep, filterErr := registry.FilterMessage(nil, "hello")
+		_, beforeErr := registry.BeforeGeneration(nil, nil)
+		_, afterErr := registry.AfterGeneration(nil, "reply")
+
+		// Assert
+		assert.False(t, keep)
+		assert.Error(t, filterErr)
+		assert.Error(t, beforeErr)
+		assert.Error(t, afterErr)
+	})
+
+	t.Run("Empty registry", func(t *testing.T) {
+		// Arrange
+		registry, err := NewRegistry()
+		require.NoError(t, err)
+
+		// Act
+		text, keep, filterErr := registry.FilterMessage(nil, "hello")
+		response, afterErr := registry.AfterGeneration(nil, "reply")
+
+		// Assert
+		require.NoError(t, filterErr)
+		require.NoError(t, afterErr)
+		assert.True(t, keep)
+		assert.Equal(t, "hello", text)
+		assert.Equal(t, "reply", response)
+		assert.Empty(t, registry.Commands())
+	})
+}
+
+func TestNewRegistry_Commands(t *testing.T) {
+	weather := Command{Endpoint: "/weather", Handler: noopHandler}
+
+	t.Run("Commands are collected", func(t *testing.T) {
+		registry, err := NewRegistry(
+			&testPlugin{name: "weather", commands: []Command{weather}},
+			&testPlugin{name: "news", commands: []Command{{Endpoint: "/news", Handler: noopHandler}}},
+		)
+		require.NoError(t, err)
+		assert.Len(t, registry.Commands(), 2)
+	})
+
+	t.Run("Duplicate command", func(t *testing.T) {
+		_, err := NewRegistry(
+			&testPlugin{name: "weather", commands: []Command{weather}},
+			&testPlugin{name: "forecast", commands: []Command{weather}},
+		)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "already registered by weather")
+	})
+
+	t.Run("Duplicate command in a plugin", func(t *testing.T) {
+		_, err := NewRegistry(&testPlugin{name: "weather", commands: []Command{weather, weather}})
+		assert.Error(t, err)
+	})
+
+	t.Run("Invalid command", func(t *testing.T) {
+		_, err := NewRegistry(&testPlugin{name: "weather", commands: []Command{{Endpoint: "weather"}}})
+		assert.Error(t, err)
+	})
+
+	t.Run("Duplicate plugin", func(t *testing.T) {
+		_, err := NewRegistry(namedPlugin("weather"), namedPlugin("weather"))
+		assert.Error(t, err)
+	})
+}
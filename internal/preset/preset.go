@@ -0,0 +1,154 @@
+// Package preset serializes the configuration of a chat into a compact text blob
+// that can be shared and imported into other chats.
+package preset
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/k4yt3x/tellama/internal/database"
+)
+
+// Prefix marks the beginning of an encoded preset.
+const Prefix = "tellama-preset:"
+
+// version is the version of the preset format.
+const version = 1
+
+// maxDecodedSize limits the size of a decompressed preset.
+const maxDecodedSize = 1 << 20
+
+// Preset holds the shareable settings of a chat override.
+// Connection settings such as the base URL and API key are never included.
+type Preset struct {
+	Version            int     `json:"v"`
+	SystemPrompt       string  `json:"system_prompt,omitempty"`
+	Model              string  `json:"model,omitempty"`
+	Options            string  `json:"options,omitempty"`
+	MaxTurns           int     `json:"max_turns,omitempty"`
+	RoutingDisabled    *bool   `json:"routing_disabled,omitempty"`
+	RoutingSmallModel  string  `json:"routing_small_model,omitempty"`
+	RoutingLargeModel  string  `json:"routing_large_model,omitempty"`
+	FileReplyMinLength int     `json:"file_reply_min_length,omitempty"`
+	FileReplyCodeRatio float64 `json:"file_reply_code_ratio,omitempty"`
+	ChatEvents         string  `json:"chat_events,omitempty"`
+	ModelBadge         string  `json:"model_badge,omitempty"`
+	Language           string  `json:"language,omitempty"`
+	Trigger            string  `json:"trigger,omitempty"`
+	MemoryDisabled     *bool   `json:"memory_disabled,omitempty"`
+	VoiceReply         *bool   `json:"voice_reply,omitempty"`
+	Persona            string  `json:"persona,omitempty"`
+	ResponseFormat     string  `json:"response_format,omitempty"`
+	WelcomeMembers     *bool   `json:"welcome_members,omitempty"`
+}
+
+// FromChatOverride returns the preset of a chat override.
+func FromChatOverride(chatOverride database.ChatOverride) Preset {
+	return Preset{
+		Version:            version,
+		SystemPrompt:       chatOverride.SystemPrompt,
+		Model:              chatOverride.Model,
+		Options:            chatOverride.Options,
+		MaxTurns:           chatOverride.MaxTurns,
+		RoutingDisabled:    chatOverride.RoutingDisabled,
+		RoutingSmallModel:  chatOverride.RoutingSmallModel,
+		RoutingLargeModel:  chatOverride.RoutingLargeModel,
+		FileReplyMinLength: chatOverride.FileReplyMinLength,
+		FileReplyCodeRatio: chatOverride.FileReplyCodeRatio,
+		ChatEvents:         chatOverride.ChatEvents,
+		ModelBadge:         chatOverride.ModelBadge,
+		Language:           chatOverride.Language,
+		Trigger:            chatOverride.Trigger,
+		MemoryDisabled:     chatOverride.MemoryDisabled,
+		VoiceReply:         chatOverride.VoiceReply,
+		Persona:            chatOverride.Persona,
+		ResponseFormat:     chatOverride.ResponseFormat,
+		WelcomeMembers:     chatOverride.WelcomeMembers,
+	}
+}
+
+// ChatOverride returns the chat override of the given chat with the settings of the preset.
+func (p Preset) ChatOverride(chatID int64, chatTitle string) database.ChatOverride {
+	return database.ChatOverride{
+		ChatID:             chatID,
+		ChatTitle:          chatTitle,
+		SystemPrompt:       p.SystemPrompt,
+		Model:              p.Model,
+		Options:            p.Options,
+		MaxTurns:           p.MaxTurns,
+		RoutingDisabled:    p.RoutingDisabled,
+		RoutingSmallModel:  p.RoutingSmallModel,
+		RoutingLargeModel:  p.RoutingLargeModel,
+		FileReplyMinLength: p.FileReplyMinLength,
+		FileReplyCodeRatio: p.FileReplyCodeRatio,
+		ChatEvents:         p.ChatEvents,
+		ModelBadge:         p.ModelBadge,
+		Language:           p.Language,
+		Trigger:            p.Trigger,
+		MemoryDisabled:     p.MemoryDisabled,
+		VoiceReply:         p.VoiceReply,
+		Persona:            p.Persona,
+		ResponseFormat:     p.ResponseFormat,
+		WelcomeMembers:     p.WelcomeMembers,
+	}
+}
+
+// Encode returns the preset as a compressed and base64-encoded blob starting with the prefix.
+func (p Preset) Encode() (string, error) {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return "", err
+	}
+
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	if _, err = writer.Write(data); err != nil {
+		return "", err
+	}
+	if err = writer.Close(); err != nil {
+		return "", err
+	}
+	return Prefix + base64.RawURLEncoding.EncodeToString(compressed.Bytes()), nil
+}
+
+// Decode parses a preset encoded by Encode, or a preset given as plain JSON.
+func Decode(text string) (Preset, error) {
+	text = strings.TrimSpace(text)
+
+	data := []byte(text)
+	if !strings.HasPrefix(text, "{") {
+		compressed, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(text, Prefix))
+		if err != nil {
+			return Preset{}, errors.New("the preset is not valid base64")
+		}
+		reader, err := gzip.NewReader(bytes.NewReader(compressed))
+		if err != nil {
+			return Preset{}, errors.New("the preset is not compressed correctly")
+		}
+		data, err = io.ReadAll(io.LimitReader(reader, maxDecodedSize))
+		if err != nil {
+			return Preset{}, errors.New("the preset is not compressed correctly")
+		}
+	}
+
+	var p Preset
+	if err := json.Unmarshal(data, &p); err != nil {
+		return Preset{}, fmt.Errorf("the preset is not valid JSON: %w", err)
+	}
+	if p.Version != version {
+		return Preset{}, fmt.Errorf("unsupported preset version %d", p.Version)
+	}
+	if p.Options != "" && !json.Valid([]byte(p.Options)) {
+		return Preset{}, errors.New("the preset options are not valid JSON")
+	}
+	if p.MaxTurns < 0 || p.FileReplyMinLength < 0 || p.FileReplyCodeRatio < 0 || p.FileReplyCodeRatio > 1 {
+		return Preset{}, errors.New("the preset contains out of range values")
+	}
+	return p, nil
+}
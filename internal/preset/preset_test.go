@@ -0,0 +1,91 @@
+package preset //nolint:testpackage // Unit tests are in the same package
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/k4yt3x/tellama/internal/database"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	// Arrange
+	enabled, disabled := true, false
+	chatOverride := database.ChatOverride{
+		ChatID:             -100,
+		APIKey:             "sk-secret",
+		BaseURL:            "http://internal:11434",
+		SystemPrompt:       "You are a helpful pirate.",
+		Model:              "llama3.3",
+		Options:            `{"temperature":0.3}`,
+		MaxTurns:           20,
+		FileReplyCodeRatio: 0.75,
+		ModelBadge:         "suffix",
+		Language:           "ja",
+		Trigger:            "prefix",
+		MemoryDisabled:     &enabled,
+		VoiceReply:         &disabled,
+		Persona:            "helpdesk",
+		ResponseFormat:     "json",
+		WelcomeMembers:     &enabled,
+	}
+
+	// Act
+	blob, err := FromChatOverride(chatOverride).Encode()
+	require.NoError(t, err)
+	decoded, err := Decode(blob)
+	require.NoError(t, err)
+	imported := decoded.ChatOverride(-200, "Other chat")
+
+	// Assert
+	assert.True(t, strings.HasPrefix(blob, Prefix))
+	assert.NotContains(t, blob, "sk-secret")
+	assert.Equal(t, int64(-200), imported.ChatID)
+	assert.Empty(t, imported.APIKey)
+	assert.Empty(t, imported.BaseURL)
+	assert.Equal(t, chatOverride.SystemPrompt, imported.SystemPrompt)
+	assert.Equal(t, chatOverride.Options, imported.Options)
+	assert.Equal(t, 20, imported.MaxTurns)
+	assert.InDelta(t, 0.75, imported.FileReplyCodeRatio, 1e-9)
+	assert.Equal(t, "suffix", imported.ModelBadge)
+	assert.Equal(t, "ja", imported.Language)
+	assert.Equal(t, "prefix", imported.Trigger)
+	assert.True(t, database.IsTrue(imported.MemoryDisabled))
+	require.NotNil(t, imported.VoiceReply)
+	assert.False(t, *imported.VoiceReply)
+	assert.Nil(t, imported.RoutingDisabled)
+	assert.Equal(t, "helpdesk", imported.Persona)
+	assert.Equal(t, "json", imported.ResponseFormat)
+	assert.True(t, database.IsTrue(imported.WelcomeMembers))
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		err   string
+	}{
+		{name: "Plain JSON", input: `{"v":1,"model":"llama3.3"}`},
+		{name: "Invalid base64", input: Prefix + "!!!", err: "not valid base64"},
+		{name: "Not compressed", input: Prefix + "aGVsbG8", err: "not compressed correctly"},
+		{name: "Unsupported version", input: `{"v":2}`, err: "unsupported preset version 2"},
+		{name: "Invalid options", input: `{"v":1,"options":"{"}`, err: "options are not valid JSON"},
+		{name: "Out of range", input: `{"v":1,"file_reply_code_ratio":2}`, err: "out of range"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			_, err := Decode(tt.input)
+
+			// Assert
+			if tt.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.err)
+			}
+		})
+	}
+}
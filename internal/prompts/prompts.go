@@ -0,0 +1,129 @@
+// Package prompts loads a library of system prompt templates from a directory, so that
+// chats can refer to a template by name and templates can include one another.
+package prompts
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+// Prefix marks a system prompt that refers to a template of the library by name.
+const Prefix = "file:"
+
+// extension is the file extension of the templates in the library directory.
+const extension = ".tmpl"
+
+// namePattern matches the names of templates, which are their file names without the extension.
+//
+//nolint:gochecknoglobals // Compiled once
+var namePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// ErrUnknownTemplate is returned when a system prompt refers to a template that is not in the library.
+var ErrUnknownTemplate = errors.New("unknown prompt template")
+
+// Library is a set of named system prompt templates.
+// Each template may include the others with {{template "name" .}}.
+type Library struct {
+	templates *template.Template
+	names     []string
+}
+
+// Load reads the templates in a directory, returning an empty library if the directory does not exist.
+func Load(dir string) (*Library, error) {
+	library := &Library{templates: template.New("")}
+	if dir == "" {
+		return library, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return library, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read prompt directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		name, isTemplate := strings.CutSuffix(entry.Name(), extension)
+		if entry.IsDir() || !isTemplate {
+			continue
+		}
+		if !namePattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid prompt template name %q", name)
+		}
+
+		var data []byte
+		data, err = os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read prompt template %q: %w", name, err)
+		}
+		if _, err = library.templates.New(name).Parse(string(data)); err != nil {
+			return nil, fmt.Errorf("failed to parse prompt template %q: %w", name, err)
+		}
+		library.names = append(library.names, name)
+	}
+	slices.Sort(library.names)
+	return library, nil
+}
+
+// Names returns the names of the templates in the library, sorted.
+func (l *Library) Names() []string {
+	if l == nil {
+		return nil
+	}
+	return l.names
+}
+
+// Has returns whether the library contains a template.
+func (l *Library) Has(name string) bool {
+	return l != nil && slices.Contains(l.names, name)
+}
+
+// Reference returns the name of the template a system prompt refers to, or false if it is an inline prompt.
+func Reference(prompt string) (string, bool) {
+	name, ok := strings.CutPrefix(strings.TrimSpace(prompt), Prefix)
+	return strings.TrimSpace(name), ok
+}
+
+// Render executes a system prompt with the given data.
+// The prompt is either a reference to a template of the library or an inline template,
+// which may include the templates of the library as well.
+func (l *Library) Render(prompt string, data any) (string, error) {
+	var tmpl *template.Template
+	if name, ok := Reference(prompt); ok {
+		if !l.Has(name) {
+			return "", fmt.Errorf("%w: %s", ErrUnknownTemplate, name)
+		}
+		tmpl = l.templates.Lookup(name)
+	} else {
+		var err error
+		tmpl, err = l.parse(prompt)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, data); err != nil {
+		return "", err
+	}
+	return rendered.String(), nil
+}
+
+// parse parses an inline template with access to the templates of the library.
+func (l *Library) parse(prompt string) (*template.Template, error) {
+	if l == nil {
+		return template.New("sysprompt").Parse(prompt)
+	}
+	templates, err := l.templates.Clone()
+	if err != nil {
+		return nil, err
+	}
+	return templates.New("sysprompt").Parse(prompt)
+}
@@ -0,0 +1,102 @@
+package prompts //nolint:testpackage // Unit tests are in the same package
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTemplates writes the given templates to a temporary directory and returns it.
+func writeTemplates(t *testing.T, templates map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range templates {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	return dir
+}
+
+func TestLoad(t *testing.T) {
+	// Arrange
+	dir := writeTemplates(t, map[string]string{
+		"rules.tmpl":    "Be polite.",
+		"helpdesk.tmpl": `You help {{.ChatTitle}}. {{template "rules" .}}`,
+		"notes.txt":     "Not a template.",
+	})
+
+	// Act
+	library, err := Load(dir)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, []string{"helpdesk", "rules"}, library.Names())
+	assert.True(t, library.Has("helpdesk"))
+	assert.False(t, library.Has("notes"))
+
+	t.Run("Reference", func(t *testing.T) {
+		// Act
+		rendered, renderErr := library.Render("file:helpdesk", map[string]any{"ChatTitle": "Example"})
+
+		// Assert
+		require.NoError(t, renderErr)
+		assert.Equal(t, "You help Example. Be polite.", rendered)
+	})
+
+	t.Run("Inline include", func(t *testing.T) {
+		// Act
+		rendered, renderErr := library.Render(`Hi. {{template "rules" .}}`, nil)
+
+		// Assert
+		require.NoError(t, renderErr)
+		assert.Equal(t, "Hi. Be polite.", rendered)
+	})
+
+	t.Run("Unknown reference", func(t *testing.T) {
+		// Act
+		_, renderErr := library.Render("file:missing", nil)
+
+		// Assert
+		assert.ErrorIs(t, renderErr, ErrUnknownTemplate)
+	})
+}
+
+func TestLoad_MissingDirectory(t *testing.T) {
+	// Act
+	library, err := Load(filepath.Join(t.TempDir(), "prompts"))
+
+	// Assert
+	require.NoError(t, err)
+	assert.Empty(t, library.Names())
+	rendered, err := library.Render("Your name is {{.Name}}.", map[string]any{"Name": "Tellama"})
+	require.NoError(t, err)
+	assert.Equal(t, "Your name is Tellama.", rendered)
+}
+
+func TestLoad_InvalidTemplate(t *testing.T) {
+	// Arrange
+	dir := writeTemplates(t, map[string]string{"broken.tmpl": "{{if .Name}}"})
+
+	// Act
+	_, err := Load(dir)
+
+	// Assert
+	assert.Error(t, err)
+}
+
+func TestNilLibrary(t *testing.T) {
+	// Arrange
+	var library *Library
+
+	// Act
+	rendered, err := library.Render("Hello {{.Name}}", map[string]any{"Name": "world"})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "Hello world", rendered)
+	assert.False(t, library.Has("helpdesk"))
+	_, err = library.Render("file:helpdesk", nil)
+	assert.ErrorIs(t, err, ErrUnknownTemplate)
+}
@@ -0,0 +1,180 @@
+// Package prompttest runs canary prompts against a model and checks that the
+// replies have the expected properties.
+package prompttest
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/k4yt3x/tellama/internal/genai"
+
+	"gopkg.in/yaml.v3"
+)
+
+// judgeSystemPrompt instructs the judge model how to grade a reply.
+const judgeSystemPrompt = `You grade the replies of a chatbot against a criterion.
+Answer with PASS or FAIL on the first line, followed by a one-sentence reason on the second line.`
+
+// Suite is a set of prompts and the properties expected of their replies.
+type Suite struct {
+	// SystemPrompt replaces the default system prompt for all cases
+	SystemPrompt string `yaml:"system_prompt"`
+
+	// JudgeModel is the model that grades judge checks, empty to use the configured model
+	JudgeModel string `yaml:"judge_model"`
+
+	Cases []Case `yaml:"cases"`
+}
+
+// Case is a prompt and the checks its reply must pass.
+type Case struct {
+	Name         string  `yaml:"name"`
+	Prompt       string  `yaml:"prompt"`
+	SystemPrompt string  `yaml:"system_prompt"`
+	Checks       []Check `yaml:"checks"`
+}
+
+// Check is a property expected of a reply. Exactly one of its fields must be set.
+type Check struct {
+	// Regex must match the reply
+	Regex string `yaml:"regex"`
+
+	// NotRegex must not match the reply
+	NotRegex string `yaml:"not_regex"`
+
+	// Judge is a criterion the judge model must consider the reply to meet
+	Judge string `yaml:"judge"`
+}
+
+// Load reads and validates a suite from a YAML file.
+func Load(path string) (*Suite, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prompt suite: %w", err)
+	}
+
+	var suite Suite
+	if err = yaml.Unmarshal(data, &suite); err != nil {
+		return nil, fmt.Errorf("failed to parse prompt suite: %w", err)
+	}
+	if err = suite.Validate(); err != nil {
+		return nil, err
+	}
+	return &suite, nil
+}
+
+func (s *Suite) Validate() error {
+	if len(s.Cases) == 0 {
+		return errors.New("prompt suite has no cases")
+	}
+	for i, testCase := range s.Cases {
+		if testCase.Name == "" {
+			return fmt.Errorf("case %d has no name", i+1)
+		}
+		if testCase.Prompt == "" {
+			return fmt.Errorf("case %q has no prompt", testCase.Name)
+		}
+		if len(testCase.Checks) == 0 {
+			return fmt.Errorf("case %q has no checks", testCase.Name)
+		}
+		for _, check := range testCase.Checks {
+			if err := check.Validate(); err != nil {
+				return fmt.Errorf("case %q: %w", testCase.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// HasJudgeChecks reports whether any case needs the judge model.
+func (s *Suite) HasJudgeChecks() bool {
+	for _, testCase := range s.Cases {
+		for _, check := range testCase.Checks {
+			if check.Judge != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c *Check) Validate() error {
+	set := 0
+	for _, field := range []string{c.Regex, c.NotRegex, c.Judge} {
+		if field != "" {
+			set++
+		}
+	}
+	if set != 1 {
+		return errors.New("check must set exactly one of regex, not_regex, and judge")
+	}
+
+	for _, pattern := range []string{c.Regex, c.NotRegex} {
+		if pattern == "" {
+			continue
+		}
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid check pattern: %w", err)
+		}
+	}
+	return nil
+}
+
+// String describes the check.
+func (c *Check) String() string {
+	switch {
+	case c.Regex != "":
+		return fmt.Sprintf("matches /%s/", c.Regex)
+	case c.NotRegex != "":
+		return fmt.Sprintf("does not match /%s/", c.NotRegex)
+	default:
+		return fmt.Sprintf("judge: %s", c.Judge)
+	}
+}
+
+// Evaluate reports whether the reply to the prompt passes the check and the reason if it does not.
+// The judge is only used for judge checks.
+func (c *Check) Evaluate(prompt string, reply string, judge genai.GenerativeAI) (bool, string, error) {
+	switch {
+	case c.Regex != "":
+		if regexp.MustCompile(c.Regex).MatchString(reply) {
+			return true, "", nil
+		}
+		return false, "reply does not match the pattern", nil
+	case c.NotRegex != "":
+		if !regexp.MustCompile(c.NotRegex).MatchString(reply) {
+			return true, "", nil
+		}
+		return false, "reply matches the pattern", nil
+	default:
+		return evaluateJudge(c.Judge, prompt, reply, judge)
+	}
+}
+
+// evaluateJudge asks the judge model whether the reply meets the criterion.
+func evaluateJudge(criterion string, prompt string, reply string, judge genai.GenerativeAI) (bool, string, error) {
+	if judge == nil {
+		return false, "", errors.New("no judge model is available")
+	}
+
+	verdict, _, err := judge.Chat([]genai.Message{
+		{Role: "system", Content: judgeSystemPrompt},
+		{Role: "user", Content: fmt.Sprintf("Criterion: %s\n\nPrompt: %s\n\nReply: %s", criterion, prompt, reply)},
+	})
+	if err != nil {
+		return false, "", fmt.Errorf("failed to get verdict from judge model: %w", err)
+	}
+
+	firstLine, reason, _ := strings.Cut(strings.TrimSpace(verdict), "\n")
+	switch {
+	case strings.HasPrefix(strings.ToUpper(strings.TrimSpace(firstLine)), "PASS"):
+		return true, "", nil
+	case strings.HasPrefix(strings.ToUpper(strings.TrimSpace(firstLine)), "FAIL"):
+		return false, strings.TrimSpace(reason), nil
+	default:
+		return false, "", fmt.Errorf("unexpected verdict from judge model: %q", firstLine)
+	}
+}
@@ -0,0 +1,115 @@
+package prompttest //nolint:testpackage // Unit tests are in the same package
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/k4yt3x/tellama/internal/genai"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeJudge returns a fixed verdict.
+type fakeJudge struct {
+	verdict string
+	err     error
+}
+
+func (f *fakeJudge) Chat(_ []genai.Message) (string, genai.GenerateStats, error) {
+	return f.verdict, genai.GenerateStats{}, f.err
+}
+
+func (f *fakeJudge) Complete(_ string) (string, genai.GenerateStats, error) {
+	return f.verdict, genai.GenerateStats{}, f.err
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("Valid suite", func(t *testing.T) {
+		// Arrange
+		content := `
+judge_model: gpt-4o-mini
+cases:
+  - name: greeting
+    prompt: Say hello
+    checks:
+      - regex: "(?i)hello"
+      - judge: The reply is friendly
+`
+		path := filepath.Join(t.TempDir(), "prompts.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+		// Act
+		suite, err := Load(path)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, "gpt-4o-mini", suite.JudgeModel)
+		require.Len(t, suite.Cases, 1)
+		assert.Len(t, suite.Cases[0].Checks, 2)
+		assert.True(t, suite.HasJudgeChecks())
+	})
+
+	t.Run("Invalid pattern", func(t *testing.T) {
+		// Arrange
+		content := `
+cases:
+  - name: broken
+    prompt: Say hello
+    checks:
+      - regex: "("
+`
+		path := filepath.Join(t.TempDir(), "prompts.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+		// Act
+		_, err := Load(path)
+
+		// Assert
+		assert.ErrorContains(t, err, "invalid check pattern")
+	})
+}
+
+func TestCheckValidate(t *testing.T) {
+	assert.NoError(t, (&Check{Regex: "a"}).Validate())
+	assert.Error(t, (&Check{}).Validate())
+	assert.Error(t, (&Check{Regex: "a", Judge: "b"}).Validate())
+}
+
+func TestCheckEvaluate(t *testing.T) {
+	t.Run("Regex checks", func(t *testing.T) {
+		// Act
+		passed, _, err := (&Check{Regex: "(?i)hello"}).Evaluate("", "Hello there", nil)
+		require.NoError(t, err)
+		notPassed, reason, err := (&Check{NotRegex: "(?i)hello"}).Evaluate("", "Hello there", nil)
+
+		// Assert
+		require.NoError(t, err)
+		assert.True(t, passed)
+		assert.False(t, notPassed)
+		assert.Equal(t, "reply matches the pattern", reason)
+	})
+
+	t.Run("Judge checks", func(t *testing.T) {
+		// Arrange
+		check := &Check{Judge: "The reply is friendly"}
+
+		// Act
+		passed, _, err := check.Evaluate("Say hello", "Hello!", &fakeJudge{verdict: "PASS\nIt greets the user."})
+		require.NoError(t, err)
+		failed, reason, err := check.Evaluate("Say hello", "Go away.", &fakeJudge{verdict: "FAIL\nIt is rude."})
+		require.NoError(t, err)
+
+		// Assert
+		assert.True(t, passed)
+		assert.False(t, failed)
+		assert.Equal(t, "It is rude.", reason)
+
+		_, _, err = check.Evaluate("Say hello", "Hello!", &fakeJudge{verdict: "Maybe"})
+		require.Error(t, err)
+		_, _, err = check.Evaluate("Say hello", "Hello!", &fakeJudge{err: errors.New("unavailable")})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,204 @@
+// Package rag implements the document processing and retrieval used to
+// answer questions from a chat knowledge base.
+package rag
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ledongthuc/pdf"
+)
+
+type Config struct {
+	Enabled         bool
+	EmbeddingModel  string
+	ChunkSize       int
+	ChunkOverlap    int
+	TopK            int
+	MinScore        float64
+	MaxDocumentSize int64
+}
+
+func (c *Config) Validate() error {
+	if c.EmbeddingModel == "" {
+		return errors.New("embedding model cannot be empty")
+	}
+	if c.ChunkSize < 1 {
+		return errors.New("chunk size must be at least 1")
+	}
+	if c.ChunkOverlap < 0 || c.ChunkOverlap >= c.ChunkSize {
+		return errors.New("chunk overlap must be between 0 and the chunk size")
+	}
+	if c.TopK < 1 {
+		return errors.New("top k must be at least 1")
+	}
+	return nil
+}
+
+// ExtractText returns the plain text content of a text or PDF document.
+func ExtractText(fileName string, data []byte) (string, error) {
+	if strings.EqualFold(filepath.Ext(fileName), ".pdf") || http.DetectContentType(data) == "application/pdf" {
+		return extractPDFText(data)
+	}
+
+	if !utf8.Valid(data) {
+		return "", errors.New("unsupported document type, only text and PDF documents are supported")
+	}
+	return string(data), nil
+}
+
+// extractPDFText returns the text of all pages of a PDF document.
+func extractPDFText(data []byte) (string, error) {
+	reader, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("failed to open PDF document: %w", err)
+	}
+
+	textReader, err := reader.GetPlainText()
+	if err != nil {
+		return "", fmt.Errorf("failed to extract PDF text: %w", err)
+	}
+
+	text, err := io.ReadAll(textReader)
+	if err != nil {
+		return "", fmt.Errorf("failed to read PDF text: %w", err)
+	}
+	return string(text), nil
+}
+
+// SplitText splits text into chunks of at most size runes that overlap by overlap runes.
+// Chunks end at whitespace where possible so words are not cut in half.
+func SplitText(text string, size int, overlap int) []string {
+	runes := []rune(strings.TrimSpace(text))
+	var chunks []string
+
+	for start := 0; start < len(runes); {
+		end := min(start+size, len(runes))
+
+		// Move the end back to the last whitespace within the chunk
+		if end < len(runes) {
+			for i := end; i > start+size/2; i-- {
+				if unicode.IsSpace(runes[i]) {
+					end = i
+					break
+				}
+			}
+		}
+
+		if chunk := strings.TrimSpace(string(runes[start:end])); chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+		if end == len(runes) {
+			break
+		}
+		start = max(end-overlap, start+1)
+	}
+
+	return chunks
+}
+
+// SelectChunks returns the chunks in the given order of priority that fit within maxLength runes in total,
+// in their original order so that the excerpt reads like the document.
+func SelectChunks(chunks []string, priority []int, maxLength int) []string {
+	var selected []int
+	length := 0
+	for _, index := range priority {
+		chunkLength := utf8.RuneCountInString(chunks[index])
+		if length+chunkLength > maxLength {
+			continue
+		}
+		selected = append(selected, index)
+		length += chunkLength
+	}
+	slices.Sort(selected)
+
+	excerpt := make([]string, len(selected))
+	for i, index := range selected {
+		excerpt[i] = chunks[index]
+	}
+	return excerpt
+}
+
+// EncodeEmbedding serializes an embedding for storage.
+func EncodeEmbedding(embedding []float32) []byte {
+	data := make([]byte, 4*len(embedding))
+	for i, value := range embedding {
+		binary.LittleEndian.PutUint32(data[4*i:], math.Float32bits(value))
+	}
+	return data
+}
+
+// DecodeEmbedding deserializes an embedding encoded by EncodeEmbedding.
+func DecodeEmbedding(data []byte) ([]float32, error) {
+	if len(data)%4 != 0 {
+		return nil, errors.New("invalid embedding length")
+	}
+	embedding := make([]float32, len(data)/4)
+	for i := range embedding {
+		embedding[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[4*i:]))
+	}
+	return embedding, nil
+}
+
+// CosineSimilarity returns the cosine similarity of two embeddings.
+// Embeddings of different dimensions have a similarity of zero.
+func CosineSimilarity(a []float32, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// Match is a candidate embedding that is similar to the query.
+type Match struct {
+	Index int
+	Score float64
+}
+
+// TopK returns the k candidates most similar to the query with a score of at least minScore,
+// ordered from the most to the least similar.
+func TopK(query []float32, candidates [][]float32, k int, minScore float64) []Match {
+	var matches []Match
+	for i, candidate := range candidates {
+		score := CosineSimilarity(query, candidate)
+		if score >= minScore {
+			matches = append(matches, Match{Index: i, Score: score})
+		}
+	}
+
+	slices.SortStableFunc(matches, func(a, b Match) int {
+		switch {
+		case a.Score > b.Score:
+			return -1
+		case a.Score < b.Score:
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	if len(matches) > k {
+		matches = matches[:k]
+	}
+	return matches
+}
@@ -0,0 +1,156 @@
+package rag //nolint:testpackage // Unit tests are in the same package
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitText(t *testing.T) {
+	t.Run("Short text", func(t *testing.T) {
+		// Act
+		chunks := SplitText("  hello world  ", 100, 10)
+
+		// Assert
+		assert.Equal(t, []string{"hello world"}, chunks)
+	})
+
+	t.Run("Empty text", func(t *testing.T) {
+		// Act
+		chunks := SplitText("   ", 100, 10)
+
+		// Assert
+		assert.Empty(t, chunks)
+	})
+
+	t.Run("Chunks break at whitespace and overlap", func(t *testing.T) {
+		// Arrange
+		text := "alpha beta gamma delta epsilon zeta eta theta"
+
+		// Act
+		chunks := SplitText(text, 20, 6)
+
+		// Assert
+		require.Greater(t, len(chunks), 1)
+		for _, chunk := range chunks {
+			assert.LessOrEqual(t, len([]rune(chunk)), 20)
+			assert.Contains(t, text, chunk)
+		}
+		assert.True(t, strings.HasPrefix(chunks[0], "alpha beta gamma"))
+		assert.True(t, strings.HasSuffix(chunks[len(chunks)-1], "theta"))
+	})
+}
+
+func TestSelectChunks(t *testing.T) {
+	chunks := []string{"first", "second", "third", "fourth"}
+
+	tests := []struct {
+		name      string
+		priority  []int
+		maxLength int
+		expected  []string
+	}{
+		{name: "All chunks fit", priority: []int{0, 1, 2, 3}, maxLength: 100, expected: chunks},
+		{name: "Document order is kept", priority: []int{2, 0}, maxLength: 10, expected: []string{"first", "third"}},
+		{
+			name:      "Chunks that do not fit are skipped",
+			priority:  []int{1, 3, 0},
+			maxLength: 11,
+			expected:  []string{"first", "second"},
+		},
+		{name: "Nothing fits", priority: []int{1}, maxLength: 3, expected: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			excerpt := SelectChunks(chunks, tt.priority, tt.maxLength)
+
+			// Assert
+			assert.Equal(t, tt.expected, excerpt)
+		})
+	}
+}
+
+func TestEmbeddingEncoding(t *testing.T) {
+	// Arrange
+	embedding := []float32{0.5, -1.25, 3}
+
+	// Act
+	decoded, err := DecodeEmbedding(EncodeEmbedding(embedding))
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, embedding, decoded)
+
+	_, err = DecodeEmbedding([]byte{1, 2, 3})
+	assert.Error(t, err)
+}
+
+func TestCosineSimilarity(t *testing.T) {
+	assert.InDelta(t, 1.0, CosineSimilarity([]float32{1, 2}, []float32{2, 4}), 1e-9)
+	assert.InDelta(t, 0.0, CosineSimilarity([]float32{1, 0}, []float32{0, 1}), 1e-9)
+	assert.InDelta(t, -1.0, CosineSimilarity([]float32{1, 0}, []float32{-1, 0}), 1e-9)
+	assert.Zero(t, CosineSimilarity([]float32{1, 0}, []float32{1, 0, 0}))
+	assert.Zero(t, CosineSimilarity([]float32{0, 0}, []float32{1, 0}))
+}
+
+func TestTopK(t *testing.T) {
+	// Arrange
+	query := []float32{1, 0}
+	candidates := [][]float32{
+		{0, 1},
+		{1, 0.1},
+		{1, 1},
+		{-1, 0},
+	}
+
+	// Act
+	matches := TopK(query, candidates, 2, 0.1)
+
+	// Assert
+	require.Len(t, matches, 2)
+	assert.Equal(t, 1, matches[0].Index)
+	assert.Equal(t, 2, matches[1].Index)
+	assert.Greater(t, matches[0].Score, matches[1].Score)
+}
+
+func TestExtractText(t *testing.T) {
+	t.Run("Text document", func(t *testing.T) {
+		// Act
+		text, err := ExtractText("notes.md", []byte("# Notes\nSome text"))
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, "# Notes\nSome text", text)
+	})
+
+	t.Run("Binary document", func(t *testing.T) {
+		// Act
+		_, err := ExtractText("image.bin", []byte{0xff, 0xfe, 0x00, 0x81})
+
+		// Assert
+		assert.Error(t, err)
+	})
+
+	t.Run("Invalid PDF document", func(t *testing.T) {
+		// Act
+		_, err := ExtractText("broken.pdf", []byte("not a pdf"))
+
+		// Assert
+		assert.Error(t, err)
+	})
+}
+
+func TestConfigValidate(t *testing.T) {
+	// Arrange
+	cfg := Config{EmbeddingModel: "nomic-embed-text", ChunkSize: 100, ChunkOverlap: 10, TopK: 3}
+
+	// Assert
+	require.NoError(t, cfg.Validate())
+
+	cfg.ChunkOverlap = 100
+	assert.Error(t, cfg.Validate())
+}
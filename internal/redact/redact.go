@@ -0,0 +1,117 @@
+// Package redact removes personal information such as email addresses and phone numbers from texts.
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+//nolint:gochecknoglobals // Compiled once
+var (
+	// creditCardPattern matches 13 to 19 digits, optionally grouped by spaces or dashes.
+	creditCardPattern = regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)
+
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+
+	// phoneNumberPattern matches international numbers and numbers of three digit groups, such as (555) 123-4567.
+	phoneNumberPattern = regexp.MustCompile(
+		`(?:\+\d{1,3}[ .-]?)?(?:\(\d{2,4}\)|\b\d{2,4})[ .-]?\d{3,4}[ .-]?\d{3,4}\b`,
+	)
+)
+
+const (
+	CreditCardPlaceholder  = "[CARD]"
+	EmailPlaceholder       = "[EMAIL]"
+	PhoneNumberPlaceholder = "[PHONE]"
+	PatternPlaceholder     = "[REDACTED]"
+)
+
+type Config struct {
+	Enabled      bool
+	CreditCards  bool
+	Emails       bool
+	PhoneNumbers bool
+
+	// Patterns are additional regular expressions whose matches are replaced with PatternPlaceholder
+	Patterns []string
+}
+
+func (c *Config) Validate() error {
+	for _, pattern := range c.Patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// Redactor replaces personal information in texts with placeholders.
+type Redactor struct {
+	creditCards  bool
+	emails       bool
+	phoneNumbers bool
+	patterns     []*regexp.Regexp
+}
+
+func New(config *Config) (*Redactor, error) {
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid redaction config: %w", err)
+	}
+
+	redactor := &Redactor{
+		creditCards:  config.CreditCards,
+		emails:       config.Emails,
+		phoneNumbers: config.PhoneNumbers,
+	}
+	for _, pattern := range config.Patterns {
+		redactor.patterns = append(redactor.patterns, regexp.MustCompile(pattern))
+	}
+	return redactor, nil
+}
+
+// Redact returns the text with the enabled kinds of personal information replaced.
+// Card numbers are replaced before phone numbers so that parts of them are not taken for phone numbers.
+func (r *Redactor) Redact(text string) string {
+	if r.creditCards {
+		text = creditCardPattern.ReplaceAllStringFunc(text, func(match string) string {
+			if luhnValid(match) {
+				return CreditCardPlaceholder
+			}
+			return match
+		})
+	}
+	if r.emails {
+		text = emailPattern.ReplaceAllString(text, EmailPlaceholder)
+	}
+	if r.phoneNumbers {
+		text = phoneNumberPattern.ReplaceAllString(text, PhoneNumberPlaceholder)
+	}
+	for _, pattern := range r.patterns {
+		text = pattern.ReplaceAllString(text, PatternPlaceholder)
+	}
+	return text
+}
+
+// luhnValid reports whether the digits of a number pass the Luhn checksum used by payment cards.
+func luhnValid(number string) bool {
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, number)
+
+	sum := 0
+	for i := range len(digits) {
+		digit := int(digits[len(digits)-1-i] - '0')
+		if i%2 == 1 {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+	}
+	return sum%10 == 0
+}
@@ -0,0 +1,64 @@
+package redact //nolint:testpackage // Unit tests are in the same package
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedact(t *testing.T) {
+	redactor, err := New(&Config{
+		Enabled:      true,
+		CreditCards:  true,
+		Emails:       true,
+		PhoneNumbers: true,
+		Patterns:     []string{`\bEMP-\d{6}\b`},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "Email", input: "Mail me at jane.doe+bot@example.co.uk.", expected: "Mail me at [EMAIL]."},
+		{name: "Phone number", input: "Call (555) 123-4567 now", expected: "Call [PHONE] now"},
+		{name: "International phone number", input: "Call +44 20 7946 0958", expected: "Call [PHONE]"},
+		{name: "Credit card", input: "Card 4111 1111 1111 1111 exp 12/30", expected: "Card [CARD] exp 12/30"},
+		{name: "Invalid card number", input: "Order 1234567890123", expected: "Order 1234567890123"},
+		{name: "Custom pattern", input: "Employee EMP-123456", expected: "Employee [REDACTED]"},
+		{name: "Dates and times", input: "Meet on 2024-05-01 at 10:30", expected: "Meet on 2024-05-01 at 10:30"},
+		{name: "Short numbers", input: "I have 42 llamas and 1000 alpacas", expected: "I have 42 llamas and 1000 alpacas"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			redacted := redactor.Redact(tt.input)
+
+			// Assert
+			assert.Equal(t, tt.expected, redacted)
+		})
+	}
+}
+
+func TestRedact_SelectedKinds(t *testing.T) {
+	// Arrange
+	redactor, err := New(&Config{Enabled: true, Emails: true})
+	require.NoError(t, err)
+
+	// Act
+	redacted := redactor.Redact("jane@example.com, 555-123-4567")
+
+	// Assert
+	assert.Equal(t, "[EMAIL], 555-123-4567", redacted)
+}
+
+func TestNew_InvalidPattern(t *testing.T) {
+	// Act
+	_, err := New(&Config{Patterns: []string{"("}})
+
+	// Assert
+	assert.Error(t, err)
+}
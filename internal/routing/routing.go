@@ -0,0 +1,84 @@
+// Package routing selects a model for each message based on its length and complexity.
+package routing
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+)
+
+// Complexity is the estimated complexity of a message.
+type Complexity int
+
+const (
+	// ComplexityMedium messages use the default model.
+	ComplexityMedium Complexity = iota
+	// ComplexitySimple messages use the small model.
+	ComplexitySimple
+	// ComplexityComplex messages use the large model.
+	ComplexityComplex
+)
+
+func (c Complexity) String() string {
+	return [...]string{"medium", "simple", "complex"}[c]
+}
+
+type Config struct {
+	Enabled          bool
+	SmallModel       string
+	LargeModel       string
+	SimpleMaxLength  int
+	ComplexMinLength int
+	ComplexMinLines  int
+}
+
+func (c *Config) Validate() error {
+	if c.SmallModel == "" && c.LargeModel == "" {
+		return errors.New("at least one of the small and large models must be set")
+	}
+	if c.SimpleMaxLength < 0 || c.ComplexMinLength < 0 || c.ComplexMinLines < 0 {
+		return errors.New("routing thresholds cannot be negative")
+	}
+	if c.ComplexMinLength != 0 && c.SimpleMaxLength >= c.ComplexMinLength {
+		return errors.New("simple max length must be less than complex min length")
+	}
+	return nil
+}
+
+// Classify estimates the complexity of a message.
+// Messages containing code, many lines, or many characters are complex,
+// while short single-line messages are simple. A threshold of zero disables its rule.
+func (c *Config) Classify(text string) Complexity {
+	text = strings.TrimSpace(text)
+	length := utf8.RuneCountInString(text)
+
+	switch {
+	case strings.Contains(text, "```"):
+		return ComplexityComplex
+	case c.ComplexMinLength > 0 && length >= c.ComplexMinLength:
+		return ComplexityComplex
+	case c.ComplexMinLines > 0 && strings.Count(text, "\n")+1 >= c.ComplexMinLines:
+		return ComplexityComplex
+	case c.SimpleMaxLength > 0 && length <= c.SimpleMaxLength && !strings.Contains(text, "\n"):
+		return ComplexitySimple
+	default:
+		return ComplexityMedium
+	}
+}
+
+// Model returns the model a message should be routed to.
+// An empty string is returned if the message should use the default model.
+func (c *Config) Model(text string) string {
+	if !c.Enabled {
+		return ""
+	}
+
+	switch c.Classify(text) {
+	case ComplexitySimple:
+		return c.SmallModel
+	case ComplexityComplex:
+		return c.LargeModel
+	default:
+		return ""
+	}
+}
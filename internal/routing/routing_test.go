@@ -0,0 +1,60 @@
+package routing //nolint:testpackage // Unit tests are in the same package
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassify(t *testing.T) {
+	// Arrange
+	cfg := Config{SimpleMaxLength: 20, ComplexMinLength: 100, ComplexMinLines: 4}
+
+	// Assert
+	assert.Equal(t, ComplexitySimple, cfg.Classify("  What time is it?  "))
+	assert.Equal(t, ComplexityMedium, cfg.Classify("Can you tell me a bit about the history of Rome?"))
+	assert.Equal(t, ComplexityMedium, cfg.Classify("Hi\nthere"))
+	assert.Equal(t, ComplexityComplex, cfg.Classify(strings.Repeat("word ", 30)))
+	assert.Equal(t, ComplexityComplex, cfg.Classify("one\ntwo\nthree\nfour"))
+	assert.Equal(t, ComplexityComplex, cfg.Classify("Fix ```x++```"))
+}
+
+func TestModel(t *testing.T) {
+	t.Run("Routes by complexity", func(t *testing.T) {
+		// Arrange
+		cfg := Config{
+			Enabled:          true,
+			SmallModel:       "small",
+			LargeModel:       "large",
+			SimpleMaxLength:  20,
+			ComplexMinLength: 100,
+		}
+
+		// Assert
+		assert.Equal(t, "small", cfg.Model("Hello!"))
+		assert.Equal(t, "large", cfg.Model(strings.Repeat("word ", 30)))
+		assert.Empty(t, cfg.Model("Can you tell me a bit about the history of Rome?"))
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		// Arrange
+		cfg := Config{SmallModel: "small", LargeModel: "large", SimpleMaxLength: 20}
+
+		// Assert
+		assert.Empty(t, cfg.Model("Hello!"))
+	})
+}
+
+func TestConfigValidate(t *testing.T) {
+	// Arrange
+	cfg := Config{SmallModel: "small", SimpleMaxLength: 100, ComplexMinLength: 1000}
+
+	// Assert
+	assert.NoError(t, cfg.Validate())
+
+	cfg.SimpleMaxLength = 1000
+	assert.Error(t, cfg.Validate())
+
+	assert.Error(t, (&Config{}).Validate())
+}
@@ -0,0 +1,184 @@
+// Package schedule parses cron expressions and computes the times they match.
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxSearchYears bounds the search for the next matching time of expressions that rarely match.
+const maxSearchYears = 5
+
+// shortcuts maps the predefined schedules to their cron expressions.
+var shortcuts = map[string]string{ //nolint:gochecknoglobals // Read-only lookup table
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+	"@yearly":  "0 0 1 1 *",
+}
+
+// field describes the range of values of a cron field.
+type field struct {
+	name     string
+	min, max int
+}
+
+// fields are the fields of a cron expression in order.
+var fields = [...]field{ //nolint:gochecknoglobals // Read-only field ranges
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// Spec is a parsed cron expression with the standard five fields:
+// minute, hour, day of month, month, and day of week.
+type Spec struct {
+	expression string
+	// sets holds a bit for every value each field matches
+	sets [len(fields)]uint64
+	// anyDayOfMonth and anyDayOfWeek report whether the day fields are unrestricted
+	anyDayOfMonth bool
+	anyDayOfWeek  bool
+}
+
+// Parse parses a cron expression such as "0 8 * * 1-5" or a shortcut such as "@daily".
+// Fields support lists, ranges, and steps, and Sunday is either 0 or 7 in the day of week field.
+func Parse(expression string) (Spec, error) {
+	expression = strings.Join(strings.Fields(expression), " ")
+	spec := Spec{expression: expression}
+	if shortcut, ok := shortcuts[expression]; ok {
+		expression = shortcut
+	}
+
+	parts := strings.Fields(expression)
+	if len(parts) != len(fields) {
+		return Spec{}, fmt.Errorf("cron expression must have %d fields, got %d", len(fields), len(parts))
+	}
+	for i, part := range parts {
+		set, err := parseField(part, fields[i])
+		if err != nil {
+			return Spec{}, err
+		}
+		spec.sets[i] = set
+	}
+
+	// Sunday can be written as 0 or 7
+	if spec.sets[4]&(1<<7) != 0 {
+		spec.sets[4] |= 1
+	}
+	spec.anyDayOfMonth = parts[2] == "*"
+	spec.anyDayOfWeek = parts[4] == "*"
+	return spec, nil
+}
+
+// parseField parses a comma-separated list of values, ranges, and steps.
+func parseField(part string, f field) (uint64, error) {
+	var set uint64
+	for _, item := range strings.Split(part, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepPart)
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q in %s field", stepPart, f.name)
+			}
+		}
+
+		start, end := f.min, f.max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			startPart, endPart, _ := strings.Cut(rangePart, "-")
+			var err error
+			if start, err = parseValue(startPart, f); err != nil {
+				return 0, err
+			}
+			if end, err = parseValue(endPart, f); err != nil {
+				return 0, err
+			}
+			if start > end {
+				return 0, fmt.Errorf("invalid range %q in %s field", rangePart, f.name)
+			}
+		default:
+			value, err := parseValue(rangePart, f)
+			if err != nil {
+				return 0, err
+			}
+			start = value
+			if !hasStep {
+				end = value
+			}
+		}
+
+		for value := start; value <= end; value += step {
+			set |= 1 << value
+		}
+	}
+	return set, nil
+}
+
+// parseValue parses a single value of a field and checks its range.
+func parseValue(value string, f field) (int, error) {
+	number, err := strconv.Atoi(value)
+	if err != nil || number < f.min || number > f.max {
+		return 0, fmt.Errorf("invalid value %q in %s field, must be %d-%d", value, f.name, f.min, f.max)
+	}
+	return number, nil
+}
+
+// String returns the expression the spec was parsed from.
+func (s Spec) String() string {
+	return s.expression
+}
+
+// Next returns the first time after the given time that matches the spec, in the location of the given time.
+// An error is returned if no time matches within the next few years, such as for February 30.
+func (s Spec) Next(after time.Time) (time.Time, error) {
+	next := after.Truncate(time.Minute).Add(time.Minute)
+	limit := after.AddDate(maxSearchYears, 0, 0)
+
+	for next.Before(limit) {
+		switch {
+		case !s.matches(3, int(next.Month())):
+			next = time.Date(next.Year(), next.Month()+1, 1, 0, 0, 0, 0, next.Location())
+		case !s.matchesDay(next):
+			next = time.Date(next.Year(), next.Month(), next.Day()+1, 0, 0, 0, 0, next.Location())
+		case !s.matches(1, next.Hour()):
+			next = time.Date(next.Year(), next.Month(), next.Day(), next.Hour()+1, 0, 0, 0, next.Location())
+		case !s.matches(0, next.Minute()):
+			next = next.Add(time.Minute)
+		default:
+			return next, nil
+		}
+	}
+	return time.Time{}, errors.New("cron expression does not match any time")
+}
+
+// matches reports whether the field matches the value.
+func (s Spec) matches(field int, value int) bool {
+	return s.sets[field]&(1<<value) != 0
+}
+
+// matchesDay reports whether the day matches the day of month and day of week fields.
+// Like in cron, a day matches either field if both are restricted.
+func (s Spec) matchesDay(t time.Time) bool {
+	dayOfMonth := s.matches(2, t.Day())
+	dayOfWeek := s.matches(4, int(t.Weekday()))
+	switch {
+	case s.anyDayOfMonth && s.anyDayOfWeek:
+		return true
+	case s.anyDayOfMonth:
+		return dayOfWeek
+	case s.anyDayOfWeek:
+		return dayOfMonth
+	default:
+		return dayOfMonth || dayOfWeek
+	}
+}
@@ -0,0 +1,78 @@
+package schedule //nolint:testpackage // Unit tests are in the same package
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	t.Run("Valid expressions", func(t *testing.T) {
+		for _, expression := range []string{"* * * * *", "0 8 * * 1-5", "*/15 9-17 * * *", "0 0 1,15 * *", "@daily"} {
+			// Act
+			spec, err := Parse(expression)
+
+			// Assert
+			require.NoError(t, err, expression)
+			assert.Equal(t, expression, spec.String())
+		}
+	})
+
+	t.Run("Invalid expressions", func(t *testing.T) {
+		for _, expression := range []string{"", "* * * *", "60 * * * *", "* 5-1 * * *", "*/0 * * * *", "a * * * *"} {
+			// Act
+			_, err := Parse(expression)
+
+			// Assert
+			assert.Error(t, err, expression)
+		}
+	})
+}
+
+func TestNext(t *testing.T) {
+	// Arrange
+	// 2025-03-14 is a Friday
+	after := time.Date(2025, time.March, 14, 10, 30, 15, 0, time.UTC)
+	tests := []struct {
+		expression string
+		expected   time.Time
+	}{
+		{"* * * * *", time.Date(2025, time.March, 14, 10, 31, 0, 0, time.UTC)},
+		{"0 8 * * *", time.Date(2025, time.March, 15, 8, 0, 0, 0, time.UTC)},
+		{"0 8 * * 1-5", time.Date(2025, time.March, 17, 8, 0, 0, 0, time.UTC)},
+		{"*/20 * * * *", time.Date(2025, time.March, 14, 10, 40, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2025, time.April, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 12 * * 7", time.Date(2025, time.March, 16, 12, 0, 0, 0, time.UTC)},
+		{"0 0 13 * 5", time.Date(2025, time.March, 21, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			// Arrange
+			spec, err := Parse(test.expression)
+			require.NoError(t, err)
+
+			// Act
+			next, err := spec.Next(after)
+
+			// Assert
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, next)
+		})
+	}
+
+	t.Run("Impossible date", func(t *testing.T) {
+		// Arrange
+		spec, err := Parse("0 0 30 2 *")
+		require.NoError(t, err)
+
+		// Act
+		_, err = spec.Next(after)
+
+		// Assert
+		assert.Error(t, err)
+	})
+}
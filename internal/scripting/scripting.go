@@ -0,0 +1,355 @@
+// Package scripting runs Starlark scripts on incoming messages, the conversations sent to the model,
+// and responses, so that operators can change the behavior of the bot without rebuilding it.
+package scripting
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/k4yt3x/tellama/internal/database"
+
+	"github.com/rs/zerolog/log"
+	"go.starlark.net/lib/json"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+	"go.starlark.net/syntax"
+	"gopkg.in/telebot.v4"
+)
+
+// Functions that scripts define to hook into the bot. Scripts may define any of them.
+const (
+	// FilterMessageFunction is called as filter_message(event, text) with the text of each incoming message.
+	// It returns the text to handle instead, False to ignore the message, or None to leave it unchanged.
+	FilterMessageFunction = "filter_message"
+
+	// BeforeGenerationFunction is called as before_generation(event, messages) with the conversation sent
+	// to the model as a list of dicts with the role and content of each message. It returns the list of
+	// messages to send instead, or None to leave the conversation unchanged.
+	BeforeGenerationFunction = "before_generation"
+
+	// AfterGenerationFunction is called as after_generation(event, response) with each generated response.
+	// It returns the response to send instead, an empty string to send nothing, or None to leave it unchanged.
+	AfterGenerationFunction = "after_generation"
+)
+
+// Extension is the file extension of the scripts loaded from the script directory.
+const Extension = ".star"
+
+type Config struct {
+	Enabled   bool
+	Directory string
+
+	// Timeout limits the time a single call of a script function may run
+	Timeout time.Duration
+}
+
+func (c *Config) Validate() error {
+	if c.Directory == "" {
+		return errors.New("directory cannot be empty")
+	}
+	if c.Timeout <= 0 {
+		return errors.New("timeout must be positive")
+	}
+	return nil
+}
+
+// script is a loaded script and the hook functions it defines.
+type script struct {
+	name             string
+	filterMessage    starlark.Callable
+	beforeGeneration starlark.Callable
+	afterGeneration  starlark.Callable
+}
+
+// Plugin runs the hook functions of the scripts in the order of their file names.
+// It implements plugin.MessageFilter, plugin.PreGenerationHook, and plugin.PostGenerationHook.
+type Plugin struct {
+	scripts []script
+	timeout time.Duration
+}
+
+// New loads the scripts in the script directory.
+func New(config *Config) (*Plugin, error) {
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid scripting config: %w", err)
+	}
+
+	if _, err := os.Stat(config.Directory); err != nil {
+		return nil, fmt.Errorf("failed to read script directory: %w", err)
+	}
+	paths, err := filepath.Glob(filepath.Join(config.Directory, "*"+Extension))
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(paths)
+
+	plugin := &Plugin{timeout: config.Timeout}
+	for _, path := range paths {
+		var loaded script
+		loaded, err = plugin.load(path)
+		if err != nil {
+			return nil, err
+		}
+		plugin.scripts = append(plugin.scripts, loaded)
+	}
+
+	log.Info().Str("directory", config.Directory).Strs("scripts", plugin.Scripts()).Msg("Loaded scripts")
+	return plugin, nil
+}
+
+// load runs a script and looks up the hook functions it defines.
+func (p *Plugin) load(path string) (script, error) {
+	name := filepath.Base(path)
+	predeclared := starlark.StringDict{
+		"json":   json.Module,
+		"struct": starlark.NewBuiltin("struct", starlarkstruct.Make),
+	}
+
+	thread, stop := p.newThread(name)
+	globals, err := starlark.ExecFileOptions(&syntax.FileOptions{}, thread, path, nil, predeclared)
+	stop()
+	if err != nil {
+		return script{}, fmt.Errorf("failed to load script %s: %w", name, scriptError(err))
+	}
+
+	loaded := script{name: name}
+	hooks := map[string]*starlark.Callable{
+		FilterMessageFunction:    &loaded.filterMessage,
+		BeforeGenerationFunction: &loaded.beforeGeneration,
+		AfterGenerationFunction:  &loaded.afterGeneration,
+	}
+	for function, hook := range hooks {
+		value, exists := globals[function]
+		if !exists {
+			continue
+		}
+		callable, ok := value.(starlark.Callable)
+		if !ok {
+			return script{}, fmt.Errorf("%s of script %s is a %s, not a function", function, name, value.Type())
+		}
+		*hook = callable
+	}
+	if loaded.filterMessage == nil && loaded.beforeGeneration == nil && loaded.afterGeneration == nil {
+		log.Warn().Str("script", name).Msg("Script defines no hook functions")
+	}
+
+	// Freezing the globals lets the hook functions run concurrently
+	globals.Freeze()
+	return loaded, nil
+}
+
+// Name implements plugin.Plugin.
+func (p *Plugin) Name() string {
+	return "scripts"
+}
+
+// Scripts returns the file names of the loaded scripts.
+func (p *Plugin) Scripts() []string {
+	names := make([]string, len(p.scripts))
+	for i, loaded := range p.scripts {
+		names[i] = loaded.name
+	}
+	return names
+}
+
+// FilterMessage implements plugin.MessageFilter.
+func (p *Plugin) FilterMessage(ctx telebot.Context, text string) (string, bool, error) {
+	for _, loaded := range p.scripts {
+		if loaded.filterMessage == nil {
+			continue
+		}
+
+		result, err := p.call(loaded, loaded.filterMessage, newEvent(ctx), starlark.String(text))
+		if err != nil {
+			return "", false, err
+		}
+		switch value := result.(type) {
+		case starlark.NoneType:
+		case starlark.String:
+			text = string(value)
+		case starlark.Bool:
+			if !value {
+				return "", false, nil
+			}
+		default:
+			return "", false, returnTypeError(loaded, FilterMessageFunction, result, "a string, a bool, or None")
+		}
+	}
+	return text, true, nil
+}
+
+// BeforeGeneration implements plugin.PreGenerationHook.
+func (p *Plugin) BeforeGeneration(ctx telebot.Context, messages []database.Message) ([]database.Message, error) {
+	for _, loaded := range p.scripts {
+		if loaded.beforeGeneration == nil {
+			continue
+		}
+
+		list, originals := messageList(messages)
+		result, err := p.call(loaded, loaded.beforeGeneration, newEvent(ctx), list)
+		if err != nil {
+			return nil, err
+		}
+		if result == starlark.None {
+			continue
+		}
+		resultList, ok := result.(*starlark.List)
+		if !ok {
+			return nil, returnTypeError(loaded, BeforeGenerationFunction, result, "a list or None")
+		}
+		messages, err = fromMessageList(resultList, originals)
+		if err != nil {
+			return nil, fmt.Errorf("%s of script %s: %w", BeforeGenerationFunction, loaded.name, err)
+		}
+	}
+	return messages, nil
+}
+
+// AfterGeneration implements plugin.PostGenerationHook.
+func (p *Plugin) AfterGeneration(ctx telebot.Context, response string) (string, error) {
+	for _, loaded := range p.scripts {
+		if loaded.afterGeneration == nil {
+			continue
+		}
+
+		result, err := p.call(loaded, loaded.afterGeneration, newEvent(ctx), starlark.String(response))
+		if err != nil {
+			return "", err
+		}
+		switch value := result.(type) {
+		case starlark.NoneType:
+		case starlark.String:
+			response = string(value)
+		default:
+			return "", returnTypeError(loaded, AfterGenerationFunction, result, "a string or None")
+		}
+	}
+	return response, nil
+}
+
+// call runs a hook function of a script.
+func (p *Plugin) call(loaded script, function starlark.Callable, args ...starlark.Value) (starlark.Value, error) {
+	thread, stop := p.newThread(loaded.name)
+	defer stop()
+
+	result, err := starlark.Call(thread, function, args, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s of script %s failed: %w", function.Name(), loaded.name, scriptError(err))
+	}
+	return result, nil
+}
+
+// newThread returns a Starlark thread that logs the output of print and is cancelled after the timeout
+// unless stop is called first.
+func (p *Plugin) newThread(name string) (*starlark.Thread, func() bool) {
+	thread := &starlark.Thread{Name: name}
+	thread.Print = func(_ *starlark.Thread, message string) {
+		log.Info().Str("script", name).Msg(message)
+	}
+
+	timer := time.AfterFunc(p.timeout, func() {
+		thread.Cancel(fmt.Sprintf("timed out after %s", p.timeout))
+	})
+	return thread, timer.Stop
+}
+
+// newEvent returns the chat, thread, and sender of the handled message as a Starlark struct.
+func newEvent(ctx telebot.Context) *starlarkstruct.Struct {
+	fields := starlark.StringDict{
+		"chat_id":    starlark.MakeInt(0),
+		"chat_title": starlark.String(""),
+		"chat_type":  starlark.String(""),
+		"thread_id":  starlark.MakeInt(0),
+		"user_id":    starlark.MakeInt(0),
+		"username":   starlark.String(""),
+		"first_name": starlark.String(""),
+		"last_name":  starlark.String(""),
+	}
+	if chat := ctx.Chat(); chat != nil {
+		fields["chat_id"] = starlark.MakeInt64(chat.ID)
+		fields["chat_title"] = starlark.String(chat.Title)
+		fields["chat_type"] = starlark.String(chat.Type)
+	}
+	if message := ctx.Message(); message != nil {
+		fields["thread_id"] = starlark.MakeInt(message.ThreadID)
+	}
+	if sender := ctx.Sender(); sender != nil {
+		fields["user_id"] = starlark.MakeInt64(sender.ID)
+		fields["username"] = starlark.String(sender.Username)
+		fields["first_name"] = starlark.String(sender.FirstName)
+		fields["last_name"] = starlark.String(sender.LastName)
+	}
+	return starlarkstruct.FromStringDict(starlarkstruct.Default, fields)
+}
+
+// messageList returns the messages as a list of dicts and the message each dict was created from.
+func messageList(messages []database.Message) (*starlark.List, map[*starlark.Dict]database.Message) {
+	dicts := make([]starlark.Value, len(messages))
+	originals := make(map[*starlark.Dict]database.Message, len(messages))
+	for i, message := range messages {
+		dict := starlark.NewDict(4)
+		_ = dict.SetKey(starlark.String("role"), starlark.String(message.Role))
+		_ = dict.SetKey(starlark.String("content"), starlark.String(message.Content))
+		_ = dict.SetKey(starlark.String("user_id"), starlark.MakeInt64(message.UserID))
+		_ = dict.SetKey(starlark.String("username"), starlark.String(message.Username))
+		dicts[i] = dict
+		originals[dict] = message
+	}
+	return starlark.NewList(dicts), originals
+}
+
+// fromMessageList converts the dicts back to messages. Dicts created from messages keep the fields
+// of the messages other than the role and content, such as their images.
+func fromMessageList(list *starlark.List, originals map[*starlark.Dict]database.Message) ([]database.Message, error) {
+	messages := make([]database.Message, list.Len())
+	for i := range list.Len() {
+		dict, ok := list.Index(i).(*starlark.Dict)
+		if !ok {
+			return nil, fmt.Errorf("message %d is a %s, not a dict", i, list.Index(i).Type())
+		}
+
+		message := originals[dict]
+		var err error
+		if message.Role, err = stringField(dict, "role"); err != nil {
+			return nil, fmt.Errorf("message %d: %w", i, err)
+		}
+		if message.Content, err = stringField(dict, "content"); err != nil {
+			return nil, fmt.Errorf("message %d: %w", i, err)
+		}
+		messages[i] = message
+	}
+	return messages, nil
+}
+
+// stringField returns the string value of a key of a dict.
+func stringField(dict *starlark.Dict, key string) (string, error) {
+	value, found, err := dict.Get(starlark.String(key))
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		return "", fmt.Errorf("missing %s", key)
+	}
+	str, ok := starlark.AsString(value)
+	if !ok {
+		return "", fmt.Errorf("%s is a %s, not a string", key, value.Type())
+	}
+	return str, nil
+}
+
+func returnTypeError(loaded script, function string, result starlark.Value, expected string) error {
+	return fmt.Errorf("%s of script %s returned a %s instead of %s", function, loaded.name, result.Type(), expected)
+}
+
+// scriptError adds the Starlark backtrace to the errors raised by scripts.
+func scriptError(err error) error {
+	var evalErr *starlark.EvalError
+	if errors.As(err, &evalErr) {
+		return errors.New(evalErr.Backtrace())
+	}
+	return err
+}
@@ -0,0 +1,243 @@
+package scripting //nolint:testpackage // Unit tests are in the same package
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/k4yt3x/tellama/internal/database"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/telebot.v4"
+)
+
+// newTestPlugin writes the scripts to a temporary directory and loads them.
+func newTestPlugin(t *testing.T, scripts map[string]string) (*Plugin, error) {
+	t.Helper()
+
+	directory := t.TempDir()
+	for name, source := range scripts {
+		require.NoError(t, os.WriteFile(filepath.Join(directory, name), []byte(source), 0o600))
+	}
+	return New(&Config{Enabled: true, Directory: directory, Timeout: time.Second})
+}
+
+// newTestContext returns the context of a message sent by a user in a group.
+func newTestContext(text string) telebot.Context {
+	return telebot.NewContext(nil, telebot.Update{Message: &telebot.Message{
+		Text:     text,
+		ThreadID: 7,
+		Chat:     &telebot.Chat{ID: -1001, Title: "Test Chat", Type: telebot.ChatSuperGroup},
+		Sender:   &telebot.User{ID: 42, Username: "alice", FirstName: "Alice"},
+	}})
+}
+
+func TestNew(t *testing.T) {
+	t.Run("Scripts load in order", func(t *testing.T) {
+		// Act
+		plugin, err := newTestPlugin(t, map[string]string{
+			"b.star":    "def after_generation(event, response):\n    return response\n",
+			"a.star":    "def filter_message(event, text):\n    return None\n",
+			"notes.txt": "not a script",
+		})
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a.star", "b.star"}, plugin.Scripts())
+	})
+
+	tests := []struct {
+		name   string
+		source string
+	}{
+		{name: "Syntax error", source: "def filter_message(event, text)\n    return text\n"},
+		{name: "Runtime error", source: "fail(\"broken\")\n"},
+		{name: "Hook is not a function", source: "filter_message = 1\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			_, err := newTestPlugin(t, map[string]string{"broken.star": tt.source})
+
+			// Assert
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "broken.star")
+		})
+	}
+
+	t.Run("Missing directory", func(t *testing.T) {
+		// Act
+		_, err := New(&Config{Enabled: true, Directory: filepath.Join(t.TempDir(), "missing"), Timeout: time.Second})
+
+		// Assert
+		assert.Error(t, err)
+	})
+}
+
+func TestFilterMessage(t *testing.T) {
+	plugin, err := newTestPlugin(t, map[string]string{
+		"1-block.star": `
+def filter_message(event, text):
+    if "crypto" in text.lower():
+        return False
+    if text.startswith("!"):
+        return True
+    return None
+`,
+		"2-rewrite.star": `
+def filter_message(event, text):
+    return "[%s in %d/%d] %s" % (event.username, event.chat_id, event.thread_id, text)
+`,
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		text     string
+		expected string
+		keep     bool
+	}{
+		{name: "Rewritten", text: "Hello", expected: "[alice in -1001/7] Hello", keep: true},
+		{name: "Kept", text: "!ping", expected: "[alice in -1001/7] !ping", keep: true},
+		{name: "Ignored", text: "Buy Crypto now"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			text, keep, err := plugin.FilterMessage(newTestContext(tt.text), tt.text)
+
+			// Assert
+			require.NoError(t, err)
+			assert.Equal(t, tt.keep, keep)
+			assert.Equal(t, tt.expected, text)
+		})
+	}
+}
+
+func TestBeforeGeneration(t *testing.T) {
+	t.Run("Add context and drop messages", func(t *testing.T) {
+		// Arrange
+		plugin, err := newTestPlugin(t, map[string]string{"context.star": `
+def before_generation(event, messages):
+    kept = [m for m in messages if "secret" not in m["content"]]
+    kept[0]["content"] += " Chat: " + event.chat_title
+    return kept + [{"role": "system", "content": "Answer briefly."}]
+`})
+		require.NoError(t, err)
+		messages := []database.Message{
+			{Role: "system", Content: "You are Tellama."},
+			{Role: "user", Content: "the secret is 42", UserID: 42},
+			{Role: "user", Content: "What is this?", UserID: 42, Images: [][]byte{{0x89}}},
+		}
+
+		// Act
+		result, err := plugin.BeforeGeneration(newTestContext("What is this?"), messages)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, []database.Message{
+			{Role: "system", Content: "You are Tellama. Chat: Test Chat"},
+			{Role: "user", Content: "What is this?", UserID: 42, Images: [][]byte{{0x89}}},
+			{Role: "system", Content: "Answer briefly."},
+		}, result)
+	})
+
+	t.Run("Unchanged", func(t *testing.T) {
+		// Arrange
+		plugin, err := newTestPlugin(t, map[string]string{"noop.star": "def before_generation(event, messages):\n    pass\n"})
+		require.NoError(t, err)
+		messages := []database.Message{{Role: "user", Content: "Hello"}}
+
+		// Act
+		result, err := plugin.BeforeGeneration(newTestContext("Hello"), messages)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, messages, result)
+	})
+
+	t.Run("Invalid messages", func(t *testing.T) {
+		// Arrange
+		plugin, err := newTestPlugin(t, map[string]string{
+			"invalid.star": "def before_generation(event, messages):\n    return [{\"role\": \"user\"}]\n",
+		})
+		require.NoError(t, err)
+
+		// Act
+		_, err = plugin.BeforeGeneration(newTestContext("Hello"), []database.Message{{Role: "user", Content: "Hello"}})
+
+		// Assert
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "missing content")
+	})
+}
+
+func TestAfterGeneration(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+		valid    bool
+	}{
+		{
+			name:     "Rewritten",
+			source:   "def after_generation(event, response):\n    return response.replace(\"damn\", \"d***\")\n",
+			expected: "Well, d***.",
+			valid:    true,
+		},
+		{
+			name:     "Suppressed",
+			source:   "def after_generation(event, response):\n    return \"\"\n",
+			expected: "",
+			valid:    true,
+		},
+		{
+			name:   "Wrong return type",
+			source: "def after_generation(event, response):\n    return 1\n",
+		},
+		{
+			name:   "Script error",
+			source: "def after_generation(event, response):\n    return response + 1\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			plugin, err := newTestPlugin(t, map[string]string{"response.star": tt.source})
+			require.NoError(t, err)
+
+			// Act
+			response, err := plugin.AfterGeneration(newTestContext("Hello"), "Well, damn.")
+
+			// Assert
+			if !tt.valid {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "response.star")
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, response)
+		})
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	// Arrange
+	directory := t.TempDir()
+	source := "def after_generation(event, response):\n    for i in range(1000000000):\n        pass\n"
+	require.NoError(t, os.WriteFile(filepath.Join(directory, "loop.star"), []byte(source), 0o600))
+	plugin, err := New(&Config{Enabled: true, Directory: directory, Timeout: 50 * time.Millisecond})
+	require.NoError(t, err)
+
+	// Act
+	_, err = plugin.AfterGeneration(newTestContext("Hello"), "Hello")
+
+	// Assert
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "timed out")
+}
@@ -0,0 +1,76 @@
+// Package secrets encrypts sensitive values before they are stored and
+// redacts them from text that may be logged or stored.
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// KeySize is the size in bytes of an encryption key.
+const KeySize = 32
+
+// apiKeyPattern matches API keys in the format used by OpenAI and compatible providers.
+var apiKeyPattern = regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{16,}`) //nolint:gochecknoglobals // Compiled once
+
+// Cipher encrypts and decrypts values with AES-256-GCM.
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// ParseKey decodes a base64-encoded encryption key.
+func ParseKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode encryption key: %w", err)
+	}
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("encryption key must be %d bytes", KeySize)
+	}
+	return key, nil
+}
+
+// NewCipher creates a cipher from a key of KeySize bytes.
+func NewCipher(key []byte) (*Cipher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Cipher{aead: aead}, nil
+}
+
+// Encrypt encrypts the plaintext, prepending the random nonce to the ciphertext.
+func (c *Cipher) Encrypt(plaintext string) ([]byte, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return c.aead.Seal(nonce, nonce, []byte(plaintext), nil), nil
+}
+
+// Decrypt decrypts a ciphertext produced by Encrypt.
+func (c *Cipher) Decrypt(ciphertext []byte) (string, error) {
+	if len(ciphertext) < c.aead.NonceSize() {
+		return "", errors.New("ciphertext is too short")
+	}
+	nonce, sealed := ciphertext[:c.aead.NonceSize()], ciphertext[c.aead.NonceSize():]
+	plaintext, err := c.aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// Redact replaces API keys in the text with a placeholder.
+func Redact(text string) string {
+	return apiKeyPattern.ReplaceAllString(text, "sk-[REDACTED]")
+}
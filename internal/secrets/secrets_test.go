@@ -0,0 +1,59 @@
+package secrets //nolint:testpackage // Unit tests are in the same package
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseKey(t *testing.T) {
+	// Arrange
+	encoded := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, KeySize))
+
+	// Act
+	key, err := ParseKey(encoded)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Len(t, key, KeySize)
+
+	_, err = ParseKey("not base64!")
+	require.Error(t, err)
+	_, err = ParseKey(base64.StdEncoding.EncodeToString([]byte("short")))
+	assert.ErrorContains(t, err, "encryption key must be 32 bytes")
+}
+
+func TestCipher(t *testing.T) {
+	// Arrange
+	c, err := NewCipher(bytes.Repeat([]byte{7}, KeySize))
+	require.NoError(t, err)
+
+	// Act
+	ciphertext, err := c.Encrypt("sk-secret")
+	require.NoError(t, err)
+	plaintext, err := c.Decrypt(ciphertext)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "sk-secret", plaintext)
+	assert.NotContains(t, string(ciphertext), "sk-secret")
+
+	// Tampered and truncated ciphertexts are rejected
+	ciphertext[len(ciphertext)-1] ^= 0xff
+	_, err = c.Decrypt(ciphertext)
+	require.Error(t, err)
+	_, err = c.Decrypt([]byte{1, 2})
+	assert.Error(t, err)
+}
+
+func TestRedact(t *testing.T) {
+	assert.Equal(
+		t,
+		"/setapikey sk-[REDACTED]",
+		Redact("/setapikey sk-proj-abcdefghijklmnopqrstuvwxyz0123456789"),
+	)
+	assert.Equal(t, "ask-me anything", Redact("ask-me anything"))
+}
@@ -0,0 +1,230 @@
+// Package tts synthesizes voice messages from the text of replies.
+package tts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+)
+
+type Backend int
+
+const (
+	BackendOpenAI Backend = iota
+	BackendPiper
+)
+
+func (b Backend) String() string {
+	return [...]string{"openai", "piper"}[b]
+}
+
+func ParseBackend(s string) (Backend, error) {
+	switch s {
+	case "openai":
+		return BackendOpenAI, nil
+	case "piper":
+		return BackendPiper, nil
+	default:
+		return 0, errors.New("unknown text-to-speech backend")
+	}
+}
+
+const openAIBaseURL = "https://api.openai.com/v1/"
+
+type Config struct {
+	Enabled bool
+	Backend Backend
+	BaseURL string
+	APIKey  string
+	Model   string
+	Voice   string
+
+	// FFmpegPath is the ffmpeg executable used to encode the WAV audio of Piper as OGG/Opus
+	FFmpegPath string
+
+	// MaxLength is the maximum number of characters read out, longer texts are cut off
+	MaxLength int
+	Timeout   time.Duration
+}
+
+func (c *Config) Validate() error {
+	switch c.Backend {
+	case BackendOpenAI:
+		if c.Model == "" || c.Voice == "" {
+			return errors.New("model and voice are required for OpenAI")
+		}
+	case BackendPiper:
+		if c.BaseURL == "" {
+			return errors.New("base URL is required for Piper")
+		}
+		if c.FFmpegPath == "" {
+			return errors.New("ffmpeg path is required for Piper")
+		}
+	}
+	if c.MaxLength < 1 {
+		return errors.New("max length must be at least 1")
+	}
+	return nil
+}
+
+// Synthesizer converts text to OGG/Opus audio that Telegram shows as a voice message.
+type Synthesizer struct {
+	client     *http.Client
+	openai     *openai.Client
+	backend    Backend
+	baseURL    string
+	model      string
+	voice      string
+	ffmpegPath string
+	maxLength  int
+}
+
+func New(config *Config) (*Synthesizer, error) {
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid text-to-speech config: %w", err)
+	}
+
+	synthesizer := &Synthesizer{
+		client:     &http.Client{Timeout: config.Timeout},
+		backend:    config.Backend,
+		baseURL:    config.BaseURL,
+		model:      config.Model,
+		voice:      config.Voice,
+		ffmpegPath: config.FFmpegPath,
+		maxLength:  config.MaxLength,
+	}
+	if config.Backend == BackendOpenAI {
+		baseURL := config.BaseURL
+		if baseURL == "" {
+			baseURL = openAIBaseURL
+		}
+		synthesizer.openai = openai.NewClient(
+			option.WithBaseURL(baseURL),
+			option.WithAPIKey(config.APIKey),
+			option.WithHTTPClient(synthesizer.client),
+		)
+	}
+	return synthesizer, nil
+}
+
+// Synthesize returns the text read out as OGG/Opus audio.
+func (s *Synthesizer) Synthesize(ctx context.Context, text string) ([]byte, error) {
+	text = Speakable(text, s.maxLength)
+	if text == "" {
+		return nil, errors.New("text cannot be empty")
+	}
+
+	var audio []byte
+	var err error
+	switch s.backend {
+	case BackendOpenAI:
+		audio, err = s.synthesizeOpenAI(ctx, text)
+	case BackendPiper:
+		audio, err = s.synthesizePiper(ctx, text)
+	default:
+		return nil, fmt.Errorf("unsupported text-to-speech backend: %s", s.backend)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s speech synthesis failed: %w", s.backend, err)
+	}
+	return audio, nil
+}
+
+func (s *Synthesizer) synthesizeOpenAI(ctx context.Context, text string) ([]byte, error) {
+	response, err := s.openai.Audio.Speech.New(ctx, openai.AudioSpeechNewParams{
+		Input:          openai.F(text),
+		Model:          openai.F(openai.SpeechModel(s.model)),
+		Voice:          openai.F(openai.AudioSpeechNewParamsVoice(s.voice)),
+		ResponseFormat: openai.F(openai.AudioSpeechNewParamsResponseFormatOpus),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	return io.ReadAll(response.Body)
+}
+
+// synthesizePiper requests WAV audio from a Piper HTTP server and encodes it as OGG/Opus.
+func (s *Synthesizer) synthesizePiper(ctx context.Context, text string) ([]byte, error) {
+	payload := map[string]string{"text": text}
+	if s.voice != "" {
+		payload["voice"] = s.voice
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, s.baseURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", response.StatusCode)
+	}
+	wav, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	return s.encodeOpus(ctx, wav)
+}
+
+// encodeOpus converts audio to OGG/Opus with ffmpeg.
+func (s *Synthesizer) encodeOpus(ctx context.Context, audio []byte) ([]byte, error) {
+	cmd := exec.CommandContext( //nolint:gosec // The ffmpeg path comes from the configuration
+		ctx, s.ffmpegPath,
+		"-hide_banner", "-loglevel", "error",
+		"-i", "pipe:0",
+		"-c:a", "libopus", "-b:a", "32k",
+		"-f", "ogg", "pipe:1",
+	)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdin = bytes.NewReader(audio)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to encode audio: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+var (
+	// codeBlockPattern matches fenced code blocks, which are not read out.
+	codeBlockPattern = regexp.MustCompile("(?s)```.*?```") //nolint:gochecknoglobals // Compiled once
+	// linkPattern matches Markdown links, which are read out as their text.
+	linkPattern = regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`) //nolint:gochecknoglobals // Compiled once
+	// markupPattern matches Markdown emphasis, code, and heading markers.
+	markupPattern = regexp.MustCompile("(?m)[*_`~]+|^#+\\s*") //nolint:gochecknoglobals // Compiled once
+)
+
+// Speakable removes the Markdown formatting that should not be read out from a text
+// and cuts it off after maxLength characters.
+func Speakable(text string, maxLength int) string {
+	text = codeBlockPattern.ReplaceAllString(text, "")
+	text = linkPattern.ReplaceAllString(text, "$1")
+	text = markupPattern.ReplaceAllString(text, "")
+	text = strings.TrimSpace(text)
+
+	if runes := []rune(text); len(runes) > maxLength {
+		text = strings.TrimSpace(string(runes[:maxLength]))
+	}
+	return text
+}
@@ -0,0 +1,137 @@
+package tts //nolint:testpackage // Unit tests are in the same package
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSynthesize_OpenAI(t *testing.T) {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/audio/speech", r.URL.Path)
+		assert.Equal(t, "Bearer test_key", r.Header.Get("Authorization"))
+		var body map[string]any
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "Hello world", body["input"])
+		assert.Equal(t, "tts-1", body["model"])
+		assert.Equal(t, "alloy", body["voice"])
+		assert.Equal(t, "opus", body["response_format"])
+		_, _ = w.Write([]byte("OggS"))
+	}))
+	defer server.Close()
+	synthesizer, err := New(&Config{
+		Backend:   BackendOpenAI,
+		BaseURL:   server.URL,
+		APIKey:    "test_key",
+		Model:     "tts-1",
+		Voice:     "alloy",
+		MaxLength: 100,
+		Timeout:   5 * time.Second,
+	})
+	require.NoError(t, err)
+
+	// Act
+	audio, err := synthesizer.Synthesize(context.Background(), "**Hello** world")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, []byte("OggS"), audio)
+}
+
+func TestSynthesize_Piper(t *testing.T) {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "Hello", body["text"])
+		assert.Equal(t, "en_US-lessac-medium", body["voice"])
+		_, _ = w.Write([]byte("RIFF"))
+	}))
+	defer server.Close()
+
+	// Stand in for ffmpeg with a script that copies the audio unchanged
+	ffmpegPath := filepath.Join(t.TempDir(), "ffmpeg")
+	require.NoError(t, os.WriteFile(ffmpegPath, []byte("#!/bin/sh\ncat\n"), 0o700)) //nolint:gosec // Test script
+	synthesizer, err := New(&Config{
+		Backend:    BackendPiper,
+		BaseURL:    server.URL,
+		Voice:      "en_US-lessac-medium",
+		FFmpegPath: ffmpegPath,
+		MaxLength:  100,
+		Timeout:    5 * time.Second,
+	})
+	require.NoError(t, err)
+
+	// Act
+	audio, err := synthesizer.Synthesize(context.Background(), "Hello")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, []byte("RIFF"), audio)
+}
+
+func TestSynthesize_EmptyText(t *testing.T) {
+	// Arrange
+	synthesizer, err := New(&Config{Backend: BackendOpenAI, Model: "tts-1", Voice: "alloy", MaxLength: 100})
+	require.NoError(t, err)
+
+	// Act
+	_, err = synthesizer.Synthesize(context.Background(), "```\ncode only\n```")
+
+	// Assert
+	require.Error(t, err)
+}
+
+func TestSpeakable(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		maxLength int
+		expected  string
+	}{
+		{name: "Plain text", text: "Hello there.", maxLength: 100, expected: "Hello there."},
+		{name: "Emphasis and code", text: "This is **bold**, _italic_, and `code`.", maxLength: 100,
+			expected: "This is bold, italic, and code."},
+		{name: "Heading", text: "# Title\nBody", maxLength: 100, expected: "Title\nBody"},
+		{name: "Link", text: "See [the docs](https://example.com).", maxLength: 100, expected: "See the docs."},
+		{name: "Code block", text: "Run this:\n```go\nfmt.Println()\n```", maxLength: 100, expected: "Run this:"},
+		{name: "Cut off", text: "こんにちは世界", maxLength: 5, expected: "こんにちは"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			text := Speakable(tt.text, tt.maxLength)
+
+			// Assert
+			assert.Equal(t, tt.expected, text)
+		})
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	// Arrange
+	openAI := Config{Backend: BackendOpenAI, Model: "tts-1", Voice: "alloy", MaxLength: 100}
+	piper := Config{Backend: BackendPiper, BaseURL: "http://localhost:5000", FFmpegPath: "ffmpeg", MaxLength: 100}
+
+	// Assert
+	require.NoError(t, openAI.Validate())
+	require.NoError(t, piper.Validate())
+
+	openAI.Voice = ""
+	assert.Error(t, openAI.Validate())
+	piper.BaseURL = ""
+	assert.Error(t, piper.Validate())
+	piper.BaseURL = "http://localhost:5000"
+	piper.MaxLength = 0
+	assert.Error(t, piper.Validate())
+}
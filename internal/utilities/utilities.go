@@ -1,5 +1,7 @@
 package utilities
 
+import "unicode/utf8"
+
 func TruncateStrToLength(input string, maxLength int) string {
 	runes := []rune(input)
 	if len(runes) <= maxLength {
@@ -10,3 +12,9 @@ func TruncateStrToLength(input string, maxLength int) string {
 	}
 	return string(runes[:maxLength-1]) + "…"
 }
+
+// EstimateTokens returns a rough estimate of the number of tokens in the input,
+// assuming an average of four characters per token.
+func EstimateTokens(input string) int {
+	return (utf8.RuneCountInString(input) + 3) / 4
+}
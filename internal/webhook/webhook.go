@@ -0,0 +1,232 @@
+// Package webhook posts JSON events to external URLs, signed with HMAC-SHA256.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Events that can be posted to webhooks.
+const (
+	EventMessageReceived   = "message_received"
+	EventResponseGenerated = "response_generated"
+	EventProviderError     = "provider_error"
+	EventUntrustedChat     = "untrusted_chat"
+)
+
+// Headers of webhook requests.
+const (
+	// EventHeader contains the type of the event.
+	EventHeader = "X-Tellama-Event"
+	// SignatureHeader contains "sha256=" followed by the hex encoded HMAC-SHA256 of the body.
+	SignatureHeader = "X-Tellama-Signature"
+)
+
+// queueSize is the number of events waiting to be posted above which new events are dropped.
+const queueSize = 100
+
+// ParseEvents validates a list of event names and removes duplicates.
+func ParseEvents(names []string) ([]string, error) {
+	var events []string
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case "":
+			continue
+		case EventMessageReceived, EventResponseGenerated, EventProviderError, EventUntrustedChat:
+			if !slices.Contains(events, name) {
+				events = append(events, name)
+			}
+		default:
+			return nil, fmt.Errorf("unknown webhook event %q", name)
+		}
+	}
+	return events, nil
+}
+
+type Config struct {
+	Enabled bool
+	URLs    []string
+
+	// Secret is the key of the signatures, which are omitted if it is empty
+	Secret string
+
+	// Events are the events posted, or all events if empty
+	Events []string
+
+	// IncludeText adds the text of messages and responses to their events
+	IncludeText bool
+	Timeout     time.Duration
+}
+
+func (c *Config) Validate() error {
+	if len(c.URLs) == 0 {
+		return errors.New("at least one URL is required")
+	}
+	for _, rawURL := range c.URLs {
+		parsed, err := url.Parse(rawURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("invalid URL %q", rawURL)
+		}
+	}
+	if _, err := ParseEvents(c.Events); err != nil {
+		return err
+	}
+	if c.Timeout <= 0 {
+		return errors.New("timeout must be positive")
+	}
+	return nil
+}
+
+// Event is the JSON body of a webhook request.
+type Event struct {
+	Type      string    `json:"type"`
+	Timestamp time.Time `json:"timestamp"`
+	Data      any       `json:"data"`
+}
+
+// MessageReceived is the data of a message received from a chat the bot may respond in.
+type MessageReceived struct {
+	ChatID    int64  `json:"chat_id"`
+	ChatTitle string `json:"chat_title,omitempty"`
+	ThreadID  int    `json:"thread_id,omitempty"`
+	MessageID int    `json:"message_id"`
+	UserID    int64  `json:"user_id"`
+	Username  string `json:"username,omitempty"`
+	Text      string `json:"text,omitempty"`
+}
+
+// ResponseGenerated is the data of a response sent to a chat.
+type ResponseGenerated struct {
+	ChatID           int64  `json:"chat_id"`
+	MessageID        int    `json:"message_id"`
+	ReplyToMessageID int    `json:"reply_to_message_id"`
+	UserID           int64  `json:"user_id"`
+	Model            string `json:"model"`
+	PromptTokens     int64  `json:"prompt_tokens"`
+	CompletionTokens int64  `json:"completion_tokens"`
+	DurationMS       int64  `json:"duration_ms"`
+	Text             string `json:"text,omitempty"`
+}
+
+// ProviderError is the data of a failed call to the generative AI provider.
+type ProviderError struct {
+	Provider string `json:"provider"`
+	Error    string `json:"error"`
+}
+
+// UntrustedChat is the data of the first message received from an untrusted chat since the bot started.
+type UntrustedChat struct {
+	ChatID    int64  `json:"chat_id"`
+	ChatTitle string `json:"chat_title,omitempty"`
+	ChatType  string `json:"chat_type"`
+	UserID    int64  `json:"user_id"`
+	Username  string `json:"username,omitempty"`
+}
+
+// Sign returns the signature of a request body with a secret.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Notifier posts events to the configured URLs in the background, in the order they are emitted.
+type Notifier struct {
+	urls        []string
+	secret      string
+	events      []string
+	includeText bool
+	client      *http.Client
+	queue       chan Event
+}
+
+func New(config *Config) (*Notifier, error) {
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid webhook config: %w", err)
+	}
+
+	events, err := ParseEvents(config.Events)
+	if err != nil {
+		return nil, err
+	}
+	return &Notifier{
+		urls:        config.URLs,
+		secret:      config.Secret,
+		events:      events,
+		includeText: config.IncludeText,
+		client:      &http.Client{Timeout: config.Timeout},
+		queue:       make(chan Event, queueSize),
+	}, nil
+}
+
+// IncludeText reports whether events include the text of messages and responses.
+func (n *Notifier) IncludeText() bool {
+	return n.includeText
+}
+
+// Emit queues an event if it is configured to be posted. Events are dropped while the queue is full.
+func (n *Notifier) Emit(eventType string, data any) {
+	if len(n.events) > 0 && !slices.Contains(n.events, eventType) {
+		return
+	}
+
+	select {
+	case n.queue <- Event{Type: eventType, Timestamp: time.Now().UTC(), Data: data}:
+	default:
+		log.Warn().Str("event", eventType).Msg("Webhook queue is full, dropping event")
+	}
+}
+
+// Run posts the queued events. It never returns, so it is run in its own goroutine.
+func (n *Notifier) Run() {
+	for event := range n.queue {
+		body, err := json.Marshal(event)
+		if err != nil {
+			log.Error().Err(err).Str("event", event.Type).Msg("Failed to encode webhook event")
+			continue
+		}
+		for _, target := range n.urls {
+			if err = n.post(target, event.Type, body); err != nil {
+				log.Warn().Err(err).Str("event", event.Type).Msg("Failed to post webhook event")
+			}
+		}
+	}
+}
+
+// post sends an event to a URL.
+func (n *Notifier) post(target string, eventType string, body []byte) error {
+	request, err := http.NewRequestWithContext(context.Background(), http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(EventHeader, eventType)
+	if n.secret != "" {
+		request.Header.Set(SignatureHeader, Sign(n.secret, body))
+	}
+
+	response, err := n.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < http.StatusOK || response.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("unexpected status %s", response.Status)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package webhook //nolint:testpackage // Unit tests are in the same package
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// receivedRequest is a webhook request captured by the test server.
+type receivedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+// newTestServer returns a server that sends the requests it receives to the returned channel.
+func newTestServer(t *testing.T) (*httptest.Server, chan receivedRequest) {
+	t.Helper()
+	requests := make(chan receivedRequest, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- receivedRequest{header: r.Header.Clone(), body: body}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+	return server, requests
+}
+
+func TestNotifier(t *testing.T) {
+	t.Run("Signed event", func(t *testing.T) {
+		// Arrange
+		server, requests := newTestServer(t)
+		notifier, err := New(&Config{URLs: []string{server.URL}, Secret: "secret", Timeout: time.Second})
+		require.NoError(t, err)
+		go notifier.Run()
+
+		// Act
+		notifier.Emit(EventProviderError, ProviderError{Provider: "ollama", Error: "connection refused"})
+
+		// Assert
+		request := <-requests
+		assert.Equal(t, EventProviderError, request.header.Get(EventHeader))
+		assert.Equal(t, Sign("secret", request.body), request.header.Get(SignatureHeader))
+
+		var event struct {
+			Type string        `json:"type"`
+			Data ProviderError `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(request.body, &event))
+		assert.Equal(t, EventProviderError, event.Type)
+		assert.Equal(t, "connection refused", event.Data.Error)
+	})
+
+	t.Run("Unsigned without a secret", func(t *testing.T) {
+		// Arrange
+		server, requests := newTestServer(t)
+		notifier, err := New(&Config{URLs: []string{server.URL}, Timeout: time.Second})
+		require.NoError(t, err)
+		go notifier.Run()
+
+		// Act
+		notifier.Emit(EventUntrustedChat, UntrustedChat{ChatID: 1})
+
+		// Assert
+		request := <-requests
+		assert.Empty(t, request.header.Get(SignatureHeader))
+	})
+
+	t.Run("Filtered events", func(t *testing.T) {
+		// Arrange
+		server, requests := newTestServer(t)
+		notifier, err := New(&Config{
+			URLs:    []string{server.URL},
+			Events:  []string{EventResponseGenerated},
+			Timeout: time.Second,
+		})
+		require.NoError(t, err)
+		go notifier.Run()
+
+		// Act
+		notifier.Emit(EventMessageReceived, MessageReceived{ChatID: 1})
+		notifier.Emit(EventResponseGenerated, ResponseGenerated{ChatID: 2})
+
+		// Assert
+		request := <-requests
+		assert.Equal(t, EventResponseGenerated, request.header.Get(EventHeader))
+	})
+}
+
+func TestSign(t *testing.T) {
+	// The expected value is the HMAC-SHA256 of "body" with the key "secret"
+	assert.Equal(
+		t,
+		"sha256=dc46983557fea127b43af721467eb9b3fde2338fe3e14f51952aa8478c13d355",
+		Sign("secret", []byte("body")),
+	)
+}
+
+func TestConfigValidate(t *testing.T) {
+	assert.NoError(t, (&Config{URLs: []string{"https://example.com/hook"}, Timeout: time.Second}).Validate())
+	assert.Error(t, (&Config{Timeout: time.Second}).Validate())
+	assert.Error(t, (&Config{URLs: []string{"example.com/hook"}, Timeout: time.Second}).Validate())
+	assert.Error(t, (&Config{
+		URLs:    []string{"https://example.com/hook"},
+		Events:  []string{"unknown"},
+		Timeout: time.Second,
+	}).Validate())
+	assert.Error(t, (&Config{URLs: []string{"https://example.com/hook"}}).Validate())
+}
+
+func TestParseEvents(t *testing.T) {
+	events, err := ParseEvents([]string{" Message_Received", "provider_error", "message_received", ""})
+	require.NoError(t, err)
+	assert.Equal(t, []string{EventMessageReceived, EventProviderError}, events)
+}
@@ -0,0 +1,308 @@
+// Package websearch implements a tool that lets the model search the web.
+package websearch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/k4yt3x/tellama/internal/genai"
+)
+
+type Backend int
+
+const (
+	BackendSearxNG Backend = iota
+	BackendBrave
+	BackendSerper
+)
+
+func (b Backend) String() string {
+	return [...]string{"searxng", "brave", "serper"}[b]
+}
+
+func ParseBackend(s string) (Backend, error) {
+	switch s {
+	case "searxng":
+		return BackendSearxNG, nil
+	case "brave":
+		return BackendBrave, nil
+	case "serper":
+		return BackendSerper, nil
+	default:
+		return 0, errors.New("unknown web search backend")
+	}
+}
+
+const (
+	braveBaseURL  = "https://api.search.brave.com/res/v1/web/search"
+	serperBaseURL = "https://google.serper.dev/search"
+)
+
+type Config struct {
+	Enabled    bool
+	Backend    Backend
+	BaseURL    string
+	APIKey     string
+	MaxResults int
+	Timeout    time.Duration
+}
+
+func (c *Config) Validate() error {
+	if c.Backend == BackendSearxNG && c.BaseURL == "" {
+		return errors.New("base URL is required for SearxNG")
+	}
+	if c.Backend != BackendSearxNG && c.APIKey == "" {
+		return fmt.Errorf("API key is required for %s", c.Backend)
+	}
+	if c.MaxResults < 1 {
+		return errors.New("max results must be at least 1")
+	}
+	return nil
+}
+
+// Result is a single web search result.
+type Result struct {
+	Title   string
+	URL     string
+	Snippet string
+}
+
+// WebSearch is a genai.Tool that queries a web search backend.
+type WebSearch struct {
+	client     *http.Client
+	backend    Backend
+	baseURL    string
+	apiKey     string
+	maxResults int
+}
+
+var _ genai.Tool = (*WebSearch)(nil)
+
+func New(config *Config) (*WebSearch, error) {
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid web search config: %w", err)
+	}
+
+	baseURL := config.BaseURL
+	if baseURL == "" {
+		switch config.Backend {
+		case BackendBrave:
+			baseURL = braveBaseURL
+		case BackendSerper:
+			baseURL = serperBaseURL
+		case BackendSearxNG:
+		}
+	}
+
+	return &WebSearch{
+		client:     &http.Client{Timeout: config.Timeout},
+		backend:    config.Backend,
+		baseURL:    baseURL,
+		apiKey:     config.APIKey,
+		maxResults: config.MaxResults,
+	}, nil
+}
+
+func (w *WebSearch) Name() string {
+	return "web_search"
+}
+
+func (w *WebSearch) Description() string {
+	return "Search the web for up-to-date information such as current events, " +
+		"recent releases, or facts you are unsure about. " +
+		"When you use the results, cite them in your reply as [n] followed by the URL."
+}
+
+func (w *WebSearch) Parameters() map[string]any {
+	return map[string]any{
+		"type":     "object",
+		"required": []string{"query"},
+		"properties": map[string]any{
+			"query": map[string]any{
+				"type":        "string",
+				"description": "The search query",
+			},
+		},
+	}
+}
+
+func (w *WebSearch) Execute(arguments string) (string, error) {
+	var args struct {
+		Query string `json:"query"`
+	}
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+	if strings.TrimSpace(args.Query) == "" {
+		return "", errors.New("query cannot be empty")
+	}
+
+	results, err := w.Search(context.Background(), args.Query)
+	if err != nil {
+		return "", err
+	}
+	return FormatResults(results), nil
+}
+
+// Search queries the configured backend and returns at most maxResults results.
+func (w *WebSearch) Search(ctx context.Context, query string) ([]Result, error) {
+	var results []Result
+	var err error
+
+	switch w.backend {
+	case BackendSearxNG:
+		results, err = w.searchSearxNG(ctx, query)
+	case BackendBrave:
+		results, err = w.searchBrave(ctx, query)
+	case BackendSerper:
+		results, err = w.searchSerper(ctx, query)
+	default:
+		return nil, fmt.Errorf("unsupported web search backend: %s", w.backend)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s search failed: %w", w.backend, err)
+	}
+
+	if len(results) > w.maxResults {
+		results = results[:w.maxResults]
+	}
+	return results, nil
+}
+
+// FormatResults renders search results as a numbered list for the model to cite.
+func FormatResults(results []Result) string {
+	if len(results) == 0 {
+		return "No results found."
+	}
+
+	var builder strings.Builder
+	for i, result := range results {
+		fmt.Fprintf(&builder, "[%d] %s\nURL: %s\n%s\n\n", i+1, result.Title, result.URL, result.Snippet)
+	}
+	return strings.TrimSpace(builder.String())
+}
+
+func (w *WebSearch) searchSearxNG(ctx context.Context, query string) ([]Result, error) {
+	endpoint, err := url.JoinPath(w.baseURL, "search")
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodGet,
+		endpoint+"?"+url.Values{"q": {query}, "format": {"json"}}.Encode(),
+		nil,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		Results []struct {
+			Title   string `json:"title"`
+			URL     string `json:"url"`
+			Content string `json:"content"`
+		} `json:"results"`
+	}
+	if err = w.do(request, &response); err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, len(response.Results))
+	for i, r := range response.Results {
+		results[i] = Result{Title: r.Title, URL: r.URL, Snippet: r.Content}
+	}
+	return results, nil
+}
+
+func (w *WebSearch) searchBrave(ctx context.Context, query string) ([]Result, error) {
+	request, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodGet,
+		w.baseURL+"?"+url.Values{"q": {query}}.Encode(),
+		nil,
+	)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Accept", "application/json")
+	request.Header.Set("X-Subscription-Token", w.apiKey)
+
+	var response struct {
+		Web struct {
+			Results []struct {
+				Title       string `json:"title"`
+				URL         string `json:"url"`
+				Description string `json:"description"`
+			} `json:"results"`
+		} `json:"web"`
+	}
+	if err = w.do(request, &response); err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, len(response.Web.Results))
+	for i, r := range response.Web.Results {
+		results[i] = Result{Title: r.Title, URL: r.URL, Snippet: r.Description}
+	}
+	return results, nil
+}
+
+func (w *WebSearch) searchSerper(ctx context.Context, query string) ([]Result, error) {
+	body, err := json.Marshal(map[string]any{"q": query, "num": w.maxResults})
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		w.baseURL,
+		strings.NewReader(string(body)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("X-Api-Key", w.apiKey)
+
+	var response struct {
+		Organic []struct {
+			Title   string `json:"title"`
+			Link    string `json:"link"`
+			Snippet string `json:"snippet"`
+		} `json:"organic"`
+	}
+	if err = w.do(request, &response); err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, len(response.Organic))
+	for i, r := range response.Organic {
+		results[i] = Result{Title: r.Title, URL: r.Link, Snippet: r.Snippet}
+	}
+	return results, nil
+}
+
+// do sends the request and decodes the JSON response body into target.
+func (w *WebSearch) do(request *http.Request, target any) error {
+	response, err := w.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return fmt.Errorf("unexpected status %s: %s", response.Status, strings.TrimSpace(string(body)))
+	}
+	return json.NewDecoder(response.Body).Decode(target)
+}
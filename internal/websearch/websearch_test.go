@@ -0,0 +1,121 @@
+package websearch //nolint:testpackage // Unit tests are in the same package
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestWebSearch(t *testing.T, backend Backend, handler http.HandlerFunc) *WebSearch {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	webSearch, err := New(&Config{
+		Enabled:    true,
+		Backend:    backend,
+		BaseURL:    server.URL,
+		APIKey:     "test_key",
+		MaxResults: 2,
+		Timeout:    5 * time.Second,
+	})
+	require.NoError(t, err)
+	return webSearch
+}
+
+func TestExecute_SearxNG(t *testing.T) {
+	// Arrange
+	webSearch := newTestWebSearch(t, BackendSearxNG, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/search", r.URL.Path)
+		assert.Equal(t, "golang", r.URL.Query().Get("q"))
+		assert.Equal(t, "json", r.URL.Query().Get("format"))
+		_ = json.NewEncoder(w).Encode(map[string]any{"results": []map[string]string{
+			{"title": "Go", "url": "https://go.dev", "content": "The Go language"},
+			{"title": "Tour", "url": "https://go.dev/tour", "content": "A tour of Go"},
+			{"title": "Extra", "url": "https://example.com", "content": "Dropped"},
+		}})
+	})
+
+	// Act
+	result, err := webSearch.Execute(`{"query":"golang"}`)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Contains(t, result, "[1] Go\nURL: https://go.dev\nThe Go language")
+	assert.Contains(t, result, "[2] Tour")
+	assert.NotContains(t, result, "Extra")
+}
+
+func TestSearch_Brave(t *testing.T) {
+	// Arrange
+	webSearch := newTestWebSearch(t, BackendBrave, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "test_key", r.Header.Get("X-Subscription-Token"))
+		_ = json.NewEncoder(w).Encode(map[string]any{"web": map[string]any{
+			"results": []map[string]string{
+				{"title": "Brave", "url": "https://brave.com", "description": "Browser"},
+			},
+		}})
+	})
+
+	// Act
+	results, err := webSearch.Search(t.Context(), "brave")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, []Result{{Title: "Brave", URL: "https://brave.com", Snippet: "Browser"}}, results)
+}
+
+func TestSearch_Serper(t *testing.T) {
+	// Arrange
+	webSearch := newTestWebSearch(t, BackendSerper, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "test_key", r.Header.Get("X-Api-Key"))
+		_ = json.NewEncoder(w).Encode(map[string]any{"organic": []map[string]string{
+			{"title": "Serper", "link": "https://serper.dev", "snippet": "Search API"},
+		}})
+	})
+
+	// Act
+	results, err := webSearch.Search(t.Context(), "serper")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, []Result{{Title: "Serper", URL: "https://serper.dev", Snippet: "Search API"}}, results)
+}
+
+func TestSearch_ErrorStatus(t *testing.T) {
+	// Arrange
+	webSearch := newTestWebSearch(t, BackendSearxNG, func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "rate limited", http.StatusTooManyRequests)
+	})
+
+	// Act
+	_, err := webSearch.Search(t.Context(), "anything")
+
+	// Assert
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "429")
+}
+
+func TestExecute_EmptyQuery(t *testing.T) {
+	// Arrange
+	webSearch := newTestWebSearch(t, BackendSearxNG, func(http.ResponseWriter, *http.Request) {})
+
+	// Act
+	_, err := webSearch.Execute(`{"query":"  "}`)
+
+	// Assert
+	assert.Error(t, err)
+}
+
+func TestNew_MissingAPIKey(t *testing.T) {
+	// Act
+	_, err := New(&Config{Backend: BackendBrave, MaxResults: 5})
+
+	// Assert
+	assert.Error(t, err)
+}